@@ -58,6 +58,7 @@ func usersMetrics(skipDaily bool) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	req.Header.Set("Metrics-Secret", mustEnv("METRICS_SECRET"))
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		log.Fatal(err)
@@ -67,7 +68,7 @@ func usersMetrics(skipDaily bool) {
 		log.Fatal("Error running user metrics", resp.Status)
 	}
 	ctx := context.Background()
-	bigQClient, err := bigquery.NewClient(ctx, "metrics-240714")
+	bigQClient, err := bigquery.NewClient(ctx, getEnv("BIGQUERY_PROJECT", defaultBigQueryProject))
 	if err != nil {
 		log.Fatal("error creating client", err)
 	}
@@ -75,7 +76,7 @@ func usersMetrics(skipDaily bool) {
 	source.AutoDetect = true   // Allow BigQuery to determine schema.
 	source.SkipLeadingRows = 1 // CSV has a single header line.
 
-	datasetID := "beta_metrics"
+	datasetID := getEnv("BIGQUERY_DATASET", defaultBigQueryDataset)
 	loader := bigQClient.Dataset(datasetID).Table(metricsTable).LoaderFrom(source)
 	loader.WriteDisposition = bigquery.WriteAppend
 	job, err := loader.Run(ctx)
@@ -143,6 +144,7 @@ func claimMetrics(skipDaily bool) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	req.Header.Set("Metrics-Secret", mustEnv("METRICS_SECRET"))
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		log.Fatal(err)
@@ -152,7 +154,7 @@ func claimMetrics(skipDaily bool) {
 		log.Fatal("Error running claim metrics", resp.Status)
 	}
 	ctx := context.Background()
-	bigQClient, err := bigquery.NewClient(ctx, "metrics-240714")
+	bigQClient, err := bigquery.NewClient(ctx, getEnv("BIGQUERY_PROJECT", defaultBigQueryProject))
 	if err != nil {
 		log.Fatal("error creating client", err)
 	}
@@ -161,7 +163,7 @@ func claimMetrics(skipDaily bool) {
 	source.SkipLeadingRows = 1 // CSV has a single header line.
 	source.AllowQuotedNewlines = true
 
-	datasetID := "beta_metrics"
+	datasetID := getEnv("BIGQUERY_DATASET", defaultBigQueryDataset)
 	loader := bigQClient.Dataset(datasetID).Table(metricsTable).LoaderFrom(source)
 	loader.WriteDisposition = bigquery.WriteAppend
 
@@ -202,6 +204,7 @@ func userClaims() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	req.Header.Set("Metrics-Secret", mustEnv("METRICS_SECRET"))
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		log.Fatal(err)
@@ -211,7 +214,7 @@ func userClaims() {
 		log.Fatal("Error running claim metrics", resp.Status)
 	}
 	ctx := context.Background()
-	bigQClient, err := bigquery.NewClient(ctx, "metrics-240714")
+	bigQClient, err := bigquery.NewClient(ctx, getEnv("BIGQUERY_PROJECT", defaultBigQueryProject))
 	if err != nil {
 		log.Fatal("error creating client", err)
 	}
@@ -220,7 +223,7 @@ func userClaims() {
 	source.SkipLeadingRows = 1 // CSV has a single header line.
 	source.AllowQuotedNewlines = true
 
-	datasetID := "beta_metrics"
+	datasetID := getEnv("BIGQUERY_DATASET", defaultBigQueryDataset)
 	loader := bigQClient.Dataset(datasetID).Table(metricsTable).LoaderFrom(source)
 	loader.WriteDisposition = bigquery.WriteAppend
 
@@ -238,6 +241,10 @@ func userClaims() {
 }
 func main() {
 	skipDaily := flag.Bool("skip-daily", false, "skip daily calculation")
+	var reportOpts reportOptions
+	flag.StringVar(&reportOpts.format, "format", "csv", "format of the reports, csv or json")
+	flag.StringVar(&reportOpts.out, "out", "", "file the report is written to (default stdout)")
+	flag.IntVar(&reportOpts.weeks, "weeks", 12, "number of weeks of signups in the reports")
 	flag.Parse()
 	args := flag.Args()
 	if len(args) == 0 {
@@ -253,6 +260,10 @@ func main() {
 		userBase()
 	case "user_claims":
 		userClaims()
+	case "cohorts":
+		cohortsReport(reportOpts)
+	case "funnel":
+		funnelReport(reportOpts)
 	case "all":
 		fmt.Println("Running users and claim metrics")
 		usersMetrics(*skipDaily)
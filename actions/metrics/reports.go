@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	truCtx "github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+// channelAll is the channel of the rows summing up all the channels
+const channelAll = "all"
+
+var funnelSteps = []db.UserJourneyStep{
+	db.JourneyStepSignedUp,
+	db.JourneyStepOneArgument,
+	db.JourneyStepGivenOneAgree,
+	db.JourneyStepReceiveFiveAgrees,
+}
+
+// cohortRow is the retention of a weekly signup cohort, the share of its users active on their
+// 1st, 7th and 30th day after signing up. A rate is computed over the users whose day is over
+// and is empty when there are none yet.
+type cohortRow struct {
+	Week         string   `json:"week"`
+	Channel      string   `json:"channel"`
+	Users        int64    `json:"users"`
+	RetentionD1  *float64 `json:"retention_d1"`
+	RetentionD7  *float64 `json:"retention_d7"`
+	RetentionD30 *float64 `json:"retention_d30"`
+}
+
+// funnelRow is a step of the journey funnel, with the conversion from the previous and the first step
+type funnelRow struct {
+	Channel      string   `json:"channel"`
+	Step         string   `json:"step"`
+	Users        int64    `json:"users"`
+	FromPrevious *float64 `json:"from_previous"`
+	FromStart    *float64 `json:"from_start"`
+}
+
+// reportOptions are the flags of the report subcommands
+type reportOptions struct {
+	format string
+	out    string
+	weeks  int
+}
+
+func newReportDBClient() *db.Client {
+	port, err := strconv.Atoi(getEnv("PG_PORT", "5432"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return db.NewDBClient(truCtx.Config{
+		Database: truCtx.DatabaseConfig{
+			Host: getEnv("PG_HOST", "localhost"),
+			Port: port,
+			User: getEnv("PG_USER", "postgres"),
+			Pass: getEnv("PG_USER_PW", ""),
+			Name: getEnv("PG_DB_NAME", "trudb"),
+			Pool: 5,
+		},
+	})
+}
+
+// since is the start of the week the reports start from
+func (o reportOptions) since(now time.Time) time.Time {
+	start := now.AddDate(0, 0, -7*o.weeks)
+	// weeks start on monday like date_trunc('week')
+	offset := (int(start.Weekday()) + 6) % 7
+	return time.Date(start.Year(), start.Month(), start.Day()-offset, 0, 0, 0, 0, time.UTC)
+}
+
+func cohortsReport(opts reportOptions) {
+	fmt.Fprintln(os.Stderr, "Running cohorts report")
+	now := time.Now().UTC()
+	cohorts, err := newReportDBClient().SignupCohorts(opts.since(now), now)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rows := cohortRows(cohorts)
+	header := []string{"week", "channel", "users", "retention_d1", "retention_d7", "retention_d30"}
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, []string{
+			row.Week,
+			row.Channel,
+			strconv.FormatInt(row.Users, 10),
+			formatRate(row.RetentionD1),
+			formatRate(row.RetentionD7),
+			formatRate(row.RetentionD30),
+		})
+	}
+	writeReport(opts, rows, header, records)
+}
+
+func funnelReport(opts reportOptions) {
+	fmt.Fprintln(os.Stderr, "Running funnel report")
+	now := time.Now().UTC()
+	steps, err := newReportDBClient().JourneyFunnel(funnelSteps, opts.since(now))
+	if err != nil {
+		log.Fatal(err)
+	}
+	rows := funnelRows(steps)
+	header := []string{"channel", "step", "users", "from_previous", "from_start"}
+	records := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, []string{
+			row.Channel,
+			row.Step,
+			strconv.FormatInt(row.Users, 10),
+			formatRate(row.FromPrevious),
+			formatRate(row.FromStart),
+		})
+	}
+	writeReport(opts, rows, header, records)
+}
+
+// cohortRows turns the cohorts into rows, each week followed by its total over all the channels
+func cohortRows(cohorts []db.SignupCohort) []cohortRow {
+	rows := make([]cohortRow, 0)
+	var week time.Time
+	var total db.SignupCohort
+	flush := func() {
+		if total.Channel == "" {
+			return
+		}
+		rows = append(rows, newCohortRow(total))
+		total = db.SignupCohort{}
+	}
+	for _, c := range cohorts {
+		if !c.Week.Equal(week) {
+			flush()
+			week = c.Week
+		}
+		rows = append(rows, newCohortRow(c))
+		total.Week = c.Week
+		total.Channel = channelAll
+		total.Users += c.Users
+		total.EligibleD1 += c.EligibleD1
+		total.RetainedD1 += c.RetainedD1
+		total.EligibleD7 += c.EligibleD7
+		total.RetainedD7 += c.RetainedD7
+		total.EligibleD30 += c.EligibleD30
+		total.RetainedD30 += c.RetainedD30
+	}
+	flush()
+	return rows
+}
+
+func newCohortRow(c db.SignupCohort) cohortRow {
+	return cohortRow{
+		Week:         c.Week.Format("2006-01-02"),
+		Channel:      c.Channel,
+		Users:        c.Users,
+		RetentionD1:  rate(c.RetainedD1, c.EligibleD1),
+		RetentionD7:  rate(c.RetainedD7, c.EligibleD7),
+		RetentionD30: rate(c.RetainedD30, c.EligibleD30),
+	}
+}
+
+// funnelRows turns the steps into rows by channel, followed by the funnel over all the channels
+func funnelRows(steps []db.JourneyFunnelStep) []funnelRow {
+	rows := make([]funnelRow, 0)
+	totals := make([]int64, len(funnelSteps))
+	channel := ""
+	var previous, start int64
+	for _, s := range steps {
+		if s.Channel != channel {
+			channel = s.Channel
+			previous, start = 0, s.Users
+		}
+		rows = append(rows, newFunnelRow(s.Channel, s.Step, s.Users, previous, start, s.Position))
+		previous = s.Users
+		if s.Position < len(totals) {
+			totals[s.Position] += s.Users
+		}
+	}
+	if len(steps) == 0 {
+		return rows
+	}
+	for i, step := range funnelSteps {
+		var previous int64
+		if i > 0 {
+			previous = totals[i-1]
+		}
+		rows = append(rows, newFunnelRow(channelAll, step, totals[i], previous, totals[0], i))
+	}
+	return rows
+}
+
+func newFunnelRow(channel string, step db.UserJourneyStep, users, previous, start int64, position int) funnelRow {
+	row := funnelRow{
+		Channel:   channel,
+		Step:      string(step),
+		Users:     users,
+		FromStart: rate(users, start),
+	}
+	if position > 0 {
+		row.FromPrevious = rate(users, previous)
+	}
+	return row
+}
+
+func rate(part, whole int64) *float64 {
+	if whole == 0 {
+		return nil
+	}
+	r := float64(part) / float64(whole)
+	return &r
+}
+
+func formatRate(r *float64) string {
+	if r == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*r, 'f', 4, 64)
+}
+
+// writeReport writes the rows as JSON or the records as CSV to the output file, stdout by default
+func writeReport(opts reportOptions, rows interface{}, header []string, records [][]string) {
+	var w io.Writer = os.Stdout
+	if opts.out != "" {
+		f, err := os.Create(opts.out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	var err error
+	switch opts.format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(rows)
+	case "csv":
+		csvWriter := csv.NewWriter(w)
+		err = csvWriter.Write(header)
+		if err == nil {
+			err = csvWriter.WriteAll(records)
+		}
+	default:
+		err = fmt.Errorf("invalid format %s, use csv or json", opts.format)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+func ratio(r float64) *float64 {
+	return &r
+}
+
+func TestRate(t *testing.T) {
+	tests := []struct {
+		part, whole int64
+		want        *float64
+	}{
+		{1, 2, ratio(0.5)},
+		{0, 5, ratio(0)},
+		{3, 3, ratio(1)},
+		{0, 0, nil},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, rate(test.part, test.whole), "%d/%d", test.part, test.whole)
+	}
+}
+
+func TestReportOptionsSince(t *testing.T) {
+	tests := []struct {
+		weeks int
+		now   time.Time
+		want  time.Time
+	}{
+		// a monday starts its own week
+		{0, time.Date(2019, 11, 18, 10, 0, 0, 0, time.UTC), time.Date(2019, 11, 18, 0, 0, 0, 0, time.UTC)},
+		// a sunday ends the week started on monday
+		{0, time.Date(2019, 11, 24, 23, 59, 0, 0, time.UTC), time.Date(2019, 11, 18, 0, 0, 0, 0, time.UTC)},
+		{1, time.Date(2019, 11, 18, 0, 0, 0, 0, time.UTC), time.Date(2019, 11, 11, 0, 0, 0, 0, time.UTC)},
+		{2, time.Date(2019, 11, 20, 12, 0, 0, 0, time.UTC), time.Date(2019, 11, 4, 0, 0, 0, 0, time.UTC)},
+		{1, time.Date(2019, 12, 2, 0, 0, 0, 0, time.UTC), time.Date(2019, 11, 25, 0, 0, 0, 0, time.UTC)},
+		{0, time.Date(2020, 1, 1, 8, 0, 0, 0, time.UTC), time.Date(2019, 12, 30, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		got := reportOptions{weeks: test.weeks}.since(test.now)
+		assert.Equal(t, test.want, got, "%d weeks before %s", test.weeks, test.now)
+		assert.Equal(t, time.Monday, got.Weekday())
+	}
+}
+
+func TestCohortRows(t *testing.T) {
+	week := time.Date(2019, 11, 11, 0, 0, 0, 0, time.UTC)
+	nextWeek := week.AddDate(0, 0, 7)
+	tests := []struct {
+		name    string
+		cohorts []db.SignupCohort
+		want    []cohortRow
+	}{
+		{"no cohorts", nil, []cohortRow{}},
+		{
+			"weeks are followed by their total",
+			[]db.SignupCohort{
+				{Week: week, Channel: db.ChannelOrganic, Users: 4, EligibleD1: 4, RetainedD1: 2, EligibleD7: 4, RetainedD7: 1},
+				{Week: week, Channel: db.ChannelReferral, Users: 2, EligibleD1: 2, RetainedD1: 2, EligibleD7: 1},
+				// a week with a single channel and no eligible users yet
+				{Week: nextWeek, Channel: db.ChannelReferral, Users: 3},
+			},
+			[]cohortRow{
+				{Week: "2019-11-11", Channel: db.ChannelOrganic, Users: 4, RetentionD1: ratio(0.5), RetentionD7: ratio(0.25)},
+				{Week: "2019-11-11", Channel: db.ChannelReferral, Users: 2, RetentionD1: ratio(1), RetentionD7: ratio(0)},
+				{Week: "2019-11-11", Channel: channelAll, Users: 6, RetentionD1: ratio(4.0 / 6), RetentionD7: ratio(0.2)},
+				{Week: "2019-11-18", Channel: db.ChannelReferral, Users: 3},
+				{Week: "2019-11-18", Channel: channelAll, Users: 3},
+			},
+		},
+		{
+			"totals sum the day 30 retention",
+			[]db.SignupCohort{
+				{Week: week, Channel: db.ChannelOrganic, Users: 1, EligibleD30: 1, RetainedD30: 1},
+				{Week: week, Channel: db.ChannelReferral, Users: 1, EligibleD30: 1},
+			},
+			[]cohortRow{
+				{Week: "2019-11-11", Channel: db.ChannelOrganic, Users: 1, RetentionD30: ratio(1)},
+				{Week: "2019-11-11", Channel: db.ChannelReferral, Users: 1, RetentionD30: ratio(0)},
+				{Week: "2019-11-11", Channel: channelAll, Users: 2, RetentionD30: ratio(0.5)},
+			},
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, cohortRows(test.cohorts), test.name)
+	}
+}
+
+func TestFunnelRows(t *testing.T) {
+	steps := func(channel string, users ...int64) []db.JourneyFunnelStep {
+		funnel := make([]db.JourneyFunnelStep, 0, len(users))
+		for i, u := range users {
+			funnel = append(funnel, db.JourneyFunnelStep{Channel: channel, Position: i, Step: funnelSteps[i], Users: u})
+		}
+		return funnel
+	}
+	row := func(channel string, position int, users int64, fromPrevious, fromStart *float64) funnelRow {
+		return funnelRow{Channel: channel, Step: string(funnelSteps[position]), Users: users, FromPrevious: fromPrevious, FromStart: fromStart}
+	}
+	tests := []struct {
+		name  string
+		steps []db.JourneyFunnelStep
+		want  []funnelRow
+	}{
+		{"no steps", nil, []funnelRow{}},
+		{
+			"channels are followed by the funnel over all of them",
+			append(steps(db.ChannelOrganic, 4, 2, 1, 0), steps(db.ChannelReferral, 4, 4, 2, 1)...),
+			[]funnelRow{
+				row(db.ChannelOrganic, 0, 4, nil, ratio(1)),
+				row(db.ChannelOrganic, 1, 2, ratio(0.5), ratio(0.5)),
+				row(db.ChannelOrganic, 2, 1, ratio(0.5), ratio(0.25)),
+				row(db.ChannelOrganic, 3, 0, ratio(0), ratio(0)),
+				row(db.ChannelReferral, 0, 4, nil, ratio(1)),
+				row(db.ChannelReferral, 1, 4, ratio(1), ratio(1)),
+				row(db.ChannelReferral, 2, 2, ratio(0.5), ratio(0.5)),
+				row(db.ChannelReferral, 3, 1, ratio(0.5), ratio(0.25)),
+				row(channelAll, 0, 8, nil, ratio(1)),
+				row(channelAll, 1, 6, ratio(0.75), ratio(0.75)),
+				row(channelAll, 2, 3, ratio(0.5), ratio(0.375)),
+				row(channelAll, 3, 1, ratio(1.0/3), ratio(0.125)),
+			},
+		},
+		{
+			// a channel without users has no rates
+			"empty channel",
+			append(steps(db.ChannelOrganic, 2, 1, 1, 1), steps(db.ChannelReferral, 0, 0, 0, 0)...),
+			[]funnelRow{
+				row(db.ChannelOrganic, 0, 2, nil, ratio(1)),
+				row(db.ChannelOrganic, 1, 1, ratio(0.5), ratio(0.5)),
+				row(db.ChannelOrganic, 2, 1, ratio(1), ratio(0.5)),
+				row(db.ChannelOrganic, 3, 1, ratio(1), ratio(0.5)),
+				row(db.ChannelReferral, 0, 0, nil, nil),
+				row(db.ChannelReferral, 1, 0, nil, nil),
+				row(db.ChannelReferral, 2, 0, nil, nil),
+				row(db.ChannelReferral, 3, 0, nil, nil),
+				row(channelAll, 0, 2, nil, ratio(1)),
+				row(channelAll, 1, 1, ratio(0.5), ratio(0.5)),
+				row(channelAll, 2, 1, ratio(1), ratio(0.5)),
+				row(channelAll, 3, 1, ratio(1), ratio(0.5)),
+			},
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, funnelRows(test.steps), test.name)
+	}
+}
@@ -5,6 +5,12 @@ import (
 	"os"
 )
 
+// BigQuery project and dataset the metrics are loaded into, unless set by BIGQUERY_PROJECT and BIGQUERY_DATASET
+const (
+	defaultBigQueryProject = "metrics-240714"
+	defaultBigQueryDataset = "beta_metrics"
+)
+
 func mustEnv(env string) string {
 	val := os.Getenv(env)
 	if val == "" {
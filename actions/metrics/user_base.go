@@ -33,7 +33,7 @@ func userBase() {
 		log.Fatal("Error running user base", resp.Status)
 	}
 	ctx := context.Background()
-	bigQClient, err := bigquery.NewClient(ctx, "metrics-240714")
+	bigQClient, err := bigquery.NewClient(ctx, getEnv("BIGQUERY_PROJECT", defaultBigQueryProject))
 	if err != nil {
 		log.Fatal("error creating client", err)
 	}
@@ -42,7 +42,7 @@ func userBase() {
 	source.SkipLeadingRows = 1 // CSV has a single header line.
 	source.AllowQuotedNewlines = true
 
-	datasetID := "beta_metrics"
+	datasetID := getEnv("BIGQUERY_DATASET", defaultBigQueryDataset)
 	loader := bigQClient.Dataset(datasetID).Table(metricsUsersTable).LoaderFrom(source)
 	loader.WriteDisposition = bigquery.WriteTruncate
 
@@ -1,9 +1,8 @@
 package campaigns
 
 import (
-	"bytes"
-
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/i18n"
 	"github.com/TruStory/octopus/services/truapi/postman"
 	"github.com/russross/blackfriday/v2"
 )
@@ -31,15 +30,15 @@ func (campaign *WaitlistApprovalCampaign) GetMessage(client *postman.Postman, re
 		RegisterLink: "https://beta.trustory.io/register",
 	}
 
-	var body bytes.Buffer
-	if err := client.Messages["register"].Execute(&body, vars); err != nil {
+	body, err := client.Render("register", i18n.DefaultLocale, vars)
+	if err != nil {
 		return nil, err
 	}
 
 	return &postman.Message{
 		To:      []string{recipient.Email},
-		Subject: "Getting you started with TruStory Beta",
-		Body:    string(blackfriday.Run(body.Bytes())),
+		Subject: client.T(i18n.DefaultLocale, "email.register.subject", nil),
+		Body:    string(blackfriday.Run(body)),
 	}, nil
 }
 
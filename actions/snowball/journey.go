@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+var allSteps = [...]db.UserJourneyStep{
+	db.JourneyStepSignedUp,
+	db.JourneyStepOneArgument,
+	db.JourneyStepGivenOneAgree,
+	db.JourneyStepReceiveFiveAgrees,
+}
+
+var requiredSteps = [...]db.UserJourneyStep{
+	db.JourneyStepSignedUp,
+	db.JourneyStepOneArgument,
+	db.JourneyStepReceiveFiveAgrees,
+}
+
+// evaluateJourneys records the progress of the users who haven't completed their journey
+// along with the rewards it makes due. In a dry run the rewards are only reported.
+func (w *worker) evaluateJourneys(users []int64, report *runReport) {
+	var newUsers []db.User
+	var err error
+	if len(users) == 0 {
+		// get all the users who haven't completed their journey yet
+		newUsers, err = w.db.UsersWithIncompleteJourney()
+	} else {
+		newUsers, err = w.db.UsersByID(users)
+	}
+	if err != nil {
+		report.error(fmt.Errorf("fetching users: %s", err))
+		return
+	}
+	fmt.Printf("Evaluating %d new user(s).\n", len(newUsers))
+
+	for _, user := range newUsers {
+		report.evaluated++
+		currentJourney, err := w.currentJourney(user)
+		if err != nil {
+			report.error(err)
+			continue
+		}
+		// if there are not new steps done by the user, we are done here
+		if len(user.Meta.Journey) == len(currentJourney) {
+			continue
+		}
+		report.progressed++
+
+		rewards := dueRewards(user, currentJourney, w.config)
+		if w.dryRun {
+			report.recorded = append(report.recorded, rewards...)
+			continue
+		}
+		recorded, err := w.db.ProgressUserJourney(user.ID, currentJourney, rewards)
+		if err != nil {
+			report.error(fmt.Errorf("recording journey of user %d: %s", user.ID, err))
+			continue
+		}
+		report.recorded = append(report.recorded, recorded...)
+	}
+}
+
+// dueRewards returns the rewards made due by the steps completed since the stored journey.
+// Becoming eligible grants invites to the user and their referrer, and the referrer
+// earns TRU for the steps completed by the user.
+func dueRewards(user db.User, current []db.UserJourneyStep, conf *config) []db.JourneyReward {
+	rewards := make([]db.JourneyReward, 0)
+	eligible := userHasBecomeEligible(user.Meta.Journey, current)
+	if eligible {
+		rewards = append(rewards, db.JourneyReward{
+			UserID:     user.ID,
+			Step:       db.JourneyCompleted,
+			RewardeeID: user.ID,
+			Currency:   db.RewardLedgerEntryCurrencyInvite,
+			Amount:     conf.inviteBatchSize,
+		})
+	}
+	// if they were not referred by anyone, we are done for them
+	if user.ReferredBy == 0 {
+		return rewards
+	}
+	if eligible {
+		rewards = append(rewards, db.JourneyReward{
+			UserID:     user.ID,
+			Step:       db.JourneyCompleted,
+			RewardeeID: user.ReferredBy,
+			Currency:   db.RewardLedgerEntryCurrencyInvite,
+			Amount:     conf.inviteBatchSize,
+		})
+	}
+	for _, step := range additionalStepsCompleted(current, user.Meta.Journey) {
+		reward, ok := conf.rewardForStep[step]
+		if !ok || reward == 0 {
+			continue
+		}
+		rewards = append(rewards, db.JourneyReward{
+			UserID:     user.ID,
+			Step:       step,
+			RewardeeID: user.ReferredBy,
+			Currency:   db.RewardLedgerEntryCurrencyTru,
+			Amount:     reward,
+		})
+	}
+	return rewards
+}
+
+func (w *worker) currentJourney(user db.User) (journey []db.UserJourneyStep, err error) {
+	response, err := w.makeHTTPRequest(http.MethodGet, fmt.Sprintf("%s?user_id=%d", w.config.endpointUserJourney, user.ID), nil)
+	if err != nil {
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode != 200 {
+		return journey, fmt.Errorf("Fetching user journey for (%d) %s failed", user.ID, user.Username)
+	}
+
+	var userJourney UserJourneyResponse
+	err = json.NewDecoder(response.Body).Decode(&userJourney)
+	if err != nil {
+		return
+	}
+
+	for _, step := range allSteps {
+		if userJourney.Data.Steps[step] {
+			journey = append(journey, step)
+		}
+	}
+
+	return
+}
+
+func userHasBecomeEligible(previous, current []db.UserJourneyStep) bool {
+	previouslyEligible := true
+	currentlyEligble := true
+	for _, step := range requiredSteps {
+		// if any step is not completed, the user is not eligible
+		if !containsStep(previous, step) {
+			previouslyEligible = false
+		}
+	}
+
+	for _, step := range requiredSteps {
+		// if any step is not completed, the user is not eligible
+		if !containsStep(current, step) {
+			currentlyEligble = false
+		}
+	}
+
+	// must not be already previously eligible, but become currently eligible
+	return !previouslyEligible && currentlyEligble
+}
+
+func (w *worker) makeHTTPRequest(method, endpoint string, body io.Reader) (*http.Response, error) {
+	request, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	request.SetBasicAuth(w.config.adminUsername, w.config.adminPassword)
+	return w.httpClient.Do(request)
+}
+
+func containsStep(haystack []db.UserJourneyStep, needle db.UserJourneyStep) bool {
+	for _, step := range haystack {
+		if step == needle {
+			return true
+		}
+	}
+
+	return false
+}
+
+func additionalStepsCompleted(current []db.UserJourneyStep, previous []db.UserJourneyStep) []db.UserJourneyStep {
+	var diff []db.UserJourneyStep
+
+	for _, step := range current {
+		if !containsStep(previous, step) {
+			diff = append(diff, step)
+		}
+	}
+
+	return diff
+}
@@ -1,40 +1,20 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	truCtx "github.com/TruStory/octopus/services/truapi/context"
 	"github.com/TruStory/octopus/services/truapi/db"
-	app "github.com/TruStory/octopus/services/truapi/truapi"
 )
 
-var allSteps = [...]db.UserJourneyStep{
-	db.JourneyStepSignedUp,
-	db.JourneyStepOneArgument,
-	db.JourneyStepGivenOneAgree,
-	db.JourneyStepReceiveFiveAgrees,
-}
-
-var requiredSteps = [...]db.UserJourneyStep{
-	db.JourneyStepSignedUp,
-	db.JourneyStepOneArgument,
-	db.JourneyStepReceiveFiveAgrees,
-}
-
-var rewardForStep = map[db.UserJourneyStep]string{
-	db.JourneyStepSignedUp:          mustEnv("REWARD_STEP_SIGNUP"),
-	db.JourneyStepOneArgument:       mustEnv("REWARD_STEP_ONE_ARGUMENT"),
-	db.JourneyStepReceiveFiveAgrees: mustEnv("REWARD_STEP_FIVE_AGREES"),
-}
-
 type usersflag []int64
 
 func (u *usersflag) String() string {
@@ -51,16 +31,31 @@ func (u *usersflag) Set(value string) error {
 	return nil
 }
 
+// worker evaluates the journey of users and pays the rewards it makes due
+type worker struct {
+	config     *config
+	db         *db.Client
+	httpClient *http.Client
+	// dryRun reports what a run would record and pay without changing anything
+	dryRun bool
+}
+
 func main() {
 	var users usersflag
 	flag.Var(&users, "u", "list of users for whom the service will run (optional)")
+	dryRun := flag.Bool("dry-run", false, "report the rewards without recording or paying them")
+	once := flag.Bool("once", false, "run once and exit instead of on a schedule")
 	flag.Parse()
 
+	conf, err := loadConfig()
+	if err != nil {
+		log.Fatalln(err)
+	}
 	dbPort, err := strconv.Atoi(getEnv("PG_PORT", "5432"))
 	if err != nil {
 		log.Fatalln(err)
 	}
-	config := truCtx.Config{
+	dbConfig := truCtx.Config{
 		Database: truCtx.DatabaseConfig{
 			Host: getEnv("PG_HOST", "localhost"),
 			Port: dbPort,
@@ -70,258 +65,43 @@ func main() {
 			Pool: 25,
 		},
 	}
-	dbClient := db.NewDBClient(config)
-
-	inviteBatchSize, err := strconv.Atoi(mustEnv("INVITE_BATCH_SIZE"))
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	var newUsers []db.User
-	if len(users) == 0 {
-		// get all the users who haven't completed their journey yet
-		newUsers, err = getNewUsers(dbClient)
-		if err != nil {
-			log.Fatalln(err)
-		}
-	} else {
-		newUsers, err = dbClient.UsersByID(users)
-		if err != nil {
-			log.Fatalln(err)
-		}
-	}
-	fmt.Printf("Evaluating %d new user(s).\n", len(newUsers))
-
-	for _, user := range newUsers {
-		fmt.Printf("Evaluating user with ID: %d -- ", user.ID)
-
-		// checking for the progress made
-		currentJourney, err := getCurrentJourney(user)
-		if err != nil {
-			log.Fatalln(err)
-		}
-		// if there are not new steps done by the user, we are done here
-		if len(user.Meta.Journey) == len(currentJourney) {
-			fmt.Printf("no progress made. ❗️(%s) vs (%s) \n", user.Meta.Journey, currentJourney)
-			// moving on to the next one
-			continue
-		}
-		additionalStepsCompleted := additionalStepsCompleted(currentJourney, user.Meta.Journey)
-
-		// check if they are eligible to be given their first set of invites
-		eligible := userHasBecomeEligible(user.Meta.Journey, currentJourney)
-		if !eligible {
-			// no invites to be given yet
-			fmt.Printf("was already eligible or not yet eligible for invites. ❌\n")
-		} else {
-			// award the first set of invites
-			fmt.Printf("has become eligible for invites. ✅\n")
-
-			fmt.Printf("\tGranting %d new invites... ", inviteBatchSize)
-			err = dbClient.GrantInvites(user.ID, inviteBatchSize)
-			if err != nil {
-				log.Fatalln(err)
-			}
-			fmt.Printf("✅\n")
-			sendNotification(app.RewardNotificationRequest{
-				RewardeeID:   user.ID,
-				RewardType:   app.RewardTypeInvite,
-				RewardAmount: strconv.Itoa(inviteBatchSize),
-			})
-		}
-
-		// if they were not referred by anyone, we are done for them
-		if user.ReferredBy == 0 {
-			// updating the current journey, and...
-			err = dbClient.UpdateUserJourney(user.ID, currentJourney)
-			if err != nil {
-				log.Fatalln(err)
-			}
-
-			// moving on to the next one...
-			continue
-		}
-
-		referrer, err := dbClient.UserByID(user.ReferredBy)
-		if err != nil {
-			log.Fatalln(err)
-		}
-
-		// if the user has become eligible, reward more invites to the inviting user
-		if eligible {
-			fmt.Printf("\tWas referred by %s. Granting them %d invites as well...", referrer.Username, inviteBatchSize)
-			err = dbClient.GrantInvites(referrer.ID, inviteBatchSize)
-			if err != nil {
-				log.Fatalln(err)
-			}
-			sendNotification(app.RewardNotificationRequest{
-				RewardeeID:   referrer.ID,
-				RewardType:   app.RewardTypeInvite,
-				RewardAmount: strconv.Itoa(inviteBatchSize),
-				CauserID:     user.ID,
-				CauserAction: app.RewardCauserActionJourneyComplete,
-			})
-			fmt.Printf("✅\n")
-		}
-
-		for _, step := range additionalStepsCompleted {
-			reward, exists := rewardForStep[step]
-			if !exists {
-				// if no reward is present for this step,
-				// move on to the next one...
-				continue
-			}
-			fmt.Printf("\tRewarding referrer (%s) them with %s because %s is completed...", referrer.Username, reward, step)
-			err = sendReward(*referrer, reward)
-			if err != nil {
-				log.Fatalln(err)
-			}
-			sendNotification(app.RewardNotificationRequest{
-				RewardeeID:   referrer.ID,
-				RewardType:   app.RewardTypeTru,
-				RewardAmount: reward,
-				CauserID:     user.ID,
-				CauserAction: getCauserActionFromJourneyStep(step),
-			})
-			fmt.Printf("✅\n")
-		}
-
-		err = dbClient.UpdateUserJourney(user.ID, currentJourney)
-		if err != nil {
-			log.Fatalln(err)
-		}
-	}
-}
-
-func getNewUsers(dbClient *db.Client) ([]db.User, error) {
-	users, err := dbClient.UsersWithIncompleteJourney()
-	if err != nil {
-		return users, err
-	}
-
-	return users, nil
-}
-
-func getCurrentJourney(user db.User) (journey []db.UserJourneyStep, err error) {
-	response, err := makeHTTPRequest(http.MethodGet, fmt.Sprintf("%s?user_id=%d", mustEnv("ENDPOINT_USER_JOURNEY"), user.ID), nil)
-	if err != nil {
-		return
-	}
-	if response.StatusCode != 200 {
-		return journey, fmt.Errorf("Fetching user journey for (%d) %s failed", user.ID, user.Username)
+	w := &worker{
+		config: conf,
+		db:     db.NewDBClient(dbConfig),
+		httpClient: &http.Client{
+			Timeout: time.Second * 10,
+		},
+		dryRun: *dryRun,
 	}
-	defer response.Body.Close()
 
-	var userJourney UserJourneyResponse
-	err = json.NewDecoder(response.Body).Decode(&userJourney)
-	if err != nil {
+	if *once {
+		w.run(users).print()
 		return
 	}
 
-	for _, step := range allSteps {
-		if userJourney.Data.Steps[step] {
-			journey = append(journey, step)
-		}
-	}
-
-	return
-}
-
-func userHasBecomeEligible(previous, current []db.UserJourneyStep) bool {
-	previouslyEligible := true
-	currentlyEligble := true
-	for _, step := range requiredSteps {
-		// if any step is not completed, the user is not eligible
-		if !containsStep(previous, step) {
-			previouslyEligible = false
-		}
-	}
-
-	for _, step := range requiredSteps {
-		// if any step is not completed, the user is not eligible
-		if !containsStep(current, step) {
-			currentlyEligble = false
-		}
-	}
-
-	// must not be already previously eligible, but become currently eligible
-	return !previouslyEligible && currentlyEligble
-}
-
-func sendReward(user db.User, amount string) error {
-	body := struct {
-		UserID int64  `json:"user_id"`
-		Amount string `json:"amount"`
-		Memo   string `json:"memo"`
-	}{
-		UserID: user.ID,
-		Amount: amount,
-		Memo:   "reward",
-	}
-	bodyBuffer := new(bytes.Buffer)
-	err := json.NewEncoder(bodyBuffer).Encode(body)
-	if err != nil {
-		return err
-	}
-	response, err := makeHTTPRequest(http.MethodPost, mustEnv("ENDPOINT_GIFT"), bodyBuffer)
-	if err != nil {
-		return err
-	}
-	if response.StatusCode != 200 {
-		return fmt.Errorf("Gift payment to (%d) %s failed", user.ID, user.Username)
-	}
-
-	return nil
-}
-
-func makeHTTPRequest(method, endpoint string, body io.Reader) (*http.Response, error) {
-	httpClient := &http.Client{
-		Timeout: time.Second * 10,
-	}
-	request, err := http.NewRequest(method, endpoint, body)
-	request.SetBasicAuth(mustEnv("ADMIN_USERNAME"), mustEnv("ADMIN_PASSWORD"))
-	if err != nil {
-		log.Fatalln(err)
-	}
-	response, err := httpClient.Do(request)
-	if err != nil {
-		return nil, err
-	}
-
-	return response, nil
-}
-
-func containsStep(haystack []db.UserJourneyStep, needle db.UserJourneyStep) bool {
-	for _, step := range haystack {
-		if step == needle {
-			return true
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	fmt.Printf("Running every %s\n", conf.interval)
+	ticker := time.NewTicker(conf.interval)
+	defer ticker.Stop()
+	for {
+		w.run(users).print()
+		select {
+		case <-ticker.C:
+		case <-stop:
+			fmt.Println("Stopped")
+			return
 		}
 	}
-
-	return false
 }
 
-func additionalStepsCompleted(current []db.UserJourneyStep, previous []db.UserJourneyStep) []db.UserJourneyStep {
-	var diff []db.UserJourneyStep
-
-	for _, step := range current {
-		if !containsStep(previous, step) {
-			diff = append(diff, step)
-		}
-	}
-
-	return diff
-}
-
-func getCauserActionFromJourneyStep(step db.UserJourneyStep) app.RewardCauserAction {
-	switch step {
-	case db.JourneyStepSignedUp:
-		return app.RewardCauserActionSignedUp
-	case db.JourneyStepOneArgument:
-		return app.RewardCauserActionOneArgument
-	case db.JourneyStepReceiveFiveAgrees:
-		return app.RewardCauserActionReceiveFiveAgrees
-	}
-
-	return app.RewardCauserActionUnknown
+// run reconciles the rewards interrupted by a previous run, records the rewards made due
+// by the progress of the users and pays the due rewards.
+func (w *worker) run(users []int64) *runReport {
+	report := newRunReport(w.dryRun)
+	w.reconcileStaleRewards(report)
+	w.evaluateJourneys(users, report)
+	w.payDueRewards(report)
+	report.finish()
+	return report
 }
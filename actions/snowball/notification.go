@@ -5,34 +5,35 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
 
 	app "github.com/TruStory/octopus/services/truapi/truapi"
 )
 
-func sendNotification(n app.RewardNotificationRequest) {
-	url := fmt.Sprintf("%s/%s", mustEnv("ENDPOINT_NOTIFICATION"), "sendRewardNotification")
+func (w *worker) sendNotification(n app.RewardNotificationRequest) {
+	url := fmt.Sprintf("%s/%s", w.config.endpointNotification, "sendRewardNotification")
 	b, err := json.Marshal(&n)
 	if err != nil {
 		fmt.Println("error encoding reward notification request", err)
-	}
-	httpClient := &http.Client{
-		Timeout: time.Second * 10,
+		return
 	}
 	request, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(b))
 	if err != nil {
 		fmt.Println("error creating http request", err)
+		return
 	}
+	request.SetBasicAuth(w.config.adminUsername, w.config.adminPassword)
 	request.Header.Add("Accept", "application/json")
 	request.Header.Add("Content-Type", "application/json")
-	resp, err := httpClient.Do(request)
+	resp, err := w.httpClient.Do(request)
 	if err != nil {
 		fmt.Println("error sending reward notification request", err)
+		return
 	}
 	// only read the status
 	_ = resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		fmt.Printf("error sending reward notification request status [%s] \n", resp.Status)
+		return
 	}
 	fmt.Printf("reward notification sent to user with id[%d]\n", n.RewardeeID)
 }
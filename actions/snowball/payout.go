@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+	app "github.com/TruStory/octopus/services/truapi/truapi"
+	truchain "github.com/TruStory/truchain/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// staleRewardAge is how long a reward can be processing before it's considered interrupted
+const staleRewardAge = 10 * time.Minute
+
+// errGiftUnconfirmed is returned when it's unknown whether a gift was sent.
+// Its reward stays processing until it's reconciled against the ledger.
+var errGiftUnconfirmed = errors.New("gift unconfirmed")
+
+// reconcileStaleRewards settles the rewards a previous run started paying and never finished.
+// A reward with a ledger entry referencing it was paid, the others are failed so they're retried.
+// Retrying is safe since the gift endpoint only pays a reference once.
+func (w *worker) reconcileStaleRewards(report *runReport) {
+	rewards, err := w.db.StaleJourneyRewards(time.Now().Add(-staleRewardAge))
+	if err != nil {
+		report.error(fmt.Errorf("fetching stale rewards: %s", err))
+		return
+	}
+	for i := range rewards {
+		reward := rewards[i]
+		if w.dryRun {
+			report.reconciled = append(report.reconciled, reward)
+			continue
+		}
+		reconciled, err := w.db.ReconcileJourneyReward(&reward)
+		if err != nil {
+			report.error(fmt.Errorf("reconciling reward %d: %s", reward.ID, err))
+			continue
+		}
+		if reconciled {
+			report.reconciled = append(report.reconciled, reward)
+			continue
+		}
+		err = w.db.FailJourneyReward(reward.ID, "interrupted before it was recorded in the ledger")
+		if err != nil {
+			report.error(fmt.Errorf("failing reward %d: %s", reward.ID, err))
+			continue
+		}
+		reward.State = db.JourneyRewardFailed
+		report.failed = append(report.failed, failedReward{reward, "interrupted"})
+	}
+}
+
+// payDueRewards pays the pending rewards and retries the failed ones with attempts left.
+// A reward is moved to processing before it's paid, so concurrent runs never pay it twice.
+func (w *worker) payDueRewards(report *runReport) {
+	rewards, err := w.db.DueJourneyRewards(w.config.maxAttempts)
+	if err != nil {
+		report.error(fmt.Errorf("fetching due rewards: %s", err))
+		return
+	}
+	if w.dryRun {
+		// rewards recorded in a dry run aren't stored, they would be paid too
+		report.paid = append(report.paid, rewards...)
+		report.paid = append(report.paid, report.recorded...)
+		return
+	}
+	for i := range rewards {
+		reward := rewards[i]
+		started, err := w.db.StartJourneyReward(&reward, w.config.maxAttempts)
+		if err != nil {
+			report.error(fmt.Errorf("starting reward %d: %s", reward.ID, err))
+			continue
+		}
+		if !started {
+			continue
+		}
+		err = w.pay(&reward)
+		if err == errGiftUnconfirmed {
+			report.unconfirmed = append(report.unconfirmed, reward)
+			continue
+		}
+		if err != nil {
+			failErr := w.db.FailJourneyReward(reward.ID, err.Error())
+			if failErr != nil {
+				report.error(fmt.Errorf("failing reward %d: %s", reward.ID, failErr))
+			}
+			report.failed = append(report.failed, failedReward{reward, err.Error()})
+			continue
+		}
+		report.paid = append(report.paid, reward)
+		w.notify(reward)
+	}
+}
+
+func (w *worker) pay(reward *db.JourneyReward) error {
+	switch reward.Currency {
+	case db.RewardLedgerEntryCurrencyInvite:
+		return w.db.PayJourneyInviteReward(reward)
+	case db.RewardLedgerEntryCurrencyTru:
+		err := w.sendGift(reward, sdk.NewInt64Coin(truchain.StakeDenom, reward.Amount))
+		if err != nil {
+			return err
+		}
+		// the gift endpoint records the ledger entry before responding
+		reconciled, err := w.db.ReconcileJourneyReward(reward)
+		if err != nil {
+			return err
+		}
+		if !reconciled {
+			return fmt.Errorf("gift sent but not found in the ledger")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown currency %s", reward.Currency)
+}
+
+// sendGift pays a reward through the gift endpoint, referencing it so it's never paid twice.
+// Errors that leave the payment unknown return errGiftUnconfirmed.
+func (w *worker) sendGift(reward *db.JourneyReward, amount sdk.Coin) error {
+	body := app.GiftRequest{
+		UserID:    reward.RewardeeID,
+		Amount:    amount.String(),
+		Memo:      "reward",
+		Reference: reward.LedgerReference(),
+	}
+	bodyBuffer := new(bytes.Buffer)
+	err := json.NewEncoder(bodyBuffer).Encode(body)
+	if err != nil {
+		return err
+	}
+	response, err := w.makeHTTPRequest(http.MethodPost, w.config.endpointGift, bodyBuffer)
+	if err != nil {
+		fmt.Printf("gift payment of reward %d is unconfirmed: %s\n", reward.ID, err)
+		return errGiftUnconfirmed
+	}
+	_ = response.Body.Close()
+	if response.StatusCode >= http.StatusInternalServerError {
+		fmt.Printf("gift payment of reward %d is unconfirmed: %s\n", reward.ID, response.Status)
+		return errGiftUnconfirmed
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("gift payment to user %d failed with status %s", reward.RewardeeID, response.Status)
+	}
+
+	return nil
+}
+
+func (w *worker) notify(reward db.JourneyReward) {
+	n := app.RewardNotificationRequest{
+		RewardeeID:   reward.RewardeeID,
+		RewardAmount: strconv.FormatInt(reward.Amount, 10),
+	}
+	if reward.RewardeeID != reward.UserID {
+		n.CauserID = reward.UserID
+		n.CauserAction = getCauserActionFromJourneyStep(reward.Step)
+	}
+	switch reward.Currency {
+	case db.RewardLedgerEntryCurrencyInvite:
+		n.RewardType = app.RewardTypeInvite
+	case db.RewardLedgerEntryCurrencyTru:
+		n.RewardType = app.RewardTypeTru
+		n.RewardAmount = sdk.NewInt64Coin(truchain.StakeDenom, reward.Amount).String()
+	}
+	w.sendNotification(n)
+}
+
+func getCauserActionFromJourneyStep(step db.UserJourneyStep) app.RewardCauserAction {
+	switch step {
+	case db.JourneyStepSignedUp:
+		return app.RewardCauserActionSignedUp
+	case db.JourneyStepOneArgument:
+		return app.RewardCauserActionOneArgument
+	case db.JourneyStepReceiveFiveAgrees:
+		return app.RewardCauserActionReceiveFiveAgrees
+	case db.JourneyCompleted:
+		return app.RewardCauserActionJourneyComplete
+	}
+
+	return app.RewardCauserActionUnknown
+}
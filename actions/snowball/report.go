@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+type failedReward struct {
+	reward db.JourneyReward
+	reason string
+}
+
+// runReport is what a run recorded, paid and failed
+type runReport struct {
+	dryRun      bool
+	started     time.Time
+	finished    time.Time
+	evaluated   int
+	progressed  int
+	recorded    []db.JourneyReward
+	reconciled  []db.JourneyReward
+	paid        []db.JourneyReward
+	unconfirmed []db.JourneyReward
+	failed      []failedReward
+	errors      []error
+}
+
+func newRunReport(dryRun bool) *runReport {
+	return &runReport{dryRun: dryRun, started: time.Now()}
+}
+
+func (r *runReport) error(err error) {
+	fmt.Println("error:", err)
+	r.errors = append(r.errors, err)
+}
+
+func (r *runReport) finish() {
+	r.finished = time.Now()
+}
+
+// totals sums the amounts of rewards by currency
+func totals(rewards []db.JourneyReward) map[db.RewardLedgerEntryCurrency]int64 {
+	sums := make(map[db.RewardLedgerEntryCurrency]int64)
+	for _, reward := range rewards {
+		sums[reward.Currency] += reward.Amount
+	}
+	return sums
+}
+
+func describeReward(reward db.JourneyReward) string {
+	return fmt.Sprintf("#%d %d %s to user %d for user %d %s",
+		reward.ID, reward.Amount, reward.Currency, reward.RewardeeID, reward.UserID, reward.Step)
+}
+
+func (r *runReport) print() {
+	title := "Run"
+	if r.dryRun {
+		title = "Dry run"
+	}
+	fmt.Printf("%s from %s to %s\n", title, r.started.Format(time.RFC3339), r.finished.Format(time.RFC3339))
+	fmt.Printf("\tevaluated %d user(s), %d made progress\n", r.evaluated, r.progressed)
+	fmt.Printf("\trecorded %d reward(s)\n", len(r.recorded))
+	for _, reward := range r.reconciled {
+		fmt.Printf("\treconciled %s\n", describeReward(reward))
+	}
+	for _, reward := range r.paid {
+		fmt.Printf("\tpaid %s\n", describeReward(reward))
+	}
+	for _, reward := range r.unconfirmed {
+		fmt.Printf("\tunconfirmed %s, left processing until it's reconciled\n", describeReward(reward))
+	}
+	for _, f := range r.failed {
+		fmt.Printf("\tfailed %s: %s\n", describeReward(f.reward), f.reason)
+	}
+	for currency, amount := range totals(r.paid) {
+		fmt.Printf("\ttotal paid %d %s\n", amount, currency)
+	}
+	if len(r.errors) > 0 {
+		fmt.Printf("\t%d error(s)\n", len(r.errors))
+	}
+}
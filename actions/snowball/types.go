@@ -3,8 +3,13 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/TruStory/octopus/services/truapi/db"
 	"github.com/TruStory/octopus/services/truapi/truapi"
+	app "github.com/TruStory/truchain/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
 )
 
 type UserJourneyResponse struct {
@@ -12,12 +17,89 @@ type UserJourneyResponse struct {
 	Data   truapi.UserJourneyResponse `json:"data"`
 }
 
-func mustEnv(env string) string {
+// config holds the settings of the rewards worker, read from the environment on start
+type config struct {
+	adminUsername        string
+	adminPassword        string
+	endpointUserJourney  string
+	endpointGift         string
+	endpointNotification string
+	inviteBatchSize      int64
+	// rewardForStep is the utru rewarded to the referrer for each step
+	rewardForStep map[db.UserJourneyStep]int64
+	// interval is the time between runs
+	interval    time.Duration
+	maxAttempts int
+}
+
+var rewardStepEnv = map[db.UserJourneyStep]string{
+	db.JourneyStepSignedUp:          "REWARD_STEP_SIGNUP",
+	db.JourneyStepOneArgument:       "REWARD_STEP_ONE_ARGUMENT",
+	db.JourneyStepReceiveFiveAgrees: "REWARD_STEP_FIVE_AGREES",
+}
+
+func loadConfig() (*config, error) {
+	c := &config{
+		rewardForStep: make(map[db.UserJourneyStep]int64),
+	}
+	var err error
+	for _, v := range []struct {
+		dst *string
+		env string
+	}{
+		{&c.adminUsername, "ADMIN_USERNAME"},
+		{&c.adminPassword, "ADMIN_PASSWORD"},
+		{&c.endpointUserJourney, "ENDPOINT_USER_JOURNEY"},
+		{&c.endpointGift, "ENDPOINT_GIFT"},
+		{&c.endpointNotification, "ENDPOINT_NOTIFICATION"},
+	} {
+		*v.dst, err = requireEnv(v.env)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	batchSize, err := requireEnv("INVITE_BATCH_SIZE")
+	if err != nil {
+		return nil, err
+	}
+	c.inviteBatchSize, err = strconv.ParseInt(batchSize, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid INVITE_BATCH_SIZE: %s", err)
+	}
+
+	for step, env := range rewardStepEnv {
+		value, err := requireEnv(env)
+		if err != nil {
+			return nil, err
+		}
+		coin, err := sdk.ParseCoin(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", env, err)
+		}
+		if coin.Denom != app.StakeDenom {
+			return nil, fmt.Errorf("invalid %s denomination got %s wanted %s", env, coin.Denom, app.StakeDenom)
+		}
+		c.rewardForStep[step] = coin.Amount.Int64()
+	}
+
+	c.interval, err = time.ParseDuration(getEnv("SNOWBALL_INTERVAL", "24h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNOWBALL_INTERVAL: %s", err)
+	}
+	c.maxAttempts, err = strconv.Atoi(getEnv("SNOWBALL_MAX_ATTEMPTS", "3"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SNOWBALL_MAX_ATTEMPTS: %s", err)
+	}
+	return c, nil
+}
+
+func requireEnv(env string) (string, error) {
 	val := os.Getenv(env)
 	if val == "" {
-		panic(fmt.Sprintf("must provide %s variable", env))
+		return "", fmt.Errorf("must provide %s variable", env)
 	}
-	return val
+	return val, nil
 }
 
 func getEnv(env, defaultValue string) string {
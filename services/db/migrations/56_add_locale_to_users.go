@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		fmt.Println("adding locale column to the users table...")
+		_, err := db.Exec(`ALTER TABLE users ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT 'en'`)
+		return err
+	}, func(db migrations.DB) error {
+		fmt.Println("dropping locale column from the users table...")
+		_, err := db.Exec(`ALTER TABLE users DROP COLUMN locale`)
+		return err
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		fmt.Println("adding broadcast_campaigns table...")
+		_, err := db.Exec(`CREATE TABLE broadcast_campaigns (
+			id BIGSERIAL PRIMARY KEY,
+			title TEXT NOT NULL,
+			message TEXT NOT NULL,
+			claim_id BIGINT,
+			segment JSONB NOT NULL DEFAULT '{}',
+			scheduled_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			throttle_per_minute INTEGER NOT NULL DEFAULT 0,
+			status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+			recipients_count INTEGER NOT NULL DEFAULT 0,
+			started_at TIMESTAMP,
+			finished_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW(),
+			deleted_at TIMESTAMP
+		)`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE INDEX broadcast_campaigns_status_scheduled_at_idx ON broadcast_campaigns (status, scheduled_at)`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE INDEX notification_events_meta_campaign_id_idx ON notification_events ((meta->>'campaignId'))`)
+		return err
+	}, func(db migrations.DB) error {
+		fmt.Println("dropping broadcast_campaigns table...")
+		_, err := db.Exec(`DROP INDEX IF EXISTS notification_events_meta_campaign_id_idx`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`DROP TABLE broadcast_campaigns`)
+		return err
+	})
+}
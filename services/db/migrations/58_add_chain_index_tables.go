@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		fmt.Println("adding chain index tables...")
+		statements := []string{
+			`CREATE TABLE chain_index_cursors (
+				id BIGSERIAL PRIMARY KEY,
+				name VARCHAR(65) NOT NULL,
+				height BIGINT NOT NULL,
+				block_time TIMESTAMP NOT NULL,
+				created_at TIMESTAMP DEFAULT NOW(),
+				updated_at TIMESTAMP DEFAULT NOW(),
+				deleted_at TIMESTAMP,
+				CONSTRAINT chain_index_cursors_no_duplicate UNIQUE(name)
+			)`,
+			`CREATE TABLE chain_claims (
+				id BIGINT PRIMARY KEY,
+				community_id VARCHAR(75) NOT NULL,
+				creator VARCHAR(65) NOT NULL,
+				body TEXT NOT NULL,
+				height BIGINT NOT NULL,
+				created_time TIMESTAMP NOT NULL,
+				created_at TIMESTAMP DEFAULT NOW(),
+				updated_at TIMESTAMP DEFAULT NOW(),
+				deleted_at TIMESTAMP
+			)`,
+			`CREATE INDEX chain_claims_created_time_idx ON chain_claims (created_time)`,
+			`CREATE TABLE chain_arguments (
+				id BIGINT PRIMARY KEY,
+				claim_id BIGINT NOT NULL,
+				community_id VARCHAR(75) NOT NULL,
+				creator VARCHAR(65) NOT NULL,
+				stake_type SMALLINT NOT NULL,
+				height BIGINT NOT NULL,
+				created_time TIMESTAMP NOT NULL,
+				created_at TIMESTAMP DEFAULT NOW(),
+				updated_at TIMESTAMP DEFAULT NOW(),
+				deleted_at TIMESTAMP
+			)`,
+			`CREATE INDEX chain_arguments_claim_id_idx ON chain_arguments (claim_id)`,
+			`CREATE TABLE chain_stakes (
+				id BIGINT PRIMARY KEY,
+				argument_id BIGINT NOT NULL,
+				community_id VARCHAR(75) NOT NULL,
+				creator VARCHAR(65) NOT NULL,
+				type SMALLINT NOT NULL,
+				amount BIGINT NOT NULL,
+				expired BOOLEAN NOT NULL DEFAULT FALSE,
+				height BIGINT NOT NULL,
+				created_time TIMESTAMP NOT NULL,
+				end_time TIMESTAMP NOT NULL,
+				created_at TIMESTAMP DEFAULT NOW(),
+				updated_at TIMESTAMP DEFAULT NOW(),
+				deleted_at TIMESTAMP
+			)`,
+			`CREATE INDEX chain_stakes_argument_id_idx ON chain_stakes (argument_id)`,
+			`CREATE INDEX chain_stakes_creator_idx ON chain_stakes (creator)`,
+			`CREATE TABLE chain_slashes (
+				id BIGINT PRIMARY KEY,
+				argument_id BIGINT NOT NULL,
+				creator VARCHAR(65) NOT NULL,
+				type SMALLINT NOT NULL,
+				reason SMALLINT NOT NULL,
+				height BIGINT NOT NULL,
+				created_time TIMESTAMP NOT NULL,
+				created_at TIMESTAMP DEFAULT NOW(),
+				updated_at TIMESTAMP DEFAULT NOW(),
+				deleted_at TIMESTAMP
+			)`,
+			`CREATE TABLE chain_transactions (
+				id BIGINT PRIMARY KEY,
+				type SMALLINT NOT NULL,
+				address VARCHAR(65) NOT NULL,
+				reference_id BIGINT NOT NULL,
+				community_id VARCHAR(75) NOT NULL DEFAULT '',
+				amount BIGINT NOT NULL,
+				height BIGINT NOT NULL,
+				created_time TIMESTAMP NOT NULL,
+				created_at TIMESTAMP DEFAULT NOW(),
+				updated_at TIMESTAMP DEFAULT NOW(),
+				deleted_at TIMESTAMP
+			)`,
+			`CREATE INDEX chain_transactions_address_created_time_idx ON chain_transactions (address, created_time)`,
+		}
+		for _, statement := range statements {
+			_, err := db.Exec(statement)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(db migrations.DB) error {
+		fmt.Println("dropping chain index tables...")
+		_, err := db.Exec(`DROP TABLE chain_index_cursors, chain_claims, chain_arguments, chain_stakes, chain_slashes, chain_transactions`)
+		return err
+	})
+}
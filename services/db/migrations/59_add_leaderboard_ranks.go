@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		fmt.Println("creating leaderboard_ranks table...")
+		_, err := db.Exec(`CREATE TABLE leaderboard_ranks (
+			id BIGSERIAL PRIMARY KEY,
+			date DATE NOT NULL,
+			period VARCHAR(10) NOT NULL,
+			metric VARCHAR(20) NOT NULL,
+			community_id VARCHAR(75) NOT NULL DEFAULT '',
+			address VARCHAR(65) NOT NULL,
+			value BIGINT NOT NULL,
+			rank INTEGER NOT NULL,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW(),
+			deleted_at TIMESTAMP,
+			CONSTRAINT leaderboard_ranks_no_duplicate UNIQUE(date, period, metric, community_id, address)
+		)`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE INDEX leaderboard_ranks_rank_idx ON leaderboard_ranks (period, metric, community_id, date, rank)`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE INDEX leaderboard_ranks_address_idx ON leaderboard_ranks (address, period, metric, community_id, date)`)
+		return err
+	}, func(db migrations.DB) error {
+		fmt.Println("dropping leaderboard_ranks table...")
+		_, err := db.Exec(`DROP TABLE leaderboard_ranks`)
+		return err
+	})
+}
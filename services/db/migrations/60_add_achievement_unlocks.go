@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		fmt.Println("creating achievement_unlocks table...")
+		_, err := db.Exec(`CREATE TABLE achievement_unlocks (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			address VARCHAR(65) NOT NULL,
+			achievement_id VARCHAR(65) NOT NULL,
+			community_id VARCHAR(75) NOT NULL DEFAULT '',
+			value BIGINT NOT NULL,
+			invites INTEGER NOT NULL DEFAULT 0,
+			reward BIGINT NOT NULL DEFAULT 0,
+			unlocked_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			rewarded_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW(),
+			deleted_at TIMESTAMP,
+			CONSTRAINT achievement_unlocks_no_duplicate UNIQUE(address, achievement_id, community_id)
+		)`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE INDEX achievement_unlocks_pending_rewards_idx ON achievement_unlocks (id) WHERE rewarded_at IS NULL AND (invites > 0 OR reward > 0)`)
+		return err
+	}, func(db migrations.DB) error {
+		fmt.Println("dropping achievement_unlocks table...")
+		_, err := db.Exec(`DROP TABLE achievement_unlocks`)
+		return err
+	})
+}
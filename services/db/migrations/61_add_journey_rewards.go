@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		fmt.Println("creating journey_rewards table...")
+		_, err := db.Exec(`CREATE TABLE journey_rewards (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			step VARCHAR(50) NOT NULL,
+			rewardee_id BIGINT NOT NULL,
+			currency VARCHAR(20) NOT NULL,
+			amount BIGINT NOT NULL,
+			state VARCHAR(20) NOT NULL DEFAULT 'pending',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			ledger_entry_id BIGINT,
+			started_at TIMESTAMP,
+			paid_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW(),
+			deleted_at TIMESTAMP,
+			CONSTRAINT journey_rewards_no_duplicate UNIQUE(user_id, step, rewardee_id, currency)
+		)`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE INDEX journey_rewards_state_idx ON journey_rewards (state)`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE UNIQUE INDEX journey_rewards_ledger_entry_id_idx ON journey_rewards (ledger_entry_id)`)
+		return err
+	}, func(db migrations.DB) error {
+		fmt.Println("dropping journey_rewards table...")
+		_, err := db.Exec(`DROP TABLE journey_rewards`)
+		return err
+	})
+}
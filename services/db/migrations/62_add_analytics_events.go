@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		fmt.Println("creating analytics_events table...")
+		// partitioned by month of created_at, the partitions are created by truapi before writing
+		// events into them and the default partition only catches the events of a missing one
+		_, err := db.Exec(`CREATE TABLE analytics_events (
+			id BIGSERIAL,
+			event VARCHAR(100) NOT NULL,
+			address VARCHAR(65) NOT NULL DEFAULT '',
+			session_id VARCHAR(65) NOT NULL DEFAULT '',
+			properties JSONB NOT NULL DEFAULT '{}',
+			sample_rate DOUBLE PRECISION NOT NULL DEFAULT 1,
+			client_time TIMESTAMP,
+			created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (id, created_at)
+		) PARTITION BY RANGE (created_at)`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE TABLE analytics_events_default PARTITION OF analytics_events DEFAULT`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE INDEX analytics_events_event_created_at_idx ON analytics_events (event, created_at)`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE TABLE analytics_export_cursors (
+			name VARCHAR(100) PRIMARY KEY,
+			last_event_id BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT NOW(),
+			updated_at TIMESTAMP DEFAULT NOW(),
+			deleted_at TIMESTAMP
+		)`)
+		return err
+	}, func(db migrations.DB) error {
+		fmt.Println("dropping analytics_events table...")
+		_, err := db.Exec(`DROP TABLE analytics_export_cursors`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`DROP TABLE analytics_events`)
+		return err
+	})
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		fmt.Println("adding reference to reward_ledger_entries...")
+		// the reference of what an entry pays, like a journey reward, so it's only paid once
+		_, err := db.Exec(`ALTER TABLE reward_ledger_entries ADD COLUMN reference VARCHAR(100)`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`CREATE UNIQUE INDEX reward_ledger_entries_reference_idx ON reward_ledger_entries (reference)`)
+		return err
+	}, func(db migrations.DB) error {
+		fmt.Println("dropping reference from reward_ledger_entries...")
+		_, err := db.Exec(`ALTER TABLE reward_ledger_entries DROP COLUMN reference`)
+		return err
+	})
+}
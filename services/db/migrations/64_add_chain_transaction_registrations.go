@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/go-pg/migrations"
+)
+
+func init() {
+	migrations.MustRegisterTx(func(db migrations.DB) error {
+		fmt.Println("adding registration to chain_transactions...")
+		_, err := db.Exec(`ALTER TABLE chain_transactions ADD COLUMN registration BOOLEAN NOT NULL DEFAULT FALSE`)
+		if err != nil {
+			return err
+		}
+		// registration gifts can only be told apart from the block they're in,
+		// so the transactions are indexed again from the start height
+		_, err = db.Exec(`DELETE FROM chain_transactions`)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`DELETE FROM chain_index_cursors WHERE name = 'chain'`)
+		return err
+	}, func(db migrations.DB) error {
+		fmt.Println("dropping registration from chain_transactions...")
+		_, err := db.Exec(`ALTER TABLE chain_transactions DROP COLUMN registration`)
+		return err
+	})
+}
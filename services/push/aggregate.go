@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/i18n"
+)
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+// aggregatedMessage prefixes a message with the number of other actors, ie:
+// "and 12 others agreed with your argument".
+func (s *service) aggregatedMessage(locale, msg string, others int) string {
+	if others <= 0 {
+		return msg
+	}
+	return s.catalog.Render(locale, i18n.Message{
+		Key:    "notification.aggregated",
+		Params: i18n.Params{"others": others, "message": msg},
+	})
+}
+
+// truncateBody shortens a message to BodyMaxLength characters, ending it with an ellipsis.
+func truncateBody(msg string) string {
+	runes := []rune(msg)
+	if len(runes) <= BodyMaxLength {
+		return msg
+	}
+	return string(runes[:BodyMaxLength-3]) + "..."
+}
+
+// mergeActors returns the actors of an aggregated notification with the new sender first
+// and each actor listed once.
+func mergeActors(from string, existing []string) []string {
+	actors := []string{from}
+	seen := map[string]bool{from: true}
+	for _, actor := range existing {
+		if !seen[actor] {
+			seen[actor] = true
+			actors = append(actors, actor)
+		}
+	}
+	return actors
+}
+
+// collapseKey identifies pushes belonging to the same aggregated event so devices replace the older alert.
+func collapseKey(evt *db.NotificationEvent) string {
+	return fmt.Sprintf("notification-%d", evt.ID)
+}
+
+// saveAggregatedNotificationEvent merges the notification into a recent unread event
+// of the same type and target or inserts a new one if none exists.
+func (s *service) saveAggregatedNotificationEvent(n *Notification, evt *db.NotificationEvent, locale string) error {
+	existing, err := s.db.AggregatableNotificationEvent(evt.Address, evt.Type, evt.TypeID, evt.Meta, time.Now().Add(-s.aggregationWindow))
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		evt.Meta.Count = int64Ptr(1)
+		evt.Meta.Actors = []string{*n.From}
+		if n.Trim {
+			evt.Message = truncateBody(evt.Message)
+		}
+		_, err = s.db.Model(evt).Returning("*").Insert()
+		return err
+	}
+
+	// the count and the "N others" of the message both come from the distinct actors
+	actors := mergeActors(*n.From, existing.Meta.Actors)
+	evt.Meta.Count = int64Ptr(int64(len(actors)))
+	evt.Meta.Actors = actors
+	evt.ID = existing.ID
+	evt.Message = s.aggregatedMessage(locale, evt.Message, len(actors)-1)
+	if n.Trim {
+		evt.Message = truncateBody(evt.Message)
+	}
+	_, err = s.db.Model(evt).
+		Column("message", "timestamp", "sender_profile_id", "meta", "read", "seen", "updated_at").
+		WherePK().
+		Update()
+	return err
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TruStory/octopus/services/truapi/i18n"
+)
+
+func TestMergeActors(t *testing.T) {
+	assert.Equal(t, []string{"a"}, mergeActors("a", nil))
+	assert.Equal(t, []string{"c", "b", "a"}, mergeActors("c", []string{"b", "a"}))
+	// a repeated sender moves to the front and is counted once
+	assert.Equal(t, []string{"a", "b"}, mergeActors("a", []string{"b", "a"}))
+	assert.Equal(t, []string{"a", "b"}, mergeActors("a", []string{"a", "b", "b"}))
+}
+
+func TestTruncateBody(t *testing.T) {
+	assert.Equal(t, "short", truncateBody("short"))
+
+	long := strings.Repeat("é", BodyMaxLength+10)
+	truncated := truncateBody(long)
+	assert.True(t, utf8.ValidString(truncated))
+	assert.Equal(t, BodyMaxLength, utf8.RuneCountInString(truncated))
+	assert.True(t, strings.HasSuffix(truncated, "..."))
+}
+
+func TestAggregatedMessage(t *testing.T) {
+	catalog, err := i18n.NewDefaultCatalog()
+	assert.NoError(t, err)
+	s := &service{catalog: catalog}
+
+	msg := "agreed with your argument"
+	assert.Equal(t, msg, s.aggregatedMessage("en", msg, 0))
+	assert.Equal(t, "and 1 other agreed with your argument", s.aggregatedMessage("en", msg, 1))
+	assert.Equal(t, "and 12 others agreed with your argument", s.aggregatedMessage("en", msg, 12))
+
+	// the prefix is added before truncating so the aggregated message stays within the limit
+	long := strings.Repeat("a", BodyMaxLength)
+	aggregated := truncateBody(s.aggregatedMessage("en", long, 3))
+	assert.Equal(t, BodyMaxLength, utf8.RuneCountInString(aggregated))
+	assert.True(t, strings.HasPrefix(aggregated, "and 3 others "))
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
 
+	"github.com/TruStory/octopus/services/truapi/logging"
+	"github.com/TruStory/octopus/services/truapi/telemetry"
 	app "github.com/TruStory/octopus/services/truapi/truapi"
 )
 
@@ -13,14 +16,27 @@ func (s *service) startHTTPServer(
 	commentNotifications chan<- *CommentNotificationRequest,
 	rewardNotifications chan<- *app.RewardNotificationRequest,
 	broadcastNotifications chan<- *app.BroadcastNotificationRequest,
+	achievementNotifications chan<- *app.AchievementNotificationRequest,
 ) {
 	mux := http.NewServeMux()
 	s.addHTTPCommentNotificationHandler(mux, commentNotifications)
 	s.addHTTPRewardNotificationHandler(mux, rewardNotifications)
 	s.addHTTPBroadcastNotificationHandler(mux, broadcastNotifications)
+	s.addHTTPAchievementNotificationHandler(mux, achievementNotifications)
+	// metrics are scraped with the metrics secret instead of a signature, every other route requires one
+	root := http.NewServeMux()
+	root.Handle("/metrics", metricsAuth(s.metricsSecret, telemetry.Handler()))
+	root.Handle("/", s.verifier.Middleware(mux))
+	route := func(r *http.Request) string {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			return pattern
+		}
+		_, pattern := root.Handler(r)
+		return pattern
+	}
 	server := &http.Server{
 		Addr:    ":9001",
-		Handler: mux,
+		Handler: telemetry.Instrument(logging.Middleware(s.log)(root), "pushd", route),
 	}
 	go func() {
 		<-stop
@@ -33,6 +49,18 @@ func (s *service) startHTTPServer(
 	}
 }
 
+// metricsAuth serves the requests with the Metrics-Secret header matching the secret,
+// all of them are rejected when the secret isn't set.
+func metricsAuth(secret string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if secret == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Metrics-Secret")), []byte(secret)) != 1 {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
 func (s *service) addHTTPCommentNotificationHandler(mux *http.ServeMux, notifications chan<- *CommentNotificationRequest) {
 	mux.HandleFunc("/sendCommentNotification", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -42,11 +70,11 @@ func (s *service) addHTTPCommentNotificationHandler(mux *http.ServeMux, notifica
 		n := &CommentNotificationRequest{}
 		err := json.NewDecoder(r.Body).Decode(n)
 		if err != nil {
-			s.log.WithError(err).Error("error decoding request")
+			logging.FromContext(r.Context()).WithError(err).Error("error decoding request")
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		s.log.WithField("commentId", n.ID).Info("comment notification request received")
+		logging.FromContext(r.Context()).WithField("commentId", n.ID).Info("comment notification request received")
 		notifications <- n
 		w.WriteHeader(http.StatusAccepted)
 	})
@@ -61,11 +89,11 @@ func (s *service) addHTTPRewardNotificationHandler(mux *http.ServeMux, notificat
 		n := &app.RewardNotificationRequest{}
 		err := json.NewDecoder(r.Body).Decode(n)
 		if err != nil {
-			s.log.WithError(err).Error("error decoding request")
+			logging.FromContext(r.Context()).WithError(err).Error("error decoding request")
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		s.log.WithField("rewardee_id", n.RewardeeID).Info("reward notification request received")
+		logging.FromContext(r.Context()).WithField("rewardee_id", n.RewardeeID).Info("reward notification request received")
 		notifications <- n
 		w.WriteHeader(http.StatusAccepted)
 	})
@@ -80,11 +108,30 @@ func (s *service) addHTTPBroadcastNotificationHandler(mux *http.ServeMux, notifi
 		n := &app.BroadcastNotificationRequest{}
 		err := json.NewDecoder(r.Body).Decode(n)
 		if err != nil {
-			s.log.WithError(err).Error("error decoding request")
+			logging.FromContext(r.Context()).WithError(err).Error("error decoding request")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logging.FromContext(r.Context()).WithField("type", n.Type).Info("broadcast notification request received")
+		notifications <- n
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+func (s *service) addHTTPAchievementNotificationHandler(mux *http.ServeMux, notifications chan<- *app.AchievementNotificationRequest) {
+	mux.HandleFunc("/sendAchievementNotification", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			fmt.Printf("only POST method allowed received [%s]\n", r.Method)
+			return
+		}
+		n := &app.AchievementNotificationRequest{}
+		err := json.NewDecoder(r.Body).Decode(n)
+		if err != nil {
+			logging.FromContext(r.Context()).WithError(err).Error("error decoding request")
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		s.log.WithField("type", n.Type).Info("broadcast notification request received")
+		logging.FromContext(r.Context()).WithField("achievement_id", n.AchievementID).Info("achievement notification request received")
 		notifications <- n
 		w.WriteHeader(http.StatusAccepted)
 	})
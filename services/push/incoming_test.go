@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsAuth(t *testing.T) {
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	tests := []struct {
+		secret string
+		header string
+		want   int
+	}{
+		{"secret", "secret", http.StatusOK},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "wrong", http.StatusUnauthorized},
+		// metrics aren't served without a secret
+		{"", "", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if test.header != "" {
+			r.Header.Set("Metrics-Secret", test.header)
+		}
+		w := httptest.NewRecorder()
+		metricsAuth(test.secret, metrics).ServeHTTP(w, r)
+		assert.Equal(t, test.want, w.Code, "secret %q header %q", test.secret, test.header)
+	}
+}
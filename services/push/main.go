@@ -8,12 +8,12 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/appleboy/go-fcm"
 	"github.com/appleboy/gorush/gorush"
-	"github.com/machinebox/graphql"
 	"github.com/sirupsen/logrus"
 	"github.com/tendermint/tendermint/rpc/client"
 	"github.com/tendermint/tendermint/types"
@@ -21,6 +21,10 @@ import (
 
 	truCtx "github.com/TruStory/octopus/services/truapi/context"
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/i18n"
+	"github.com/TruStory/octopus/services/truapi/lookup"
+	"github.com/TruStory/octopus/services/truapi/sigauth"
+	"github.com/TruStory/octopus/services/truapi/telemetry"
 	app "github.com/TruStory/octopus/services/truapi/truapi"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 )
@@ -86,7 +90,9 @@ func (s *service) sendNotification(notification PushNotification, tokens []strin
 			Body:      notification.Body,
 			ChannelID: "all",
 		},
-		Data: notification.NotificationData.ToGorushData(),
+		Data:        notification.NotificationData.ToGorushData(),
+		CollapseKey: notification.CollapseKey,
+		CollapseID:  notification.CollapseKey,
 	}
 	n := &gorush.RequestPush{
 		Notifications: []gorush.PushNotification{pushNotification},
@@ -115,8 +121,7 @@ func (s *service) notificationSender(notifications <-chan *Notification, stop <-
 	for {
 		select {
 		case notification := <-notifications:
-			msg := notification.Msg
-			title := notification.Type.String()
+			notificationsTotal.WithLabelValues(notification.Type.String()).Inc()
 			receiver, err := s.db.UserByAddress(notification.To)
 			if err != nil {
 				s.log.WithError(err).Errorf("could not retrieve user for address %s", notification.To)
@@ -126,9 +131,9 @@ func (s *service) notificationSender(notifications <-chan *Notification, stop <-
 				s.log.Warnf("profile doesn't exist for  %s", notification.To)
 				continue
 			}
-			if notification.Trim && len(msg) > BodyMaxLength {
-				msg = fmt.Sprintf("%s...", msg[:BodyMaxLength-3])
-			}
+			coin := i18n.Params{"coin": db.CoinDisplayName}
+			msg := s.catalog.Render(receiver.Locale, notification.Msg)
+			title := s.catalog.T(receiver.Locale, notification.Type.Key(), coin)
 			notificationEvent := &db.NotificationEvent{
 				Address:       notification.To,
 				UserProfileID: receiver.ID,
@@ -152,7 +157,15 @@ func (s *service) notificationSender(notifications <-chan *Notification, stop <-
 				senderImage = strPtr(sender.AvatarURL)
 				senderAddress = strPtr(sender.Address)
 			}
-			_, err = s.db.Model(notificationEvent).Returning("*").Insert()
+			aggregate := notification.Aggregate && notification.From != nil && s.aggregationWindow > 0
+			if aggregate {
+				err = s.saveAggregatedNotificationEvent(notification, notificationEvent, receiver.Locale)
+			} else {
+				if notification.Trim {
+					notificationEvent.Message = truncateBody(notificationEvent.Message)
+				}
+				_, err = s.db.Model(notificationEvent).Returning("*").Insert()
+			}
 			if err != nil {
 				s.log.WithError(err).Error("error saving event in database")
 			}
@@ -174,7 +187,7 @@ func (s *service) notificationSender(notifications <-chan *Notification, stop <-
 
 			pushNotification := PushNotification{
 				Title: title,
-				Body:  stripmd.Strip(msg),
+				Body:  stripmd.Strip(notificationEvent.Message),
 				NotificationData: NotificationData{
 					Title:     title,
 					ID:        notificationEvent.ID,
@@ -188,13 +201,20 @@ func (s *service) notificationSender(notifications <-chan *Notification, stop <-
 				},
 			}
 
-			if notification.Action != "" {
-				pushNotification.Subtitle = notification.Action
-				pushNotification.NotificationData.Subtitle = notification.Action
+			if aggregate {
+				pushNotification.CollapseKey = collapseKey(notificationEvent)
+			}
+			if notification.Action.Key != "" {
+				action := s.catalog.Render(receiver.Locale, notification.Action)
+				pushNotification.Subtitle = action
+				pushNotification.NotificationData.Subtitle = action
 			}
 			for p, t := range tokens {
 				pushNotification.Platform = p
+				start := time.Now()
 				r, err := s.sendNotification(pushNotification, t)
+				telemetry.Since(deliveryDuration.WithLabelValues(p), start)
+				deliveriesTotal.WithLabelValues(p, telemetry.Status(err)).Add(float64(len(t)))
 				if err != nil {
 					s.log.WithError(err).Error("error sending notifications")
 					continue
@@ -259,6 +279,7 @@ func (s *service) logChainStatus(c *client.HTTP) {
 func (s *service) run(stop <-chan struct{}) {
 	remote := getEnv("REMOTE_ENDPOINT", "tcp://0.0.0.0:26657")
 	client := client.NewHTTP(remote, "/websocket")
+	s.lookup = lookup.NewClient(lookup.NewRPCQuerier(client), s.db, s.lookupCacheTTL)
 
 	// example complex query:
 	// query := fmt.Sprintf("tm.event='Tx' AND tx.height=1 AND tx.hash='%X' AND testType.baz=1", tx.Hash())
@@ -297,11 +318,14 @@ func (s *service) run(stop <-chan struct{}) {
 	cNotificationsCh := make(chan *CommentNotificationRequest)
 	rNotificationsCh := make(chan *app.RewardNotificationRequest)
 	bNotificationsCh := make(chan *app.BroadcastNotificationRequest)
-	go s.startHTTPServer(stop, cNotificationsCh, rNotificationsCh, bNotificationsCh)
+	aNotificationsCh := make(chan *app.AchievementNotificationRequest)
+	go s.startHTTPServer(stop, cNotificationsCh, rNotificationsCh, bNotificationsCh, aNotificationsCh)
 	go s.processCommentsNotifications(cNotificationsCh, notificationsCh)
 	go s.processRewardsNotifications(rNotificationsCh, notificationsCh)
 	go s.processBroadcastNotifications(bNotificationsCh, notificationsCh)
+	go s.processAchievementNotifications(aNotificationsCh, notificationsCh)
 	go s.notificationSender(notificationsCh, stop)
+	go s.campaignScheduler(notificationsCh, stop)
 	for {
 		select {
 		case event := <-txsCh:
@@ -338,7 +362,23 @@ func main() {
 	})
 	gorushHTTPAddress := getEnv("GORUSH_ADDRESS", "http://localhost:9000/api/push")
 	topic := getEnv("NOTIFICATION_TOPIC", "app.trustory.io")
-	graphqlEndpoint := mustEnv("PUSHD_GRAPHQL_ENDPOINT")
+	secret := mustEnv("PUSHD_SECRET")
+	aggregationWindow, err := time.ParseDuration(getEnv("PUSHD_AGGREGATION_WINDOW", "30m"))
+	if err != nil {
+		log.WithError(err).Fatal("invalid PUSHD_AGGREGATION_WINDOW")
+	}
+	lookupCacheTTL, err := time.ParseDuration(getEnv("PUSHD_LOOKUP_CACHE_TTL", "1m"))
+	if err != nil {
+		log.WithError(err).Fatal("invalid PUSHD_LOOKUP_CACHE_TTL")
+	}
+	campaignPollInterval, err := time.ParseDuration(getEnv("PUSHD_CAMPAIGN_POLL_INTERVAL", "1m"))
+	if err != nil || campaignPollInterval <= 0 {
+		log.WithError(err).Fatal("invalid PUSHD_CAMPAIGN_POLL_INTERVAL")
+	}
+	campaignStaleTimeout, err := time.ParseDuration(getEnv("PUSHD_CAMPAIGN_STALE_TIMEOUT", "15m"))
+	if err != nil || campaignStaleTimeout <= 0 {
+		log.WithError(err).Fatal("invalid PUSHD_CAMPAIGN_STALE_TIMEOUT")
+	}
 
 	config := truCtx.Config{
 		Database: truCtx.DatabaseConfig{
@@ -350,20 +390,53 @@ func main() {
 			Pool: 25,
 		},
 	}
+	catalog, err := i18n.NewDefaultCatalog()
+	if err != nil {
+		log.WithError(err).Fatal("could not load message catalog")
+	}
 	dbClient := db.NewDBClient(config)
-	graphqlClient := graphql.NewClient(graphqlEndpoint)
+	dbClient.RegisterPoolMetrics()
 	log.Info("pushd connected to db and starting")
 
+	sampleRatio, err := strconv.ParseFloat(getEnv("PUSHD_TRACE_SAMPLE_RATIO", "1"), 64)
+	if err != nil {
+		log.WithError(err).Fatal("invalid PUSHD_TRACE_SAMPLE_RATIO")
+	}
+	stopTracing, err := telemetry.StartTracing(telemetry.TracingConfig{
+		Service:     "pushd",
+		ZipkinURL:   getEnv("PUSHD_ZIPKIN_URL", ""),
+		SampleRatio: sampleRatio,
+	})
+	if err != nil {
+		log.WithError(err).Fatal("could not start tracing")
+	}
+	defer stopTracing()
+
 	quit := setupSignals()
 	srvc := &service{
 		apnsTopic: topic,
 		db:        dbClient,
 		log:       log,
+		catalog:   catalog,
 		httpClient: &http.Client{
 			Timeout: time.Second * 5,
 		},
-		gorushHTTPAddress: gorushHTTPAddress,
-		graphqlClient:     graphqlClient,
+		gorushHTTPAddress:    gorushHTTPAddress,
+		lookupCacheTTL:       lookupCacheTTL,
+		aggregationWindow:    aggregationWindow,
+		campaignPollInterval: campaignPollInterval,
+		campaignStaleTimeout: campaignStaleTimeout,
+		verifier:             sigauth.NewVerifier(secret, sigauth.DefaultMaxSkew),
+		spotlightURL:         getEnv("PUSHD_SPOTLIGHT_URL", ""),
+		spotlightSecret:      getEnv("PUSHD_SPOTLIGHT_SECRET", ""),
+		metricsSecret:        getEnv("PUSHD_METRICS_SECRET", ""),
+	}
+
+	if srvc.spotlightSecret == "" {
+		log.Warn("PUSHD_SPOTLIGHT_SECRET is not set, previews of new claims and arguments won't be prewarmed")
+	}
+	if srvc.metricsSecret == "" {
+		log.Warn("PUSHD_METRICS_SECRET is not set, /metrics won't be served")
 	}
 
 	srvc.run(quit)
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/TruStory/octopus/services/truapi/telemetry"
+)
+
+var (
+	// notificationsTotal counts the notifications processed by type
+	notificationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pushd_notifications_total",
+		Help: "Notifications processed.",
+	}, []string{"type"})
+
+	// deliveriesTotal counts the push notifications handed to gorush by platform and result
+	deliveriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pushd_deliveries_total",
+		Help: "Push notifications handed to gorush, one per device token.",
+	}, []string{"platform", "result"})
+
+	// deliveryDuration is the time gorush took to accept push notifications by platform
+	deliveryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pushd_delivery_duration_seconds",
+		Help:    "Time gorush took to accept push notifications.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"platform"})
+)
+
+func init() {
+	telemetry.Register(notificationsTotal, deliveriesTotal, deliveryDuration)
+}
@@ -1,54 +1,32 @@
 package main
 
-import (
-	"context"
-
-	"github.com/machinebox/graphql"
-)
-
 type claimParticipants struct {
 	ClaimID      int64
 	Creator      string
 	Participants []string
 }
 
-func (s *service) getArgumentSummary(argumentId int64) (*ArgumentSummaryResponse, error) {
-	req := graphql.NewRequest(argumentSummaryByIDQuery)
-	req.Var("argumentId", argumentId)
-	res := &ArgumentSummaryResponse{}
-	ctx := context.Background()
-	if err := s.graphqlClient.Run(ctx, req, &res); err != nil {
-		return nil, err
-	}
-	return res, nil
-}
-
-func (s *service) getClaimArgument(argumentId int64) (*ClaimArgumentResponse, error) {
-	req := graphql.NewRequest(ClaimArgumentByIDQuery)
-	req.Var("argumentId", argumentId)
-	res := &ClaimArgumentResponse{}
-	ctx := context.Background()
-	if err := s.graphqlClient.Run(ctx, req, &res); err != nil {
-		return nil, err
+// getClaimParticipants returns the claim creator and everyone else who took part in the claim.
+func (s *service) getClaimParticipants(claimID uint64) (claimParticipants, error) {
+	claim, err := s.lookup.Claim(claimID)
+	if err != nil {
+		return claimParticipants{}, err
 	}
-	return res, nil
-}
-
-func (s *service) getClaimParticipantsByArgumentId(argumentId int64) (claimParticipants, error) {
-	res, err := s.getClaimArgument(argumentId)
+	addresses, err := s.lookup.ClaimParticipants(claimID)
 	if err != nil {
 		return claimParticipants{}, err
 	}
-	participants := make([]string, 0, len(res.ClaimArgument.Claim.Participants))
-	for _, p := range res.ClaimArgument.Claim.Participants {
-		if p.Address == res.ClaimArgument.Claim.Creator.Address {
+	creator := claim.Creator.String()
+	participants := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if address == creator {
 			continue
 		}
-		participants = append(participants, p.Address)
+		participants = append(participants, address)
 	}
 	return claimParticipants{
-		Creator:      res.ClaimArgument.Claim.Creator.Address,
-		ClaimID:      res.ClaimArgument.ClaimID,
+		Creator:      creator,
+		ClaimID:      int64(claim.ID),
 		Participants: participants,
 	}, nil
 }
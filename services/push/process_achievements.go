@@ -0,0 +1,44 @@
+package main
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/i18n"
+	app "github.com/TruStory/octopus/services/truapi/truapi"
+	truchain "github.com/TruStory/truchain/types"
+)
+
+func (s *service) processAchievementNotifications(aNotifications <-chan *app.AchievementNotificationRequest, notifications chan<- *Notification) {
+	for n := range aNotifications {
+		s.log.Infoln("processing an achievement notification", n)
+		achievementID := n.AchievementID
+		notifications <- &Notification{
+			To:   n.Address,
+			Type: db.NotificationAchievementUnlocked,
+			Msg:  getAchievementMessage(*n),
+			Meta: db.NotificationMeta{
+				AchievementID: &achievementID,
+			},
+			Action: i18n.Message{Key: "notification.achievement.action"},
+		}
+	}
+}
+
+func getAchievementMessage(n app.AchievementNotificationRequest) i18n.Message {
+	params := i18n.Params{"name": n.Name}
+	key := "notification.achievement.body"
+	if n.Invites > 0 {
+		params["count"] = n.Invites
+		key = "notification.achievement.invites.body"
+	}
+	if n.Reward > 0 {
+		params["amount"] = humanReadable(sdk.NewInt64Coin(truchain.StakeDenom, n.Reward))
+		params["coin"] = db.CoinDisplayName
+		key = "notification.achievement.tru.body"
+		if n.Invites > 0 {
+			key = "notification.achievement.invites_tru.body"
+		}
+	}
+	return i18n.Message{Key: key, Params: params}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/i18n"
 	"github.com/TruStory/truchain/x/account"
 	"github.com/TruStory/truchain/x/staking"
 	sdk "github.com/cosmos/cosmos-sdk/types"
@@ -52,47 +53,65 @@ func (s *service) processExpiredStakes(data []byte, notifications chan<- *Notifi
 			s.log.Errorf("stake result is nil for stake id %d", expiredStake.ID)
 			return
 		}
-		argument, err := s.getClaimArgument(int64(expiredStake.ArgumentID))
+		argument, err := s.lookup.Argument(expiredStake.ArgumentID)
 		if err != nil {
 			s.log.WithError(err).Error("error getting argument ")
 			return
 		}
+		claim, err := s.lookup.Claim(argument.ClaimID)
+		if err != nil {
+			s.log.WithError(err).Error("error getting claim ")
+			return
+		}
 		meta := db.NotificationMeta{
-			ClaimID:    &argument.ClaimArgument.ClaimID,
+			ClaimID:    uint64Ptr(claim.ID),
 			ArgumentID: uint64Ptr(expiredStake.ArgumentID),
 		}
 		if expiredStake.Result.Type == staking.RewardResultArgumentCreation {
 			notifications <- &Notification{
 				To: expiredStake.Creator.String(),
-				Msg: fmt.Sprintf("You just earned %s %s from your Argument on Claim: %s",
-					humanReadable(expiredStake.Result.ArgumentCreatorReward), db.CoinDisplayName,
-					argument.ClaimArgument.Claim.Body),
+				Msg: i18n.Message{
+					Key: "notification.earned_stake.argument_creation.body",
+					Params: i18n.Params{
+						"amount": humanReadable(expiredStake.Result.ArgumentCreatorReward),
+						"coin":   db.CoinDisplayName,
+						"claim":  claim.Body,
+					},
+				},
 				TypeID: int64(expiredStake.ArgumentID),
 				Type:   db.NotificationEarnedStake,
 				Meta:   meta,
-				Action: fmt.Sprintf("Earned %s", db.CoinDisplayName),
+				Action: i18n.Message{Key: "notification.earned_stake.action", Params: i18n.Params{"coin": db.CoinDisplayName}},
 			}
 			return
 		}
 		notifications <- &Notification{
 			To: expiredStake.Result.ArgumentCreator.String(),
-			Msg: fmt.Sprintf("You just earned %s %s because someone Agreed with you",
-				humanReadable(expiredStake.Result.ArgumentCreatorReward), db.CoinDisplayName,
-			),
+			Msg: i18n.Message{
+				Key: "notification.earned_stake.argument_creator.body",
+				Params: i18n.Params{
+					"amount": humanReadable(expiredStake.Result.ArgumentCreatorReward),
+					"coin":   db.CoinDisplayName,
+				},
+			},
 			TypeID: int64(expiredStake.ArgumentID),
 			Type:   db.NotificationEarnedStake,
 			Meta:   meta,
-			Action: fmt.Sprintf("Earned %s", db.CoinDisplayName),
+			Action: i18n.Message{Key: "notification.earned_stake.action", Params: i18n.Params{"coin": db.CoinDisplayName}},
 		}
 		notifications <- &Notification{
 			To: expiredStake.Result.StakeCreator.String(),
-			Msg: fmt.Sprintf("You just earned %s %s on an Argument you Agreed with",
-				humanReadable(expiredStake.Result.StakeCreatorReward), db.CoinDisplayName,
-			),
+			Msg: i18n.Message{
+				Key: "notification.earned_stake.stake_creator.body",
+				Params: i18n.Params{
+					"amount": humanReadable(expiredStake.Result.StakeCreatorReward),
+					"coin":   db.CoinDisplayName,
+				},
+			},
 			TypeID: int64(expiredStake.ArgumentID),
 			Type:   db.NotificationEarnedStake,
 			Meta:   meta,
-			Action: fmt.Sprintf("Earned %s", db.CoinDisplayName),
+			Action: i18n.Message{Key: "notification.earned_stake.action", Params: i18n.Params{"coin": db.CoinDisplayName}},
 		}
 	}
 }
@@ -106,19 +125,24 @@ func (s *service) processStakeLimitUpgrade(data []byte, notifications chan<- *No
 	}
 	notifications <- &Notification{
 		To: upgrade.Address.String(),
-		Msg: fmt.Sprintf("Congrats! You've earned a total of %s %s. Your weekly staking limits are increased to %d %s.",
-			humanReadable(upgrade.EarnedStake), db.CoinDisplayName, upgrade.NewLimit, db.CoinDisplayName,
-		),
+		Msg: i18n.Message{
+			Key: "notification.stake_limit_increased.body",
+			Params: i18n.Params{
+				"amount": humanReadable(upgrade.EarnedStake),
+				"coin":   db.CoinDisplayName,
+				"limit":  upgrade.NewLimit,
+			},
+		},
 		Type:   db.NotificationStakeLimitIncreased,
-		Action: "Staking Limit Increased",
+		Action: i18n.Message{Key: "notification.stake_limit_increased.action"},
 	}
 }
 func (s *service) processUnjailedAccount(data []byte, notifications chan<- *Notification) {
 	notifications <- &Notification{
 		To:     string(data),
-		Msg:    "Hooray you're out of timeout!",
+		Msg:    i18n.Message{Key: "notification.unjailed.body"},
 		Type:   db.NotificationUnjailed,
-		Action: "Freedom",
+		Action: i18n.Message{Key: "notification.unjailed.action"},
 	}
 }
 
@@ -1,13 +1,10 @@
 package main
 
 import (
-	"context"
-	"fmt"
 	"strings"
 
-	"github.com/machinebox/graphql"
-
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/i18n"
 	app "github.com/TruStory/octopus/services/truapi/truapi"
 )
 
@@ -20,7 +17,7 @@ func (s *service) processBroadcastNotifications(bNotifications <-chan *app.Broad
 			s.log.WithError(err).Errorf("could not retrieve featured claim for community [%s]\n", FEATURED_DEBATE_COMMUNITY_ID)
 			continue
 		}
-		featuredClaim, err := s.getClaim(featuredClaimID)
+		featuredClaim, err := s.lookup.Claim(uint64(featuredClaimID))
 		if err != nil {
 			s.log.WithError(err).Errorf("could not claim for id [%d]\n", featuredClaimID)
 			continue
@@ -32,35 +29,25 @@ func (s *service) processBroadcastNotifications(bNotifications <-chan *app.Broad
 			continue
 		}
 
-		if !strings.HasSuffix(featuredClaim.Claim.Body, ".") {
-			featuredClaim.Claim.Body = featuredClaim.Claim.Body + "."
+		if !strings.HasSuffix(featuredClaim.Body, ".") {
+			featuredClaim.Body = featuredClaim.Body + "."
 		}
 
 		for _, user := range users {
 			notifications <- &Notification{
 				To:     user.Address,
-				TypeID: featuredClaim.Claim.ID,
+				TypeID: int64(featuredClaim.ID),
 				Type:   db.NotificationFeaturedDebate,
-				Msg:    fmt.Sprintf("New Featured Debate: %s Join the debate and share your thoughts!", featuredClaim.Claim.Body),
+				Msg: i18n.Message{
+					Key:    "notification.featured_debate.body",
+					Params: i18n.Params{"claim": featuredClaim.Body},
+				},
 				Meta: db.NotificationMeta{
-					ClaimID: &featuredClaim.Claim.ID,
+					ClaimID: uint64Ptr(featuredClaim.ID),
 				},
-				Action: "Featured Debate",
+				Action: i18n.Message{Key: "notification.featured_debate.action"},
 				Trim:   true,
 			}
 		}
 	}
 }
-
-func (s *service) getClaim(claimID int64) (ClaimResponse, error) {
-	graphqlReq := graphql.NewRequest(claimByIDQuery)
-
-	graphqlReq.Var("claimId", claimID)
-	var graphqlRes ClaimResponse
-	ctx := context.Background()
-	if err := s.graphqlClient.Run(ctx, graphqlReq, &graphqlRes); err != nil {
-		return graphqlRes, err
-	}
-
-	return graphqlRes, nil
-}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/i18n"
+)
+
+// campaignScheduler picks up due broadcast campaigns, and the ones left sending by a stopped
+// pushd, on startup and then periodically, and fans them out.
+func (s *service) campaignScheduler(notifications chan<- *Notification, stop <-chan struct{}) {
+	ticker := time.NewTicker(s.campaignPollInterval)
+	defer ticker.Stop()
+	claim := func() {
+		now := time.Now()
+		campaigns, err := s.db.ClaimDueBroadcastCampaigns(now, now.Add(-s.campaignStaleTimeout))
+		if err != nil {
+			s.log.WithError(err).Error("could not retrieve due broadcast campaigns")
+			return
+		}
+		for _, campaign := range campaigns {
+			go s.sendCampaign(campaign, notifications, stop)
+		}
+	}
+	claim()
+	for {
+		select {
+		case <-ticker.C:
+			claim()
+		case <-stop:
+			s.log.Info("stopping campaign scheduler")
+			return
+		}
+	}
+}
+
+func (s *service) sendCampaign(campaign db.BroadcastCampaign, notifications chan<- *Notification, stop <-chan struct{}) {
+	logger := s.log.WithField("campaign", campaign.ID)
+	users, err := s.db.UsersBySegment(campaign.Segment)
+	if err != nil {
+		logger.WithError(err).Error("could not resolve campaign audience")
+		s.finishCampaign(campaign.ID, db.BroadcastCampaignFailed)
+		return
+	}
+	err = s.db.SetBroadcastCampaignRecipients(campaign.ID, len(users))
+	if err != nil {
+		logger.WithError(err).Error("could not record campaign recipients")
+	}
+	// a resumed campaign skips the users it was already delivered to
+	notified, err := s.db.BroadcastCampaignNotifiedAddresses(campaign.ID)
+	if err != nil {
+		logger.WithError(err).Error("could not retrieve campaign deliveries")
+		s.releaseCampaign(campaign.ID)
+		return
+	}
+	addresses := pendingCampaignAddresses(users, notified)
+	logger.Infof("sending campaign to %d of %d users", len(addresses), len(users))
+
+	interval := campaignInterval(campaign.ThrottlePerMinute)
+	var typeID int64
+	if campaign.ClaimID != nil {
+		typeID = *campaign.ClaimID
+	}
+	lastProgress := time.Now()
+	for i, address := range addresses {
+		if i > 0 && interval > 0 {
+			select {
+			case <-time.After(interval):
+			case <-stop:
+				logger.Warnf("campaign interrupted after %d of %d users, it will be resumed", i, len(addresses))
+				s.releaseCampaign(campaign.ID)
+				return
+			}
+		}
+		if time.Since(lastProgress) > s.campaignStaleTimeout/3 {
+			lastProgress = time.Now()
+			err = s.db.TouchBroadcastCampaign(campaign.ID)
+			if err != nil {
+				logger.WithError(err).Error("could not record campaign progress")
+			}
+		}
+		notifications <- &Notification{
+			To:     address,
+			TypeID: typeID,
+			Type:   db.NotificationBroadcast,
+			Msg: i18n.Message{
+				Key:    "notification.broadcast.body",
+				Params: i18n.Params{"message": campaign.Message},
+			},
+			Meta: db.NotificationMeta{
+				ClaimID:    campaign.ClaimID,
+				CampaignID: int64Ptr(campaign.ID),
+			},
+			Action: i18n.Message{
+				Key:    "notification.broadcast.action",
+				Params: i18n.Params{"title": campaign.Title},
+			},
+			Trim: true,
+		}
+	}
+	s.finishCampaign(campaign.ID, db.BroadcastCampaignSent)
+}
+
+// campaignInterval is the delay between two notifications of a campaign, zero when unthrottled.
+func campaignInterval(throttlePerMinute int) time.Duration {
+	if throttlePerMinute <= 0 {
+		return 0
+	}
+	return time.Minute / time.Duration(throttlePerMinute)
+}
+
+// pendingCampaignAddresses returns the addresses of the users a campaign wasn't delivered to yet.
+func pendingCampaignAddresses(users []db.User, notified []string) []string {
+	skip := make(map[string]bool, len(notified))
+	for _, address := range notified {
+		skip[address] = true
+	}
+	addresses := make([]string, 0, len(users))
+	for _, user := range users {
+		if !skip[user.Address] {
+			addresses = append(addresses, user.Address)
+		}
+	}
+	return addresses
+}
+
+func (s *service) releaseCampaign(id int64) {
+	err := s.db.ReleaseBroadcastCampaign(id)
+	if err != nil {
+		s.log.WithError(err).Errorf("could not release campaign [%d]", id)
+	}
+}
+
+func (s *service) finishCampaign(id int64, status db.BroadcastCampaignStatus) {
+	err := s.db.FinishBroadcastCampaign(id, status)
+	if err != nil {
+		s.log.WithError(err).Errorf("could not mark campaign [%d] as %s", id, status)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+func TestCampaignInterval(t *testing.T) {
+	assert.Equal(t, time.Duration(0), campaignInterval(0))
+	assert.Equal(t, time.Duration(0), campaignInterval(-1))
+	assert.Equal(t, time.Minute, campaignInterval(1))
+	assert.Equal(t, 120*time.Millisecond, campaignInterval(500))
+}
+
+func TestPendingCampaignAddresses(t *testing.T) {
+	users := []db.User{{Address: "a"}, {Address: "b"}, {Address: "c"}}
+	assert.Equal(t, []string{"a", "b", "c"}, pendingCampaignAddresses(users, nil))
+	// a resumed campaign skips the users already notified, in the audience order
+	assert.Equal(t, []string{"a", "c"}, pendingCampaignAddresses(users, []string{"b", "unknown"}))
+	assert.Empty(t, pendingCampaignAddresses(users, []string{"c", "b", "a"}))
+}
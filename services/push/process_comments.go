@@ -1,11 +1,10 @@
 package main
 
 import (
-	"fmt"
-
 	"strings"
 
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/i18n"
 	"github.com/gernest/mention"
 	stripmd "github.com/writeas/go-strip-markdown"
 )
@@ -48,6 +47,8 @@ func (s *service) processCommentsNotifications(cNotifications <-chan *CommentNot
 			s.log.WithError(err).Errorf("could not retrieve comment for id [%d]\n", n.ID)
 			continue
 		}
+		// the commenter is now a participant of the claim
+		s.lookup.InvalidateClaim(uint64(c.ClaimID))
 		var participants []string
 		var notificationType db.NotificationType
 		var mentionType db.MentionType
@@ -70,6 +71,10 @@ func (s *service) processCommentsNotifications(cNotifications <-chan *CommentNot
 		notified[n.Creator] = true
 		parsedComment, mentions := s.parseCosmosMentions(c.Body)
 		parsedComment = stripmd.Strip(parsedComment)
+		reply := i18n.Message{
+			Key:    "notification.reply.body",
+			Params: i18n.Params{"comment": parsedComment},
+		}
 		meta := db.NotificationMeta{
 			ClaimID:    &c.ClaimID,
 			ArgumentID: &c.ArgumentID,
@@ -94,9 +99,12 @@ func (s *service) processCommentsNotifications(cNotifications <-chan *CommentNot
 				To:     p,
 				TypeID: typeId,
 				Type:   db.NotificationMentionAction,
-				Msg:    fmt.Sprintf("mentioned you %s: %s", mentionType.String(), parsedComment),
+				Msg: i18n.Message{
+					Key:    "notification.mention.reply.body",
+					Params: i18n.Params{"comment": parsedComment},
+				},
 				Meta:   mentionMeta,
-				Action: "Mentioned you in a reply",
+				Action: i18n.Message{Key: "notification.mention.reply.action"},
 				Trim:   true,
 			}
 		}
@@ -107,14 +115,15 @@ func (s *service) processCommentsNotifications(cNotifications <-chan *CommentNot
 			}
 			notified[p] = true
 			notifications <- &Notification{
-				From:   &c.Creator,
-				To:     p,
-				TypeID: typeId,
-				Type:   notificationType,
-				Msg:    fmt.Sprintf("added a Reply: %s", parsedComment),
-				Meta:   meta,
-				Action: "Added a new reply",
-				Trim:   true,
+				From:      &c.Creator,
+				To:        p,
+				TypeID:    typeId,
+				Type:      notificationType,
+				Msg:       reply,
+				Meta:      meta,
+				Action:    i18n.Message{Key: "notification.reply.action"},
+				Trim:      true,
+				Aggregate: true,
 			}
 		}
 
@@ -123,14 +132,15 @@ func (s *service) processCommentsNotifications(cNotifications <-chan *CommentNot
 			if _, ok := notified[n.ClaimCreator]; !ok {
 				notified[n.ClaimCreator] = true
 				notifications <- &Notification{
-					From:   &c.Creator,
-					To:     n.ClaimCreator,
-					TypeID: typeId,
-					Type:   notificationType,
-					Msg:    fmt.Sprintf("added a Reply: %s", parsedComment),
-					Meta:   meta,
-					Action: "Added a new reply",
-					Trim:   true,
+					From:      &c.Creator,
+					To:        n.ClaimCreator,
+					TypeID:    typeId,
+					Type:      notificationType,
+					Msg:       reply,
+					Meta:      meta,
+					Action:    i18n.Message{Key: "notification.reply.action"},
+					Trim:      true,
+					Aggregate: true,
 				}
 			}
 		} else {
@@ -138,14 +148,15 @@ func (s *service) processCommentsNotifications(cNotifications <-chan *CommentNot
 			if _, ok := notified[n.ArgumentCreator]; !ok {
 				notified[n.ArgumentCreator] = true
 				notifications <- &Notification{
-					From:   &c.Creator,
-					To:     n.ArgumentCreator,
-					TypeID: typeId,
-					Type:   notificationType,
-					Msg:    fmt.Sprintf("added a Reply: %s", parsedComment),
-					Meta:   meta,
-					Action: "Added a new reply",
-					Trim:   true,
+					From:      &c.Creator,
+					To:        n.ArgumentCreator,
+					TypeID:    typeId,
+					Type:      notificationType,
+					Msg:       reply,
+					Meta:      meta,
+					Action:    i18n.Message{Key: "notification.reply.action"},
+					Trim:      true,
+					Aggregate: true,
 				}
 			}
 		}
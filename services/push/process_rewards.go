@@ -1,11 +1,10 @@
 package main
 
 import (
-	"fmt"
-
 	sdk "github.com/cosmos/cosmos-sdk/types"
 
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/i18n"
 	app "github.com/TruStory/octopus/services/truapi/truapi"
 )
 
@@ -36,11 +35,11 @@ func (s *service) processRewardsNotifications(rNotifications <-chan *app.RewardN
 			To:     user.Address,
 			TypeID: 0,
 			Type:   nType,
-			Msg:    fmt.Sprintf("You were rewarded with %s because %s", getRewardStringFromRequest(*n), getRewardReasonFromRequest(*n, causer)),
+			Msg:    getRewardMessageFromRequest(*n, causer),
 			Meta: db.NotificationMeta{
 				RewardCauserID: &n.CauserID,
 			},
-			Action: "Reward unlocked",
+			Action: i18n.Message{Key: "notification.reward.action"},
 			Trim:   true,
 		}
 	}
@@ -57,44 +56,44 @@ func getNotificationTypeFromRequest(n app.RewardNotificationRequest) (db.Notific
 	return 0, false
 }
 
-func getRewardStringFromRequest(n app.RewardNotificationRequest) string {
+func getRewardMessageFromRequest(n app.RewardNotificationRequest, causer *db.User) i18n.Message {
 	switch n.RewardType {
 	case app.RewardTypeInvite:
-		return fmt.Sprintf("%s invites", n.RewardAmount)
-	case app.RewardTypeTru:
-		amount, err := sdk.ParseCoin(n.RewardAmount)
-		if err != nil {
-			return n.RewardAmount
+		if causer == nil {
+			return i18n.Message{
+				Key:    "notification.reward.invite.self.body",
+				Params: i18n.Params{"count": n.RewardAmount},
+			}
 		}
-		return fmt.Sprintf("%s %s", humanReadable(amount), db.CoinDisplayName)
-	}
-
-	return ""
-}
-
-func getRewardReasonFromRequest(n app.RewardNotificationRequest, causer *db.User) string {
-	switch n.RewardType {
-	case app.RewardTypeInvite:
-		reason := "%s became an active user on TruStory."
-		causedBy := "you"
-		if causer != nil {
-			causedBy = causer.Username
+		return i18n.Message{
+			Key:    "notification.reward.invite.body",
+			Params: i18n.Params{"count": n.RewardAmount, "causer": causer.Username},
 		}
-		return fmt.Sprintf(reason, causedBy)
 
 	case app.RewardTypeTru:
-		reason := "%s %s on TruStory."
-		stepCompleted := ""
+		amount := n.RewardAmount
+		coin, err := sdk.ParseCoin(n.RewardAmount)
+		if err == nil {
+			amount = humanReadable(coin)
+		}
+		key := "notification.reward.tru.body"
 		switch n.CauserAction {
 		case app.RewardCauserActionSignedUp:
-			stepCompleted = "signed up"
+			key = "notification.reward.tru.signed_up.body"
 		case app.RewardCauserActionOneArgument:
-			stepCompleted = "has written at least one argument"
+			key = "notification.reward.tru.one_argument.body"
 		case app.RewardCauserActionReceiveFiveAgrees:
-			stepCompleted = "has received at least five agrees"
+			key = "notification.reward.tru.receive_five_agrees.body"
+		}
+		return i18n.Message{
+			Key: key,
+			Params: i18n.Params{
+				"amount": amount,
+				"coin":   db.CoinDisplayName,
+				"causer": causer.Username,
+			},
 		}
-		return fmt.Sprintf(reason, causer.Username, stepCompleted)
 	}
 
-	return ""
+	return i18n.Message{}
 }
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/i18n"
+	app "github.com/TruStory/octopus/services/truapi/truapi"
 	"github.com/TruStory/truchain/x/bank"
+	"github.com/TruStory/truchain/x/claim"
 	"github.com/TruStory/truchain/x/slashing"
 	"github.com/TruStory/truchain/x/staking"
 	"github.com/cosmos/cosmos-sdk/codec"
@@ -15,6 +19,24 @@ import (
 	"github.com/tendermint/tendermint/types"
 )
 
+func (s *service) processClaimCreated(data []byte) {
+	c := claim.Claim{}
+	err := claim.ModuleCodec.UnmarshalJSON(data, &c)
+	if err != nil {
+		s.log.WithError(err).Error("error decoding claim created event")
+		return
+	}
+	go s.prewarmSpotlight("claim", c.ID)
+}
+
+// prewarmSpotlight renders the previews of a new claim or argument before anyone shares them.
+func (s *service) prewarmSpotlight(entityType string, id uint64) {
+	err := app.PrewarmSpotlight(context.Background(), s.spotlightURL, s.spotlightSecret, entityType, id)
+	if err != nil {
+		s.log.WithError(err).Errorf("error prewarming spotlight for %s %d", entityType, id)
+	}
+}
+
 func (s *service) processArgumentCreated(data []byte, notifications chan<- *Notification) {
 	fmt.Println("data " + string(data))
 	argument := staking.Argument{}
@@ -23,7 +45,10 @@ func (s *service) processArgumentCreated(data []byte, notifications chan<- *Noti
 		s.log.WithError(err).Error("error decoding argument created event")
 		return
 	}
-	claimParticipants, err := s.getClaimParticipantsByArgumentId(int64(argument.ID))
+	// the new argument changes the participants of the claim
+	s.lookup.InvalidateClaim(argument.ClaimID)
+	go s.prewarmSpotlight("argument", argument.ID)
+	claimParticipants, err := s.getClaimParticipants(argument.ClaimID)
 	if err != nil {
 		s.log.WithError(err).Error("error getting participants ")
 		return
@@ -40,12 +65,13 @@ func (s *service) processArgumentCreated(data []byte, notifications chan<- *Noti
 	_, addresses := s.parseCosmosMentions(argument.Body)
 	mentionType := db.MentionArgument
 	addresses = unique(addresses)
+	summary := i18n.Params{"summary": argument.Summary}
 	for _, address := range addresses {
 		notified[address] = true
 		notifications <- &Notification{
 			From:   &creatorAddress,
 			To:     address,
-			Msg:    fmt.Sprintf("mentioned you %s: %s", mentionType.String(), argument.Summary),
+			Msg:    i18n.Message{Key: "notification.mention.argument.body", Params: summary},
 			TypeID: int64(argument.ID),
 			Type:   db.NotificationMentionAction,
 			Meta: db.NotificationMeta{
@@ -53,7 +79,7 @@ func (s *service) processArgumentCreated(data []byte, notifications chan<- *Noti
 				ArgumentID:  uint64Ptr(argument.ID),
 				MentionType: &mentionType,
 			},
-			Action: "Mentioned you in an argument",
+			Action: i18n.Message{Key: "notification.mention.argument.action"},
 			Trim:   true,
 		}
 	}
@@ -63,11 +89,11 @@ func (s *service) processArgumentCreated(data []byte, notifications chan<- *Noti
 		notifications <- &Notification{
 			From:   strPtr(argument.Creator.String()),
 			To:     claimParticipants.Creator,
-			Msg:    fmt.Sprintf("added a new argument on a claim you created: %s", argument.Summary),
+			Msg:    i18n.Message{Key: "notification.new_argument.creator.body", Params: summary},
 			TypeID: int64(argument.ID),
 			Type:   db.NotificationNewArgument,
 			Meta:   meta,
-			Action: "New Argument",
+			Action: i18n.Message{Key: "notification.new_argument.action"},
 		}
 	}
 
@@ -79,11 +105,11 @@ func (s *service) processArgumentCreated(data []byte, notifications chan<- *Noti
 		notifications <- &Notification{
 			From:   strPtr(argument.Creator.String()),
 			To:     p,
-			Msg:    fmt.Sprintf("added a new argument on a claim you participated in: %s", argument.Summary),
+			Msg:    i18n.Message{Key: "notification.new_argument.participant.body", Params: summary},
 			TypeID: int64(argument.ID),
 			Type:   db.NotificationNewArgument,
 			Meta:   meta,
-			Action: "New Argument",
+			Action: i18n.Message{Key: "notification.new_argument.action"},
 		}
 	}
 }
@@ -95,25 +121,32 @@ func (s *service) processUpvote(data []byte, notifications chan<- *Notification)
 		s.log.WithError(err).Error("error decoding argument created event")
 		return
 	}
-	argument, err := s.getArgumentSummary(int64(stake.ArgumentID))
+	argument, err := s.lookup.Argument(stake.ArgumentID)
 	if err != nil {
-		s.log.WithError(err).Error("error getting participants ")
+		s.log.WithError(err).Error("error getting argument ")
 		return
 	}
+	// the agree changes the stakes and participants
+	s.lookup.InvalidateArgument(argument.ID)
+	s.lookup.InvalidateClaim(argument.ClaimID)
 	meta := db.NotificationMeta{
-		ClaimID:    &argument.ClaimArgument.ClaimID,
+		ClaimID:    uint64Ptr(argument.ClaimID),
 		ArgumentID: uint64Ptr(stake.ArgumentID),
 	}
 
-	argumentCreatorAddress := argument.ClaimArgument.Creator.Address
+	argumentCreatorAddress := argument.Creator.String()
 	notifications <- &Notification{
-		From:   strPtr(stake.Creator.String()),
-		To:     argumentCreatorAddress,
-		Msg:    fmt.Sprintf("agreed with your argument: %s", argument.ClaimArgument.Summary),
-		TypeID: int64(stake.ArgumentID),
-		Type:   db.NotificationAgreeReceived,
-		Meta:   meta,
-		Action: "Agree Received",
+		From: strPtr(stake.Creator.String()),
+		To:   argumentCreatorAddress,
+		Msg: i18n.Message{
+			Key:    "notification.agree_received.body",
+			Params: i18n.Params{"summary": argument.Summary},
+		},
+		TypeID:    int64(stake.ArgumentID),
+		Type:      db.NotificationAgreeReceived,
+		Meta:      meta,
+		Action:    i18n.Message{Key: "notification.agree_received.action"},
+		Aggregate: true,
 	}
 }
 
@@ -141,21 +174,22 @@ func (s *service) processGift(data []byte, notifications chan<- *Notification) {
 		return
 	}
 
-	nMsg := "You've been gifted %s! %sHappy Debating!"
-	reqMsg := ""
+	key := "notification.gift.body"
 	if tx.GetMemo() == "request" {
-		reqMsg = "Thanks for being an awesome TruStorian. "
+		key = "notification.gift.request.body"
 	}
 	if tx.GetMemo() == "reward" {
 		fmt.Println("ignoring reward notification")
 		return
 	}
-	reward := fmt.Sprintf("%s %s", humanReadable(msg.Reward), db.CoinDisplayName)
 	notifications <- &Notification{
-		To:     msg.Recipient.String(),
-		Msg:    fmt.Sprintf(nMsg, reward, reqMsg),
+		To: msg.Recipient.String(),
+		Msg: i18n.Message{
+			Key:    key,
+			Params: i18n.Params{"amount": humanReadable(msg.Reward), "coin": db.CoinDisplayName},
+		},
 		Type:   db.NotificationGift,
-		Action: "Gift Received",
+		Action: i18n.Message{Key: "notification.gift.action"},
 	}
 }
 
@@ -183,12 +217,14 @@ func (s *service) notifySlashes(punishResults []slashing.PunishmentResult,
 	for k := range slashed {
 		notifications <- &Notification{
 			To: k,
-			Msg: fmt.Sprintf("You've been penalized! You've either wrote an argument that has been marked Not Helpful %s times or Agreed with an argument marked as Not Helpful %s times.",
-				minCount, minCount),
+			Msg: i18n.Message{
+				Key:    "notification.slashed.body",
+				Params: i18n.Params{"count": minCount},
+			},
 			TypeID: argumentID,
 			Type:   db.NotificationSlashed,
 			Meta:   meta,
-			Action: "Slashed",
+			Action: i18n.Message{Key: "notification.slashed.action"},
 		}
 	}
 
@@ -196,22 +232,24 @@ func (s *service) notifySlashes(punishResults []slashing.PunishmentResult,
 		if p.Type == slashing.PunishmentCuratorRewarded {
 			notifications <- &Notification{
 				To: p.AppAccAddress.String(),
-				Msg: fmt.Sprintf("You just earned %s %s from an argument you marked as Not Helpful",
-					humanReadable(p.Coin), db.CoinDisplayName),
+				Msg: i18n.Message{
+					Key:    "notification.earned_stake.curator.body",
+					Params: i18n.Params{"amount": humanReadable(p.Coin), "coin": db.CoinDisplayName},
+				},
 				TypeID: argumentID,
 				Type:   db.NotificationEarnedStake,
 				Meta:   meta,
-				Action: fmt.Sprintf("Earned %s", db.CoinDisplayName),
+				Action: i18n.Message{Key: "notification.earned_stake.action", Params: i18n.Params{"coin": db.CoinDisplayName}},
 			}
 		}
 		if p.Type == slashing.PunishmentJailed {
 			notifications <- &Notification{
 				To:     p.AppAccAddress.String(),
-				Msg:    "You've been slashed too many times and have been put in timeout. Basic privileges will be stripped.",
+				Msg:    i18n.Message{Key: "notification.jailed.body"},
 				TypeID: argumentID,
 				Type:   db.NotificationJailed,
 				Meta:   meta,
-				Action: "Timeout",
+				Action: i18n.Message{Key: "notification.jailed.action"},
 			}
 		}
 	}
@@ -224,13 +262,13 @@ func (s *service) processSlash(data []byte, events []abci.Event, notifications c
 		s.log.WithError(err).Error("error decoding argument created event")
 		return
 	}
-	argument, err := s.getArgumentSummary(int64(slash.ArgumentID))
+	argument, err := s.lookup.Argument(slash.ArgumentID)
 	if err != nil {
-		s.log.WithError(err).Error("error getting participants ")
+		s.log.WithError(err).Error("error getting argument ")
 		return
 	}
 	meta := db.NotificationMeta{
-		ClaimID:    &argument.ClaimArgument.ClaimID,
+		ClaimID:    uint64Ptr(argument.ClaimID),
 		ArgumentID: uint64Ptr(slash.ArgumentID),
 	}
 
@@ -239,12 +277,12 @@ func (s *service) processSlash(data []byte, events []abci.Event, notifications c
 		reason = slash.DetailedReason
 	}
 	notifications <- &Notification{
-		To:     argument.ClaimArgument.Creator.Address,
-		Msg:    fmt.Sprintf("Someone marked your argument as **Not Helpful** because: **%s** ", reason),
+		To:     argument.Creator.String(),
+		Msg:    i18n.Message{Key: "notification.not_helpful.body", Params: i18n.Params{"reason": reason}},
 		TypeID: int64(slash.ArgumentID),
 		Type:   db.NotificationNotHelpful,
 		Meta:   meta,
-		Action: "Not Helpful received on an Argument",
+		Action: i18n.Message{Key: "notification.not_helpful.action"},
 	}
 
 	b, ok := getTagValue(slashing.AttributeKeySlashResults, events)
@@ -269,6 +307,8 @@ func (s *service) processTxEvent(evt types.EventDataTx, notifications chan<- *No
 			for _, attr := range event.GetAttributes() {
 				if string(attr.Key) == sdk.AttributeKeyAction {
 					switch v := string(attr.Value); v {
+					case claim.TypeMsgCreateClaim:
+						s.processClaimCreated(evt.Result.Data)
 					case staking.TypeMsgSubmitArgument:
 						s.processArgumentCreated(evt.Result.Data, notifications)
 					case staking.TypeMsgSubmitUpvote:
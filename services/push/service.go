@@ -2,10 +2,12 @@ package main
 
 import (
 	"net/http"
-
-	"github.com/machinebox/graphql"
+	"time"
 
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/i18n"
+	"github.com/TruStory/octopus/services/truapi/lookup"
+	"github.com/TruStory/octopus/services/truapi/sigauth"
 	"github.com/sirupsen/logrus"
 )
 
@@ -13,9 +15,24 @@ type service struct {
 	db        *db.Client
 	apnsTopic string
 	log       logrus.FieldLogger
+	catalog   *i18n.Catalog
+	// aggregationWindow is the period in which similar notifications are collapsed.
+	aggregationWindow time.Duration
+	// campaignPollInterval is how often scheduled broadcast campaigns are checked.
+	campaignPollInterval time.Duration
+	// campaignStaleTimeout is how long a campaign can be sending without progress before it is resumed.
+	campaignStaleTimeout time.Duration
 	// gorush
 	httpClient        *http.Client
 	gorushHTTPAddress string
-	// graphql
-	graphqlClient *graphql.Client
+	// lookup reads claims and arguments from the chain
+	lookup         *lookup.Client
+	lookupCacheTTL time.Duration
+	// verifier authenticates requests signed by the internal services
+	verifier *sigauth.Verifier
+	// spotlight renders the previews of new claims and arguments ahead of time when its url and secret are set
+	spotlightURL    string
+	spotlightSecret string
+	// metricsSecret is the Metrics-Secret header /metrics requires, metrics aren't served without it
+	metricsSecret string
 }
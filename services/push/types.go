@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/i18n"
 	"github.com/appleboy/gorush/gorush"
 )
 
@@ -11,12 +12,15 @@ import (
 type Notification struct {
 	From   *string
 	To     string
-	Msg    string
+	Msg    i18n.Message
 	TypeID int64
 	Type   db.NotificationType
 	Meta   db.NotificationMeta
-	Action string
+	Action i18n.Message
 	Trim   bool
+	// Aggregate collapses notifications of the same type and target
+	// sent within the aggregation window into a single event.
+	Aggregate bool
 }
 
 // NotificationData represents the data relevant to the app.
@@ -49,6 +53,7 @@ type PushNotification struct {
 	Subtitle         string
 	Body             string
 	Platform         string
+	CollapseKey      string
 	NotificationData NotificationData
 }
 
@@ -71,79 +76,3 @@ type CommentNotificationRequest struct {
 	Creator         string    `json:"creator"`
 	Timestamp       time.Time `json:"timestamp"`
 }
-
-// GraphQL responses
-const ClaimArgumentByIDQuery = `
-query ClaimArgumentQuery($argumentId: ID!) {
-  claimArgument(id: $argumentId) {
-    id
-    claimId
-    claim {
-      body
-      id
-      creator {
-        address
-      }
-      participants {
-        address
-      }
-    }
-  }
-}
-`
-
-const claimByIDQuery = `
-  query ClaimQuery($claimId: ID!) {
-	claim(id: $claimId) {
-		id
-		body
-	}
-}
-`
-
-const argumentSummaryByIDQuery = `
-query ClaimArgumentQuery($argumentId: ID!) {
-  claimArgument(id: $argumentId) {
-    id
-    claimId
-    summary
-	creator{
-      address
-    }
-  }
-}
-`
-
-// Creator represents the user that backed/challenge.
-type Creator struct {
-	Address string `json:"address"`
-}
-
-// ClaimArgumentResponse is the response from the graphql endpoint.
-type ClaimArgumentResponse struct {
-	ClaimArgument struct {
-		ClaimID int64 `json:"claimId"`
-		Claim   struct {
-			Body         string    `json:"body"`
-			Creator      Creator   `json:"creator"`
-			Participants []Creator `json:"participants"`
-		} `json:"claim"`
-	} `json:"claimArgument"`
-}
-
-// ClaimResponse is the response from the graphql endpoint.
-type ClaimResponse struct {
-	Claim struct {
-		ID   int64  `json:"id"`
-		Body string `json:"body"`
-	} `json:"claim"`
-}
-
-// ArgumentSummaryResponse is the response from the graphql endpoint.
-type ArgumentSummaryResponse struct {
-	ClaimArgument struct {
-		ClaimID int64   `json:"claimId"`
-		Creator Creator `json:"creator"`
-		Summary string  `json:"summary"`
-	} `json:"claimArgument"`
-}
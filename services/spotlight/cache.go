@@ -0,0 +1,178 @@
+package spotlight
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/storage"
+)
+
+// Cache stores rendered previews by content address.
+type Cache interface {
+	// Get returns nil when the key is not cached.
+	Get(key string) ([]byte, error)
+	Put(key string, data []byte, contentType string) error
+}
+
+// cacheKey addresses a preview by the compiled SVG, the output size and format.
+func cacheKey(svg []byte, width, height int, format string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%dx%d\x00", format, width, height)
+	h.Write(svg)
+	return hex.EncodeToString(h.Sum(nil)) + "." + format
+}
+
+// StorageCache stores previews in an object storage, sharded by the first characters of their key.
+type StorageCache struct {
+	store  storage.Storage
+	prefix string
+}
+
+// NewStorageCache creates a cache storing previews under prefix in store.
+func NewStorageCache(store storage.Storage, prefix string) *StorageCache {
+	return &StorageCache{store: store, prefix: prefix}
+}
+
+// NewDiskCache creates a cache in dir, creating it if needed.
+func NewDiskCache(dir string) (*StorageCache, error) {
+	store, err := storage.NewLocal(dir, "", "")
+	if err != nil {
+		return nil, err
+	}
+	return NewStorageCache(store, ""), nil
+}
+
+func (c *StorageCache) path(key string) string {
+	return c.prefix + key[:2] + "/" + key
+}
+
+// Get reads a cached preview.
+func (c *StorageCache) Get(key string) ([]byte, error) {
+	data, err := c.store.Get(c.path(key))
+	if err == storage.ErrNotFound {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Put writes a preview.
+func (c *StorageCache) Put(key string, data []byte, contentType string) error {
+	_, err := c.store.Put(c.path(key), bytes.NewReader(data), contentType)
+	return err
+}
+
+// indexEntry is the cache key last rendered for a variant of an entity.
+type indexEntry struct {
+	Key       string    `json:"key"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// previewIndex remembers the cache keys last rendered for a claim, argument, comment, highlight, user or community,
+// so cached previews are served without fetching and compiling them again. It's kept in the render cache,
+// shared by the replicas, so invalidating an entity on any of them drops its previews on all of them.
+//
+// Entries are stored under the generation of their entity, which invalidating replaces. A preview is indexed
+// under the generation read before its data was fetched, so a render racing an invalidation stays unindexed.
+type previewIndex struct {
+	cache Cache
+	ttl   time.Duration
+
+	mu sync.Mutex
+	// templates is bumped when templates are reloaded, dropping the previews this replica indexed before
+	templates int64
+}
+
+func newPreviewIndex(cache Cache, ttl time.Duration) *previewIndex {
+	return &previewIndex{cache: cache, ttl: ttl}
+}
+
+func (i *previewIndex) enabled() bool {
+	return i.cache != nil && i.ttl > 0
+}
+
+func indexKey(kind string, parts ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(h[:]) + "." + kind
+}
+
+// generation returns the current generation of an entity, empty until it's invalidated.
+func (i *previewIndex) generation(entity string) (string, error) {
+	if !i.enabled() {
+		return "", nil
+	}
+	data, err := i.cache.Get(indexKey("generation", entity))
+	if err != nil {
+		return "", err
+	}
+	i.mu.Lock()
+	templates := i.templates
+	i.mu.Unlock()
+	return fmt.Sprintf("%s/%d", data, templates), nil
+}
+
+// get returns the cache key of a variant of an entity along with the generation a fresh render is indexed under.
+func (i *previewIndex) get(entity, variant string) (string, string, bool) {
+	generation, err := i.generation(entity)
+	if err != nil {
+		log.Println(err)
+		return "", "", false
+	}
+	if !i.enabled() {
+		return "", generation, false
+	}
+	data, err := i.cache.Get(indexKey("index", entity, generation, variant))
+	if err != nil {
+		log.Println(err)
+		return "", generation, false
+	}
+	if data == nil {
+		return "", generation, false
+	}
+	var entry indexEntry
+	err = json.Unmarshal(data, &entry)
+	if err != nil || time.Now().After(entry.ExpiresAt) {
+		return "", generation, false
+	}
+	return entry.Key, generation, true
+}
+
+// set indexes the cache key of a variant of an entity rendered from data fetched in a generation.
+func (i *previewIndex) set(entity, generation, variant, key string) {
+	// an empty generation couldn't be read
+	if !i.enabled() || generation == "" {
+		return
+	}
+	data, err := json.Marshal(indexEntry{Key: key, ExpiresAt: time.Now().Add(i.ttl)})
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	err = i.cache.Put(indexKey("index", entity, generation, variant), data, "application/json")
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// invalidate forgets the previews of an entity in every size and format, on every replica.
+func (i *previewIndex) invalidate(entity string) error {
+	if !i.enabled() {
+		return nil
+	}
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+	return i.cache.Put(indexKey("generation", entity), []byte(generation), "text/plain")
+}
+
+// clear forgets the previews of every entity this replica indexed.
+func (i *previewIndex) clear() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.templates++
+}
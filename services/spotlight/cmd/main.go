@@ -3,29 +3,113 @@ package main
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/TruStory/octopus/services/spotlight"
 
 	truCtx "github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/storage"
+	"github.com/TruStory/octopus/services/truapi/telemetry"
 )
 
 func main() {
-	port := getEnv("PORT", "54448")
-	endpoint := mustEnv("SPOTLIGHT_GRAPHQL_ENDPOINT")
-	jpegEnabled := getEnv("SPOTLIGHT_JPEG_ENABLED", "") == "true"
-	config := truCtx.Config{
-		Database: truCtx.DatabaseConfig{
-			Host: getEnv("PG_ADDR", "localhost"),
-			Port: 5432,
-			User: getEnv("PG_USER", "postgres"),
-			Pass: getEnv("PG_USER_PW", ""),
-			Name: getEnv("PG_DB_NAME", "trudb"),
-			Pool: 25,
+	indexTTL, err := time.ParseDuration(getEnv("SPOTLIGHT_CACHE_INDEX_TTL", "10m"))
+	if err != nil {
+		panic(err)
+	}
+	workers, err := strconv.Atoi(getEnv("SPOTLIGHT_WORKERS", "2"))
+	if err != nil {
+		panic(err)
+	}
+	snippetWorkers, err := strconv.Atoi(getEnv("SPOTLIGHT_SNIPPET_WORKERS", "1"))
+	if err != nil {
+		panic(err)
+	}
+	attempts, err := strconv.Atoi(getEnv("SPOTLIGHT_ATTEMPTS", "3"))
+	if err != nil {
+		panic(err)
+	}
+	var prewarmSizes []string
+	if sizes := getEnv("SPOTLIGHT_PREWARM_SIZES", ""); sizes != "" {
+		prewarmSizes = strings.Split(sizes, ",")
+	}
+	config := spotlight.Config{
+		Port:            getEnv("PORT", "54448"),
+		GraphQLEndpoint: mustEnv("SPOTLIGHT_GRAPHQL_ENDPOINT"),
+		JPEGEnabled:     getEnv("SPOTLIGHT_JPEG_ENABLED", "") == "true",
+		Renderer:        getEnv("SPOTLIGHT_RENDERER", spotlight.RendererNative),
+		Cache:           cache(),
+		CacheIndexTTL:   indexTTL,
+		Secret:          getEnv("SPOTLIGHT_SECRET", ""),
+		MetricsSecret:   getEnv("SPOTLIGHT_METRICS_SECRET", ""),
+		TemplatesDir:    getEnv("SPOTLIGHT_TEMPLATES_DIR", ""),
+		Dev:             getEnv("SPOTLIGHT_DEV", "") == "true",
+		Workers:         workers,
+		SnippetWorkers:  snippetWorkers,
+		Attempts:        attempts,
+		PrewarmSizes:    prewarmSizes,
+		Database: truCtx.Config{
+			Database: truCtx.DatabaseConfig{
+				Host: getEnv("PG_ADDR", "localhost"),
+				Port: 5432,
+				User: getEnv("PG_USER", "postgres"),
+				Pass: getEnv("PG_USER_PW", ""),
+				Name: getEnv("PG_DB_NAME", "trudb"),
+				Pool: 25,
+			},
 		},
 	}
-	service := spotlight.NewService(port, endpoint, jpegEnabled, config)
+	sampleRatio, err := strconv.ParseFloat(getEnv("SPOTLIGHT_TRACE_SAMPLE_RATIO", "1"), 64)
+	if err != nil {
+		panic(err)
+	}
+	stopTracing, err := telemetry.StartTracing(telemetry.TracingConfig{
+		Service:     "spotlight",
+		ZipkinURL:   getEnv("SPOTLIGHT_ZIPKIN_URL", ""),
+		SampleRatio: sampleRatio,
+	})
+	if err != nil {
+		panic(err)
+	}
+	defer stopTracing()
+	service, err := spotlight.NewService(config)
+	if err != nil {
+		panic(err)
+	}
 	service.Run()
 }
+
+// cache returns the render cache selected with SPOTLIGHT_CACHE.
+func cache() spotlight.Cache {
+	switch backend := getEnv("SPOTLIGHT_CACHE", "disk"); backend {
+	case "none":
+		return nil
+	case "disk":
+		c, err := spotlight.NewDiskCache(getEnv("SPOTLIGHT_CACHE_DIR", "storage"))
+		if err != nil {
+			panic(err)
+		}
+		return c
+	case storage.BackendS3, storage.BackendMinIO:
+		store, err := storage.New(storage.Config{
+			Backend:      backend,
+			Bucket:       mustEnv("SPOTLIGHT_CACHE_S3_BUCKET"),
+			Region:       getEnv("SPOTLIGHT_CACHE_S3_REGION", "us-west-1"),
+			Endpoint:     getEnv("SPOTLIGHT_CACHE_S3_ENDPOINT", ""),
+			AccessKey:    getEnv("SPOTLIGHT_CACHE_S3_ACCESS_KEY", ""),
+			AccessSecret: getEnv("SPOTLIGHT_CACHE_S3_ACCESS_SECRET", ""),
+		})
+		if err != nil {
+			panic(err)
+		}
+		return spotlight.NewStorageCache(store, getEnv("SPOTLIGHT_CACHE_S3_PREFIX", "spotlight/"))
+	default:
+		panic(fmt.Sprintf("unknown cache backend %s", backend))
+	}
+}
+
 func getEnv(env, defaultValue string) string {
 	val := os.Getenv(env)
 	if val != "" {
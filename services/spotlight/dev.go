@@ -0,0 +1,148 @@
+package spotlight
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/gobuffalo/packr/v2"
+	"github.com/gorilla/mux"
+)
+
+// maxFixtureSize limits the fixtures posted to the template authoring endpoint.
+const maxFixtureSize = 1 << 20
+
+// highlightFixture is the data a highlight template is rendered with.
+type highlightFixture struct {
+	Text    string     `json:"text"`
+	Creator UserObject `json:"creator"`
+}
+
+type templateInfo struct {
+	Name   string  `json:"name"`
+	Layout *Layout `json:"layout,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// listTemplates lists the templates with their layout, templates that fail to parse list their error.
+func listTemplates(s *Service) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		infos := make([]templateInfo, 0)
+		for _, name := range s.templates.list() {
+			info := templateInfo{Name: name}
+			tmpl, err := s.templates.find(name)
+			if err != nil {
+				info.Error = err.Error()
+			} else {
+				info.Layout = &tmpl.layout
+			}
+			infos = append(infos, info)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(infos); err != nil {
+			log.Println(err)
+		}
+	}
+	return http.HandlerFunc(fn)
+}
+
+// renderTemplate renders a template against a fixture, the bundled fixture of its kind
+// on GET and the JSON body on POST. Previews rendered here are never cached.
+func renderTemplate(s *Service) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+		out, err := parseOutput(r, s.defaultFormat())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tmpl, err := s.templates.find(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		kind := strings.SplitN(name, ".", 2)[0]
+		var fixture []byte
+		if r.Method == http.MethodPost {
+			fixture, err = ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxFixtureSize))
+		} else {
+			fixture, err = packr.New("Fixtures", "./fixtures").Find(kind + ".json")
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		compiledPreview, err := compileFixture(tmpl, kind, fixture)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		compiledPreview = fitViewBox(compiledPreview, out.Width, out.Height)
+		img, err := s.rasterizer.Rasterize([]byte(compiledPreview), out.Width, out.Height)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data, err := out.encode(img)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", out.contentType())
+		w.Header().Set("Cache-Control", "no-store")
+		if _, err := w.Write(data); err != nil {
+			log.Println(err)
+		}
+	}
+	return http.HandlerFunc(fn)
+}
+
+// compileFixture compiles a template with the compile function of its kind,
+// the kind being the name of the template up to the first dot, e.g. claim for claim.square.svg.
+// Comments are rendered with the highlight templates, so they have no kind of their own.
+func compileFixture(tmpl *previewTemplate, kind string, fixture []byte) (string, error) {
+	switch kind {
+	case "claim":
+		var data ClaimByIDResponse
+		if err := json.Unmarshal(fixture, &data); err != nil {
+			return "", err
+		}
+		return compileClaimPreview(tmpl, data.Claim), nil
+	case "argument":
+		var data ArgumentObject
+		if err := json.Unmarshal(fixture, &data); err != nil {
+			return "", err
+		}
+		return compileArgumentPreview(tmpl, data)
+	case "highlight":
+		var data highlightFixture
+		if err := json.Unmarshal(fixture, &data); err != nil {
+			return "", err
+		}
+		return compileHighlightPreview(tmpl, &db.Highlight{Text: data.Text}, data.Creator)
+	case "profile":
+		var data ProfileByAddressResponse
+		if err := json.Unmarshal(fixture, &data); err != nil {
+			return "", err
+		}
+		if data.AppAccount == nil {
+			return "", fmt.Errorf("fixture has no appAccount")
+		}
+		return compileProfilePreview(tmpl, data)
+	case "community":
+		var data CommunityByIDResponse
+		if err := json.Unmarshal(fixture, &data); err != nil {
+			return "", err
+		}
+		if data.Community == nil {
+			return "", fmt.Errorf("fixture has no community")
+		}
+		return compileCommunityPreview(tmpl, data)
+	default:
+		return "", fmt.Errorf("no fixture for %s templates", kind)
+	}
+}
@@ -0,0 +1,51 @@
+#!/bin/sh
+# usage: fetch-fonts.sh [-pin] MANIFEST DIR
+#
+# Downloads the fonts listed in MANIFEST (see fonts.sha256) into DIR. A font already in DIR is
+# kept when it matches its SHA-256, a download that doesn't match fails and is removed.
+# With -pin, fonts without a SHA-256 are downloaded and their SHA-256 recorded in MANIFEST.
+set -eu
+
+pin=false
+if [ "$1" = "-pin" ]; then
+	pin=true
+	shift
+fi
+manifest=$1
+dir=$2
+
+sha256() {
+	if command -v sha256sum >/dev/null; then
+		sha256sum "$1" | cut -d ' ' -f 1
+	else
+		shasum -a 256 "$1" | cut -d ' ' -f 1
+	fi
+}
+
+grep -v '^#' "$manifest" | while read -r sum file url; do
+	if [ -z "$file" ]; then
+		continue
+	fi
+	if [ "$sum" = "-" ]; then
+		if ! $pin; then
+			echo "$file has no SHA-256 in $manifest, run make pin-fonts" >&2
+			exit 1
+		fi
+		curl -fsSL -o "$dir/$file" "$url"
+		sum=$(sha256 "$dir/$file")
+		sed -i.bak "s|^- $file |$sum $file |" "$manifest"
+		rm -f "$manifest.bak"
+		echo "pinned $file to $sum"
+		continue
+	fi
+	if [ -f "$dir/$file" ] && [ "$(sha256 "$dir/$file")" = "$sum" ]; then
+		continue
+	fi
+	curl -fsSL -o "$dir/$file.download" "$url"
+	if [ "$(sha256 "$dir/$file.download")" != "$sum" ]; then
+		rm -f "$dir/$file.download"
+		echo "$file downloaded from $url doesn't match its SHA-256 $sum" >&2
+		exit 1
+	fi
+	mv "$dir/$file.download" "$dir/$file"
+done
@@ -0,0 +1,213 @@
+package spotlight
+
+import (
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gobuffalo/packr/v2"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// DefaultFontFamily is used when a template asks for a font that isn't bundled.
+const DefaultFontFamily = "Poppins"
+
+// fallbackFontFamilies are tried in order for glyphs missing from the selected font.
+// They aren't checked in, `make fonts` downloads them before they're bundled.
+var fallbackFontFamilies = []string{"NotoEmoji", "NotoSansCJKjp", "NotoSansHebrew", "NotoSansArabic"}
+
+var fontWeights = map[string]int{
+	"Thin":       100,
+	"ExtraLight": 200,
+	"Light":      300,
+	"Regular":    400,
+	"Medium":     500,
+	"SemiBold":   600,
+	"Bold":       700,
+	"ExtraBold":  800,
+	"Black":      900,
+}
+
+type fontFace struct {
+	name   string
+	family string
+	weight int
+	italic bool
+	font   *sfnt.Font
+}
+
+// fontSet holds the fonts available to the native renderer.
+type fontSet struct {
+	faces []*fontFace
+}
+
+var (
+	bundledOnce  sync.Once
+	bundled      *fontSet
+	bundledError error
+)
+
+// bundledFonts returns the bundled fonts, parsing them once.
+// Fonts are only read after parsing, so the set is shared by renderers and text measurement.
+func bundledFonts() (*fontSet, error) {
+	bundledOnce.Do(func() {
+		bundled, bundledError = loadFonts()
+	})
+	return bundled, bundledError
+}
+
+// loadFonts parses the bundled TrueType and OpenType fonts named Family-Style.ttf or Family-Style.otf.
+func loadFonts() (*fontSet, error) {
+	box := packr.New("Fonts", "./fonts")
+	set := &fontSet{}
+	for _, filename := range box.List() {
+		ext := filepath.Ext(filename)
+		if ext != ".ttf" && ext != ".otf" {
+			continue
+		}
+		b, err := box.Find(filename)
+		if err != nil {
+			return nil, err
+		}
+		f, err := sfnt.Parse(b)
+		if err != nil {
+			return nil, err
+		}
+		set.add(strings.TrimSuffix(filename, ext), f)
+	}
+	return set, nil
+}
+
+func (s *fontSet) add(name string, f *sfnt.Font) {
+	family, style := name, "Regular"
+	if i := strings.IndexByte(name, '-'); i >= 0 {
+		family, style = name[:i], name[i+1:]
+	}
+	italic := strings.HasSuffix(style, "Italic")
+	style = strings.TrimSuffix(style, "Italic")
+	weight, ok := fontWeights[style]
+	if !ok {
+		weight = 400
+	}
+	s.faces = append(s.faces, &fontFace{name: name, family: family, weight: weight, italic: italic, font: f})
+}
+
+// match returns the face closest to a CSS font-family list, weight and style.
+func (s *fontSet) match(families string, weight int, italic bool) *fontFace {
+	for _, family := range strings.Split(families, ",") {
+		family = strings.Trim(strings.TrimSpace(family), `'"`)
+		// PostScript names like Poppins-Bold
+		for _, face := range s.faces {
+			if strings.EqualFold(face.name, family) {
+				return face
+			}
+		}
+		if face := s.closest(family, weight, italic); face != nil {
+			return face
+		}
+	}
+	return s.closest(DefaultFontFamily, weight, italic)
+}
+
+func (s *fontSet) closest(family string, weight int, italic bool) *fontFace {
+	var best *fontFace
+	bestScore := math.MaxInt32
+	for _, face := range s.faces {
+		if !strings.EqualFold(face.family, family) {
+			continue
+		}
+		score := abs(face.weight - weight)
+		if face.italic != italic {
+			score += 1000
+		}
+		if score < bestScore {
+			best, bestScore = face, score
+		}
+	}
+	return best
+}
+
+// glyph returns the face and index rendering r, taken from the fallback fonts when face lacks it.
+// The index is 0 when no bundled font has r.
+func (s *fontSet) glyph(b *sfnt.Buffer, face *fontFace, r rune) (*fontFace, sfnt.GlyphIndex) {
+	if idx, err := face.font.GlyphIndex(b, r); err == nil && idx != 0 {
+		return face, idx
+	}
+	if fallback, idx := s.fallback(b, r); fallback != nil {
+		return fallback, idx
+	}
+	return face, 0
+}
+
+// fallback returns a face able to render r, preferring the fallback fonts in order.
+func (s *fontSet) fallback(b *sfnt.Buffer, r rune) (*fontFace, sfnt.GlyphIndex) {
+	for _, family := range fallbackFontFamilies {
+		if face := s.closest(family, 400, false); face != nil {
+			if idx, err := face.font.GlyphIndex(b, r); err == nil && idx != 0 {
+				return face, idx
+			}
+		}
+	}
+	for _, face := range s.faces {
+		if idx, err := face.font.GlyphIndex(b, r); err == nil && idx != 0 {
+			return face, idx
+		}
+	}
+	return nil, 0
+}
+
+// shape lays out a line of text, calling glyph with the face, index and pen position of every glyph,
+// and returns its advance. Glyphs missing from face are taken from the fallback fonts.
+func (s *fontSet) shape(b *sfnt.Buffer, face *fontFace, text string, size, spacing float64, glyph func(*fontFace, sfnt.GlyphIndex, float64)) float64 {
+	ppem := fixed.Int26_6(size * 64)
+	pen := 0.0
+	var prev sfnt.GlyphIndex
+	var prevFace *fontFace
+	for _, r := range text {
+		glyphFace, idx := s.glyph(b, face, r)
+		if prevFace == glyphFace && prev != 0 {
+			if kern, err := glyphFace.font.Kern(b, prev, idx, ppem, font.HintingNone); err == nil {
+				pen += float64(kern) / 64
+			}
+		}
+		if glyph != nil {
+			glyph(glyphFace, idx, pen)
+		}
+		if adv, err := glyphFace.font.GlyphAdvance(b, idx, ppem, font.HintingNone); err == nil {
+			pen += float64(adv) / 64
+		}
+		pen += spacing
+		prev, prevFace = idx, glyphFace
+	}
+	return pen
+}
+
+// parseFontWeight converts a CSS font-weight to its numeric value.
+func parseFontWeight(s string) int {
+	switch strings.TrimSpace(s) {
+	case "", "normal":
+		return 400
+	case "bold":
+		return 700
+	case "lighter":
+		return 300
+	case "bolder":
+		return 800
+	}
+	w, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 400
+	}
+	return w
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
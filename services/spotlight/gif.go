@@ -0,0 +1,202 @@
+package spotlight
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"io"
+	"sort"
+	"time"
+)
+
+// gifSamples is about the number of pixels sampled to build the palette.
+const gifSamples = 250000
+
+// bayer is a 4x4 ordered dithering matrix, it hides the banding of gradients in 256 colors.
+var bayer = [4][4]int{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// frame is a frame of an animation and how long it is shown.
+type frame struct {
+	img   *image.RGBA
+	delay time.Duration
+}
+
+// encodeGIF encodes frames as a looping animated GIF.
+// All frames share a palette built from all of them, so crossfades don't flicker.
+func encodeGIF(w io.Writer, frames []frame) error {
+	pixels := 0
+	for _, f := range frames {
+		pixels += f.img.Bounds().Dx() * f.img.Bounds().Dy()
+	}
+	// an odd step doesn't sample the same columns of every row
+	step := pixels/gifSamples | 1
+	samples := make([][3]uint8, 0, gifSamples+len(frames))
+	for _, f := range frames {
+		samples = samplePixels(samples, f.img, step)
+	}
+	palette := medianCut(samples, 256)
+	lut := newPaletteLUT(palette)
+	anim := &gif.GIF{}
+	for _, f := range frames {
+		anim.Image = append(anim.Image, lut.dither(f.img))
+		// GIF delays are in hundredths of a second
+		anim.Delay = append(anim.Delay, int(f.delay/(10*time.Millisecond)))
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+func samplePixels(samples [][3]uint8, img *image.RGBA, step int) [][3]uint8 {
+	b := img.Bounds()
+	for i := 0; i < b.Dx()*b.Dy(); i += step {
+		offset := img.PixOffset(b.Min.X+i%b.Dx(), b.Min.Y+i/b.Dx())
+		samples = append(samples, [3]uint8{img.Pix[offset], img.Pix[offset+1], img.Pix[offset+2]})
+	}
+	return samples
+}
+
+// medianCut builds a palette of at most n colors by splitting the box of colors with the widest channel at its median.
+func medianCut(samples [][3]uint8, n int) color.Palette {
+	if len(samples) == 0 {
+		return color.Palette{color.Black}
+	}
+	boxes := [][][3]uint8{samples}
+	for len(boxes) < n {
+		// split the box with the widest range
+		best, bestChannel, bestRange := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			channel, r := widestChannel(box)
+			if r > bestRange {
+				best, bestChannel, bestRange = i, channel, r
+			}
+		}
+		if best < 0 {
+			break
+		}
+		box := boxes[best]
+		sort.Slice(box, func(i, j int) bool { return box[i][bestChannel] < box[j][bestChannel] })
+		boxes[best] = box[:len(box)/2]
+		boxes = append(boxes, box[len(box)/2:])
+	}
+	palette := make(color.Palette, 0, len(boxes))
+	for _, box := range boxes {
+		var r, g, b int
+		for _, c := range box {
+			r, g, b = r+int(c[0]), g+int(c[1]), b+int(c[2])
+		}
+		palette = append(palette, color.RGBA{R: uint8(r / len(box)), G: uint8(g / len(box)), B: uint8(b / len(box)), A: 0xff})
+	}
+	return palette
+}
+
+func widestChannel(box [][3]uint8) (int, int) {
+	min := [3]uint8{255, 255, 255}
+	var max [3]uint8
+	for _, c := range box {
+		for i := 0; i < 3; i++ {
+			if c[i] < min[i] {
+				min[i] = c[i]
+			}
+			if c[i] > max[i] {
+				max[i] = c[i]
+			}
+		}
+	}
+	channel, r := 0, 0
+	for i := 0; i < 3; i++ {
+		if int(max[i])-int(min[i]) > r {
+			channel, r = i, int(max[i])-int(min[i])
+		}
+	}
+	return channel, r
+}
+
+// paletteLUT maps colors with 5 bits per channel to their nearest palette index.
+type paletteLUT struct {
+	palette color.Palette
+	rgb     [][3]int
+	index   []int16
+}
+
+func newPaletteLUT(palette color.Palette) *paletteLUT {
+	lut := &paletteLUT{palette: palette, index: make([]int16, 1<<15)}
+	for i := range lut.index {
+		lut.index[i] = -1
+	}
+	for _, c := range palette {
+		r, g, b, _ := c.RGBA()
+		lut.rgb = append(lut.rgb, [3]int{int(r >> 8), int(g >> 8), int(b >> 8)})
+	}
+	return lut
+}
+
+func (lut *paletteLUT) lookup(r, g, b int) uint8 {
+	i := r>>3<<10 | g>>3<<5 | b>>3
+	if lut.index[i] < 0 {
+		// the center of the 5 bit cell
+		cr, cg, cb := r>>3<<3|4, g>>3<<3|4, b>>3<<3|4
+		best, bestDistance := 0, 1<<30
+		for j, c := range lut.rgb {
+			d := (c[0]-cr)*(c[0]-cr) + (c[1]-cg)*(c[1]-cg) + (c[2]-cb)*(c[2]-cb)
+			if d < bestDistance {
+				best, bestDistance = j, d
+			}
+		}
+		lut.index[i] = int16(best)
+	}
+	return uint8(lut.index[i])
+}
+
+// dither converts an image to the palette with ordered dithering.
+func (lut *paletteLUT) dither(img *image.RGBA) *image.Paletted {
+	b := img.Bounds()
+	dst := image.NewPaletted(b, lut.palette)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		offset := img.PixOffset(b.Min.X, y)
+		for x := b.Min.X; x < b.Max.X; x++ {
+			// spread by up to one 5 bit step
+			d := bayer[y&3][x&3] - 8
+			dst.Pix[dst.PixOffset(x, y)] = lut.lookup(clampByte(int(img.Pix[offset])+d), clampByte(int(img.Pix[offset+1])+d), clampByte(int(img.Pix[offset+2])+d))
+			offset += 4
+		}
+	}
+	return dst
+}
+
+func clampByte(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return v
+}
+
+// toRGBA converts an image to RGBA, without copying images that already are.
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	rgba := image.NewRGBA(img.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return rgba
+}
+
+// crossfade blends a into b, t going from 0 for a to 1 for b.
+func crossfade(a, b *image.RGBA, t float64) *image.RGBA {
+	dst := image.NewRGBA(a.Bounds())
+	w := int(t * 256)
+	for i := range dst.Pix {
+		dst.Pix[i] = uint8((int(a.Pix[i])*(256-w) + int(b.Pix[i])*w) >> 8)
+	}
+	return dst
+}
@@ -0,0 +1,280 @@
+package spotlight
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Statuses of a job.
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// Kinds of jobs, every kind runs on its own workers.
+const (
+	JobPrewarm = "prewarm"
+	JobSnippet = "snippet"
+)
+
+// ErrQueueFull is returned when too many jobs are queued.
+var ErrQueueFull = errors.New("render queue is full")
+
+const (
+	// jobTTL is how long finished jobs can be polled.
+	jobTTL = time.Hour
+	// jobQueueSize is the number of jobs of a kind that can wait for a worker.
+	jobQueueSize = 1000
+	// jobRetryDelay is the delay before the first retry of a failed job, doubled for every retry.
+	jobRetryDelay = 2 * time.Second
+)
+
+// Job is a render running in the background.
+type Job struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Entity string `json:"entity"`
+	// Variant is the size and format rendered, e.g. twitter.gif
+	Variant string `json:"variant"`
+	Status  string `json:"status"`
+	// Attempts is the number of times the job ran, Error is the error of the last one
+	Attempts  int       `json:"attempts"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// key is the cache key of the result
+	key string
+}
+
+// finished reports whether the job is done or failed for good.
+func (j *Job) finished() bool {
+	return j.Status == JobDone || j.Status == JobFailed
+}
+
+// jobFunc renders a job and returns the cache key of the result.
+type jobFunc func() (string, error)
+
+type jobTask struct {
+	job *Job
+	run jobFunc
+}
+
+// jobPool runs the jobs of a kind on a fixed number of workers.
+type jobPool struct {
+	workers int
+	tasks   chan jobTask
+}
+
+// JobStats counts the jobs of a kind by status.
+type JobStats struct {
+	Workers int `json:"workers"`
+	Queued  int `json:"queued"`
+	Running int `json:"running"`
+	Done    int `json:"done"`
+	Failed  int `json:"failed"`
+}
+
+// jobQueue runs jobs in the background, retrying the failed ones.
+// Jobs live in memory, a restart loses the queued jobs but not the rendered results in the cache.
+type jobQueue struct {
+	// attempts is the number of times a job runs before it fails
+	attempts   int
+	retryDelay time.Duration
+
+	mu    sync.Mutex
+	pools map[string]*jobPool
+	jobs  map[string]*Job
+	// pending maps kind/entity/variant to the job queued or running for it
+	pending map[string]*Job
+}
+
+func newJobQueue(attempts int) *jobQueue {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return &jobQueue{
+		attempts:   attempts,
+		retryDelay: jobRetryDelay,
+		pools:      make(map[string]*jobPool),
+		jobs:       make(map[string]*Job),
+		pending:    make(map[string]*Job),
+	}
+}
+
+// start starts the workers running the jobs of a kind.
+func (q *jobQueue) start(kind string, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	pool := &jobPool{workers: workers, tasks: make(chan jobTask, jobQueueSize)}
+	q.mu.Lock()
+	q.pools[kind] = pool
+	q.mu.Unlock()
+	for i := 0; i < workers; i++ {
+		go q.work(pool)
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func pendingKey(kind, entity, variant string) string {
+	return kind + "/" + entity + "/" + variant
+}
+
+func (q *jobQueue) work(pool *jobPool) {
+	for task := range pool.tasks {
+		q.mu.Lock()
+		task.job.Status = JobRunning
+		task.job.Attempts++
+		task.job.UpdatedAt = time.Now()
+		q.mu.Unlock()
+		key, err := task.run()
+		q.finish(pool, task, key, err)
+	}
+}
+
+// finish records the result of a run, queueing the job again after a delay when it failed and has attempts left.
+func (q *jobQueue) finish(pool *jobPool, task jobTask, key string, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := task.job
+	job.UpdatedAt = time.Now()
+	if err == nil {
+		job.Status = JobDone
+		job.Error = ""
+		job.key = key
+		delete(q.pending, pendingKey(job.Kind, job.Entity, job.Variant))
+		return
+	}
+	job.Error = err.Error()
+	if job.Attempts >= q.attempts {
+		log.Printf("%s job %s for %s failed after %d attempts: %s", job.Kind, job.ID, job.Entity, job.Attempts, err)
+		job.Status = JobFailed
+		delete(q.pending, pendingKey(job.Kind, job.Entity, job.Variant))
+		return
+	}
+	log.Printf("%s job %s for %s failed, retrying: %s", job.Kind, job.ID, job.Entity, err)
+	job.Status = JobQueued
+	delay := q.retryDelay << uint(job.Attempts-1)
+	time.AfterFunc(delay, func() {
+		select {
+		case pool.tasks <- task:
+		default:
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			job.Status = JobFailed
+			job.Error = ErrQueueFull.Error()
+			job.UpdatedAt = time.Now()
+			delete(q.pending, pendingKey(job.Kind, job.Entity, job.Variant))
+		}
+	})
+}
+
+// enqueue queues a job, returning the job already queued or running for the same entity and variant if any.
+func (q *jobQueue) enqueue(kind, entity, variant string, run jobFunc) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pool, ok := q.pools[kind]
+	if !ok {
+		return Job{}, fmt.Errorf("no workers for %s jobs", kind)
+	}
+	q.prune()
+	if job, ok := q.pending[pendingKey(kind, entity, variant)]; ok {
+		return *job, nil
+	}
+	job := q.add(kind, entity, variant, JobQueued)
+	select {
+	case pool.tasks <- jobTask{job: job, run: run}:
+	default:
+		delete(q.jobs, job.ID)
+		return Job{}, ErrQueueFull
+	}
+	q.pending[pendingKey(kind, entity, variant)] = job
+	return *job, nil
+}
+
+// finished records a job whose result is already cached.
+func (q *jobQueue) finished(kind, entity, variant, key string) Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune()
+	job := q.add(kind, entity, variant, JobDone)
+	job.key = key
+	return *job
+}
+
+func (q *jobQueue) add(kind, entity, variant, status string) *Job {
+	now := time.Now()
+	job := &Job{
+		ID:        newJobID(),
+		Kind:      kind,
+		Entity:    entity,
+		Variant:   variant,
+		Status:    status,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	q.jobs[job.ID] = job
+	return job
+}
+
+// get returns a copy of a job.
+func (q *jobQueue) get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// stats counts the jobs of every kind by status.
+func (q *jobQueue) stats() map[string]JobStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.prune()
+	stats := make(map[string]JobStats)
+	for kind, pool := range q.pools {
+		stats[kind] = JobStats{Workers: pool.workers}
+	}
+	for _, job := range q.jobs {
+		s := stats[job.Kind]
+		switch job.Status {
+		case JobQueued:
+			s.Queued++
+		case JobRunning:
+			s.Running++
+		case JobDone:
+			s.Done++
+		case JobFailed:
+			s.Failed++
+		}
+		stats[job.Kind] = s
+	}
+	return stats
+}
+
+// prune forgets the jobs finished more than jobTTL ago.
+func (q *jobQueue) prune() {
+	expired := time.Now().Add(-jobTTL)
+	for id, job := range q.jobs {
+		if job.finished() && job.UpdatedAt.Before(expired) {
+			delete(q.jobs, id)
+		}
+	}
+}
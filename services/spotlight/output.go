@@ -0,0 +1,176 @@
+package spotlight
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Formats previews can be encoded in.
+const (
+	FormatPNG  = "png"
+	FormatJPEG = "jpeg"
+	FormatWebP = "webp"
+)
+
+// Formats of animated snippets.
+const (
+	FormatGIF = "gif"
+	FormatMP4 = "mp4"
+)
+
+// Layouts of the templates, a template named claim.svg has its variants in claim.square.svg and claim.story.svg.
+const (
+	LayoutLandscape = "landscape"
+	LayoutSquare    = "square"
+	LayoutStory     = "story"
+)
+
+// DefaultSize is the size served when no size is requested.
+const DefaultSize = "default"
+
+// Size is an output size for previews.
+type Size struct {
+	Width  int
+	Height int
+	Layout string
+}
+
+// Sizes are the sizes that can be requested with the size query parameter.
+var Sizes = map[string]Size{
+	DefaultSize: {Width: PREVIEW_WIDTH, Height: PREVIEW_HEIGHT, Layout: LayoutLandscape},
+	"twitter":   {Width: 1200, Height: 675, Layout: LayoutLandscape},
+	"og":        {Width: 1200, Height: 630, Layout: LayoutLandscape},
+	"square":    {Width: 1080, Height: 1080, Layout: LayoutSquare},
+	"story":     {Width: 1080, Height: 1920, Layout: LayoutStory},
+}
+
+// output is the size and format of a requested preview.
+type output struct {
+	sizeName string
+	Size
+	format string
+}
+
+// variant identifies the output in the preview index.
+func (o output) variant() string {
+	return o.sizeName + "." + o.format
+}
+
+func (o output) contentType() string {
+	if o.format == FormatMP4 {
+		return "video/mp4"
+	}
+	return "image/" + o.format
+}
+
+// template returns the name of the variant of a template for the layout of the output.
+func (o output) template(name string) string {
+	if o.Layout == LayoutLandscape {
+		return name
+	}
+	return strings.TrimSuffix(name, ".svg") + "." + o.Layout + ".svg"
+}
+
+// parseOutput reads the size and format query parameters of a request.
+func parseOutput(r *http.Request, defaultFormat string) (output, error) {
+	query := r.URL.Query()
+	o := output{sizeName: DefaultSize, format: defaultFormat}
+	if size := query.Get("size"); size != "" {
+		o.sizeName = strings.ToLower(size)
+	}
+	var ok bool
+	o.Size, ok = Sizes[o.sizeName]
+	if !ok {
+		return output{}, fmt.Errorf("unknown size %q", o.sizeName)
+	}
+	switch format := strings.ToLower(query.Get("format")); format {
+	case "":
+	case "png":
+		o.format = FormatPNG
+	case "jpg", "jpeg":
+		o.format = FormatJPEG
+	case "webp":
+		o.format = FormatWebP
+	default:
+		return output{}, fmt.Errorf("unknown format %q", format)
+	}
+	return o, nil
+}
+
+// encode encodes a rendered preview in the output format.
+func (o output) encode(img image.Image) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	var err error
+	switch o.format {
+	case FormatJPEG:
+		err = jpeg.Encode(buf, img, nil)
+	case FormatWebP:
+		err = encodeWebP(buf, img)
+	default:
+		err = png.Encode(buf, img)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var (
+	regexSVGTag     = regexp.MustCompile(`<svg\s[^>]*>`)
+	regexSVGSize    = regexp.MustCompile(`\s(width|height|viewBox)="[^"]*"`)
+	regexSVGViewBox = regexp.MustCompile(`\sviewBox="([^"]*)"`)
+)
+
+// fitViewBox crops the view box of a compiled template around its center to the aspect ratio
+// of width x height, so a template designed for another aspect ratio isn't stretched.
+// Templates within 1% of the aspect ratio are left alone.
+func fitViewBox(svg string, width, height int) string {
+	tag := regexSVGTag.FindStringIndex(svg)
+	if tag == nil {
+		return svg
+	}
+	root := svg[tag[0]:tag[1]]
+	match := regexSVGViewBox.FindStringSubmatch(root)
+	if match == nil {
+		return svg
+	}
+	box := strings.FieldsFunc(match[1], func(r rune) bool { return r == ',' || r == ' ' })
+	if len(box) != 4 {
+		return svg
+	}
+	var v [4]float64
+	for i, s := range box {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return svg
+		}
+		v[i] = f
+	}
+	x, y, w, h := v[0], v[1], v[2], v[3]
+	if w <= 0 || h <= 0 {
+		return svg
+	}
+	target := float64(width) / float64(height)
+	if math.Abs(w/h-target)/target < 0.01 {
+		return svg
+	}
+	if w/h > target {
+		x += (w - h*target) / 2
+		w = h * target
+	} else {
+		y += (h - w/target) / 2
+		h = w / target
+	}
+	// the intrinsic size must match the view box or rsvg-convert would letterbox it
+	root = regexSVGSize.ReplaceAllString(root, "")
+	root = strings.Replace(root, "<svg", fmt.Sprintf(`<svg width="%g" height="%g" viewBox="%g %g %g %g"`, w, h, x, y, w, h), 1)
+	return svg[:tag[0]] + root + svg[tag[1]:]
+}
@@ -0,0 +1,451 @@
+package spotlight
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/math/fixed"
+)
+
+type pathOp byte
+
+const (
+	opMove pathOp = iota
+	opLine
+	opQuad
+	opCubic
+	opClose
+)
+
+type pathSegment struct {
+	op  pathOp
+	pts [3][2]float64
+}
+
+// vectorPath is a path in user space coordinates.
+type vectorPath []pathSegment
+
+func (p *vectorPath) moveTo(x, y float64) {
+	*p = append(*p, pathSegment{op: opMove, pts: [3][2]float64{{x, y}}})
+}
+
+func (p *vectorPath) lineTo(x, y float64) {
+	*p = append(*p, pathSegment{op: opLine, pts: [3][2]float64{{x, y}}})
+}
+
+func (p *vectorPath) quadTo(x1, y1, x, y float64) {
+	*p = append(*p, pathSegment{op: opQuad, pts: [3][2]float64{{x1, y1}, {x, y}}})
+}
+
+func (p *vectorPath) cubicTo(x1, y1, x2, y2, x, y float64) {
+	*p = append(*p, pathSegment{op: opCubic, pts: [3][2]float64{{x1, y1}, {x2, y2}, {x, y}}})
+}
+
+func (p *vectorPath) close() {
+	*p = append(*p, pathSegment{op: opClose})
+}
+
+// addTo feeds the path transformed by m to a rasterx adder.
+func (p vectorPath) addTo(adder rasterx.Adder, m rasterx.Matrix2D) {
+	point := func(pt [2]float64) fixed.Point26_6 {
+		x, y := m.Transform(pt[0], pt[1])
+		return fixed.Point26_6{X: fixed.Int26_6(x * 64), Y: fixed.Int26_6(y * 64)}
+	}
+	started := false
+	var start [2]float64
+	for _, s := range p {
+		if !started && s.op != opMove && s.op != opClose {
+			// segments after a close continue from the start of the closed subpath
+			adder.Start(point(start))
+			started = true
+		}
+		switch s.op {
+		case opMove:
+			if started {
+				adder.Stop(false)
+			}
+			start = s.pts[0]
+			adder.Start(point(s.pts[0]))
+			started = true
+		case opLine:
+			adder.Line(point(s.pts[0]))
+		case opQuad:
+			adder.QuadBezier(point(s.pts[0]), point(s.pts[1]))
+		case opCubic:
+			adder.CubeBezier(point(s.pts[0]), point(s.pts[1]), point(s.pts[2]))
+		case opClose:
+			if started {
+				adder.Stop(true)
+				started = false
+			}
+		}
+	}
+	if started {
+		adder.Stop(false)
+	}
+}
+
+// bounds returns the bounding box of the path control points.
+func (p vectorPath) bounds() (minX, minY, maxX, maxY float64) {
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	for _, s := range p {
+		n := 0
+		switch s.op {
+		case opMove, opLine:
+			n = 1
+		case opQuad:
+			n = 2
+		case opCubic:
+			n = 3
+		}
+		for _, pt := range s.pts[:n] {
+			minX, maxX = math.Min(minX, pt[0]), math.Max(maxX, pt[0])
+			minY, maxY = math.Min(minY, pt[1]), math.Max(maxY, pt[1])
+		}
+	}
+	if math.IsInf(minX, 1) {
+		return 0, 0, 0, 0
+	}
+	return
+}
+
+func rectPath(x, y, w, h, rx, ry float64) vectorPath {
+	p := vectorPath{}
+	if w <= 0 || h <= 0 {
+		return p
+	}
+	if rx <= 0 && ry <= 0 {
+		p.moveTo(x, y)
+		p.lineTo(x+w, y)
+		p.lineTo(x+w, y+h)
+		p.lineTo(x, y+h)
+		p.close()
+		return p
+	}
+	if rx <= 0 {
+		rx = ry
+	}
+	if ry <= 0 {
+		ry = rx
+	}
+	rx, ry = math.Min(rx, w/2), math.Min(ry, h/2)
+	p.moveTo(x+rx, y)
+	p.lineTo(x+w-rx, y)
+	p.arcTo(rx, ry, 0, false, true, x+w, y+ry)
+	p.lineTo(x+w, y+h-ry)
+	p.arcTo(rx, ry, 0, false, true, x+w-rx, y+h)
+	p.lineTo(x+rx, y+h)
+	p.arcTo(rx, ry, 0, false, true, x, y+h-ry)
+	p.lineTo(x, y+ry)
+	p.arcTo(rx, ry, 0, false, true, x+rx, y)
+	p.close()
+	return p
+}
+
+func ellipsePath(cx, cy, rx, ry float64) vectorPath {
+	p := vectorPath{}
+	if rx <= 0 || ry <= 0 {
+		return p
+	}
+	p.moveTo(cx+rx, cy)
+	p.arcTo(rx, ry, 0, false, true, cx-rx, cy)
+	p.arcTo(rx, ry, 0, false, true, cx+rx, cy)
+	p.close()
+	return p
+}
+
+func (p vectorPath) current() (float64, float64) {
+	for i := len(p) - 1; i >= 0; i-- {
+		switch p[i].op {
+		case opMove, opLine:
+			return p[i].pts[0][0], p[i].pts[0][1]
+		case opQuad:
+			return p[i].pts[1][0], p[i].pts[1][1]
+		case opCubic:
+			return p[i].pts[2][0], p[i].pts[2][1]
+		}
+	}
+	return 0, 0
+}
+
+// arcTo adds an elliptical arc from the current point, approximated with cubic curves.
+func (p *vectorPath) arcTo(rx, ry, rotation float64, largeArc, sweep bool, x, y float64) {
+	x0, y0 := p.current()
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 || (x0 == x && y0 == y) {
+		p.lineTo(x, y)
+		return
+	}
+	phi := rotation * math.Pi / 180
+	sinPhi, cosPhi := math.Sincos(phi)
+	dx, dy := (x0-x)/2, (y0-y)/2
+	x1 := cosPhi*dx + sinPhi*dy
+	y1 := -sinPhi*dx + cosPhi*dy
+	// scale up the radii if they can't reach the end point
+	lambda := (x1*x1)/(rx*rx) + (y1*y1)/(ry*ry)
+	if lambda > 1 {
+		rx *= math.Sqrt(lambda)
+		ry *= math.Sqrt(lambda)
+	}
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := 0.0
+	if den != 0 && num > 0 {
+		coef = math.Sqrt(num / den)
+	}
+	if largeArc == sweep {
+		coef = -coef
+	}
+	cx1 := coef * rx * y1 / ry
+	cy1 := -coef * ry * x1 / rx
+	cx := cosPhi*cx1 - sinPhi*cy1 + (x0+x)/2
+	cy := sinPhi*cx1 + cosPhi*cy1 + (y0+y)/2
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		a := math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+		return a
+	}
+	theta1 := angle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := angle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	} else if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	segments := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(segments)
+	t := 4.0 / 3.0 * math.Tan(step/4)
+	point := func(theta float64) (float64, float64, float64, float64) {
+		sin, cos := math.Sincos(theta)
+		px := cx + rx*cos*cosPhi - ry*sin*sinPhi
+		py := cy + rx*cos*sinPhi + ry*sin*cosPhi
+		// derivative
+		dpx := -rx*sin*cosPhi - ry*cos*sinPhi
+		dpy := -rx*sin*sinPhi + ry*cos*cosPhi
+		return px, py, dpx, dpy
+	}
+	theta := theta1
+	for i := 0; i < segments; i++ {
+		ax, ay, adx, ady := point(theta)
+		bx, by, bdx, bdy := point(theta + step)
+		p.cubicTo(ax+t*adx, ay+t*ady, bx-t*bdx, by-t*bdy, bx, by)
+		theta += step
+	}
+}
+
+// parsePathData parses the "d" attribute of a path element.
+func parsePathData(d string) vectorPath {
+	p := vectorPath{}
+	s := pathScanner{data: d}
+	var cmd byte
+	var x, y, startX, startY float64
+	var lastCtrlX, lastCtrlY float64
+	var lastCmd byte
+	for {
+		s.skipSeparators()
+		if s.done() {
+			return p
+		}
+		if c := s.peek(); isPathCommand(c) {
+			cmd = c
+			s.pos++
+		} else if cmd == 0 {
+			return p
+		}
+		relative := cmd >= 'a' && cmd <= 'z'
+		ox, oy := 0.0, 0.0
+		if relative {
+			ox, oy = x, y
+		}
+		var ok bool
+		switch cmd {
+		case 'M', 'm':
+			var nx, ny float64
+			if nx, ny, ok = s.point(); !ok {
+				return p
+			}
+			x, y = nx+ox, ny+oy
+			startX, startY = x, y
+			p.moveTo(x, y)
+			// subsequent pairs are implicit line commands
+			if relative {
+				cmd = 'l'
+			} else {
+				cmd = 'L'
+			}
+		case 'L', 'l':
+			var nx, ny float64
+			if nx, ny, ok = s.point(); !ok {
+				return p
+			}
+			x, y = nx+ox, ny+oy
+			p.lineTo(x, y)
+		case 'H', 'h':
+			var nx float64
+			if nx, ok = s.number(); !ok {
+				return p
+			}
+			x = nx + ox
+			p.lineTo(x, y)
+		case 'V', 'v':
+			var ny float64
+			if ny, ok = s.number(); !ok {
+				return p
+			}
+			y = ny + oy
+			p.lineTo(x, y)
+		case 'C', 'c':
+			var pts [6]float64
+			if !s.numbers(pts[:]) {
+				return p
+			}
+			p.cubicTo(pts[0]+ox, pts[1]+oy, pts[2]+ox, pts[3]+oy, pts[4]+ox, pts[5]+oy)
+			lastCtrlX, lastCtrlY = pts[2]+ox, pts[3]+oy
+			x, y = pts[4]+ox, pts[5]+oy
+		case 'S', 's':
+			var pts [4]float64
+			if !s.numbers(pts[:]) {
+				return p
+			}
+			c1x, c1y := x, y
+			if lastCmd == 'C' || lastCmd == 'S' {
+				c1x, c1y = 2*x-lastCtrlX, 2*y-lastCtrlY
+			}
+			p.cubicTo(c1x, c1y, pts[0]+ox, pts[1]+oy, pts[2]+ox, pts[3]+oy)
+			lastCtrlX, lastCtrlY = pts[0]+ox, pts[1]+oy
+			x, y = pts[2]+ox, pts[3]+oy
+		case 'Q', 'q':
+			var pts [4]float64
+			if !s.numbers(pts[:]) {
+				return p
+			}
+			p.quadTo(pts[0]+ox, pts[1]+oy, pts[2]+ox, pts[3]+oy)
+			lastCtrlX, lastCtrlY = pts[0]+ox, pts[1]+oy
+			x, y = pts[2]+ox, pts[3]+oy
+		case 'T', 't':
+			var pts [2]float64
+			if !s.numbers(pts[:]) {
+				return p
+			}
+			cx, cy := x, y
+			if lastCmd == 'Q' || lastCmd == 'T' {
+				cx, cy = 2*x-lastCtrlX, 2*y-lastCtrlY
+			}
+			p.quadTo(cx, cy, pts[0]+ox, pts[1]+oy)
+			lastCtrlX, lastCtrlY = cx, cy
+			x, y = pts[0]+ox, pts[1]+oy
+		case 'A', 'a':
+			var radii [3]float64
+			if !s.numbers(radii[:]) {
+				return p
+			}
+			largeArc, ok1 := s.flag()
+			sweep, ok2 := s.flag()
+			nx, ny, ok3 := s.point()
+			if !ok1 || !ok2 || !ok3 {
+				return p
+			}
+			x, y = nx+ox, ny+oy
+			p.arcTo(radii[0], radii[1], radii[2], largeArc, sweep, x, y)
+		case 'Z', 'z':
+			p.close()
+			x, y = startX, startY
+		default:
+			return p
+		}
+		lastCmd = cmd &^ 0x20 // upper case
+	}
+}
+
+func isPathCommand(c byte) bool {
+	switch c &^ 0x20 {
+	case 'M', 'L', 'H', 'V', 'C', 'S', 'Q', 'T', 'A', 'Z':
+		return true
+	}
+	return false
+}
+
+type pathScanner struct {
+	data string
+	pos  int
+}
+
+func (s *pathScanner) done() bool { return s.pos >= len(s.data) }
+
+func (s *pathScanner) peek() byte { return s.data[s.pos] }
+
+func (s *pathScanner) skipSeparators() {
+	for !s.done() {
+		switch s.peek() {
+		case ' ', ',', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (s *pathScanner) number() (float64, bool) {
+	s.skipSeparators()
+	start := s.pos
+	if !s.done() && (s.peek() == '-' || s.peek() == '+') {
+		s.pos++
+	}
+	seenDot, seenExp := false, false
+scan:
+	for !s.done() {
+		c := s.peek()
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '.' && !seenDot && !seenExp:
+			seenDot = true
+		case (c == 'e' || c == 'E') && !seenExp:
+			seenExp = true
+			if s.pos+1 < len(s.data) && (s.data[s.pos+1] == '-' || s.data[s.pos+1] == '+') {
+				s.pos++
+			}
+		default:
+			break scan
+		}
+		s.pos++
+	}
+	if start == s.pos {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s.data[start:s.pos], 64)
+	return f, err == nil
+}
+
+func (s *pathScanner) numbers(dst []float64) bool {
+	for i := range dst {
+		v, ok := s.number()
+		if !ok {
+			return false
+		}
+		dst[i] = v
+	}
+	return true
+}
+
+func (s *pathScanner) point() (float64, float64, bool) {
+	var pt [2]float64
+	ok := s.numbers(pt[:])
+	return pt[0], pt[1], ok
+}
+
+// flag reads an arc flag, which may not be separated from the next value.
+func (s *pathScanner) flag() (bool, bool) {
+	s.skipSeparators()
+	if s.done() {
+		return false, false
+	}
+	c := s.peek()
+	if c != '0' && c != '1' {
+		return false, false
+	}
+	s.pos++
+	return c == '1', true
+}
@@ -0,0 +1,164 @@
+package spotlight
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// DefaultPrewarmSizes are the sizes rendered ahead of time, the ones social crawlers request.
+var DefaultPrewarmSizes = []string{DefaultSize, "twitter", "og"}
+
+// prewarmOutputs returns the outputs rendered when prewarming, in the default format.
+func (s *Service) prewarmOutputs() []output {
+	outputs := make([]output, 0, len(s.prewarmSizes))
+	for _, name := range s.prewarmSizes {
+		outputs = append(outputs, output{sizeName: name, Size: Sizes[name], format: s.defaultFormat()})
+	}
+	return outputs
+}
+
+// compileEntity fetches a claim, argument, comment or highlight and compiles its preview.
+func (s *Service) compileEntity(entityType string, id int64, out output) (string, error) {
+	switch entityType {
+	case "claim":
+		tmpl, err := s.templates.find(out.template("claim.svg"))
+		if err != nil {
+			return "", err
+		}
+		data, err := getClaim(s, id)
+		if err != nil {
+			return "", err
+		}
+		if data.Claim.ID == 0 {
+			return "", fmt.Errorf("claim %d not found", id)
+		}
+		return compileClaimPreview(tmpl, data.Claim), nil
+	case "argument":
+		tmpl, err := s.templates.find(out.template("argument.svg"))
+		if err != nil {
+			return "", err
+		}
+		data, err := getArgument(s, id)
+		if err != nil {
+			return "", err
+		}
+		if data.ClaimArgument.ID == 0 {
+			return "", fmt.Errorf("argument %d not found", id)
+		}
+		return compileArgumentPreview(tmpl, data.ClaimArgument)
+	case "comment":
+		tmpl, err := s.templates.find(out.template("highlight.svg"))
+		if err != nil {
+			return "", err
+		}
+		comment, err := getComment(s, id)
+		if err != nil {
+			return "", err
+		}
+		if comment == nil {
+			return "", fmt.Errorf("comment %d not found", id)
+		}
+		return compileCommentPreview(tmpl, *comment)
+	case "highlight":
+		tmpl, err := s.templates.find(out.template("highlight.svg"))
+		if err != nil {
+			return "", err
+		}
+		highlight, err := getHighlight(s, id)
+		if err != nil {
+			return "", err
+		}
+		if highlight == nil {
+			return "", fmt.Errorf("highlight %d not found", id)
+		}
+		user, err := highlightCreator(s, highlight)
+		if err != nil {
+			return "", err
+		}
+		return compileHighlightPreview(tmpl, highlight, user)
+	default:
+		return "", fmt.Errorf("can't prewarm %s previews", entityType)
+	}
+}
+
+// prewarmPreview renders the preview of an entity from fresh data into the cache, returning its cache key.
+func (s *Service) prewarmPreview(entityType string, id int64, out output) (string, error) {
+	entity := fmt.Sprintf("%s/%d", entityType, id)
+	generation, err := s.index.generation(entity)
+	if err != nil {
+		return "", err
+	}
+	preview, err := s.compileEntity(entityType, id, out)
+	if err != nil {
+		return "", err
+	}
+	preview = fitViewBox(preview, out.Width, out.Height)
+	key := cacheKey([]byte(preview), out.Width, out.Height, out.format)
+	if _, err := s.rasterizeCached(key, preview, out); err != nil {
+		return "", err
+	}
+	s.index.set(entity, generation, out.variant(), key)
+	return key, nil
+}
+
+// prewarm queues rendering the previews of a claim, argument, comment or highlight in the prewarmed sizes,
+// so the first crawler asking for them is served from the cache.
+func prewarm(s *Service, entityType string) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid ID passed.", http.StatusBadRequest)
+			return
+		}
+		entity := entityType + "/" + vars["id"]
+		jobs := make([]Job, 0)
+		for _, out := range s.prewarmOutputs() {
+			out := out
+			job, err := s.jobs.enqueue(JobPrewarm, entity, out.variant(), func() (string, error) {
+				return s.prewarmPreview(entityType, id, out)
+			})
+			if err != nil {
+				log.Println(err)
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+			jobs = append(jobs, job)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(jobs); err != nil {
+			log.Println(err)
+		}
+	}
+	return http.HandlerFunc(fn)
+}
+
+// jobStatus responds with a job of any kind.
+func jobStatus(s *Service) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		job, ok := s.jobs.get(mux.Vars(r)["id"])
+		if !ok {
+			http.Error(w, "Unknown job", http.StatusNotFound)
+			return
+		}
+		writeJob(w, http.StatusOK, job)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// jobsStats responds with the number of jobs of every kind by status.
+func jobsStats(s *Service) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(s.jobs.stats()); err != nil {
+			log.Println(err)
+		}
+	}
+	return http.HandlerFunc(fn)
+}
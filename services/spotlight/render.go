@@ -0,0 +1,794 @@
+package spotlight
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"math"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	// image formats supported in embedded images
+	_ "image/gif"
+	_ "image/jpeg"
+
+	"github.com/srwiley/rasterx"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+	_ "golang.org/x/image/webp"
+)
+
+// Renderers selectable with SPOTLIGHT_RENDERER.
+const (
+	RendererNative = "native"
+	RendererRSVG   = "rsvg"
+)
+
+// maxUseDepth bounds the nesting of use elements.
+const maxUseDepth = 8
+
+// rasterizer converts an SVG document to an image of the given size.
+type rasterizer interface {
+	Rasterize(svg []byte, width, height int) (image.Image, error)
+}
+
+// newRasterizer returns the renderer with the given name.
+// The native renderer falls back to rsvg-convert when it is installed.
+func newRasterizer(name string) (rasterizer, error) {
+	switch name {
+	case RendererRSVG:
+		return rsvgRasterizer{}, nil
+	case "", RendererNative:
+		native, err := newNativeRasterizer()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := exec.LookPath("rsvg-convert"); err == nil {
+			return fallbackRasterizer{primary: native, fallback: rsvgRasterizer{}}, nil
+		}
+		return native, nil
+	}
+	return nil, fmt.Errorf("unknown renderer %q", name)
+}
+
+// rsvgRasterizer shells out to rsvg-convert from librsvg.
+type rsvgRasterizer struct{}
+
+func (rsvgRasterizer) Rasterize(svg []byte, width, height int) (image.Image, error) {
+	cmd := exec.Command("rsvg-convert", "-f", "png", "--width", strconv.Itoa(width), "--height", strconv.Itoa(height))
+	cmd.Stdin = bytes.NewReader(svg)
+	buf := new(bytes.Buffer)
+	cmd.Stdout = buf
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	return png.Decode(buf)
+}
+
+type fallbackRasterizer struct {
+	primary  rasterizer
+	fallback rasterizer
+}
+
+func (r fallbackRasterizer) Rasterize(svg []byte, width, height int) (image.Image, error) {
+	img, err := r.primary.Rasterize(svg, width, height)
+	if err == nil {
+		return img, nil
+	}
+	log.Println("native rendering failed, falling back to rsvg-convert:", err)
+	return r.fallback.Rasterize(svg, width, height)
+}
+
+// nativeRasterizer renders the subset of SVG used by the spotlight templates in-process.
+// Patterns are drawn as a single tile and radial gradients ignore their focal point.
+type nativeRasterizer struct {
+	fonts *fontSet
+}
+
+func newNativeRasterizer() (*nativeRasterizer, error) {
+	fonts, err := bundledFonts()
+	if err != nil {
+		return nil, err
+	}
+	return &nativeRasterizer{fonts: fonts}, nil
+}
+
+// Rasterize stretches the view box of the document to width x height, like rsvg-convert does.
+func (r *nativeRasterizer) Rasterize(svg []byte, width, height int) (img image.Image, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("rendering svg: %v", r)
+		}
+	}()
+	root, ids, err := parseSVG(svg)
+	if err != nil {
+		return nil, err
+	}
+	c := &renderContext{fonts: r.fonts, ids: ids, width: width, height: height}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	c.drawChildren(dst, root, c.viewport(root))
+	if c.err != nil {
+		return nil, c.err
+	}
+	return dst, nil
+}
+
+type renderContext struct {
+	fonts         *fontSet
+	ids           map[string]*svgNode
+	width, height int
+	buf           sfnt.Buffer
+	depth         int
+	// err is the first element that couldn't be rendered, so the fallback renderer gets a chance
+	err error
+}
+
+func (c *renderContext) viewport(root *svgNode) rasterx.Matrix2D {
+	x, y := 0.0, 0.0
+	w, h := root.number("width", float64(c.width)), root.number("height", float64(c.height))
+	if vb := parseNumbers(root.attrs["viewBox"]); len(vb) == 4 && vb[2] > 0 && vb[3] > 0 {
+		x, y, w, h = vb[0], vb[1], vb[2], vb[3]
+	}
+	return rasterx.Identity.Scale(float64(c.width)/w, float64(c.height)/h).Translate(-x, -y)
+}
+
+func (c *renderContext) drawChildren(dst *image.RGBA, n *svgNode, m rasterx.Matrix2D) {
+	for _, child := range n.children {
+		c.draw(dst, child, m)
+	}
+}
+
+// draw renders an element, compositing it through a layer when it has opacity, a clip path or a mask.
+func (c *renderContext) draw(dst *image.RGBA, n *svgNode, m rasterx.Matrix2D) {
+	switch n.name {
+	case "defs", "mask", "clipPath", "pattern", "linearGradient", "radialGradient", "symbol",
+		"style", "title", "desc", "metadata":
+		return
+	}
+	if n.attrs["display"] == "none" {
+		return
+	}
+	if transform, ok := n.attrs["transform"]; ok {
+		m = m.Mult(parseTransform(transform))
+	}
+	opacity := parseOpacity(n.attrs["opacity"])
+	clipID, clipped := parseURLRef(n.attrs["clip-path"])
+	maskID, masked := parseURLRef(n.attrs["mask"])
+	if opacity >= 1 && !clipped && !masked {
+		c.drawElement(dst, n, m)
+		return
+	}
+	layer := image.NewRGBA(dst.Bounds())
+	c.drawElement(layer, n, m)
+	mask := image.NewAlpha(dst.Bounds())
+	fill := uint8(opacity*255 + 0.5)
+	for i := range mask.Pix {
+		mask.Pix[i] = fill
+	}
+	if clip := c.ids[clipID]; clipped && clip != nil {
+		multiplyAlpha(mask, c.clipCoverage(clip, m, c.bounds(n)))
+	}
+	if maskNode := c.ids[maskID]; masked && maskNode != nil {
+		multiplyAlpha(mask, c.maskCoverage(maskNode, m, c.bounds(n)))
+	}
+	draw.DrawMask(dst, dst.Bounds(), layer, image.ZP, mask, image.ZP, draw.Over)
+}
+
+func (c *renderContext) drawElement(dst *image.RGBA, n *svgNode, m rasterx.Matrix2D) {
+	switch n.name {
+	case "svg", "g", "a", "switch":
+		c.drawChildren(dst, n, m)
+	case "use":
+		target := c.ids[strings.TrimPrefix(n.attrs["href"], "#")]
+		if target == nil || c.depth >= maxUseDepth {
+			return
+		}
+		c.depth++
+		c.draw(dst, target, m.Translate(n.number("x", 0), n.number("y", 0)))
+		c.depth--
+	case "image":
+		c.drawImage(dst, n, m)
+	case "text":
+		c.drawText(dst, n, m)
+	default:
+		if p := shapePath(n); len(p) > 0 {
+			c.drawShape(dst, n, p, m)
+		}
+	}
+}
+
+// shapePath returns the outline of a basic shape or path element.
+func shapePath(n *svgNode) vectorPath {
+	switch n.name {
+	case "rect":
+		rx, ry := n.number("rx", -1), n.number("ry", -1)
+		return rectPath(n.number("x", 0), n.number("y", 0), n.number("width", 0), n.number("height", 0), rx, ry)
+	case "circle":
+		r := n.number("r", 0)
+		return ellipsePath(n.number("cx", 0), n.number("cy", 0), r, r)
+	case "ellipse":
+		return ellipsePath(n.number("cx", 0), n.number("cy", 0), n.number("rx", 0), n.number("ry", 0))
+	case "line":
+		p := vectorPath{}
+		p.moveTo(n.number("x1", 0), n.number("y1", 0))
+		p.lineTo(n.number("x2", 0), n.number("y2", 0))
+		return p
+	case "polyline", "polygon":
+		points := parseNumbers(n.attrs["points"])
+		p := vectorPath{}
+		for i := 0; i+1 < len(points); i += 2 {
+			if i == 0 {
+				p.moveTo(points[i], points[i+1])
+			} else {
+				p.lineTo(points[i], points[i+1])
+			}
+		}
+		if n.name == "polygon" && len(p) > 0 {
+			p.close()
+		}
+		return p
+	case "path":
+		return parsePathData(n.attrs["d"])
+	}
+	return nil
+}
+
+func (c *renderContext) drawShape(dst *image.RGBA, n *svgNode, p vectorPath, m rasterx.Matrix2D) {
+	if n.attr("visibility") == "hidden" {
+		return
+	}
+	fill := n.attr("fill")
+	if fill == "" {
+		fill = "black"
+	}
+	if fill != "none" {
+		coverage := c.fillCoverage(p, m, n.attr("fill-rule") != "evenodd")
+		c.paint(dst, coverage, fill, parseOpacity(n.attr("fill-opacity")), m, p)
+	}
+	if stroke := n.attr("stroke"); stroke != "" && stroke != "none" {
+		coverage := c.strokeCoverage(p, m, n)
+		c.paint(dst, coverage, stroke, parseOpacity(n.attr("stroke-opacity")), m, p)
+	}
+}
+
+func (c *renderContext) fillCoverage(p vectorPath, m rasterx.Matrix2D, nonZero bool) *image.Alpha {
+	scanner := newCoverageScanner(c.width, c.height)
+	filler := rasterx.NewFiller(c.width, c.height, scanner)
+	scanner.SetWinding(nonZero)
+	p.addTo(filler, m)
+	filler.Draw()
+	return scanner.mask
+}
+
+func (c *renderContext) strokeCoverage(p vectorPath, m rasterx.Matrix2D, n *svgNode) *image.Alpha {
+	width := 1.0
+	if v, err := parseLength(n.attr("stroke-width")); err == nil {
+		width = v
+	}
+	// strokes scale with the average scale factor of the transform
+	width *= math.Sqrt(math.Abs(m.A*m.D - m.B*m.C))
+	miterLimit := 4.0
+	if v, err := parseLength(n.attr("stroke-miterlimit")); err == nil && v >= 1 {
+		miterLimit = v
+	}
+	capFunc := rasterx.ButtCap
+	switch n.attr("stroke-linecap") {
+	case "round":
+		capFunc = rasterx.RoundCap
+	case "square":
+		capFunc = rasterx.SquareCap
+	}
+	join := rasterx.Miter
+	switch n.attr("stroke-linejoin") {
+	case "round":
+		join = rasterx.Round
+	case "bevel":
+		join = rasterx.Bevel
+	}
+	scanner := newCoverageScanner(c.width, c.height)
+	stroker := rasterx.NewStroker(c.width, c.height, scanner)
+	stroker.SetStroke(fixed.Int26_6(width*64), fixed.Int26_6(miterLimit*64), capFunc, nil, nil, join)
+	p.addTo(stroker, m)
+	stroker.Draw()
+	return scanner.mask
+}
+
+// paint composites a fill or stroke value through a coverage mask.
+func (c *renderContext) paint(dst *image.RGBA, coverage *image.Alpha, value string, opacity float64, m rasterx.Matrix2D, p vectorPath) {
+	r := coverage.Bounds().Intersect(dst.Bounds())
+	if r.Empty() {
+		return
+	}
+	var src image.Image
+	if id, ok := parseURLRef(value); ok {
+		ref := c.ids[id]
+		if ref == nil {
+			return
+		}
+		switch ref.name {
+		case "linearGradient", "radialGradient":
+			src = c.gradient(ref, m, p)
+		case "pattern":
+			src = c.pattern(ref, m, p)
+		}
+	} else if col, ok := parseColor(value); ok {
+		src = image.NewUniform(col)
+	}
+	if src == nil {
+		return
+	}
+	if opacity < 1 {
+		scaleAlpha(coverage, opacity)
+	}
+	draw.DrawMask(dst, r, src, r.Min, coverage, r.Min, draw.Over)
+}
+
+// bboxMatrix maps the unit square to the bounding box of a path, for objectBoundingBox units.
+func bboxMatrix(p vectorPath) rasterx.Matrix2D {
+	minX, minY, maxX, maxY := p.bounds()
+	return rasterx.Identity.Translate(minX, minY).Scale(maxX-minX, maxY-minY)
+}
+
+// bounds approximates the bounding box of an element in its own user space.
+func (c *renderContext) bounds(n *svgNode) vectorPath {
+	p := shapePath(n)
+	if len(p) > 0 || len(n.children) == 0 {
+		return p
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, child := range n.children {
+		b := c.bounds(child)
+		if len(b) == 0 {
+			continue
+		}
+		m := parseTransform(child.attrs["transform"])
+		x0, y0, x1, y1 := b.bounds()
+		for _, pt := range [][2]float64{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}} {
+			x, y := m.Transform(pt[0], pt[1])
+			minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+			minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+		}
+	}
+	if math.IsInf(minX, 1) {
+		return nil
+	}
+	return rectPath(minX, minY, maxX-minX, maxY-minY, 0, 0)
+}
+
+// href returns the element referenced with href or xlink:href.
+func (c *renderContext) href(n *svgNode) *svgNode {
+	ref := n.attrs["href"]
+	if !strings.HasPrefix(ref, "#") {
+		return nil
+	}
+	return c.ids[ref[1:]]
+}
+
+// clipCoverage returns the union of the shapes in a clipPath.
+func (c *renderContext) clipCoverage(clip *svgNode, m rasterx.Matrix2D, bbox vectorPath) *image.Alpha {
+	if clip.attrs["clipPathUnits"] == "objectBoundingBox" {
+		m = m.Mult(bboxMatrix(bbox))
+	}
+	coverage := image.NewAlpha(image.Rect(0, 0, c.width, c.height))
+	for _, child := range clip.children {
+		p := shapePath(child)
+		if len(p) == 0 {
+			continue
+		}
+		childM := m
+		if transform, ok := child.attrs["transform"]; ok {
+			childM = m.Mult(parseTransform(transform))
+		}
+		shape := c.fillCoverage(p, childM, child.attr("clip-rule") != "evenodd")
+		r := shape.Bounds()
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				a, b := uint32(coverage.AlphaAt(x, y).A), uint32(shape.AlphaAt(x, y).A)
+				coverage.SetAlpha(x, y, color.Alpha{A: uint8(a + b - a*b/255)})
+			}
+		}
+	}
+	return coverage
+}
+
+// maskCoverage returns the luminance of the mask content, limited to the mask region.
+func (c *renderContext) maskCoverage(mask *svgNode, m rasterx.Matrix2D, bbox vectorPath) *image.Alpha {
+	bm := bboxMatrix(bbox)
+	regionM := m
+	x, y, w, h := -0.1, -0.1, 1.2, 1.2
+	if mask.attrs["maskUnits"] == "userSpaceOnUse" {
+		x, y, w, h = mask.number("x", 0), mask.number("y", 0), mask.number("width", 0), mask.number("height", 0)
+	} else {
+		x, y = gradientNumber(mask.attrs["x"], x), gradientNumber(mask.attrs["y"], y)
+		w, h = gradientNumber(mask.attrs["width"], w), gradientNumber(mask.attrs["height"], h)
+		regionM = m.Mult(bm)
+	}
+	contentM := m
+	if mask.attrs["maskContentUnits"] == "objectBoundingBox" {
+		contentM = m.Mult(bm)
+	}
+	layer := image.NewRGBA(image.Rect(0, 0, c.width, c.height))
+	c.drawChildren(layer, mask, contentM)
+
+	coverage := image.NewAlpha(layer.Bounds())
+	for i := range coverage.Pix {
+		r, g, b := layer.Pix[i*4], layer.Pix[i*4+1], layer.Pix[i*4+2]
+		// premultiplied channels, so the luminance already includes the alpha
+		coverage.Pix[i] = uint8(0.2125*float64(r) + 0.7154*float64(g) + 0.0721*float64(b) + 0.5)
+	}
+	multiplyAlpha(coverage, c.fillCoverage(rectPath(x, y, w, h, 0, 0), regionM, true))
+	return coverage
+}
+
+// multiplyAlpha multiplies dst by src, treating pixels outside of src as transparent.
+func multiplyAlpha(dst, src *image.Alpha) {
+	r := dst.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			i := dst.PixOffset(x, y)
+			if !(image.Point{X: x, Y: y}).In(src.Rect) {
+				dst.Pix[i] = 0
+				continue
+			}
+			dst.Pix[i] = uint8(uint32(dst.Pix[i]) * uint32(src.Pix[src.PixOffset(x, y)]) / 255)
+		}
+	}
+}
+
+func scaleAlpha(a *image.Alpha, opacity float64) {
+	for i, v := range a.Pix {
+		a.Pix[i] = uint8(float64(v)*opacity + 0.5)
+	}
+}
+
+// gradientNumber parses a gradient coordinate, which may be a percentage.
+func gradientNumber(s string, def float64) float64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def
+	}
+	if strings.HasSuffix(s, "%") {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil {
+			return def
+		}
+		return v / 100
+	}
+	v, err := parseLength(s)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+type gradientStop struct {
+	offset float64
+	color  color.NRGBA
+}
+
+// gradientImage is an image painting a gradient in device space.
+type gradientImage struct {
+	radial bool
+	// inverse maps device to gradient space
+	inverse                rasterx.Matrix2D
+	x1, y1, x2, y2, cx, cy float64
+	r                      float64
+	spread                 string
+	stops                  []gradientStop
+}
+
+func (g *gradientImage) ColorModel() color.Model { return color.NRGBAModel }
+
+func (g *gradientImage) Bounds() image.Rectangle {
+	return image.Rect(-1e9, -1e9, 1e9, 1e9)
+}
+
+func (g *gradientImage) At(x, y int) color.Color {
+	px, py := g.inverse.Transform(float64(x)+0.5, float64(y)+0.5)
+	var t float64
+	if g.radial {
+		if g.r > 0 {
+			t = math.Hypot(px-g.cx, py-g.cy) / g.r
+		}
+	} else {
+		dx, dy := g.x2-g.x1, g.y2-g.y1
+		if l := dx*dx + dy*dy; l > 0 {
+			t = ((px-g.x1)*dx + (py-g.y1)*dy) / l
+		}
+	}
+	switch g.spread {
+	case "repeat":
+		t -= math.Floor(t)
+	case "reflect":
+		t = math.Mod(math.Abs(t), 2)
+		if t > 1 {
+			t = 2 - t
+		}
+	}
+	return g.colorAt(t)
+}
+
+func (g *gradientImage) colorAt(t float64) color.NRGBA {
+	if t <= g.stops[0].offset {
+		return g.stops[0].color
+	}
+	for i := 1; i < len(g.stops); i++ {
+		a, b := g.stops[i-1], g.stops[i]
+		if t > b.offset {
+			continue
+		}
+		f := 0.0
+		if b.offset > a.offset {
+			f = (t - a.offset) / (b.offset - a.offset)
+		}
+		lerp := func(u, v uint8) uint8 {
+			return uint8(float64(u) + (float64(v)-float64(u))*f + 0.5)
+		}
+		return color.NRGBA{R: lerp(a.color.R, b.color.R), G: lerp(a.color.G, b.color.G), B: lerp(a.color.B, b.color.B), A: lerp(a.color.A, b.color.A)}
+	}
+	return g.stops[len(g.stops)-1].color
+}
+
+// gradient builds the paint for a gradient element, following its href chain for missing attributes and stops.
+func (c *renderContext) gradient(n *svgNode, m rasterx.Matrix2D, p vectorPath) image.Image {
+	attr := func(name string) string {
+		for node, i := n, 0; node != nil && i < maxUseDepth; node, i = c.href(node), i+1 {
+			if v, ok := node.attrs[name]; ok {
+				return v
+			}
+		}
+		return ""
+	}
+	var stops []gradientStop
+	for node, i := n, 0; node != nil && len(stops) == 0 && i < maxUseDepth; node, i = c.href(node), i+1 {
+		for _, child := range node.children {
+			if child.name != "stop" {
+				continue
+			}
+			col, ok := parseColor(child.attrs["stop-color"])
+			if !ok {
+				col = color.NRGBA{A: 0xff}
+			}
+			col.A = uint8(float64(col.A) * parseOpacity(child.attrs["stop-opacity"]))
+			offset := math.Max(0, math.Min(1, gradientNumber(child.attrs["offset"], 0)))
+			if len(stops) > 0 && offset < stops[len(stops)-1].offset {
+				offset = stops[len(stops)-1].offset
+			}
+			stops = append(stops, gradientStop{offset: offset, color: col})
+		}
+	}
+	if len(stops) == 0 {
+		return nil
+	}
+	gm := m
+	if attr("gradientUnits") != "userSpaceOnUse" {
+		gm = gm.Mult(bboxMatrix(p))
+	}
+	gm = gm.Mult(parseTransform(attr("gradientTransform")))
+	g := &gradientImage{
+		radial:  n.name == "radialGradient",
+		inverse: gm.Invert(),
+		spread:  attr("spreadMethod"),
+		stops:   stops,
+	}
+	if g.radial {
+		g.cx, g.cy = gradientNumber(attr("cx"), 0.5), gradientNumber(attr("cy"), 0.5)
+		g.r = gradientNumber(attr("r"), 0.5)
+	} else {
+		g.x1, g.y1 = gradientNumber(attr("x1"), 0), gradientNumber(attr("y1"), 0)
+		g.x2, g.y2 = gradientNumber(attr("x2"), 1), gradientNumber(attr("y2"), 0)
+	}
+	return g
+}
+
+// pattern renders a single tile of a pattern into a layer.
+func (c *renderContext) pattern(n *svgNode, m rasterx.Matrix2D, p vectorPath) image.Image {
+	minX, minY, maxX, maxY := p.bounds()
+	w, h := maxX-minX, maxY-minY
+	pm := m.Mult(parseTransform(n.attrs["patternTransform"]))
+	x, y := n.number("x", 0), n.number("y", 0)
+	if n.attrs["patternUnits"] != "userSpaceOnUse" {
+		x, y = minX+gradientNumber(n.attrs["x"], 0)*w, minY+gradientNumber(n.attrs["y"], 0)*h
+	}
+	pm = pm.Translate(x, y)
+	if n.attrs["patternContentUnits"] == "objectBoundingBox" {
+		pm = pm.Scale(w, h)
+	}
+	layer := image.NewRGBA(image.Rect(0, 0, c.width, c.height))
+	c.drawChildren(layer, n, pm)
+	return layer
+}
+
+// drawImage draws an image element embedded as a data URI.
+func (c *renderContext) drawImage(dst *image.RGBA, n *svgNode, m rasterx.Matrix2D) {
+	img, err := decodeDataURI(n.attrs["href"])
+	if err != nil {
+		if c.err == nil {
+			c.err = fmt.Errorf("loading image: %s", err)
+		}
+		return
+	}
+	iw, ih := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	x, y := n.number("x", 0), n.number("y", 0)
+	w, h := n.number("width", iw), n.number("height", ih)
+	if iw == 0 || ih == 0 || w <= 0 || h <= 0 {
+		return
+	}
+	sx, sy := w/iw, h/ih
+	target := dst
+	switch aspect := n.attrs["preserveAspectRatio"]; {
+	case aspect == "none":
+	case strings.HasSuffix(aspect, "slice"):
+		// xMidYMid slice, the image covers the viewport and is clipped to it
+		x0, y0 := m.Transform(x, y)
+		x1, y1 := m.Transform(x+w, y+h)
+		clip := image.Rect(int(math.Floor(math.Min(x0, x1))), int(math.Floor(math.Min(y0, y1))),
+			int(math.Ceil(math.Max(x0, x1))), int(math.Ceil(math.Max(y0, y1))))
+		target = dst.SubImage(clip).(*image.RGBA)
+		s := math.Max(sx, sy)
+		x, y = x+(w-iw*s)/2, y+(h-ih*s)/2
+		sx, sy = s, s
+	default:
+		// xMidYMid meet
+		s := math.Min(sx, sy)
+		x, y = x+(w-iw*s)/2, y+(h-ih*s)/2
+		sx, sy = s, s
+	}
+	im := m.Translate(x, y).Scale(sx, sy).Translate(-float64(img.Bounds().Min.X), -float64(img.Bounds().Min.Y))
+	xdraw.BiLinear.Transform(target, f64.Aff3{im.A, im.C, im.E, im.B, im.D, im.F}, img, img.Bounds(), xdraw.Over, nil)
+}
+
+func decodeDataURI(uri string) (image.Image, error) {
+	if !strings.HasPrefix(uri, "data:") {
+		return nil, fmt.Errorf("unsupported image reference")
+	}
+	comma := strings.IndexByte(uri, ',')
+	if comma < 0 {
+		return nil, fmt.Errorf("invalid data uri")
+	}
+	header, payload := uri[5:comma], uri[comma+1:]
+	var data []byte
+	if strings.HasSuffix(header, ";base64") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(payload), ""))
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	} else {
+		unescaped, err := url.PathUnescape(payload)
+		if err != nil {
+			return nil, err
+		}
+		data = []byte(unescaped)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// textRun is a span of text sharing the same style.
+type textRun struct {
+	node *svgNode
+	text string
+}
+
+func (c *renderContext) drawText(dst *image.RGBA, n *svgNode, m rasterx.Matrix2D) {
+	x, y := n.number("x", 0), n.number("y", 0)
+	runs := []textRun{{node: n, text: n.text}}
+	for _, child := range n.children {
+		if child.name == "tspan" || child.name == "#text" {
+			runs = append(runs, textRun{node: child, text: child.text})
+		}
+	}
+	for _, run := range runs {
+		if strings.TrimSpace(run.text) == "" {
+			continue
+		}
+		_, hasX := run.node.attrs["x"]
+		if hasX {
+			x = parseNumbers(run.node.attrs["x"])[0]
+		}
+		if _, ok := run.node.attrs["y"]; ok {
+			y = parseNumbers(run.node.attrs["y"])[0]
+		}
+		x += c.drawTextRun(dst, run, x, y, m, hasX || run.node == n)
+	}
+}
+
+// drawTextRun draws a run with its baseline starting at x, y and returns its advance.
+func (c *renderContext) drawTextRun(dst *image.RGBA, run textRun, x, y float64, m rasterx.Matrix2D, anchored bool) float64 {
+	n := run.node
+	text := run.text
+	if n.attr("xml:space") == "preserve" || strings.HasPrefix(n.attr("white-space"), "pre") {
+		text = strings.NewReplacer("\n", " ", "\r", " ", "\t", " ").Replace(text)
+	} else {
+		text = strings.Join(strings.Fields(text), " ")
+	}
+	text = visualOrder(text)
+	size := 16.0
+	if v, err := parseLength(n.attr("font-size")); err == nil {
+		size = v
+	}
+	spacing := 0.0
+	if ls := n.attr("letter-spacing"); strings.HasSuffix(ls, "em") {
+		if v, err := strconv.ParseFloat(strings.TrimSuffix(ls, "em"), 64); err == nil {
+			spacing = v * size
+		}
+	} else if v, err := parseLength(ls); err == nil {
+		spacing = v
+	}
+	style := n.attr("font-style")
+	face := c.fonts.match(n.attr("font-family"), parseFontWeight(n.attr("font-weight")), style == "italic" || style == "oblique")
+	if face == nil {
+		return 0
+	}
+
+	p, advance := c.layoutText(face, text, size, spacing)
+	if anchored {
+		switch n.attr("text-anchor") {
+		case "middle":
+			x -= advance / 2
+		case "end":
+			x -= advance
+		}
+	}
+	if n.attr("visibility") != "hidden" {
+		fill := n.attr("fill")
+		if fill == "" {
+			fill = "black"
+		}
+		if fill != "none" {
+			tm := m.Translate(x, y)
+			c.paint(dst, c.fillCoverage(p, tm, true), fill, parseOpacity(n.attr("fill-opacity")), tm, p)
+		}
+	}
+	return advance
+}
+
+// layoutText returns the glyph outlines of a line of text with its origin on the baseline, and its advance.
+func (c *renderContext) layoutText(face *fontFace, text string, size, spacing float64) (vectorPath, float64) {
+	ppem := fixed.Int26_6(size * 64)
+	p := vectorPath{}
+	advance := c.fonts.shape(&c.buf, face, text, size, spacing, func(glyphFace *fontFace, idx sfnt.GlyphIndex, pen float64) {
+		segments, err := glyphFace.font.LoadGlyph(&c.buf, idx, ppem, nil)
+		if err != nil {
+			return
+		}
+		for _, s := range segments {
+			pt := func(i int) (float64, float64) {
+				return pen + float64(s.Args[i].X)/64, float64(s.Args[i].Y) / 64
+			}
+			switch s.Op {
+			case sfnt.SegmentOpMoveTo:
+				if len(p) > 0 {
+					p.close()
+				}
+				p.moveTo(pt(0))
+			case sfnt.SegmentOpLineTo:
+				p.lineTo(pt(0))
+			case sfnt.SegmentOpQuadTo:
+				x1, y1 := pt(0)
+				x, y := pt(1)
+				p.quadTo(x1, y1, x, y)
+			case sfnt.SegmentOpCubeTo:
+				x1, y1 := pt(0)
+				x2, y2 := pt(1)
+				x, y := pt(2)
+				p.cubicTo(x1, y1, x2, y2, x, y)
+			}
+		}
+		if len(p) > 0 {
+			p.close()
+		}
+	})
+	return p, advance
+}
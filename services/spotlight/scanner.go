@@ -0,0 +1,188 @@
+package spotlight
+
+import (
+	"image"
+	"math"
+	"sort"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// subsamples is the number of scanlines sampled per pixel row for anti-aliasing.
+const subsamples = 5
+
+type edge struct {
+	x0, y0, x1, y1 float64
+	dir            int
+}
+
+type crossing struct {
+	x   float64
+	dir int
+}
+
+// coverageScanner is a rasterx.Scanner computing an anti-aliased coverage mask.
+// Unlike the default scanner it supports both the nonzero and the even-odd fill rules.
+type coverageScanner struct {
+	width, height int
+	nonZero       bool
+	edges         []edge
+	last          [2]float64
+	minX, minY    float64
+	maxX, maxY    float64
+
+	// mask is the coverage computed by the last call to Draw, bounded by the path extent.
+	mask *image.Alpha
+}
+
+func newCoverageScanner(width, height int) *coverageScanner {
+	s := &coverageScanner{nonZero: true}
+	s.SetBounds(width, height)
+	s.Clear()
+	return s
+}
+
+// Start moves the pen without closing the previous outline: the stroker
+// emits outlines in pieces which are only closed once combined.
+func (s *coverageScanner) Start(a fixed.Point26_6) {
+	s.last = [2]float64{float64(a.X) / 64, float64(a.Y) / 64}
+	s.extend(s.last)
+}
+
+func (s *coverageScanner) Line(b fixed.Point26_6) {
+	p := [2]float64{float64(b.X) / 64, float64(b.Y) / 64}
+	s.addEdge(s.last, p)
+	s.last = p
+	s.extend(p)
+}
+
+func (s *coverageScanner) addEdge(a, b [2]float64) {
+	if a[1] == b[1] {
+		return
+	}
+	if a[1] < b[1] {
+		s.edges = append(s.edges, edge{x0: a[0], y0: a[1], x1: b[0], y1: b[1], dir: 1})
+	} else {
+		s.edges = append(s.edges, edge{x0: b[0], y0: b[1], x1: a[0], y1: a[1], dir: -1})
+	}
+}
+
+func (s *coverageScanner) extend(p [2]float64) {
+	s.minX, s.maxX = math.Min(s.minX, p[0]), math.Max(s.maxX, p[0])
+	s.minY, s.maxY = math.Min(s.minY, p[1]), math.Max(s.maxY, p[1])
+}
+
+func (s *coverageScanner) GetPathExtent() fixed.Rectangle26_6 {
+	return fixed.Rectangle26_6{
+		Min: fixed.Point26_6{X: fixed.Int26_6(s.minX * 64), Y: fixed.Int26_6(s.minY * 64)},
+		Max: fixed.Point26_6{X: fixed.Int26_6(s.maxX * 64), Y: fixed.Int26_6(s.maxY * 64)},
+	}
+}
+
+func (s *coverageScanner) SetBounds(width, height int) {
+	s.width, s.height = width, height
+}
+
+// SetColor is a no-op, the coverage is painted by the caller.
+func (s *coverageScanner) SetColor(color interface{}) {}
+
+func (s *coverageScanner) SetWinding(useNonZeroWinding bool) {
+	s.nonZero = useNonZeroWinding
+}
+
+// SetClip is a no-op, clipping is applied by the caller.
+func (s *coverageScanner) SetClip(rect image.Rectangle) {}
+
+func (s *coverageScanner) Clear() {
+	s.edges = s.edges[:0]
+	s.minX, s.minY = math.Inf(1), math.Inf(1)
+	s.maxX, s.maxY = math.Inf(-1), math.Inf(-1)
+}
+
+// Draw computes the coverage of the accumulated path into mask.
+func (s *coverageScanner) Draw() {
+	s.mask = image.NewAlpha(image.Rectangle{})
+	if len(s.edges) == 0 {
+		return
+	}
+	sort.Slice(s.edges, func(i, j int) bool { return s.edges[i].y0 < s.edges[j].y0 })
+
+	top := int(math.Max(0, math.Floor(s.minY)))
+	bottom := int(math.Min(float64(s.height), math.Ceil(s.maxY)))
+	left := int(math.Max(0, math.Floor(s.minX)))
+	right := int(math.Min(float64(s.width), math.Ceil(s.maxX)))
+	if left >= right || top >= bottom {
+		return
+	}
+	// the mask only covers the extent of the path
+	s.mask = image.NewAlpha(image.Rect(left, top, right, bottom))
+	acc := make([]float64, right-left+1)
+	var active []edge
+	var crossings []crossing
+	next := 0
+	for y := top; y < bottom; y++ {
+		for i := range acc {
+			acc[i] = 0
+		}
+		for sub := 0; sub < subsamples; sub++ {
+			sy := float64(y) + (float64(sub)+0.5)/subsamples
+			// update the active edge list
+			for next < len(s.edges) && s.edges[next].y0 <= sy {
+				active = append(active, s.edges[next])
+				next++
+			}
+			crossings = crossings[:0]
+			kept := active[:0]
+			for _, e := range active {
+				if e.y1 <= sy {
+					continue
+				}
+				kept = append(kept, e)
+				if e.y0 <= sy {
+					x := e.x0 + (sy-e.y0)*(e.x1-e.x0)/(e.y1-e.y0)
+					crossings = append(crossings, crossing{x: x, dir: e.dir})
+				}
+			}
+			active = kept
+			sort.Slice(crossings, func(i, j int) bool { return crossings[i].x < crossings[j].x })
+			winding := 0
+			for i, c := range crossings {
+				winding += c.dir
+				inside := winding != 0
+				if !s.nonZero {
+					inside = winding%2 != 0
+				}
+				if inside && i+1 < len(crossings) {
+					s.addSpan(acc, left, c.x, crossings[i+1].x)
+				}
+			}
+		}
+		offset := s.mask.PixOffset(left, y)
+		for x := left; x < right; x++ {
+			v := acc[x-left] / subsamples
+			if v > 1 {
+				v = 1
+			}
+			s.mask.Pix[offset+x-left] = uint8(v*255 + 0.5)
+		}
+	}
+}
+
+// addSpan adds the horizontal coverage of [x0, x1) on a single scanline.
+func (s *coverageScanner) addSpan(acc []float64, left int, x0, x1 float64) {
+	x0 = math.Max(x0, float64(left))
+	x1 = math.Min(x1, float64(left+len(acc)-1))
+	if x1 <= x0 {
+		return
+	}
+	i0, i1 := int(x0), int(x1)
+	if i0 == i1 {
+		acc[i0-left] += x1 - x0
+		return
+	}
+	acc[i0-left] += float64(i0+1) - x0
+	for i := i0 + 1; i < i1; i++ {
+		acc[i-left]++
+	}
+	acc[i1-left] += x1 - float64(i1)
+}
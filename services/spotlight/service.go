@@ -3,16 +3,16 @@ package spotlight
 import (
 	"bytes"
 	"context"
+	"crypto/subtle"
 	"encoding/base64"
+	"errors"
+	"fmt"
 	"html"
-	"image/jpeg"
-	"image/png"
-	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
-	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
@@ -22,52 +22,150 @@ import (
 
 	"github.com/TruStory/octopus/services/truapi/db"
 
-	"github.com/gobuffalo/packr/v2"
 	"github.com/gorilla/mux"
 	"github.com/machinebox/graphql"
 	stripmd "github.com/writeas/go-strip-markdown"
 
 	truCtx "github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/logging"
+	"github.com/TruStory/octopus/services/truapi/sigauth"
+	"github.com/TruStory/octopus/services/truapi/telemetry"
 )
 
 var regexMention = regexp.MustCompile("(cosmos|tru)([a-z0-9]{4})[a-z0-9]{31}([a-z0-9]{4})")
 
-const (
-	WORDS_PER_LINE_CLAIM     = 7
-	WORDS_PER_LINE_ARGUMENT  = 10
-	WORDS_PER_LINE_COMMENT   = 10
-	WORDS_PER_LINE_HIGHLIGHT = 10
+// regexDataURI matches base64 encoded data URIs like data:image/png;base64,iVBOR...
+var regexDataURI = regexp.MustCompile(`^data:([a-z]+/[a-z0-9.+-]+);base64,([A-Za-z0-9+/=]+)$`)
 
+const (
+	// MAX_CHARS_PER_LINE is used by templates that don't declare their layout
 	MAX_CHARS_PER_LINE = 40
 
-	BODY_LINES_CLAIM     = 3
-	BODY_LINES_ARGUMENT  = 4
-	BODY_LINES_COMMENT   = 4
-	BODY_LINES_HIGHLIGHT = 4
+	// TOP_COMMUNITIES is the number of communities shown on a profile
+	TOP_COMMUNITIES = 3
+
+	PREVIEW_WIDTH  = 1920
+	PREVIEW_HEIGHT = 1080
 )
 
+// Config configures the spotlight service.
+type Config struct {
+	Port            string
+	GraphQLEndpoint string
+	JPEGEnabled     bool
+	// Renderer is either RendererNative or RendererRSVG
+	Renderer string
+	// Cache stores rendered previews, nil disables caching
+	Cache Cache
+	// CacheIndexTTL is how long a preview is served without checking its claim, argument or comment again
+	CacheIndexTTL time.Duration
+	// Secret verifies the signature of invalidation requests, they are disabled when empty
+	Secret string
+	// MetricsSecret is the Metrics-Secret header /metrics and /jobs require, they aren't served without it
+	MetricsSecret string
+	Database      truCtx.Config
+	// TemplatesDir overrides the bundled templates, they are reloaded when modified
+	TemplatesDir string
+	// Dev enables the template authoring endpoints under /dev
+	Dev bool
+	// Workers is the number of previews prewarmed at once, SnippetWorkers the number of snippets rendered at once
+	Workers        int
+	SnippetWorkers int
+	// Attempts is the number of times a background render runs before it fails
+	Attempts int
+	// PrewarmSizes are the sizes rendered when prewarming, DefaultPrewarmSizes when empty
+	PrewarmSizes []string
+}
+
 type Service struct {
 	port          string
 	router        *mux.Router
 	graphqlClient *graphql.Client
 	dbClient      *db.Client
 	jpeg          bool
+	rasterizer    rasterizer
+	cache         Cache
+	index         *previewIndex
+	templates     *templateStore
+	jobs          *jobQueue
+	prewarmSizes  []string
+	verifier      *sigauth.Verifier
+	metricsSecret string
+	dev           bool
 }
 
-func NewService(port, endpoint string, jpeg bool, config truCtx.Config) *Service {
-	return &Service{
-		port:          port,
+// NewService creates the spotlight service.
+func NewService(config Config) (*Service, error) {
+	r, err := newRasterizer(config.Renderer)
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{
+		port:          config.Port,
 		router:        mux.NewRouter(),
-		graphqlClient: graphql.NewClient(endpoint),
-		dbClient:      db.NewDBClient(config),
-		jpeg:          jpeg,
+		graphqlClient: graphql.NewClient(config.GraphQLEndpoint),
+		dbClient:      db.NewDBClient(config.Database),
+		jpeg:          config.JPEGEnabled,
+		rasterizer:    r,
+		cache:         config.Cache,
+		index:         newPreviewIndex(config.Cache, config.CacheIndexTTL),
+		templates:     newTemplateStore(config.TemplatesDir),
+		jobs:          newJobQueue(config.Attempts),
+		prewarmSizes:  config.PrewarmSizes,
+		metricsSecret: config.MetricsSecret,
+		dev:           config.Dev,
+	}
+	s.dbClient.RegisterPoolMetrics()
+	if len(s.prewarmSizes) == 0 {
+		s.prewarmSizes = DefaultPrewarmSizes
+	}
+	for _, name := range s.prewarmSizes {
+		if _, ok := Sizes[name]; !ok {
+			return nil, fmt.Errorf("unknown prewarm size %q", name)
+		}
 	}
+	s.jobs.start(JobPrewarm, config.Workers)
+	s.jobs.start(JobSnippet, config.SnippetWorkers)
+	// previews compiled from the previous version of a template are stale
+	s.templates.onReload = func(name string) {
+		log.Printf("reloaded template %s", name)
+		s.index.clear()
+	}
+	if config.Secret != "" {
+		s.verifier = sigauth.NewVerifier(config.Secret, sigauth.DefaultMaxSkew)
+	}
+	if config.MetricsSecret == "" {
+		log.Println("no metrics secret, /metrics and /jobs won't be served")
+	}
+	return s, nil
 }
+
 func (s *Service) Run() {
+	s.router.Use(telemetry.Middleware("spotlight"))
+	s.router.Use(logging.Middleware(logging.New("spotlight")))
+	s.router.Handle("/metrics", metricsAuth(s.metricsSecret, telemetry.Handler())).Methods(http.MethodGet)
+	if s.verifier != nil {
+		for _, entityType := range []string{"claim", "argument", "comment", "highlight"} {
+			path := fmt.Sprintf("/%s/{id:[0-9]+}/spotlight", entityType)
+			s.router.Handle(path, s.verifier.Middleware(invalidate(s, entityType))).Methods(http.MethodDelete)
+			s.router.Handle(path, s.verifier.Middleware(prewarm(s, entityType))).Methods(http.MethodPost)
+		}
+	}
 	s.router.Handle("/claim/{id:[0-9]+}/spotlight", renderClaim(s))
 	s.router.Handle("/argument/{id:[0-9]+}/spotlight", renderArgument(s))
 	s.router.Handle("/comment/{id:[0-9]+}/spotlight", renderComment(s))
 	s.router.Handle("/highlight/{id:[0-9]+}/spotlight", renderHighlight(s))
+	s.router.Handle("/user/{address:[a-z0-9]+}/spotlight", renderProfile(s))
+	s.router.Handle("/community/{id:[a-z0-9-]+}/spotlight", renderCommunity(s))
+	s.router.Handle("/claim/{id:[0-9]+}/snippet", createSnippet(s)).Methods(http.MethodPost)
+	s.router.Handle("/snippets/{id:[0-9a-f]+}", snippetStatus(s)).Methods(http.MethodGet)
+	s.router.Handle("/snippets/{id:[0-9a-f]+}/download", downloadSnippet(s)).Methods(http.MethodGet)
+	s.router.Handle("/jobs", metricsAuth(s.metricsSecret, jobsStats(s))).Methods(http.MethodGet)
+	s.router.Handle("/jobs/{id:[0-9a-f]+}", metricsAuth(s.metricsSecret, jobStatus(s))).Methods(http.MethodGet)
+	if s.dev {
+		s.router.Handle("/dev/templates", listTemplates(s)).Methods(http.MethodGet)
+		s.router.Handle("/dev/templates/{name}", renderTemplate(s)).Methods(http.MethodGet, http.MethodPost)
+	}
 	http.Handle("/", s.router)
 	err := http.ListenAndServe(":"+s.port, nil)
 	if err != nil {
@@ -76,45 +174,168 @@ func (s *Service) Run() {
 	}
 }
 
-func render(preview string, w http.ResponseWriter, jpegEnabled bool) {
-	cmd := exec.Command("rsvg-convert", "-f", "png", "--width", "1920", "--height", "1080")
-	contentType := "image/png"
-	if jpegEnabled {
-		contentType = "image/jpeg"
+// metricsAuth serves the handler to requests with the metrics secret in their Metrics-Secret header,
+// like pushd and truapi, and to none when the secret is empty.
+func metricsAuth(secret string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if secret == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("Metrics-Secret")), []byte(secret)) != 1 {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// defaultFormat is the format served when no format is requested.
+func (s *Service) defaultFormat() string {
+	if s.jpeg {
+		return FormatJPEG
+	}
+	return FormatPNG
+}
+
+// serveIndexed responds with the preview last rendered for the entity when it's still cached.
+// Otherwise it returns the generation of the entity the preview rendered next is indexed under.
+func (s *Service) serveIndexed(w http.ResponseWriter, r *http.Request, entity string, out output) (string, bool) {
+	key, generation, ok := s.index.get(entity, out.variant())
+	if !ok || s.cache == nil {
+		return generation, false
+	}
+	if etagMatches(r, key) {
+		writeNotModified(w, key)
+		return generation, true
 	}
-	w.Header().Add("Content-Type", contentType)
-	cmd.Stdin = strings.NewReader(preview)
-	buf := new(bytes.Buffer)
-	cmd.Stdout = buf
+	data, err := s.cache.Get(key)
+	if err != nil {
+		log.Println(err)
+		return generation, false
+	}
+	if data == nil {
+		return generation, false
+	}
+	s.write(w, out, key, data)
+	return generation, true
+}
 
-	err := cmd.Run()
+// render rasterizes a compiled preview, unless the same preview was already cached.
+func (s *Service) render(w http.ResponseWriter, r *http.Request, entity, generation, preview string, out output) {
+	preview = fitViewBox(preview, out.Width, out.Height)
+	key := cacheKey([]byte(preview), out.Width, out.Height, out.format)
+	if etagMatches(r, key) {
+		s.index.set(entity, generation, out.variant(), key)
+		writeNotModified(w, key)
+		return
+	}
+	data, err := s.rasterizeCached(key, preview, out)
 	if err != nil {
+		log.Println(err)
 		http.Error(w, "URL Preview cannot be generated", http.StatusInternalServerError)
 		return
 	}
-	if !jpegEnabled {
-		_, err := io.Copy(w, buf)
+	if s.cache != nil {
+		s.index.set(entity, generation, out.variant(), key)
+	}
+	s.write(w, out, key, data)
+}
+
+// rasterizeCached returns the cached preview with the given key, rasterizing and caching it when missing.
+func (s *Service) rasterizeCached(key, preview string, out output) ([]byte, error) {
+	if s.cache != nil {
+		data, err := s.cache.Get(key)
 		if err != nil {
-			http.Error(w, "URL Preview cannot be generated", http.StatusInternalServerError)
+			log.Println(err)
+		}
+		if data != nil {
+			return data, nil
 		}
-		return
 	}
+	img, err := s.rasterizer.Rasterize([]byte(preview), out.Width, out.Height)
+	if err != nil {
+		return nil, err
+	}
+	data, err := out.encode(img)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache != nil {
+		if err := s.cache.Put(key, data, out.contentType()); err != nil {
+			log.Println(err)
+		}
+	}
+	return data, nil
+}
 
-	pngImage, err := png.Decode(buf)
+func (s *Service) write(w http.ResponseWriter, out output, key string, data []byte) {
+	w.Header().Set("Content-Type", out.contentType())
+	w.Header().Set("ETag", etag(key))
+	w.Header().Set("Cache-Control", "public, no-cache")
+	_, err := w.Write(data)
 	if err != nil {
-		http.Error(w, "PNG can't be decoded", http.StatusInternalServerError)
-		return
+		log.Println(err)
+	}
+}
+
+func etag(key string) string {
+	return `"` + strings.SplitN(key, ".", 2)[0] + `"`
+}
+
+// etagMatches checks the If-None-Match header of a conditional request.
+func etagMatches(r *http.Request, key string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	tag := etag(key)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == tag || candidate == "*" {
+			return true
+		}
 	}
+	return false
+}
 
-	if err := jpeg.Encode(w, pngImage, nil); err != nil {
-		http.Error(w, "Can't encode to JPEG", http.StatusInternalServerError)
-		return
+func writeNotModified(w http.ResponseWriter, key string) {
+	w.Header().Set("ETag", etag(key))
+	w.Header().Set("Cache-Control", "public, no-cache")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// invalidate forgets the previews rendered for a claim, argument, comment or highlight
+// so the next request to any replica renders them from fresh data.
+func invalidate(s *Service, entityType string) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		err := s.index.invalidate(entityType + "/" + vars["id"])
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Spotlight invalidation error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
 	}
+	return http.HandlerFunc(fn)
 }
 
 func renderClaim(s *Service) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
+		entity := "claim/" + vars["id"]
+		out, err := parseOutput(r, s.defaultFormat())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tmpl, err := s.templates.find(out.template("claim.svg"))
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Claim URL Preview error: svg file not found", http.StatusInternalServerError)
+			return
+		}
+		generation, served := s.serveIndexed(w, r, entity, out)
+		if served {
+			return
+		}
 		claimID, err := strconv.ParseInt(vars["id"], 10, 64)
 		if err != nil {
 			log.Println(err)
@@ -128,15 +349,8 @@ func renderClaim(s *Service) http.Handler {
 			return
 		}
 
-		box := packr.New("Templates", "./templates")
-		rawPreview, err := box.Find("claim.svg")
-		if err != nil {
-			log.Println(err)
-			http.Error(w, "Claim URL Preview error: svg file not found", http.StatusInternalServerError)
-			return
-		}
-		compiledPreview := compileClaimPreview(rawPreview, data.Claim)
-		render(compiledPreview, w, s.jpeg)
+		compiledPreview := compileClaimPreview(tmpl, data.Claim)
+		s.render(w, r, entity, generation, compiledPreview, out)
 	}
 	return http.HandlerFunc(fn)
 }
@@ -144,6 +358,22 @@ func renderClaim(s *Service) http.Handler {
 func renderHighlight(s *Service) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
+		entity := "highlight/" + vars["id"]
+		out, err := parseOutput(r, s.defaultFormat())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tmpl, err := s.templates.find(out.template("highlight.svg"))
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Highlight URL Preview error, svg file not found", http.StatusInternalServerError)
+			return
+		}
+		generation, served := s.serveIndexed(w, r, entity, out)
+		if served {
+			return
+		}
 		highlightID, err := strconv.ParseInt(vars["id"], 10, 64)
 		if err != nil {
 			log.Println(err)
@@ -161,44 +391,20 @@ func renderHighlight(s *Service) http.Handler {
 			http.Error(w, "Invalid highlight ID passed.", http.StatusInternalServerError)
 			return
 		}
-		var user UserObject
-		switch highlight.HighlightableType {
-		case "argument":
-			argument, err := getArgument(s, highlight.HighlightableID)
-			if err != nil {
-				log.Println(err)
-				http.Error(w, "Highlight URL Preview error, argument not found", http.StatusInternalServerError)
-				return
-			}
-			user = argument.ClaimArgument.Creator
-		case "comment":
-			comment, err := getComment(s, highlight.HighlightableID)
-			if err != nil {
-				log.Println(err)
-				http.Error(w, "Highlight URL Preview error, comment not found", http.StatusInternalServerError)
-				return
-			}
-			user = comment.Creator
-		default:
-			log.Println("invalid highlightable type")
-			http.Error(w, "", http.StatusInternalServerError)
-			return
-		}
-
-		box := packr.New("Templates", "./templates")
-		rawPreview, err := box.Find("highlight.svg")
+		user, err := highlightCreator(s, highlight)
 		if err != nil {
 			log.Println(err)
-			http.Error(w, "Highlight URL Preview error, svg file not found", http.StatusInternalServerError)
+			http.Error(w, "Highlight URL Preview error, "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		compiledPreview, err := compileHighlightPreview(rawPreview, highlight, user)
+
+		compiledPreview, err := compileHighlightPreview(tmpl, highlight, user)
 		if err != nil {
 			log.Println(err)
 			http.Error(w, "Highlight URL Preview error, template compilation failed", http.StatusInternalServerError)
 			return
 		}
-		render(compiledPreview, w, s.jpeg)
+		s.render(w, r, entity, generation, compiledPreview, out)
 	}
 	return http.HandlerFunc(fn)
 }
@@ -206,34 +412,42 @@ func renderHighlight(s *Service) http.Handler {
 func renderArgument(s *Service) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
-		argumentID, err := strconv.ParseInt(vars["id"], 10, 64)
+		entity := "argument/" + vars["id"]
+		out, err := parseOutput(r, s.defaultFormat())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tmpl, err := s.templates.find(out.template("argument.svg"))
 		if err != nil {
 			log.Println(err)
-			http.Error(w, "Invalid argument ID passed.", http.StatusBadRequest)
+			http.Error(w, "Argument URL Preview error: svg file not found", http.StatusInternalServerError)
 			return
 		}
-		data, err := getArgument(s, argumentID)
+		generation, served := s.serveIndexed(w, r, entity, out)
+		if served {
+			return
+		}
+		argumentID, err := strconv.ParseInt(vars["id"], 10, 64)
 		if err != nil {
 			log.Println(err)
-			http.Error(w, "", http.StatusInternalServerError)
+			http.Error(w, "Invalid argument ID passed.", http.StatusBadRequest)
 			return
 		}
-
-		box := packr.New("Templates", "./templates")
-		rawPreview, err := box.Find("highlight.svg")
+		data, err := getArgument(s, argumentID)
 		if err != nil {
 			log.Println(err)
-			http.Error(w, "Argument URL Preview error: svg file not found", http.StatusInternalServerError)
+			http.Error(w, "", http.StatusInternalServerError)
 			return
 		}
 
-		compiledPreview, err := compileArgumentPreview(rawPreview, data.ClaimArgument)
+		compiledPreview, err := compileArgumentPreview(tmpl, data.ClaimArgument)
 		if err != nil {
 			log.Println(err)
 			http.Error(w, "Argument URL Preview error: svg file not found", http.StatusInternalServerError)
 			return
 		}
-		render(compiledPreview, w, s.jpeg)
+		s.render(w, r, entity, generation, compiledPreview, out)
 	}
 
 	return http.HandlerFunc(fn)
@@ -242,6 +456,22 @@ func renderArgument(s *Service) http.Handler {
 func renderComment(s *Service) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
+		entity := "comment/" + vars["id"]
+		out, err := parseOutput(r, s.defaultFormat())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tmpl, err := s.templates.find(out.template("highlight.svg"))
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Comment URL Preview error: svg file not found", http.StatusInternalServerError)
+			return
+		}
+		generation, served := s.serveIndexed(w, r, entity, out)
+		if served {
+			return
+		}
 		commentID, err := strconv.ParseInt(vars["id"], 10, 64)
 		if err != nil {
 			log.Println(err)
@@ -260,40 +490,112 @@ func renderComment(s *Service) http.Handler {
 			return
 		}
 
-		box := packr.New("Templates", "./templates")
-		rawPreview, err := box.Find("highlight.svg")
+		compiledPreview, err := compileCommentPreview(tmpl, *comment)
 		if err != nil {
 			log.Println(err)
 			http.Error(w, "Comment URL Preview error: svg file not found", http.StatusInternalServerError)
 			return
 		}
+		s.render(w, r, entity, generation, compiledPreview, out)
+	}
 
-		compiledPreview, err := compileCommentPreview(rawPreview, *comment)
+	return http.HandlerFunc(fn)
+}
+
+func renderProfile(s *Service) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		entity := "user/" + vars["address"]
+		out, err := parseOutput(r, s.defaultFormat())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tmpl, err := s.templates.find(out.template("profile.svg"))
 		if err != nil {
 			log.Println(err)
-			http.Error(w, "Comment URL Preview error: svg file not found", http.StatusInternalServerError)
+			http.Error(w, "Profile URL Preview error: svg file not found", http.StatusInternalServerError)
+			return
+		}
+		generation, served := s.serveIndexed(w, r, entity, out)
+		if served {
 			return
 		}
-		render(compiledPreview, w, s.jpeg)
+		data, err := getProfile(s, vars["address"])
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "", http.StatusInternalServerError)
+			return
+		}
+		if data.AppAccount == nil || data.AppAccount.UserProfile.Username == "" {
+			http.Error(w, "Invalid address passed.", http.StatusNotFound)
+			return
+		}
+
+		compiledPreview, err := compileProfilePreview(tmpl, data)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Profile URL Preview error, template compilation failed", http.StatusInternalServerError)
+			return
+		}
+		s.render(w, r, entity, generation, compiledPreview, out)
 	}
+	return http.HandlerFunc(fn)
+}
 
+func renderCommunity(s *Service) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		entity := "community/" + vars["id"]
+		out, err := parseOutput(r, s.defaultFormat())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tmpl, err := s.templates.find(out.template("community.svg"))
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Community URL Preview error: svg file not found", http.StatusInternalServerError)
+			return
+		}
+		generation, served := s.serveIndexed(w, r, entity, out)
+		if served {
+			return
+		}
+		data, err := getCommunity(s, vars["id"])
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "", http.StatusInternalServerError)
+			return
+		}
+		if data.Community == nil {
+			http.Error(w, "Invalid community ID passed.", http.StatusNotFound)
+			return
+		}
+
+		compiledPreview, err := compileCommunityPreview(tmpl, data)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Community URL Preview error, template compilation failed", http.StatusInternalServerError)
+			return
+		}
+		s.render(w, r, entity, generation, compiledPreview, out)
+	}
 	return http.HandlerFunc(fn)
 }
 
-func compileClaimPreview(raw []byte, claim ClaimObject) string {
+func compileClaimPreview(tmpl *previewTemplate, claim ClaimObject) string {
+	layout := tmpl.layout
 	// BODY
-	bodyLines := wordWrap(claim.Body, WORDS_PER_LINE_CLAIM)
-	// make sure to have minimum lines atleast
-	if len(bodyLines) < BODY_LINES_CLAIM {
-		for i := len(bodyLines); i < BODY_LINES_CLAIM; i++ {
-			bodyLines = append(bodyLines, "")
-		}
-	} else if len(bodyLines) > BODY_LINES_CLAIM {
-		bodyLines[BODY_LINES_CLAIM-1] += "..." // ellipsis if the entire body couldn't be contained in this preview
+	bodyLines := wrapLines(claim.Body, layout)
+	compiled := tmpl.raw
+	// last line first, so $PLACEHOLDER__BODY_LINE_1 doesn't replace the start of $PLACEHOLDER__BODY_LINE_10
+	for i := layout.Lines; i >= 1; i-- {
+		placeholder := fmt.Sprintf("$PLACEHOLDER__BODY_LINE_%d", i)
+		compiled = bytes.Replace(compiled, []byte(placeholder), []byte(bodyLines[i-1]), -1)
 	}
-	compiled := bytes.Replace(raw, []byte("$PLACEHOLDER__BODY_LINE_1"), []byte(bodyLines[0]), -1)
-	compiled = bytes.Replace(compiled, []byte("$PLACEHOLDER__BODY_LINE_2"), []byte(bodyLines[1]), -1)
-	compiled = bytes.Replace(compiled, []byte("$PLACEHOLDER__BODY_LINE_3"), []byte(bodyLines[2]), -1)
+	fontSize := strconv.FormatFloat(layout.FontSize, 'g', -1, 64)
+	compiled = bytes.Replace(compiled, []byte("$PLACEHOLDER__BODY_FONT_SIZE"), []byte(fontSize), -1)
 
 	// ARGUMENT COUNT
 	compiled = bytes.Replace(compiled, []byte("$PLACEHOLDER__ARGUMENT_COUNT"), []byte(strconv.Itoa(claim.ArgumentCount)), -1)
@@ -311,29 +613,21 @@ func compileClaimPreview(raw []byte, claim ClaimObject) string {
 	return string(compiled)
 }
 
-func compileHighlightPreview(raw []byte, highlight *db.Highlight, user UserObject) (string, error) {
-	return compilePreview(raw, highlight.Text, WORDS_PER_LINE_HIGHLIGHT, BODY_LINES_HIGHLIGHT, user)
+func compileHighlightPreview(tmpl *previewTemplate, highlight *db.Highlight, user UserObject) (string, error) {
+	return compilePreview(tmpl, highlight.Text, user, 0)
 }
 
-func compileArgumentPreview(raw []byte, argument ArgumentObject) (string, error) {
-	return compilePreview(raw, argument.Summary, WORDS_PER_LINE_ARGUMENT, BODY_LINES_COMMENT, argument.Creator)
+func compileArgumentPreview(tmpl *previewTemplate, argument ArgumentObject) (string, error) {
+	return compilePreview(tmpl, argument.Summary, argument.Creator, argument.UpvotedCount)
 }
 
-func compileCommentPreview(raw []byte, comment CommentObject) (string, error) {
-	return compilePreview(raw, comment.Body, WORDS_PER_LINE_COMMENT, BODY_LINES_COMMENT, comment.Creator)
+func compileCommentPreview(tmpl *previewTemplate, comment CommentObject) (string, error) {
+	return compilePreview(tmpl, comment.Body, comment.Creator, 0)
 }
 
-func compilePreview(raw []byte, body string, wordsPerLine, numLines int, user UserObject) (string, error) {
+func compilePreview(tmpl *previewTemplate, body string, user UserObject, agreeCount int) (string, error) {
 	// BODY
-	bodyLines := wordWrap(body, wordsPerLine)
-	// make sure to have minimum lines atleast
-	if len(bodyLines) < numLines {
-		for i := len(bodyLines); i < numLines; i++ {
-			bodyLines = append(bodyLines, "")
-		}
-	} else if len(bodyLines) > numLines {
-		bodyLines[numLines-1] += "..." // ellipsis if the entire body couldn't be contained in this preview
-	}
+	bodyLines := wrapLines(body, tmpl.layout)
 	// base64-ing the avatar
 	// we need to fetch the image and convert it into base64 so that we can embed it in the SVG template.
 	avatarType, avatarBase64, err := imageURLToBase64(user.UserProfile.AvatarURL)
@@ -343,32 +637,176 @@ func compilePreview(raw []byte, body string, wordsPerLine, numLines int, user Us
 
 	// compiling the template
 	var compiled bytes.Buffer
-	tmpl, err := template.New("highlight").Parse(string(raw))
+	t, err := template.New("highlight").Parse(string(tmpl.raw))
 	if err != nil {
 		return "", err
 	}
 
 	vars := struct {
+		Layout       Layout
 		BodyLines    []string
 		User         UserObject
 		AvatarType   string
 		AvatarBase64 string
+		AgreeCount   int
 	}{
+		Layout:       tmpl.layout,
 		BodyLines:    bodyLines,
 		User:         user,
 		AvatarType:   avatarType,
 		AvatarBase64: avatarBase64,
+		AgreeCount:   agreeCount,
+	}
+
+	err = t.Execute(&compiled, vars)
+	if err != nil {
+		return "", err
 	}
 
-	err = tmpl.Execute(&compiled, vars)
+	return compiled.String(), nil
+}
+
+// ProfileCommunity is a community a user earned TRU in
+type ProfileCommunity struct {
+	Name   string
+	Earned string
+}
+
+func compileProfilePreview(tmpl *previewTemplate, profile ProfileByAddressResponse) (string, error) {
+	user := profile.AppAccount.UserProfile
+	avatarType, avatarBase64, err := imageURLToBase64(user.AvatarURL)
+	if err != nil {
+		return "", err
+	}
+
+	// communities the user earned the most in
+	earnings := make([]CommunityEarningObject, 0)
+	for _, earning := range profile.AppAccountCommunityEarnings {
+		if amount, _ := strconv.ParseInt(earning.TotalEarned.Amount, 10, 64); amount > 0 {
+			earnings = append(earnings, earning)
+		}
+	}
+	sort.SliceStable(earnings, func(i, j int) bool {
+		a, _ := strconv.ParseInt(earnings[i].TotalEarned.Amount, 10, 64)
+		b, _ := strconv.ParseInt(earnings[j].TotalEarned.Amount, 10, 64)
+		return a > b
+	})
+	if len(earnings) > TOP_COMMUNITIES {
+		earnings = earnings[:TOP_COMMUNITIES]
+	}
+	communities := make([]ProfileCommunity, 0, len(earnings))
+	for _, earning := range earnings {
+		communities = append(communities, ProfileCommunity{
+			Name:   html.EscapeString(earning.Community.Name),
+			Earned: earning.TotalEarned.HumanReadable,
+		})
+	}
+
+	var compiled bytes.Buffer
+	t, err := template.New("profile").Parse(string(tmpl.raw))
+	if err != nil {
+		return "", err
+	}
+	vars := struct {
+		Layout       Layout
+		FullName     string
+		Username     string
+		Earned       string
+		Communities  []ProfileCommunity
+		AvatarType   string
+		AvatarBase64 string
+	}{
+		Layout:       tmpl.layout,
+		FullName:     html.EscapeString(user.FullName),
+		Username:     html.EscapeString(user.Username),
+		Earned:       profile.AppAccount.EarnedBalance.HumanReadable,
+		Communities:  communities,
+		AvatarType:   avatarType,
+		AvatarBase64: avatarBase64,
+	}
+	err = t.Execute(&compiled, vars)
 	if err != nil {
 		return "", err
 	}
+	return compiled.String(), nil
+}
+
+func compileCommunityPreview(tmpl *previewTemplate, community CommunityByIDResponse) (string, error) {
+	trendingLines := make([]string, tmpl.layout.Lines)
+	if len(community.Claims.Edges) > 0 {
+		trendingLines = wrapLines(community.Claims.Edges[0].Node.Body, tmpl.layout)
+	}
+
+	// communities without a hero image are rendered on the default background
+	heroType, heroBase64, err := imageURLToBase64(community.Community.HeroImage)
+	if err != nil || !strings.HasPrefix(heroType, "image/") {
+		heroType, heroBase64 = "", ""
+	}
 
+	var compiled bytes.Buffer
+	t, err := template.New("community").Parse(string(tmpl.raw))
+	if err != nil {
+		return "", err
+	}
+	vars := struct {
+		Layout        Layout
+		Name          string
+		ClaimCount    int
+		TrendingLines []string
+		HeroType      string
+		HeroBase64    string
+	}{
+		Layout:        tmpl.layout,
+		Name:          html.EscapeString(community.Community.Name),
+		ClaimCount:    community.Claims.TotalCount,
+		TrendingLines: trendingLines,
+		HeroType:      heroType,
+		HeroBase64:    heroBase64,
+	}
+	err = t.Execute(&compiled, vars)
+	if err != nil {
+		return "", err
+	}
 	return compiled.String(), nil
 }
 
-func wordWrap(body string, defaultWordsPerLine int) []string {
+// wrapLines wraps a body into exactly as many lines as the layout has.
+func wrapLines(body string, layout Layout) []string {
+	if layout.Width > 0 {
+		fonts, err := bundledFonts()
+		if err == nil {
+			return measureWrap(body, fonts, layout)
+		}
+		log.Println(err)
+	}
+	lines := wordWrap(body, layout.WordsPerLine, layout.MaxCharsPerLine)
+	// make sure to have minimum lines atleast
+	for len(lines) < layout.Lines {
+		lines = append(lines, "")
+	}
+	if len(lines) > layout.Lines {
+		lines = lines[:layout.Lines]
+		lines[layout.Lines-1] += "..." // ellipsis if the entire body couldn't be contained in this preview
+	}
+	return lines
+}
+
+// measureWrap wraps a body by measuring it in the font of the layout.
+func measureWrap(body string, fonts *fontSet, layout Layout) []string {
+	body = stripmd.Strip(html.EscapeString(body))
+	body = regexMention.ReplaceAllString(body, "$1$2...$3")
+	m := newTextMeasurer(fonts, layout)
+	lines := m.wrap(html.UnescapeString(body), layout.Width, layout.Lines)
+	for i, line := range lines {
+		lines[i] = html.EscapeString(line)
+	}
+	for len(lines) < layout.Lines {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+func wordWrap(body string, defaultWordsPerLine, maxCharsPerLine int) []string {
 	body = stripmd.Strip(html.EscapeString(body))
 	body = regexMention.ReplaceAllString(body, "$1$2...$3") // converts @cosmos1xqc5gsesg5m4jv252ce9g4jgfev52s68an2ss9 into @cosmos1xqc...2ss9
 	lines := make([]string, 0)
@@ -380,7 +818,6 @@ func wordWrap(body string, defaultWordsPerLine int) []string {
 	// convert string to slice
 	words := strings.Fields(body)
 	wordsPerLine := defaultWordsPerLine
-	maxCharsPerLine := MAX_CHARS_PER_LINE
 
 	if len(words) < wordsPerLine {
 		wordsPerLine = len(words)
@@ -428,6 +865,32 @@ func getClaim(s *Service, claimID int64) (ClaimByIDResponse, error) {
 	return graphqlRes, nil
 }
 
+func getProfile(s *Service, address string) (ProfileByAddressResponse, error) {
+	graphqlReq := graphql.NewRequest(ProfileByAddressQuery)
+
+	graphqlReq.Var("address", address)
+	var graphqlRes ProfileByAddressResponse
+	ctx := context.Background()
+	if err := s.graphqlClient.Run(ctx, graphqlReq, &graphqlRes); err != nil {
+		return graphqlRes, err
+	}
+
+	return graphqlRes, nil
+}
+
+func getCommunity(s *Service, communityID string) (CommunityByIDResponse, error) {
+	graphqlReq := graphql.NewRequest(CommunityByIDQuery)
+
+	graphqlReq.Var("communityId", communityID)
+	var graphqlRes CommunityByIDResponse
+	ctx := context.Background()
+	if err := s.graphqlClient.Run(ctx, graphqlReq, &graphqlRes); err != nil {
+		return graphqlRes, err
+	}
+
+	return graphqlRes, nil
+}
+
 func getHighlight(s *Service, highlightID int64) (*db.Highlight, error) {
 	highlight := &db.Highlight{ID: highlightID}
 	err := s.dbClient.Find(highlight)
@@ -441,6 +904,28 @@ func getHighlight(s *Service, highlightID int64) (*db.Highlight, error) {
 	return highlight, nil
 }
 
+// highlightCreator returns the creator of the argument or comment a highlight is from.
+func highlightCreator(s *Service, highlight *db.Highlight) (UserObject, error) {
+	switch highlight.HighlightableType {
+	case "argument":
+		argument, err := getArgument(s, highlight.HighlightableID)
+		if err != nil {
+			log.Println(err)
+			return UserObject{}, errors.New("argument not found")
+		}
+		return argument.ClaimArgument.Creator, nil
+	case "comment":
+		comment, err := getComment(s, highlight.HighlightableID)
+		if err != nil || comment == nil {
+			log.Println(err)
+			return UserObject{}, errors.New("comment not found")
+		}
+		return comment.Creator, nil
+	default:
+		return UserObject{}, errors.New("invalid highlightable type")
+	}
+}
+
 func getArgument(s *Service, argumentID int64) (ArgumentByIDResponse, error) {
 	graphqlReq := graphql.NewRequest(ArgumentByIDQuery)
 
@@ -491,12 +976,24 @@ func getComment(s *Service, commentID int64) (*CommentObject, error) {
 }
 
 func imageURLToBase64(url string) (string, string, error) {
+	// data URIs are embedded as they are, fixtures use them to render without the network
+	if strings.HasPrefix(url, "data:") {
+		match := regexDataURI.FindStringSubmatch(url)
+		if match == nil {
+			return "", "", fmt.Errorf("unsupported data URI")
+		}
+		return match[1], match[2], nil
+	}
 	response, err := (&http.Client{
 		Timeout: time.Second * 5,
 	}).Get(url)
 	if err != nil {
 		return "", "", err
 	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("fetching image %s: %s", url, response.Status)
+	}
 
 	avatar, err := ioutil.ReadAll(response.Body)
 	if err != nil {
@@ -0,0 +1,330 @@
+package spotlight
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/machinebox/graphql"
+)
+
+// DefaultSnippetSize is the size of snippets when no size is requested.
+const DefaultSnippetSize = "twitter"
+
+const (
+	// snippetArguments is the number of arguments animated after the claim
+	snippetArguments = 3
+	// snippetHold is how long every card is shown
+	snippetHold = 3 * time.Second
+	// snippetFade is how long cards crossfade into the next one, in snippetFadeFrames frames
+	snippetFade       = 600 * time.Millisecond
+	snippetFadeFrames = 6
+)
+
+// errNoFFmpeg is returned for MP4 snippets when ffmpeg is not installed.
+var errNoFFmpeg = errors.New("mp4 snippets need ffmpeg")
+
+// parseSnippetOutput reads the size and format query parameters of a snippet request.
+func parseSnippetOutput(r *http.Request) (output, error) {
+	query := r.URL.Query()
+	o := output{sizeName: DefaultSnippetSize, format: FormatGIF}
+	if size := query.Get("size"); size != "" {
+		o.sizeName = strings.ToLower(size)
+	}
+	var ok bool
+	o.Size, ok = Sizes[o.sizeName]
+	if !ok {
+		return output{}, fmt.Errorf("unknown size %q", o.sizeName)
+	}
+	switch format := strings.ToLower(query.Get("format")); format {
+	case "", FormatGIF:
+	case FormatMP4:
+		o.format = FormatMP4
+	default:
+		return output{}, fmt.Errorf("unknown format %q", format)
+	}
+	return o, nil
+}
+
+// snippetVariant identifies a snippet in the preview index, apart from the still previews.
+func snippetVariant(out output) string {
+	return "snippet." + out.variant()
+}
+
+// topArguments returns the top argument of a claim followed by the most upvoted ones.
+func topArguments(claim ClaimSnippetObject) []ArgumentObject {
+	arguments := make([]ArgumentObject, 0)
+	if claim.TopArgument != nil {
+		arguments = append(arguments, *claim.TopArgument)
+	}
+	rest := make([]ArgumentObject, 0)
+	for _, argument := range claim.Arguments {
+		if claim.TopArgument != nil && argument.ID == claim.TopArgument.ID {
+			continue
+		}
+		rest = append(rest, argument)
+	}
+	sort.SliceStable(rest, func(i, j int) bool { return rest[i].UpvotedCount > rest[j].UpvotedCount })
+	arguments = append(arguments, rest...)
+	if len(arguments) > snippetArguments {
+		arguments = arguments[:snippetArguments]
+	}
+	return arguments
+}
+
+// compileSnippet compiles the cards of a snippet, the claim followed by its top arguments.
+func (s *Service) compileSnippet(claim ClaimSnippetObject, out output) ([]string, error) {
+	claimTemplate, err := s.templates.find(out.template("claim.svg"))
+	if err != nil {
+		return nil, err
+	}
+	argumentTemplate, err := s.templates.find(out.template("argument.svg"))
+	if err != nil {
+		return nil, err
+	}
+	cards := []string{compileClaimPreview(claimTemplate, claim.ClaimObject)}
+	for _, argument := range topArguments(claim) {
+		card, err := compileArgumentPreview(argumentTemplate, argument)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	for i, card := range cards {
+		cards[i] = fitViewBox(card, out.Width, out.Height)
+	}
+	return cards, nil
+}
+
+// snippetFrames rasterizes the cards and crossfades every card into the next one,
+// the last one into the first so the animation loops.
+func (s *Service) snippetFrames(cards []string, out output) ([]frame, error) {
+	images := make([]frame, 0, len(cards))
+	for _, card := range cards {
+		img, err := s.rasterizer.Rasterize([]byte(card), out.Width, out.Height)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, frame{img: toRGBA(img), delay: snippetHold})
+	}
+	if len(images) < 2 {
+		return images, nil
+	}
+	frames := make([]frame, 0, len(images)*(snippetFadeFrames+1))
+	for i, card := range images {
+		frames = append(frames, card)
+		next := images[(i+1)%len(images)]
+		for f := 1; f <= snippetFadeFrames; f++ {
+			frames = append(frames, frame{
+				img:   crossfade(card.img, next.img, float64(f)/float64(snippetFadeFrames+1)),
+				delay: snippetFade / snippetFadeFrames,
+			})
+		}
+	}
+	return frames, nil
+}
+
+// renderSnippet renders the snippet of a claim, indexed under the generation of the claim, returning its cache key.
+func (s *Service) renderSnippet(entity, generation string, claimID int64, out output) (string, error) {
+	data, err := getClaimSnippet(s, claimID)
+	if err != nil {
+		return "", err
+	}
+	if data.Claim == nil {
+		return "", fmt.Errorf("claim %d not found", claimID)
+	}
+	cards, err := s.compileSnippet(*data.Claim, out)
+	if err != nil {
+		return "", err
+	}
+	key := cacheKey([]byte(strings.Join(cards, "\x00")), out.Width, out.Height, out.format)
+	cached, err := s.cache.Get(key)
+	if err != nil {
+		log.Println(err)
+	}
+	if cached == nil {
+		frames, err := s.snippetFrames(cards, out)
+		if err != nil {
+			return "", err
+		}
+		buf := new(bytes.Buffer)
+		if out.format == FormatMP4 {
+			err = encodeMP4(buf, frames)
+		} else {
+			err = encodeGIF(buf, frames)
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := s.cache.Put(key, buf.Bytes(), out.contentType()); err != nil {
+			return "", err
+		}
+	}
+	s.index.set(entity, generation, snippetVariant(out), key)
+	return key, nil
+}
+
+// encodeMP4 encodes frames as an H.264 MP4 with ffmpeg, holding every frame for its delay.
+func encodeMP4(w io.Writer, frames []frame) error {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return errNoFFmpeg
+	}
+	dir, err := ioutil.TempDir("", "snippet")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	list := new(bytes.Buffer)
+	for i, f := range frames {
+		name := filepath.Join(dir, fmt.Sprintf("%04d.png", i))
+		file, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		err = png.Encode(file, f.img)
+		file.Close()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(list, "file '%s'\nduration %.3f\n", name, f.delay.Seconds())
+	}
+	// the concat demuxer ignores the duration of the last file unless it is listed again
+	fmt.Fprintf(list, "file '%s'\n", filepath.Join(dir, fmt.Sprintf("%04d.png", len(frames)-1)))
+	listPath := filepath.Join(dir, "frames.txt")
+	if err := ioutil.WriteFile(listPath, list.Bytes(), 0644); err != nil {
+		return err
+	}
+	outPath := filepath.Join(dir, "snippet.mp4")
+	cmd := exec.Command(ffmpeg, "-y", "-loglevel", "error", "-f", "concat", "-safe", "0", "-i", listPath,
+		"-vf", "fps=25,format=yuv420p", "-c:v", "libx264", "-movflags", "+faststart", outPath)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	data, err := ioutil.ReadFile(outPath)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func writeJob(w http.ResponseWriter, status int, job Job) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Println(err)
+	}
+}
+
+// createSnippet starts rendering the snippet of a claim, responding with the job to poll.
+func createSnippet(s *Service) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if s.cache == nil {
+			http.Error(w, "Snippets need a render cache", http.StatusNotImplemented)
+			return
+		}
+		vars := mux.Vars(r)
+		entity := "claim/" + vars["id"]
+		out, err := parseSnippetOutput(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		claimID, err := strconv.ParseInt(vars["id"], 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid claim ID passed.", http.StatusBadRequest)
+			return
+		}
+		key, generation, ok := s.index.get(entity, snippetVariant(out))
+		if ok {
+			writeJob(w, http.StatusOK, s.jobs.finished(JobSnippet, entity, out.variant(), key))
+			return
+		}
+		job, err := s.jobs.enqueue(JobSnippet, entity, out.variant(), func() (string, error) {
+			return s.renderSnippet(entity, generation, claimID, out)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Location", "/snippets/"+job.ID)
+		writeJob(w, http.StatusAccepted, job)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// snippetStatus responds with a snippet job.
+func snippetStatus(s *Service) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		job, ok := s.jobs.get(mux.Vars(r)["id"])
+		if !ok || job.Kind != JobSnippet {
+			http.Error(w, "Unknown snippet job", http.StatusNotFound)
+			return
+		}
+		writeJob(w, http.StatusOK, job)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// downloadSnippet serves the snippet rendered by a finished job.
+func downloadSnippet(s *Service) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		job, ok := s.jobs.get(mux.Vars(r)["id"])
+		if !ok || job.Kind != JobSnippet {
+			http.Error(w, "Unknown snippet job", http.StatusNotFound)
+			return
+		}
+		if job.Status != JobDone {
+			http.Error(w, "Snippet is "+job.Status, http.StatusConflict)
+			return
+		}
+		out := output{format: strings.TrimPrefix(filepath.Ext(job.Variant), ".")}
+		if etagMatches(r, job.key) {
+			writeNotModified(w, job.key)
+			return
+		}
+		data, err := s.cache.Get(job.key)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "", http.StatusInternalServerError)
+			return
+		}
+		if data == nil {
+			http.Error(w, "Snippet expired from the cache", http.StatusGone)
+			return
+		}
+		s.write(w, out, job.key, data)
+	}
+	return http.HandlerFunc(fn)
+}
+
+func getClaimSnippet(s *Service, claimID int64) (ClaimSnippetResponse, error) {
+	graphqlReq := graphql.NewRequest(ClaimSnippetQuery)
+
+	graphqlReq.Var("claimId", claimID)
+	var graphqlRes ClaimSnippetResponse
+	ctx := context.Background()
+	if err := s.graphqlClient.Run(ctx, graphqlReq, &graphqlRes); err != nil {
+		return graphqlRes, err
+	}
+
+	return graphqlRes, nil
+}
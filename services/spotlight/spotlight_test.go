@@ -1,44 +1,683 @@
 package spotlight
 
 import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+	"unicode"
 
+	"github.com/TruStory/octopus/services/truapi/db"
 	"github.com/stretchr/testify/assert"
 	stripmd "github.com/writeas/go-strip-markdown"
+	"golang.org/x/image/webp"
+)
+
+var update = flag.Bool("update", false, "update the golden images in testdata/golden")
+
+const (
+	goldenWidth  = 480
+	goldenHeight = 270
+	// fraction of pixels allowed to differ from the golden images
+	goldenTolerance = 0.005
 )
 
 func TestShortText(t *testing.T) {
 	text := "Hello"
-	lines := wordWrap(text, 7)
+	lines := wordWrap(text, 7, MAX_CHARS_PER_LINE)
 	assert.Equal(t, len(lines), 1)
 	assert.Equal(t, lines[0], text)
 }
 
 func TestLongText(t *testing.T) {
 	text := "Lorem ipsum dolor sit amet, consectetur adipiscing elit. Maecenas ultricies leo at metus porta, sed fermentum nibh malesuada. Donec vehicula ligula ut turpis efficitur gravida. Proin mattis aliquet pharetra. Curabitur vitae elit purus. Etiam aliquet metus ac neque rhoncus, non commodo arcu blandit. Pellentesque in ultricies magna. Nulla nec felis."
-	lines := wordWrap(text, 7)
+	lines := wordWrap(text, 7, MAX_CHARS_PER_LINE)
 	assert.Equal(t, len(lines) > 1, true)
 }
 
 func TestMarkdownText(t *testing.T) {
 	text := "## Heading"
-	lines := wordWrap(text, 7)
+	lines := wordWrap(text, 7, MAX_CHARS_PER_LINE)
 	assert.Equal(t, len(lines), 1)
 	assert.Equal(t, lines[0], stripmd.Strip(text))
 }
 
 func TestMentionText(t *testing.T) {
 	text := "I agree with @cosmos1xqc5gsesg5m4jv252ce9g4jgfev52s68an2ss9."
-	lines := wordWrap(text, 7)
+	lines := wordWrap(text, 7, MAX_CHARS_PER_LINE)
 	assert.Equal(t, len(lines), 1)
 	assert.Equal(t, lines[0], "I agree with @cosmos1xqc...2ss9.")
 }
 
 func TestLinkText(t *testing.T) {
 	text := "The link http://someveryveryverylongurlgoeshere.com/and-the-url-doesnt-seem-to-end-anytime-soon/what-would-happen-now?id=123 says that TruStory is awesome."
-	lines := wordWrap(text, 7)
+	lines := wordWrap(text, 7, MAX_CHARS_PER_LINE)
 	assert.Equal(t, len(lines), 3)
 	assert.Equal(t, lines[0], "The link")
 	assert.Equal(t, lines[1], "http://someveryveryv... says that")
 	assert.Equal(t, lines[2], "TruStory is awesome.")
 }
+
+func TestRenderGolden(t *testing.T) {
+	avatar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		img := image.NewRGBA(image.Rect(0, 0, 128, 128))
+		for y := 0; y < 128; y++ {
+			for x := 0; x < 128; x++ {
+				img.Set(x, y, color.RGBA{R: uint8(x * 2), G: 80, B: uint8(y * 2), A: 0xff})
+			}
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_ = png.Encode(w, img)
+	}))
+	defer avatar.Close()
+	user := UserObject{
+		Address:     "cosmos1xqc5gsesg5m4jv252ce9g4jgfev52s68an2ss9",
+		UserProfile: UserProfileObject{AvatarURL: avatar.URL, FullName: "Alice", Username: "alice"},
+	}
+
+	templates := newTemplateStore("")
+	find := func(name string) *previewTemplate {
+		tmpl, err := templates.find(name)
+		assert.NoError(t, err)
+		return tmpl
+	}
+	body := "Cats are better than dogs because they are more independent & need less attention 🐱"
+	type golden struct {
+		preview       string
+		width, height int
+	}
+	goldens := make(map[string]golden)
+	for _, size := range []string{DefaultSize, "og", "square", "story"} {
+		out := output{sizeName: size, Size: Sizes[size], format: FormatPNG}
+		// golden images are a quarter of the size of the full size preview
+		width, height := goldenWidth, goldenHeight
+		if size != DefaultSize {
+			width, height = out.Width/4, out.Height/4
+		}
+		previews := map[string]string{
+			"claim": compileClaimPreview(find(out.template("claim.svg")), ClaimObject{
+				Body: body, Source: "https://example.com/cats", ArgumentCount: 12, Creator: user,
+			}),
+		}
+		highlight, err := compileHighlightPreview(find(out.template("highlight.svg")), &db.Highlight{Text: body}, user)
+		assert.NoError(t, err)
+		previews["highlight"] = highlight
+		argument, err := compileArgumentPreview(find(out.template("argument.svg")), ArgumentObject{Summary: body, Creator: user, UpvotedCount: 7})
+		assert.NoError(t, err)
+		previews["argument"] = argument
+		comment, err := compileCommentPreview(find(out.template("highlight.svg")), CommentObject{Body: "Short comment", Creator: user})
+		assert.NoError(t, err)
+		previews["comment"] = comment
+		profile, err := compileProfilePreview(find(out.template("profile.svg")), ProfileByAddressResponse{
+			AppAccount: &AppAccountObject{UserProfile: user.UserProfile, EarnedBalance: CoinObject{Amount: "1250000000", HumanReadable: "1,250"}},
+			AppAccountCommunityEarnings: []CommunityEarningObject{
+				{Community: CommunityObject{Name: "Crypto"}, TotalEarned: CoinObject{Amount: "50000000", HumanReadable: "50"}},
+				{Community: CommunityObject{Name: "Sports"}, TotalEarned: CoinObject{Amount: "0", HumanReadable: "0"}},
+				{Community: CommunityObject{Name: "Tech & Science"}, TotalEarned: CoinObject{Amount: "1200000000", HumanReadable: "1,200"}},
+			},
+		})
+		assert.NoError(t, err)
+		previews["profile"] = profile
+		community := CommunityByIDResponse{
+			Community: &CommunityObject{Name: "Tech & Science", HeroImage: avatar.URL},
+			Claims:    ClaimConnection{TotalCount: 42, Edges: []ClaimEdge{{Node: ClaimObject{Body: body}}}},
+		}
+		previews["community"], err = compileCommunityPreview(find(out.template("community.svg")), community)
+		assert.NoError(t, err)
+		for name, preview := range previews {
+			if size != DefaultSize {
+				name += "." + size
+			}
+			goldens[name] = golden{preview: fitViewBox(preview, width, height), width: width, height: height}
+		}
+	}
+
+	r, err := newNativeRasterizer()
+	assert.NoError(t, err)
+	for name, golden := range goldens {
+		img, err := r.Rasterize([]byte(golden.preview), golden.width, golden.height)
+		assert.NoError(t, err)
+		path := filepath.Join("testdata", "golden", name+".png")
+		if *update {
+			buf := new(bytes.Buffer)
+			assert.NoError(t, png.Encode(buf, img))
+			assert.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+			continue
+		}
+		raw, err := ioutil.ReadFile(path)
+		if !assert.NoError(t, err, "run go test with -update to create the golden images") {
+			continue
+		}
+		golden, err := png.Decode(bytes.NewReader(raw))
+		assert.NoError(t, err)
+		diff := imageDiff(golden, img)
+		assert.True(t, diff <= goldenTolerance, "%s differs from its golden image in %.2f%% of pixels", name, diff*100)
+	}
+}
+
+// imageDiff returns the fraction of pixels with a channel differing by more than a small threshold.
+func imageDiff(a, b image.Image) float64 {
+	if a.Bounds() != b.Bounds() {
+		return 1
+	}
+	const threshold = 8 << 8
+	bounds := a.Bounds()
+	differing := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, a1 := a.At(x, y).RGBA()
+			r2, g2, b2, a2 := b.At(x, y).RGBA()
+			for _, d := range []int{int(r1) - int(r2), int(g1) - int(g2), int(b1) - int(b2), int(a1) - int(a2)} {
+				if d > threshold || d < -threshold {
+					differing++
+					break
+				}
+			}
+		}
+	}
+	return float64(differing) / float64(bounds.Dx()*bounds.Dy())
+}
+
+type countingRasterizer struct {
+	calls int
+}
+
+func (r *countingRasterizer) Rasterize(svg []byte, width, height int) (image.Image, error) {
+	r.calls++
+	return image.NewRGBA(image.Rect(0, 0, width, height)), nil
+}
+
+type fakeRasterizer struct {
+	calls int
+}
+
+func (r *fakeRasterizer) Rasterize(svg []byte, width, height int) (image.Image, error) {
+	r.calls++
+	return image.NewRGBA(image.Rect(0, 0, width, height)), nil
+}
+
+func TestRenderImageErrorFallsBack(t *testing.T) {
+	native, err := newNativeRasterizer()
+	assert.NoError(t, err)
+	svg := []byte(`<svg width="10" height="10" xmlns="http://www.w3.org/2000/svg">
+<image width="10" height="10" href="data:image/png;base64,bm90IGFuIGltYWdl"/>
+</svg>`)
+	_, err = native.Rasterize(svg, 10, 10)
+	assert.Error(t, err)
+
+	fallback := &fakeRasterizer{}
+	img, err := fallbackRasterizer{primary: native, fallback: fallback}.Rasterize(svg, 10, 10)
+	assert.NoError(t, err)
+	assert.NotNil(t, img)
+	assert.Equal(t, 1, fallback.calls)
+}
+
+func TestImageURLToBase64(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("png"))
+	}))
+	defer server.Close()
+
+	contentType, encoded, err := imageURLToBase64(server.URL + "/avatar.png")
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+	assert.Equal(t, "cG5n", encoded)
+
+	_, _, err = imageURLToBase64(server.URL + "/missing.png")
+	assert.Error(t, err)
+}
+
+func TestRenderCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spotlight")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cache, err := NewDiskCache(dir)
+	assert.NoError(t, err)
+	rasterizer := &countingRasterizer{}
+	s := &Service{rasterizer: rasterizer, cache: cache, index: newPreviewIndex(cache, time.Minute)}
+	// another replica sharing the render cache
+	replica := &Service{rasterizer: rasterizer, cache: cache, index: newPreviewIndex(cache, time.Minute)}
+	preview := `<svg width="10" height="10"></svg>`
+	out := output{sizeName: DefaultSize, Size: Sizes[DefaultSize], format: FormatPNG}
+
+	generation, served := s.serveIndexed(httptest.NewRecorder(), httptest.NewRequest("GET", "/claim/1/spotlight", nil), "claim/1", out)
+	assert.False(t, served)
+	w := httptest.NewRecorder()
+	s.render(w, httptest.NewRequest("GET", "/claim/1/spotlight", nil), "claim/1", generation, preview, out)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// same content is served from the cache
+	w = httptest.NewRecorder()
+	s.render(w, httptest.NewRequest("GET", "/claim/1/spotlight", nil), "claim/1", generation, preview, out)
+	assert.Equal(t, 1, rasterizer.calls)
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	// indexed previews are served without compiling them, by every replica
+	r := httptest.NewRequest("GET", "/claim/1/spotlight", nil)
+	r.Header.Set("If-None-Match", etag)
+	for _, service := range []*Service{s, replica} {
+		w = httptest.NewRecorder()
+		_, served = service.serveIndexed(w, r, "claim/1", out)
+		assert.True(t, served)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+	}
+
+	// other sizes are rendered separately
+	twitter := output{sizeName: "twitter", Size: Sizes["twitter"], format: FormatPNG}
+	_, served = s.serveIndexed(httptest.NewRecorder(), r, "claim/1", twitter)
+	assert.False(t, served)
+
+	// invalidating on a replica drops the previews on all of them
+	assert.NoError(t, replica.index.invalidate("claim/1"))
+	for _, service := range []*Service{s, replica} {
+		_, served = service.serveIndexed(httptest.NewRecorder(), r, "claim/1", out)
+		assert.False(t, served)
+	}
+
+	// a render from data fetched before the invalidation isn't indexed
+	s.render(httptest.NewRecorder(), r, "claim/1", generation, preview, out)
+	_, served = replica.serveIndexed(httptest.NewRecorder(), r, "claim/1", out)
+	assert.False(t, served)
+
+	generation, _ = s.serveIndexed(httptest.NewRecorder(), r, "claim/1", out)
+	s.render(httptest.NewRecorder(), r, "claim/1", generation, preview, out)
+	_, served = replica.serveIndexed(httptest.NewRecorder(), r, "claim/1", out)
+	assert.True(t, served)
+
+	// reloading templates drops the previews the replica indexed
+	replica.index.clear()
+	_, served = replica.serveIndexed(httptest.NewRecorder(), r, "claim/1", out)
+	assert.False(t, served)
+}
+
+func TestParseOutput(t *testing.T) {
+	out, err := parseOutput(httptest.NewRequest("GET", "/claim/1/spotlight", nil), FormatJPEG)
+	assert.NoError(t, err)
+	assert.Equal(t, PREVIEW_WIDTH, out.Width)
+	assert.Equal(t, "image/jpeg", out.contentType())
+
+	out, err = parseOutput(httptest.NewRequest("GET", "/claim/1/spotlight?size=story&format=webp", nil), FormatPNG)
+	assert.NoError(t, err)
+	assert.Equal(t, 1080, out.Width)
+	assert.Equal(t, 1920, out.Height)
+	assert.Equal(t, "story.webp", out.variant())
+	assert.Equal(t, "claim.story.svg", out.template("claim.svg"))
+
+	_, err = parseOutput(httptest.NewRequest("GET", "/claim/1/spotlight?size=banner", nil), FormatPNG)
+	assert.Error(t, err)
+	_, err = parseOutput(httptest.NewRequest("GET", "/claim/1/spotlight?format=gif", nil), FormatPNG)
+	assert.Error(t, err)
+}
+
+func TestFitViewBox(t *testing.T) {
+	svg := `<svg width="1920px" height="1080px" viewBox="0 0 1920 1080"><rect/></svg>`
+	assert.Equal(t, svg, fitViewBox(svg, 1200, 675))
+	assert.Equal(t, `<svg width="1920" height="1008" viewBox="0 36 1920 1008"><rect/></svg>`, fitViewBox(svg, 1200, 630))
+	assert.Equal(t, `<svg width="1080" height="1080" viewBox="420 0 1080 1080"><rect/></svg>`, fitViewBox(svg, 1080, 1080))
+}
+
+func TestEncodeWebP(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 123, 77))
+	for y := 0; y < 77; y++ {
+		for x := 0; x < 123; x++ {
+			c := color.NRGBA{R: uint8(x * 2), G: uint8(y * 3), B: uint8(x ^ y), A: 0xff}
+			switch {
+			case x > 60 && y > 30:
+				// flat area encoded with backward references
+				c = color.NRGBA{R: 0x73, G: 0x50, B: 0xff, A: 0xff}
+			case x < 10:
+				c.A = uint8(y * 3)
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, encodeWebP(buf, img))
+	decoded, err := webp.Decode(buf)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, img.Bounds(), decoded.Bounds())
+	for y := 0; y < 77; y++ {
+		for x := 0; x < 123; x++ {
+			if !assert.Equal(t, img.NRGBAAt(x, y), color.NRGBAModel.Convert(decoded.At(x, y)), "pixel %d,%d", x, y) {
+				return
+			}
+		}
+	}
+}
+
+func TestTemplateLayout(t *testing.T) {
+	tmpl, err := parseTemplate("claim.svg", []byte(`<svg viewBox="0 0 10 10"><metadata id="spotlight-layout">{"lines": 5, "wordsPerLine": 4}</metadata></svg>`))
+	assert.NoError(t, err)
+	assert.Equal(t, Layout{Lines: 5, WordsPerLine: 4, MaxCharsPerLine: MAX_CHARS_PER_LINE, FontSize: 75}, tmpl.layout)
+
+	tmpl, err = parseTemplate("profile.svg", []byte(`<svg viewBox="0 0 10 10"></svg>`))
+	assert.NoError(t, err)
+	assert.Equal(t, defaultLayout, tmpl.layout)
+
+	_, err = parseTemplate("claim.svg", []byte(`<svg><metadata id="spotlight-layout">{"lines": 0}</metadata></svg>`))
+	assert.Error(t, err)
+}
+
+func TestTemplateReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store := newTemplateStore(dir)
+	reloaded := make([]string, 0)
+	store.onReload = func(name string) { reloaded = append(reloaded, name) }
+
+	// templates missing from the directory fall back to the bundled ones
+	tmpl, err := store.find("claim.svg")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, tmpl.layout.Lines)
+
+	path := filepath.Join(dir, "claim.svg")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`<svg><metadata id="spotlight-layout">{"lines": 4}</metadata></svg>`), 0644))
+	tmpl, err = store.find("claim.svg")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, tmpl.layout.Lines)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`<svg><metadata id="spotlight-layout">{"lines": 6}</metadata></svg>`), 0644))
+	modTime := time.Now().Add(time.Second)
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	tmpl, err = store.find("claim.svg")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, tmpl.layout.Lines)
+	// overriding the bundled template is a reload too
+	assert.Equal(t, []string{"claim.svg", "claim.svg"}, reloaded)
+
+	_, err = store.find("../claim.svg")
+	assert.Error(t, err)
+}
+
+func TestCompileFixtures(t *testing.T) {
+	store := newTemplateStore("")
+	for _, name := range store.list() {
+		tmpl, err := store.find(name)
+		if !assert.NoError(t, err, name) {
+			continue
+		}
+		kind := strings.SplitN(name, ".", 2)[0]
+		fixture, err := ioutil.ReadFile(filepath.Join("fixtures", kind+".json"))
+		if os.IsNotExist(err) {
+			// comment.svg is not used by any route
+			continue
+		}
+		preview, err := compileFixture(tmpl, kind, fixture)
+		assert.NoError(t, err, name)
+		assert.NotContains(t, preview, "$PLACEHOLDER", name)
+	}
+}
+
+func TestWrapFits(t *testing.T) {
+	fonts, err := bundledFonts()
+	assert.NoError(t, err)
+	texts := []struct {
+		text string
+		// family is the fallback font rendering the text, fetched by make fonts
+		family string
+	}{
+		{text: "Cats are better than dogs because they are more independent & need less attention, and they keep the house free of mice all year long without being asked to"},
+		{text: "WWWWWWWWWW MMMMMMMMMM WWWWWWWWWW MMMMMMMMMM WWWWWWWWWW MMMMMMMMMM WWWWWWWWWW MMMMMMMMMM WWWWWWWWWW MMMMMMMMMM WWWWWWWWWW"},
+		{text: "iiii llll iiii llll iiii llll iiii llll iiii llll iiii llll iiii llll iiii llll iiii llll iiii llll iiii llll iiii llll"},
+		{text: "🐱🐶🐭🐹🐰🦊🐻🐼🐨🐯🦁🐮🐷🐸🐵🐔🐧🐦🐤🦆🦅🦉🦇🐺🐗🐴🦄🐝🐛🦋🐌🐞🐜🦗🕷🦂🐢🐍🦎🦖🦕🐙🦑🦐🦀🐡🐠🐟🐬🐳🐋🦈🐊", family: "NotoEmoji"},
+		{text: "猫は犬よりも優れています。なぜなら、猫はより独立していて、注意をあまり必要としないからです。猫は一年中ネズミを家から遠ざけます。", family: "NotoSansCJKjp"},
+		{text: "חתולים טובים יותר מכלבים כי הם עצמאיים יותר וצריכים פחות תשומת לב, והם שומרים על הבית נקי מעכברים כל השנה", family: "NotoSansHebrew"},
+		{text: "القطط أفضل من الكلاب لأنها أكثر استقلالية وتحتاج إلى اهتمام أقل، وتبقي المنزل خاليا من الفئران طوال العام", family: "NotoSansArabic"},
+		{text: "The link http://someveryveryverylongurlgoeshere.com/and-the-url-doesnt-seem-to-end-anytime-soon/what-would-happen-now?id=123 says that TruStory is awesome."},
+	}
+	for _, family := range fallbackFontFamilies {
+		if fonts.closest(family, 400, false) == nil {
+			t.Fatalf("%s is not bundled, run make fonts", family)
+		}
+	}
+	store := newTemplateStore("")
+	for _, name := range store.list() {
+		tmpl, err := store.find(name)
+		if !assert.NoError(t, err) || tmpl.layout.Width == 0 {
+			continue
+		}
+		m := newTextMeasurer(fonts, tmpl.layout)
+		for _, text := range texts {
+			// measuring missing glyphs would only check the width of .notdef
+			for _, r := range text.text {
+				if unicode.IsSpace(r) {
+					continue
+				}
+				face, idx := fonts.glyph(&m.buf, m.face, r)
+				assert.NotZero(t, idx, "%s: no glyph for %q", name, r)
+				if text.family != "" && face != m.face {
+					assert.Equal(t, text.family, face.family, "%s: %q", name, r)
+				}
+			}
+			lines := wrapLines(text.text, tmpl.layout)
+			assert.Len(t, lines, tmpl.layout.Lines, name)
+			for _, line := range lines {
+				width := m.width(html.UnescapeString(line))
+				assert.True(t, width <= tmpl.layout.Width, "%s: %q is %.1f wide", name, line, width)
+			}
+		}
+		lines := wrapLines(strings.Repeat(texts[0].text+" ", 5), tmpl.layout)
+		assert.True(t, strings.HasSuffix(lines[len(lines)-1], ellipsis), name)
+	}
+}
+
+func TestWrapRendered(t *testing.T) {
+	r, err := newNativeRasterizer()
+	assert.NoError(t, err)
+	layout := Layout{Lines: 1, FontSize: 64, Width: 600, FontFamily: "Poppins", FontWeight: 700}
+	for _, text := range []string{"Wide WWW glyphs overflow a line counted in characters", "猫は犬よりも優れています。なぜなら", "Emoji 🐱🐶🐭🐹🐰🦊🐻🐼🐨🐯🦁🐮🐷🐸"} {
+		line := wrapLines(text, layout)[0]
+		svg := fmt.Sprintf(`<svg width="800" height="100" viewBox="0 0 800 100" xmlns="http://www.w3.org/2000/svg">`+
+			`<text font-family="Poppins" font-weight="700" font-size="64" xml:space="preserve"><tspan x="0" y="70">%s</tspan></text></svg>`, line)
+		img, err := r.Rasterize([]byte(svg), 800, 100)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		// glyphs may overhang their advance by a few pixels
+		right := 0
+		for y := 0; y < 100; y++ {
+			for x := 0; x < 800; x++ {
+				if _, _, _, a := img.At(x, y).RGBA(); a > 0 && x > right {
+					right = x
+				}
+			}
+		}
+		assert.True(t, right > 300, "%q is rendered %d wide", line, right)
+		assert.True(t, right <= 600+4, "%q is rendered %d wide", line, right)
+	}
+}
+
+func TestSegmentText(t *testing.T) {
+	texts := func(segments []textSegment) []string {
+		list := make([]string, 0)
+		for _, s := range segments {
+			list = append(list, s.text)
+		}
+		return list
+	}
+	assert.Equal(t, []string{"Cats", "are", "great!"}, texts(segmentText("Cats  are great!")))
+	assert.Equal(t, []string{"猫", "は", "犬", "で", "す。"}, texts(segmentText("猫は犬です。")))
+	assert.Equal(t, []string{"TruStory", "は", "最", "高"}, texts(segmentText("TruStory は最高")))
+	assert.Equal(t, []string{"Cats", "🐱", "👩\u200d💻", "👍🏽"}, texts(segmentText("Cats 🐱👩\u200d💻👍🏽")))
+}
+
+func TestVisualOrder(t *testing.T) {
+	assert.Equal(t, "Cats are great", visualOrder("Cats are great"))
+	assert.Equal(t, "םולש", visualOrder("שלום"))
+	assert.Equal(t, "TruStory הבוט", visualOrder("TruStory טובה"))
+	assert.Equal(t, "TruStory םולש", visualOrder("שלום TruStory"))
+}
+
+func TestEncodeGIF(t *testing.T) {
+	red := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	blue := image.NewRGBA(red.Bounds())
+	for i := 0; i < len(red.Pix); i += 4 {
+		copy(red.Pix[i:], []byte{0xe0, 0x20, 0x20, 0xff})
+		copy(blue.Pix[i:], []byte{0x20, 0x20, 0xe0, 0xff})
+	}
+	frames := []frame{
+		{img: red, delay: snippetHold},
+		{img: crossfade(red, blue, 0.5), delay: 100 * time.Millisecond},
+		{img: blue, delay: snippetHold},
+	}
+	buf := new(bytes.Buffer)
+	assert.NoError(t, encodeGIF(buf, frames))
+	anim, err := gif.DecodeAll(buf)
+	assert.NoError(t, err)
+	assert.Len(t, anim.Image, 3)
+	assert.Equal(t, []int{300, 10, 300}, anim.Delay)
+	r, _, b, _ := anim.Image[0].At(10, 10).RGBA()
+	assert.True(t, r>>8 > 0xc0 && b>>8 < 0x40)
+	r, _, b, _ = anim.Image[2].At(10, 10).RGBA()
+	assert.True(t, b>>8 > 0xc0 && r>>8 < 0x40)
+}
+
+func TestMetricsAuth(t *testing.T) {
+	metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	tests := []struct {
+		secret string
+		header string
+		want   int
+	}{
+		{"secret", "secret", http.StatusOK},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "wrong", http.StatusUnauthorized},
+		// metrics and jobs aren't served without a secret
+		{"", "", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+		if test.header != "" {
+			r.Header.Set("Metrics-Secret", test.header)
+		}
+		w := httptest.NewRecorder()
+		metricsAuth(test.secret, metrics).ServeHTTP(w, r)
+		assert.Equal(t, test.want, w.Code, "secret %q header %q", test.secret, test.header)
+	}
+}
+
+func TestJobQueue(t *testing.T) {
+	q := newJobQueue(1)
+	q.start(JobSnippet, 1)
+	release := make(chan struct{})
+	job, err := q.enqueue(JobSnippet, "claim/1", "twitter.gif", func() (string, error) {
+		<-release
+		return "key", nil
+	})
+	assert.NoError(t, err)
+	same, err := q.enqueue(JobSnippet, "claim/1", "twitter.gif", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, job.ID, same.ID)
+	failed, err := q.enqueue(JobSnippet, "claim/2", "twitter.gif", func() (string, error) {
+		return "", errors.New("claim 2 not found")
+	})
+	assert.NoError(t, err)
+	close(release)
+
+	wait := func(id string) Job {
+		for i := 0; i < 100; i++ {
+			job, ok := q.get(id)
+			assert.True(t, ok)
+			if job.Status == JobDone || job.Status == JobFailed {
+				return job
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("job %s didn't finish", id)
+		return Job{}
+	}
+	done := wait(job.ID)
+	assert.Equal(t, JobDone, done.Status)
+	assert.Equal(t, "key", done.key)
+	failedJob := wait(failed.ID)
+	assert.Equal(t, JobFailed, failedJob.Status)
+	assert.Equal(t, "claim 2 not found", failedJob.Error)
+
+	again, err := q.enqueue(JobSnippet, "claim/1", "twitter.gif", func() (string, error) { return "key", nil })
+	assert.NoError(t, err)
+	assert.NotEqual(t, job.ID, again.ID)
+}
+
+func TestTopArguments(t *testing.T) {
+	top := &ArgumentObject{ID: 2, UpvotedCount: 1}
+	claim := ClaimSnippetObject{
+		TopArgument: top,
+		Arguments: []ArgumentObject{
+			{ID: 1, UpvotedCount: 3},
+			{ID: 2, UpvotedCount: 1},
+			{ID: 3, UpvotedCount: 9},
+			{ID: 4, UpvotedCount: 0},
+		},
+	}
+	ids := make([]int64, 0)
+	for _, argument := range topArguments(claim) {
+		ids = append(ids, argument.ID)
+	}
+	assert.Equal(t, []int64{2, 3, 1}, ids)
+}
+
+func TestJobQueueRetries(t *testing.T) {
+	q := newJobQueue(3)
+	q.retryDelay = time.Millisecond
+	q.start(JobPrewarm, 2)
+	_, err := q.enqueue(JobSnippet, "claim/1", "twitter.gif", nil)
+	assert.Error(t, err)
+
+	runs := 0
+	flaky, err := q.enqueue(JobPrewarm, "claim/1", "og.png", func() (string, error) {
+		runs++
+		if runs < 3 {
+			return "", errors.New("graphql unavailable")
+		}
+		return "key", nil
+	})
+	assert.NoError(t, err)
+	broken, err := q.enqueue(JobPrewarm, "claim/2", "og.png", func() (string, error) {
+		return "", errors.New("claim 2 not found")
+	})
+	assert.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		a, _ := q.get(flaky.ID)
+		b, _ := q.get(broken.ID)
+		if a.finished() && b.finished() {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	job, _ := q.get(flaky.ID)
+	assert.Equal(t, JobDone, job.Status)
+	assert.Equal(t, 3, job.Attempts)
+	assert.Empty(t, job.Error)
+	job, _ = q.get(broken.ID)
+	assert.Equal(t, JobFailed, job.Status)
+	assert.Equal(t, 3, job.Attempts)
+	assert.Equal(t, "claim 2 not found", job.Error)
+	assert.Equal(t, JobStats{Workers: 2, Done: 1, Failed: 1}, q.stats()[JobPrewarm])
+}
@@ -0,0 +1,266 @@
+package spotlight
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/colornames"
+)
+
+// svgNode is a parsed SVG element.
+type svgNode struct {
+	name     string
+	attrs    map[string]string
+	parent   *svgNode
+	children []*svgNode
+	// text is the character data of text content elements.
+	text string
+}
+
+// inheritedAttrs are the presentation attributes children inherit from their parents.
+var inheritedAttrs = map[string]bool{
+	"fill":              true,
+	"fill-opacity":      true,
+	"fill-rule":         true,
+	"stroke":            true,
+	"stroke-width":      true,
+	"stroke-opacity":    true,
+	"stroke-linecap":    true,
+	"stroke-linejoin":   true,
+	"stroke-miterlimit": true,
+	"font-family":       true,
+	"font-size":         true,
+	"font-weight":       true,
+	"font-style":        true,
+	"letter-spacing":    true,
+	"text-anchor":       true,
+	"visibility":        true,
+	"xml:space":         true,
+	"white-space":       true,
+}
+
+// attr returns the value of an attribute, looking it up in the ancestors for inherited ones.
+func (n *svgNode) attr(name string) string {
+	for node := n; node != nil; node = node.parent {
+		if v, ok := node.attrs[name]; ok && v != "inherit" {
+			return v
+		}
+		if !inheritedAttrs[name] {
+			return ""
+		}
+	}
+	return ""
+}
+
+// number returns an attribute as a float, or def when missing or invalid.
+func (n *svgNode) number(name string, def float64) float64 {
+	v, ok := n.attrs[name]
+	if !ok {
+		return def
+	}
+	f, err := parseLength(v)
+	if err != nil {
+		return def
+	}
+	return f
+}
+
+// parseSVG parses a document into a tree of elements.
+func parseSVG(data []byte) (*svgNode, map[string]*svgNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	ids := make(map[string]*svgNode)
+	var root, current *svgNode
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &svgNode{name: t.Name.Local, attrs: make(map[string]string), parent: current}
+			for _, a := range t.Attr {
+				name := a.Name.Local
+				if a.Name.Space == "xml" || a.Name.Space == "http://www.w3.org/XML/1998/namespace" {
+					name = "xml:" + name
+				}
+				node.attrs[name] = strings.TrimSpace(a.Value)
+			}
+			// style declarations override presentation attributes
+			for _, declaration := range strings.Split(node.attrs["style"], ";") {
+				parts := strings.SplitN(declaration, ":", 2)
+				if len(parts) == 2 {
+					node.attrs[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+				}
+			}
+			if id, ok := node.attrs["id"]; ok {
+				ids[id] = node
+			}
+			if current == nil {
+				root = node
+			} else {
+				current.children = append(current.children, node)
+			}
+			current = node
+		case xml.EndElement:
+			if current != nil {
+				current = current.parent
+			}
+		case xml.CharData:
+			if current != nil && (current.name == "text" || current.name == "tspan") {
+				if len(current.children) == 0 || current.name == "tspan" {
+					current.text += string(t)
+				} else {
+					// character data between tspans is kept as an anonymous span
+					current.children = append(current.children, &svgNode{
+						name: "#text", attrs: map[string]string{}, parent: current, text: string(t),
+					})
+				}
+			}
+		}
+	}
+	if root == nil || root.name != "svg" {
+		return nil, nil, fmt.Errorf("invalid svg document")
+	}
+	return root, ids, nil
+}
+
+// parseLength parses a number with an optional px unit.
+func parseLength(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(s, "px")
+	return strconv.ParseFloat(s, 64)
+}
+
+// parseNumbers parses a list of numbers separated by commas or spaces.
+func parseNumbers(s string) []float64 {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	numbers := make([]float64, 0, len(fields))
+	for _, field := range fields {
+		f, err := parseLength(field)
+		if err != nil {
+			continue
+		}
+		numbers = append(numbers, f)
+	}
+	return numbers
+}
+
+// parseTransform parses a transform list like "translate(10 20) rotate(45)".
+func parseTransform(s string) rasterx.Matrix2D {
+	m := rasterx.Identity
+	for {
+		open := strings.IndexByte(s, '(')
+		close := strings.IndexByte(s, ')')
+		if open < 0 || close < open {
+			return m
+		}
+		name := strings.TrimSpace(strings.Trim(s[:open], ", "))
+		args := parseNumbers(s[open+1 : close])
+		s = s[close+1:]
+		arg := func(i int, def float64) float64 {
+			if i < len(args) {
+				return args[i]
+			}
+			return def
+		}
+		switch name {
+		case "matrix":
+			if len(args) == 6 {
+				m = m.Mult(rasterx.Matrix2D{A: args[0], B: args[1], C: args[2], D: args[3], E: args[4], F: args[5]})
+			}
+		case "translate":
+			m = m.Translate(arg(0, 0), arg(1, 0))
+		case "scale":
+			m = m.Scale(arg(0, 1), arg(1, arg(0, 1)))
+		case "rotate":
+			cx, cy := arg(1, 0), arg(2, 0)
+			m = m.Translate(cx, cy).Rotate(arg(0, 0)*math.Pi/180).Translate(-cx, -cy)
+		case "skewX":
+			m = m.SkewX(arg(0, 0) * math.Pi / 180)
+		case "skewY":
+			m = m.SkewY(arg(0, 0) * math.Pi / 180)
+		}
+	}
+}
+
+// parseColor parses hex, rgb() and named colors.
+func parseColor(s string) (color.NRGBA, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case strings.HasPrefix(s, "#"):
+		hex := s[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) != 6 {
+			return color.NRGBA{}, false
+		}
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return color.NRGBA{}, false
+		}
+		return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, true
+	case strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")"):
+		parts := strings.Split(s[4:len(s)-1], ",")
+		if len(parts) != 3 {
+			return color.NRGBA{}, false
+		}
+		c := color.NRGBA{A: 0xff}
+		channels := []*uint8{&c.R, &c.G, &c.B}
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			percent := strings.HasSuffix(part, "%")
+			v, err := strconv.ParseFloat(strings.TrimSuffix(part, "%"), 64)
+			if err != nil {
+				return color.NRGBA{}, false
+			}
+			if percent {
+				v = v * 255 / 100
+			}
+			*channels[i] = uint8(math.Max(0, math.Min(255, v)))
+		}
+		return c, true
+	}
+	if c, ok := colornames.Map[s]; ok {
+		return color.NRGBA{R: c.R, G: c.G, B: c.B, A: c.A}, true
+	}
+	return color.NRGBA{}, false
+}
+
+// parseURLRef returns the id referenced by a "url(#id)" value.
+func parseURLRef(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "url(") || !strings.HasSuffix(s, ")") {
+		return "", false
+	}
+	ref := strings.Trim(s[4:len(s)-1], `'" `)
+	if !strings.HasPrefix(ref, "#") {
+		return "", false
+	}
+	return ref[1:], true
+}
+
+// parseOpacity parses an opacity value clamped between 0 and 1.
+func parseOpacity(s string) float64 {
+	if s == "" {
+		return 1
+	}
+	percent := strings.HasSuffix(s, "%")
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 1
+	}
+	if percent {
+		v /= 100
+	}
+	return math.Max(0, math.Min(1, v))
+}
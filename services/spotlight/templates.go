@@ -0,0 +1,156 @@
+package spotlight
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/packr/v2"
+)
+
+// regexLayout matches the layout declared by a template, e.g.
+// <metadata id="spotlight-layout">{"lines": 3, "wordsPerLine": 7}</metadata>
+var regexLayout = regexp.MustCompile(`(?s)<metadata\s+id="spotlight-layout"\s*>(.*?)</metadata>`)
+
+// Layout is how a template wraps the text it shows.
+// Templates declaring the width of their text box are wrapped by measuring the text in their font,
+// the others by counting words and characters.
+type Layout struct {
+	Lines           int     `json:"lines"`
+	WordsPerLine    int     `json:"wordsPerLine"`
+	MaxCharsPerLine int     `json:"maxCharsPerLine"`
+	FontSize        float64 `json:"fontSize"`
+	Width           float64 `json:"width,omitempty"`
+	FontFamily      string  `json:"fontFamily,omitempty"`
+	FontWeight      int     `json:"fontWeight,omitempty"`
+	LetterSpacing   float64 `json:"letterSpacing,omitempty"`
+}
+
+// defaultLayout fills in the parameters a template doesn't declare.
+var defaultLayout = Layout{
+	Lines:           3,
+	WordsPerLine:    7,
+	MaxCharsPerLine: MAX_CHARS_PER_LINE,
+	FontSize:        75,
+}
+
+// previewTemplate is an SVG template along with its layout.
+type previewTemplate struct {
+	name    string
+	raw     []byte
+	layout  Layout
+	modTime time.Time
+}
+
+func parseTemplate(name string, raw []byte) (*previewTemplate, error) {
+	layout := defaultLayout
+	if match := regexLayout.FindSubmatch(raw); match != nil {
+		if err := json.Unmarshal(match[1], &layout); err != nil {
+			return nil, fmt.Errorf("invalid layout in %s: %s", name, err)
+		}
+	}
+	// wordWrap shortens long words to 23 characters
+	if layout.Lines < 1 || layout.FontSize <= 0 || (layout.Width <= 0 && (layout.WordsPerLine < 1 || layout.MaxCharsPerLine < 24)) {
+		return nil, fmt.Errorf("invalid layout in %s: %+v", name, layout)
+	}
+	return &previewTemplate{name: name, raw: raw, layout: layout}, nil
+}
+
+// templateStore finds templates in a directory, falling back to the ones bundled with packr.
+// Templates in the directory are parsed again whenever they are modified.
+type templateStore struct {
+	dir string
+	box *packr.Box
+	// onReload is called when a template in dir changed
+	onReload func(name string)
+
+	mu        sync.Mutex
+	templates map[string]*previewTemplate
+}
+
+func newTemplateStore(dir string) *templateStore {
+	return &templateStore{
+		dir:       dir,
+		box:       packr.New("Templates", "./templates"),
+		templates: make(map[string]*previewTemplate),
+	}
+}
+
+// find returns the template with the given file name.
+func (s *templateStore) find(name string) (*previewTemplate, error) {
+	if strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid template name %s", name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached := s.templates[name]
+	if s.dir != "" {
+		info, err := os.Stat(filepath.Join(s.dir, name))
+		if err == nil {
+			if cached != nil && cached.modTime.Equal(info.ModTime()) {
+				return cached, nil
+			}
+			raw, err := ioutil.ReadFile(filepath.Join(s.dir, name))
+			if err != nil {
+				return nil, err
+			}
+			t, err := parseTemplate(name, raw)
+			if err != nil {
+				return nil, err
+			}
+			t.modTime = info.ModTime()
+			s.templates[name] = t
+			if cached != nil && s.onReload != nil {
+				s.onReload(name)
+			}
+			return t, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if cached != nil && cached.modTime.IsZero() {
+		return cached, nil
+	}
+	raw, err := s.box.Find(name)
+	if err != nil {
+		return nil, err
+	}
+	t, err := parseTemplate(name, raw)
+	if err != nil {
+		return nil, err
+	}
+	s.templates[name] = t
+	return t, nil
+}
+
+// list returns the names of the available templates.
+func (s *templateStore) list() []string {
+	names := make(map[string]bool)
+	for _, name := range s.box.List() {
+		names[name] = true
+	}
+	if s.dir != "" {
+		files, err := ioutil.ReadDir(s.dir)
+		if err == nil {
+			for _, f := range files {
+				names[f.Name()] = true
+			}
+		}
+	}
+	list := make([]string, 0, len(names))
+	for name := range names {
+		if strings.HasSuffix(name, ".svg") {
+			list = append(list, name)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
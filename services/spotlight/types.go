@@ -28,6 +28,52 @@ const ClaimByIDQuery = `
 }
 `
 
+// ClaimSnippetQuery fetches a claim with its arguments for an animated snippet
+const ClaimSnippetQuery = `
+	query ClaimSnippetQuery($claimId: ID!) {
+		claim(id: $claimId) {
+			id
+			body
+			creator {
+				id
+				userProfile {
+					avatarURL
+					fullName
+					username
+				}
+			}
+			source
+			argumentCount
+			topArgument {
+				id
+				summary
+				creator {
+					address
+					userProfile {
+						avatarURL
+						fullName
+						username
+					}
+				}
+				upvotedCount
+			}
+			arguments {
+				id
+				summary
+				creator {
+					address
+					userProfile {
+						avatarURL
+						fullName
+						username
+					}
+				}
+				upvotedCount
+			}
+		}
+	}
+`
+
 // ArgumentByIDQuery fetches an argument by the given ID
 const ArgumentByIDQuery = `
 	query ArgumentQuery($argumentId: ID!) {
@@ -48,10 +94,81 @@ const ArgumentByIDQuery = `
 	}
 `
 
+// ProfileByAddressQuery fetches a user profile and its earnings by address
+const ProfileByAddressQuery = `
+	query ProfileQuery($address: String!) {
+		appAccount(id: $address) {
+			id
+			earnedBalance {
+				amount
+				humanReadable
+			}
+			userProfile {
+				avatarURL
+				fullName
+				username
+			}
+		}
+		appAccountCommunityEarnings(id: $address) {
+			community {
+				id
+				name
+			}
+			totalEarned {
+				amount
+				humanReadable
+			}
+		}
+	}
+`
+
+// CommunityByIDQuery fetches a community with its trending claim
+const CommunityByIDQuery = `
+	query CommunityQuery($communityId: String!) {
+		community(communityId: $communityId) {
+			id
+			name
+			description
+			heroImage
+		}
+		claims(communityId: $communityId, feedFilter: 1, first: 1) {
+			totalCount
+			edges {
+				node {
+					id
+					body
+					argumentCount
+				}
+			}
+		}
+	}
+`
+
 // CommunityObject defines the schema of a category
 type CommunityObject struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	HeroImage   string `json:"heroImage"`
+}
+
+// CoinObject defines the schema of an amount of coins
+type CoinObject struct {
+	Amount        string `json:"amount"`
+	HumanReadable string `json:"humanReadable"`
+}
+
+// AppAccountObject defines the schema of an account
+type AppAccountObject struct {
+	ID            string            `json:"id"`
+	EarnedBalance CoinObject        `json:"earnedBalance"`
+	UserProfile   UserProfileObject `json:"userProfile"`
+}
+
+// CommunityEarningObject defines the schema of the earnings of an account in a community
+type CommunityEarningObject struct {
+	Community   CommunityObject `json:"community"`
+	TotalEarned CoinObject      `json:"totalEarned"`
 }
 
 // UserObject defines the schema of a user
@@ -113,7 +230,42 @@ type ClaimByIDResponse struct {
 	Claim ClaimObject `json:"claim"`
 }
 
+// ClaimSnippetObject is a claim with its arguments
+type ClaimSnippetObject struct {
+	ClaimObject
+	TopArgument *ArgumentObject  `json:"topArgument"`
+	Arguments   []ArgumentObject `json:"arguments"`
+}
+
+// ClaimSnippetResponse defines the JSON response
+type ClaimSnippetResponse struct {
+	Claim *ClaimSnippetObject `json:"claim"`
+}
+
 // ArgumentByIDResponse defines the JSON response
 type ArgumentByIDResponse struct {
 	ClaimArgument ArgumentObject `json:"claimArgument"`
 }
+
+// ProfileByAddressResponse defines the JSON response
+type ProfileByAddressResponse struct {
+	AppAccount                  *AppAccountObject        `json:"appAccount"`
+	AppAccountCommunityEarnings []CommunityEarningObject `json:"appAccountCommunityEarnings"`
+}
+
+// ClaimConnection defines the schema of a paginated list of claims
+type ClaimConnection struct {
+	TotalCount int         `json:"totalCount"`
+	Edges      []ClaimEdge `json:"edges"`
+}
+
+// ClaimEdge defines the schema of a claim in a paginated list
+type ClaimEdge struct {
+	Node ClaimObject `json:"node"`
+}
+
+// CommunityByIDResponse defines the JSON response
+type CommunityByIDResponse struct {
+	Community *CommunityObject `json:"community"`
+	Claims    ClaimConnection  `json:"claims"`
+}
@@ -0,0 +1,571 @@
+package spotlight
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"sort"
+)
+
+// The encoder below writes lossless WebP (VP8L) images, as specified in
+// https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification.
+// It keeps to the subset of the format that suits rendered previews, large flat areas
+// and smooth gradients: the subtract green and predictor transforms, and backward
+// references to the pixel on the left or above.
+
+const (
+	webpMaxDimension = 1 << 14
+
+	// predictorBits is the log2 of the predictor tile size
+	predictorBits = 4
+
+	transformPredictor     = 0
+	transformSubtractGreen = 2
+
+	nLiteralCodes  = 256
+	nLengthCodes   = 24
+	nDistanceCodes = 40
+
+	maxMatchLength = 4096
+	minMatchLength = 3
+
+	// distance codes 1 and 2 refer to the pixel above and on the left
+	distanceCodeAbove = 1
+	distanceCodeLeft  = 2
+
+	maxCodeLength           = 15
+	maxCodeLengthCodeLength = 7
+)
+
+var codeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// encodeWebP writes img as a lossless WebP image.
+func encodeWebP(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > webpMaxDimension || height > webpMaxDimension {
+		return errors.New("webp: invalid image size")
+	}
+	argb, alpha := argbPixels(img)
+
+	bw := &bitWriter{}
+	bw.writeBits(0x2f, 8)
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	if alpha {
+		bw.writeBits(1, 1)
+	} else {
+		bw.writeBits(0, 1)
+	}
+	bw.writeBits(0, 3)
+
+	// the decoder undoes the transforms in reverse order
+	bw.writeBits(1, 1)
+	bw.writeBits(transformSubtractGreen, 2)
+	subtractGreen(argb)
+
+	bw.writeBits(1, 1)
+	bw.writeBits(transformPredictor, 2)
+	bw.writeBits(predictorBits-2, 3)
+	modes := predict(argb, width, height)
+	writeImage(bw, modes, tiles(width), false)
+
+	bw.writeBits(0, 1)
+	writeImage(bw, argb, width, true)
+	data := bw.flush()
+
+	size := len(data)
+	pad := size & 1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+size+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(size))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if pad != 0 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// argbPixels returns the non premultiplied pixels of img and whether any of them is transparent.
+func argbPixels(img image.Image) ([]uint32, bool) {
+	b := img.Bounds()
+	argb := make([]uint32, 0, b.Dx()*b.Dy())
+	alpha := false
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A != 0xff {
+				alpha = true
+			}
+			argb = append(argb, uint32(c.A)<<24|uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B))
+		}
+	}
+	return argb, alpha
+}
+
+func subtractGreen(argb []uint32) {
+	for i, p := range argb {
+		g := p >> 8 & 0xff
+		r := (p>>16 - g) & 0xff
+		b := (p - g) & 0xff
+		argb[i] = p&0xff00ff00 | r<<16 | b
+	}
+}
+
+func tiles(size int) int {
+	return (size + 1<<predictorBits - 1) >> predictorBits
+}
+
+// predict replaces the pixels by their residuals, choosing the predictor with
+// the smallest residuals for every tile. It returns the sub-image of predictor modes.
+func predict(argb []uint32, width, height int) []uint32 {
+	tilesX, tilesY := tiles(width), tiles(height)
+	modes := make([]uint32, tilesX*tilesY)
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			best, bestCost := 1, -1
+			for _, mode := range []int{1, 2, 7} {
+				cost := 0
+				forTile(tx, ty, width, height, func(x, y int) {
+					cost += residualCost(argb[y*width+x], predictor(argb, width, x, y, mode))
+				})
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			modes[ty*tilesX+tx] = 0xff000000 | uint32(best)<<8
+		}
+	}
+	// residuals are computed from the last pixel backwards, so predictions use the original pixels
+	for i := len(argb) - 1; i >= 0; i-- {
+		x, y := i%width, i/width
+		mode := int(modes[(y>>predictorBits)*tilesX+x>>predictorBits] >> 8 & 0xf)
+		argb[i] = subPixels(argb[i], predictor(argb, width, x, y, mode))
+	}
+	return modes
+}
+
+func forTile(tx, ty, width, height int, fn func(x, y int)) {
+	for y := ty << predictorBits; y < (ty+1)<<predictorBits && y < height; y++ {
+		for x := tx << predictorBits; x < (tx+1)<<predictorBits && x < width; x++ {
+			fn(x, y)
+		}
+	}
+}
+
+// predictor returns the prediction of the pixel at x, y. The first pixel is
+// predicted as opaque black, the rest of the first row from the left and
+// the first column from above, whatever the mode of their tile.
+func predictor(argb []uint32, width, x, y, mode int) uint32 {
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return argb[y*width+x-1]
+	case x == 0:
+		return argb[(y-1)*width+x]
+	}
+	left, top := argb[y*width+x-1], argb[(y-1)*width+x]
+	switch mode {
+	case 1:
+		return left
+	case 2:
+		return top
+	default:
+		return average2(left, top)
+	}
+}
+
+func average2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + (a & b)
+}
+
+// subPixels subtracts every channel modulo 256.
+func subPixels(a, b uint32) uint32 {
+	var p uint32
+	for shift := uint(0); shift < 32; shift += 8 {
+		p |= ((a>>shift - b>>shift) & 0xff) << shift
+	}
+	return p
+}
+
+func residualCost(p, prediction uint32) int {
+	cost := 0
+	r := subPixels(p, prediction)
+	for shift := uint(0); shift < 32; shift += 8 {
+		c := int(int8(r >> shift))
+		if c < 0 {
+			c = -c
+		}
+		cost += c
+	}
+	return cost
+}
+
+// token is a literal pixel, or a backward reference when length is set.
+type token struct {
+	pixel        uint32
+	length       int
+	distanceCode int
+}
+
+// writeImage entropy codes pixels with a single set of prefix codes.
+func writeImage(bw *bitWriter, argb []uint32, width int, topLevel bool) {
+	tokens := backwardReferences(argb, width)
+
+	var green [nLiteralCodes + nLengthCodes]int
+	var red, blue, alpha [nLiteralCodes]int
+	var distance [nDistanceCodes]int
+	for _, t := range tokens {
+		if t.length == 0 {
+			green[t.pixel>>8&0xff]++
+			red[t.pixel>>16&0xff]++
+			blue[t.pixel&0xff]++
+			alpha[t.pixel>>24]++
+			continue
+		}
+		symbol, _, _ := prefixEncode(t.length)
+		green[nLiteralCodes+symbol]++
+		symbol, _, _ = prefixEncode(t.distanceCode)
+		distance[symbol]++
+	}
+
+	// no color cache
+	bw.writeBits(0, 1)
+	if topLevel {
+		// no meta prefix codes
+		bw.writeBits(0, 1)
+	}
+	codes := [5]*prefixCode{
+		writePrefixCode(bw, green[:]),
+		writePrefixCode(bw, red[:]),
+		writePrefixCode(bw, blue[:]),
+		writePrefixCode(bw, alpha[:]),
+		writePrefixCode(bw, distance[:]),
+	}
+	for _, t := range tokens {
+		if t.length == 0 {
+			codes[0].write(bw, int(t.pixel>>8&0xff))
+			codes[1].write(bw, int(t.pixel>>16&0xff))
+			codes[2].write(bw, int(t.pixel&0xff))
+			codes[3].write(bw, int(t.pixel>>24))
+			continue
+		}
+		symbol, extraBits, extra := prefixEncode(t.length)
+		codes[0].write(bw, nLiteralCodes+symbol)
+		bw.writeBits(extra, extraBits)
+		symbol, extraBits, extra = prefixEncode(t.distanceCode)
+		codes[4].write(bw, symbol)
+		bw.writeBits(extra, extraBits)
+	}
+}
+
+// backwardReferences greedily replaces runs of pixels equal to the ones
+// on their left or above by backward references.
+func backwardReferences(argb []uint32, width int) []token {
+	tokens := make([]token, 0, len(argb)/4)
+	for i := 0; i < len(argb); {
+		length, code := 0, 0
+		if i >= width {
+			if n := matchLength(argb, i, width); n > length {
+				length, code = n, distanceCodeAbove
+			}
+		}
+		if i >= 1 {
+			if n := matchLength(argb, i, 1); n > length {
+				length, code = n, distanceCodeLeft
+			}
+		}
+		if length >= minMatchLength {
+			tokens = append(tokens, token{length: length, distanceCode: code})
+			i += length
+			continue
+		}
+		tokens = append(tokens, token{pixel: argb[i]})
+		i++
+	}
+	return tokens
+}
+
+func matchLength(argb []uint32, i, distance int) int {
+	n := 0
+	for i+n < len(argb) && n < maxMatchLength && argb[i+n] == argb[i+n-distance] {
+		n++
+	}
+	return n
+}
+
+// prefixEncode splits a length or distance code into a prefix symbol and extra bits.
+func prefixEncode(v int) (symbol int, extraBits uint, extra uint32) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	h := uint(0)
+	for d>>(h+1) != 0 {
+		h++
+	}
+	second := (d >> (h - 1)) & 1
+	return int(2*h) + second, h - 1, uint32(d & (1<<(h-1) - 1))
+}
+
+// prefixCode maps symbols to canonical Huffman codes.
+type prefixCode struct {
+	lengths []int
+	codes   []uint32
+}
+
+func (c *prefixCode) write(bw *bitWriter, symbol int) {
+	bw.writeBits(c.codes[symbol], uint(c.lengths[symbol]))
+}
+
+// writePrefixCode writes the prefix code for the histogram of an alphabet and returns it.
+func writePrefixCode(bw *bitWriter, histogram []int) *prefixCode {
+	var used []int
+	for symbol, n := range histogram {
+		if n > 0 {
+			used = append(used, symbol)
+		}
+	}
+	if len(used) == 0 {
+		used = []int{0}
+	}
+	if len(used) <= 2 && used[len(used)-1] < nLiteralCodes {
+		return writeSimplePrefixCode(bw, len(histogram), used)
+	}
+
+	code := newPrefixCode(histogram, maxCodeLength)
+	tokens := codeLengthTokens(code.lengths)
+	var clHistogram [19]int
+	for _, t := range tokens {
+		clHistogram[t.symbol]++
+	}
+	clCode := newPrefixCode(clHistogram[:], maxCodeLengthCodeLength)
+	n := len(codeLengthCodeOrder)
+	for n > 4 && clCode.lengths[codeLengthCodeOrder[n-1]] == 0 {
+		n--
+	}
+	bw.writeBits(0, 1)
+	bw.writeBits(uint32(n-4), 4)
+	for _, symbol := range codeLengthCodeOrder[:n] {
+		bw.writeBits(uint32(clCode.lengths[symbol]), 3)
+	}
+	// code lengths are written for the whole alphabet
+	bw.writeBits(0, 1)
+	for _, t := range tokens {
+		clCode.write(bw, t.symbol)
+		bw.writeBits(t.extra, t.extraBits)
+	}
+	return code
+}
+
+// writeSimplePrefixCode writes a code of one or two symbols below 256.
+// Single symbols take no bits in the image data.
+func writeSimplePrefixCode(bw *bitWriter, alphabetSize int, symbols []int) *prefixCode {
+	bw.writeBits(1, 1)
+	bw.writeBits(uint32(len(symbols)-1), 1)
+	if symbols[0] < 2 {
+		bw.writeBits(0, 1)
+		bw.writeBits(uint32(symbols[0]), 1)
+	} else {
+		bw.writeBits(1, 1)
+		bw.writeBits(uint32(symbols[0]), 8)
+	}
+	code := &prefixCode{lengths: make([]int, alphabetSize), codes: make([]uint32, alphabetSize)}
+	if len(symbols) == 2 {
+		bw.writeBits(uint32(symbols[1]), 8)
+		code.lengths[symbols[0]], code.lengths[symbols[1]] = 1, 1
+		code.codes[symbols[1]] = 1
+	}
+	return code
+}
+
+type codeLengthToken struct {
+	symbol    int
+	extraBits uint
+	extra     uint32
+}
+
+// codeLengthTokens run length encodes code lengths with the repeat codes 16, 17 and 18.
+func codeLengthTokens(lengths []int) []codeLengthToken {
+	var tokens []codeLengthToken
+	for i := 0; i < len(lengths); {
+		length := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == length {
+			run++
+		}
+		i += run
+		if length == 0 {
+			for run >= 11 {
+				n := run
+				if n > 138 {
+					n = 138
+				}
+				tokens = append(tokens, codeLengthToken{symbol: 18, extraBits: 7, extra: uint32(n - 11)})
+				run -= n
+			}
+			if run >= 3 {
+				tokens = append(tokens, codeLengthToken{symbol: 17, extraBits: 3, extra: uint32(run - 3)})
+				run = 0
+			}
+			for ; run > 0; run-- {
+				tokens = append(tokens, codeLengthToken{symbol: 0})
+			}
+			continue
+		}
+		tokens = append(tokens, codeLengthToken{symbol: length})
+		run--
+		for run >= 3 {
+			n := run
+			if n > 6 {
+				n = 6
+			}
+			tokens = append(tokens, codeLengthToken{symbol: 16, extraBits: 2, extra: uint32(n - 3)})
+			run -= n
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, codeLengthToken{symbol: length})
+		}
+	}
+	return tokens
+}
+
+// newPrefixCode builds a canonical Huffman code for a histogram, with at least two
+// symbols so every code is complete, and code lengths limited to maxLength.
+func newPrefixCode(histogram []int, maxLength int) *prefixCode {
+	freqs := make([]int, len(histogram))
+	copy(freqs, histogram)
+	used := 0
+	for _, f := range freqs {
+		if f > 0 {
+			used++
+		}
+	}
+	for symbol := 0; used < 2; symbol++ {
+		if freqs[symbol] == 0 {
+			freqs[symbol] = 1
+			used++
+		}
+	}
+	var lengths []int
+	for {
+		lengths = huffmanLengths(freqs)
+		longest := 0
+		for _, l := range lengths {
+			if l > longest {
+				longest = l
+			}
+		}
+		if longest <= maxLength {
+			break
+		}
+		// flatten the histogram until the tree is shallow enough
+		for i, f := range freqs {
+			if f > 0 {
+				freqs[i] = (f + 1) / 2
+			}
+		}
+	}
+
+	var count [maxCodeLength + 1]int
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [maxCodeLength + 1]uint32
+	code := uint32(0)
+	for l := 1; l <= maxCodeLength; l++ {
+		code = (code + uint32(count[l-1])) << 1
+		next[l] = code
+	}
+	codes := make([]uint32, len(lengths))
+	for symbol, l := range lengths {
+		if l > 0 {
+			codes[symbol] = reverseBits(next[l], uint(l))
+			next[l]++
+		}
+	}
+	return &prefixCode{lengths: lengths, codes: codes}
+}
+
+// reverseBits reverses the n bits of a code, which are read starting with the most significant one.
+func reverseBits(code uint32, n uint) uint32 {
+	var r uint32
+	for i := uint(0); i < n; i++ {
+		r = r<<1 | code>>i&1
+	}
+	return r
+}
+
+type huffmanNode struct {
+	freq        int
+	left, right int
+}
+
+// huffmanLengths returns the code length of every symbol with a non zero frequency.
+func huffmanLengths(freqs []int) []int {
+	var nodes []huffmanNode
+	var queue []int
+	leaves := make(map[int]int)
+	for symbol, f := range freqs {
+		if f > 0 {
+			leaves[len(nodes)] = symbol
+			queue = append(queue, len(nodes))
+			nodes = append(nodes, huffmanNode{freq: f, left: -1, right: -1})
+		}
+	}
+	for len(queue) > 1 {
+		sort.SliceStable(queue, func(i, j int) bool { return nodes[queue[i]].freq < nodes[queue[j]].freq })
+		a, b := queue[0], queue[1]
+		queue = append(queue[2:], len(nodes))
+		nodes = append(nodes, huffmanNode{freq: nodes[a].freq + nodes[b].freq, left: a, right: b})
+	}
+	lengths := make([]int, len(freqs))
+	var walk func(n, depth int)
+	walk = func(n, depth int) {
+		if nodes[n].left < 0 {
+			lengths[leaves[n]] = depth
+			return
+		}
+		walk(nodes[n].left, depth+1)
+		walk(nodes[n].right, depth+1)
+	}
+	walk(queue[0], 0)
+	return lengths
+}
+
+// bitWriter packs bits starting with the least significant one.
+type bitWriter struct {
+	buf   []byte
+	bits  uint64
+	nBits uint
+}
+
+func (bw *bitWriter) writeBits(v uint32, n uint) {
+	bw.bits |= uint64(v) << bw.nBits
+	bw.nBits += n
+	for bw.nBits >= 8 {
+		bw.buf = append(bw.buf, byte(bw.bits))
+		bw.bits >>= 8
+		bw.nBits -= 8
+	}
+}
+
+func (bw *bitWriter) flush() []byte {
+	if bw.nBits > 0 {
+		bw.buf = append(bw.buf, byte(bw.bits))
+		bw.bits, bw.nBits = 0, 0
+	}
+	return bw.buf
+}
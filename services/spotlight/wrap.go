@@ -0,0 +1,234 @@
+package spotlight
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// ellipsis ends text that doesn't fit, fonts without the character get three dots.
+const ellipsis = "…"
+
+// noBreakBefore are the characters a line can't start with, mostly CJK closing punctuation.
+const noBreakBefore = "、。，．・：；！？）」』】〕〉》ー々ゝゞヽヾぁぃぅぇぉっゃゅょァィゥェォッャュョ,.:;!?)]}%"
+
+// textMeasurer measures lines of text in a font the way the native renderer lays them out.
+type textMeasurer struct {
+	fonts   *fontSet
+	face    *fontFace
+	size    float64
+	spacing float64
+	buf     sfnt.Buffer
+}
+
+func newTextMeasurer(fonts *fontSet, layout Layout) *textMeasurer {
+	weight := layout.FontWeight
+	if weight == 0 {
+		weight = 400
+	}
+	return &textMeasurer{
+		fonts:   fonts,
+		face:    fonts.match(layout.FontFamily, weight, false),
+		size:    layout.FontSize,
+		spacing: layout.LetterSpacing,
+	}
+}
+
+// width is the advance of a line of text.
+func (m *textMeasurer) width(text string) float64 {
+	return m.fonts.shape(&m.buf, m.face, text, m.size, m.spacing, nil)
+}
+
+// ellipsis returns the ellipsis in the measured font.
+func (m *textMeasurer) ellipsis() string {
+	r, _ := utf8.DecodeRuneInString(ellipsis)
+	if idx, err := m.face.font.GlyphIndex(&m.buf, r); err == nil && idx != 0 {
+		return ellipsis
+	}
+	return "..."
+}
+
+// textSegment is a unit of text lines are only broken around.
+type textSegment struct {
+	text string
+	// space is whether the segment follows whitespace
+	space bool
+}
+
+// isCJK reports whether lines can be broken on both sides of r without whitespace.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
+
+// isEmoji reports whether r is a pictograph lines can be broken around.
+func isEmoji(r rune) bool {
+	return (r >= 0x1f300 && r <= 0x1faff) || (r >= 0x2600 && r <= 0x27bf)
+}
+
+// joinsPrevious reports whether r is part of the character before it, like emoji modifiers and combining marks.
+func joinsPrevious(r rune) bool {
+	return r == 0x200d || r == 0xfe0f || (r >= 0x1f3fb && r <= 0x1f3ff) || unicode.Is(unicode.Mn, r)
+}
+
+// segmentText splits text into words, and CJK text and emoji into characters.
+func segmentText(text string) []textSegment {
+	segments := make([]textSegment, 0)
+	var word strings.Builder
+	space := false
+	flush := func() {
+		if word.Len() > 0 {
+			segments = append(segments, textSegment{text: word.String(), space: space})
+			word.Reset()
+			space = false
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+			space = len(segments) > 0
+		case word.Len() == 0 && !space && len(segments) > 0 &&
+			(strings.ContainsRune(noBreakBefore, r) || joinsPrevious(r) || strings.HasSuffix(segments[len(segments)-1].text, "\u200d")):
+			segments[len(segments)-1].text += string(r)
+		case isCJK(r) || isEmoji(r):
+			flush()
+			segments = append(segments, textSegment{text: string(r), space: space})
+			space = false
+		default:
+			word.WriteRune(r)
+		}
+	}
+	flush()
+	return segments
+}
+
+// wrap breaks text into at most maxLines lines no wider than width.
+// Words wider than a line and the last line of text that doesn't fit are truncated with an ellipsis.
+func (m *textMeasurer) wrap(text string, width float64, maxLines int) []string {
+	lines := make([]string, 0)
+	line := ""
+	for _, segment := range segmentText(text) {
+		if line != "" {
+			candidate := line + segment.text
+			if segment.space {
+				candidate = line + " " + segment.text
+			}
+			if m.width(candidate) <= width {
+				line = candidate
+				continue
+			}
+			lines = append(lines, line)
+		}
+		line = segment.text
+		if m.width(line) > width {
+			line = m.truncate(line, width)
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		lines[maxLines-1] = m.truncate(lines[maxLines-1]+" ", width)
+	}
+	return lines
+}
+
+// truncate shortens text until it fits in width with an ellipsis.
+func (m *textMeasurer) truncate(text string, width float64) string {
+	ellipsis := m.ellipsis()
+	runes := []rune(strings.TrimRightFunc(text, unicode.IsSpace))
+	for len(runes) > 0 && m.width(string(runes)+ellipsis) > width {
+		runes = []rune(strings.TrimRightFunc(string(runes[:len(runes)-1]), unicode.IsSpace))
+	}
+	return string(runes) + ellipsis
+}
+
+// isRTL reports whether r is a strong right-to-left character.
+func isRTL(r rune) bool {
+	return unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko)
+}
+
+// visualOrder reorders a line of text from logical to display order for the native renderer.
+// Runs of right-to-left characters are reversed, and so is the order of the runs when the line
+// starts with a right-to-left character. Neutral characters between runs of the same direction
+// join them. It covers mixed Latin, Hebrew and Arabic text, not the whole bidi algorithm.
+func visualOrder(text string) string {
+	runes := []rune(text)
+	rtl := make([]bool, len(runes))
+	hasRTL := false
+	base := false
+	strongSeen := false
+	for i, r := range runes {
+		if isRTL(r) {
+			rtl[i], hasRTL = true, true
+			if !strongSeen {
+				base, strongSeen = true, true
+			}
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			strongSeen = true
+		}
+	}
+	if !hasRTL {
+		return text
+	}
+	// neutrals take the direction of their neighbours when they agree, the base direction otherwise
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if isRTL(r) || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(runes) && !(isRTL(runes[j]) || unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+			j++
+		}
+		before, after := base, base
+		if i > 0 {
+			before = rtl[i-1]
+		}
+		if j < len(runes) {
+			after = rtl[j]
+		}
+		dir := base
+		if before == after {
+			dir = before
+		}
+		for k := i; k < j; k++ {
+			rtl[k] = dir
+		}
+		i = j
+	}
+	runs := make([][]rune, 0)
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && rtl[j] == rtl[i] {
+			j++
+		}
+		run := append([]rune(nil), runes[i:j]...)
+		if rtl[i] {
+			reverseRunes(run)
+		}
+		runs = append(runs, run)
+		i = j
+	}
+	if base {
+		for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+			runs[i], runs[j] = runs[j], runs[i]
+		}
+	}
+	var b strings.Builder
+	for _, run := range runs {
+		b.WriteString(string(run))
+	}
+	return b.String()
+}
+
+func reverseRunes(runes []rune) {
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+}
@@ -0,0 +1,296 @@
+// Package analytics ingests the events tracked by the apps. Events are validated against a
+// schema registry, sampled, buffered and written in batches to the monthly partitions of
+// analytics_events, from where exporters forward them to BigQuery, Mixpanel or local files.
+package analytics
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 5 * time.Second
+	// bufferedBatches is the number of batches buffered before new events are dropped
+	bufferedBatches = 20
+)
+
+// ErrBatchTooLarge is returned when tracking more events at once than the batch size.
+var ErrBatchTooLarge = errors.New("analytics: too many events in the batch")
+
+// Event is an event tracked by a client.
+type Event struct {
+	Name       string                 `json:"event"`
+	Properties map[string]interface{} `json:"properties"`
+	// Time is when the client tracked the event, optional
+	Time *time.Time `json:"time"`
+}
+
+// Identity identifies who tracked the events, the address of a user or the session of an anonymous visitor.
+type Identity struct {
+	Address   string
+	SessionID string
+}
+
+// Rejection is an event of a batch that didn't match its schema.
+type Rejection struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// Result sums up what happened to a batch of events.
+type Result struct {
+	Accepted int `json:"accepted"`
+	// Sampled is the number of valid events left out by sampling
+	Sampled  int         `json:"sampled"`
+	Dropped  int         `json:"dropped"`
+	Rejected []Rejection `json:"rejected"`
+}
+
+// Store writes the events and keeps track of the exports.
+type Store interface {
+	EnsureAnalyticsEventPartition(month time.Time) error
+	AddAnalyticsEvents(events []db.AnalyticsEvent) error
+	AnalyticsEventsAfter(id int64, before time.Time, limit int) ([]db.AnalyticsEvent, error)
+	AnalyticsExportCursor(name string) (int64, error)
+	SaveAnalyticsExportCursor(name string, lastEventID int64) error
+}
+
+// Config configures the pipeline.
+type Config struct {
+	// SampleRate is the share of events kept for the events without their own, 0 keeps every event
+	SampleRate float64
+	// BatchSize is the largest batch tracked at once and written to the store at once
+	BatchSize     int
+	FlushInterval time.Duration
+	// ExportInterval is the interval between runs of the exporters
+	ExportInterval time.Duration
+	Exporters      []ExporterConfig
+}
+
+// Pipeline validates, samples and buffers events before writing them in batches.
+type Pipeline struct {
+	registry  *Registry
+	store     Store
+	config    Config
+	exporters []*exportJob
+
+	mu      sync.Mutex
+	buffer  []db.AnalyticsEvent
+	flushCh chan struct{}
+
+	// flushMu serializes the flushes, which own the partitions
+	flushMu    sync.Mutex
+	partitions map[string]bool
+}
+
+// New creates a pipeline accepting the events of the registry.
+func New(registry *Registry, store Store, config Config) (*Pipeline, error) {
+	if config.SampleRate < 0 || config.SampleRate > 1 {
+		return nil, fmt.Errorf("analytics: sample rate must be between 0 and 1")
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultFlushInterval
+	}
+	p := &Pipeline{
+		registry:   registry,
+		store:      store,
+		config:     config,
+		partitions: make(map[string]bool),
+		flushCh:    make(chan struct{}, 1),
+	}
+	for _, c := range config.Exporters {
+		job, err := newExportJob(c, registry)
+		if err != nil {
+			return nil, err
+		}
+		p.exporters = append(p.exporters, job)
+	}
+	return p, nil
+}
+
+// Registry returns the registry of the events accepted by the pipeline.
+func (p *Pipeline) Registry() *Registry {
+	return p.registry
+}
+
+// BatchSize is the largest number of events tracked at once.
+func (p *Pipeline) BatchSize() int {
+	return p.config.BatchSize
+}
+
+// Track validates and samples a batch of events and buffers the kept ones. Invalid events
+// are rejected one by one, the rest of the batch is still tracked.
+func (p *Pipeline) Track(identity Identity, events []Event) (*Result, error) {
+	if len(events) > p.config.BatchSize {
+		return nil, ErrBatchTooLarge
+	}
+	result := &Result{Rejected: make([]Rejection, 0)}
+	kept := make([]db.AnalyticsEvent, 0, len(events))
+	for i, event := range events {
+		err := p.registry.Validate(event)
+		if err != nil {
+			result.Rejected = append(result.Rejected, Rejection{Index: i, Error: err.Error()})
+			continue
+		}
+		rate := p.sampleRate(event.Name)
+		if !sampled(rate, identity) {
+			result.Sampled++
+			continue
+		}
+		properties := event.Properties
+		if properties == nil {
+			properties = make(map[string]interface{})
+		}
+		kept = append(kept, db.AnalyticsEvent{
+			Event:      event.Name,
+			Address:    identity.Address,
+			SessionID:  identity.SessionID,
+			Properties: properties,
+			SampleRate: rate,
+			ClientTime: event.Time,
+		})
+	}
+
+	p.mu.Lock()
+	room := p.config.BatchSize*bufferedBatches - len(p.buffer)
+	if room < 0 {
+		room = 0
+	}
+	if len(kept) > room {
+		result.Dropped = len(kept) - room
+		kept = kept[:room]
+	}
+	p.buffer = append(p.buffer, kept...)
+	full := len(p.buffer) >= p.config.BatchSize
+	p.mu.Unlock()
+	result.Accepted = len(kept)
+	if result.Dropped > 0 {
+		log.Printf("analytics: buffer is full, dropped %d events\n", result.Dropped)
+	}
+	if full {
+		select {
+		case p.flushCh <- struct{}{}:
+		default:
+		}
+	}
+	return result, nil
+}
+
+// Flush writes the buffered events in batches. Events that couldn't be written stay buffered
+// and are written by the next flush.
+func (p *Pipeline) Flush() error {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+	for {
+		p.mu.Lock()
+		n := len(p.buffer)
+		if n > p.config.BatchSize {
+			n = p.config.BatchSize
+		}
+		batch := make([]db.AnalyticsEvent, n)
+		copy(batch, p.buffer[:n])
+		p.mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+
+		// events are stored in the partition of when they are written
+		now := time.Now().UTC()
+		for i := range batch {
+			batch[i].CreatedAt = now
+		}
+		err := p.ensurePartition(now)
+		if err != nil {
+			return err
+		}
+		err = p.store.AddAnalyticsEvents(batch)
+		if err != nil {
+			return err
+		}
+
+		p.mu.Lock()
+		p.buffer = p.buffer[n:]
+		p.mu.Unlock()
+	}
+}
+
+// Run writes the buffered events every flush interval, or as soon as a batch is full,
+// and runs the exporters every export interval until stop is closed.
+func (p *Pipeline) Run(stop <-chan struct{}) {
+	if len(p.exporters) > 0 && p.config.ExportInterval > 0 {
+		go p.runExporters(stop)
+	}
+	ticker := time.NewTicker(p.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.flushCh:
+		case <-stop:
+			err := p.Flush()
+			if err != nil {
+				log.Println("analytics: couldn't write the buffered events", err)
+			}
+			return
+		}
+		err := p.Flush()
+		if err != nil {
+			log.Println("analytics: couldn't write the buffered events", err)
+		}
+	}
+}
+
+func (p *Pipeline) ensurePartition(t time.Time) error {
+	month := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	key := month.Format("2006-01")
+	if p.partitions[key] {
+		return nil
+	}
+	err := p.store.EnsureAnalyticsEventPartition(month)
+	if err != nil {
+		return err
+	}
+	p.partitions[key] = true
+	return nil
+}
+
+func (p *Pipeline) sampleRate(event string) float64 {
+	if schema, ok := p.registry.Schema(event); ok && schema.SampleRate > 0 {
+		return schema.SampleRate
+	}
+	if p.config.SampleRate > 0 {
+		return p.config.SampleRate
+	}
+	return 1
+}
+
+// sampled decides whether an event is kept. The decision only depends on the user or session,
+// which is kept for all their events with a rate above its hash, so sampled events still make
+// up whole funnels.
+func sampled(rate float64, identity Identity) bool {
+	if rate >= 1 {
+		return true
+	}
+	id := identity.Address
+	if id == "" {
+		id = identity.SessionID
+	}
+	if id == "" {
+		return rand.Float64() < rate
+	}
+	h := sha256.Sum256([]byte(id))
+	return float64(binary.BigEndian.Uint64(h[:8]))/math.MaxUint64 < rate
+}
@@ -0,0 +1,173 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+type memoryStore struct {
+	partitions []time.Time
+	events     []db.AnalyticsEvent
+	cursors    map[string]int64
+	fail       bool
+}
+
+func (s *memoryStore) EnsureAnalyticsEventPartition(month time.Time) error {
+	s.partitions = append(s.partitions, month)
+	return nil
+}
+
+func (s *memoryStore) AddAnalyticsEvents(events []db.AnalyticsEvent) error {
+	if s.fail {
+		return fmt.Errorf("store is down")
+	}
+	for _, e := range events {
+		e.ID = int64(len(s.events) + 1)
+		s.events = append(s.events, e)
+	}
+	return nil
+}
+
+func (s *memoryStore) AnalyticsEventsAfter(id int64, before time.Time, limit int) ([]db.AnalyticsEvent, error) {
+	events := make([]db.AnalyticsEvent, 0)
+	for _, e := range s.events {
+		if e.ID > id && e.CreatedAt.Before(before) && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (s *memoryStore) AnalyticsExportCursor(name string) (int64, error) {
+	return s.cursors[name], nil
+}
+
+func (s *memoryStore) SaveAnalyticsExportCursor(name string, lastEventID int64) error {
+	s.cursors[name] = lastEventID
+	return nil
+}
+
+type memoryExporter struct {
+	events []db.AnalyticsEvent
+}
+
+func (e *memoryExporter) Export(ctx context.Context, events []db.AnalyticsEvent) error {
+	e.events = append(e.events, events...)
+	return nil
+}
+
+func newTestPipeline(t *testing.T, config Config) (*Pipeline, *memoryStore) {
+	registry, err := NewRegistry([]Schema{
+		{Name: "claim_opened", Properties: map[string]PropertyType{"claimId": PropertyNumber}, Required: []string{"claimId"}},
+		{Name: "app_opened"},
+		{Name: "feed_scrolled", SampleRate: 0.5},
+	})
+	assert.NoError(t, err)
+	store := &memoryStore{cursors: make(map[string]int64)}
+	pipeline, err := New(registry, store, config)
+	assert.NoError(t, err)
+	return pipeline, store
+}
+
+func TestPipelineTrackAndFlush(t *testing.T) {
+	pipeline, store := newTestPipeline(t, Config{BatchSize: 2})
+	identity := Identity{Address: "cosmos1xqc5gwzpgdr4wjz8xscnys2jx3f9x4zy223g9w"}
+
+	_, err := pipeline.Track(identity, make([]Event, 3))
+	assert.Equal(t, ErrBatchTooLarge, err)
+
+	result, err := pipeline.Track(identity, []Event{
+		{Name: "claim_opened", Properties: map[string]interface{}{"claimId": float64(1)}},
+		{Name: "claim_opened"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Accepted)
+	assert.Equal(t, []Rejection{{Index: 1, Error: `property "claimId" is required`}}, result.Rejected)
+	_, err = pipeline.Track(Identity{SessionID: "session"}, []Event{{Name: "app_opened"}, {Name: "app_opened"}})
+	assert.NoError(t, err)
+
+	store.fail = true
+	assert.Error(t, pipeline.Flush())
+	assert.Len(t, store.events, 0)
+
+	// the events are kept until they're written
+	store.fail = false
+	assert.NoError(t, pipeline.Flush())
+	assert.Len(t, store.events, 3)
+	assert.Len(t, store.partitions, 1)
+	assert.Equal(t, identity.Address, store.events[0].Address)
+	assert.Equal(t, float64(1), store.events[0].SampleRate)
+	assert.Equal(t, "session", store.events[1].SessionID)
+	assert.NotNil(t, store.events[1].Properties)
+	assert.NoError(t, pipeline.Flush())
+	assert.Len(t, store.events, 3)
+}
+
+func TestPipelineBufferLimit(t *testing.T) {
+	pipeline, _ := newTestPipeline(t, Config{BatchSize: 1})
+	for i := 0; i < bufferedBatches; i++ {
+		result, err := pipeline.Track(Identity{}, []Event{{Name: "app_opened"}})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Accepted)
+	}
+	result, err := pipeline.Track(Identity{}, []Event{{Name: "app_opened"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Accepted)
+	assert.Equal(t, 1, result.Dropped)
+}
+
+func TestPipelineSampling(t *testing.T) {
+	pipeline, store := newTestPipeline(t, Config{BatchSize: 1000})
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		identity := Identity{SessionID: fmt.Sprintf("session-%d", i)}
+		result, err := pipeline.Track(identity, []Event{{Name: "feed_scrolled"}, {Name: "feed_scrolled"}})
+		assert.NoError(t, err)
+		// all the events of a session are kept or left out together
+		assert.True(t, result.Accepted == 0 || result.Accepted == 2)
+		assert.Equal(t, 2, result.Accepted+result.Sampled)
+		kept += result.Accepted / 2
+	}
+	assert.InDelta(t, 500, kept, 75)
+	assert.NoError(t, pipeline.Flush())
+	assert.Equal(t, 0.5, store.events[0].SampleRate)
+
+	assert.True(t, sampled(1, Identity{}))
+	assert.Equal(t, sampled(0.3, Identity{Address: "a"}), sampled(0.3, Identity{Address: "a"}))
+}
+
+func TestPipelineExport(t *testing.T) {
+	pipeline, store := newTestPipeline(t, Config{})
+	all := &memoryExporter{}
+	claims := &memoryExporter{}
+	pipeline.exporters = []*exportJob{
+		{name: "all", exporter: all},
+		{name: "claims", exporter: claims, events: map[string]bool{"claim_opened": true}},
+	}
+	old := time.Now().Add(-time.Hour)
+	store.events = []db.AnalyticsEvent{
+		{ID: 1, Event: "app_opened", CreatedAt: old},
+		{ID: 2, Event: "claim_opened", CreatedAt: old},
+		// too recent to be exported yet
+		{ID: 3, Event: "claim_opened", CreatedAt: time.Now()},
+	}
+
+	pipeline.Export()
+	assert.Len(t, all.events, 2)
+	assert.Len(t, claims.events, 1)
+	assert.Equal(t, int64(2), store.cursors["all"])
+	assert.Equal(t, int64(2), store.cursors["claims"])
+
+	// exports resume from the cursor
+	store.events[2].CreatedAt = old
+	pipeline.Export()
+	assert.Len(t, all.events, 3)
+	assert.Len(t, claims.events, 2)
+	assert.Equal(t, int64(3), store.cursors["all"])
+}
@@ -0,0 +1,101 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/googleapi"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+var bigQuerySchema = bigquery.Schema{
+	{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+	{Name: "event", Type: bigquery.StringFieldType, Required: true},
+	{Name: "address", Type: bigquery.StringFieldType},
+	{Name: "session_id", Type: bigquery.StringFieldType},
+	{Name: "properties", Type: bigquery.StringFieldType},
+	{Name: "sample_rate", Type: bigquery.FloatFieldType},
+	{Name: "client_time", Type: bigquery.TimestampFieldType},
+	{Name: "created_at", Type: bigquery.TimestampFieldType, Required: true},
+}
+
+// bigQueryExporter streams the events into a BigQuery table partitioned by day of created_at.
+// The credentials are found by the client, e.g. GOOGLE_APPLICATION_CREDENTIALS.
+type bigQueryExporter struct {
+	config ExporterConfig
+	table  *bigquery.Table
+}
+
+func newBigQueryExporter(config ExporterConfig) (*bigQueryExporter, error) {
+	if config.Project == "" || config.Dataset == "" {
+		return nil, fmt.Errorf("analytics: bigquery exporter needs a project and a dataset")
+	}
+	if config.Table == "" {
+		config.Table = "events"
+	}
+	return &bigQueryExporter{config: config}, nil
+}
+
+func (e *bigQueryExporter) Export(ctx context.Context, events []db.AnalyticsEvent) error {
+	if e.table == nil {
+		table, err := e.openTable(ctx)
+		if err != nil {
+			return err
+		}
+		e.table = table
+	}
+	rows := make([]*bigQueryEvent, 0, len(events))
+	for i := range events {
+		rows = append(rows, &bigQueryEvent{&events[i]})
+	}
+	return e.table.Inserter().Put(ctx, rows)
+}
+
+func (e *bigQueryExporter) openTable(ctx context.Context) (*bigquery.Table, error) {
+	client, err := bigquery.NewClient(ctx, e.config.Project)
+	if err != nil {
+		return nil, err
+	}
+	table := client.Dataset(e.config.Dataset).Table(e.config.Table)
+	_, err = table.Metadata(ctx)
+	if apiErr, ok := err.(*googleapi.Error); ok && apiErr.Code == http.StatusNotFound {
+		err = table.Create(ctx, &bigquery.TableMetadata{
+			Schema:           bigQuerySchema,
+			TimePartitioning: &bigquery.TimePartitioning{Field: "created_at"},
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// bigQueryEvent saves an event as a row, deduped by its id.
+type bigQueryEvent struct {
+	*db.AnalyticsEvent
+}
+
+func (e *bigQueryEvent) Save() (map[string]bigquery.Value, string, error) {
+	properties, err := json.Marshal(e.Properties)
+	if err != nil {
+		return nil, "", err
+	}
+	row := map[string]bigquery.Value{
+		"id":          e.ID,
+		"event":       e.Event,
+		"address":     e.Address,
+		"session_id":  e.SessionID,
+		"properties":  string(properties),
+		"sample_rate": e.SampleRate,
+		"created_at":  e.CreatedAt,
+	}
+	if e.ClientTime != nil {
+		row["client_time"] = *e.ClientTime
+	}
+	return row, strconv.FormatInt(e.ID, 10), nil
+}
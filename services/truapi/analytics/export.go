@@ -0,0 +1,153 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+// Types of exporters.
+const (
+	ExporterBigQuery = "bigquery"
+	ExporterMixpanel = "mixpanel"
+	ExporterFile     = "file"
+)
+
+const (
+	exportBatchSize = 500
+	// exportLag keeps the exports behind the latest events, so events written by a slower
+	// transaction with a lower id aren't skipped
+	exportLag = time.Minute
+)
+
+// Exporter forwards stored events to another system. Events are exported at least once,
+// exporters dedupe them by id where the destination allows it.
+type Exporter interface {
+	Export(ctx context.Context, events []db.AnalyticsEvent) error
+}
+
+// ExporterConfig selects and configures an exporter.
+type ExporterConfig struct {
+	// Name identifies the exporter's cursor, it defaults to the type
+	Name string
+	// Type is ExporterBigQuery, ExporterMixpanel or ExporterFile
+	Type string
+	// Events restricts the exported events, all of them are exported when empty
+	Events []string
+	// Project, Dataset and Table locate the BigQuery table, created when missing
+	Project string
+	Dataset string
+	Table   string
+	// Token is the Mixpanel project token
+	Token string
+	// Dir is the directory of the files and Format is csv, ndjson or parquet
+	Dir    string
+	Format string
+}
+
+// NewExporter creates the exporter selected by the config.
+func NewExporter(config ExporterConfig) (Exporter, error) {
+	switch config.Type {
+	case ExporterBigQuery:
+		return newBigQueryExporter(config)
+	case ExporterMixpanel:
+		return newMixpanelExporter(config)
+	case ExporterFile:
+		return newFileExporter(config)
+	}
+	return nil, fmt.Errorf("analytics: unknown exporter %q", config.Type)
+}
+
+type exportJob struct {
+	name     string
+	exporter Exporter
+	// events are the exported events, nil exports all of them
+	events map[string]bool
+}
+
+func newExportJob(config ExporterConfig, registry *Registry) (*exportJob, error) {
+	exporter, err := NewExporter(config)
+	if err != nil {
+		return nil, err
+	}
+	job := &exportJob{name: config.Name, exporter: exporter}
+	if job.name == "" {
+		job.name = config.Type
+	}
+	if len(config.Events) > 0 {
+		job.events = make(map[string]bool)
+		for _, name := range config.Events {
+			if _, ok := registry.Schema(name); !ok {
+				return nil, fmt.Errorf("analytics: exporter %s exports unknown event %s", job.name, name)
+			}
+			job.events[name] = true
+		}
+	}
+	return job, nil
+}
+
+// Export runs every exporter until it has caught up with the stored events.
+func (p *Pipeline) Export() {
+	for _, job := range p.exporters {
+		err := p.export(job)
+		if err != nil {
+			log.Printf("analytics: exporter %s failed: %s\n", job.name, err)
+		}
+	}
+}
+
+func (p *Pipeline) export(job *exportJob) error {
+	cursor, err := p.store.AnalyticsExportCursor(job.name)
+	if err != nil {
+		return err
+	}
+	before := time.Now().UTC().Add(-exportLag)
+	for {
+		events, err := p.store.AnalyticsEventsAfter(cursor, before, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		exported := events
+		if job.events != nil {
+			exported = make([]db.AnalyticsEvent, 0, len(events))
+			for _, e := range events {
+				if job.events[e.Event] {
+					exported = append(exported, e)
+				}
+			}
+		}
+		if len(exported) > 0 {
+			err = job.exporter.Export(context.Background(), exported)
+			if err != nil {
+				return err
+			}
+		}
+		cursor = events[len(events)-1].ID
+		err = p.store.SaveAnalyticsExportCursor(job.name, cursor)
+		if err != nil {
+			return err
+		}
+		if len(events) < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+func (p *Pipeline) runExporters(stop <-chan struct{}) {
+	ticker := time.NewTicker(p.config.ExportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.Export()
+		case <-stop:
+			return
+		}
+	}
+}
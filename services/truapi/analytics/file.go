@@ -0,0 +1,157 @@
+package analytics
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+// Formats of the file exporter.
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+var csvHeader = []string{"id", "event", "address", "session_id", "properties", "sample_rate", "client_time", "created_at"}
+
+// fileExporter appends the events to a file per day in a directory, events-2006-01-02.csv.
+// Parquet files can't be appended to, each export writes a file per day named after its first
+// event, events-2006-01-02-1.parquet, so exporting a batch again overwrites its files.
+type fileExporter struct {
+	dir    string
+	format string
+}
+
+func newFileExporter(config ExporterConfig) (*fileExporter, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("analytics: file exporter needs a directory")
+	}
+	format := config.Format
+	if format == "" {
+		format = FormatCSV
+	}
+	if format != FormatCSV && format != FormatNDJSON && format != FormatParquet {
+		return nil, fmt.Errorf("analytics: file exporter doesn't support the %s format", format)
+	}
+	err := os.MkdirAll(config.Dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &fileExporter{dir: config.Dir, format: format}, nil
+}
+
+func (e *fileExporter) Export(ctx context.Context, events []db.AnalyticsEvent) error {
+	byDay := make(map[string][]db.AnalyticsEvent)
+	days := make([]string, 0)
+	for _, event := range events {
+		day := event.CreatedAt.UTC().Format("2006-01-02")
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+		byDay[day] = append(byDay[day], event)
+	}
+	for _, day := range days {
+		if e.format == FormatParquet {
+			path := filepath.Join(e.dir, fmt.Sprintf("events-%s-%d.%s", day, byDay[day][0].ID, e.format))
+			err := writeParquetFile(path, byDay[day])
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err := e.write(filepath.Join(e.dir, fmt.Sprintf("events-%s.%s", day, e.format)), byDay[day])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *fileExporter) write(path string, events []db.AnalyticsEvent) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	if e.format == FormatNDJSON {
+		err = writeNDJSON(f, events)
+	} else {
+		err = writeCSV(f, events, info.Size() == 0)
+	}
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeParquetFile(path string, events []db.AnalyticsEvent) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = writeParquet(f, events)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeNDJSON(f *os.File, events []db.AnalyticsEvent) error {
+	encoder := json.NewEncoder(f)
+	for _, event := range events {
+		err := encoder.Encode(event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSV(f *os.File, events []db.AnalyticsEvent, header bool) error {
+	w := csv.NewWriter(f)
+	if header {
+		err := w.Write(csvHeader)
+		if err != nil {
+			return err
+		}
+	}
+	for _, event := range events {
+		properties, err := json.Marshal(event.Properties)
+		if err != nil {
+			return err
+		}
+		clientTime := ""
+		if event.ClientTime != nil {
+			clientTime = event.ClientTime.UTC().Format(time.RFC3339)
+		}
+		err = w.Write([]string{
+			strconv.FormatInt(event.ID, 10),
+			event.Event,
+			event.Address,
+			event.SessionID,
+			string(properties),
+			strconv.FormatFloat(event.SampleRate, 'f', -1, 64),
+			clientTime,
+			event.CreatedAt.UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,52 @@
+package analytics
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+func TestFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "analytics")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = NewExporter(ExporterConfig{Type: ExporterFile, Dir: dir, Format: "xml"})
+	assert.Error(t, err)
+
+	for _, format := range []string{FormatCSV, FormatNDJSON} {
+		exporter, err := NewExporter(ExporterConfig{Type: ExporterFile, Dir: filepath.Join(dir, format), Format: format})
+		assert.NoError(t, err)
+		day := time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC)
+		events := []db.AnalyticsEvent{
+			{ID: 1, Event: "claim_opened", Address: "cosmos1", Properties: map[string]interface{}{"claimId": 1}, SampleRate: 1, CreatedAt: day},
+			{ID: 2, Event: "app_opened", SessionID: "session", SampleRate: 0.5, CreatedAt: day.Add(24 * time.Hour)},
+		}
+		assert.NoError(t, exporter.Export(context.Background(), events[:1]))
+		assert.NoError(t, exporter.Export(context.Background(), events))
+
+		first, err := ioutil.ReadFile(filepath.Join(dir, format, "events-2019-11-20."+format))
+		assert.NoError(t, err)
+		second, err := ioutil.ReadFile(filepath.Join(dir, format, "events-2019-11-21."+format))
+		assert.NoError(t, err)
+		switch format {
+		case FormatCSV:
+			// the header is written once per file
+			assert.Equal(t, 3, strings.Count(string(first), "\n"))
+			assert.True(t, strings.HasPrefix(string(first), strings.Join(csvHeader, ",")+"\n"))
+			assert.Contains(t, string(first), `1,claim_opened,cosmos1,,"{""claimId"":1}",1,,2019-11-20T10:00:00Z`)
+			assert.Contains(t, string(second), "2,app_opened,,session,null,0.5,,2019-11-21T10:00:00Z")
+		case FormatNDJSON:
+			assert.Equal(t, 2, strings.Count(string(first), "\n"))
+			assert.Contains(t, string(second), `"event":"app_opened"`)
+		}
+	}
+}
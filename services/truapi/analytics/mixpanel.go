@@ -0,0 +1,52 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/dukex/mixpanel"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+// mixpanelExporter tracks the events in a Mixpanel project, deduped by their $insert_id.
+type mixpanelExporter struct {
+	client mixpanel.Mixpanel
+}
+
+func newMixpanelExporter(config ExporterConfig) (*mixpanelExporter, error) {
+	if config.Token == "" {
+		return nil, fmt.Errorf("analytics: mixpanel exporter needs a token")
+	}
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	return &mixpanelExporter{client: mixpanel.NewFromClient(httpClient, config.Token, "")}, nil
+}
+
+func (e *mixpanelExporter) Export(ctx context.Context, events []db.AnalyticsEvent) error {
+	for _, event := range events {
+		distinctID := event.Address
+		if distinctID == "" {
+			distinctID = event.SessionID
+		}
+		properties := make(map[string]interface{}, len(event.Properties)+2)
+		for k, v := range event.Properties {
+			properties[k] = v
+		}
+		properties["$insert_id"] = fmt.Sprintf("%d", event.ID)
+		properties["sample_rate"] = event.SampleRate
+		timestamp := event.CreatedAt
+		if event.ClientTime != nil {
+			timestamp = *event.ClientTime
+		}
+		err := e.client.Track(distinctID, event.Event, &mixpanel.Event{
+			Timestamp:  &timestamp,
+			Properties: properties,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
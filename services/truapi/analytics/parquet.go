@@ -0,0 +1,336 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+// A Parquet file of events has a single row group with a single uncompressed, plain encoded page
+// per column. The metadata is Thrift compact encoded, as described by parquet-format's parquet.thrift.
+
+var parquetMagic = []byte("PAR1")
+
+// parquet.thrift enums
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+	parquetJSON            = 19
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetDataPage     = 0
+	parquetUncompressed = 0
+)
+
+// parquetColumn is a column of the events file, value appends the plain encoded value of an event
+// and returns false when it's null.
+type parquetColumn struct {
+	name      string
+	kind      int32
+	converted int32
+	optional  bool
+	value     func(b *bytes.Buffer, event db.AnalyticsEvent) (bool, error)
+}
+
+var parquetColumns = []parquetColumn{
+	{"id", parquetInt64, -1, false, func(b *bytes.Buffer, event db.AnalyticsEvent) (bool, error) {
+		return true, binary.Write(b, binary.LittleEndian, event.ID)
+	}},
+	{"event", parquetByteArray, parquetUTF8, false, func(b *bytes.Buffer, event db.AnalyticsEvent) (bool, error) {
+		return true, writeParquetByteArray(b, []byte(event.Event))
+	}},
+	{"address", parquetByteArray, parquetUTF8, false, func(b *bytes.Buffer, event db.AnalyticsEvent) (bool, error) {
+		return true, writeParquetByteArray(b, []byte(event.Address))
+	}},
+	{"session_id", parquetByteArray, parquetUTF8, false, func(b *bytes.Buffer, event db.AnalyticsEvent) (bool, error) {
+		return true, writeParquetByteArray(b, []byte(event.SessionID))
+	}},
+	{"properties", parquetByteArray, parquetJSON, false, func(b *bytes.Buffer, event db.AnalyticsEvent) (bool, error) {
+		properties, err := json.Marshal(event.Properties)
+		if err != nil {
+			return false, err
+		}
+		return true, writeParquetByteArray(b, properties)
+	}},
+	{"sample_rate", parquetDouble, -1, false, func(b *bytes.Buffer, event db.AnalyticsEvent) (bool, error) {
+		return true, binary.Write(b, binary.LittleEndian, math.Float64bits(event.SampleRate))
+	}},
+	{"client_time", parquetInt64, parquetTimestampMillis, true, func(b *bytes.Buffer, event db.AnalyticsEvent) (bool, error) {
+		if event.ClientTime == nil {
+			return false, nil
+		}
+		return true, binary.Write(b, binary.LittleEndian, event.ClientTime.UnixNano()/1e6)
+	}},
+	{"created_at", parquetInt64, parquetTimestampMillis, false, func(b *bytes.Buffer, event db.AnalyticsEvent) (bool, error) {
+		return true, binary.Write(b, binary.LittleEndian, event.CreatedAt.UnixNano()/1e6)
+	}},
+}
+
+func writeParquetByteArray(b *bytes.Buffer, value []byte) error {
+	err := binary.Write(b, binary.LittleEndian, uint32(len(value)))
+	if err != nil {
+		return err
+	}
+	_, err = b.Write(value)
+	return err
+}
+
+// parquetChunk locates a column chunk written in the file.
+type parquetChunk struct {
+	column parquetColumn
+	offset int64
+	size   int64
+}
+
+// writeParquet writes the events as a Parquet file.
+func writeParquet(w io.Writer, events []db.AnalyticsEvent) error {
+	file := new(bytes.Buffer)
+	file.Write(parquetMagic)
+	chunks := make([]parquetChunk, 0, len(parquetColumns))
+	for _, column := range parquetColumns {
+		page, err := parquetPage(column, events)
+		if err != nil {
+			return err
+		}
+		offset := int64(file.Len())
+		file.Write(page)
+		chunks = append(chunks, parquetChunk{column: column, offset: offset, size: int64(len(page))})
+	}
+	footer := parquetFileMetaData(chunks, int64(len(events)))
+	file.Write(footer)
+	err := binary.Write(file, binary.LittleEndian, uint32(len(footer)))
+	if err != nil {
+		return err
+	}
+	file.Write(parquetMagic)
+	_, err = w.Write(file.Bytes())
+	return err
+}
+
+// parquetPage returns the header and the data of the page holding a column of the events.
+// The definition levels of an optional column precede its values, 0 for null and 1 otherwise.
+func parquetPage(column parquetColumn, events []db.AnalyticsEvent) ([]byte, error) {
+	values := new(bytes.Buffer)
+	levels := make([]bool, 0, len(events))
+	for _, event := range events {
+		defined, err := column.value(values, event)
+		if err != nil {
+			return nil, err
+		}
+		levels = append(levels, defined)
+	}
+	data := new(bytes.Buffer)
+	if column.optional {
+		encoded := parquetLevels(levels)
+		err := binary.Write(data, binary.LittleEndian, uint32(len(encoded)))
+		if err != nil {
+			return nil, err
+		}
+		data.Write(encoded)
+	}
+	data.Write(values.Bytes())
+
+	header := newThriftWriter()
+	header.i32(1, parquetDataPage)
+	header.i32(2, int32(data.Len()))
+	header.i32(3, int32(data.Len()))
+	header.beginStruct(5)
+	header.i32(1, int32(len(events)))
+	header.i32(2, parquetPlain)
+	header.i32(3, parquetRLE)
+	header.i32(4, parquetRLE)
+	header.endStruct()
+	header.stop()
+	return append(header.bytes(), data.Bytes()...), nil
+}
+
+// parquetLevels encodes definition levels of bit width 1 with the RLE/bit-packing hybrid,
+// as runs of repeated levels.
+func parquetLevels(levels []bool) []byte {
+	encoded := make([]byte, 0)
+	for start := 0; start < len(levels); {
+		end := start
+		for end < len(levels) && levels[end] == levels[start] {
+			end++
+		}
+		encoded = appendUvarint(encoded, uint64(end-start)<<1)
+		if levels[start] {
+			encoded = append(encoded, 1)
+		} else {
+			encoded = append(encoded, 0)
+		}
+		start = end
+	}
+	return encoded
+}
+
+func parquetFileMetaData(chunks []parquetChunk, rows int64) []byte {
+	t := newThriftWriter()
+	t.i32(1, 1)
+	t.list(2, thriftStruct, len(chunks)+1)
+	t.beginElement()
+	t.binary(4, []byte("schema"))
+	t.i32(5, int32(len(chunks)))
+	t.endStruct()
+	for _, chunk := range chunks {
+		t.beginElement()
+		t.i32(1, chunk.column.kind)
+		repetition := int32(parquetRequired)
+		if chunk.column.optional {
+			repetition = parquetOptional
+		}
+		t.i32(3, repetition)
+		t.binary(4, []byte(chunk.column.name))
+		if chunk.column.converted >= 0 {
+			t.i32(6, chunk.column.converted)
+		}
+		t.endStruct()
+	}
+	t.i64(3, rows)
+	t.list(4, thriftStruct, 1)
+	t.beginElement()
+	t.list(1, thriftStruct, len(chunks))
+	total := int64(0)
+	for _, chunk := range chunks {
+		t.beginElement()
+		t.i64(2, chunk.offset)
+		t.beginStruct(3)
+		t.i32(1, chunk.column.kind)
+		if chunk.column.optional {
+			t.list(2, thriftI32, 2)
+			t.element32(parquetPlain)
+			t.element32(parquetRLE)
+		} else {
+			t.list(2, thriftI32, 1)
+			t.element32(parquetPlain)
+		}
+		t.list(3, thriftBinary, 1)
+		t.elementBinary([]byte(chunk.column.name))
+		t.i32(4, parquetUncompressed)
+		t.i64(5, rows)
+		t.i64(6, chunk.size)
+		t.i64(7, chunk.size)
+		t.i64(9, chunk.offset)
+		t.endStruct()
+		t.endStruct()
+		total += chunk.size
+	}
+	t.i64(2, total)
+	t.i64(3, rows)
+	t.endStruct()
+	t.binary(6, []byte("truapi"))
+	t.stop()
+	return t.bytes()
+}
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter writes a struct with the Thrift compact protocol. Field ids are delta encoded
+// against the previous field of the struct being written.
+type thriftWriter struct {
+	buf  []byte
+	last []int16
+}
+
+func newThriftWriter() *thriftWriter {
+	return &thriftWriter{last: []int16{0}}
+}
+
+func (t *thriftWriter) bytes() []byte {
+	return t.buf
+}
+
+func (t *thriftWriter) field(id int16, kind byte) {
+	delta := id - t.last[len(t.last)-1]
+	if delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|kind)
+	} else {
+		t.buf = append(t.buf, kind)
+		t.buf = appendUvarint(t.buf, zigzag(int64(id)))
+	}
+	t.last[len(t.last)-1] = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.element32(v)
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.buf = appendUvarint(t.buf, zigzag(v))
+}
+
+func (t *thriftWriter) binary(id int16, v []byte) {
+	t.field(id, thriftBinary)
+	t.elementBinary(v)
+}
+
+func (t *thriftWriter) list(id int16, kind byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|kind)
+	} else {
+		t.buf = append(t.buf, 0xf0|kind)
+		t.buf = appendUvarint(t.buf, uint64(size))
+	}
+}
+
+func (t *thriftWriter) element32(v int32) {
+	t.buf = appendUvarint(t.buf, zigzag(int64(v)))
+}
+
+func (t *thriftWriter) elementBinary(v []byte) {
+	t.buf = appendUvarint(t.buf, uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
+
+// beginStruct starts a struct field, beginElement a struct in a list, both end with endStruct.
+func (t *thriftWriter) beginStruct(id int16) {
+	t.field(id, thriftStruct)
+	t.beginElement()
+}
+
+func (t *thriftWriter) beginElement() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftWriter) endStruct() {
+	t.stop()
+	t.last = t.last[:len(t.last)-1]
+}
+
+// stop ends the top level struct.
+func (t *thriftWriter) stop() {
+	t.buf = append(t.buf, 0)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64((v << 1) ^ (v >> 63))
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, v)
+	return append(b, buf[:n]...)
+}
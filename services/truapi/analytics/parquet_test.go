@@ -0,0 +1,222 @@
+package analytics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"flag"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+var update = flag.Bool("update", false, "update testdata/events.parquet")
+
+// readThrift decodes a Thrift compact struct into its fields by id, without knowing its schema.
+func readThrift(r *bufio.Reader) (map[int16]interface{}, error) {
+	fields := make(map[int16]interface{})
+	last := int16(0)
+	for {
+		header, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if header == 0 {
+			return fields, nil
+		}
+		id := last + int16(header>>4)
+		if header>>4 == 0 {
+			v, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, err
+			}
+			id = int16(unzigzag(v))
+		}
+		last = id
+		fields[id], err = readThriftValue(r, header&0x0f)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func readThriftValue(r *bufio.Reader, kind byte) (interface{}, error) {
+	switch kind {
+	case thriftI32, thriftI64:
+		v, err := binary.ReadUvarint(r)
+		return unzigzag(v), err
+	case thriftBinary:
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		v := make([]byte, size)
+		_, err = io.ReadFull(r, v)
+		return string(v), err
+	case thriftList:
+		header, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			size, err = binary.ReadUvarint(r)
+			if err != nil {
+				return nil, err
+			}
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i], err = readThriftValue(r, header&0x0f)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case thriftStruct:
+		return readThrift(r)
+	}
+	return nil, io.ErrUnexpectedEOF
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func TestWriteParquet(t *testing.T) {
+	created := time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC)
+	clientTime := created.Add(-time.Second)
+	events := []db.AnalyticsEvent{
+		{ID: 1, Event: "claim_opened", Address: "cosmos1", Properties: map[string]interface{}{"claimId": 1}, SampleRate: 1, CreatedAt: created},
+		{ID: 2, Event: "app_opened", SessionID: "session", SampleRate: 0.5, ClientTime: &clientTime, CreatedAt: created},
+		{ID: 3, Event: "app_opened", SampleRate: 0.5, CreatedAt: created},
+	}
+	b := new(bytes.Buffer)
+	assert.NoError(t, writeParquet(b, events))
+	file := b.Bytes()
+
+	// testdata/events.parquet was read back with the reader of github.com/xitongsys/parquet-go,
+	// read a file written with -update with a Parquet reader too before checking it in
+	golden := filepath.Join("testdata", "events.parquet")
+	if *update {
+		assert.NoError(t, ioutil.WriteFile(golden, file, 0644))
+	}
+	expected, err := ioutil.ReadFile(golden)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, file, "the file differs from testdata/events.parquet")
+
+	assert.Equal(t, "PAR1", string(file[:4]))
+	assert.Equal(t, "PAR1", string(file[len(file)-4:]))
+
+	size := binary.LittleEndian.Uint32(file[len(file)-8:])
+	footer := file[len(file)-8-int(size) : len(file)-8]
+	meta, err := readThrift(bufio.NewReader(bytes.NewReader(footer)))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), meta[3])
+	schema := meta[2].([]interface{})
+	assert.Len(t, schema, len(parquetColumns)+1)
+	assert.Equal(t, int64(len(parquetColumns)), schema[0].(map[int16]interface{})[5])
+	rowGroups := meta[4].([]interface{})
+	assert.Len(t, rowGroups, 1)
+	chunks := rowGroups[0].(map[int16]interface{})[1].([]interface{})
+	assert.Len(t, chunks, len(parquetColumns))
+
+	// reads back the plain values of a column, nil for nulls
+	column := func(i int) []interface{} {
+		element := schema[i+1].(map[int16]interface{})
+		columnMeta := chunks[i].(map[int16]interface{})[3].(map[int16]interface{})
+		assert.Equal(t, parquetColumns[i].name, element[4])
+		assert.Equal(t, []interface{}{parquetColumns[i].name}, columnMeta[3])
+		assert.Equal(t, int64(3), columnMeta[5])
+
+		r := bufio.NewReader(bytes.NewReader(file[columnMeta[9].(int64):]))
+		header, err := readThrift(r)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(parquetDataPage), header[1])
+		assert.Equal(t, int64(3), header[5].(map[int16]interface{})[1])
+		data := make([]byte, header[2].(int64))
+		_, err = io.ReadFull(r, data)
+		assert.NoError(t, err)
+
+		defined := []bool{true, true, true}
+		if element[3] == int64(parquetOptional) {
+			levels := data[4 : 4+binary.LittleEndian.Uint32(data)]
+			data = data[4+len(levels):]
+			defined = defined[:0]
+			lr := bufio.NewReader(bytes.NewReader(levels))
+			for len(defined) < 3 {
+				run, err := binary.ReadUvarint(lr)
+				assert.NoError(t, err)
+				level, err := lr.ReadByte()
+				assert.NoError(t, err)
+				for j := uint64(0); j < run>>1; j++ {
+					defined = append(defined, level == 1)
+				}
+			}
+		}
+		values := make([]interface{}, 0)
+		for _, ok := range defined {
+			if !ok {
+				values = append(values, nil)
+				continue
+			}
+			switch element[1] {
+			case int64(parquetInt64):
+				values = append(values, int64(binary.LittleEndian.Uint64(data)))
+				data = data[8:]
+			case int64(parquetDouble):
+				values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(data)))
+				data = data[8:]
+			case int64(parquetByteArray):
+				n := binary.LittleEndian.Uint32(data)
+				values = append(values, string(data[4:4+n]))
+				data = data[4+n:]
+			}
+		}
+		assert.Empty(t, data)
+		return values
+	}
+	millis := created.UnixNano() / 1e6
+	assert.Equal(t, []interface{}{int64(1), int64(2), int64(3)}, column(0))
+	assert.Equal(t, []interface{}{"claim_opened", "app_opened", "app_opened"}, column(1))
+	assert.Equal(t, []interface{}{"cosmos1", "", ""}, column(2))
+	assert.Equal(t, []interface{}{"", "session", ""}, column(3))
+	assert.Equal(t, []interface{}{`{"claimId":1}`, "null", "null"}, column(4))
+	assert.Equal(t, []interface{}{1.0, 0.5, 0.5}, column(5))
+	assert.Equal(t, []interface{}{nil, millis - 1000, nil}, column(6))
+	assert.Equal(t, []interface{}{millis, millis, millis}, column(7))
+}
+
+func TestFileExporterParquet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "analytics")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	exporter, err := NewExporter(ExporterConfig{Type: ExporterFile, Dir: dir, Format: FormatParquet})
+	assert.NoError(t, err)
+	day := time.Date(2019, 11, 20, 10, 0, 0, 0, time.UTC)
+	events := []db.AnalyticsEvent{
+		{ID: 1, Event: "claim_opened", SampleRate: 1, CreatedAt: day},
+		{ID: 2, Event: "app_opened", SampleRate: 1, CreatedAt: day.Add(24 * time.Hour)},
+		{ID: 3, Event: "app_opened", SampleRate: 1, CreatedAt: day.Add(24 * time.Hour)},
+	}
+	assert.NoError(t, exporter.Export(context.Background(), events[:2]))
+	assert.NoError(t, exporter.Export(context.Background(), events[2:]))
+	// a file per day and export, named after its first event
+	files, err := filepath.Glob(filepath.Join(dir, "*.parquet"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "events-2019-11-20-1.parquet"),
+		filepath.Join(dir, "events-2019-11-21-2.parquet"),
+		filepath.Join(dir, "events-2019-11-21-3.parquet"),
+	}, files)
+}
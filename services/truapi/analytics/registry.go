@@ -0,0 +1,118 @@
+package analytics
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// PropertyType is the type of an event property.
+type PropertyType string
+
+// Types of the event properties, as decoded from JSON.
+const (
+	PropertyString  PropertyType = "string"
+	PropertyNumber  PropertyType = "number"
+	PropertyBoolean PropertyType = "boolean"
+	PropertyObject  PropertyType = "object"
+	PropertyArray   PropertyType = "array"
+)
+
+var eventNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{0,99}$`)
+
+// Schema declares an event and the properties it can have.
+type Schema struct {
+	Name       string
+	Properties map[string]PropertyType
+	Required   []string
+	// SampleRate is the share of the events kept, 0 uses the default rate
+	SampleRate float64
+}
+
+// Registry holds the schemas of the events accepted by the pipeline.
+type Registry struct {
+	schemas map[string]Schema
+}
+
+// NewRegistry validates the schemas and registers them, a later schema replaces an earlier one
+// with the same name so the config can override the built-in events.
+func NewRegistry(schemas []Schema) (*Registry, error) {
+	r := &Registry{schemas: make(map[string]Schema)}
+	for _, schema := range schemas {
+		if !eventNameRegex.MatchString(schema.Name) {
+			return nil, fmt.Errorf("analytics: invalid event name %q", schema.Name)
+		}
+		for name, t := range schema.Properties {
+			switch t {
+			case PropertyString, PropertyNumber, PropertyBoolean, PropertyObject, PropertyArray:
+			default:
+				return nil, fmt.Errorf("analytics: event %s property %s has unknown type %q", schema.Name, name, t)
+			}
+		}
+		for _, name := range schema.Required {
+			if _, ok := schema.Properties[name]; !ok {
+				return nil, fmt.Errorf("analytics: event %s requires undeclared property %s", schema.Name, name)
+			}
+		}
+		if schema.SampleRate < 0 || schema.SampleRate > 1 {
+			return nil, fmt.Errorf("analytics: event %s sample rate must be between 0 and 1", schema.Name)
+		}
+		r.schemas[schema.Name] = schema
+	}
+	return r, nil
+}
+
+// Schema returns the schema of an event.
+func (r *Registry) Schema(name string) (Schema, bool) {
+	schema, ok := r.schemas[name]
+	return schema, ok
+}
+
+// Names returns the names of the registered events, sorted.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.schemas))
+	for name := range r.schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks an event against its schema. Unknown events and properties are rejected.
+func (r *Registry) Validate(event Event) error {
+	schema, ok := r.schemas[event.Name]
+	if !ok {
+		return fmt.Errorf("unknown event %q", event.Name)
+	}
+	for name, value := range event.Properties {
+		t, ok := schema.Properties[name]
+		if !ok {
+			return fmt.Errorf("unknown property %q", name)
+		}
+		if value != nil && !hasType(value, t) {
+			return fmt.Errorf("property %q must be a %s", name, t)
+		}
+	}
+	for _, name := range schema.Required {
+		if event.Properties[name] == nil {
+			return fmt.Errorf("property %q is required", name)
+		}
+	}
+	return nil
+}
+
+func hasType(value interface{}, t PropertyType) bool {
+	switch value.(type) {
+	case string:
+		return t == PropertyString
+	case float64, int, int64:
+		return t == PropertyNumber
+	case bool:
+		return t == PropertyBoolean
+	case map[string]interface{}:
+		return t == PropertyObject
+	case []interface{}:
+		return t == PropertyArray
+	}
+	return false
+}
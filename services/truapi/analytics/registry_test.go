@@ -0,0 +1,66 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRegistry(t *testing.T) {
+	_, err := NewRegistry([]Schema{{Name: "Claim Opened"}})
+	assert.Error(t, err)
+	_, err = NewRegistry([]Schema{{Name: "claim_opened", Properties: map[string]PropertyType{"claimId": "int"}}})
+	assert.Error(t, err)
+	_, err = NewRegistry([]Schema{{Name: "claim_opened", Required: []string{"claimId"}}})
+	assert.Error(t, err)
+	_, err = NewRegistry([]Schema{{Name: "claim_opened", SampleRate: 2}})
+	assert.Error(t, err)
+
+	registry, err := NewRegistry([]Schema{
+		{Name: "claim_opened", Properties: map[string]PropertyType{"claimId": PropertyNumber}},
+		{Name: "claim_opened", Properties: map[string]PropertyType{"claimId": PropertyString}},
+		{Name: "app_opened"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app_opened", "claim_opened"}, registry.Names())
+	schema, ok := registry.Schema("claim_opened")
+	assert.True(t, ok)
+	assert.Equal(t, PropertyString, schema.Properties["claimId"])
+}
+
+func TestRegistryValidate(t *testing.T) {
+	registry, err := NewRegistry([]Schema{{
+		Name: "argument_opened",
+		Properties: map[string]PropertyType{
+			"claimId":  PropertyNumber,
+			"source":   PropertyString,
+			"featured": PropertyBoolean,
+			"tags":     PropertyArray,
+			"context":  PropertyObject,
+		},
+		Required: []string{"claimId"},
+	}})
+	assert.NoError(t, err)
+
+	assert.NoError(t, registry.Validate(Event{Name: "argument_opened", Properties: map[string]interface{}{
+		"claimId":  float64(1),
+		"source":   "feed",
+		"featured": true,
+		"tags":     []interface{}{"a"},
+		"context":  map[string]interface{}{"a": 1},
+	}}))
+	// optional properties can be null
+	assert.NoError(t, registry.Validate(Event{Name: "argument_opened", Properties: map[string]interface{}{
+		"claimId": int64(1),
+		"source":  nil,
+	}}))
+	assert.EqualError(t, registry.Validate(Event{Name: "claim_shared"}), `unknown event "claim_shared"`)
+	assert.EqualError(t, registry.Validate(Event{Name: "argument_opened"}), `property "claimId" is required`)
+	assert.EqualError(t, registry.Validate(Event{Name: "argument_opened", Properties: map[string]interface{}{
+		"claimId": "1",
+	}}), `property "claimId" must be a number`)
+	assert.EqualError(t, registry.Validate(Event{Name: "argument_opened", Properties: map[string]interface{}{
+		"claimId": float64(1),
+		"user":    "alice",
+	}}), `unknown property "user"`)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"time"
 
 	"github.com/tendermint/tendermint/crypto/secp256k1"
 
@@ -26,6 +27,8 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	truCtx "github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/logging"
+	"github.com/TruStory/octopus/services/truapi/telemetry"
 )
 
 // MsgTypes is a map of `Msg` type names to empty instances
@@ -45,9 +48,11 @@ type API struct {
 	router    *mux.Router
 }
 
-// NewAPI creates an `API` struct from a client context and a `MsgTypes` schema
+// NewAPI creates an `API` struct from a client context and a `MsgTypes` schema.
+// Every request is assigned an ID, echoed in the response, and a logger tagged with it.
 func NewAPI(apiCtx truCtx.TruAPIContext, supported MsgTypes) *API {
 	a := API{apiCtx: apiCtx, Supported: supported, router: mux.NewRouter()}
+	a.router.Use(logging.Middleware(logging.New("truapi")))
 	return &a
 }
 
@@ -225,6 +230,15 @@ func (a *API) signAndBroadcastRegistrationTx(addr []byte, k tcmn.HexBytes, algo
 	return res, nil
 }
 
+// GiftUnconfirmedError is returned when a gift was broadcast without a result, it may still be included in a block.
+type GiftUnconfirmedError struct {
+	Err error
+}
+
+func (e GiftUnconfirmedError) Error() string {
+	return fmt.Sprintf("gift broadcast but not confirmed: %s", e.Err)
+}
+
 // SendGiftToAddress sends gift coins to any user
 func (a *API) SendGiftToAddress(address string, amount sdk.Coin, brokerAccountNumber, brokerSequence uint64, memo string) error {
 	recipient, err := sdk.AccAddressFromBech32(address)
@@ -232,10 +246,14 @@ func (a *API) SendGiftToAddress(address string, amount sdk.Coin, brokerAccountNu
 		return err
 	}
 
-	_, err = a.signAndBroadcastGiftTx(recipient, amount, brokerAccountNumber, brokerSequence, memo)
+	res, err := a.signAndBroadcastGiftTx(recipient, amount, brokerAccountNumber, brokerSequence, memo)
 	if err != nil {
 		return err
 	}
+	// the transaction is included in a block even when the gift fails
+	if res.Code != 0 {
+		return fmt.Errorf("gift failed with code %d: %s", res.Code, res.RawLog)
+	}
 
 	return nil
 }
@@ -272,6 +290,7 @@ func (a *API) signAndBroadcastGiftTx(recipient sdk.AccAddress, amount sdk.Coin,
 	res, err = cliCtx.WithBroadcastMode(client.BroadcastBlock).BroadcastTx(txBytes)
 	if err != nil {
 		fmt.Println(err)
+		err = GiftUnconfirmedError{err}
 		return
 	}
 	fmt.Println(res)
@@ -287,12 +306,7 @@ func (a *API) RunQuery(path string, params interface{}) ([]byte, error) {
 		return nil, err
 	}
 
-	res, _, err := a.apiCtx.QueryWithData("/custom/"+path, paramBytes)
-	if err != nil {
-		return res, err
-	}
-
-	return res, nil
+	return a.queryWithData(path, paramBytes)
 }
 
 // Query dispatches a query to the Tendermint node with Amino encoded params
@@ -301,7 +315,17 @@ func (a *API) Query(path string, params interface{}, cdc *codec.Codec) ([]byte,
 	if err != nil {
 		return nil, err
 	}
-	res, _, err := a.apiCtx.QueryWithData("/custom/"+path, paramBytes)
+
+	return a.queryWithData(path, paramBytes)
+}
+
+// queryWithData queries a custom path of the Tendermint node and records how long it took
+func (a *API) queryWithData(path string, data []byte) ([]byte, error) {
+	start := time.Now()
+	res, _, err := a.apiCtx.QueryWithData("/custom/"+path, data)
+	telemetry.ChainQueryDuration.
+		WithLabelValues(path, telemetry.Status(err)).
+		Observe(time.Since(start).Seconds())
 	if err != nil {
 		return res, err
 	}
@@ -1,8 +1,9 @@
 package chttp
 
 import (
-	"fmt"
 	"net/http"
+
+	"github.com/TruStory/octopus/services/truapi/logging"
 )
 
 // Handler is an http.Handler that renders a chttp.Response
@@ -15,7 +16,7 @@ func (h Handler) HandlerFunc() http.HandlerFunc {
 		bs, err := res.Marshal()
 
 		if err != nil {
-			fmt.Println("INTERNAL DECODING ERROR: ", err, string(res.Data()))
+			logging.FromContext(r.Context()).WithError(err).WithField("data", string(res.Data())).Error("internal decoding error")
 			panic(err)
 		}
 
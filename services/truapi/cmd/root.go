@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/telemetry"
 	"github.com/TruStory/octopus/services/truapi/truapi"
 	chain "github.com/TruStory/truchain/app"
 	"github.com/cosmos/cosmos-sdk/client"
@@ -102,6 +103,17 @@ func startCmd(codec *codec.Codec) *cobra.Command {
 				panic(err)
 			}
 
+			stopTracing, err := telemetry.StartTracing(telemetry.TracingConfig{
+				Service:     "truapi",
+				ZipkinURL:   config.Telemetry.ZipkinURL,
+				SampleRatio: config.Telemetry.SampleRatio,
+			})
+			if err != nil {
+				fmt.Println("Tracing could not be started: ", err)
+				os.Exit(1)
+			}
+			defer stopTracing()
+
 			cliCtx := sdkContext.NewCLIContext().WithCodec(codec)
 			apiCtx := context.NewTruAPIContext(&cliCtx, config)
 			truAPI := truapi.NewTruAPI(apiCtx)
@@ -115,7 +127,11 @@ func startCmd(codec *codec.Codec) *cobra.Command {
 				fmt.Println("Notification sender could not be started: ", err)
 				os.Exit(1)
 			}
+			truAPI.RunChainIndexer(apiCtx)
 			truAPI.RunLeaderboardScheduler(apiCtx)
+			truAPI.RunAchievementsEngine(apiCtx)
+			truAPI.RunRewardReconciliation(apiCtx)
+			truAPI.RunAnalytics(apiCtx)
 
 			port := strconv.Itoa(apiCtx.Config.Host.Port)
 			log.Fatal(truAPI.ListenAndServe(net.JoinHostPort(apiCtx.Config.Host.Name, port)))
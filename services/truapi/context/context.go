@@ -54,6 +54,8 @@ type HostConfig struct {
 // PushConfig is the config for push notifications
 type PushConfig struct {
 	EndpointURL string `mapstructure:"endpoint-url"`
+	// Secret is shared with the push service to sign requests
+	Secret string `mapstructure:"secret"`
 }
 
 // RegistrarConfig is the config for the registrar account that signs in users
@@ -117,9 +119,35 @@ type AWSConfig struct {
 	S3Bucket     string `mapstructure:"aws-s3-bucket"`
 }
 
+// StorageConfig is the config for the storage of uploaded and cached media.
+// The s3 and minio backends use the bucket and credentials of the AWS config.
+type StorageConfig struct {
+	// Backend is s3 (default), minio or local
+	Backend string `mapstructure:"backend"`
+	// Endpoint is the URL of a MinIO compatible store
+	Endpoint string `mapstructure:"endpoint"`
+	// Dir, BaseURL and Secret configure the local backend, its files are served under /storage/
+	Dir     string `mapstructure:"dir"`
+	BaseURL string `mapstructure:"base-url"`
+	Secret  string `mapstructure:"secret"`
+}
+
 // SpotlightConfig is the config for the Spotlight service
 type SpotlightConfig struct {
 	URL string `mapstructure:"spotlight-url"`
+	// Secret is shared with the spotlight service to sign invalidation and prewarm requests
+	Secret string `mapstructure:"secret"`
+	// SnippetsPerHour is the number of snippets a user can start rendering in an hour
+	SnippetsPerHour int `mapstructure:"snippets-per-hour"`
+}
+
+// UploaderConfig is the config for the uploader service
+type UploaderConfig struct {
+	URL string `mapstructure:"url"`
+	// Secret is shared with the uploader service to sign requests processing uploads
+	Secret string `mapstructure:"secret"`
+	// ImagesURL is the storage URL of the ImageFolder of the uploader, processed images are stored under it
+	ImagesURL string `mapstructure:"images-url"`
 }
 
 // DripperConfig is the config to send the drip campaigns
@@ -145,11 +173,98 @@ type LeaderboardConfig struct {
 	TopDisplaying int `mapstructure:"top-displaying"`
 }
 
+// IndexerConfig is the config for the chain indexer feeding the leaderboard and metrics
+type IndexerConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Interval is the interval in seconds between polls of the node for new blocks
+	Interval int `mapstructure:"interval"`
+	// StartHeight is the first block indexed when there is no cursor
+	StartHeight int64 `mapstructure:"start-height"`
+}
+
+// AchievementsConfig is the config for the achievements engine
+type AchievementsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Interval is the interval in minutes between evaluations of the rules
+	Interval int                     `mapstructure:"interval"`
+	Rules    []AchievementRuleConfig `mapstructure:"rules"`
+}
+
+// AchievementRuleConfig unlocks an achievement when a metric of the indexed activity reaches a threshold
+type AchievementRuleConfig struct {
+	ID          string `mapstructure:"id"`
+	Name        string `mapstructure:"name"`
+	Description string `mapstructure:"description"`
+	Image       string `mapstructure:"image"`
+	Metric      string `mapstructure:"metric"`
+	Threshold   int64  `mapstructure:"threshold"`
+	// PerCommunity unlocks the achievement once per community on the metric within it
+	PerCommunity bool `mapstructure:"per-community"`
+	Invites      int  `mapstructure:"invites"`
+	// Reward is the TRU rewarded, e.g. 5000000utru
+	Reward string `mapstructure:"reward"`
+}
+
+// LedgerConfig is the config for the reconciliation of the reward ledger with the actual balances
+type LedgerConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Interval is the interval in minutes between reconciliations
+	Interval int `mapstructure:"interval"`
+}
+
+// AnalyticsConfig is the config for the pipeline of events tracked by the apps
+type AnalyticsConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// SampleRate is the share of events kept for the events without their own, 1 keeps all of them
+	SampleRate float64 `mapstructure:"sample-rate"`
+	// BatchSize is the largest number of events sent in a request and written at once
+	BatchSize int `mapstructure:"batch-size"`
+	// FlushInterval is the interval in seconds between writes of the buffered events
+	FlushInterval int `mapstructure:"flush-interval"`
+	// ExportInterval is the interval in seconds between runs of the exporters
+	ExportInterval int                       `mapstructure:"export-interval"`
+	Events         []AnalyticsEventConfig    `mapstructure:"events"`
+	Exporters      []AnalyticsExporterConfig `mapstructure:"exporters"`
+}
+
+// AnalyticsEventConfig registers an event and its properties
+type AnalyticsEventConfig struct {
+	Name string `mapstructure:"name"`
+	// Properties are declared as name:type, the type is string, number, boolean, object or array.
+	// They aren't a table because the config keys are lowercased.
+	Properties []string `mapstructure:"properties"`
+	Required   []string `mapstructure:"required"`
+	SampleRate float64  `mapstructure:"sample-rate"`
+}
+
+// AnalyticsExporterConfig forwards the stored events to BigQuery, Mixpanel or local files
+type AnalyticsExporterConfig struct {
+	Name string `mapstructure:"name"`
+	// Type is bigquery, mixpanel or file
+	Type   string   `mapstructure:"type"`
+	Events []string `mapstructure:"events"`
+	// Project, Dataset and Table of the bigquery exporter
+	Project string `mapstructure:"project"`
+	Dataset string `mapstructure:"dataset"`
+	Table   string `mapstructure:"table"`
+	// Token of the mixpanel exporter
+	Token string `mapstructure:"token"`
+	// Dir and Format, csv or ndjson, of the file exporter
+	Dir    string `mapstructure:"dir"`
+	Format string `mapstructure:"format"`
+}
+
 // Metrics represents metrics configuration
 type MetricsConfig struct {
 	Secret string `mapstructure:"secret"`
 }
 
+// TelemetryConfig configures the traces, they're exported to the zipkin collector at ZipkinURL when set
+type TelemetryConfig struct {
+	ZipkinURL   string  `mapstructure:"zipkin-url"`
+	SampleRatio float64 `mapstructure:"sample-ratio"`
+}
+
 // DefaultsConfig represents the default values
 type DefaultsConfig struct {
 	AvatarURL string `mapstructure:"default-avatar-url"`
@@ -172,11 +287,18 @@ type Config struct {
 	Params       ParamsConfig
 	Admin        AdminConfig
 	AWS          AWSConfig
+	Storage      StorageConfig
 	Spotlight    SpotlightConfig
+	Uploader     UploaderConfig
 	Dripper      DripperConfig
 	Leaderboard  LeaderboardConfig
+	Indexer      IndexerConfig
+	Achievements AchievementsConfig
+	Ledger       LedgerConfig
+	Analytics    AnalyticsConfig
 	Defaults     DefaultsConfig
 	Metrics      MetricsConfig
+	Telemetry    TelemetryConfig
 }
 
 // TruAPIContext stores the config for the API and the underlying client context
@@ -0,0 +1,219 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TruStory/truchain/x/staking"
+	"github.com/go-pg/pg"
+)
+
+// Metrics achievement rules are evaluated on, computed from the chain index
+const (
+	AchievementMetricClaims         = "claims"
+	AchievementMetricArguments      = "arguments"
+	AchievementMetricAgreesGiven    = "agrees_given"
+	AchievementMetricAgreesReceived = "agrees_received"
+	// AchievementMetricStreak is the longest run of consecutive days with an argument or an agree
+	AchievementMetricStreak = "streak"
+)
+
+// achievementMetricQueries select the address, community_id and value of a metric.
+// They're formatted with the community column and the grouping by community,
+// so the metric is either per community or across communities.
+var achievementMetricQueries = map[string]string{
+	AchievementMetricClaims: `
+		SELECT creator address, %[1]s community_id, COUNT(*) value
+		FROM chain_claims
+		GROUP BY creator %[2]s`,
+	AchievementMetricArguments: `
+		SELECT creator address, %[1]s community_id, COUNT(*) value
+		FROM chain_arguments
+		GROUP BY creator %[2]s`,
+	AchievementMetricAgreesGiven: `
+		SELECT creator address, %[1]s community_id, COUNT(*) value
+		FROM chain_stakes
+		WHERE type = ?0
+		GROUP BY creator %[2]s`,
+	AchievementMetricAgreesReceived: `
+		SELECT address, %[1]s community_id, COUNT(*) value FROM (
+			SELECT a.creator address, s.community_id
+			FROM chain_stakes s
+			JOIN chain_arguments a ON a.id = s.argument_id
+			WHERE s.type = ?0
+		) received
+		GROUP BY address %[2]s`,
+	AchievementMetricStreak: `
+		SELECT address, community_id, MAX(days) value FROM (
+			SELECT address, community_id, COUNT(*) days FROM (
+				SELECT address, community_id, day,
+					day - (ROW_NUMBER() OVER (PARTITION BY address, community_id ORDER BY day))::INTEGER streak
+				FROM (
+					SELECT creator address, %[1]s community_id, created_time::DATE AS day FROM chain_arguments
+					UNION
+					SELECT creator address, %[1]s community_id, created_time::DATE AS day FROM chain_stakes WHERE type = ?0
+				) activity
+			) days
+			GROUP BY address, community_id, streak
+		) streaks
+		GROUP BY address, community_id`,
+}
+
+// IsAchievementMetric returns whether achievements can be evaluated on the metric.
+func IsAchievementMetric(metric string) bool {
+	_, ok := achievementMetricQueries[metric]
+	return ok
+}
+
+// AchievementCandidate is a user whose metric reached the threshold of an achievement.
+type AchievementCandidate struct {
+	UserID      int64
+	Address     string
+	CommunityID string
+	Value       int64
+}
+
+// AchievementUnlock records an achievement unlocked by a user and its reward.
+// Reward is in utru, RewardedAt is set once the invites and reward were granted.
+type AchievementUnlock struct {
+	ID            int64
+	UserID        int64
+	Address       string
+	AchievementID string
+	CommunityID   string `sql:",notnull"`
+	Value         int64  `sql:",notnull"`
+	Invites       int64  `sql:",notnull"`
+	Reward        int64  `sql:",notnull"`
+	UnlockedAt    time.Time
+	RewardedAt    *time.Time
+	Timestamps
+}
+
+// AchievementCandidates returns the users who reached the threshold of an achievement
+// and haven't unlocked it yet. Per community achievements are unlocked once per community.
+func (c *Client) AchievementCandidates(achievementID, metric string, perCommunity bool, threshold int64) ([]AchievementCandidate, error) {
+	query, ok := achievementMetricQueries[metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown achievement metric %s", metric)
+	}
+	if perCommunity {
+		query = fmt.Sprintf(query, "community_id", ", community_id")
+	} else {
+		query = fmt.Sprintf(query, "''", "")
+	}
+	candidates := make([]AchievementCandidate, 0)
+	_, err := c.Query(&candidates, `
+		SELECT u.id user_id, m.address, m.community_id, m.value
+		FROM (`+query+`) m
+		JOIN users u ON u.address = m.address
+		WHERE m.value >= ?1
+			AND u.deleted_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM achievement_unlocks au
+				WHERE au.address = m.address AND au.achievement_id = ?2 AND au.community_id = m.community_id
+			)
+		ORDER BY m.address, m.community_id
+	`, staking.StakeUpvote, threshold, achievementID)
+	if err != nil {
+		return nil, err
+	}
+	return candidates, nil
+}
+
+// RecordAchievementUnlock saves an unlock and returns whether it's new.
+func (c *Client) RecordAchievementUnlock(unlock *AchievementUnlock) (bool, error) {
+	result, err := c.Model(unlock).
+		OnConflict("ON CONSTRAINT achievement_unlocks_no_duplicate DO NOTHING").
+		Insert()
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// AchievementUnlocksByAddress returns the achievements unlocked by an address, oldest first.
+func (c *Client) AchievementUnlocksByAddress(address string) ([]AchievementUnlock, error) {
+	unlocks := make([]AchievementUnlock, 0)
+	err := c.Model(&unlocks).
+		Where("address = ?", address).
+		Order("unlocked_at ASC", "id ASC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return unlocks, nil
+}
+
+// UnrewardedAchievementUnlocks returns the unlocks with invites or a reward not granted yet.
+func (c *Client) UnrewardedAchievementUnlocks() ([]AchievementUnlock, error) {
+	unlocks := make([]AchievementUnlock, 0)
+	err := c.Model(&unlocks).
+		Where("rewarded_at IS NULL").
+		Where("invites > 0 OR reward > 0").
+		Order("id ASC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return unlocks, nil
+}
+
+// ClaimAchievementReward marks the reward of an unlock as granted before granting it,
+// returning false when it already was, so a reward is never granted twice.
+func (c *Client) ClaimAchievementReward(id int64) (bool, error) {
+	result, err := c.Model((*AchievementUnlock)(nil)).
+		Set("rewarded_at = NOW()").
+		Set("updated_at = NOW()").
+		Where("id = ?", id).
+		Where("rewarded_at IS NULL").
+		Update()
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// RecordAchievementReward grants the invites of a claimed unlock and records them and its TRU in the
+// reward ledger in a single transaction. It runs before the TRU is sent, so a gift that fails
+// afterwards shows in the ledger reconciliation instead of being sent again.
+func (c *Client) RecordAchievementReward(unlock *AchievementUnlock) error {
+	return c.RunInTransaction(func(tx *pg.Tx) error {
+		if unlock.Invites > 0 {
+			_, err := tx.Model((*User)(nil)).
+				Where("id = ?", unlock.UserID).
+				Set("invites_left = invites_left + ?", unlock.Invites).
+				Update()
+			if err != nil {
+				return err
+			}
+			err = tx.Insert(&RewardLedgerEntry{
+				UserID:    unlock.UserID,
+				Direction: RewardLedgerEntryDirectionCredit,
+				Amount:    unlock.Invites,
+				Currency:  RewardLedgerEntryCurrencyInvite,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if unlock.Reward > 0 {
+			return tx.Insert(&RewardLedgerEntry{
+				UserID:    unlock.UserID,
+				Direction: RewardLedgerEntryDirectionCredit,
+				Amount:    unlock.Reward,
+				Currency:  RewardLedgerEntryCurrencyTru,
+			})
+		}
+		return nil
+	})
+}
+
+// ReleaseAchievementReward clears a claimed reward that couldn't be granted so it's retried.
+func (c *Client) ReleaseAchievementReward(id int64) error {
+	_, err := c.Model((*AchievementUnlock)(nil)).
+		Set("rewarded_at = NULL").
+		Set("updated_at = NOW()").
+		Where("id = ?", id).
+		Update()
+	return err
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TruStory/truchain/x/staking"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAchievementCandidates(t *testing.T) {
+	client := testClient(t)
+	defer client.Close()
+	prefix := testAddress("achievement")
+	alice, bob := prefix+"alice", prefix+"bob"
+	for _, address := range []string{alice, bob} {
+		assert.NoError(t, client.Add(&User{FullName: address, Address: address, UserGroup: UserGroupUser}))
+	}
+	defer client.Model((*User)(nil)).Where("address LIKE ?", prefix+"%").Delete()
+
+	id := uint64(time.Now().UnixNano() / 1000)
+	day := func(n int) time.Time {
+		return time.Date(2019, 11, 1+n, 12, 0, 0, 0, time.UTC)
+	}
+	claims := []*ChainClaim{
+		{ID: id, CommunityID: "crypto", Creator: alice, Body: "claim", Height: 1, CreatedTime: day(0)},
+		{ID: id + 1, CommunityID: "sports", Creator: alice, Body: "claim", Height: 1, CreatedTime: day(0)},
+	}
+	arguments := []*ChainArgument{
+		{ID: id, ClaimID: id, CommunityID: "crypto", Creator: alice, StakeType: staking.StakeBacking, Height: 1, CreatedTime: day(0)},
+		{ID: id + 1, ClaimID: id + 1, CommunityID: "sports", Creator: alice, StakeType: staking.StakeChallenge, Height: 1, CreatedTime: day(1)},
+		{ID: id + 2, ClaimID: id, CommunityID: "crypto", Creator: bob, StakeType: staking.StakeBacking, Height: 1, CreatedTime: day(0)},
+	}
+	stakes := []*ChainStake{
+		// the stakes of the arguments aren't agrees
+		{ID: id, ArgumentID: id, CommunityID: "crypto", Creator: alice, Type: staking.StakeBacking, Height: 1, CreatedTime: day(0), EndTime: day(7)},
+		{ID: id + 1, ArgumentID: id, CommunityID: "crypto", Creator: bob, Type: staking.StakeUpvote, Height: 1, CreatedTime: day(1), EndTime: day(8)},
+		{ID: id + 2, ArgumentID: id + 1, CommunityID: "sports", Creator: bob, Type: staking.StakeUpvote, Height: 1, CreatedTime: day(2), EndTime: day(9)},
+	}
+	for _, claim := range claims {
+		assert.NoError(t, client.Add(claim))
+	}
+	defer client.Model((*ChainClaim)(nil)).Where("creator LIKE ?", prefix+"%").Delete()
+	for _, argument := range arguments {
+		assert.NoError(t, client.Add(argument))
+	}
+	defer client.Model((*ChainArgument)(nil)).Where("creator LIKE ?", prefix+"%").Delete()
+	for _, stake := range stakes {
+		assert.NoError(t, client.Add(stake))
+	}
+	defer client.Model((*ChainStake)(nil)).Where("creator LIKE ?", prefix+"%").Delete()
+
+	// values by user and community, "" across communities
+	type values map[string]int64
+	candidates := func(metric string, perCommunity bool) values {
+		found, err := client.AchievementCandidates(prefix, metric, perCommunity, 1)
+		assert.NoError(t, err, metric)
+		matched := make(values)
+		for _, candidate := range found {
+			if strings.HasPrefix(candidate.Address, prefix) {
+				matched[strings.TrimPrefix(candidate.Address, prefix)+"/"+candidate.CommunityID] = candidate.Value
+			}
+		}
+		return matched
+	}
+	tests := []struct {
+		metric             string
+		overall, community values
+	}{
+		{AchievementMetricClaims, values{"alice/": 2}, values{"alice/crypto": 1, "alice/sports": 1}},
+		{AchievementMetricArguments, values{"alice/": 2, "bob/": 1}, values{"alice/crypto": 1, "alice/sports": 1, "bob/crypto": 1}},
+		{AchievementMetricAgreesGiven, values{"bob/": 2}, values{"bob/crypto": 1, "bob/sports": 1}},
+		{AchievementMetricAgreesReceived, values{"alice/": 2}, values{"alice/crypto": 1, "alice/sports": 1}},
+		// bob argued on the first day and agreed on the next two, in another community on the third
+		{AchievementMetricStreak, values{"alice/": 2, "bob/": 3}, values{"alice/crypto": 1, "alice/sports": 1, "bob/crypto": 2, "bob/sports": 1}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.overall, candidates(test.metric, false), test.metric)
+		assert.Equal(t, test.community, candidates(test.metric, true), test.metric)
+	}
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-pg/pg"
+)
+
+// AnalyticsEvent is an event tracked by the apps, stored in the monthly partitions of analytics_events.
+type AnalyticsEvent struct {
+	ID         int64                  `json:"id"`
+	Event      string                 `json:"event"`
+	Address    string                 `json:"address" sql:",notnull"`
+	SessionID  string                 `json:"session_id" sql:",notnull"`
+	Properties map[string]interface{} `json:"properties"`
+	// SampleRate is the share of the events kept when it was tracked, each one stands for 1/SampleRate events
+	SampleRate float64    `json:"sample_rate"`
+	ClientTime *time.Time `json:"client_time"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// AnalyticsExportCursor is the last event exported by an analytics exporter.
+type AnalyticsExportCursor struct {
+	Name        string `sql:",pk"`
+	LastEventID int64  `sql:",notnull"`
+	Timestamps
+}
+
+// EnsureAnalyticsEventPartition creates the partition of analytics_events for the month of a time.
+func (c *Client) EnsureAnalyticsEventPartition(month time.Time) error {
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	name := fmt.Sprintf("analytics_events_y%04dm%02d", from.Year(), from.Month())
+	_, err := c.Exec(`CREATE TABLE IF NOT EXISTS ? PARTITION OF analytics_events FOR VALUES FROM (?) TO (?)`,
+		pg.F(name), from, to)
+	return err
+}
+
+// AddAnalyticsEvents inserts a batch of events.
+func (c *Client) AddAnalyticsEvents(events []AnalyticsEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	_, err := c.Model(&events).Insert()
+	return err
+}
+
+// AnalyticsEventsAfter returns the events with an id above the given one created before a time, by id.
+func (c *Client) AnalyticsEventsAfter(id int64, before time.Time, limit int) ([]AnalyticsEvent, error) {
+	events := make([]AnalyticsEvent, 0)
+	err := c.Model(&events).
+		Where("id > ?", id).
+		Where("created_at < ?", before).
+		Order("id ASC").
+		Limit(limit).
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// AnalyticsExportCursor returns the last event exported by an exporter, 0 when it hasn't exported any.
+func (c *Client) AnalyticsExportCursor(name string) (int64, error) {
+	cursor := new(AnalyticsExportCursor)
+	err := c.Model(cursor).Where("name = ?", name).Select()
+	if err == pg.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return cursor.LastEventID, nil
+}
+
+// SaveAnalyticsExportCursor moves the cursor of an exporter.
+func (c *Client) SaveAnalyticsExportCursor(name string, lastEventID int64) error {
+	cursor := &AnalyticsExportCursor{Name: name, LastEventID: lastEventID}
+	_, err := c.Model(cursor).
+		OnConflict("(name) DO UPDATE").
+		Set("last_event_id = EXCLUDED.last_event_id").
+		Set("updated_at = NOW()").
+		Insert()
+	return err
+}
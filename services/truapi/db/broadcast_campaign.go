@@ -0,0 +1,216 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-pg/pg"
+)
+
+// BroadcastCampaignStatus is the delivery status of a broadcast campaign.
+type BroadcastCampaignStatus string
+
+// Statuses of a broadcast campaign.
+const (
+	BroadcastCampaignScheduled BroadcastCampaignStatus = "scheduled"
+	BroadcastCampaignSending   BroadcastCampaignStatus = "sending"
+	BroadcastCampaignSent      BroadcastCampaignStatus = "sent"
+	BroadcastCampaignFailed    BroadcastCampaignStatus = "failed"
+	BroadcastCampaignCanceled  BroadcastCampaignStatus = "canceled"
+)
+
+// BroadcastSegment selects the audience of a broadcast campaign.
+// Empty criteria match every user, set criteria are combined with AND.
+type BroadcastSegment struct {
+	// CommunityIDs matches users following any of the communities.
+	CommunityIDs []string `json:"communityIds,omitempty"`
+	// UserGroups matches users belonging to any of the groups.
+	UserGroups []UserGroup `json:"userGroups,omitempty"`
+	// JourneySteps matches users that completed all of the steps.
+	JourneySteps []UserJourneyStep `json:"journeySteps,omitempty"`
+	// ActiveWithinDays matches users that authenticated within the last days.
+	ActiveWithinDays int64 `json:"activeWithinDays,omitempty"`
+}
+
+// BroadcastCampaign is a notification sent to a segment of users at a scheduled time.
+type BroadcastCampaign struct {
+	Timestamps
+	ID                int64                   `json:"id"`
+	Title             string                  `json:"title"`
+	Message           string                  `json:"message"`
+	ClaimID           *int64                  `json:"claim_id,omitempty"`
+	Segment           BroadcastSegment        `json:"segment"`
+	ScheduledAt       time.Time               `json:"scheduled_at"`
+	ThrottlePerMinute int                     `json:"throttle_per_minute" sql:",notnull"`
+	Status            BroadcastCampaignStatus `json:"status" sql:",notnull,default:'scheduled'"`
+	RecipientsCount   int                     `json:"recipients_count" sql:",notnull"`
+	StartedAt         *time.Time              `json:"started_at,omitempty"`
+	FinishedAt        *time.Time              `json:"finished_at,omitempty"`
+}
+
+// BroadcastCampaignStats contains the delivery stats of a campaign.
+type BroadcastCampaignStats struct {
+	Delivered int `json:"delivered"`
+	Opened    int `json:"opened"`
+}
+
+// AddBroadcastCampaign schedules a new broadcast campaign.
+func (c *Client) AddBroadcastCampaign(campaign *BroadcastCampaign) error {
+	_, err := c.Model(campaign).Returning("*").Insert()
+	return err
+}
+
+// BroadcastCampaigns returns all the campaigns, most recently scheduled first.
+func (c *Client) BroadcastCampaigns() ([]BroadcastCampaign, error) {
+	campaigns := make([]BroadcastCampaign, 0)
+	err := c.Model(&campaigns).Order("scheduled_at DESC").Select()
+	if err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// BroadcastCampaignByID returns a campaign by its id.
+func (c *Client) BroadcastCampaignByID(id int64) (*BroadcastCampaign, error) {
+	campaign := new(BroadcastCampaign)
+	err := c.Model(campaign).Where("id = ?", id).First()
+	if err == pg.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// CancelBroadcastCampaign cancels a campaign that has not started sending yet.
+// It returns false if the campaign was not scheduled.
+func (c *Client) CancelBroadcastCampaign(id int64) (bool, error) {
+	result, err := c.Model(&BroadcastCampaign{}).
+		Where("id = ?", id).
+		Where("status = ?", BroadcastCampaignScheduled).
+		Set("status = ?", BroadcastCampaignCanceled).
+		Set("updated_at = NOW()").
+		Update()
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// ClaimDueBroadcastCampaigns marks campaigns scheduled before now as sending and returns them.
+// Campaigns still sending without progress since staleBefore were left behind by a stopped
+// pushd and are claimed again. Claiming is atomic so a campaign is only picked up once.
+func (c *Client) ClaimDueBroadcastCampaigns(now, staleBefore time.Time) ([]BroadcastCampaign, error) {
+	campaigns := make([]BroadcastCampaign, 0)
+	_, err := c.Model(&campaigns).
+		Where("(status = ? AND scheduled_at <= ?) OR (status = ? AND updated_at < ?)",
+			BroadcastCampaignScheduled, now, BroadcastCampaignSending, staleBefore).
+		Set("status = ?", BroadcastCampaignSending).
+		Set("started_at = COALESCE(started_at, ?)", now).
+		Set("updated_at = ?", now).
+		Returning("*").
+		Update()
+	if err != nil {
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// TouchBroadcastCampaign records progress on a sending campaign so it isn't claimed again.
+func (c *Client) TouchBroadcastCampaign(id int64) error {
+	_, err := c.Model(&BroadcastCampaign{}).
+		Where("id = ?", id).
+		Where("status = ?", BroadcastCampaignSending).
+		Set("updated_at = NOW()").
+		Update()
+	return err
+}
+
+// ReleaseBroadcastCampaign puts a sending campaign back in the schedule to be resumed.
+func (c *Client) ReleaseBroadcastCampaign(id int64) error {
+	_, err := c.Model(&BroadcastCampaign{}).
+		Where("id = ?", id).
+		Where("status = ?", BroadcastCampaignSending).
+		Set("status = ?", BroadcastCampaignScheduled).
+		Set("updated_at = NOW()").
+		Update()
+	return err
+}
+
+// BroadcastCampaignNotifiedAddresses returns the addresses a campaign was already delivered to.
+func (c *Client) BroadcastCampaignNotifiedAddresses(id int64) ([]string, error) {
+	addresses := make([]string, 0)
+	_, err := c.Query(&addresses, `
+		SELECT DISTINCT address
+		FROM notification_events
+		WHERE meta->>'campaignId' = ?`, strconv.FormatInt(id, 10))
+	if err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+// SetBroadcastCampaignRecipients records the size of the audience of a campaign.
+func (c *Client) SetBroadcastCampaignRecipients(id int64, count int) error {
+	_, err := c.Model(&BroadcastCampaign{}).
+		Where("id = ?", id).
+		Set("recipients_count = ?", count).
+		Set("updated_at = NOW()").
+		Update()
+	return err
+}
+
+// FinishBroadcastCampaign marks a campaign as sent or failed.
+func (c *Client) FinishBroadcastCampaign(id int64, status BroadcastCampaignStatus) error {
+	_, err := c.Model(&BroadcastCampaign{}).
+		Where("id = ?", id).
+		Set("status = ?", status).
+		Set("finished_at = NOW()").
+		Set("updated_at = NOW()").
+		Update()
+	return err
+}
+
+// UsersBySegment returns the users with an address matching a broadcast segment.
+func (c *Client) UsersBySegment(segment BroadcastSegment) ([]User, error) {
+	users := make([]User, 0)
+	q := c.Model(&users).
+		Where("address IS NOT NULL AND address <> ''").
+		Where("blacklisted_at IS NULL")
+	if len(segment.CommunityIDs) > 0 {
+		q = q.Where(`address IN (
+			SELECT address FROM followed_communities WHERE community_id IN (?) AND deleted_at IS NULL
+		)`, pg.In(segment.CommunityIDs))
+	}
+	if len(segment.UserGroups) > 0 {
+		q = q.Where("user_group IN (?)", pg.In(segment.UserGroups))
+	}
+	for _, step := range segment.JourneySteps {
+		q = q.Where("meta->'journey' @> ?::jsonb", fmt.Sprintf("[%q]", step))
+	}
+	if segment.ActiveWithinDays > 0 {
+		q = q.Where("last_authenticated_at > NOW() - interval '? days'", segment.ActiveWithinDays)
+	}
+	err := q.Order("id ASC").Select()
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// BroadcastCampaignStats returns how many notifications of a campaign were delivered and opened.
+func (c *Client) BroadcastCampaignStats(id int64) (*BroadcastCampaignStats, error) {
+	stats := new(BroadcastCampaignStats)
+	_, err := c.QueryOne(stats, `
+		SELECT
+			COUNT(*) AS delivered,
+			COUNT(*) FILTER (WHERE read IS TRUE) AS opened
+		FROM notification_events
+		WHERE meta->>'campaignId' = ?`, strconv.FormatInt(id, 10))
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsersBySegment(t *testing.T) {
+	client := testClient(t)
+	defer client.Close()
+	prefix := testAddress("seg")
+	recently := time.Now().Add(-24 * time.Hour)
+	longAgo := time.Now().Add(-90 * 24 * time.Hour)
+	users := []*User{
+		{FullName: "follower", Address: prefix + "a", UserGroup: UserGroupUser, LastAuthenticatedAt: &recently,
+			Meta: UserMeta{Journey: []UserJourneyStep{JourneyStepSignedUp, JourneyStepOneArgument}}},
+		{FullName: "employee", Address: prefix + "b", UserGroup: UserGroupEmployee, LastAuthenticatedAt: &longAgo,
+			Meta: UserMeta{Journey: []UserJourneyStep{JourneyStepSignedUp}}},
+		{FullName: "blacklisted", Address: prefix + "c", UserGroup: UserGroupUser, BlacklistedAt: time.Now()},
+	}
+	for _, user := range users {
+		assert.NoError(t, client.Add(user))
+	}
+	defer client.Model((*User)(nil)).Where("address LIKE ?", prefix+"%").Delete()
+	assert.NoError(t, client.Add(&FollowedCommunity{Address: prefix + "a", CommunityID: "crypto", FollowingSince: time.Now()}))
+	defer client.Model((*FollowedCommunity)(nil)).Where("address LIKE ?", prefix+"%").Delete()
+
+	addresses := func(segment BroadcastSegment) []string {
+		found, err := client.UsersBySegment(segment)
+		assert.NoError(t, err)
+		matched := make([]string, 0)
+		for _, user := range found {
+			if len(user.Address) > len(prefix) && user.Address[:len(prefix)] == prefix {
+				matched = append(matched, user.Address[len(prefix):])
+			}
+		}
+		return matched
+	}
+
+	assert.Equal(t, []string{"a", "b"}, addresses(BroadcastSegment{}))
+	assert.Equal(t, []string{"a"}, addresses(BroadcastSegment{CommunityIDs: []string{"crypto", "sports"}}))
+	assert.Equal(t, []string{"b"}, addresses(BroadcastSegment{UserGroups: []UserGroup{UserGroupEmployee}}))
+	assert.Equal(t, []string{"a"}, addresses(BroadcastSegment{JourneySteps: []UserJourneyStep{JourneyStepSignedUp, JourneyStepOneArgument}}))
+	assert.Equal(t, []string{"a"}, addresses(BroadcastSegment{ActiveWithinDays: 30}))
+	// criteria are combined with AND
+	assert.Empty(t, addresses(BroadcastSegment{CommunityIDs: []string{"crypto"}, UserGroups: []UserGroup{UserGroupEmployee}}))
+}
+
+func TestClaimDueBroadcastCampaigns(t *testing.T) {
+	client := testClient(t)
+	defer client.Close()
+	now := time.Now()
+	title := testAddress("campaign")
+	defer client.Model((*BroadcastCampaign)(nil)).Where("title = ?", title).Delete()
+
+	campaigns := map[string]*BroadcastCampaign{
+		"due":      {Title: title, Message: "due", ScheduledAt: now.Add(-time.Minute)},
+		"future":   {Title: title, Message: "future", ScheduledAt: now.Add(time.Hour)},
+		"canceled": {Title: title, Message: "canceled", ScheduledAt: now.Add(-time.Minute), Status: BroadcastCampaignCanceled},
+		"stale":    {Title: title, Message: "stale", ScheduledAt: now.Add(-time.Hour), Status: BroadcastCampaignSending},
+		"sending":  {Title: title, Message: "sending", ScheduledAt: now.Add(-time.Hour), Status: BroadcastCampaignSending},
+	}
+	for _, campaign := range campaigns {
+		assert.NoError(t, client.AddBroadcastCampaign(campaign))
+	}
+	// the stale campaign made no progress for an hour, the other one just did
+	_, err := client.Model(&BroadcastCampaign{}).Where("id = ?", campaigns["stale"].ID).Set("updated_at = ?", now.Add(-time.Hour)).Update()
+	assert.NoError(t, err)
+	assert.NoError(t, client.TouchBroadcastCampaign(campaigns["sending"].ID))
+
+	claimed := func() []string {
+		found, err := client.ClaimDueBroadcastCampaigns(time.Now(), time.Now().Add(-15*time.Minute))
+		assert.NoError(t, err)
+		messages := make([]string, 0)
+		for _, campaign := range found {
+			if campaign.Title == title {
+				messages = append(messages, campaign.Message)
+			}
+		}
+		return messages
+	}
+	assert.ElementsMatch(t, []string{"due", "stale"}, claimed())
+	// claimed campaigns are only picked up once
+	assert.Empty(t, claimed())
+
+	// a released campaign is resumed on the next claim
+	assert.NoError(t, client.ReleaseBroadcastCampaign(campaigns["sending"].ID))
+	assert.Equal(t, []string{"sending"}, claimed())
+}
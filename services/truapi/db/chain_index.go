@@ -0,0 +1,407 @@
+package db
+
+import (
+	"time"
+
+	"github.com/TruStory/truchain/x/bank/exported"
+	"github.com/TruStory/truchain/x/staking"
+	"github.com/go-pg/pg"
+)
+
+// ChainIndexCursor is the last block indexed by an indexer.
+type ChainIndexCursor struct {
+	ID        int64
+	Name      string
+	Height    int64
+	BlockTime time.Time
+	Timestamps
+}
+
+// ChainClaim is an indexed claim.
+type ChainClaim struct {
+	ID          uint64
+	CommunityID string
+	Creator     string
+	Body        string
+	Height      int64
+	CreatedTime time.Time
+	Timestamps
+}
+
+// ChainArgument is an indexed argument.
+type ChainArgument struct {
+	ID          uint64
+	ClaimID     uint64
+	CommunityID string
+	Creator     string
+	StakeType   staking.StakeType `sql:",notnull"`
+	Height      int64
+	CreatedTime time.Time
+	Timestamps
+}
+
+// ChainStake is an indexed stake, either the stake of an argument or an agree.
+type ChainStake struct {
+	ID          uint64
+	ArgumentID  uint64
+	CommunityID string
+	Creator     string
+	Type        staking.StakeType `sql:",notnull"`
+	Amount      int64             `sql:",notnull"`
+	Expired     bool              `sql:",notnull"`
+	Height      int64
+	CreatedTime time.Time
+	EndTime     time.Time
+	Timestamps
+}
+
+// ChainSlash is an indexed slash of an argument.
+type ChainSlash struct {
+	ID          uint64
+	ArgumentID  uint64
+	Creator     string
+	Type        int `sql:",notnull"`
+	Reason      int `sql:",notnull"`
+	Height      int64
+	CreatedTime time.Time
+	Timestamps
+}
+
+// ChainTransaction is an indexed bank transaction.
+type ChainTransaction struct {
+	ID          uint64
+	Type        exported.TransactionType `sql:",notnull"`
+	Address     string
+	ReferenceID uint64 `sql:",notnull"`
+	CommunityID string `sql:",notnull"`
+	Amount      int64  `sql:",notnull"`
+	// Registration marks the gift an account is registered with
+	Registration bool `sql:",notnull"`
+	Height       int64
+	CreatedTime  time.Time
+	Timestamps
+}
+
+// ChainBlock holds what an indexer extracted from a block.
+type ChainBlock struct {
+	Height        int64
+	Time          time.Time
+	Claims        []ChainClaim
+	Arguments     []ChainArgument
+	Stakes        []ChainStake
+	ExpiredStakes []uint64
+	Slashes       []ChainSlash
+	Transactions  []ChainTransaction
+}
+
+// ChainIndexCursorByName returns the cursor of an indexer, nil when it hasn't indexed any block.
+func (c *Client) ChainIndexCursorByName(name string) (*ChainIndexCursor, error) {
+	cursor := new(ChainIndexCursor)
+	err := c.Model(cursor).Where("name = ?", name).Select()
+	if err == pg.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cursor, nil
+}
+
+// SaveChainBlock stores an indexed block and moves the cursor in a single transaction,
+// so an indexer resumes from the last block that was completely stored.
+// Blocks can be saved more than once, rows that already exist are kept.
+func (c *Client) SaveChainBlock(name string, block *ChainBlock) error {
+	return c.RunInTransaction(func(tx *pg.Tx) error {
+		for i := range block.Claims {
+			_, err := tx.Model(&block.Claims[i]).OnConflict("(id) DO NOTHING").Insert()
+			if err != nil {
+				return err
+			}
+		}
+		for i := range block.Arguments {
+			_, err := tx.Model(&block.Arguments[i]).OnConflict("(id) DO NOTHING").Insert()
+			if err != nil {
+				return err
+			}
+		}
+		for i := range block.Stakes {
+			_, err := tx.Model(&block.Stakes[i]).OnConflict("(id) DO NOTHING").Insert()
+			if err != nil {
+				return err
+			}
+		}
+		if len(block.ExpiredStakes) > 0 {
+			_, err := tx.Model((*ChainStake)(nil)).
+				Set("expired = TRUE").
+				Set("updated_at = NOW()").
+				Where("id IN (?)", pg.In(block.ExpiredStakes)).
+				Update()
+			if err != nil {
+				return err
+			}
+		}
+		for i := range block.Slashes {
+			_, err := tx.Model(&block.Slashes[i]).OnConflict("(id) DO NOTHING").Insert()
+			if err != nil {
+				return err
+			}
+		}
+		for i := range block.Transactions {
+			_, err := tx.Model(&block.Transactions[i]).OnConflict("(id) DO NOTHING").Insert()
+			if err != nil {
+				return err
+			}
+		}
+		cursor := &ChainIndexCursor{Name: name, Height: block.Height, BlockTime: block.Time}
+		_, err := tx.Model(cursor).
+			OnConflict("ON CONSTRAINT chain_index_cursors_no_duplicate DO UPDATE").
+			Set("height = EXCLUDED.height").
+			Set("block_time = EXCLUDED.block_time").
+			Set("updated_at = NOW()").
+			Insert()
+		return err
+	})
+}
+
+// ChainArgumentsByIDs returns the indexed arguments with the given ids.
+func (c *Client) ChainArgumentsByIDs(ids []uint64) ([]ChainArgument, error) {
+	arguments := make([]ChainArgument, 0)
+	if len(ids) == 0 {
+		return arguments, nil
+	}
+	err := c.Model(&arguments).Where("id IN (?)", pg.In(ids)).Select()
+	if err != nil {
+		return nil, err
+	}
+	return arguments, nil
+}
+
+// ChainUserCommunityStats aggregates the indexed activity of a user in a community.
+// Amounts are in the staking denomination.
+type ChainUserCommunityStats struct {
+	Address                  string
+	CommunityID              string
+	Claims                   int64
+	Arguments                int64
+	AgreesGiven              int64
+	AgreesReceived           int64
+	Staked                   int64
+	StakedArgument           int64
+	StakedAgree              int64
+	PendingStake             int64
+	InterestArgumentCreation int64
+	InterestAgreeReceived    int64
+	InterestAgreeGiven       int64
+	CuratorReward            int64
+	InterestSlashed          int64
+	StakeSlashed             int64
+}
+
+// Interest is the interest earned before slashing.
+func (s ChainUserCommunityStats) Interest() int64 {
+	return s.InterestArgumentCreation + s.InterestAgreeReceived + s.InterestAgreeGiven
+}
+
+func (s *ChainUserCommunityStats) add(o ChainUserCommunityStats) {
+	s.Claims += o.Claims
+	s.Arguments += o.Arguments
+	s.AgreesGiven += o.AgreesGiven
+	s.AgreesReceived += o.AgreesReceived
+	s.Staked += o.Staked
+	s.StakedArgument += o.StakedArgument
+	s.StakedAgree += o.StakedAgree
+	s.PendingStake += o.PendingStake
+	s.InterestArgumentCreation += o.InterestArgumentCreation
+	s.InterestAgreeReceived += o.InterestAgreeReceived
+	s.InterestAgreeGiven += o.InterestAgreeGiven
+	s.CuratorReward += o.CuratorReward
+	s.InterestSlashed += o.InterestSlashed
+	s.StakeSlashed += o.StakeSlashed
+}
+
+// ChainUserCommunityStatsBefore aggregates the indexed activity of every user per community before a date.
+// Expired stakes are still pending at the date when they ended after it, if they were created since pendingSince.
+func (c *Client) ChainUserCommunityStatsBefore(date, pendingSince time.Time) ([]ChainUserCommunityStats, error) {
+	queries := []struct {
+		query  string
+		params []interface{}
+	}{
+		{`SELECT creator address, community_id, COUNT(*) claims
+			FROM chain_claims
+			WHERE created_time < ?
+			GROUP BY creator, community_id`, []interface{}{date}},
+		{`SELECT creator address, community_id, COUNT(*) arguments
+			FROM chain_arguments
+			WHERE created_time < ?
+			GROUP BY creator, community_id`, []interface{}{date}},
+		{`SELECT
+				creator address,
+				community_id,
+				COUNT(*) FILTER (WHERE type = ?0) agrees_given,
+				SUM(amount) staked,
+				COALESCE(SUM(amount) FILTER (WHERE type != ?0), 0) staked_argument,
+				COALESCE(SUM(amount) FILTER (WHERE type = ?0), 0) staked_agree,
+				COALESCE(SUM(amount) FILTER (WHERE NOT expired OR (created_time >= ?2 AND end_time >= ?1)), 0) pending_stake
+			FROM chain_stakes
+			WHERE created_time < ?1
+			GROUP BY creator, community_id`, []interface{}{staking.StakeUpvote, date, pendingSince}},
+		{`SELECT a.creator address, s.community_id, COUNT(*) agrees_received
+			FROM chain_stakes s
+			JOIN chain_arguments a ON a.id = s.argument_id
+			WHERE s.type = ? AND s.created_time < ?
+			GROUP BY a.creator, s.community_id`, []interface{}{staking.StakeUpvote, date}},
+		{`SELECT
+				address,
+				community_id,
+				COALESCE(SUM(amount) FILTER (WHERE type = ?0), 0) interest_argument_creation,
+				COALESCE(SUM(amount) FILTER (WHERE type = ?1), 0) interest_agree_received,
+				COALESCE(SUM(amount) FILTER (WHERE type = ?2), 0) interest_agree_given,
+				COALESCE(SUM(amount) FILTER (WHERE type = ?3), 0) curator_reward,
+				COALESCE(SUM(amount) FILTER (WHERE type IN (?4)), 0) interest_slashed,
+				COALESCE(SUM(amount) FILTER (WHERE type IN (?5)), 0) stake_slashed
+			FROM chain_transactions
+			WHERE created_time < ?6 AND community_id != ''
+			GROUP BY address, community_id`, []interface{}{
+			exported.TransactionInterestArgumentCreation,
+			exported.TransactionInterestUpvoteReceived,
+			exported.TransactionInterestUpvoteGiven,
+			exported.TransactionCuratorReward,
+			pg.In(exported.AllowedTransactionsForEarningDeduction),
+			pg.In([]exported.TransactionType{exported.TransactionStakeCreatorSlashed, exported.TransactionStakeCuratorSlashed}),
+			date,
+		}},
+	}
+
+	type key struct{ address, communityID string }
+	merged := make(map[key]*ChainUserCommunityStats)
+	keys := make([]key, 0)
+	for _, q := range queries {
+		rows := make([]ChainUserCommunityStats, 0)
+		_, err := c.Query(&rows, q.query, q.params...)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			k := key{row.Address, row.CommunityID}
+			stats, ok := merged[k]
+			if !ok {
+				stats = &ChainUserCommunityStats{Address: row.Address, CommunityID: row.CommunityID}
+				merged[k] = stats
+				keys = append(keys, k)
+			}
+			stats.add(row)
+		}
+	}
+	results := make([]ChainUserCommunityStats, 0, len(keys))
+	for _, k := range keys {
+		results = append(results, *merged[k])
+	}
+	return results, nil
+}
+
+// ChainBalancesBefore returns the balance of every address from the indexed transactions before a date.
+func (c *Client) ChainBalancesBefore(date time.Time) (map[string]int64, error) {
+	rows := make([]struct {
+		Address string
+		Balance int64
+	}, 0)
+	_, err := c.Query(&rows, `
+		SELECT address, SUM(CASE WHEN type IN (?) THEN -amount ELSE amount END) balance
+		FROM chain_transactions
+		WHERE created_time < ?
+		GROUP BY address`, pg.In(exported.AllowedTransactionsForDeduction), date)
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		balances[row.Address] = row.Balance
+	}
+	return balances, nil
+}
+
+// ChainClaimStats aggregates the indexed arguments and stakes of a claim.
+type ChainClaimStats struct {
+	ClaimID                  uint64
+	CommunityID              string
+	Body                     string
+	CreatedTime              time.Time
+	Arguments                int64
+	AgreesGiven              int64
+	StakedBacked             int64
+	StakedArgumentBacked     int64
+	StakedAgreeBacked        int64
+	StakedChallenged         int64
+	StakedArgumentChallenged int64
+	StakedAgreeChallenged    int64
+	LastArgumentTime         *time.Time
+	LastAgreeTime            *time.Time
+}
+
+// ChainClaimStatsBefore aggregates the claims created before a date, ordered by id.
+func (c *Client) ChainClaimStatsBefore(date time.Time) ([]ChainClaimStats, error) {
+	claims := make([]ChainClaimStats, 0)
+	_, err := c.Query(&claims, `
+		SELECT id claim_id, community_id, body, created_time
+		FROM chain_claims
+		WHERE created_time < ?
+		ORDER BY id`, date)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[uint64]*ChainClaimStats, len(claims))
+	for i := range claims {
+		index[claims[i].ClaimID] = &claims[i]
+	}
+
+	arguments := make([]ChainClaimStats, 0)
+	_, err = c.Query(&arguments, `
+		SELECT claim_id, COUNT(*) arguments, MAX(created_time) last_argument_time
+		FROM chain_arguments
+		WHERE created_time < ?
+		GROUP BY claim_id`, date)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range arguments {
+		if claim, ok := index[a.ClaimID]; ok {
+			claim.Arguments = a.Arguments
+			claim.LastArgumentTime = a.LastArgumentTime
+		}
+	}
+
+	stakes := make([]ChainClaimStats, 0)
+	_, err = c.Query(&stakes, `
+		SELECT
+			a.claim_id,
+			COUNT(*) FILTER (WHERE s.type = ?0) agrees_given,
+			COALESCE(SUM(s.amount) FILTER (WHERE a.stake_type = ?1), 0) staked_backed,
+			COALESCE(SUM(s.amount) FILTER (WHERE a.stake_type = ?1 AND s.type != ?0), 0) staked_argument_backed,
+			COALESCE(SUM(s.amount) FILTER (WHERE a.stake_type = ?1 AND s.type = ?0), 0) staked_agree_backed,
+			COALESCE(SUM(s.amount) FILTER (WHERE a.stake_type = ?2), 0) staked_challenged,
+			COALESCE(SUM(s.amount) FILTER (WHERE a.stake_type = ?2 AND s.type != ?0), 0) staked_argument_challenged,
+			COALESCE(SUM(s.amount) FILTER (WHERE a.stake_type = ?2 AND s.type = ?0), 0) staked_agree_challenged,
+			MAX(s.created_time) FILTER (WHERE s.type = ?0) last_agree_time
+		FROM chain_stakes s
+		JOIN chain_arguments a ON a.id = s.argument_id
+		WHERE s.created_time < ?3
+		GROUP BY a.claim_id`, staking.StakeUpvote, staking.StakeBacking, staking.StakeChallenge, date)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range stakes {
+		claim, ok := index[s.ClaimID]
+		if !ok {
+			continue
+		}
+		claim.AgreesGiven = s.AgreesGiven
+		claim.StakedBacked = s.StakedBacked
+		claim.StakedArgumentBacked = s.StakedArgumentBacked
+		claim.StakedAgreeBacked = s.StakedAgreeBacked
+		claim.StakedChallenged = s.StakedChallenged
+		claim.StakedArgumentChallenged = s.StakedArgumentChallenged
+		claim.StakedAgreeChallenged = s.StakedAgreeChallenged
+		claim.LastAgreeTime = s.LastAgreeTime
+	}
+	return claims, nil
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Referral channels of the users, attributed from who referred them.
+const (
+	ChannelOrganic  = "organic"
+	ChannelReferral = "referral"
+)
+
+// signupChannelQuery selects the users who signed up since a time with their referral channel
+const signupChannelQuery = `
+	SELECT
+		u.id, u.address, u.created_at, u.last_authenticated_at, u.meta,
+		CASE WHEN COALESCE(u.referred_by, 0) = 0 THEN '` + ChannelOrganic + `' ELSE '` + ChannelReferral + `' END AS channel
+	FROM users u
+	WHERE u.deleted_at IS NULL AND u.created_at >= ?`
+
+// SignupCohort is the retention of the users who signed up in a week through a channel.
+// Users are retained on day N when they were active during the Nth day after signing up,
+// from N to N+1 days after it. EligibleDN counts the users whose day N is over.
+type SignupCohort struct {
+	Week        time.Time
+	Channel     string
+	Users       int64
+	EligibleD1  int64
+	RetainedD1  int64
+	EligibleD7  int64
+	RetainedD7  int64
+	EligibleD30 int64
+	RetainedD30 int64
+}
+
+// JourneyFunnelStep is the number of users of a channel who completed a step of the journey
+// along with all the steps before it.
+type JourneyFunnelStep struct {
+	Channel  string
+	Position int
+	Step     UserJourneyStep
+	Users    int64
+}
+
+// SignupCohorts returns the weekly signup cohorts since a time by referral channel. A user is
+// active when they track an event, in track_events or analytics_events, or last authenticated.
+func (c *Client) SignupCohorts(since, now time.Time) ([]SignupCohort, error) {
+	cohorts := make([]SignupCohort, 0)
+	_, err := c.Query(&cohorts, `
+		WITH cohort_users AS (`+signupChannelQuery+`
+		), activity AS (
+			SELECT address, created_at AS at
+			FROM track_events
+			WHERE address IS NOT NULL AND address <> '' AND created_at >= ?
+			UNION ALL
+			SELECT address, created_at
+			FROM analytics_events
+			WHERE address <> '' AND created_at >= ?
+			UNION ALL
+			SELECT address, last_authenticated_at
+			FROM cohort_users
+			WHERE last_authenticated_at IS NOT NULL
+		), retention AS (
+			SELECT
+				cu.channel,
+				cu.created_at,
+				date_trunc('week', cu.created_at) AS week,
+				BOOL_OR(a.at >= cu.created_at + interval '1 day' AND a.at < cu.created_at + interval '2 days') AS active_d1,
+				BOOL_OR(a.at >= cu.created_at + interval '7 days' AND a.at < cu.created_at + interval '8 days') AS active_d7,
+				BOOL_OR(a.at >= cu.created_at + interval '30 days' AND a.at < cu.created_at + interval '31 days') AS active_d30
+			FROM cohort_users cu
+			LEFT JOIN activity a ON a.address = cu.address
+			GROUP BY cu.id, cu.channel, cu.created_at
+		)
+		SELECT
+			week,
+			channel,
+			COUNT(*) AS users,
+			COUNT(*) FILTER (WHERE created_at + interval '2 days' <= ?) AS eligible_d1,
+			COUNT(*) FILTER (WHERE created_at + interval '2 days' <= ? AND active_d1) AS retained_d1,
+			COUNT(*) FILTER (WHERE created_at + interval '8 days' <= ?) AS eligible_d7,
+			COUNT(*) FILTER (WHERE created_at + interval '8 days' <= ? AND active_d7) AS retained_d7,
+			COUNT(*) FILTER (WHERE created_at + interval '31 days' <= ?) AS eligible_d30,
+			COUNT(*) FILTER (WHERE created_at + interval '31 days' <= ? AND active_d30) AS retained_d30
+		FROM retention
+		GROUP BY week, channel
+		ORDER BY week, channel`,
+		since, since, since, now, now, now, now, now, now)
+	if err != nil {
+		return nil, err
+	}
+	return cohorts, nil
+}
+
+// JourneyFunnel returns the funnel across the journey steps, in order, of the users who signed
+// up since a time by referral channel.
+func (c *Client) JourneyFunnel(steps []UserJourneyStep, since time.Time) ([]JourneyFunnelStep, error) {
+	funnel := make([]JourneyFunnelStep, 0)
+	if len(steps) == 0 {
+		return funnel, nil
+	}
+	params := []interface{}{since}
+	selects := make([]string, 0, len(steps))
+	for i, step := range steps {
+		// the users whose journey contains this step and all the ones before it
+		completed, err := json.Marshal(steps[:i+1])
+		if err != nil {
+			return nil, err
+		}
+		selects = append(selects, `
+			SELECT channel, ? AS position, ? AS step,
+				COUNT(*) FILTER (WHERE meta->'journey' @> ?::jsonb) AS users
+			FROM cohort_users
+			GROUP BY channel`)
+		params = append(params, i, step, string(completed))
+	}
+	_, err := c.Query(&funnel, `
+		WITH cohort_users AS (`+signupChannelQuery+`
+		)`+strings.Join(selects, `
+		UNION ALL`)+`
+		ORDER BY channel, position`, params...)
+	if err != nil {
+		return nil, err
+	}
+	return funnel, nil
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignupCohorts(t *testing.T) {
+	client := testClient(t)
+	defer client.Close()
+	prefix := testAddress("cohort")
+	signup := time.Date(2001, 10, 2, 12, 0, 0, 0, time.UTC)
+	now := signup.AddDate(0, 0, 60)
+	after := func(created time.Time, days int, hours time.Duration) *time.Time {
+		at := created.AddDate(0, 0, days).Add(hours * time.Hour)
+		return &at
+	}
+	late := now.Add(-50 * time.Hour)
+	users := []struct {
+		address           string
+		created           time.Time
+		lastAuthenticated *time.Time
+		tracked           []*time.Time
+	}{
+		// active on day 1 and 10, last authenticated on day 30
+		{prefix + "a", signup, after(signup, 30, 2), []*time.Time{after(signup, 1, 1), after(signup, 10, 0)}},
+		// active on day 0 and 7, last authenticated on day 40
+		{prefix + "b", signup.Add(time.Hour), after(signup.Add(time.Hour), 40, 0), []*time.Time{after(signup.Add(time.Hour), 0, 12), after(signup.Add(time.Hour), 7, 23)}},
+		{prefix + "c", signup, nil, nil},
+		// signed up too recently for day 7 to be over
+		{prefix + "d", late, nil, []*time.Time{after(late, 1, 2)}},
+	}
+	for _, u := range users {
+		user := &User{FullName: u.address, Address: u.address, UserGroup: UserGroupUser, LastAuthenticatedAt: u.lastAuthenticated}
+		user.CreatedAt = u.created
+		assert.NoError(t, client.Add(user))
+		for _, at := range u.tracked {
+			event := &TrackEvent{Address: u.address, Event: "claim_opened"}
+			event.CreatedAt = *at
+			assert.NoError(t, client.Add(event))
+		}
+	}
+	defer client.Model((*User)(nil)).Where("address LIKE ?", prefix+"%").Delete()
+	defer client.Model((*TrackEvent)(nil)).Where("address LIKE ?", prefix+"%").Delete()
+
+	cohorts, err := client.SignupCohorts(signup.AddDate(0, 0, -7), now)
+	assert.NoError(t, err)
+	found := make(map[string]SignupCohort)
+	for _, cohort := range cohorts {
+		if cohort.Channel == ChannelOrganic {
+			found[cohort.Week.Format("2006-01-02")] = cohort
+		}
+	}
+	// each user is retained on the day they were active, not on every day before their last activity
+	week := found["2001-10-01"]
+	assert.Equal(t, int64(3), week.Users)
+	assert.Equal(t, []int64{3, 1, 3, 1, 3, 1}, []int64{
+		week.EligibleD1, week.RetainedD1, week.EligibleD7, week.RetainedD7, week.EligibleD30, week.RetainedD30,
+	})
+	lateWeek := found["2001-11-26"]
+	assert.Equal(t, int64(1), lateWeek.Users)
+	assert.Equal(t, []int64{1, 1, 0, 0, 0, 0}, []int64{
+		lateWeek.EligibleD1, lateWeek.RetainedD1, lateWeek.EligibleD7, lateWeek.RetainedD7, lateWeek.EligibleD30, lateWeek.RetainedD30,
+	})
+}
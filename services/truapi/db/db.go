@@ -34,6 +34,7 @@ func NewDBClient(config truCtx.Config) *Client {
 		Database: config.Database.Name,
 		PoolSize: config.Database.Pool,
 	})
+	db.AddQueryHook(queryMetrics{})
 	if os.Getenv("PG_DEBUG_QUERY") == "true" {
 		db.AddQueryHook(dbLogger{})
 	}
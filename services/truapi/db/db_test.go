@@ -0,0 +1,47 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	truCtx "github.com/TruStory/octopus/services/truapi/context"
+)
+
+// testClient connects to the database DB-backed tests run against, migrated with
+// services/db. Tests are skipped when PG_TEST_DB_NAME isn't set, callers close the client.
+func testClient(t *testing.T) *Client {
+	name := os.Getenv("PG_TEST_DB_NAME")
+	if name == "" {
+		t.Skip("PG_TEST_DB_NAME not set, skipping DB-backed test")
+	}
+	port, err := strconv.Atoi(getTestEnv("PG_TEST_PORT", "5432"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewDBClient(truCtx.Config{
+		Database: truCtx.DatabaseConfig{
+			Host: getTestEnv("PG_TEST_ADDR", "localhost"),
+			Port: port,
+			User: getTestEnv("PG_TEST_USER", "postgres"),
+			Pass: os.Getenv("PG_TEST_USER_PW"),
+			Name: name,
+			Pool: 5,
+		},
+	})
+	return client
+}
+
+// testAddress returns an address no other test uses, so rows can be cleaned up by address.
+func testAddress(prefix string) string {
+	return fmt.Sprintf("%s%d", prefix, time.Now().UnixNano())
+}
+
+func getTestEnv(env, defaultValue string) string {
+	if val := os.Getenv(env); val != "" {
+		return val
+	}
+	return defaultValue
+}
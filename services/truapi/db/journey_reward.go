@@ -0,0 +1,227 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
+)
+
+// JourneyCompleted is the step of the rewards given for completing the required journey steps
+const JourneyCompleted UserJourneyStep = "completed"
+
+// JourneyRewardState is the state of a journey reward
+type JourneyRewardState string
+
+// A reward is pending until a run starts paying it, then either paid or failed.
+// Failed rewards are retried until they run out of attempts.
+const (
+	JourneyRewardPending    JourneyRewardState = "pending"
+	JourneyRewardProcessing JourneyRewardState = "processing"
+	JourneyRewardPaid       JourneyRewardState = "paid"
+	JourneyRewardFailed     JourneyRewardState = "failed"
+)
+
+// JourneyReward is a reward made due by a step of a user's journey, paid to the user or their referrer.
+// Amount is in the ledger currency, a count of invites or utru.
+type JourneyReward struct {
+	ID            int64
+	UserID        int64
+	Step          UserJourneyStep
+	RewardeeID    int64
+	Currency      RewardLedgerEntryCurrency
+	Amount        int64
+	State         JourneyRewardState
+	Attempts      int    `sql:",notnull"`
+	LastError     string `sql:",notnull"`
+	LedgerEntryID *int64
+	StartedAt     *time.Time
+	PaidAt        *time.Time
+	Timestamps
+}
+
+// LedgerReference is the reference of the ledger entry paying the reward, sent as the idempotency key of its gift.
+func (r *JourneyReward) LedgerReference() string {
+	return fmt.Sprintf("journey_reward:%d", r.ID)
+}
+
+// ProgressUserJourney updates the journey of a user and records the rewards it made due in a
+// single transaction, so rewards are recorded once for every completed step. It returns the
+// rewards that weren't recorded yet.
+func (c *Client) ProgressUserJourney(userID int64, journey []UserJourneyStep, rewards []JourneyReward) ([]JourneyReward, error) {
+	recorded := make([]JourneyReward, 0, len(rewards))
+	err := c.RunInTransaction(func(tx *pg.Tx) error {
+		_, err := tx.Model((*User)(nil)).
+			Where("id = ?", userID).
+			Where("deleted_at IS NULL").
+			Set("meta = COALESCE(meta, '{}') || ?", &UserMeta{Journey: journey}).
+			Update()
+		if err != nil {
+			return err
+		}
+		for i := range rewards {
+			reward := rewards[i]
+			reward.State = JourneyRewardPending
+			result, err := tx.Model(&reward).
+				OnConflict("ON CONSTRAINT journey_rewards_no_duplicate DO NOTHING").
+				Insert()
+			if err != nil {
+				return err
+			}
+			if result.RowsAffected() > 0 {
+				recorded = append(recorded, reward)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recorded, nil
+}
+
+// DueJourneyRewards returns the pending rewards and the failed ones with attempts left, oldest first.
+func (c *Client) DueJourneyRewards(maxAttempts int) ([]JourneyReward, error) {
+	rewards := make([]JourneyReward, 0)
+	err := c.Model(&rewards).
+		WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+			q = q.WhereOr("state = ?", JourneyRewardPending).
+				WhereOrGroup(func(q *orm.Query) (*orm.Query, error) {
+					return q.Where("state = ?", JourneyRewardFailed).Where("attempts < ?", maxAttempts), nil
+				})
+			return q, nil
+		}).
+		Order("id ASC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return rewards, nil
+}
+
+// StaleJourneyRewards returns the rewards a run started paying before a time and never finished.
+func (c *Client) StaleJourneyRewards(before time.Time) ([]JourneyReward, error) {
+	rewards := make([]JourneyReward, 0)
+	err := c.Model(&rewards).
+		Where("state = ?", JourneyRewardProcessing).
+		Where("started_at < ?", before).
+		Order("id ASC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return rewards, nil
+}
+
+// StartJourneyReward moves a due reward to processing, returning false when it isn't due anymore.
+func (c *Client) StartJourneyReward(reward *JourneyReward, maxAttempts int) (bool, error) {
+	result, err := c.Model(reward).
+		Set("state = ?", JourneyRewardProcessing).
+		Set("attempts = attempts + 1").
+		Set("started_at = NOW()").
+		Set("updated_at = NOW()").
+		WherePK().
+		WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+			q = q.WhereOr("state = ?", JourneyRewardPending).
+				WhereOrGroup(func(q *orm.Query) (*orm.Query, error) {
+					return q.Where("state = ?", JourneyRewardFailed).Where("attempts < ?", maxAttempts), nil
+				})
+			return q, nil
+		}).
+		Returning("*").
+		Update()
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// FailJourneyReward records why paying a processing reward failed.
+func (c *Client) FailJourneyReward(id int64, reason string) error {
+	_, err := c.Model((*JourneyReward)(nil)).
+		Set("state = ?", JourneyRewardFailed).
+		Set("last_error = ?", reason).
+		Set("updated_at = NOW()").
+		Where("id = ?", id).
+		Where("state = ?", JourneyRewardProcessing).
+		Update()
+	return err
+}
+
+// PayJourneyInviteReward grants the invites of a processing reward, records them in the
+// ledger and marks the reward paid in a single transaction.
+func (c *Client) PayJourneyInviteReward(reward *JourneyReward) error {
+	if reward.Currency != RewardLedgerEntryCurrencyInvite {
+		return fmt.Errorf("reward %d is in %s, not invites", reward.ID, reward.Currency)
+	}
+	return c.RunInTransaction(func(tx *pg.Tx) error {
+		_, err := tx.Model((*User)(nil)).
+			Where("id = ?", reward.RewardeeID).
+			Set("invites_left = invites_left + ?", reward.Amount).
+			Update()
+		if err != nil {
+			return err
+		}
+		entry := &RewardLedgerEntry{
+			UserID:    reward.RewardeeID,
+			Direction: RewardLedgerEntryDirectionCredit,
+			Amount:    reward.Amount,
+			Currency:  reward.Currency,
+			Reference: reward.LedgerReference(),
+		}
+		err = tx.Insert(entry)
+		if err != nil {
+			return err
+		}
+		return markJourneyRewardPaid(tx, reward, entry.ID)
+	})
+}
+
+// ReconcileJourneyReward marks a processing reward paid when the ledger has the entry referencing it.
+// It returns false when there's none.
+func (c *Client) ReconcileJourneyReward(reward *JourneyReward) (bool, error) {
+	reconciled := false
+	err := c.RunInTransaction(func(tx *pg.Tx) error {
+		entry := new(RewardLedgerEntry)
+		err := tx.Model(entry).
+			Where("reference = ?", reward.LedgerReference()).
+			Select()
+		if err == pg.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = markJourneyRewardPaid(tx, reward, entry.ID)
+		if err != nil {
+			return err
+		}
+		reconciled = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return reconciled, nil
+}
+
+func markJourneyRewardPaid(tx *pg.Tx, reward *JourneyReward, ledgerEntryID int64) error {
+	result, err := tx.Model(reward).
+		Set("state = ?", JourneyRewardPaid).
+		Set("ledger_entry_id = ?", ledgerEntryID).
+		Set("last_error = ''").
+		Set("paid_at = NOW()").
+		Set("updated_at = NOW()").
+		WherePK().
+		Where("state = ?", JourneyRewardProcessing).
+		Returning("*").
+		Update()
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("reward %d isn't processing", reward.ID)
+	}
+	return nil
+}
@@ -106,3 +106,171 @@ func (c *Client) Leaderboard(since time.Time, sortBy string, limit int, excluded
 	}
 	return topUsers, nil
 }
+
+// Leaderboard periods ranks are kept for
+const (
+	LeaderboardPeriodWeek    = "week"
+	LeaderboardPeriodMonth   = "month"
+	LeaderboardPeriodAllTime = "all"
+)
+
+// LeaderboardRank is the rank of a user for a metric over a period ending on the date.
+// An empty community ID ranks the activity across all active communities.
+type LeaderboardRank struct {
+	ID          int64
+	Date        time.Time
+	Period      string
+	Metric      string
+	CommunityID string `sql:",notnull"`
+	Address     string
+	Value       int64 `sql:"type:,notnull"`
+	Rank        int64 `sql:"type:,notnull"`
+	Timestamps
+}
+
+// LeaderboardRankEntry is a rank along with the rank of the previous period and the
+// position in the list. PreviousRank is 0 when the user wasn't ranked.
+type LeaderboardRankEntry struct {
+	Date         time.Time
+	Address      string
+	CommunityID  string
+	Value        int64
+	Rank         int64
+	PreviousRank int64
+	Position     int64
+}
+
+// LastLeaderboardRankDate returns the date of the latest ranks, nil if none are saved yet.
+func (c *Client) LastLeaderboardRankDate() (*time.Time, error) {
+	rank := &LeaderboardRank{}
+	err := c.Model(rank).Column("date").Order("date DESC").Limit(1).Select()
+	if err == pg.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rank.Date, nil
+}
+
+// SaveLeaderboardRanks replaces the ranks of a period ending on the date, ranking the
+// metrics of users from since until the date per community and across communities.
+func (c *Client) SaveLeaderboardRanks(tx *pg.Tx, date time.Time, period string, since time.Time, metrics []string, excludedCommunities []string) error {
+	_, err := tx.Exec(`DELETE FROM leaderboard_ranks WHERE date = ? AND period = ?`, date, period)
+	if err != nil {
+		return err
+	}
+	excluded := ""
+	if len(excludedCommunities) > 0 {
+		excluded = "AND community_id NOT IN (?5)"
+	}
+	for _, metric := range metrics {
+		_, err = tx.Exec(`
+			INSERT INTO leaderboard_ranks (date, period, metric, community_id, address, value, rank)
+			SELECT ?0, ?1, ?2, community_id, address, SUM(?3),
+				RANK() OVER (PARTITION BY community_id ORDER BY SUM(?3) DESC)
+			FROM leaderboard_user_metrics
+			WHERE date >= ?4 AND date <= ?0
+			GROUP BY community_id, address
+			HAVING SUM(?3) > 0
+		`, date, period, metric, pg.F(metric), since)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(fmt.Sprintf(`
+			INSERT INTO leaderboard_ranks (date, period, metric, community_id, address, value, rank)
+			SELECT ?0, ?1, ?2, '', address, SUM(?3),
+				RANK() OVER (ORDER BY SUM(?3) DESC)
+			FROM leaderboard_user_metrics
+			WHERE date >= ?4 AND date <= ?0 %s
+			GROUP BY address
+			HAVING SUM(?3) > 0
+		`, excluded), date, period, metric, pg.F(metric), since, pg.In(excludedCommunities))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rankedEntriesQuery numbers the ranks of a period and metric on ?0 joined with the ranks on ?1.
+const rankedEntriesQuery = `
+	SELECT r.date, r.address, r.community_id, r.value, r.rank,
+		COALESCE(p.rank, 0) AS previous_rank,
+		ROW_NUMBER() OVER (ORDER BY r.rank, r.address) AS position
+	FROM leaderboard_ranks r
+	LEFT JOIN leaderboard_ranks p ON p.date = ?1
+		AND p.period = r.period
+		AND p.metric = r.metric
+		AND p.community_id = r.community_id
+		AND p.address = r.address
+	WHERE r.date = ?0 AND r.period = ?2 AND r.metric = ?3 AND r.community_id = ?4
+`
+
+// LeaderboardRanks returns a page of the ranks on the date along with the ranks on the previous date.
+func (c *Client) LeaderboardRanks(date, previousDate time.Time, period, metric, communityID string, limit, offset int) ([]LeaderboardRankEntry, error) {
+	entries := make([]LeaderboardRankEntry, 0)
+	_, err := c.Query(&entries, `
+		SELECT * FROM (`+rankedEntriesQuery+`) ranked
+		WHERE position > ?5
+		ORDER BY position
+		LIMIT ?6
+	`, date, previousDate, period, metric, communityID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// LeaderboardRankNeighbors returns the rank of the address surrounded by up to count users
+// ranked above and below it. It's empty when the address isn't ranked.
+func (c *Client) LeaderboardRankNeighbors(date, previousDate time.Time, period, metric, communityID, address string, count int) ([]LeaderboardRankEntry, error) {
+	entries := make([]LeaderboardRankEntry, 0)
+	_, err := c.Query(&entries, `
+		WITH ranked AS (`+rankedEntriesQuery+`),
+		own AS (SELECT position FROM ranked WHERE address = ?5)
+		SELECT ranked.* FROM ranked, own
+		WHERE ranked.position BETWEEN own.position - ?6 AND own.position + ?6
+		ORDER BY ranked.position
+	`, date, previousDate, period, metric, communityID, address, count)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// LeaderboardUserRank returns the rank of the address on the date, nil when it isn't ranked.
+func (c *Client) LeaderboardUserRank(date time.Time, period, metric, communityID, address string) (*LeaderboardRank, error) {
+	rank := &LeaderboardRank{}
+	err := c.Model(rank).
+		Where("date = ?", date).
+		Where("period = ?", period).
+		Where("metric = ?", metric).
+		Where("community_id = ?", communityID).
+		Where("address = ?", address).
+		Select()
+	if err == pg.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rank, nil
+}
+
+// LeaderboardRankHistory returns the daily ranks of the address since the date, oldest first.
+func (c *Client) LeaderboardRankHistory(address, period, metric, communityID string, since time.Time) ([]LeaderboardRank, error) {
+	ranks := make([]LeaderboardRank, 0)
+	err := c.Model(&ranks).
+		Where("address = ?", address).
+		Where("period = ?", period).
+		Where("metric = ?", metric).
+		Where("community_id = ?", communityID).
+		Where("date >= ?", since).
+		Order("date ASC").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return ranks, nil
+}
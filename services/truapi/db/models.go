@@ -49,6 +49,7 @@ type Mutations interface {
 	UpdateProfile(id int64, profile *UserProfile) error
 	SetUserCredentials(id int64, credentials *UserCredentials) error
 	SetUserMeta(id int64, userMeta *UserMeta) error
+	SetUserLocale(id int64, locale string) error
 	IssueResetToken(userID int64) (*PasswordResetToken, error)
 	UseResetToken(prt *PasswordResetToken) error
 	UpsertConnectedAccount(connectedAccount *ConnectedAccount) error
@@ -57,13 +58,34 @@ type Mutations interface {
 	FollowedCommunities(address string) ([]FollowedCommunity, error)
 	UnfollowCommunity(address, communityID string) error
 	FollowsCommunity(address, communityID string) (bool, error)
-	AddImageURLToHighlight(id int64, url string) error
 	GrantInvites(id int64, count int) error
 	ConsumeInvite(id int64) (bool, error)
 	UsersWithIncompleteJourney() ([]User, error)
 	UpdateUserJourney(id int64, journey []UserJourneyStep) error
 	RecordRewardLedgerEntry(userID int64, direction RewardLedgerEntryDirection, amount int64, currency RewardLedgerEntryCurrency) (*RewardLedgerEntry, error)
+	RecordReferencedRewardLedgerEntry(entry *RewardLedgerEntry) (*RewardLedgerEntry, bool, error)
+	RemoveRewardLedgerEntry(id int64) error
 	RecordVerificationAttempt(id int64) error
+	AddBroadcastCampaign(campaign *BroadcastCampaign) error
+	CancelBroadcastCampaign(id int64) (bool, error)
+	ClaimDueBroadcastCampaigns(now, staleBefore time.Time) ([]BroadcastCampaign, error)
+	TouchBroadcastCampaign(id int64) error
+	ReleaseBroadcastCampaign(id int64) error
+	SetBroadcastCampaignRecipients(id int64, count int) error
+	FinishBroadcastCampaign(id int64, status BroadcastCampaignStatus) error
+	SaveChainBlock(name string, block *ChainBlock) error
+	RecordAchievementUnlock(unlock *AchievementUnlock) (bool, error)
+	ClaimAchievementReward(id int64) (bool, error)
+	RecordAchievementReward(unlock *AchievementUnlock) error
+	ReleaseAchievementReward(id int64) error
+	ProgressUserJourney(userID int64, journey []UserJourneyStep, rewards []JourneyReward) ([]JourneyReward, error)
+	StartJourneyReward(reward *JourneyReward, maxAttempts int) (bool, error)
+	FailJourneyReward(id int64, reason string) error
+	PayJourneyInviteReward(reward *JourneyReward) error
+	EnsureAnalyticsEventPartition(month time.Time) error
+	AddAnalyticsEvents(events []AnalyticsEvent) error
+	SaveAnalyticsExportCursor(name string, lastEventID int64) error
+	ReconcileJourneyReward(reward *JourneyReward) (bool, error)
 }
 
 // Queries read from the database
@@ -73,6 +95,7 @@ type Queries interface {
 	KeyPairByUserID(userID int64) (*KeyPair, error)
 	DeviceTokensByAddress(addr string) ([]DeviceToken, error)
 	NotificationEventsByAddress(addr string) ([]NotificationEvent, error)
+	AggregatableNotificationEvent(addr string, nType NotificationType, typeID int64, meta NotificationMeta, since time.Time) (*NotificationEvent, error)
 	UnreadNotificationEventsCountByAddress(addr string) (*NotificationsCountResponse, error)
 	UnseenNotificationEventsCountByAddress(addr string) (*NotificationsCountResponse, error)
 	FlaggedStoriesIDs(flagAdmin string, flagLimit int) ([]int64, error)
@@ -122,8 +145,36 @@ type Queries interface {
 	FeedLeaderboardInTransaction(fn func(*pg.Tx) error) error
 	UpsertLeaderboardMetric(tx *pg.Tx, metric *LeaderboardUserMetric) error
 	UpsertLeaderboardProcessedDate(tx *pg.Tx, metric *LeaderboardProcessedDate) error
+	SaveLeaderboardRanks(tx *pg.Tx, date time.Time, period string, since time.Time, metrics []string, excludedCommunities []string) error
+	LastLeaderboardRankDate() (*time.Time, error)
+	LeaderboardRanks(date, previousDate time.Time, period, metric, communityID string, limit, offset int) ([]LeaderboardRankEntry, error)
+	LeaderboardRankNeighbors(date, previousDate time.Time, period, metric, communityID, address string, count int) ([]LeaderboardRankEntry, error)
+	LeaderboardRankHistory(address, period, metric, communityID string, since time.Time) ([]LeaderboardRank, error)
+	LeaderboardUserRank(date time.Time, period, metric, communityID, address string) (*LeaderboardRank, error)
+	AchievementCandidates(achievementID, metric string, perCommunity bool, threshold int64) ([]AchievementCandidate, error)
+	AchievementUnlocksByAddress(address string) ([]AchievementUnlock, error)
+	UnrewardedAchievementUnlocks() ([]AchievementUnlock, error)
+	DueJourneyRewards(maxAttempts int) ([]JourneyReward, error)
+	StaleJourneyRewards(before time.Time) ([]JourneyReward, error)
+	RewardLedgerBalances(userID int64) ([]RewardLedgerBalance, error)
+	RewardLedgerEntries(userID int64, currency RewardLedgerEntryCurrency, beforeID int64, limit int) ([]RewardLedgerEntry, error)
+	RewardLedgerDiscrepancies(cursor string) ([]RewardLedgerDiscrepancy, error)
+	AnalyticsEventsAfter(id int64, before time.Time, limit int) ([]AnalyticsEvent, error)
+	AnalyticsExportCursor(name string) (int64, error)
+	SignupCohorts(since, now time.Time) ([]SignupCohort, error)
+	JourneyFunnel(steps []UserJourneyStep, since time.Time) ([]JourneyFunnelStep, error)
 	UserRepliesStats(date time.Time) ([]UserRepliesStats, error)
 	UnverifiedUsersWithinDays(days int64) ([]User, error)
+	BroadcastCampaigns() ([]BroadcastCampaign, error)
+	BroadcastCampaignByID(id int64) (*BroadcastCampaign, error)
+	BroadcastCampaignStats(id int64) (*BroadcastCampaignStats, error)
+	BroadcastCampaignNotifiedAddresses(id int64) ([]string, error)
+	UsersBySegment(segment BroadcastSegment) ([]User, error)
+	ChainIndexCursorByName(name string) (*ChainIndexCursor, error)
+	ChainArgumentsByIDs(ids []uint64) ([]ChainArgument, error)
+	ChainUserCommunityStatsBefore(date, pendingSince time.Time) ([]ChainUserCommunityStats, error)
+	ChainBalancesBefore(date time.Time) (map[string]int64, error)
+	ChainClaimStatsBefore(date time.Time) ([]ChainClaimStats, error)
 
 	// deprecated, use UserProfileByAddress/UserProfileByUsername
 	TwitterProfileByAddress(addr string) (*TwitterProfile, error)
@@ -3,6 +3,8 @@ package db
 import (
 	"fmt"
 	"time"
+
+	"github.com/go-pg/pg"
 )
 
 // NotificationType represents a type of notification defiend by the system.
@@ -35,6 +37,8 @@ const (
 	NotificationFeaturedDebate
 	NotificationStakeLimitIncreased
 	NotificationGift
+	NotificationBroadcast
+	NotificationAchievementUnlocked
 )
 
 var NotificationTypeName = []string{
@@ -55,6 +59,30 @@ var NotificationTypeName = []string{
 	NotificationFeaturedDebate:        "Featured Debate",
 	NotificationStakeLimitIncreased:   "Staking Limit Increased",
 	NotificationGift:                  "Gift Received",
+	NotificationBroadcast:             "Announcement",
+	NotificationAchievementUnlocked:   "Achievement Unlocked",
+}
+
+var notificationTypeKey = []string{
+	NotificationStoryAction:           "story_action",
+	NotificationArgumentAction:        "argument_action",
+	NotificationCommentAction:         "comment_action",
+	NotificationMentionAction:         "mention_action",
+	NotificationNewArgument:           "new_argument",
+	NotificationAgreeReceived:         "agree_received",
+	NotificationNotHelpful:            "not_helpful",
+	NotificationEarnedStake:           "earned_stake",
+	NotificationSlashed:               "slashed",
+	NotificationJailed:                "jailed",
+	NotificationUnjailed:              "unjailed",
+	NotificationArgumentCommentAction: "argument_comment_action",
+	NotificationRewardInviteUnlocked:  "reward_invite_unlocked",
+	NotificationRewardTruUnlocked:     "reward_tru_unlocked",
+	NotificationFeaturedDebate:        "featured_debate",
+	NotificationStakeLimitIncreased:   "stake_limit_increased",
+	NotificationGift:                  "gift",
+	NotificationBroadcast:             "broadcast",
+	NotificationAchievementUnlocked:   "achievement_unlocked",
 }
 
 func (t NotificationType) String() string {
@@ -64,6 +92,14 @@ func (t NotificationType) String() string {
 	return NotificationTypeName[t]
 }
 
+// Key returns the message catalog key for the notification type name.
+func (t NotificationType) Key() string {
+	if int(t) >= len(notificationTypeKey) {
+		return ""
+	}
+	return fmt.Sprintf("notification.type.%s", notificationTypeKey[t])
+}
+
 // MentionType represents  the types on how an user can be mentioned.
 type MentionType int64
 
@@ -96,6 +132,14 @@ type NotificationMeta struct {
 	CommentID      *int64       `json:"commentId,omitempty" graphql:"commentId"`
 	MentionType    *MentionType `json:"mentionType,omitempty" graphql:"mentionType"`
 	RewardCauserID *int64       `json:"rewardCauserId,omitempty" graphql:"rewardCauserId"`
+	// Count is the number of events collapsed into an aggregated notification.
+	Count *int64 `json:"count,omitempty" graphql:"count"`
+	// Actors are the addresses of the senders of an aggregated notification, most recent first.
+	Actors []string `json:"actors,omitempty" graphql:"actors"`
+	// CampaignID is the broadcast campaign the notification was sent for.
+	CampaignID *int64 `json:"campaignId,omitempty" graphql:"campaignId"`
+	// AchievementID is the achievement unlocked.
+	AchievementID *string `json:"achievementId,omitempty" graphql:"achievementId"`
 }
 
 // NotificationEvent represents a notification sent to an user.
@@ -130,6 +174,32 @@ func (c *Client) NotificationEventsByAddress(addr string) ([]NotificationEvent,
 	return evts, nil
 }
 
+// AggregatableNotificationEvent retrieves the latest unread notification sent to an user
+// for the same type and target created after the given time.
+func (c *Client) AggregatableNotificationEvent(addr string, nType NotificationType, typeID int64, meta NotificationMeta, since time.Time) (*NotificationEvent, error) {
+	evt := new(NotificationEvent)
+	q := c.Model(evt).
+		Where("notification_event.address = ?", addr).
+		Where("notification_event.type = ?", nType).
+		Where("notification_event.type_id = ?", typeID).
+		Where("notification_event.timestamp > ?", since).
+		Where("read is NULL or read is FALSE")
+	if meta.ArgumentID != nil {
+		q = q.Where("(notification_event.meta->>'argumentId')::integer = ?", *meta.ArgumentID)
+	}
+	if meta.ElementID != nil {
+		q = q.Where("(notification_event.meta->>'elementId')::integer = ?", *meta.ElementID)
+	}
+	err := q.Order("timestamp DESC").First()
+	if err == pg.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return evt, nil
+}
+
 // UnreadNotificationEventsCountByAddress retrieves the number of unread notifications sent to an user.
 func (c *Client) UnreadNotificationEventsCountByAddress(addr string) (*NotificationsCountResponse, error) {
 	notificationEvent := new(NotificationEvent)
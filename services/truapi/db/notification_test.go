@@ -0,0 +1,50 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregatableNotificationEvent(t *testing.T) {
+	client := testClient(t)
+	defer client.Close()
+	addr := testAddress("agg")
+	defer client.Model((*NotificationEvent)(nil)).Where("address = ?", addr).Delete()
+
+	argumentID, otherArgumentID := int64(1), int64(2)
+	now := time.Now()
+	events := []*NotificationEvent{
+		// the most recent unread agree on the argument is the one aggregated into
+		{Address: addr, Type: NotificationAgreeReceived, TypeID: 10, Timestamp: now.Add(-5 * time.Minute), Meta: NotificationMeta{ArgumentID: &argumentID}, Message: "recent"},
+		{Address: addr, Type: NotificationAgreeReceived, TypeID: 10, Timestamp: now.Add(-10 * time.Minute), Meta: NotificationMeta{ArgumentID: &argumentID}, Message: "older"},
+		// outside the window, read, on another argument, of another type or target
+		{Address: addr, Type: NotificationAgreeReceived, TypeID: 11, Timestamp: now.Add(-time.Hour), Meta: NotificationMeta{ArgumentID: &argumentID}, Message: "expired"},
+		{Address: addr, Type: NotificationAgreeReceived, TypeID: 11, Timestamp: now.Add(-time.Minute), Meta: NotificationMeta{ArgumentID: &argumentID}, Message: "read", Read: true},
+		{Address: addr, Type: NotificationAgreeReceived, TypeID: 11, Timestamp: now.Add(-time.Minute), Meta: NotificationMeta{ArgumentID: &otherArgumentID}, Message: "other argument"},
+		{Address: addr, Type: NotificationCommentAction, TypeID: 12, Timestamp: now.Add(-time.Minute), Message: "reply"},
+	}
+	for _, evt := range events {
+		assert.NoError(t, client.Add(evt))
+	}
+	since := now.Add(-30 * time.Minute)
+
+	evt, err := client.AggregatableNotificationEvent(addr, NotificationAgreeReceived, 10, NotificationMeta{ArgumentID: &argumentID}, since)
+	assert.NoError(t, err)
+	if assert.NotNil(t, evt) {
+		assert.Equal(t, "recent", evt.Message)
+	}
+
+	evt, err = client.AggregatableNotificationEvent(addr, NotificationAgreeReceived, 11, NotificationMeta{ArgumentID: &argumentID}, since)
+	assert.NoError(t, err)
+	assert.Nil(t, evt)
+
+	evt, err = client.AggregatableNotificationEvent(addr, NotificationAgreeReceived, 12, NotificationMeta{}, since)
+	assert.NoError(t, err)
+	assert.Nil(t, evt)
+
+	evt, err = client.AggregatableNotificationEvent(addr, NotificationCommentAction, 12, NotificationMeta{}, since)
+	assert.NoError(t, err)
+	assert.NotNil(t, evt)
+}
@@ -1,5 +1,9 @@
 package db
 
+import (
+	"github.com/TruStory/truchain/x/bank/exported"
+)
+
 // RewardLedgerEntry represents an entry into the reward ledger
 type RewardLedgerEntry struct {
 	Timestamps
@@ -9,6 +13,8 @@ type RewardLedgerEntry struct {
 	Direction RewardLedgerEntryDirection `json:"direction"`
 	Amount    int64                      `json:"amount"`
 	Currency  RewardLedgerEntryCurrency  `json:"currency"`
+	// Reference identifies what the entry pays, like a journey reward, so it's recorded once
+	Reference string `json:"reference,omitempty"`
 }
 
 // RewardLedgerEntryDirection represents the direction for an entry
@@ -49,3 +55,151 @@ func (c *Client) RecordRewardLedgerEntry(
 
 	return entry, nil
 }
+
+// RecordReferencedRewardLedgerEntry records an entry with a reference before what it pays is sent.
+// It returns false with the recorded entry when the reference already has one, and true after
+// recording the new entry. Entries without a reference are always recorded.
+func (c *Client) RecordReferencedRewardLedgerEntry(entry *RewardLedgerEntry) (*RewardLedgerEntry, bool, error) {
+	if entry.Reference == "" {
+		err := c.Add(entry)
+		if err != nil {
+			return nil, false, err
+		}
+		return entry, true, nil
+	}
+	result, err := c.Model(entry).
+		OnConflict("(reference) DO NOTHING").
+		Insert()
+	if err != nil {
+		return nil, false, err
+	}
+	if result.RowsAffected() > 0 {
+		return entry, true, nil
+	}
+	recorded := new(RewardLedgerEntry)
+	err = c.Model(recorded).Where("reference = ?", entry.Reference).Select()
+	if err != nil {
+		return nil, false, err
+	}
+	return recorded, false, nil
+}
+
+// RemoveRewardLedgerEntry removes an entry recorded for a payment that wasn't sent.
+func (c *Client) RemoveRewardLedgerEntry(id int64) error {
+	_, err := c.Model((*RewardLedgerEntry)(nil)).Where("id = ?", id).Delete()
+	return err
+}
+
+// RewardLedgerBalance is the balance of a user in a currency of the reward ledger
+type RewardLedgerBalance struct {
+	Currency RewardLedgerEntryCurrency `json:"currency"`
+	Credits  int64                     `json:"credits"`
+	Debits   int64                     `json:"debits"`
+	Balance  int64                     `json:"balance"`
+}
+
+// RewardLedgerDiscrepancy is a user whose ledger balance doesn't match the actual balance,
+// the on-chain gifts for utru and the invites left for invites.
+type RewardLedgerDiscrepancy struct {
+	UserID     int64                     `json:"user_id"`
+	Username   string                    `json:"username"`
+	Address    string                    `json:"address"`
+	Currency   RewardLedgerEntryCurrency `json:"currency"`
+	Ledger     int64                     `json:"ledger"`
+	Actual     int64                     `json:"actual"`
+	Difference int64                     `json:"difference"`
+}
+
+// RewardLedgerBalances returns the balances of a user in every currency of the ledger
+func (c *Client) RewardLedgerBalances(userID int64) ([]RewardLedgerBalance, error) {
+	balances := make([]RewardLedgerBalance, 0)
+	_, err := c.Query(&balances, `
+		SELECT currency, credits, debits, credits - debits AS balance
+		FROM (
+			SELECT
+				currency,
+				COALESCE(SUM(amount) FILTER (WHERE direction = ?1), 0) AS credits,
+				COALESCE(SUM(amount) FILTER (WHERE direction = ?2), 0) AS debits
+			FROM reward_ledger_entries
+			WHERE user_id = ?0 AND deleted_at IS NULL
+			GROUP BY currency
+		) totals
+		ORDER BY currency`,
+		userID, RewardLedgerEntryDirectionCredit, RewardLedgerEntryDirectionDebit)
+	if err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+// RewardLedgerEntries returns the entries of a user, newest first. The currency is optional
+// and beforeID pages through the history, 0 starts from the latest entry.
+func (c *Client) RewardLedgerEntries(userID int64, currency RewardLedgerEntryCurrency, beforeID int64, limit int) ([]RewardLedgerEntry, error) {
+	entries := make([]RewardLedgerEntry, 0)
+	query := c.Model(&entries).
+		Where("user_id = ?", userID).
+		Where("deleted_at IS NULL")
+	if currency != "" {
+		query = query.Where("currency = ?", currency)
+	}
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+	err := query.Order("id DESC").Limit(limit).Select()
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// RewardLedgerDiscrepancies compares the ledger with the actual balances of the users.
+// utru credits are compared with the gifts in the chain index, both from the first indexed
+// transaction to the block time of the cursor, leaving out the registration gifts which aren't
+// rewards. The invite balance is compared with the invites left.
+func (c *Client) RewardLedgerDiscrepancies(cursor string) ([]RewardLedgerDiscrepancy, error) {
+	discrepancies := make([]RewardLedgerDiscrepancy, 0)
+	_, err := c.Query(&discrepancies, `
+		WITH indexed AS (
+			SELECT MIN(created_time) AS since,
+				(SELECT block_time FROM chain_index_cursors WHERE name = ?5) AS until
+			FROM chain_transactions
+		), tru_ledger AS (
+			SELECT e.user_id, SUM(e.amount) AS amount
+			FROM reward_ledger_entries e, indexed
+			WHERE e.currency = ?0 AND e.direction = ?2 AND e.deleted_at IS NULL
+				AND e.created_at >= indexed.since AND e.created_at <= indexed.until
+			GROUP BY e.user_id
+		), gifts AS (
+			SELECT address, SUM(amount) AS amount
+			FROM chain_transactions
+			WHERE type = ?4 AND NOT registration
+			GROUP BY address
+		), invite_ledger AS (
+			SELECT user_id, SUM(CASE WHEN direction = ?2 THEN amount ELSE -amount END) AS amount
+			FROM reward_ledger_entries
+			WHERE currency = ?1 AND direction IN (?2, ?3) AND deleted_at IS NULL
+			GROUP BY user_id
+		), balances AS (
+			SELECT u.id AS user_id, u.username, u.address, ?0 AS currency,
+				COALESCE(l.amount, 0) AS ledger, COALESCE(g.amount, 0) AS actual
+			FROM users u
+			LEFT JOIN tru_ledger l ON l.user_id = u.id
+			LEFT JOIN gifts g ON g.address = u.address
+			UNION ALL
+			SELECT u.id, u.username, u.address, ?1,
+				COALESCE(l.amount, 0), COALESCE(u.invites_left, 0)
+			FROM users u
+			LEFT JOIN invite_ledger l ON l.user_id = u.id
+		)
+		SELECT *, ledger - actual AS difference
+		FROM balances
+		WHERE ledger <> actual
+		ORDER BY currency, user_id`,
+		RewardLedgerEntryCurrencyTru, RewardLedgerEntryCurrencyInvite,
+		RewardLedgerEntryDirectionCredit, RewardLedgerEntryDirectionDebit,
+		exported.TransactionGift, cursor)
+	if err != nil {
+		return nil, err
+	}
+	return discrepancies, nil
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TruStory/truchain/x/bank/exported"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordReferencedRewardLedgerEntry(t *testing.T) {
+	client := testClient(t)
+	defer client.Close()
+	address := testAddress("ledger")
+	user := &User{FullName: address, Address: address, UserGroup: UserGroupUser}
+	assert.NoError(t, client.Add(user))
+	defer client.Model((*User)(nil)).Where("address = ?", address).Delete()
+	defer client.Model((*RewardLedgerEntry)(nil)).Where("user_id = ?", user.ID).Delete()
+
+	entry := func(reference string) *RewardLedgerEntry {
+		return &RewardLedgerEntry{
+			UserID:    user.ID,
+			Direction: RewardLedgerEntryDirectionCredit,
+			Amount:    1000,
+			Currency:  RewardLedgerEntryCurrencyTru,
+			Reference: reference,
+		}
+	}
+
+	first, recorded, err := client.RecordReferencedRewardLedgerEntry(entry(address))
+	assert.NoError(t, err)
+	assert.True(t, recorded)
+
+	again, recorded, err := client.RecordReferencedRewardLedgerEntry(entry(address))
+	assert.NoError(t, err)
+	assert.False(t, recorded)
+	assert.Equal(t, first.ID, again.ID)
+
+	// entries without a reference are always recorded
+	for i := 0; i < 2; i++ {
+		_, recorded, err = client.RecordReferencedRewardLedgerEntry(entry(""))
+		assert.NoError(t, err)
+		assert.True(t, recorded)
+	}
+
+	assert.NoError(t, client.RemoveRewardLedgerEntry(first.ID))
+	_, recorded, err = client.RecordReferencedRewardLedgerEntry(entry(address))
+	assert.NoError(t, err)
+	assert.True(t, recorded)
+}
+
+func TestRewardLedgerDiscrepancies(t *testing.T) {
+	client := testClient(t)
+	defer client.Close()
+	prefix := testAddress("reconciliation")
+	// alice was gifted a reward on top of her registration gift, bob's reward was recorded and
+	// never sent, carol registered before the first indexed block so her registration isn't indexed,
+	// dave's reward was gifted after the last indexed block
+	alice, bob, carol, dave := prefix+"alice", prefix+"bob", prefix+"carol", prefix+"dave"
+	users := make(map[string]*User)
+	for _, address := range []string{alice, bob, carol, dave} {
+		user := &User{FullName: address, Address: address, UserGroup: UserGroupUser}
+		assert.NoError(t, client.Add(user))
+		users[address] = user
+	}
+	defer client.Model((*User)(nil)).Where("address LIKE ?", prefix+"%").Delete()
+
+	id := uint64(time.Now().UnixNano() / 1000)
+	indexedAt := time.Now().Add(-time.Hour)
+	transactions := []*ChainTransaction{
+		{ID: id, Type: exported.TransactionGift, Address: alice, Amount: 300, Registration: true, Height: 1, CreatedTime: indexedAt},
+		{ID: id + 1, Type: exported.TransactionGift, Address: alice, Amount: 500, Height: 2, CreatedTime: indexedAt},
+		{ID: id + 2, Type: exported.TransactionGift, Address: bob, Amount: 300, Registration: true, Height: 1, CreatedTime: indexedAt},
+		{ID: id + 3, Type: exported.TransactionGift, Address: carol, Amount: 200, Height: 2, CreatedTime: indexedAt},
+	}
+	for _, transaction := range transactions {
+		assert.NoError(t, client.Add(transaction))
+	}
+	defer client.Model((*ChainTransaction)(nil)).Where("address LIKE ?", prefix+"%").Delete()
+
+	cursor := &ChainIndexCursor{Name: prefix + "chain", Height: 2, BlockTime: indexedAt.Add(30 * time.Minute)}
+	assert.NoError(t, client.Add(cursor))
+	defer client.Delete(cursor)
+
+	credits := map[string]int64{alice: 500, bob: 400, carol: 200, dave: 250}
+	for address, amount := range credits {
+		_, err := client.RecordRewardLedgerEntry(users[address].ID, RewardLedgerEntryDirectionCredit, amount, RewardLedgerEntryCurrencyTru)
+		assert.NoError(t, err)
+		if address != dave {
+			_, err = client.Model((*RewardLedgerEntry)(nil)).
+				Set("created_at = ?", indexedAt.Add(15*time.Minute)).
+				Where("user_id = ?", users[address].ID).
+				Update()
+			assert.NoError(t, err)
+		}
+	}
+	for _, user := range users {
+		defer client.Model((*RewardLedgerEntry)(nil)).Where("user_id = ?", user.ID).Delete()
+	}
+
+	discrepancies, err := client.RewardLedgerDiscrepancies(cursor.Name)
+	assert.NoError(t, err)
+	found := make([]RewardLedgerDiscrepancy, 0)
+	for _, discrepancy := range discrepancies {
+		if strings.HasPrefix(discrepancy.Address, prefix) {
+			found = append(found, discrepancy)
+		}
+	}
+	assert.Equal(t, []RewardLedgerDiscrepancy{{
+		UserID:     users[bob].ID,
+		Username:   users[bob].Username,
+		Address:    bob,
+		Currency:   RewardLedgerEntryCurrencyTru,
+		Ledger:     400,
+		Actual:     0,
+		Difference: 400,
+	}}, found)
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/TruStory/octopus/services/truapi/telemetry"
+)
+
+type queryStartKey struct{}
+type querySpanKey struct{}
+
+// queryMetrics records the duration of queries, and traces them when they run in a traced context
+type queryMetrics struct{}
+
+func (queryMetrics) BeforeQuery(q *pg.QueryEvent) {
+	q.Data[queryStartKey{}] = time.Now()
+	if q.Ctx == nil {
+		return
+	}
+	_, span := telemetry.StartSpan(q.Ctx, "db.query")
+	q.Data[querySpanKey{}] = span
+}
+
+func (queryMetrics) AfterQuery(q *pg.QueryEvent) {
+	operation, table := queryLabels(q.Query)
+	if start, ok := q.Data[queryStartKey{}].(time.Time); ok {
+		telemetry.DBQueryDuration.
+			WithLabelValues(operation, table, telemetry.Status(q.Error)).
+			Observe(time.Since(start).Seconds())
+	}
+	if span, ok := q.Data[querySpanKey{}].(trace.Span); ok {
+		span.SetAttributes(
+			attribute.String("db.operation", operation),
+			attribute.String("db.sql.table", table),
+		)
+		telemetry.EndSpan(span, q.Error)
+	}
+}
+
+// queryLabels returns the operation and the table of a query. Queries built with the orm
+// are labelled with their model table, raw queries with their first keyword.
+func queryLabels(query interface{}) (operation, table string) {
+	switch q := query.(type) {
+	case interface{ Query() *orm.Query }:
+		// the orm query types are named after their operation, like *orm.selectQuery
+		operation = strings.TrimSuffix(strings.TrimPrefix(fmt.Sprintf("%T", q), "*orm."), "Query")
+		model := q.Query().GetModel()
+		if model != nil && model.Table() != nil {
+			return operation, model.Table().Name
+		}
+		return operation, "raw"
+	case string:
+		fields := strings.Fields(q)
+		if len(fields) > 0 {
+			return strings.ToLower(fields[0]), "raw"
+		}
+	}
+	return "other", "raw"
+}
+
+// RegisterPoolMetrics exposes the usage of the connection pool of the client
+func (c *Client) RegisterPoolMetrics() {
+	stats := func(value func(*pg.PoolStats) uint32) func() float64 {
+		return func() float64 {
+			return float64(value(c.PoolStats()))
+		}
+	}
+	telemetry.Register(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "db_pool_connections",
+			Help: "Connections in the database pool.",
+		}, stats(func(s *pg.PoolStats) uint32 { return s.TotalConns })),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "db_pool_idle_connections",
+			Help: "Idle connections in the database pool.",
+		}, stats(func(s *pg.PoolStats) uint32 { return s.IdleConns })),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "db_pool_hits_total",
+			Help: "Times a free connection was found in the database pool.",
+		}, stats(func(s *pg.PoolStats) uint32 { return s.Hits })),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "db_pool_misses_total",
+			Help: "Times a free connection wasn't found in the database pool.",
+		}, stats(func(s *pg.PoolStats) uint32 { return s.Misses })),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "db_pool_timeouts_total",
+			Help: "Times waiting for a connection of the database pool timed out.",
+		}, stats(func(s *pg.PoolStats) uint32 { return s.Timeouts })),
+	)
+}
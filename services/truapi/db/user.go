@@ -67,6 +67,7 @@ type User struct {
 	UserGroup                 UserGroup  `json:"user_group"`
 	LastVerificationAttemptAt time.Time  `json:"last_verification_attempt_at" graphql:"-"`
 	VerificationAttemptCount  int        `json:"verification_attempt_count"`
+	Locale                    string     `json:"locale" sql:",notnull,default:'en'"`
 	Meta                      UserMeta   `json:"meta"`
 }
 
@@ -250,6 +251,20 @@ func (c *Client) TouchLastAuthenticatedAt(id int64) error {
 	return nil
 }
 
+// SetUserLocale updates the locale used to render notifications and emails
+func (c *Client) SetUserLocale(id int64, locale string) error {
+	var user User
+	_, err := c.Model(&user).
+		Where("id = ?", id).
+		Where("deleted_at IS NULL").
+		Set("locale = ?", locale).
+		Update()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 // SetUserMeta updates the meta column
 func (c *Client) SetUserMeta(id int64, meta *UserMeta) error {
 	var user User
@@ -0,0 +1,60 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Codes of the errors returned to clients in the `extensions` of GraphQL errors
+const (
+	// CodeBadRequest is returned for invalid arguments
+	CodeBadRequest = "BAD_REQUEST"
+	// CodeUnauthenticated is returned when a query requires a user
+	CodeUnauthenticated = "UNAUTHENTICATED"
+	// CodeForbidden is returned when the user isn't allowed to query
+	CodeForbidden = "FORBIDDEN"
+	// CodeNotFound is returned when the entity queried doesn't exist
+	CodeNotFound = "NOT_FOUND"
+	// CodeUnavailable is returned when the chain or another service couldn't be queried
+	CodeUnavailable = "UNAVAILABLE"
+	// CodeInternal is returned for any other error
+	CodeInternal = "INTERNAL"
+)
+
+// Error is an error returned by a resolver with the code clients can handle it by
+type Error struct {
+	Code string
+	Err  error
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error the resolver failed with
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NewError wraps the error a resolver failed with in a coded error
+func NewError(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Err: err}
+}
+
+// Errorf returns a coded error formatted like fmt.Errorf
+func Errorf(code, format string, a ...interface{}) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, a...)}
+}
+
+// ErrorCode returns the code of an error, CodeInternal when it has none
+func ErrorCode(err error) string {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return coded.Code
+	}
+	return CodeInternal
+}
@@ -35,21 +35,22 @@ func NewGraphQLClient() *Client {
 	return &client
 }
 
+// Handler serves GraphQL queries, errors are returned with their code (see errors.go)
 func (c *Client) Handler() http.Handler {
 	if !c.Built {
 		c.BuildSchema()
 	}
-	return thunder.HTTPHandler(c.Schema)
+	return newHandler(c.Schema)
 }
 
 // RegisterQueryResolver adds a top-level resolver to find the first batch of entities in a GraphQL query
 func (c *Client) RegisterQueryResolver(name string, fn interface{}) {
-	c.queries.FieldFunc(name, fn, builder.Expensive)
+	c.queries.FieldFunc(name, timed("Query", name, fn), builder.Expensive)
 }
 
 // RegisterPaginatedQueryResolver adds a top-level resolver to find the first paginated batch of entities in a GraphQL query
 func (c *Client) RegisterPaginatedQueryResolver(name string, fn interface{}) {
-	c.queries.FieldFunc(name, fn, builder.Paginated, builder.Expensive)
+	c.queries.FieldFunc(name, timed("Query", name, fn), builder.Paginated, builder.Expensive)
 }
 
 // RegisterPaginatedQueryResolverWithFilter adds a top-level resolver to find the first paginated batch of entities in a GraphQL query filtered by content
@@ -59,19 +60,19 @@ func (c *Client) RegisterPaginatedQueryResolverWithFilter(name string, fn interf
 	for k, i := range filter {
 		options = append(options, builder.FilterField(k, i))
 	}
-	c.queries.FieldFunc(name, fn, options...)
+	c.queries.FieldFunc(name, timed("Query", name, fn), options...)
 }
 
 // RegisterMutation registers a mutation
 func (c *Client) RegisterMutation(name string, fn interface{}) {
-	c.mutations.FieldFunc(name, fn, builder.Expensive)
+	c.mutations.FieldFunc(name, timed("Mutation", name, fn), builder.Expensive)
 }
 
 // RegisterObjectResolver adds a set of field resolvers for objects of the given type that are returned by top-level resolvers
 func (c *Client) RegisterObjectResolver(name string, objPrototype interface{}, fields map[string]interface{}) {
 	obj := c.pendingSchema.Object(name, objPrototype)
 	for fieldName, fn := range fields {
-		obj.FieldFunc(fieldName, fn, builder.Expensive)
+		obj.FieldFunc(fieldName, timed(name, fieldName, fn), builder.Expensive)
 	}
 }
 
@@ -81,7 +82,7 @@ func (c *Client) RegisterPaginatedObjectResolver(name, key string, objPrototype
 	obj.Key(key)
 
 	for fieldName, fn := range fields {
-		obj.FieldFunc(fieldName, fn, builder.Expensive)
+		obj.FieldFunc(fieldName, timed(name, fieldName, fn), builder.Expensive)
 	}
 }
 
@@ -0,0 +1,123 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/samsarahq/thunder/batch"
+	thunder "github.com/samsarahq/thunder/graphql"
+	"github.com/samsarahq/thunder/reactive"
+
+	"github.com/TruStory/octopus/services/truapi/logging"
+)
+
+// ResponseError is a GraphQL error as returned to clients
+type ResponseError struct {
+	Message    string                 `json:"message"`
+	Extensions map[string]interface{} `json:"extensions"`
+}
+
+type response struct {
+	Data   interface{}     `json:"data"`
+	Errors []ResponseError `json:"errors,omitempty"`
+}
+
+// handler serves queries like the thunder HTTP handler, with errors rendered as objects
+// carrying their code and the ID of the request, and logged with the request logger
+type handler struct {
+	schema   *thunder.Schema
+	executor thunder.ExecutorRunner
+}
+
+func newHandler(schema *thunder.Schema) http.Handler {
+	return &handler{
+		schema:   schema,
+		executor: thunder.NewExecutor(thunder.NewImmediateGoroutineScheduler()),
+	}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeResponse := func(value interface{}, err error) {
+		res := response{Data: value}
+		if err != nil {
+			res.Errors = []ResponseError{h.responseError(r, err)}
+		}
+		b, err := json.Marshal(res)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		_, _ = w.Write(b)
+	}
+
+	if r.Method != http.MethodPost {
+		writeResponse(nil, Errorf(CodeBadRequest, "request must be a POST"))
+		return
+	}
+	if r.Body == nil {
+		writeResponse(nil, Errorf(CodeBadRequest, "request must include a query"))
+		return
+	}
+
+	var params Request
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeResponse(nil, NewError(CodeBadRequest, err))
+		return
+	}
+	query, err := thunder.Parse(params.Query, params.Variables)
+	if err != nil {
+		writeResponse(nil, NewError(CodeBadRequest, err))
+		return
+	}
+	schema := h.schema.Query
+	if query.Kind == "mutation" {
+		schema = h.schema.Mutation
+	}
+	if err := thunder.PrepareQuery(r.Context(), schema, query.SelectionSet); err != nil {
+		writeResponse(nil, NewError(CodeBadRequest, err))
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	runner := reactive.NewRerunner(r.Context(), func(ctx context.Context) (interface{}, error) {
+		defer wg.Done()
+		ctx = batch.WithBatching(ctx)
+		current, err := h.executor.Execute(ctx, schema, nil, query)
+		if err != nil {
+			if thunder.ErrorCause(err) == context.Canceled {
+				return nil, err
+			}
+			writeResponse(nil, err)
+			return nil, err
+		}
+		writeResponse(current, nil)
+		return nil, nil
+	}, thunder.DefaultMinRerunInterval, false)
+
+	wg.Wait()
+	runner.Stop()
+}
+
+// responseError logs the error a query failed with and renders it for the client
+func (h *handler) responseError(r *http.Request, err error) ResponseError {
+	code := ErrorCode(err)
+	logger := logging.FromContext(r.Context()).WithError(err).WithField("code", code)
+	switch code {
+	case CodeInternal, CodeUnavailable:
+		logger.Error("graphql query failed")
+	default:
+		logger.Info("graphql query failed")
+	}
+
+	extensions := map[string]interface{}{"code": code}
+	if id := logging.RequestID(r.Context()); id != "" {
+		extensions["requestId"] = id
+	}
+	return ResponseError{Message: err.Error(), Extensions: extensions}
+}
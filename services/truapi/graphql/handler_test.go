@@ -0,0 +1,65 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TruStory/octopus/services/truapi/logging"
+)
+
+func TestErrorCode(t *testing.T) {
+	err := NewError(CodeNotFound, errors.New("claim not found"))
+	assert.Equal(t, CodeNotFound, ErrorCode(err))
+	assert.Equal(t, CodeNotFound, ErrorCode(fmt.Errorf("claim: %w", err)))
+	assert.Equal(t, "claim not found", err.Error())
+	assert.Equal(t, CodeInternal, ErrorCode(errors.New("unknown")))
+	assert.Nil(t, NewError(CodeNotFound, nil))
+}
+
+func TestHandlerRendersErrorCodes(t *testing.T) {
+	client := NewGraphQLClient()
+	client.RegisterQueryResolver("claim", func(ctx context.Context, args struct {
+		ID int64 `graphql:"id"`
+	}) (int64, error) {
+		if args.ID == 0 {
+			return 0, Errorf(CodeNotFound, "claim not found")
+		}
+		return args.ID, nil
+	})
+	handler := logging.Middleware(logging.New("test"))(client.Handler())
+
+	query := func(q string) (*httptest.ResponseRecorder, response) {
+		body := strings.NewReader(fmt.Sprintf(`{"query": %q}`, q))
+		req := httptest.NewRequest(http.MethodPost, "/graphql", body)
+		req.Header.Set(logging.RequestIDHeader, "request-1")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		var r response
+		assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &r))
+		return res, r
+	}
+
+	_, r := query("{ claim(id: 1) }")
+	assert.Empty(t, r.Errors)
+	assert.Equal(t, map[string]interface{}{"claim": float64(1)}, r.Data)
+
+	res, r := query("{ claim(id: 0) }")
+	assert.Nil(t, r.Data)
+	assert.Len(t, r.Errors, 1)
+	assert.Contains(t, r.Errors[0].Message, "claim not found")
+	assert.Equal(t, CodeNotFound, r.Errors[0].Extensions["code"])
+	assert.Equal(t, "request-1", r.Errors[0].Extensions["requestId"])
+	assert.Equal(t, "request-1", res.Header().Get(logging.RequestIDHeader))
+
+	_, r = query("{ claim(")
+	assert.Len(t, r.Errors, 1)
+	assert.Equal(t, CodeBadRequest, r.Errors[0].Extensions["code"])
+}
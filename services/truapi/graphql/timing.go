@@ -0,0 +1,61 @@
+package graphql
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/telemetry"
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// timed wraps a resolver to record its duration. The wrapper has the type of the resolver
+// so the schema builder sees the same arguments and results. Resolvers taking a context
+// get a span of their own in the trace of the request.
+func timed(object, field string, fn interface{}) interface{} {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func {
+		return fn
+	}
+	observer := telemetry.ResolverDuration.WithLabelValues(object, field)
+	spanName := "graphql." + object + "." + field
+	wrapped := reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		start := time.Now()
+		ctxIndex := -1
+		for i := 0; i < t.NumIn(); i++ {
+			if t.In(i) == contextType {
+				ctxIndex = i
+				break
+			}
+		}
+		if ctxIndex < 0 || args[ctxIndex].IsNil() {
+			results := v.Call(args)
+			observer.Observe(time.Since(start).Seconds())
+			return results
+		}
+		ctx, span := telemetry.StartSpan(args[ctxIndex].Interface().(context.Context), spanName)
+		args[ctxIndex] = reflect.ValueOf(&ctx).Elem()
+		results := v.Call(args)
+		observer.Observe(time.Since(start).Seconds())
+		telemetry.EndSpan(span, resultError(results))
+		return results
+	})
+	return wrapped.Interface()
+}
+
+// resultError returns the error a resolver returned last, if any
+func resultError(results []reflect.Value) error {
+	if len(results) == 0 {
+		return nil
+	}
+	last := results[len(results)-1]
+	if last.Type() != errorType || last.IsNil() {
+		return nil
+	}
+	return last.Interface().(error)
+}
@@ -0,0 +1,36 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TruStory/octopus/services/truapi/telemetry"
+)
+
+func TestTimedKeepsResolverType(t *testing.T) {
+	resolver := func(ctx context.Context, args struct{ ID int64 }) (int64, error) {
+		if args.ID == 0 {
+			return 0, errors.New("not found")
+		}
+		return args.ID, nil
+	}
+	wrapped, ok := timed("Claim", "id", resolver).(func(context.Context, struct{ ID int64 }) (int64, error))
+	assert.True(t, ok)
+
+	id, err := wrapped(context.Background(), struct{ ID int64 }{ID: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), id)
+	_, err = wrapped(context.Background(), struct{ ID int64 }{})
+	assert.Error(t, err)
+
+	metric := &dto.Metric{}
+	observer, err := telemetry.ResolverDuration.GetMetricWithLabelValues("Claim", "id")
+	assert.NoError(t, err)
+	assert.NoError(t, observer.(prometheus.Histogram).Write(metric))
+	assert.Equal(t, uint64(2), metric.GetHistogram().GetSampleCount())
+}
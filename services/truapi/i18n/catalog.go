@@ -0,0 +1,110 @@
+package i18n
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	packr "github.com/gobuffalo/packr/v2"
+)
+
+// DefaultLocale is the locale used when a message is missing for the requested locale.
+const DefaultLocale = "en"
+
+// Message is a catalog key along with the parameters used to render it.
+type Message struct {
+	Key    string
+	Params Params
+}
+
+// Catalog holds the translated messages for every supported locale.
+type Catalog struct {
+	mu       sync.RWMutex
+	messages map[string]map[string]string
+}
+
+// NewCatalog creates an empty catalog.
+func NewCatalog() *Catalog {
+	return &Catalog{
+		messages: make(map[string]map[string]string),
+	}
+}
+
+// NewDefaultCatalog creates a catalog with the bundled locales.
+func NewDefaultCatalog() (*Catalog, error) {
+	box := packr.New("Locales", "./locales")
+	catalog := NewCatalog()
+	for _, filename := range box.List() {
+		if !strings.HasSuffix(filename, ".json") {
+			continue
+		}
+		b, err := box.Find(filename)
+		if err != nil {
+			return nil, err
+		}
+		messages := make(map[string]string)
+		err = json.Unmarshal(b, &messages)
+		if err != nil {
+			return nil, err
+		}
+		catalog.Add(strings.TrimSuffix(filename, ".json"), messages)
+	}
+	return catalog, nil
+}
+
+// Add registers messages for a locale, overriding existing keys.
+func (c *Catalog) Add(locale string, messages map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	locale = normalize(locale)
+	if _, ok := c.messages[locale]; !ok {
+		c.messages[locale] = make(map[string]string)
+	}
+	for key, msg := range messages {
+		c.messages[locale][key] = msg
+	}
+}
+
+// HasLocale returns whether the catalog has messages for the locale or its base language.
+func (c *Catalog) HasLocale(locale string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if _, ok := c.messages[normalize(locale)]; ok {
+		return true
+	}
+	_, ok := c.messages[language(locale)]
+	return ok
+}
+
+// Render renders a message in the given locale. It falls back to the base language,
+// then to the default locale and finally to the key itself.
+func (c *Catalog) Render(locale string, msg Message) string {
+	pattern, ok := c.lookup(locale, msg.Key)
+	if !ok {
+		return msg.Key
+	}
+	return format(pattern, msg.Params, pluralRuleFor(locale))
+}
+
+// T renders a key in the given locale.
+func (c *Catalog) T(locale, key string, params Params) string {
+	return c.Render(locale, Message{Key: key, Params: params})
+}
+
+func (c *Catalog) lookup(locale, key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, l := range []string{normalize(locale), language(locale), DefaultLocale} {
+		if pattern, ok := c.messages[l][key]; ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+func normalize(locale string) string {
+	if locale == "" {
+		return DefaultLocale
+	}
+	return strings.ToLower(strings.Replace(locale, "_", "-", -1))
+}
@@ -0,0 +1,149 @@
+package i18n
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Params are the named arguments interpolated into a message.
+type Params map[string]interface{}
+
+// format renders an ICU-style message pattern. Supported arguments are
+// simple placeholders `{name}` and plurals
+// `{count, plural, =0 {none} one {# item} other {# items}}` where `#` is
+// replaced by the number inside a plural branch.
+func format(pattern string, params Params, plural pluralRule) string {
+	var b strings.Builder
+	formatInto(&b, pattern, params, plural, "")
+	return b.String()
+}
+
+func formatInto(b *strings.Builder, pattern string, params Params, plural pluralRule, number string) {
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '#' && number != "":
+			b.WriteString(number)
+		case c == '{':
+			end := matchingBrace(pattern, i)
+			if end < 0 {
+				b.WriteString(pattern[i:])
+				return
+			}
+			formatArgument(b, pattern[i+1:end], params, plural)
+			i = end
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func formatArgument(b *strings.Builder, arg string, params Params, plural pluralRule) {
+	parts := strings.SplitN(arg, ",", 3)
+	name := strings.TrimSpace(parts[0])
+	value, ok := params[name]
+	if len(parts) < 3 || strings.TrimSpace(parts[1]) != "plural" {
+		if !ok {
+			b.WriteString("{" + arg + "}")
+			return
+		}
+		b.WriteString(fmt.Sprint(value))
+		return
+	}
+	n, err := strconv.ParseInt(fmt.Sprint(value), 10, 64)
+	if err != nil {
+		b.WriteString("{" + arg + "}")
+		return
+	}
+	branches := pluralBranches(parts[2])
+	branch, ok := branches[fmt.Sprintf("=%d", n)]
+	if !ok {
+		branch, ok = branches[plural(n)]
+	}
+	if !ok {
+		branch = branches["other"]
+	}
+	formatInto(b, branch, params, plural, strconv.FormatInt(n, 10))
+}
+
+// pluralBranches parses `one {...} other {...}` into a selector to sub-pattern map.
+func pluralBranches(s string) map[string]string {
+	branches := make(map[string]string)
+	for {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			return branches
+		}
+		end := matchingBrace(s, start)
+		if end < 0 {
+			return branches
+		}
+		selector := strings.TrimSpace(s[:start])
+		branches[selector] = s[start+1 : end]
+		s = s[end+1:]
+	}
+}
+
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// pluralRule maps a number to its CLDR plural category.
+type pluralRule func(n int64) string
+
+func oneOther(n int64) string {
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+func zeroOneOther(n int64) string {
+	if n == 0 || n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+func otherOnly(n int64) string {
+	return "other"
+}
+
+var pluralRules = map[string]pluralRule{
+	"en": oneOther,
+	"es": oneOther,
+	"de": oneOther,
+	"it": oneOther,
+	"pt": zeroOneOther,
+	"fr": zeroOneOther,
+	"hi": zeroOneOther,
+	"ja": otherOnly,
+	"ko": otherOnly,
+	"zh": otherOnly,
+}
+
+func pluralRuleFor(locale string) pluralRule {
+	if rule, ok := pluralRules[language(locale)]; ok {
+		return rule
+	}
+	return oneOther
+}
+
+// language returns the base language of a locale, ie: "en" for "en-US".
+func language(locale string) string {
+	locale = strings.ToLower(strings.Replace(locale, "_", "-", -1))
+	return strings.SplitN(locale, "-", 2)[0]
+}
@@ -0,0 +1,80 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatParams(t *testing.T) {
+	msg := format("agreed with your argument: {summary}", Params{"summary": "Hello"}, oneOther)
+	assert.Equal(t, "agreed with your argument: Hello", msg)
+
+	// missing params are kept as is
+	msg = format("Hi {name}!", Params{}, oneOther)
+	assert.Equal(t, "Hi {name}!", msg)
+}
+
+func TestFormatPlural(t *testing.T) {
+	pattern := "{others, plural, =0 {nobody} one {and # other} other {and # others}} agreed"
+	assert.Equal(t, "nobody agreed", format(pattern, Params{"others": 0}, oneOther))
+	assert.Equal(t, "and 1 other agreed", format(pattern, Params{"others": 1}, oneOther))
+	assert.Equal(t, "and 12 others agreed", format(pattern, Params{"others": "12"}, oneOther))
+}
+
+func TestFormatNestedParams(t *testing.T) {
+	pattern := "{count, plural, one {# invite from {name}} other {# invites from {name}}}"
+	assert.Equal(t, "2 invites from Alice", format(pattern, Params{"count": 2, "name": "Alice"}, oneOther))
+}
+
+func TestCatalogFallback(t *testing.T) {
+	catalog := NewCatalog()
+	catalog.Add("en", map[string]string{"greeting": "Hello", "farewell": "Bye"})
+	catalog.Add("es", map[string]string{"greeting": "Hola"})
+
+	assert.Equal(t, "Hola", catalog.T("es-MX", "greeting", nil))
+	assert.Equal(t, "Bye", catalog.T("es", "farewell", nil))
+	assert.Equal(t, "missing", catalog.T("es", "missing", nil))
+	assert.True(t, catalog.HasLocale("es_MX"))
+	assert.False(t, catalog.HasLocale("fr"))
+}
+
+func TestDefaultCatalogLocales(t *testing.T) {
+	catalog, err := NewDefaultCatalog()
+	assert.NoError(t, err)
+	assert.True(t, catalog.HasLocale("en"))
+	assert.True(t, catalog.HasLocale("fr-CA"))
+	assert.False(t, catalog.HasLocale("xx"))
+
+	// every bundled locale translates every key of the default locale
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+	for locale, messages := range catalog.messages {
+		for key := range catalog.messages[DefaultLocale] {
+			_, ok := messages[key]
+			assert.True(t, ok, "%s is missing %s", locale, key)
+		}
+	}
+}
+
+func TestCatalogPluralRules(t *testing.T) {
+	catalog, err := NewDefaultCatalog()
+	assert.NoError(t, err)
+	invites := func(locale string, count int) string {
+		return catalog.Render(locale, Message{
+			Key:    "notification.reward.invite.self.body",
+			Params: Params{"count": count},
+		})
+	}
+
+	// English uses "one" only for 1, French for 0 and 1
+	assert.Contains(t, invites("en", 0), "0 invites")
+	assert.Contains(t, invites("en", 1), "1 invite ")
+	assert.Contains(t, invites("en", 2), "2 invites")
+	assert.Contains(t, invites("fr", 0), "0 invitation ")
+	assert.Contains(t, invites("fr", 1), "1 invitation ")
+	assert.Contains(t, invites("fr", 2), "2 invitations")
+
+	aggregated := Message{Key: "notification.aggregated", Params: Params{"others": 3, "message": "est d’accord"}}
+	assert.Equal(t, "et 3 autres personnes est d’accord", catalog.Render("fr-FR", aggregated))
+}
@@ -0,0 +1,116 @@
+// Package logging scopes structured loggers to requests so every entry logged
+// while serving a request carries its ID, across the services it goes through.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestIDHeader carries the ID of a request to the services it's forwarded to and back to the client
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the IDs accepted from clients and upstream services
+const maxRequestIDLength = 128
+
+type contextKey int
+
+const (
+	loggerContextKey contextKey = iota
+	requestIDContextKey
+)
+
+// New returns the base logger of a service
+func New(service string) logrus.FieldLogger {
+	return logrus.StandardLogger().WithField("service", service)
+}
+
+// FromContext returns the logger of the request a context belongs to,
+// the standard logger outside of requests
+func FromContext(ctx context.Context) logrus.FieldLogger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerContextKey).(logrus.FieldLogger); ok {
+			return logger
+		}
+	}
+	return logrus.StandardLogger()
+}
+
+// WithLogger returns a copy of a context carrying a logger
+func WithLogger(ctx context.Context, logger logrus.FieldLogger) context.Context {
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// RequestID returns the ID of the request a context belongs to, empty outside of requests
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// WithRequestID returns a copy of a context carrying a request ID and a logger tagged with it
+func WithRequestID(ctx context.Context, logger logrus.FieldLogger, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDContextKey, id)
+	return WithLogger(ctx, logger.WithField("request_id", id))
+}
+
+// Detach returns a context carrying the request ID and logger of ctx without its deadline
+// and cancellation, for work outliving the request
+func Detach(ctx context.Context) context.Context {
+	detached := WithLogger(context.Background(), FromContext(ctx))
+	if id := RequestID(ctx); id != "" {
+		detached = context.WithValue(detached, requestIDContextKey, id)
+	}
+	return detached
+}
+
+// NewRequestID generates a random request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Middleware keeps the ID of requests forwarded by another service, or assigns a new one,
+// echoes it in the response and scopes a logger tagged with it to the request
+func Middleware(logger logrus.FieldLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = NewRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(w, r.WithContext(WithRequestID(r.Context(), logger, id)))
+		})
+	}
+}
+
+// Forward sets the ID of the request a proxied request is made for
+func Forward(req *http.Request) {
+	if id := RequestID(req.Context()); id != "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+}
+
+// validRequestID accepts printable ASCII IDs short enough to be logged as is
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
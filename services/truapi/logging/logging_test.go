@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareKeepsValidRequestID(t *testing.T) {
+	var id string
+	handler := Middleware(New("test"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = RequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	assert.Equal(t, "abc-123", id)
+	assert.Equal(t, "abc-123", res.Header().Get(RequestIDHeader))
+
+	for _, invalid := range []string{"", "has space", strings.Repeat("a", maxRequestIDLength+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(RequestIDHeader, invalid)
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		assert.NotEqual(t, invalid, id)
+		assert.Len(t, id, 32)
+		assert.Equal(t, id, res.Header().Get(RequestIDHeader))
+	}
+}
+
+func TestForward(t *testing.T) {
+	handler := Middleware(New("test"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied, err := http.NewRequestWithContext(r.Context(), http.MethodGet, "http://localhost", nil)
+		assert.NoError(t, err)
+		Forward(proxied)
+		assert.Equal(t, RequestID(r.Context()), proxied.Header.Get(RequestIDHeader))
+		assert.NotEmpty(t, proxied.Header.Get(RequestIDHeader))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	outside := httptest.NewRequest(http.MethodGet, "/", nil)
+	Forward(outside)
+	assert.Empty(t, outside.Header.Get(RequestIDHeader))
+}
@@ -0,0 +1,218 @@
+// Package lookup reads claims, arguments and participants directly from the chain
+// and the database. Results are cached for a short period so services reacting to
+// chain events don't query the node for every notification.
+package lookup
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/telemetry"
+	"github.com/TruStory/truchain/x/claim"
+	"github.com/TruStory/truchain/x/staking"
+	"github.com/cosmos/cosmos-sdk/codec"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+)
+
+// DefaultCacheTTL is how long results are cached by default.
+const DefaultCacheTTL = time.Minute
+
+// Querier runs custom queries against a node.
+// It is implemented by the cosmos-sdk CLIContext.
+type Querier interface {
+	QueryWithData(path string, data []byte) ([]byte, int64, error)
+}
+
+// CommentStore retrieves the comments of a claim.
+type CommentStore interface {
+	CommentsByClaimID(claimID uint64) ([]db.Comment, error)
+}
+
+type rpcQuerier struct {
+	client rpcclient.Client
+}
+
+// NewRPCQuerier creates a Querier using a Tendermint RPC client.
+func NewRPCQuerier(client rpcclient.Client) Querier {
+	return rpcQuerier{client: client}
+}
+
+func (q rpcQuerier) QueryWithData(path string, data []byte) ([]byte, int64, error) {
+	res, err := q.client.ABCIQuery(path, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !res.Response.IsOK() {
+		return nil, res.Response.Height, errors.New(res.Response.Log)
+	}
+	return res.Response.Value, res.Response.Height, nil
+}
+
+// Client looks up chain and database data.
+type Client struct {
+	querier  Querier
+	comments CommentStore
+	cache    *cache
+}
+
+// NewClient creates a lookup client caching results for ttl.
+func NewClient(querier Querier, comments CommentStore, ttl time.Duration) *Client {
+	return &Client{
+		querier:  querier,
+		comments: comments,
+		cache:    newCache(ttl),
+	}
+}
+
+// Claim returns a claim by its id.
+func (c *Client) Claim(id uint64) (*claim.Claim, error) {
+	key := fmt.Sprintf("claim/%d", id)
+	if v, ok := c.cache.get(key); ok {
+		clm := v.(claim.Claim)
+		return &clm, nil
+	}
+	clm := claim.Claim{}
+	err := c.query(path.Join(claim.QuerierRoute, claim.QueryClaim), claim.QueryClaimParams{ID: id}, claim.ModuleCodec, &clm)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.set(key, clm)
+	return &clm, nil
+}
+
+// Argument returns an argument by its id.
+func (c *Client) Argument(id uint64) (*staking.Argument, error) {
+	key := fmt.Sprintf("argument/%d", id)
+	if v, ok := c.cache.get(key); ok {
+		argument := v.(staking.Argument)
+		return &argument, nil
+	}
+	argument := staking.Argument{}
+	err := c.query(path.Join(staking.ModuleName, staking.QueryClaimArgument),
+		staking.QueryClaimArgumentParams{ArgumentID: id}, staking.ModuleCodec, &argument)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.set(key, argument)
+	return &argument, nil
+}
+
+// ClaimArguments returns the arguments written for a claim.
+func (c *Client) ClaimArguments(claimID uint64) ([]staking.Argument, error) {
+	key := fmt.Sprintf("claim/%d/arguments", claimID)
+	if v, ok := c.cache.get(key); ok {
+		return copyArguments(v.([]staking.Argument)), nil
+	}
+	arguments := make([]staking.Argument, 0)
+	err := c.query(path.Join(staking.ModuleName, staking.QueryClaimArguments),
+		staking.QueryClaimArgumentsParams{ClaimID: claimID}, staking.ModuleCodec, &arguments)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.set(key, arguments)
+	return copyArguments(arguments), nil
+}
+
+// ArgumentStakes returns the stakes of an argument, including the one of its writer.
+func (c *Client) ArgumentStakes(argumentID uint64) ([]staking.Stake, error) {
+	key := fmt.Sprintf("argument/%d/stakes", argumentID)
+	if v, ok := c.cache.get(key); ok {
+		return copyStakes(v.([]staking.Stake)), nil
+	}
+	stakes := make([]staking.Stake, 0)
+	err := c.query(path.Join(staking.ModuleName, staking.QueryArgumentStakes),
+		staking.QueryArgumentStakesParams{ArgumentID: argumentID}, staking.ModuleCodec, &stakes)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.set(key, stakes)
+	return copyStakes(stakes), nil
+}
+
+// ClaimParticipants returns the sorted addresses of everyone who wrote, agreed
+// or replied on a claim.
+func (c *Client) ClaimParticipants(claimID uint64) ([]string, error) {
+	key := fmt.Sprintf("claim/%d/participants", claimID)
+	if v, ok := c.cache.get(key); ok {
+		return copyStrings(v.([]string)), nil
+	}
+	arguments, err := c.ClaimArguments(claimID)
+	if err != nil {
+		return nil, err
+	}
+	participantsMap := make(map[string]bool)
+	for _, argument := range arguments {
+		stakes, err := c.ArgumentStakes(argument.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, stake := range stakes {
+			participantsMap[stake.Creator.String()] = true
+		}
+	}
+	comments, err := c.comments.CommentsByClaimID(claimID)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		participantsMap[comment.Creator] = true
+	}
+	participants := make([]string, 0, len(participantsMap))
+	for address := range participantsMap {
+		participants = append(participants, address)
+	}
+	sort.Strings(participants)
+	c.cache.set(key, participants)
+	return copyStrings(participants), nil
+}
+
+// InvalidateClaim drops the cached data of a claim so the next lookup hits the chain.
+func (c *Client) InvalidateClaim(claimID uint64) {
+	c.cache.delete(fmt.Sprintf("claim/%d", claimID))
+	c.cache.delete(fmt.Sprintf("claim/%d/arguments", claimID))
+	c.cache.delete(fmt.Sprintf("claim/%d/participants", claimID))
+}
+
+// InvalidateArgument drops the cached data of an argument.
+func (c *Client) InvalidateArgument(argumentID uint64) {
+	c.cache.delete(fmt.Sprintf("argument/%d", argumentID))
+	c.cache.delete(fmt.Sprintf("argument/%d/stakes", argumentID))
+}
+
+func (c *Client) query(route string, params interface{}, cdc *codec.Codec, v interface{}) error {
+	paramBytes, err := cdc.MarshalJSON(params)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	res, _, err := c.querier.QueryWithData("/custom/"+route, paramBytes)
+	telemetry.ChainQueryDuration.WithLabelValues(route, telemetry.Status(err)).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return err
+	}
+	return cdc.UnmarshalJSON(res, v)
+}
+
+// Cached slices are copied before being returned so callers can't modify the cache.
+
+func copyArguments(arguments []staking.Argument) []staking.Argument {
+	c := make([]staking.Argument, len(arguments))
+	copy(c, arguments)
+	return c
+}
+
+func copyStakes(stakes []staking.Stake) []staking.Stake {
+	c := make([]staking.Stake, len(stakes))
+	copy(c, stakes)
+	return c
+}
+
+func copyStrings(s []string) []string {
+	c := make([]string, len(s))
+	copy(c, s)
+	return c
+}
@@ -0,0 +1,82 @@
+package lookup
+
+import (
+	"path"
+	"testing"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/truchain/x/staking"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeQuerier struct {
+	responses map[string]interface{}
+	calls     map[string]int
+}
+
+func (q *fakeQuerier) QueryWithData(path string, data []byte) ([]byte, int64, error) {
+	q.calls[path]++
+	b, err := staking.ModuleCodec.MarshalJSON(q.responses[path])
+	return b, 1, err
+}
+
+type fakeComments []db.Comment
+
+func (f fakeComments) CommentsByClaimID(claimID uint64) ([]db.Comment, error) {
+	return f, nil
+}
+
+func TestClaimParticipants(t *testing.T) {
+	argumentsRoute := "/custom/" + path.Join(staking.ModuleName, staking.QueryClaimArguments)
+	stakesRoute := "/custom/" + path.Join(staking.ModuleName, staking.QueryArgumentStakes)
+	alice := sdk.AccAddress([]byte("alice_______________"))
+	bob := sdk.AccAddress([]byte("bob_________________"))
+	querier := &fakeQuerier{
+		responses: map[string]interface{}{
+			argumentsRoute: []staking.Argument{{ID: 1, ClaimID: 7, Creator: alice}},
+			stakesRoute:    []staking.Stake{{ID: 1, ArgumentID: 1, Creator: alice}, {ID: 2, ArgumentID: 1, Creator: bob}},
+		},
+		calls: make(map[string]int),
+	}
+	comments := fakeComments{{Creator: "carol"}, {Creator: bob.String()}}
+	client := NewClient(querier, comments, time.Minute)
+
+	participants, err := client.ClaimParticipants(7)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{alice.String(), bob.String(), "carol"}, participants)
+
+	// cached
+	_, err = client.ClaimParticipants(7)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, querier.calls[argumentsRoute])
+
+	// modifying a result doesn't modify the cache
+	participants[0] = "mallory"
+	arguments, err := client.ClaimArguments(7)
+	assert.NoError(t, err)
+	arguments[0].ID = 99
+	cached, err := client.ClaimParticipants(7)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{alice.String(), bob.String(), "carol"}, cached)
+	arguments, err = client.ClaimArguments(7)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), arguments[0].ID)
+
+	client.InvalidateClaim(7)
+	_, err = client.ClaimParticipants(7)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, querier.calls[argumentsRoute])
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c := newCache(10 * time.Millisecond)
+	c.set("key", 1)
+	v, ok := c.get("key")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	time.Sleep(20 * time.Millisecond)
+	_, ok = c.get("key")
+	assert.False(t, ok)
+}
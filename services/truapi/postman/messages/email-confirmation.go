@@ -1,7 +1,6 @@
 package messages
 
 import (
-	"bytes"
 	"fmt"
 
 	"github.com/russross/blackfriday/v2"
@@ -21,15 +20,15 @@ func MakeEmailConfirmationMessage(client *postman.Postman, config context.Config
 		VerificationLink: makeVerificationLink(config, user),
 	}
 
-	var body bytes.Buffer
-	if err := client.Messages["email-confirmation"].Execute(&body, vars); err != nil {
+	body, err := client.Render("email-confirmation", user.Locale, vars)
+	if err != nil {
 		return nil, err
 	}
 
 	return &postman.Message{
 		To:      []string{user.Email},
-		Subject: "Confirm your email address",
-		Body:    string(blackfriday.Run(body.Bytes())),
+		Subject: client.T(user.Locale, "email.email_confirmation.subject", nil),
+		Body:    string(blackfriday.Run(body)),
 	}, nil
 }
 
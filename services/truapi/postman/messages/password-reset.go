@@ -1,7 +1,6 @@
 package messages
 
 import (
-	"bytes"
 	"fmt"
 
 	"github.com/russross/blackfriday/v2"
@@ -21,15 +20,15 @@ func MakePasswordResetMessage(client *postman.Postman, config context.Config, us
 		ResetLink: makeResetLink(config, user, prt),
 	}
 
-	var body bytes.Buffer
-	if err := client.Messages["password-reset"].Execute(&body, vars); err != nil {
+	body, err := client.Render("password-reset", user.Locale, vars)
+	if err != nil {
 		return nil, err
 	}
 
 	return &postman.Message{
 		To:      []string{user.Email},
-		Subject: "Reset your password?",
-		Body:    string(blackfriday.Run(body.Bytes())),
+		Subject: client.T(user.Locale, "email.password_reset.subject", nil),
+		Body:    string(blackfriday.Run(body)),
 	}, nil
 }
 
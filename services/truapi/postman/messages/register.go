@@ -1,7 +1,6 @@
 package messages
 
 import (
-	"bytes"
 	"fmt"
 
 	"github.com/russross/blackfriday/v2"
@@ -21,15 +20,15 @@ func MakeRegisterMessage(client *postman.Postman, config context.Config, user db
 		RegisterLink: makeRegisterLink(config, user),
 	}
 
-	var body bytes.Buffer
-	if err := client.Messages["register"].Execute(&body, vars); err != nil {
+	body, err := client.Render("register", user.Locale, vars)
+	if err != nil {
 		return nil, err
 	}
 
 	return &postman.Message{
 		To:      []string{user.Email},
-		Subject: "Getting you started with TruStory Beta",
-		Body:    string(blackfriday.Run(body.Bytes())),
+		Subject: client.T(user.Locale, "email.register.subject", nil),
+		Body:    string(blackfriday.Run(body)),
 	}, nil
 }
 
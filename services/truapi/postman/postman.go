@@ -1,10 +1,12 @@
 package postman
 
 import (
+	"bytes"
 	"html/template"
 
 	
 	"github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/i18n"
 	
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -20,6 +22,7 @@ type Postman struct {
 	CharSet  string
 	SES      *ses.SES
 	Messages map[string]*template.Template
+	Catalog  *i18n.Catalog
 }
 
 // Message represents an email that can be sent
@@ -33,6 +36,10 @@ type Message struct {
 // NewVanillaPostman creates the client without the truapi dependency
 func NewVanillaPostman(region, sender, key, secret string) (*Postman, error) {
 	// setting up all message templates
+	catalog, err := i18n.NewDefaultCatalog()
+	if err != nil {
+		return nil, err
+	}
 	box := packr.New("Email Templates", "./templates")
 	templates := []string{
 		"register", "invitation", "password-reset", "email-confirmation",
@@ -44,7 +51,7 @@ func NewVanillaPostman(region, sender, key, secret string) (*Postman, error) {
 		if err != nil {
 			return nil, err
 		}
-		parsedTemplate, err := template.New(templateFilename).Parse(filename)
+		parsedTemplate, err := template.New(templateFilename).Funcs(translateFuncs(catalog, i18n.DefaultLocale)).Parse(filename)
 		if err != nil {
 			return nil, err
 		}
@@ -66,9 +73,45 @@ func NewVanillaPostman(region, sender, key, secret string) (*Postman, error) {
 		CharSet:  "UTF-8",
 		SES:      ses.New(session),
 		Messages: messages,
+		Catalog:  catalog,
 	}, nil
 }
 
+// Render executes the named message template in the given locale
+func (postman *Postman) Render(name, locale string, vars interface{}) ([]byte, error) {
+	tmpl, err := postman.Messages[name].Clone()
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	err = tmpl.Funcs(translateFuncs(postman.Catalog, locale)).Execute(&body, vars)
+	if err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+// T translates a catalog key in the given locale
+func (postman *Postman) T(locale, key string, params i18n.Params) string {
+	return postman.Catalog.T(locale, key, params)
+}
+
+// translateFuncs exposes the catalog to templates as `{{ t "key" "param" value }}`.
+// Catalog messages are trusted, parameters are escaped.
+func translateFuncs(catalog *i18n.Catalog, locale string) template.FuncMap {
+	return template.FuncMap{
+		"t": func(key string, kv ...interface{}) template.HTML {
+			params := make(i18n.Params)
+			for i := 0; i+1 < len(kv); i += 2 {
+				if name, ok := kv[i].(string); ok {
+					params[name] = template.HTMLEscaper(kv[i+1])
+				}
+			}
+			return template.HTML(catalog.T(locale, key, params))
+		},
+	}
+}
+
 // NewPostman creates the client to deliver SES emails
 func NewPostman(config context.Config) (*Postman, error) {
 	return NewVanillaPostman(config.AWS.Region, config.AWS.Sender, config.AWS.AccessKey, config.AWS.AccessSecret)
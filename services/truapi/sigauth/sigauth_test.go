@@ -0,0 +1,66 @@
+package sigauth
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSignedRequest(t *testing.T, secret string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/sendBroadcastNotification", bytes.NewBufferString(`{"type":0}`))
+	assert.NoError(t, Sign(req, secret))
+	return req
+}
+
+func TestVerifySignedRequest(t *testing.T) {
+	v := NewVerifier("secret", DefaultMaxSkew)
+	assert.NoError(t, v.Verify(newSignedRequest(t, "secret")))
+}
+
+func TestVerifyRejectsWrongSecret(t *testing.T) {
+	v := NewVerifier("secret", DefaultMaxSkew)
+	assert.Equal(t, ErrInvalidSignature, v.Verify(newSignedRequest(t, "other")))
+}
+
+func TestVerifyRejectsMissingSignature(t *testing.T) {
+	v := NewVerifier("secret", DefaultMaxSkew)
+	req := httptest.NewRequest(http.MethodPost, "/sendBroadcastNotification", nil)
+	assert.Equal(t, ErrMissingSignature, v.Verify(req))
+}
+
+func TestVerifyRejectsTamperedBody(t *testing.T) {
+	v := NewVerifier("secret", DefaultMaxSkew)
+	req := newSignedRequest(t, "secret")
+	tampered := httptest.NewRequest(http.MethodPost, "/sendBroadcastNotification", bytes.NewBufferString(`{"type":1}`))
+	tampered.Header = req.Header
+	assert.Equal(t, ErrInvalidSignature, v.Verify(tampered))
+}
+
+func TestVerifyRejectsTamperedQuery(t *testing.T) {
+	v := NewVerifier("secret", DefaultMaxSkew)
+	req := httptest.NewRequest(http.MethodPost, "/sendBroadcastNotification?type=0", bytes.NewBufferString(`{"type":0}`))
+	assert.NoError(t, Sign(req, "secret"))
+	tampered := httptest.NewRequest(http.MethodPost, "/sendBroadcastNotification?type=1", bytes.NewBufferString(`{"type":0}`))
+	tampered.Header = req.Header
+	assert.Equal(t, ErrInvalidSignature, v.Verify(tampered))
+	assert.NoError(t, v.Verify(req))
+}
+
+func TestVerifyRejectsReplay(t *testing.T) {
+	v := NewVerifier("secret", DefaultMaxSkew)
+	req := newSignedRequest(t, "secret")
+	assert.NoError(t, v.Verify(req))
+	assert.Equal(t, ErrReplayedRequest, v.Verify(req))
+}
+
+func TestVerifyRejectsExpired(t *testing.T) {
+	v := NewVerifier("secret", time.Minute)
+	req := newSignedRequest(t, "secret")
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10))
+	assert.Equal(t, ErrExpiredSignature, v.Verify(req))
+}
@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local stores objects in a directory and serves them over HTTP.
+// Presigned URLs are simulated with an HMAC of the key and the expiry, verified by ServeHTTP.
+type Local struct {
+	dir     string
+	baseURL string
+	secret  []byte
+}
+
+// NewLocal creates a storage in dir, creating it if needed.
+// Without a secret, presigned URLs are only valid until the process restarts.
+func NewLocal(dir, baseURL, secret string) (*Local, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("storage: %s needs a directory", BackendLocal)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	key := []byte(secret)
+	if secret == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &Local{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/"), secret: key}, nil
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.dir, filepath.FromSlash(key))
+}
+
+// Put writes an object, replacing the file atomically so readers never see partial content.
+func (l *Local) Put(key string, body io.Reader, contentType string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	path := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return l.URL(key), nil
+}
+
+// Get reads an object.
+func (l *Local) Get(key string) ([]byte, error) {
+	if !validKey(key) {
+		return nil, ErrNotFound
+	}
+	data, err := ioutil.ReadFile(l.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Delete removes an object, deleting a missing object isn't an error.
+func (l *Local) Delete(key string) error {
+	if !validKey(key) {
+		return fmt.Errorf("storage: invalid key %q", key)
+	}
+	err := os.Remove(l.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// URL is where ServeHTTP serves an object.
+func (l *Local) URL(key string) string {
+	return l.baseURL + "/" + key
+}
+
+func (l *Local) signature(key string, expires int64) string {
+	mac := hmac.New(sha256.New, l.secret)
+	fmt.Fprintf(mac, "PUT\n%s\n%d", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// PresignPut returns a URL ServeHTTP accepts a PUT of the object on until it expires.
+func (l *Local) PresignPut(key string, expires time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	expiry := time.Now().Add(expires).Unix()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiry, 10))
+	query.Set("signature", l.signature(key, expiry))
+	return l.URL(key) + "?" + query.Encode(), nil
+}
+
+// localPolicy is the policy of a presigned POST, signed like S3 POST policies are.
+type localPolicy struct {
+	Key         string `json:"key"`
+	ContentType string `json:"contentType"`
+	MaxSize     int64  `json:"maxSize"`
+	Expires     int64  `json:"expires"`
+}
+
+func (l *Local) policySignature(policy string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	fmt.Fprintf(mac, "POST\n%s", policy)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// PresignPost returns a form ServeHTTP accepts on the base URL until the policy expires.
+func (l *Local) PresignPost(policy PostPolicy) (*PresignedPost, error) {
+	if !validKey(policy.Key) {
+		return nil, fmt.Errorf("storage: invalid key %q", policy.Key)
+	}
+	document, err := json.Marshal(localPolicy{
+		Key:         policy.Key,
+		ContentType: policy.ContentType,
+		MaxSize:     policy.MaxSize,
+		Expires:     time.Now().Add(policy.Expires).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(document)
+	return &PresignedPost{
+		URL: l.baseURL + "/",
+		Fields: map[string]string{
+			"key":          policy.Key,
+			"Content-Type": policy.ContentType,
+			"policy":       encoded,
+			"signature":    l.policySignature(encoded),
+		},
+	}, nil
+}
+
+// post stores the file of a multipart form sent to a presigned POST, once its policy is verified.
+func (l *Local) post(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fields := make(map[string]string)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			http.Error(w, "Missing file", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if part.FormName() != "file" {
+			value, err := ioutil.ReadAll(io.LimitReader(part, 1<<16))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			fields[part.FormName()] = string(value)
+			continue
+		}
+		// the policy covers the fields sent before the file
+		if !hmac.Equal([]byte(l.policySignature(fields["policy"])), []byte(fields["signature"])) {
+			http.Error(w, "Signature does not match", http.StatusForbidden)
+			return
+		}
+		document, err := base64.StdEncoding.DecodeString(fields["policy"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var policy localPolicy
+		if err := json.Unmarshal(document, &policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if time.Now().Unix() > policy.Expires {
+			http.Error(w, "Policy has expired", http.StatusForbidden)
+			return
+		}
+		if fields["key"] != policy.Key || fields["Content-Type"] != policy.ContentType {
+			http.Error(w, "Fields don't match the policy", http.StatusForbidden)
+			return
+		}
+		data, err := ioutil.ReadAll(io.LimitReader(part, policy.MaxSize+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(data) == 0 || int64(len(data)) > policy.MaxSize {
+			http.Error(w, "File size is outside the allowed range", http.StatusBadRequest)
+			return
+		}
+		if _, err := l.Put(policy.Key, bytes.NewReader(data), policy.ContentType); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Location", l.URL(policy.Key))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+}
+
+// ServeHTTP serves objects on GET, stores them on a PUT to a presigned URL and on a POST of a presigned form.
+// It expects the path relative to the base URL, mount it with http.StripPrefix.
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if key == "" && r.Method == http.MethodPost {
+		l.post(w, r)
+		return
+	}
+	if !validKey(key) {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		file, err := os.Open(l.path(key))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil || info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+	case http.MethodPut:
+		expiry, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+		if err != nil || time.Now().Unix() > expiry {
+			http.Error(w, "Request has expired", http.StatusForbidden)
+			return
+		}
+		expected := l.signature(key, expiry)
+		if !hmac.Equal([]byte(expected), []byte(r.URL.Query().Get("signature"))) {
+			http.Error(w, "Signature does not match", http.StatusForbidden)
+			return
+		}
+		if _, err := l.Put(key, r.Body, r.Header.Get("Content-Type")); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
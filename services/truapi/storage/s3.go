@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3 stores objects in an S3 bucket, or a MinIO compatible one when an endpoint is configured.
+type S3 struct {
+	client   *s3.S3
+	creds    *credentials.Credentials
+	uploader *s3manager.Uploader
+	bucket   string
+	region   string
+	endpoint string
+}
+
+// NewS3 creates a storage in the configured bucket.
+// Credentials fall back to the default AWS chain when not configured.
+func NewS3(config Config) (*S3, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("storage: %s needs a bucket", config.Backend)
+	}
+	region := config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	awsConfig := &aws.Config{Region: aws.String(region)}
+	if config.AccessKey != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(config.AccessKey, config.AccessSecret, "")
+	}
+	if config.Endpoint != "" {
+		awsConfig.Endpoint = aws.String(config.Endpoint)
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+	}
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &S3{
+		client:   s3.New(sess),
+		creds:    sess.Config.Credentials,
+		uploader: s3manager.NewUploader(sess),
+		bucket:   config.Bucket,
+		region:   region,
+		endpoint: strings.TrimSuffix(config.Endpoint, "/"),
+	}, nil
+}
+
+// Put uploads an object.
+func (s *S3) Put(key string, body io.Reader, contentType string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	uploaded, err := s.uploader.Upload(input)
+	if err != nil {
+		return "", err
+	}
+	return uploaded.Location, nil
+}
+
+// Get downloads an object.
+func (s *S3) Get(key string) ([]byte, error) {
+	output, err := s.client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+	return ioutil.ReadAll(output.Body)
+}
+
+// Delete removes an object, deleting a missing object isn't an error.
+func (s *S3) Delete(key string) error {
+	_, err := s.client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// URL is the public URL of an object, path style on MinIO compatible stores.
+func (s *S3) URL(key string) string {
+	if s.endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", s.endpoint, s.bucket, key)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, s.region, key)
+}
+
+// PresignPut presigns an upload of an object.
+func (s *S3) PresignPut(key string, expires time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("storage: invalid key %q", key)
+	}
+	req, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return req.Presign(expires)
+}
+
+// PresignPost signs a POST policy with AWS signature version 4.
+// See https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-HTTPPOSTConstructPolicy.html
+func (s *S3) PresignPost(policy PostPolicy) (*PresignedPost, error) {
+	if !validKey(policy.Key) {
+		return nil, fmt.Errorf("storage: invalid key %q", policy.Key)
+	}
+	creds, err := s.creds.Get()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	fields := map[string]string{
+		"key":              policy.Key,
+		"Content-Type":     policy.ContentType,
+		"x-amz-algorithm":  "AWS4-HMAC-SHA256",
+		"x-amz-credential": fmt.Sprintf("%s/%s/%s/s3/aws4_request", creds.AccessKeyID, date, s.region),
+		"x-amz-date":       now.Format("20060102T150405Z"),
+	}
+	if creds.SessionToken != "" {
+		fields["x-amz-security-token"] = creds.SessionToken
+	}
+	conditions := []interface{}{
+		map[string]string{"bucket": s.bucket},
+		[]interface{}{"content-length-range", 1, policy.MaxSize},
+	}
+	for name, value := range fields {
+		conditions = append(conditions, map[string]string{name: value})
+	}
+	document, err := json.Marshal(map[string]interface{}{
+		"expiration": now.Add(policy.Expires).Format("2006-01-02T15:04:05.000Z"),
+		"conditions": conditions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(document)
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	for _, part := range []string{s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	fields["policy"] = encoded
+	fields["x-amz-signature"] = hex.EncodeToString(hmacSHA256(key, encoded))
+	url := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", s.bucket, s.region)
+	if s.endpoint != "" {
+		url = fmt.Sprintf("%s/%s/", s.endpoint, s.bucket)
+	}
+	return &PresignedPost{URL: url, Fields: fields}, nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
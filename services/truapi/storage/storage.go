@@ -0,0 +1,99 @@
+// Package storage stores uploaded media and rendered images in S3, a MinIO compatible store
+// or a local directory, so the services run offline in development and CI.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Backends of the storage.
+const (
+	BackendS3    = "s3"
+	BackendMinIO = "minio"
+	BackendLocal = "local"
+)
+
+// ErrNotFound is returned when getting a key that isn't stored.
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage stores objects by key, keys are slash separated paths like images/avatar.png.
+type Storage interface {
+	// Put stores an object and returns its public URL.
+	Put(key string, body io.Reader, contentType string) (string, error)
+	// Get returns an object or ErrNotFound.
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+	// URL is the public URL of an object.
+	URL(key string) string
+	// PresignPut returns a URL clients can PUT an object to until it expires.
+	PresignPut(key string, expires time.Duration) (string, error)
+	// PresignPost returns a form clients can POST an object with until the policy expires.
+	PresignPost(policy PostPolicy) (*PresignedPost, error)
+}
+
+// PostPolicy restricts what can be uploaded with a presigned POST.
+type PostPolicy struct {
+	Key         string
+	ContentType string
+	// MaxSize is the largest accepted object in bytes
+	MaxSize int64
+	Expires time.Duration
+}
+
+// PresignedPost is a form to upload an object, the fields have to be sent before the file field.
+type PresignedPost struct {
+	URL    string            `json:"url"`
+	Fields map[string]string `json:"fields"`
+}
+
+// Config selects and configures a backend.
+type Config struct {
+	// Backend is BackendS3 (default), BackendMinIO or BackendLocal
+	Backend string
+	Bucket  string
+	Region  string
+	// Endpoint is the URL of a MinIO compatible store, required by BackendMinIO
+	Endpoint     string
+	AccessKey    string
+	AccessSecret string
+	// Dir is the directory BackendLocal stores objects in
+	Dir string
+	// BaseURL is where BackendLocal objects are served, e.g. http://localhost:4000/storage
+	BaseURL string
+	// Secret signs the presigned URLs of BackendLocal
+	Secret string
+}
+
+// New creates the storage selected by the config.
+func New(config Config) (Storage, error) {
+	switch config.Backend {
+	case "", BackendS3:
+		return NewS3(config)
+	case BackendMinIO:
+		if config.Endpoint == "" {
+			return nil, errors.New("storage: minio needs an endpoint")
+		}
+		return NewS3(config)
+	case BackendLocal:
+		return NewLocal(config.Dir, config.BaseURL, config.Secret)
+	default:
+		return nil, fmt.Errorf("storage: unknown backend %q", config.Backend)
+	}
+}
+
+// validKey reports whether a key is a relative path that can't escape the storage.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
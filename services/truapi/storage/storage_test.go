@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLocal(t *testing.T) (*Local, *httptest.Server, func()) {
+	dir, err := ioutil.TempDir("", "storage")
+	assert.NoError(t, err)
+	local, err := NewLocal(dir, "", "secret")
+	assert.NoError(t, err)
+	server := httptest.NewServer(http.StripPrefix("/storage", local))
+	local.baseURL = server.URL + "/storage"
+	return local, server, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestLocalPutGetDelete(t *testing.T) {
+	local, _, cleanup := newTestLocal(t)
+	defer cleanup()
+
+	_, err := local.Get("images/avatar.png")
+	assert.Equal(t, ErrNotFound, err)
+	url, err := local.Put("images/avatar.png", bytes.NewBufferString("png"), "image/png")
+	assert.NoError(t, err)
+	assert.Equal(t, local.URL("images/avatar.png"), url)
+	data, err := local.Get("images/avatar.png")
+	assert.NoError(t, err)
+	assert.Equal(t, "png", string(data))
+
+	res, err := http.Get(url)
+	assert.NoError(t, err)
+	body, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "image/png", res.Header.Get("Content-Type"))
+	assert.Equal(t, "png", string(body))
+
+	assert.NoError(t, local.Delete("images/avatar.png"))
+	assert.NoError(t, local.Delete("images/avatar.png"))
+	_, err = local.Get("images/avatar.png")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestLocalPresignPut(t *testing.T) {
+	local, _, cleanup := newTestLocal(t)
+	defer cleanup()
+
+	put := func(url string) int {
+		req, err := http.NewRequest(http.MethodPut, url, bytes.NewBufferString("jpeg"))
+		assert.NoError(t, err)
+		res, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+	url, err := local.PresignPut("images/upload.jpg", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, put(strings.Replace(url, "upload.jpg", "other.jpg", 1)))
+	assert.Equal(t, http.StatusOK, put(url))
+	data, err := local.Get("images/upload.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, "jpeg", string(data))
+
+	expired, err := local.PresignPut("images/upload.jpg", -time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, put(expired))
+	assert.Equal(t, http.StatusForbidden, put(local.URL("images/upload.jpg")))
+}
+
+func TestValidKey(t *testing.T) {
+	for _, key := range []string{"avatar.png", "images/avatar.png", "spotlight/ab/abcd.png"} {
+		assert.True(t, validKey(key), key)
+	}
+	for _, key := range []string{"", "/etc/passwd", "../secret", "images/../../secret", "images//avatar.png", `images\avatar.png`} {
+		assert.False(t, validKey(key), key)
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{Backend: BackendMinIO, Bucket: "trustory"})
+	assert.Error(t, err)
+	_, err = New(Config{Backend: "gcs"})
+	assert.Error(t, err)
+	s, err := New(Config{Backend: BackendMinIO, Bucket: "trustory", Endpoint: "http://localhost:9000/"})
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:9000/trustory/images/avatar.png", s.URL("images/avatar.png"))
+	s, err = New(Config{Bucket: "trustory", Region: "us-west-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://trustory.s3.us-west-1.amazonaws.com/images/avatar.png", s.URL("images/avatar.png"))
+}
+
+func postForm(t *testing.T, post *PresignedPost, contentType string, file []byte) int {
+	body := new(bytes.Buffer)
+	form := multipart.NewWriter(body)
+	for _, name := range []string{"key", "Content-Type", "policy", "signature"} {
+		value := post.Fields[name]
+		if name == "Content-Type" && contentType != "" {
+			value = contentType
+		}
+		assert.NoError(t, form.WriteField(name, value))
+	}
+	part, err := form.CreateFormFile("file", "upload")
+	assert.NoError(t, err)
+	_, err = part.Write(file)
+	assert.NoError(t, err)
+	assert.NoError(t, form.Close())
+	res, err := http.Post(post.URL, form.FormDataContentType(), body)
+	assert.NoError(t, err)
+	res.Body.Close()
+	return res.StatusCode
+}
+
+func TestLocalPresignPost(t *testing.T) {
+	local, _, cleanup := newTestLocal(t)
+	defer cleanup()
+
+	policy := PostPolicy{Key: "images/uploads/a.png", ContentType: "image/png", MaxSize: 8, Expires: time.Minute}
+	post, err := local.PresignPost(policy)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, postForm(t, post, "text/html", []byte("png")))
+	assert.Equal(t, http.StatusBadRequest, postForm(t, post, "", []byte("too large png")))
+	assert.Equal(t, http.StatusBadRequest, postForm(t, post, "", nil))
+	_, err = local.Get(policy.Key)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, http.StatusNoContent, postForm(t, post, "", []byte("png")))
+	data, err := local.Get(policy.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, "png", string(data))
+
+	policy.Expires = -time.Minute
+	expired, err := local.PresignPost(policy)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, postForm(t, expired, "", []byte("png")))
+	expired.Fields["policy"] = post.Fields["policy"]
+	assert.Equal(t, http.StatusForbidden, postForm(t, expired, "", []byte("png")))
+}
+
+func TestS3PresignPost(t *testing.T) {
+	s, err := NewS3(Config{Bucket: "trustory", Region: "us-west-1", AccessKey: "AKID", AccessSecret: "secret"})
+	assert.NoError(t, err)
+	post, err := s.PresignPost(PostPolicy{Key: "images/uploads/a.png", ContentType: "image/png", MaxSize: 1024, Expires: time.Minute})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://trustory.s3.us-west-1.amazonaws.com/", post.URL)
+	assert.Equal(t, "images/uploads/a.png", post.Fields["key"])
+	assert.True(t, strings.HasPrefix(post.Fields["x-amz-credential"], "AKID/"))
+	assert.Len(t, post.Fields["x-amz-signature"], 64)
+	document, err := base64.StdEncoding.DecodeString(post.Fields["policy"])
+	assert.NoError(t, err)
+	assert.Contains(t, string(document), `["content-length-range",1,1024]`)
+	assert.Contains(t, string(document), `{"Content-Type":"image/png"}`)
+}
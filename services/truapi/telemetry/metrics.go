@@ -0,0 +1,133 @@
+package telemetry
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// HTTPRequestDuration is the time spent serving requests by route
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time spent serving HTTP requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+
+	// DBQueryDuration is the time spent running database queries by operation and table
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Time spent running database queries.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation", "table", "status"})
+
+	// ResolverDuration is the time spent in GraphQL resolvers by object and field
+	ResolverDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "graphql_resolver_duration_seconds",
+		Help:    "Time spent in GraphQL resolvers.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"object", "field"})
+
+	// ChainQueryDuration is the time spent querying the chain by query path
+	ChainQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "chain_query_duration_seconds",
+		Help:    "Time spent querying the chain.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"path", "status"})
+)
+
+func init() {
+	prometheus.MustRegister(HTTPRequestDuration, DBQueryDuration, ResolverDuration, ChainQueryDuration)
+}
+
+// Handler serves the metrics of the process in the Prometheus format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Register registers collectors, a collector already registered is left as is
+func Register(collectors ...prometheus.Collector) {
+	for _, c := range collectors {
+		err := prometheus.Register(c)
+		if _, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			continue
+		}
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Status labels the outcome of an operation
+func Status(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// Since observes the time elapsed since start in seconds
+func Since(o prometheus.Observer, start time.Time) {
+	o.Observe(time.Since(start).Seconds())
+}
+
+// Middleware traces requests and records their duration. Routes of a mux router are labelled
+// with their path template so ids in paths don't make a label each.
+func Middleware(operation string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return Instrument(next, operation, routeName)
+	}
+}
+
+// Instrument traces requests and records their duration labelled with the route returned by route
+func Instrument(next http.Handler, operation string, route func(*http.Request) string) http.Handler {
+	measured := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		name := route(r)
+		if name == "" {
+			name = "other"
+		}
+		HTTPRequestDuration.
+			WithLabelValues(name, r.Method, strconv.Itoa(sw.status)).
+			Observe(time.Since(start).Seconds())
+	})
+	return TraceHandler(measured, operation)
+}
+
+func routeName(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	if name := route.GetName(); name != "" {
+		return name
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return template
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush lets streamed responses through
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,44 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareLabelsRouteTemplate(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Middleware("test"))
+	router.HandleFunc("/claims/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	for _, path := range []string{"/claims/1", "/claims/2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	metric := &dto.Metric{}
+	observer, err := HTTPRequestDuration.GetMetricWithLabelValues("/claims/{id:[0-9]+}", "GET", "418")
+	assert.NoError(t, err)
+	assert.NoError(t, observer.(prometheus.Histogram).Write(metric))
+	assert.Equal(t, uint64(2), metric.GetHistogram().GetSampleCount())
+}
+
+func TestStartSpanWithoutTrace(t *testing.T) {
+	ctx := context.Background()
+	spanCtx, span := StartSpan(ctx, "query")
+	assert.Equal(t, ctx, spanCtx)
+	assert.False(t, span.SpanContext().IsValid())
+	EndSpan(span, errors.New("failed"))
+}
+
+func TestStatus(t *testing.T) {
+	assert.Equal(t, "ok", Status(nil))
+	assert.Equal(t, "error", Status(errors.New("failed")))
+}
@@ -0,0 +1,98 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/trace/zipkin"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/TruStory/octopus"
+
+// TracingConfig configures the traces of a service
+type TracingConfig struct {
+	Service string
+	// ZipkinURL is the collector the spans are exported to, tracing only propagates when empty
+	ZipkinURL string
+	// SampleRatio is the ratio of the traces started by the service that are sampled,
+	// traces started by another service follow its decision. Every trace is sampled when zero.
+	SampleRatio float64
+}
+
+// StartTracing installs the tracer of a service and the W3C trace context propagation.
+// The returned function flushes the spans left when the service stops.
+func StartTracing(config TracingConfig) (func(), error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	if config.ZipkinURL == "" {
+		return func() {}, nil
+	}
+	if config.SampleRatio <= 0 {
+		config.SampleRatio = 1
+	}
+	exporter, err := zipkin.NewRawExporter(config.ZipkinURL)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(sdkresource.NewWithAttributes(semconv.ServiceNameKey.String(config.Service))),
+	)
+	otel.SetTracerProvider(provider)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = provider.Shutdown(ctx)
+	}, nil
+}
+
+// TraceHandler continues the trace of incoming requests
+func TraceHandler(handler http.Handler, operation string) http.Handler {
+	return otelhttp.NewHandler(handler, operation)
+}
+
+// HTTPClient returns a client propagating the trace of the context of its requests
+func HTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+	}
+}
+
+// StartSpan starts a span in the trace of a context, when there's one
+func StartSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return otel.Tracer(tracerName).Start(ctx, name)
+}
+
+// ContextWithSpanOf returns a copy of ctx continuing the trace of another context,
+// for work outliving the request that started the trace
+func ContextWithSpanOf(ctx, of context.Context) context.Context {
+	return trace.ContextWithSpan(ctx, trace.SpanFromContext(of))
+}
+
+// EndSpan ends a span, recording the error it failed with
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
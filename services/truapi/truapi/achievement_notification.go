@@ -0,0 +1,63 @@
+package truapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/logging"
+	"github.com/TruStory/octopus/services/truapi/sigauth"
+)
+
+func (ta *TruAPI) sendAchievementNotification(ctx context.Context, n AchievementNotificationRequest) {
+	if !ta.notificationsInitialized || ta.achievementNotificationsCh == nil {
+		return
+	}
+	n.ctx = logging.Detach(ctx)
+	ta.achievementNotificationsCh <- n
+}
+
+func (ta *TruAPI) runAchievementNotificationSender(notifications <-chan AchievementNotificationRequest, pushEndpoint string) {
+	pushURL := fmt.Sprintf("%s/%s", strings.TrimRight(strings.TrimSpace(pushEndpoint), "/"), "sendAchievementNotification")
+
+	for n := range notifications {
+		logger := logging.FromContext(n.ctx)
+		httpClient := &http.Client{
+			Timeout: time.Second * 10,
+		}
+		b, err := json.Marshal(&n)
+		if err != nil {
+			logger.WithError(err).Error("error encoding achievement notification request")
+			continue
+		}
+		request, err := http.NewRequestWithContext(n.ctx, http.MethodPost, pushURL, bytes.NewBuffer(b))
+		if err != nil {
+			logger.WithError(err).Error("error creating http request")
+			continue
+		}
+		request.Header.Add("Accept", "application/json")
+		request.Header.Add("Content-Type", "application/json")
+		logging.Forward(request)
+		err = sigauth.Sign(request, ta.APIContext.Config.Push.Secret)
+		if err != nil {
+			logger.WithError(err).Error("error signing achievement notification request")
+			continue
+		}
+		resp, err := httpClient.Do(request)
+		if err != nil {
+			logger.WithError(err).Error("error sending achievement notification request")
+			continue
+		}
+		// only read the status
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusAccepted {
+			logger.WithField("status", resp.Status).Error("error sending achievement notification request")
+			continue
+		}
+		logger.WithField("address", n.Address).WithField("achievement_id", n.AchievementID).Info("achievement notification sent")
+	}
+}
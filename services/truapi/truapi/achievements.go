@@ -0,0 +1,224 @@
+package truapi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	app "github.com/TruStory/truchain/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	truCtx "github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/graphql"
+)
+
+// achievementsDefaultInterval is the interval in minutes between evaluations of the rules
+const achievementsDefaultInterval = 10
+
+// achievementRule is a validated achievement rule from the config
+type achievementRule struct {
+	truCtx.AchievementRuleConfig
+	reward sdk.Int
+}
+
+// achievementRules validates the configured rules, skipping the invalid ones.
+func achievementRules(configs []truCtx.AchievementRuleConfig) []achievementRule {
+	rules := make([]achievementRule, 0, len(configs))
+	seen := make(map[string]bool)
+	for _, config := range configs {
+		err := validateAchievementRule(config, seen)
+		if err != nil {
+			log.Printf("achievements: skipping rule [%s]: %s\n", config.ID, err)
+			continue
+		}
+		seen[config.ID] = true
+		reward := sdk.ZeroInt()
+		if config.Reward != "" {
+			coin, _ := sdk.ParseCoin(config.Reward)
+			reward = coin.Amount
+		}
+		rules = append(rules, achievementRule{AchievementRuleConfig: config, reward: reward})
+	}
+	return rules
+}
+
+func validateAchievementRule(config truCtx.AchievementRuleConfig, seen map[string]bool) error {
+	if config.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if seen[config.ID] {
+		return fmt.Errorf("duplicate id")
+	}
+	if !db.IsAchievementMetric(config.Metric) {
+		return fmt.Errorf("unknown metric %s", config.Metric)
+	}
+	if config.Threshold <= 0 {
+		return fmt.Errorf("threshold must be positive")
+	}
+	if config.Invites < 0 {
+		return fmt.Errorf("invites can't be negative")
+	}
+	if config.Reward != "" {
+		coin, err := sdk.ParseCoin(config.Reward)
+		if err != nil {
+			return err
+		}
+		if coin.Denom != app.StakeDenom {
+			return fmt.Errorf("invalid reward denomination got %s wanted %s", coin.Denom, app.StakeDenom)
+		}
+	}
+	return nil
+}
+
+func (ta *TruAPI) achievementRuleConfig(id string) (truCtx.AchievementRuleConfig, bool) {
+	for _, config := range ta.APIContext.Config.Achievements.Rules {
+		if config.ID == id {
+			return config, true
+		}
+	}
+	return truCtx.AchievementRuleConfig{}, false
+}
+
+// unlockAchievements records the achievements unlocked since the last evaluation
+// and notifies the users who unlocked them.
+func (ta *TruAPI) unlockAchievements(rules []achievementRule) error {
+	for _, rule := range rules {
+		candidates, err := ta.DBClient.AchievementCandidates(rule.ID, rule.Metric, rule.PerCommunity, rule.Threshold)
+		if err != nil {
+			return err
+		}
+		for _, candidate := range candidates {
+			unlock := &db.AchievementUnlock{
+				UserID:        candidate.UserID,
+				Address:       candidate.Address,
+				AchievementID: rule.ID,
+				CommunityID:   candidate.CommunityID,
+				Value:         candidate.Value,
+				Invites:       int64(rule.Invites),
+				Reward:        rule.reward.Int64(),
+				UnlockedAt:    time.Now(),
+			}
+			unlocked, err := ta.DBClient.RecordAchievementUnlock(unlock)
+			if err != nil {
+				return err
+			}
+			if !unlocked {
+				continue
+			}
+			log.Printf("achievements: [%s] unlocked [%s] %s\n", unlock.Address, rule.ID, unlock.CommunityID)
+			ta.sendAchievementNotification(context.Background(), AchievementNotificationRequest{
+				Address:       unlock.Address,
+				AchievementID: rule.ID,
+				Name:          rule.Name,
+				CommunityID:   unlock.CommunityID,
+				Invites:       unlock.Invites,
+				Reward:        unlock.Reward,
+			})
+		}
+	}
+	return nil
+}
+
+// rewardAchievements grants the invites and TRU of the unlocks that weren't rewarded yet.
+// A reward is claimed before being granted so it's granted at most once. The claim is only
+// released when nothing was granted: once the invites and TRU are recorded it's kept even if
+// sending the TRU fails, and the ledger reconciliation reports the missing gift for review.
+func (ta *TruAPI) rewardAchievements() error {
+	unlocks, err := ta.DBClient.UnrewardedAchievementUnlocks()
+	if err != nil {
+		return err
+	}
+	for i := range unlocks {
+		unlock := unlocks[i]
+		claimed, err := ta.DBClient.ClaimAchievementReward(unlock.ID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		err = ta.DBClient.RecordAchievementReward(&unlock)
+		if err != nil {
+			log.Printf("achievements: couldn't reward unlock [%d]: %s\n", unlock.ID, err)
+			releaseErr := ta.DBClient.ReleaseAchievementReward(unlock.ID)
+			if releaseErr != nil {
+				return releaseErr
+			}
+			continue
+		}
+		if unlock.Reward > 0 {
+			err = ta.sendAchievementReward(unlock)
+			if err != nil {
+				log.Printf("achievements: couldn't send the reward of unlock [%d], it needs a manual review: %s\n", unlock.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sendAchievementReward sends the TRU of an unlock from the reward broker.
+func (ta *TruAPI) sendAchievementReward(unlock db.AchievementUnlock) error {
+	broker, err := ta.accountQuery(context.Background(), ta.APIContext.Config.RewardBroker.Addr)
+	if err != nil {
+		return err
+	}
+	amount := sdk.NewInt64Coin(app.StakeDenom, unlock.Reward)
+	// pushd doesn't send gift notifications for rewards
+	return ta.SendGiftToAddress(unlock.Address, amount, broker.GetAccountNumber(), broker.GetSequence(), "reward")
+}
+
+func (ta *TruAPI) evaluateAchievements() error {
+	rules := achievementRules(ta.APIContext.Config.Achievements.Rules)
+	err := ta.unlockAchievements(rules)
+	if err != nil {
+		return err
+	}
+	return ta.rewardAchievements()
+}
+
+func (ta *TruAPI) achievementsEngine() {
+	if !ta.APIContext.Config.Achievements.Enabled {
+		log.Println("achievements are disabled")
+		return
+	}
+	interval := achievementsDefaultInterval
+	if ta.APIContext.Config.Achievements.Interval > 0 {
+		interval = ta.APIContext.Config.Achievements.Interval
+	}
+	log.Printf("achievements: evaluation interval of %d minutes \n", interval)
+	ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+	for {
+		err := ta.evaluateAchievements()
+		if err != nil {
+			log.Println("an error occurred evaluating achievements", err)
+		}
+		<-ticker.C
+	}
+}
+
+func (ta *TruAPI) badgesResolver(ctx context.Context, address string) ([]Badge, error) {
+	unlocks, err := ta.DBClient.AchievementUnlocksByAddress(address)
+	if err != nil {
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
+	}
+	badges := make([]Badge, 0, len(unlocks))
+	for _, unlock := range unlocks {
+		badge := Badge{
+			ID:          unlock.AchievementID,
+			Name:        unlock.AchievementID,
+			CommunityID: unlock.CommunityID,
+			Value:       unlock.Value,
+			UnlockedAt:  unlock.UnlockedAt,
+		}
+		rule, ok := ta.achievementRuleConfig(unlock.AchievementID)
+		if ok {
+			badge.Name = rule.Name
+			badge.Description = rule.Description
+			badge.Image = rule.Image
+		}
+		badges = append(badges, badge)
+	}
+	return badges, nil
+}
@@ -0,0 +1,30 @@
+package truapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	truCtx "github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+func TestAchievementRules(t *testing.T) {
+	rules := achievementRules([]truCtx.AchievementRuleConfig{
+		{ID: "first_argument", Metric: db.AchievementMetricArguments, Threshold: 1, Invites: 3},
+		{ID: "agrees_50", Metric: db.AchievementMetricAgreesReceived, Threshold: 50, Reward: "5000000utru"},
+		{ID: "community_expert", Metric: db.AchievementMetricAgreesReceived, Threshold: 100, PerCommunity: true},
+		// invalid rules are skipped
+		{ID: "first_argument", Metric: db.AchievementMetricArguments, Threshold: 2},
+		{ID: "", Metric: db.AchievementMetricClaims, Threshold: 1},
+		{ID: "unknown", Metric: "followers", Threshold: 1},
+		{ID: "no_threshold", Metric: db.AchievementMetricStreak},
+		{ID: "bad_denom", Metric: db.AchievementMetricStreak, Threshold: 7, Reward: "5stake"},
+	})
+
+	assert.Len(t, rules, 3)
+	assert.Equal(t, "first_argument", rules[0].ID)
+	assert.Equal(t, int64(0), rules[0].reward.Int64())
+	assert.Equal(t, int64(5000000), rules[1].reward.Int64())
+	assert.True(t, rules[2].PerCommunity)
+}
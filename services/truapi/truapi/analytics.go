@@ -0,0 +1,92 @@
+package truapi
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/analytics"
+	truCtx "github.com/TruStory/octopus/services/truapi/context"
+)
+
+// builtinAnalyticsEvents are the events tracked by the apps through /track, the config can override them
+var builtinAnalyticsEvents = []analytics.Schema{
+	{
+		Name: TrackEventClaimOpened,
+		Properties: map[string]analytics.PropertyType{
+			"claimId":     analytics.PropertyNumber,
+			"communityId": analytics.PropertyString,
+		},
+		Required: []string{"claimId"},
+	},
+	{
+		Name: TrackEventArgumentOpened,
+		Properties: map[string]analytics.PropertyType{
+			"claimId":     analytics.PropertyNumber,
+			"argumentId":  analytics.PropertyNumber,
+			"communityId": analytics.PropertyString,
+		},
+		Required: []string{"claimId", "argumentId"},
+	},
+}
+
+func newAnalytics(config truCtx.Config, store analytics.Store) (*analytics.Pipeline, error) {
+	schemas := append([]analytics.Schema{}, builtinAnalyticsEvents...)
+	for _, e := range config.Analytics.Events {
+		properties := make(map[string]analytics.PropertyType)
+		for _, p := range e.Properties {
+			parts := strings.SplitN(p, ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("analytics: event %s property %q must be declared as name:type", e.Name, p)
+			}
+			properties[strings.TrimSpace(parts[0])] = analytics.PropertyType(strings.TrimSpace(parts[1]))
+		}
+		schemas = append(schemas, analytics.Schema{
+			Name:       e.Name,
+			Properties: properties,
+			Required:   e.Required,
+			SampleRate: e.SampleRate,
+		})
+	}
+	registry, err := analytics.NewRegistry(schemas)
+	if err != nil {
+		return nil, err
+	}
+	exporters := make([]analytics.ExporterConfig, 0, len(config.Analytics.Exporters))
+	for _, e := range config.Analytics.Exporters {
+		exporters = append(exporters, analytics.ExporterConfig{
+			Name:    e.Name,
+			Type:    e.Type,
+			Events:  e.Events,
+			Project: e.Project,
+			Dataset: e.Dataset,
+			Table:   e.Table,
+			Token:   e.Token,
+			Dir:     e.Dir,
+			Format:  e.Format,
+		})
+	}
+	return analytics.New(registry, store, analytics.Config{
+		SampleRate:     config.Analytics.SampleRate,
+		BatchSize:      config.Analytics.BatchSize,
+		FlushInterval:  time.Duration(config.Analytics.FlushInterval) * time.Second,
+		ExportInterval: time.Duration(config.Analytics.ExportInterval) * time.Second,
+		Exporters:      exporters,
+	})
+}
+
+// trackAnalytics tracks events from the API, e.g. the legacy /track events
+func (ta *TruAPI) trackAnalytics(identity analytics.Identity, events ...analytics.Event) {
+	if ta.Analytics == nil {
+		return
+	}
+	result, err := ta.Analytics.Track(identity, events)
+	if err != nil {
+		log.Println("analytics: couldn't track events", err)
+		return
+	}
+	for _, r := range result.Rejected {
+		log.Printf("analytics: %s was rejected: %s\n", events[r.Index].Name, r.Error)
+	}
+}
@@ -0,0 +1,40 @@
+package truapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TruStory/octopus/services/truapi/analytics"
+	truCtx "github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+func TestNewAnalytics(t *testing.T) {
+	config := truCtx.Config{Analytics: truCtx.AnalyticsConfig{
+		Enabled: true,
+		Events: []truCtx.AnalyticsEventConfig{
+			{Name: "claim_shared", Properties: []string{"claimId:number", "channel: string"}, Required: []string{"claimId"}},
+		},
+	}}
+	pipeline, err := newAnalytics(config, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{TrackEventArgumentOpened, TrackEventClaimOpened, "claim_shared"}, pipeline.Registry().Names())
+	schema, _ := pipeline.Registry().Schema("claim_shared")
+	assert.Equal(t, analytics.PropertyString, schema.Properties["channel"])
+
+	config.Analytics.Events[0].Properties = []string{"claimId"}
+	_, err = newAnalytics(config, nil)
+	assert.Error(t, err)
+	config.Analytics.Events[0].Properties = []string{"claimId:number"}
+	config.Analytics.Exporters = []truCtx.AnalyticsExporterConfig{{Type: "segment"}}
+	_, err = newAnalytics(config, nil)
+	assert.Error(t, err)
+}
+
+func TestTrackEventProperties(t *testing.T) {
+	claimID := int64(1)
+	communityID := "crypto"
+	properties := trackEventProperties(db.TrackEventMeta{ClaimID: &claimID, CommunityID: &communityID})
+	assert.Equal(t, map[string]interface{}{"claimId": int64(1), "communityId": "crypto"}, properties)
+}
@@ -2,17 +2,22 @@ package truapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/TruStory/octopus/services/truapi/logging"
+	"github.com/TruStory/octopus/services/truapi/sigauth"
 )
 
-func (ta *TruAPI) sendBroadcastNotification(n BroadcastNotificationRequest) {
+func (ta *TruAPI) sendBroadcastNotification(ctx context.Context, n BroadcastNotificationRequest) {
 	if !ta.notificationsInitialized || ta.broadcastNotificationsCh == nil {
 		return
 	}
+	n.ctx = logging.Detach(ctx)
 	ta.broadcastNotificationsCh <- n
 }
 
@@ -20,31 +25,39 @@ func (ta *TruAPI) runBroadcastNotificationSender(notifications <-chan BroadcastN
 	pushURL := fmt.Sprintf("%s/%s", strings.TrimRight(strings.TrimSpace(pushEndpoint), "/"), "sendBroadcastNotification")
 
 	for n := range notifications {
+		logger := logging.FromContext(n.ctx)
 		httpClient := &http.Client{
 			Timeout: time.Second * 10,
 		}
 		b, err := json.Marshal(&n)
 		if err != nil {
-			fmt.Println("error encoding broadcast notification request", err)
+			logger.WithError(err).Error("error encoding broadcast notification request")
 			continue
 		}
-		request, err := http.NewRequest(http.MethodPost, pushURL, bytes.NewBuffer(b))
+		request, err := http.NewRequestWithContext(n.ctx, http.MethodPost, pushURL, bytes.NewBuffer(b))
 		if err != nil {
-			fmt.Println("error creating http request", err)
+			logger.WithError(err).Error("error creating http request")
+			continue
 		}
 		request.Header.Add("Accept", "application/json")
 		request.Header.Add("Content-Type", "application/json")
+		logging.Forward(request)
+		err = sigauth.Sign(request, ta.APIContext.Config.Push.Secret)
+		if err != nil {
+			logger.WithError(err).Error("error signing broadcast notification request")
+			continue
+		}
 		resp, err := httpClient.Do(request)
 		if err != nil {
-			fmt.Println("error sending broadcast notification request", err)
+			logger.WithError(err).Error("error sending broadcast notification request")
 			continue
 		}
 		// only read the status
 		_ = resp.Body.Close()
 		if resp.StatusCode != http.StatusAccepted {
-			fmt.Printf("error sending broadcast notification request status [%s] \n", resp.Status)
+			logger.WithField("status", resp.Status).Error("error sending broadcast notification request")
 			continue
 		}
-		fmt.Printf("broadcast notification sent type[%d]\n", n.Type)
+		logger.WithField("type", n.Type).Info("broadcast notification sent")
 	}
 }
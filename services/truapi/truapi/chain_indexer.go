@@ -0,0 +1,416 @@
+package truapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"time"
+
+	"github.com/TruStory/truchain/x/account"
+	"github.com/TruStory/truchain/x/bank"
+	"github.com/TruStory/truchain/x/claim"
+	"github.com/TruStory/truchain/x/slashing"
+	"github.com/TruStory/truchain/x/staking"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	abci "github.com/tendermint/tendermint/abci/types"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	truCtx "github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/telemetry"
+)
+
+// chain indexer defaults
+const (
+	// chainIndexerName names the cursor of the indexer
+	chainIndexerName = "chain"
+	// 5 seconds between polls of the node for new blocks
+	chainIndexerDefaultInterval = 5
+	// transactions queried at once per address
+	chainIndexerTransactionsPage = 50
+)
+
+// RunChainIndexer runs the chain indexer in the background.
+func (ta *TruAPI) RunChainIndexer(apiCtx truCtx.TruAPIContext) {
+	go ta.chainIndexer()
+}
+
+func (ta *TruAPI) chainIndexer() {
+	if !ta.APIContext.Config.Indexer.Enabled {
+		log.Println("chain indexer is disabled")
+		return
+	}
+	interval := chainIndexerDefaultInterval
+	if ta.APIContext.Config.Indexer.Interval > 0 {
+		interval = ta.APIContext.Config.Indexer.Interval
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	for {
+		err := ta.indexChain()
+		if err != nil {
+			log.Println("chain indexer: an error occurred, retrying on next interval", err)
+		}
+		<-ticker.C
+	}
+}
+
+// indexChain indexes the blocks committed since the last indexed one, block by block.
+func (ta *TruAPI) indexChain() error {
+	cursor, err := ta.DBClient.ChainIndexCursorByName(chainIndexerName)
+	if err != nil {
+		return err
+	}
+	height := ta.APIContext.Config.Indexer.StartHeight
+	if height < 1 {
+		height = 1
+	}
+	if cursor != nil {
+		height = cursor.Height + 1
+	}
+	node, err := ta.APIContext.GetNode()
+	if err != nil {
+		return err
+	}
+	status, err := node.Status()
+	if err != nil {
+		return err
+	}
+	for ; height <= status.SyncInfo.LatestBlockHeight; height++ {
+		block, err := ta.indexBlock(height)
+		if err != nil {
+			return fmt.Errorf("indexing block %d: %s", height, err)
+		}
+		err = ta.DBClient.SaveChainBlock(chainIndexerName, block)
+		if err != nil {
+			return fmt.Errorf("saving block %d: %s", height, err)
+		}
+	}
+	return nil
+}
+
+// chainIndexedTime returns the time of the last indexed block, zero when nothing was indexed.
+func (ta *TruAPI) chainIndexedTime() (time.Time, error) {
+	cursor, err := ta.DBClient.ChainIndexCursorByName(chainIndexerName)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if cursor == nil {
+		return time.Time{}, nil
+	}
+	return cursor.BlockTime, nil
+}
+
+// checkChainIndexedUntil returns an error unless every block before the date is indexed.
+func (ta *TruAPI) checkChainIndexedUntil(date time.Time) error {
+	indexedTime, err := ta.chainIndexedTime()
+	if err != nil {
+		return err
+	}
+	if indexedTime.Before(date) {
+		return fmt.Errorf("chain is only indexed until %s", indexedTime.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// indexBlock extracts the claims, arguments, stakes, slashes and bank transactions of a block.
+// Bank transactions aren't part of the block results, they're queried at the height of the block
+// for every address a message or an end block event involves.
+func (ta *TruAPI) indexBlock(height int64) (*db.ChainBlock, error) {
+	node, err := ta.APIContext.GetNode()
+	if err != nil {
+		return nil, err
+	}
+	block, err := node.Block(&height)
+	if err != nil {
+		return nil, err
+	}
+	results, err := node.BlockResults(&height)
+	if err != nil {
+		return nil, err
+	}
+	indexed := &db.ChainBlock{Height: height, Time: block.Block.Time}
+	addresses := make(map[string]bool)
+	registered := make(map[string]bool)
+	err = ta.indexTxs(indexed, block.Block.Txs, results.Results.DeliverTx, addresses, registered)
+	if err != nil {
+		return nil, err
+	}
+	if results.Results.EndBlock != nil {
+		err = ta.indexEndBlock(indexed, results.Results.EndBlock.Events, addresses)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for address := range addresses {
+		transactions, err := ta.blockTransactions(height, indexed.Time, address)
+		if err != nil {
+			return nil, err
+		}
+		indexed.Transactions = append(indexed.Transactions, transactions...)
+	}
+	markRegistrationGifts(indexed.Transactions, registered)
+	return indexed, nil
+}
+
+// indexTxs indexes the successful transactions of a block, adding the addresses their messages involve
+// and the accounts registered.
+func (ta *TruAPI) indexTxs(indexed *db.ChainBlock, txs tmtypes.Txs, results []*abci.ResponseDeliverTx, addresses, registered map[string]bool) error {
+	decodeTx := auth.DefaultTxDecoder(ta.APIContext.Codec)
+	for i, result := range results {
+		if result == nil || result.Code != 0 || i >= len(txs) {
+			continue
+		}
+		tx, txErr := decodeTx(txs[i])
+		if txErr != nil {
+			return txErr
+		}
+		for _, msg := range tx.GetMsgs() {
+			for _, address := range msgAddresses(msg) {
+				addresses[address.String()] = true
+			}
+			if msg, ok := msg.(account.MsgRegisterKey); ok {
+				registered[msg.Address.String()] = true
+			}
+		}
+		err := ta.indexTxResult(indexed, result, addresses)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// msgAddresses returns the signers of a message and the accounts it credits, like the recipient of a gift
+// or the coins a new account is registered with.
+func msgAddresses(msg sdk.Msg) []sdk.AccAddress {
+	addresses := msg.GetSigners()
+	switch msg := msg.(type) {
+	case bank.MsgSendGift:
+		addresses = append(addresses, msg.Recipient)
+	case account.MsgRegisterKey:
+		addresses = append(addresses, msg.Address)
+	}
+	return addresses
+}
+
+// markRegistrationGifts marks the gift each account registered in a block was created with,
+// its first gift in the block since the account can't be gifted before it exists.
+func markRegistrationGifts(transactions []db.ChainTransaction, registered map[string]bool) {
+	first := make(map[string]int)
+	for i, transaction := range transactions {
+		if transaction.Type != bank.TransactionGift || !registered[transaction.Address] {
+			continue
+		}
+		j, ok := first[transaction.Address]
+		if !ok || transaction.ID < transactions[j].ID {
+			first[transaction.Address] = i
+		}
+	}
+	for _, i := range first {
+		transactions[i].Registration = true
+	}
+}
+
+func (ta *TruAPI) indexTxResult(indexed *db.ChainBlock, result *abci.ResponseDeliverTx, addresses map[string]bool) error {
+	action, ok := getEventAttribute(sdk.EventTypeMessage, sdk.AttributeKeyAction, result.Events)
+	if !ok {
+		return nil
+	}
+	switch string(action) {
+	case claim.TypeMsgCreateClaim:
+		c := claim.Claim{}
+		err := claim.ModuleCodec.UnmarshalJSON(result.Data, &c)
+		if err != nil {
+			return err
+		}
+		indexed.Claims = append(indexed.Claims, db.ChainClaim{
+			ID:          c.ID,
+			CommunityID: c.CommunityID,
+			Creator:     c.Creator.String(),
+			Body:        c.Body,
+			Height:      indexed.Height,
+			CreatedTime: c.CreatedTime,
+		})
+	case staking.TypeMsgSubmitArgument:
+		argument := staking.Argument{}
+		err := staking.ModuleCodec.UnmarshalJSON(result.Data, &argument)
+		if err != nil {
+			return err
+		}
+		indexed.Arguments = append(indexed.Arguments, db.ChainArgument{
+			ID:          argument.ID,
+			ClaimID:     argument.ClaimID,
+			CommunityID: argument.CommunityID,
+			Creator:     argument.Creator.String(),
+			StakeType:   argument.StakeType,
+			Height:      indexed.Height,
+			CreatedTime: argument.CreatedTime,
+		})
+		// the stake of the argument isn't part of the result
+		stakes := make([]staking.Stake, 0)
+		err = ta.queryAtHeight(indexed.Height, path.Join(staking.ModuleName, staking.QueryArgumentStakes),
+			staking.QueryArgumentStakesParams{ArgumentID: argument.ID}, staking.ModuleCodec, &stakes)
+		if err != nil {
+			return err
+		}
+		for _, stake := range stakes {
+			if stake.Type != staking.StakeUpvote {
+				indexed.Stakes = append(indexed.Stakes, chainStake(indexed.Height, stake))
+			}
+		}
+	case staking.TypeMsgSubmitUpvote:
+		stake := staking.Stake{}
+		err := staking.ModuleCodec.UnmarshalJSON(result.Data, &stake)
+		if err != nil {
+			return err
+		}
+		indexed.Stakes = append(indexed.Stakes, chainStake(indexed.Height, stake))
+	case slashing.TypeMsgSlashArgument:
+		slash := slashing.Slash{}
+		err := slashing.ModuleCodec.UnmarshalJSON(result.Data, &slash)
+		if err != nil {
+			return err
+		}
+		indexed.Slashes = append(indexed.Slashes, db.ChainSlash{
+			ID:          slash.ID,
+			ArgumentID:  slash.ArgumentID,
+			Creator:     slash.Creator.String(),
+			Type:        int(slash.Type),
+			Reason:      int(slash.Reason),
+			Height:      indexed.Height,
+			CreatedTime: slash.CreatedTime,
+		})
+		b, ok := getEventAttribute(sdk.EventTypeMessage, slashing.AttributeKeySlashResults, result.Events)
+		if ok {
+			punishResults := make([]slashing.PunishmentResult, 0)
+			err := json.Unmarshal(b, &punishResults)
+			if err != nil {
+				return err
+			}
+			for _, p := range punishResults {
+				addresses[p.AppAccAddress.String()] = true
+			}
+		}
+	}
+	return nil
+}
+
+// indexEndBlock marks the stakes expired by the end blocker, which pays interest to the stake creators
+// and, for agrees, to the argument creators.
+func (ta *TruAPI) indexEndBlock(indexed *db.ChainBlock, events []abci.Event, addresses map[string]bool) error {
+	b, ok := getEventAttribute(staking.EventTypeInterestRewardPaid, staking.AttributeKeyExpiredStakes, events)
+	if !ok {
+		return nil
+	}
+	expiredStakes := make([]staking.Stake, 0)
+	err := staking.ModuleCodec.UnmarshalJSON(b, &expiredStakes)
+	if err != nil {
+		return err
+	}
+	argumentIDs := make([]uint64, 0)
+	for _, stake := range expiredStakes {
+		indexed.Stakes = append(indexed.Stakes, chainStake(indexed.Height, stake))
+		indexed.ExpiredStakes = append(indexed.ExpiredStakes, stake.ID)
+		addresses[stake.Creator.String()] = true
+		if stake.Type == staking.StakeUpvote {
+			argumentIDs = append(argumentIDs, stake.ArgumentID)
+		}
+	}
+	arguments, err := ta.DBClient.ChainArgumentsByIDs(argumentIDs)
+	if err != nil {
+		return err
+	}
+	for _, argument := range append(arguments, indexed.Arguments...) {
+		addresses[argument.Creator] = true
+	}
+	return nil
+}
+
+// blockTransactions returns the bank transactions of an address created in a block.
+func (ta *TruAPI) blockTransactions(height int64, blockTime time.Time, address string) ([]db.ChainTransaction, error) {
+	addr, err := sdk.AccAddressFromBech32(address)
+	if err != nil {
+		return nil, err
+	}
+	transactions := make([]db.ChainTransaction, 0)
+	for offset := 0; ; offset += chainIndexerTransactionsPage {
+		page := make([]bank.Transaction, 0)
+		err := ta.queryAtHeight(height, path.Join(bank.QuerierRoute, bank.QueryTransactionsByAddress),
+			bank.QueryTransactionsByAddressParams{
+				Address:   addr,
+				SortOrder: bank.SortDesc,
+				Limit:     chainIndexerTransactionsPage,
+				Offset:    offset,
+			}, bank.ModuleCodec, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, transaction := range page {
+			// newest first, every transaction of the block has the time of the block
+			if transaction.CreatedTime.Before(blockTime) {
+				return transactions, nil
+			}
+			transactions = append(transactions, db.ChainTransaction{
+				ID:          transaction.ID,
+				Type:        transaction.Type,
+				Address:     address,
+				ReferenceID: transaction.ReferenceID,
+				CommunityID: transaction.CommunityID,
+				Amount:      transaction.Amount.Amount.Int64(),
+				Height:      height,
+				CreatedTime: transaction.CreatedTime,
+			})
+		}
+		if len(page) < chainIndexerTransactionsPage {
+			return transactions, nil
+		}
+	}
+}
+
+// queryAtHeight queries the state of the chain as it was after a block,
+// which requires a node that doesn't prune it.
+func (ta *TruAPI) queryAtHeight(height int64, route string, params interface{}, cdc *codec.Codec, result interface{}) error {
+	paramBytes, err := cdc.MarshalJSON(params)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	res, _, err := ta.APIContext.WithHeight(height).QueryWithData("/custom/"+route, paramBytes)
+	telemetry.ChainQueryDuration.WithLabelValues(route, telemetry.Status(err)).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return err
+	}
+	return cdc.UnmarshalJSON(res, result)
+}
+
+func chainStake(height int64, stake staking.Stake) db.ChainStake {
+	return db.ChainStake{
+		ID:          stake.ID,
+		ArgumentID:  stake.ArgumentID,
+		CommunityID: stake.CommunityID,
+		Creator:     stake.Creator.String(),
+		Type:        stake.Type,
+		Amount:      stake.Amount.Amount.Int64(),
+		Expired:     stake.Expired,
+		Height:      height,
+		CreatedTime: stake.CreatedTime,
+		EndTime:     stake.EndTime,
+	}
+}
+
+func getEventAttribute(eventType, key string, events []abci.Event) ([]byte, bool) {
+	for _, event := range events {
+		if event.Type != eventType {
+			continue
+		}
+		for _, attr := range event.GetAttributes() {
+			if string(attr.Key) == key {
+				return attr.Value, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,75 @@
+package truapi
+
+import (
+	"testing"
+
+	chain "github.com/TruStory/truchain/app"
+	"github.com/TruStory/truchain/x/account"
+	"github.com/TruStory/truchain/x/bank"
+	sdkContext "github.com/cosmos/cosmos-sdk/client/context"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/stretchr/testify/assert"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	tmtypes "github.com/tendermint/tendermint/types"
+
+	truCtx "github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+func TestIndexTxsRegistration(t *testing.T) {
+	cdc := chain.MakeCodec()
+	ta := &TruAPI{APIContext: truCtx.TruAPIContext{CLIContext: &sdkContext.CLIContext{Codec: cdc}}}
+	encode := auth.DefaultTxEncoder(cdc)
+	registrar := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address())
+	coins := sdk.NewCoins(sdk.NewInt64Coin("tru", 300000000000))
+	tx := func(msg sdk.Msg) tmtypes.Tx {
+		b, err := encode(auth.NewStdTx([]sdk.Msg{msg}, auth.NewStdFee(200000, nil), nil, ""))
+		assert.NoError(t, err)
+		return b
+	}
+
+	registeredKey := secp256k1.GenPrivKey().PubKey()
+	registered := sdk.AccAddress(registeredKey.Address())
+	failedKey := secp256k1.GenPrivKey().PubKey()
+	failed := sdk.AccAddress(failedKey.Address())
+	gifted := sdk.AccAddress(secp256k1.GenPrivKey().PubKey().Address())
+	txs := tmtypes.Txs{
+		tx(account.NewMsgRegisterKey(registrar, registered, registeredKey, "secp256k1", coins)),
+		tx(bank.NewMsgSendGift(registrar, gifted, sdk.NewInt64Coin("tru", 1000))),
+		tx(account.NewMsgRegisterKey(registrar, failed, failedKey, "secp256k1", coins)),
+	}
+	results := []*abci.ResponseDeliverTx{{Code: 0}, {Code: 0}, {Code: 1}}
+
+	indexed := &db.ChainBlock{Height: 1}
+	addresses := make(map[string]bool)
+	registeredAddresses := make(map[string]bool)
+	err := ta.indexTxs(indexed, txs, results, addresses, registeredAddresses)
+	assert.NoError(t, err)
+	// the transactions of the registered account are queried like the registrar's
+	assert.Equal(t, map[string]bool{
+		registrar.String():  true,
+		registered.String(): true,
+		gifted.String():     true,
+	}, addresses)
+	assert.Equal(t, map[string]bool{registered.String(): true}, registeredAddresses)
+}
+
+func TestMarkRegistrationGifts(t *testing.T) {
+	transactions := []db.ChainTransaction{
+		{ID: 4, Type: bank.TransactionGift, Address: "registered"},
+		{ID: 2, Type: bank.TransactionBacking, Address: "registered"},
+		{ID: 3, Type: bank.TransactionGift, Address: "registered"},
+		{ID: 1, Type: bank.TransactionGift, Address: "gifted"},
+	}
+	markRegistrationGifts(transactions, map[string]bool{"registered": true})
+	registrations := make([]uint64, 0)
+	for _, transaction := range transactions {
+		if transaction.Registration {
+			registrations = append(registrations, transaction.ID)
+		}
+	}
+	// a gift sent in the block the account is registered in comes after the registration gift
+	assert.Equal(t, []uint64{3}, registrations)
+}
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/TruStory/octopus/services/truapi/sigauth"
 )
 
 func (ta *TruAPI) sendCommentNotification(n CommentNotificationRequest) {
@@ -21,16 +23,16 @@ func (ta *TruAPI) runCommentNotificationSender(notifications <-chan CommentNotif
 	url := fmt.Sprintf("%s/%s", strings.TrimRight(strings.TrimSpace(endpoint), "/"), "sendCommentNotification")
 
 	for n := range notifications {
-		claim := ta.claimResolver(ta.createContext(context.Background()), queryByClaimID{ID: uint64(n.ClaimID)})
-		if claim.ID == 0 {
-			fmt.Println("error retrieving claim id", n.ClaimID)
+		claim, err := ta.claimResolver(ta.createContext(context.Background()), queryByClaimID{ID: uint64(n.ClaimID)})
+		if err != nil || claim.ID == 0 {
+			fmt.Println("error retrieving claim id", n.ClaimID, err)
 			continue
 		}
 		n.ClaimCreator = claim.Creator.String()
 		if n.ArgumentID != 0 {
-			argument := ta.claimArgumentResolver(ta.createContext(context.Background()), queryByArgumentID{ID: uint64(n.ArgumentID)})
-			if argument.ID == 0 {
-				fmt.Println("error retrieving argument id", n.ArgumentID)
+			argument, err := ta.claimArgumentResolver(ta.createContext(context.Background()), queryByArgumentID{ID: uint64(n.ArgumentID)})
+			if err != nil || argument.ID == 0 {
+				fmt.Println("error retrieving argument id", n.ArgumentID, err)
 				continue
 			}
 			n.ArgumentCreator = argument.Creator.String()
@@ -49,6 +51,11 @@ func (ta *TruAPI) runCommentNotificationSender(notifications <-chan CommentNotif
 		}
 		request.Header.Add("Accept", "application/json")
 		request.Header.Add("Content-Type", "application/json")
+		err = sigauth.Sign(request, ta.APIContext.Config.Push.Secret)
+		if err != nil {
+			fmt.Println("error signing comment notification request", err)
+			continue
+		}
 		resp, err := httpClient.Do(request)
 		if err != nil {
 			fmt.Println("error sending comment notification request", err)
@@ -0,0 +1,152 @@
+package truapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/truapi/render"
+)
+
+// BroadcastCampaignRequest represents the request to schedule a broadcast campaign
+type BroadcastCampaignRequest struct {
+	Title             string              `json:"title"`
+	Message           string              `json:"message"`
+	ClaimID           *int64              `json:"claim_id"`
+	Segment           db.BroadcastSegment `json:"segment"`
+	ScheduledAt       *time.Time          `json:"scheduled_at"`
+	ThrottlePerMinute int                 `json:"throttle_per_minute"`
+}
+
+// BroadcastCampaignResponse is a campaign along with its delivery stats
+type BroadcastCampaignResponse struct {
+	db.BroadcastCampaign
+	Stats db.BroadcastCampaignStats `json:"stats"`
+}
+
+// HandleBroadcastCampaigns lists and schedules broadcast campaigns
+func (ta *TruAPI) HandleBroadcastCampaigns(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ta.listBroadcastCampaigns(w, r)
+	case http.MethodPost:
+		ta.createBroadcastCampaign(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleBroadcastCampaign returns or cancels a single broadcast campaign
+func (ta *TruAPI) HandleBroadcastCampaign(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		render.Error(w, r, "invalid campaign id", http.StatusBadRequest)
+		return
+	}
+	campaign, err := ta.DBClient.BroadcastCampaignByID(id)
+	if err != nil {
+		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if campaign == nil {
+		render.Error(w, r, "campaign not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		response, err := ta.broadcastCampaignResponse(*campaign)
+		if err != nil {
+			render.Error(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		render.Response(w, r, response, http.StatusOK)
+	case http.MethodDelete:
+		canceled, err := ta.DBClient.CancelBroadcastCampaign(id)
+		if err != nil {
+			render.Error(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !canceled {
+			render.Error(w, r, "only scheduled campaigns can be canceled", http.StatusConflict)
+			return
+		}
+		render.Response(w, r, true, http.StatusOK)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ta *TruAPI) listBroadcastCampaigns(w http.ResponseWriter, r *http.Request) {
+	campaigns, err := ta.DBClient.BroadcastCampaigns()
+	if err != nil {
+		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	responses := make([]BroadcastCampaignResponse, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		response, err := ta.broadcastCampaignResponse(campaign)
+		if err != nil {
+			render.Error(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		responses = append(responses, *response)
+	}
+	render.Response(w, r, responses, http.StatusOK)
+}
+
+func (ta *TruAPI) createBroadcastCampaign(w http.ResponseWriter, r *http.Request) {
+	var request BroadcastCampaignRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		render.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	request.Title = strings.TrimSpace(request.Title)
+	request.Message = strings.TrimSpace(request.Message)
+	if request.Title == "" || request.Message == "" {
+		render.Error(w, r, "title and message are required", http.StatusBadRequest)
+		return
+	}
+	if request.ThrottlePerMinute < 0 {
+		render.Error(w, r, "throttle_per_minute can't be negative", http.StatusBadRequest)
+		return
+	}
+	if request.Segment.ActiveWithinDays < 0 {
+		render.Error(w, r, "activeWithinDays can't be negative", http.StatusBadRequest)
+		return
+	}
+
+	scheduledAt := time.Now()
+	if request.ScheduledAt != nil {
+		scheduledAt = *request.ScheduledAt
+	}
+	campaign := &db.BroadcastCampaign{
+		Title:             request.Title,
+		Message:           request.Message,
+		ClaimID:           request.ClaimID,
+		Segment:           request.Segment,
+		ScheduledAt:       scheduledAt.UTC(),
+		ThrottlePerMinute: request.ThrottlePerMinute,
+		Status:            db.BroadcastCampaignScheduled,
+	}
+	err = ta.DBClient.AddBroadcastCampaign(campaign)
+	if err != nil {
+		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	render.Response(w, r, campaign, http.StatusCreated)
+}
+
+func (ta *TruAPI) broadcastCampaignResponse(campaign db.BroadcastCampaign) (*BroadcastCampaignResponse, error) {
+	stats, err := ta.DBClient.BroadcastCampaignStats(campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &BroadcastCampaignResponse{BroadcastCampaign: campaign, Stats: *stats}, nil
+}
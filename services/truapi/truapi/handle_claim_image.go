@@ -37,16 +37,30 @@ func (ta *TruAPI) updateClaimImage(r *http.Request) chttp.Response {
 		return chttp.SimpleErrorResponse(401, Err401NotAuthenticated)
 	}
 
-	claim := ta.claimResolver(r.Context(), queryByClaimID{ID: request.ClaimID})
-	settings := ta.settingsResolver(r.Context())
+	claim, err := ta.claimResolver(r.Context(), queryByClaimID{ID: request.ClaimID})
+	if err != nil {
+		return chttp.SimpleErrorResponse(500, err)
+	}
+	settings, err := ta.settingsResolver(r.Context())
+	if err != nil {
+		return chttp.SimpleErrorResponse(500, err)
+	}
 
 	if claim.Creator.String() != user.Address && !contains(settings.ClaimAdmins, user.Address) {
 		return chttp.SimpleErrorResponse(403, Err403NotAuthorized)
 	}
 
+	imageURL, err := ta.processUpload(request.URL, uploadKindClaim)
+	if _, ok := err.(uploadRejectedError); ok {
+		return chttp.SimpleErrorResponse(400, err)
+	}
+	if err != nil {
+		return chttp.SimpleErrorResponse(500, err)
+	}
+
 	claimImageURL := &db.ClaimImage{
 		ClaimID:       request.ClaimID,
-		ClaimImageURL: request.URL,
+		ClaimImageURL: imageURL,
 	}
 	err = ta.DBClient.AddClaimImage(claimImageURL)
 	if err != nil {
@@ -53,7 +53,7 @@ func (ta *TruAPI) addClaimOfTheDayID(r *http.Request) chttp.Response {
 	}
 	// Only notify when setting the Homepage featured debate, not for individual community featured debates
 	if request.CommunityID == "all" {
-		ta.sendBroadcastNotification(BroadcastNotificationRequest{
+		ta.sendBroadcastNotification(r.Context(), BroadcastNotificationRequest{
 			Type: db.NotificationFeaturedDebate,
 		})
 	}
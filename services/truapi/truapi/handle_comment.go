@@ -42,8 +42,8 @@ func (ta *TruAPI) handleCreateComment(w http.ResponseWriter, r *http.Request) {
 		render.Error(w, r, Err401NotAuthenticated.Error(), http.StatusUnauthorized)
 		return
 	}
-	claim := ta.claimResolver(r.Context(), queryByClaimID{ID: uint64(request.ClaimID)})
-	if claim.ID == 0 {
+	claim, err := ta.claimResolver(r.Context(), queryByClaimID{ID: uint64(request.ClaimID)})
+	if err != nil || claim.ID == 0 {
 		render.Error(w, r, "Invalid claim", http.StatusBadRequest)
 		return
 	}
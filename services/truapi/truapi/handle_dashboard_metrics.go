@@ -1,7 +1,9 @@
 package truapi
 
 import (
+	"context"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"path"
@@ -9,10 +11,8 @@ import (
 	"time"
 
 	app "github.com/TruStory/truchain/types"
-	"github.com/TruStory/truchain/x/bank/exported"
 	"github.com/TruStory/truchain/x/claim"
 	"github.com/TruStory/truchain/x/community"
-	"github.com/TruStory/truchain/x/staking"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 
 	"github.com/TruStory/octopus/services/truapi/db"
@@ -84,44 +84,7 @@ func (m *Metrics) getUserCommunityMetric(address, communityID string) *UserCommu
 	return ucm
 }
 
-func (ta *TruAPI) getClaimArguments(claimID uint64) ([]staking.Argument, error) {
-	queryRoute := path.Join(staking.ModuleName, staking.QueryClaimArguments)
-	res, err := ta.Query(queryRoute, staking.QueryClaimArgumentsParams{ClaimID: claimID}, staking.ModuleCodec)
-	if err != nil {
-		return nil, err
-	}
-
-	arguments := make([]staking.Argument, 0)
-	err = staking.ModuleCodec.UnmarshalJSON(res, &arguments)
-	if err != nil {
-		return nil, err
-	}
-	return arguments, nil
-}
-
-func notExpiredAt(date, created, end time.Time) bool {
-	betaReleaseDate, err := time.Parse("2006-01-02", "2019-07-11")
-	if err != nil {
-		return false
-	}
-	betaReleaseDate = betaReleaseDate.UTC()
-
-	// return always as expired any stake created before beta.
-	if created.Before(betaReleaseDate) {
-		return false
-	}
-	if date.Before(created) {
-		return false
-	}
-	if date.After(end) {
-		return false
-	}
-	if !created.Before(end) {
-		return false
-	}
-	return true
-}
-
+// HandleUsersMetrics returns the metrics of every user in every community for each day of a range
 func (ta *TruAPI) HandleUsersMetrics(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("x-metrics-version", metricsVersion)
 	jobTime := time.Now().UTC().Format("200601021504")
@@ -130,32 +93,14 @@ func (ta *TruAPI) HandleUsersMetrics(w http.ResponseWriter, r *http.Request) {
 		render.Error(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	date := r.FormValue("date")
-	if date == "" {
-		render.Error(w, r, "provide a valid date", http.StatusBadRequest)
-		return
-	}
-
-	beforeDate, err := time.Parse("2006-01-02", date)
-	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	// Get all claims
-	claims := make([]claim.Claim, 0)
-	result, err := ta.Query(
-		path.Join(claim.QuerierRoute, claim.QueryClaimsBeforeTime),
-		claim.QueryClaimsTimeParams{CreatedTime: beforeDate},
-		claim.ModuleCodec,
-	)
+	params, err := parseMetricsParams(r)
 	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		render.Error(w, r, err.Error(), http.StatusBadRequest)
 		return
 	}
-	err = claim.ModuleCodec.UnmarshalJSON(result, &claims)
+	err = ta.checkChainIndexedUntil(params.To)
 	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		render.Error(w, r, err.Error(), http.StatusServiceUnavailable)
 		return
 	}
 
@@ -164,86 +109,33 @@ func (ta *TruAPI) HandleUsersMetrics(w http.ResponseWriter, r *http.Request) {
 	err = ta.DBClient.FindAll(&users)
 	if err != nil {
 		render.Error(w, r, err.Error(), http.StatusInternalServerError)
-	}
-
-	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
-	}
-	chainMetrics := &Metrics{UserMetrics: make(map[string]*UserMetrics)}
-
-	for _, claim := range claims {
-		if !claim.CreatedTime.Before(beforeDate) {
-			continue
-		}
-		argumentIDCreator := make(map[uint64]string)
-		ucm := chainMetrics.getUserCommunityMetric(claim.Creator.String(), claim.CommunityID)
-		ucm.Claims++
-		arguments, err := ta.getClaimArguments(claim.ID)
-		if err != nil {
-			render.Error(w, r, err.Error(), http.StatusInternalServerError)
-		}
-		for _, argument := range arguments {
-			if !argument.CreatedTime.Before(beforeDate) {
-				continue
-			}
-			acm := chainMetrics.getUserCommunityMetric(argument.Creator.String(), claim.CommunityID)
-			acm.Arguments++
-			argumentIDCreator[argument.ID] = argument.Creator.String()
-		}
-		stakes := ta.claimStakesResolver(r.Context(), claim)
-		for _, stake := range stakes {
-			if !stake.CreatedTime.Before(beforeDate) {
-				continue
-			}
-			scm := chainMetrics.getUserCommunityMetric(stake.Creator.String(), claim.CommunityID)
-			if !stake.Expired || notExpiredAt(beforeDate, stake.CreatedTime, stake.EndTime) {
-				scm.PendingStake = scm.PendingStake.Add(stake.Amount)
-			}
-			if stake.Type == staking.StakeUpvote {
-				scm.StakedAgree = scm.StakedAgree.Add(stake.Amount)
-				chainMetrics.getUserCommunityMetric(argumentIDCreator[stake.ArgumentID], stake.CommunityID).AgreesReceived++
-				scm.AgreesGiven++
-			}
-
-			if stake.Type != staking.StakeUpvote {
-				scm.StakedArgument = scm.StakedArgument.Add(stake.Amount)
-			}
-			scm.Staked = scm.Staked.Add(stake.Amount)
-
-		}
+		return
 	}
 	// Get all communities
 	queryRoute := path.Join(community.QuerierRoute, community.QueryCommunities)
 	res, err := ta.Query(queryRoute, struct{}{}, community.ModuleCodec)
 	if err != nil {
 		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	communities := make([]community.Community, 0)
 	err = community.ModuleCodec.UnmarshalJSON(res, &communities)
 	if err != nil {
 		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if params.Community != "" {
+		communities = filterCommunities(communities, params.Community)
+		if len(communities) == 0 {
+			render.Error(w, r, fmt.Sprintf("community %s not found", params.Community), http.StatusBadRequest)
+			return
+		}
 	}
 	if len(communities) == 0 {
 		render.Error(w, r, "no communities found", http.StatusInternalServerError)
 		return
 	}
-	trackedTransactions := []exported.TransactionType{
-		exported.TransactionBacking,
-		exported.TransactionChallenge,
-		exported.TransactionCuratorReward,
-		exported.TransactionInterestArgumentCreation,
-		exported.TransactionInterestUpvoteReceived,
-		exported.TransactionInterestUpvoteGiven,
-		// slashing
-		exported.TransactionInterestArgumentCreationSlashed,
-		exported.TransactionInterestUpvoteReceivedSlashed,
-		exported.TransactionInterestUpvoteGivenSlashed,
-		exported.TransactionStakeCreatorSlashed,
-		exported.TransactionStakeCuratorSlashed,
-	}
-	w.Header().Add("Content-Type", "text/csv")
-	csvw := csv.NewWriter(w)
 	header := []string{"job_date_time", "date", "address", "username", "balance",
 		"community", "community_name", "stake_earned",
 		"claims_created", "claims_opened", "unique_claims_opened",
@@ -254,13 +146,60 @@ func (ta *TruAPI) HandleUsersMetrics(w http.ResponseWriter, r *http.Request) {
 		"replies",
 		"arguments_opened", "unique_arguments_opened",
 	}
-	err = csvw.Write(header)
+	export := newMetricsExport(w, params, "users_metrics", header)
+	for _, beforeDate := range params.Dates() {
+		err = ta.writeUsersMetrics(export, jobTime, beforeDate, users, communities)
+		if err != nil {
+			export.Error(r, err, http.StatusInternalServerError)
+			return
+		}
+	}
+	err = export.Close()
 	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		export.Error(r, err, http.StatusInternalServerError)
+	}
+}
+
+// writeUsersMetrics writes the metrics of the users created before a date, a row for each community
+func (ta *TruAPI) writeUsersMetrics(export *metricsExport, jobTime string, beforeDate time.Time,
+	users []db.User, communities []community.Community) error {
+	betaReleaseDate, err := time.Parse("2006-01-02", leaderboardInitialDate)
+	if err != nil {
+		return err
+	}
+	userCommunityStats, err := ta.DBClient.ChainUserCommunityStatsBefore(beforeDate, betaReleaseDate)
+	if err != nil {
+		return err
+	}
+	balances, err := ta.DBClient.ChainBalancesBefore(beforeDate)
+	if err != nil {
+		return err
+	}
+	chainMetrics := &Metrics{UserMetrics: make(map[string]*UserMetrics)}
+	coin := func(amount int64) sdk.Coin {
+		return sdk.NewInt64Coin(app.StakeDenom, amount)
+	}
+	for _, s := range userCommunityStats {
+		ucm := chainMetrics.getUserCommunityMetric(s.Address, s.CommunityID)
+		ucm.Claims = int(s.Claims)
+		ucm.Arguments = int(s.Arguments)
+		ucm.AgreesGiven = int(s.AgreesGiven)
+		ucm.AgreesReceived = int(s.AgreesReceived)
+		ucm.Staked = coin(s.Staked)
+		ucm.StakedArgument = coin(s.StakedArgument)
+		ucm.StakedAgree = coin(s.StakedAgree)
+		ucm.PendingStake = coin(s.PendingStake)
+		ucm.InterestArgumentCreated = coin(s.InterestArgumentCreation)
+		ucm.InterestAgreeReceived = coin(s.InterestAgreeReceived)
+		ucm.InterestAgreeGiven = coin(s.InterestAgreeGiven)
+		ucm.CuratorReward = coin(s.CuratorReward)
+		ucm.InterestSlashed = coin(s.InterestSlashed)
+		ucm.StakeSlashed = coin(s.StakeSlashed)
+		ucm.EarnedCoin = coin(s.Interest() - s.InterestSlashed)
 	}
 	openedClaims, err := ta.DBClient.OpenedClaimsSummary(beforeDate)
 	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return err
 	}
 	for _, userOpenedClaims := range openedClaims {
 		userMetrics := chainMetrics.getUserCommunityMetric(userOpenedClaims.Address, userOpenedClaims.CommunityID)
@@ -270,9 +209,7 @@ func (ta *TruAPI) HandleUsersMetrics(w http.ResponseWriter, r *http.Request) {
 
 	openedArguments, err := ta.DBClient.OpenedArgumentsSummary(beforeDate)
 	if err != nil {
-		fmt.Println(err)
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 	for _, userOpenedArguments := range openedArguments {
 		userMetrics := chainMetrics.getUserCommunityMetric(userOpenedArguments.Address, userOpenedArguments.CommunityID)
@@ -282,9 +219,7 @@ func (ta *TruAPI) HandleUsersMetrics(w http.ResponseWriter, r *http.Request) {
 
 	replies, err := ta.DBClient.UserRepliesStats(beforeDate)
 	if err != nil {
-		fmt.Println(err)
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 	for _, userReplies := range replies {
 		userMetrics := chainMetrics.getUserCommunityMetric(userReplies.Address, userReplies.CommunityID)
@@ -294,105 +229,62 @@ func (ta *TruAPI) HandleUsersMetrics(w http.ResponseWriter, r *http.Request) {
 		if user.Address == "" || !user.CreatedAt.Before(beforeDate) {
 			continue
 		}
-		transactions := ta.appAccountTransactionsResolver(r.Context(), queryByAddress{ID: user.Address})
-		balance := sdk.NewInt64Coin(app.StakeDenom, 0)
-		for _, transaction := range transactions {
-			if !transaction.CreatedTime.Before(beforeDate) {
-				continue
-			}
-
-			if transaction.Type.AllowedForDeduction() {
-				transaction.Amount.Amount = transaction.Amount.Amount.Neg()
-			}
-			balance = balance.Add(transaction.Amount)
-			if !transaction.Type.OneOf(trackedTransactions) {
-				continue
-			}
-			if transaction.CommunityID == "" {
-				render.Error(w, r,
-					fmt.Sprintf("transaction %s [%d] must contain community id",
-						transaction.Type.String(), transaction.ID),
-					http.StatusInternalServerError)
-				return
-			}
-
-			ucm := chainMetrics.getUserCommunityMetric(user.Address, transaction.CommunityID)
-			switch transaction.Type {
-			case exported.TransactionInterestArgumentCreation:
-				ucm.InterestArgumentCreated = ucm.InterestArgumentCreated.Add(transaction.Amount)
-				ucm.EarnedCoin = sdk.NewCoin(transaction.CommunityID, ucm.EarnedCoin.Amount.Add(transaction.Amount.Amount))
-			case exported.TransactionInterestUpvoteReceived:
-				ucm.InterestAgreeReceived = ucm.InterestAgreeReceived.Add(transaction.Amount)
-				ucm.EarnedCoin = sdk.NewCoin(transaction.CommunityID, ucm.EarnedCoin.Amount.Add(transaction.Amount.Amount))
-			case exported.TransactionInterestUpvoteGiven:
-				ucm.InterestAgreeGiven = ucm.InterestAgreeGiven.Add(transaction.Amount)
-				ucm.EarnedCoin = sdk.NewCoin(transaction.CommunityID, ucm.EarnedCoin.Amount.Add(transaction.Amount.Amount))
-			case exported.TransactionCuratorReward:
-				ucm.CuratorReward = ucm.CuratorReward.Add(transaction.Amount)
-			case exported.TransactionInterestArgumentCreationSlashed:
-				ucm.InterestSlashed = ucm.InterestSlashed.Add(transaction.Amount)
-				ucm.EarnedCoin = sdk.NewCoin(transaction.CommunityID, ucm.EarnedCoin.Amount.Sub(transaction.Amount.Amount))
-			case exported.TransactionInterestUpvoteReceivedSlashed:
-				ucm.InterestSlashed = ucm.InterestSlashed.Add(transaction.Amount)
-				ucm.EarnedCoin = sdk.NewCoin(transaction.CommunityID, ucm.EarnedCoin.Amount.Sub(transaction.Amount.Amount))
-			case exported.TransactionInterestUpvoteGivenSlashed:
-				ucm.InterestSlashed = ucm.InterestSlashed.Add(transaction.Amount)
-				ucm.EarnedCoin = sdk.NewCoin(transaction.CommunityID, ucm.EarnedCoin.Amount.Sub(transaction.Amount.Amount))
-			case exported.TransactionStakeCreatorSlashed:
-				ucm.StakeSlashed = ucm.StakeSlashed.Add(transaction.Amount)
-			case exported.TransactionStakeCuratorSlashed:
-				ucm.StakeSlashed = ucm.StakeSlashed.Add(transaction.Amount)
-			}
-
-		}
-		// "job_time", "date", "address", "username", "balance"
-		rowStart := []string{jobTime, beforeDate.Format(time.RFC3339Nano), user.Address, user.Username, balance.Amount.String()}
-
 		for _, community := range communities {
-			// 	"community", "community_name"
-			record := append(rowStart, community.ID)
-			record = append(record, community.Name)
 			m := chainMetrics.getUserCommunityMetric(user.Address, community.ID)
-			// "stake_earned"
-			record = append(record, m.EarnedCoin.Amount.String())
-			// "claims_created", "claims_opened", "unique_claims_opened",
-			record = append(record, fmt.Sprintf("%d", m.Claims))
-			record = append(record, fmt.Sprintf("%d", m.ClaimsOpened))
-			record = append(record, fmt.Sprintf("%d", m.UniqueClaimsOpened))
-			// "arguments_created", "agrees_received", "agrees_given",
-			record = append(record, fmt.Sprintf("%d", m.Arguments))
-			record = append(record, fmt.Sprintf("%d", m.AgreesReceived))
-			record = append(record, fmt.Sprintf("%d", m.AgreesGiven))
-			// "staked", "staked_argument", "staked_agree"
-			record = append(record, m.Staked.Amount.String())
-			record = append(record, m.StakedArgument.Amount.String())
-			record = append(record, m.StakedAgree.Amount.String())
-			// "interest_argument_creation", "interest_agree_received", "interest_agree_given", "reward_not_helpful",
-			record = append(record, m.InterestArgumentCreated.Amount.String())
-			record = append(record, m.InterestAgreeReceived.Amount.String())
-			record = append(record, m.InterestAgreeGiven.Amount.String())
-			record = append(record, m.CuratorReward.Amount.String())
-			// "interest_slashed", "stake_slashed", "at_stake"
-			record = append(record, m.InterestSlashed.Amount.String())
-			record = append(record, m.StakeSlashed.Amount.String())
-			record = append(record, m.PendingStake.Amount.String())
-			// "replies"
-			record = append(record, fmt.Sprintf("%d", m.Replies))
-			// "arguments_opened", "unique_arguments_opened"
-			record = append(record, fmt.Sprintf("%d", m.ArgumentsOpened))
-			record = append(record, fmt.Sprintf("%d", m.UniqueArgumentsOpened))
-			err = csvw.Write(record)
+			record := []interface{}{
+				// "job_time", "date", "address", "username", "balance"
+				jobTime, beforeDate.Format(time.RFC3339Nano), user.Address, user.Username, balances[user.Address],
+				// 	"community", "community_name"
+				community.ID, community.Name,
+				// "stake_earned"
+				json.Number(m.EarnedCoin.Amount.String()),
+				// "claims_created", "claims_opened", "unique_claims_opened",
+				m.Claims,
+				m.ClaimsOpened,
+				m.UniqueClaimsOpened,
+				// "arguments_created", "agrees_received", "agrees_given",
+				m.Arguments,
+				m.AgreesReceived,
+				m.AgreesGiven,
+				// "staked", "staked_argument", "staked_agree"
+				json.Number(m.Staked.Amount.String()),
+				json.Number(m.StakedArgument.Amount.String()),
+				json.Number(m.StakedAgree.Amount.String()),
+				// "interest_argument_creation", "interest_agree_received", "interest_agree_given", "reward_not_helpful",
+				json.Number(m.InterestArgumentCreated.Amount.String()),
+				json.Number(m.InterestAgreeReceived.Amount.String()),
+				json.Number(m.InterestAgreeGiven.Amount.String()),
+				json.Number(m.CuratorReward.Amount.String()),
+				// "interest_slashed", "stake_slashed", "at_stake"
+				json.Number(m.InterestSlashed.Amount.String()),
+				json.Number(m.StakeSlashed.Amount.String()),
+				json.Number(m.PendingStake.Amount.String()),
+				// "replies"
+				m.Replies,
+				// "arguments_opened", "unique_arguments_opened"
+				m.ArgumentsOpened,
+				m.UniqueArgumentsOpened,
+			}
+			err = export.Write(record)
 			if err != nil {
-				render.Error(w, r, err.Error(), http.StatusInternalServerError)
-				return
+				return err
 			}
 		}
-		csvw.Flush()
 	}
+	return nil
+}
 
+func filterCommunities(communities []community.Community, communityID string) []community.Community {
+	filtered := make([]community.Community, 0)
+	for _, c := range communities {
+		if c.ID == communityID {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
 }
 
-// HandleClaimMetrics returns metrics for claims
+// HandleClaimMetrics returns metrics for claims for each day of a range
 func (ta *TruAPI) HandleClaimMetrics(w http.ResponseWriter, r *http.Request) {
 	w.Header().Add("x-metrics-version", metricsVersion)
 	jobTime := time.Now().UTC().Format("200601021504")
@@ -401,45 +293,25 @@ func (ta *TruAPI) HandleClaimMetrics(w http.ResponseWriter, r *http.Request) {
 		render.Error(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	date := r.FormValue("date")
-	if date == "" {
-		render.Error(w, r, "provide a valid date", http.StatusBadRequest)
-		return
-	}
-
-	beforeDate, err := time.Parse("2006-01-02", date)
-	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	// Get all claims
-	claims := make([]claim.Claim, 0)
-	result, err := ta.Query(
-		path.Join(claim.QuerierRoute, claim.QueryClaimsBeforeTime),
-		claim.QueryClaimsTimeParams{CreatedTime: beforeDate},
-		claim.ModuleCodec,
-	)
+	params, err := parseMetricsParams(r)
 	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		render.Error(w, r, err.Error(), http.StatusBadRequest)
 		return
 	}
-	err = claim.ModuleCodec.UnmarshalJSON(result, &claims)
+	err = ta.checkChainIndexedUntil(params.To)
 	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		render.Error(w, r, err.Error(), http.StatusServiceUnavailable)
 		return
 	}
-	claimViewsStats, err := ta.DBClient.ClaimViewsStats(beforeDate)
+	flaggedClaimsIDs, err := ta.DBClient.FlaggedStoriesIDs(ta.APIContext.Config.Flag.Admin, ta.APIContext.Config.Flag.Limit)
 	if err != nil {
 		render.Error(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	claimRepliesStats, err := ta.DBClient.ClaimRepliesStats(beforeDate)
-	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
-		return
+	flaggedClaimsMappings := make(map[uint64]int)
+	for _, c := range flaggedClaimsIDs {
+		flaggedClaimsMappings[uint64(c)] = 1
 	}
-	w.Header().Add("Content-Type", "text/csv")
-	csvw := csv.NewWriter(w)
 	header := []string{
 		"job_date_time", "date", "created_date", "flagged", "id", "community_id", "claim_name",
 		"arguments_created", "agrees_given",
@@ -458,9 +330,34 @@ func (ta *TruAPI) HandleClaimMetrics(w http.ResponseWriter, r *http.Request) {
 		"last_activiy_argument",
 		"last_activity_agree",
 	}
-	err = csvw.Write(header)
+	export := newMetricsExport(w, params, "claim_metrics", header)
+	for _, beforeDate := range params.Dates() {
+		err = ta.writeClaimMetrics(export, jobTime, beforeDate, params.Community, flaggedClaimsMappings)
+		if err != nil {
+			export.Error(r, err, http.StatusInternalServerError)
+			return
+		}
+	}
+	err = export.Close()
 	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		export.Error(r, err, http.StatusInternalServerError)
+	}
+}
+
+// writeClaimMetrics writes the metrics of the claims created before a date
+func (ta *TruAPI) writeClaimMetrics(export *metricsExport, jobTime string, beforeDate time.Time,
+	communityID string, flaggedClaimsMappings map[uint64]int) error {
+	claims, err := ta.DBClient.ChainClaimStatsBefore(beforeDate)
+	if err != nil {
+		return err
+	}
+	claimViewsStats, err := ta.DBClient.ClaimViewsStats(beforeDate)
+	if err != nil {
+		return err
+	}
+	claimRepliesStats, err := ta.DBClient.ClaimRepliesStats(beforeDate)
+	if err != nil {
+		return err
 	}
 	// claim stats
 	claimViewsStatsMappings := make(map[uint64]int)
@@ -485,161 +382,91 @@ func (ta *TruAPI) HandleClaimMetrics(w http.ResponseWriter, r *http.Request) {
 		}
 		return claimRepliesStats[index]
 	}
-	flaggedClaimsIDs, err := ta.DBClient.FlaggedStoriesIDs(ta.APIContext.Config.Flag.Admin, ta.APIContext.Config.Flag.Limit)
-	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
-	}
-	flaggedClaimsMappings := make(map[uint64]int)
-	for _, c := range flaggedClaimsIDs {
-		flaggedClaimsMappings[uint64(c)] = 1
-	}
 	for _, claim := range claims {
-		if !claim.CreatedTime.Before(beforeDate) {
+		if communityID != "" && claim.CommunityID != communityID {
 			continue
 		}
-		totalArguments := 0
-		agreesGiven := 0
-		totalBacked := sdk.NewInt(0)
-		totalBackedAgree := sdk.NewInt(0)
-		totalBackedArgument := sdk.NewInt(0)
-		totalChallenged := sdk.NewInt(0)
-		totalChallengedAgree := sdk.NewInt(0)
-		totalChallengedArgument := sdk.NewInt(0)
-		var lastActivityArgument time.Time
-		var lastActivityAgree time.Time
-		mapArguments := make(map[uint64]int)
-		arguments, err := ta.getClaimArguments(claim.ID)
-
-		for idx, argument := range arguments {
-			mapArguments[argument.ID] = idx
-			if !argument.CreatedTime.Before(beforeDate) {
-				continue
-			}
-			if lastActivityArgument.Before(argument.CreatedTime) {
-				lastActivityArgument = argument.CreatedTime
-			}
-			totalArguments++
-		}
-		if err != nil {
-			render.Error(w, r, err.Error(), http.StatusInternalServerError)
-		}
-		stakes := ta.claimStakesResolver(r.Context(), claim)
-		for _, stake := range stakes {
-			if !stake.CreatedTime.Before(beforeDate) {
-				continue
-			}
-			i, ok := mapArguments[stake.ArgumentID]
-			if !ok {
-				render.Error(w, r, fmt.Sprintf("unable to find argument with id %d", stake.ArgumentID), http.StatusInternalServerError)
-			}
-			a := arguments[i]
-			if stake.Type == staking.StakeUpvote && lastActivityAgree.Before(stake.CreatedTime) {
-				lastActivityAgree = stake.CreatedTime
-			}
-			if a.StakeType == staking.StakeBacking && stake.Type == staking.StakeUpvote {
-				totalBacked = totalBacked.Add(stake.Amount.Amount)
-				totalBackedAgree = totalBackedAgree.Add(stake.Amount.Amount)
-				agreesGiven++
-				continue
-			}
-			if a.StakeType == staking.StakeChallenge && stake.Type == staking.StakeUpvote {
-				totalChallenged = totalChallenged.Add(stake.Amount.Amount)
-				totalChallengedAgree = totalChallengedAgree.Add(stake.Amount.Amount)
-				agreesGiven++
-				continue
-			}
-
-			if a.StakeType == staking.StakeBacking {
-				totalBacked = totalBacked.Add(stake.Amount.Amount)
-				totalBackedArgument = totalBackedArgument.Add(stake.Amount.Amount)
-			}
-			if a.StakeType == staking.StakeChallenge {
-				totalChallenged = totalChallenged.Add(stake.Amount.Amount)
-				totalChallengedArgument = totalChallengedArgument.Add(stake.Amount.Amount)
-			}
-
-		}
 		body := strings.ReplaceAll(claim.Body, "\n", " ")
-		viewsStats := getClaimViewsStats(claim.ID)
-		repliesStats := getClaimRepliesStats(claim.ID)
+		viewsStats := getClaimViewsStats(claim.ClaimID)
+		repliesStats := getClaimRepliesStats(claim.ClaimID)
 		lastActivityArgumentDateString := ""
-		if !lastActivityArgument.IsZero() {
-			lastActivityArgumentDateString = lastActivityArgument.Format(time.RFC3339Nano)
+		if claim.LastArgumentTime != nil {
+			lastActivityArgumentDateString = claim.LastArgumentTime.Format(time.RFC3339Nano)
 		}
 		lastActivityAgreeDateString := ""
-		if !lastActivityAgree.IsZero() {
-			lastActivityAgreeDateString = lastActivityAgree.Format(time.RFC3339Nano)
+		if claim.LastAgreeTime != nil {
+			lastActivityAgreeDateString = claim.LastAgreeTime.Format(time.RFC3339Nano)
 		}
-		row := []string{jobTime,
+		row := []interface{}{jobTime,
 			beforeDate.Format(time.RFC3339Nano),
 			claim.CreatedTime.Format(time.RFC3339Nano),
-			fmt.Sprintf("%d", flaggedClaimsMappings[claim.ID]),
-			fmt.Sprintf("%d", claim.ID),
+			flaggedClaimsMappings[claim.ClaimID],
+			claim.ClaimID,
 			claim.CommunityID,
 			strings.TrimSpace(body),
-			fmt.Sprintf("%d", totalArguments),
-			fmt.Sprintf("%d", agreesGiven),
-			totalBacked.Add(totalChallenged).String(),
-			totalBacked.String(),
-			totalBackedArgument.String(),
-			totalBackedAgree.String(),
-			totalChallenged.String(),
-			totalChallengedArgument.String(),
-			totalChallengedAgree.String(),
-			fmt.Sprintf("%d", viewsStats.UserViews),
-			fmt.Sprintf("%d", viewsStats.UniqueUserViews),
-			fmt.Sprintf("%d", viewsStats.AnonViews),
-			fmt.Sprintf("%d", viewsStats.UniqueAnonViews),
-			fmt.Sprintf("%d", viewsStats.UserArgumentsViews),
-			fmt.Sprintf("%d", viewsStats.UniqueUserArgumentsViews),
-			fmt.Sprintf("%d", viewsStats.AnonArgumentsViews),
-			fmt.Sprintf("%d", viewsStats.UniqueAnonArgumentsViews),
-			fmt.Sprintf("%d", repliesStats.Replies),
+			claim.Arguments,
+			claim.AgreesGiven,
+			claim.StakedBacked + claim.StakedChallenged,
+			claim.StakedBacked,
+			claim.StakedArgumentBacked,
+			claim.StakedAgreeBacked,
+			claim.StakedChallenged,
+			claim.StakedArgumentChallenged,
+			claim.StakedAgreeChallenged,
+			viewsStats.UserViews,
+			viewsStats.UniqueUserViews,
+			viewsStats.AnonViews,
+			viewsStats.UniqueAnonViews,
+			viewsStats.UserArgumentsViews,
+			viewsStats.UniqueUserArgumentsViews,
+			viewsStats.AnonArgumentsViews,
+			viewsStats.UniqueAnonArgumentsViews,
+			repliesStats.Replies,
 			lastActivityArgumentDateString,
 			lastActivityAgreeDateString,
 		}
-		if len(header) != len(row) {
-			render.Error(w, r, "header and row content mismatch", http.StatusInternalServerError)
-			return
-		}
-		err = csvw.Write(row)
+		err = export.Write(row)
 		if err != nil {
-			render.Error(w, r, err.Error(), http.StatusInternalServerError)
-			return
+			return err
 		}
-		csvw.Flush()
 	}
+	return nil
 }
 
+// HandleUserClaims returns the new participants of the claims for each day of a range
 func (ta *TruAPI) HandleUserClaims(w http.ResponseWriter, r *http.Request) {
 	jobTime := time.Now().UTC().Format("200601021504")
-	w.Header().Add("Content-Type", "text/csv")
-	csvw := csv.NewWriter(w)
-	header := []string{
-		"job_date_time", "date", "claim_id", "claim", "community", "address", "creation_date", "participants",
-	}
-	err := csvw.Write(header)
+	ctx := ta.createContext(r.Context())
+	err := r.ParseForm()
 	if err != nil {
 		render.Error(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	ctx := ta.createContext(r.Context())
-	err = r.ParseForm()
+	params, err := parseMetricsParams(r)
 	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		render.Error(w, r, err.Error(), http.StatusBadRequest)
 		return
 	}
-	date := r.FormValue("date")
-	if date == "" {
-		render.Error(w, r, "provide a valid date", http.StatusBadRequest)
-		return
+	header := []string{
+		"job_date_time", "date", "claim_id", "claim", "community", "address", "creation_date", "participants",
 	}
-
-	targetDate, err := time.Parse("2006-01-02", date)
+	export := newMetricsExport(w, params, "user_claims", header)
+	for _, targetDate := range params.Dates() {
+		err = ta.writeUserClaims(ctx, export, jobTime, targetDate, params.Community)
+		if err != nil {
+			export.Error(r, err, http.StatusInternalServerError)
+			return
+		}
+	}
+	err = export.Close()
 	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
-		return
+		export.Error(r, err, http.StatusInternalServerError)
 	}
+}
+
+// writeUserClaims writes the claims created before a date with the participants they gained the day before
+func (ta *TruAPI) writeUserClaims(ctx context.Context, export *metricsExport, jobTime string,
+	targetDate time.Time, communityID string) error {
 	// Get all claims
 	claims := make([]claim.Claim, 0)
 	result, err := ta.Query(
@@ -648,22 +475,26 @@ func (ta *TruAPI) HandleUserClaims(w http.ResponseWriter, r *http.Request) {
 		claim.ModuleCodec,
 	)
 	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 	err = claim.ModuleCodec.UnmarshalJSON(result, &claims)
 	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 	previousDay := targetDate.Add(-24 * time.Hour)
 	for _, claim := range claims {
 		if !claim.CreatedTime.Before(targetDate) {
 			continue
 		}
+		if communityID != "" && claim.CommunityID != communityID {
+			continue
+		}
 		participantsTarget := make(map[string]bool)
 		participantsPreviousDay := make(map[string]bool)
-		stakes := ta.claimStakesResolver(ctx, claim)
+		stakes, err := ta.claimStakesResolver(ctx, claim)
+		if err != nil {
+			return err
+		}
 
 		for _, s := range stakes {
 			if !s.CreatedTime.Before(targetDate) {
@@ -686,27 +517,20 @@ func (ta *TruAPI) HandleUserClaims(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		// "job_date_time", "claim_id", "claim", "community", "address", "creation_date", "participants",
-		row := []string{jobTime, targetDate.Format(time.RFC3339Nano), fmt.Sprintf("%d", claim.ID),
+		row := []interface{}{jobTime, targetDate.Format(time.RFC3339Nano), claim.ID,
 			claim.Body, claim.CommunityID, claim.Creator.String(), claim.CreatedTime.Format(time.RFC3339Nano),
-			fmt.Sprintf("%d", len(participantsTarget)-len(participantsPreviousDay)),
+			len(participantsTarget) - len(participantsPreviousDay),
 		}
-		err := csvw.Write(row)
+		err = export.Write(row)
 		if err != nil {
-			render.Error(w, r, err.Error(), http.StatusInternalServerError)
-			return
+			return err
 		}
-		csvw.Flush()
 	}
+	return nil
 }
 
 // HandleUserBase returns the user base.
 func (ta *TruAPI) HandleUserBase(w http.ResponseWriter, r *http.Request) {
-	token := ta.APIContext.Config.Metrics.Secret
-	if token == "" || token != r.Header.Get("Metrics-Secret") {
-		render.Error(w, r, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
 	w.Header().Add("Content-Type", "text/csv")
 	csvw := csv.NewWriter(w)
 	header := []string{
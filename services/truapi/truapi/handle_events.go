@@ -0,0 +1,53 @@
+package truapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/TruStory/octopus/services/truapi/analytics"
+	"github.com/TruStory/octopus/services/truapi/truapi/cookies"
+	"github.com/TruStory/octopus/services/truapi/truapi/render"
+)
+
+// maxEventsRequestSize is the largest body accepted by the events endpoint
+const maxEventsRequestSize = 1 << 20
+
+// EventsRequest is a batch of events tracked by a client
+type EventsRequest struct {
+	Events []analytics.Event `json:"events"`
+}
+
+// HandleEvents ingests a batch of events registered in the analytics pipeline
+func (ta *TruAPI) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	if ta.Analytics == nil {
+		render.Error(w, r, "analytics are disabled", http.StatusNotFound)
+		return
+	}
+
+	var request EventsRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxEventsRequestSize)).Decode(&request)
+	if err != nil {
+		render.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	identity := analytics.Identity{}
+	user, ok := r.Context().Value(userContextKey).(*cookies.AuthenticatedUser)
+	if ok && user != nil {
+		identity.Address = user.Address
+	} else if session, err := cookies.GetAnonymousSession(ta.APIContext, r); err == nil {
+		identity.SessionID = session.SessionID
+	}
+
+	result, err := ta.Analytics.Track(identity, request.Events)
+	if err == analytics.ErrBatchTooLarge {
+		render.Error(w, r, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.Response(w, r, result, http.StatusOK)
+}
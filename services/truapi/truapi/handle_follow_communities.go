@@ -39,7 +39,11 @@ func (ta *TruAPI) handleFollowCommunities(w http.ResponseWriter, r *http.Request
 		render.Error(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	communities := ta.communitiesResolver(r.Context())
+	communities, err := ta.communitiesResolver(r.Context())
+	if err != nil {
+		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	for _, c := range followCommunitiesRequest.Communities {
 		if !validCommunity(communities, c) {
 			render.Error(w, r, fmt.Sprintf("Invalid community id %s", c), http.StatusBadRequest)
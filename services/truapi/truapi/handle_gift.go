@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/TruStory/octopus/services/truapi/chttp"
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/logging"
 	app "github.com/TruStory/truchain/types"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
@@ -18,9 +20,14 @@ type GiftRequest struct {
 	UserID int64  `json:"user_id"`
 	Amount string `json:"amount"`
 	Memo   string `json:"memo"`
+	// Reference is an idempotency key, a gift with a reference already in the ledger isn't sent again
+	Reference string `json:"reference"`
 }
 
-// HandleGift gifts TRU to the user
+// HandleGift gifts TRU to the user. The gift is recorded in the reward ledger before it's sent and
+// removed if sending fails, so a request retried with the same reference never pays twice. A gift
+// broadcast without a result is kept in the ledger and answered with a 504, it must be reconciled
+// with the chain rather than sent again.
 func (ta *TruAPI) HandleGift(w http.ResponseWriter, r *http.Request) {
 	// only supports GET requests
 	if r.Method != http.MethodPost {
@@ -52,18 +59,44 @@ func (ta *TruAPI) HandleGift(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	broker, err := ta.accountQuery(r.Context(), ta.APIContext.Config.RewardBroker.Addr)
+	entry, recorded, err := ta.DBClient.RecordReferencedRewardLedgerEntry(&db.RewardLedgerEntry{
+		UserID:    user.ID,
+		Direction: db.RewardLedgerEntryDirectionCredit,
+		Amount:    amount.Amount.Int64(),
+		Currency:  db.RewardLedgerEntryCurrencyTru,
+		Reference: request.Reference,
+	})
 	if err != nil {
 		render.Error(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	err = ta.SendGiftToAddress(user.Address, amount, broker.GetAccountNumber(), broker.GetSequence(), request.Memo)
-	if err != nil {
-		render.Error(w, r, err.Error(), http.StatusBadRequest)
+	if !recorded {
+		if entry.UserID != user.ID || entry.Amount != amount.Amount.Int64() || entry.Currency != db.RewardLedgerEntryCurrencyTru {
+			render.Error(w, r, fmt.Sprintf("reference %s was used for another gift", request.Reference), http.StatusConflict)
+			return
+		}
+		// already sent
+		render.Response(w, r, true, http.StatusOK)
+		return
+	}
+
+	broker, err := ta.accountQuery(r.Context(), ta.APIContext.Config.RewardBroker.Addr)
+	if err == nil {
+		err = ta.SendGiftToAddress(user.Address, amount, broker.GetAccountNumber(), broker.GetSequence(), request.Memo)
+	}
+	if _, ok := err.(chttp.GiftUnconfirmedError); ok {
+		// the entry is kept, the reconciliation reports it if the gift wasn't included
+		logging.FromContext(r.Context()).WithError(err).Errorf("gift of ledger entry %d is unconfirmed", entry.ID)
+		render.Error(w, r, err.Error(), http.StatusGatewayTimeout)
 		return
 	}
-	_, err = ta.DBClient.RecordRewardLedgerEntry(user.ID, db.RewardLedgerEntryDirectionCredit, amount.Amount.Int64(), db.RewardLedgerEntryCurrencyTru)
 	if err != nil {
+		removeErr := ta.DBClient.RemoveRewardLedgerEntry(entry.ID)
+		if removeErr != nil {
+			logging.FromContext(r.Context()).WithError(removeErr).Errorf("error removing the ledger entry %d of a failed gift", entry.ID)
+			render.Error(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		render.Error(w, r, err.Error(), http.StatusBadRequest)
 		return
 	}
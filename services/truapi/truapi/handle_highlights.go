@@ -3,18 +3,9 @@ package truapi
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 
 	"github.com/TruStory/octopus/services/truapi/db"
 	"github.com/TruStory/octopus/services/truapi/truapi/render"
@@ -70,77 +61,12 @@ func (ta *TruAPI) createHighlight(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go renderAndCacheHighlight(ta, highlight)
+	// the highlight is shared right away, its preview is rendered in the background
+	go ta.prewarmSpotlight(backgroundContext(r), "highlight", uint64(highlight.ID))
 
 	render.Response(w, r, highlight, 200)
 }
 
-func renderAndCacheHighlight(ta *TruAPI, highlight *db.Highlight) {
-	rendered, err := renderHighlight(ta, highlight)
-	if err != nil {
-		log.Println(err)
-		return
-	}
-
-	url, err := cacheHighlight(ta, highlight, rendered)
-	if err != nil {
-		log.Println(err)
-		return
-	}
-
-	err = ta.DBClient.AddImageURLToHighlight(highlight.ID, url)
-	if err != nil {
-		log.Println(err)
-		return
-	}
-}
-
-func renderHighlight(ta *TruAPI, highlight *db.Highlight) (io.Reader, error) {
-	// firing up the http client
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
-
-	spotlightURL := fmt.Sprintf("%s/highlight/%d/spotlight", ta.APIContext.Config.Spotlight.URL, highlight.ID)
-	request, err := http.NewRequest("GET", spotlightURL, nil)
-	if err != nil {
-		return nil, err
-	}
-	q := request.URL.Query()
-	q.Add("highlight_id", strconv.FormatInt(highlight.ID, 10))
-	request.URL.RawQuery = q.Encode()
-
-	// processing the request
-	response, err := client.Do(request)
-	if err != nil {
-		return nil, err
-	}
-
-	return response.Body, nil
-}
-
-func cacheHighlight(ta *TruAPI, highlight *db.Highlight, rendered io.Reader) (string, error) {
-	session, err := session.NewSession(&aws.Config{
-		Region:      aws.String(ta.APIContext.Config.AWS.S3Region),
-		Credentials: credentials.NewStaticCredentials(ta.APIContext.Config.AWS.AccessKey, ta.APIContext.Config.AWS.AccessSecret, ""),
-	})
-	if err != nil {
-		return "", err
-	}
-
-	uploader := s3manager.NewUploader(session)
-	uploaded, err := uploader.Upload(&s3manager.UploadInput{
-		Bucket: aws.String(ta.APIContext.Config.AWS.S3Bucket),
-		Key:    aws.String(fmt.Sprintf("highlights/highlight-%d-%d.jpg", highlight.ID, time.Now().Unix())),
-		Body:   rendered,
-	})
-	if err != nil {
-		return "", err
-	}
-
-	return uploaded.Location, nil
-}
-
 func validateCreateHighlightRequest(request CreateHighlightRequest) error {
 	// if highlighted url is provided
 	if request.HighlightedURL != "" {
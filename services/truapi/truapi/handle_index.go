@@ -268,13 +268,25 @@ func joinPath(baseURL, route string) string {
 func makeClaimMetaTags(ta *TruAPI, route string, claimID uint64) (*Tags, error) {
 	ctx := ta.createContext(context.Background())
 
-	claimObj := ta.claimResolver(ctx, queryByClaimID{ID: claimID})
+	claimObj, err := ta.claimResolver(ctx, queryByClaimID{ID: claimID})
+	if err != nil {
+		return nil, err
+	}
 	claimImage := ta.claimImageResolver(ctx, claimObj)
-	participants := ta.claimParticipantsResolver(ctx, claimObj)
+	participants, err := ta.claimParticipantsResolver(ctx, claimObj)
+	if err != nil {
+		return nil, err
+	}
 	totalStaked := sdk.NewCoin(app.StakeDenom, sdk.NewInt(0))
-	arguments := ta.claimArgumentsResolver(ctx, queryClaimArgumentParams{ClaimID: claimID})
+	arguments, err := ta.claimArgumentsResolver(ctx, queryClaimArgumentParams{ClaimID: claimID})
+	if err != nil {
+		return nil, err
+	}
 	for _, argument := range arguments {
-		stakes := ta.claimArgumentStakesResolver(ctx, argument)
+		stakes, err := ta.claimArgumentStakesResolver(ctx, argument)
+		if err != nil {
+			return nil, err
+		}
 		for _, stake := range stakes {
 			totalStaked = totalStaked.Add(stake.Amount)
 		}
@@ -301,7 +313,10 @@ func makeClaimMetaTags(ta *TruAPI, route string, claimID uint64) (*Tags, error)
 
 func makeClaimArgumentMetaTags(ta *TruAPI, route string, claimID uint64, argumentID uint64) (*Tags, error) {
 	ctx := ta.createContext(context.Background())
-	argumentObj := ta.claimArgumentResolver(ctx, queryByArgumentID{ID: argumentID})
+	argumentObj, err := ta.claimArgumentResolver(ctx, queryByArgumentID{ID: argumentID})
+	if err != nil {
+		return nil, err
+	}
 	creatorObj, err := ta.DBClient.UserByAddress(argumentObj.Creator.String())
 	if creatorObj == nil || err != nil {
 		// if error, return default
@@ -317,7 +332,10 @@ func makeClaimArgumentMetaTags(ta *TruAPI, route string, claimID uint64, argumen
 
 func makeClaimArgumentHighlightMetaTags(ta *TruAPI, route string, claimID uint64, argumentID uint64, highlightID int64) (*Tags, error) {
 	ctx := ta.createContext(context.Background())
-	argumentObj := ta.claimArgumentResolver(ctx, queryByArgumentID{ID: argumentID})
+	argumentObj, err := ta.claimArgumentResolver(ctx, queryByArgumentID{ID: argumentID})
+	if err != nil {
+		return nil, err
+	}
 	creatorObj, err := ta.DBClient.UserByAddress(argumentObj.Creator.String())
 	if creatorObj == nil || err != nil {
 		// if error, return default
@@ -394,16 +412,18 @@ func makeClaimCommentMetaTags(ta *TruAPI, route string, commentID int64) (*Tags,
 // makes the community meta tags
 func makeCommunityMetaTags(ta *TruAPI, route string, communityID string) (*Tags, error) {
 	ctx := ta.createContext(context.Background())
-	community := ta.communityResolver(ctx, queryByCommunityID{CommunityID: communityID})
+	community, err := ta.communityResolver(ctx, queryByCommunityID{CommunityID: communityID})
+	if err != nil {
+		return nil, err
+	}
 	if community == nil {
 		return nil, errors.New("Community not found")
 	}
-	previewsDirectory := joinPath(ta.APIContext.Config.App.S3AssetsURL, previewDirectory)
 
 	return &Tags{
 		Title:       fmt.Sprintf("%s Community on %s", community.Name, ta.APIContext.Config.App.Name),
 		Description: community.Description,
-		Image:       joinPath(previewsDirectory, fmt.Sprintf("%s.jpg", communityID)),
+		Image:       fmt.Sprintf("%s/api/v1/spotlight?community_id=%s", ta.APIContext.Config.App.URL, url.QueryEscape(communityID)),
 		URL:         joinPath(ta.APIContext.Config.App.URL, route),
 	}, nil
 }
@@ -419,7 +439,7 @@ func makeProfileMetaTags(ta *TruAPI, route string, address string) (*Tags, error
 	return &Tags{
 		Title:       fmt.Sprintf("%s — TruStory", profileObj.FullName),
 		Description: profileObj.Bio,
-		Image:       profileObj.AvatarURL,
+		Image:       fmt.Sprintf("%s/api/v1/spotlight?user_address=%s", ta.APIContext.Config.App.URL, url.QueryEscape(address)),
 		URL:         joinPath(ta.APIContext.Config.App.URL, route),
 	}, nil
 }
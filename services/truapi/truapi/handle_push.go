@@ -1,47 +1,80 @@
 package truapi
 
 import (
-	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/TruStory/octopus/services/truapi/logging"
+	"github.com/TruStory/octopus/services/truapi/sigauth"
+	"github.com/TruStory/octopus/services/truapi/telemetry"
 	"github.com/TruStory/octopus/services/truapi/truapi/render"
 )
 
+// pushProxyRoutes are the push service routes reachable through HandlePush and their allowed methods
+var pushProxyRoutes = map[string][]string{
+	"/sendRewardNotification": {http.MethodPost},
+}
+
+func isPushProxyRouteAllowed(method, path string) bool {
+	for _, m := range pushProxyRoutes[path] {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
 // HandlePush proxies the request from the clients to the push service
 func (ta *TruAPI) HandlePush(res http.ResponseWriter, req *http.Request) {
+	path := parsePath(req.URL.Path)
+	if !isPushProxyRouteAllowed(req.Method, path) {
+		render.Error(res, req, "route not allowed", http.StatusForbidden)
+		return
+	}
 
 	// firing up the http client
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
+	client := telemetry.HTTPClient(time.Second * 10)
 
 	// preparing the request
-	request, err := http.NewRequest(http.MethodPost, ta.APIContext.Config.Push.EndpointURL+parsePath(req.URL.Path), req.Body)
-	fmt.Println(request)
+	request, err := http.NewRequestWithContext(req.Context(), req.Method, ta.APIContext.Config.Push.EndpointURL+path, req.Body)
 	if err != nil {
 		render.Error(res, req, err.Error(), http.StatusBadRequest)
+		return
 	}
 	request.Header.Add("Accept", "application/json")
 	request.Header.Add("Content-Type", "application/json")
+	logging.Forward(request)
+	err = sigauth.Sign(request, ta.APIContext.Config.Push.Secret)
+	if err != nil {
+		render.Error(res, req, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	// processing the request
 	response, err := client.Do(request)
 	if err != nil {
+		logging.FromContext(req.Context()).WithError(err).Error("error requesting push service")
 		render.Error(res, req, err.Error(), http.StatusBadRequest)
+		return
 	}
+	defer response.Body.Close()
 
 	// reading the response
 	responseBody, err := ioutil.ReadAll(response.Body)
 	if err != nil {
 		render.Error(res, req, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	// if all went well, sending back the response
+	status := http.StatusOK
+	if response.StatusCode >= http.StatusBadRequest {
+		status = response.StatusCode
+	}
 	res.Header().Set("Content-Type", "application/json")
-	res.WriteHeader(http.StatusOK)
+	res.WriteHeader(status)
 	_, err = res.Write(responseBody)
 	if err != nil {
 		render.Error(res, req, err.Error(), http.StatusBadRequest)
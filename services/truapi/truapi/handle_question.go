@@ -74,7 +74,10 @@ func (ta *TruAPI) handleDeleteQuestion(r *http.Request) chttp.Response {
 		return chttp.SimpleErrorResponse(401, Err401NotAuthenticated)
 	}
 
-	settings := ta.settingsResolver(r.Context())
+	settings, err := ta.settingsResolver(r.Context())
+	if err != nil {
+		return chttp.SimpleErrorResponse(500, err)
+	}
 	if !contains(settings.ClaimAdmins, user.Address) {
 		return chttp.SimpleErrorResponse(403, Err403NotAuthorized)
 	}
@@ -15,10 +15,7 @@ import (
 	truCtx "github.com/TruStory/octopus/services/truapi/context"
 	"github.com/TruStory/octopus/services/truapi/db"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/TruStory/octopus/services/truapi/storage"
 
 	"github.com/TruStory/octopus/services/truapi/truapi/cookies"
 	"github.com/TruStory/octopus/services/truapi/truapi/render"
@@ -124,7 +121,7 @@ func CalibrateUser(ta *TruAPI, twitterUser *twitter.User, referrerCode string) (
 	}
 
 	// we'll make a local copy of their avatar photo to remove the dependency on twitter
-	avatarURL, err := cacheAvatarLocally(ta.APIContext, twitterUser.ProfileImageURL)
+	avatarURL, err := cacheAvatarLocally(ta.Storage, twitterUser.ProfileImageURL)
 	if err != nil {
 		return nil, false, err
 	}
@@ -183,7 +180,10 @@ func CalibrateUser(ta *TruAPI, twitterUser *twitter.User, referrerCode string) (
 				return nil, false, err
 			}
 			// follow all communities by default
-			communities := ta.communitiesResolver(ctx)
+			communities, err := ta.communitiesResolver(ctx)
+			if err != nil {
+				return nil, false, err
+			}
 			communityIDs := make([]string, 0)
 			for _, community := range communities {
 				communityIDs = append(communityIDs, community.ID)
@@ -262,8 +262,12 @@ func getTwitterUser(apiCtx truCtx.TruAPIContext, authToken string, authTokenSecr
 	return user, nil
 }
 
-func cacheAvatarLocally(apiCtx truCtx.TruAPIContext, avatarURL string) (string, error) {
+// cacheAvatarLocally copies a Twitter avatar to the storage, keeping the Twitter URL when the storage isn't configured.
+func cacheAvatarLocally(store storage.Storage, avatarURL string) (string, error) {
 	avatarURL = strings.Replace(avatarURL, "_normal", "_400x400", 1)
+	if store == nil {
+		return avatarURL, nil
+	}
 
 	httpClient := &http.Client{
 		Timeout: time.Second * 10,
@@ -280,28 +284,8 @@ func cacheAvatarLocally(apiCtx truCtx.TruAPIContext, avatarURL string) (string,
 		return "", nil
 	}
 
-	session, err := session.NewSession(&aws.Config{
-		Region:      aws.String(apiCtx.Config.AWS.S3Region),
-		Credentials: credentials.NewStaticCredentials(apiCtx.Config.AWS.AccessKey, apiCtx.Config.AWS.AccessSecret, ""),
-	})
-	if err != nil {
-		return "", err
-	}
 	contentType := avatarResponse.Header.Get("Content-Type")
-
-	uploader := s3manager.NewUploader(session)
-	uploaded, err := uploader.Upload(&s3manager.UploadInput{
-		Bucket:      aws.String(apiCtx.Config.AWS.S3Bucket),
-		Key:         aws.String(fmt.Sprintf("images/avatar-%s", filename)),
-		Body:        avatarResponse.Body,
-		ContentType: &contentType,
-	})
-	if err != nil {
-		return "", err
-	}
-
-	return uploaded.Location, nil
-
+	return store.Put(fmt.Sprintf("images/avatar-%s", filename), avatarResponse.Body, contentType)
 }
 
 func makeFileName(response *http.Response) (string, error) {
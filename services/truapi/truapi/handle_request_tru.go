@@ -27,7 +27,11 @@ func (ta *TruAPI) handleRequestTru(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// dispatch a slack message here
-	userProfile := ta.userProfileResolver(ctx, user.Address)
+	userProfile, err := ta.userProfileResolver(ctx, user.Address)
+	if err != nil {
+		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	if userProfile == nil {
 		render.Error(w, r, Err422UnprocessableEntity.Error(), http.StatusUnprocessableEntity)
 		return
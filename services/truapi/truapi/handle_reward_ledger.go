@@ -0,0 +1,105 @@
+package truapi
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/truapi/render"
+)
+
+const (
+	rewardLedgerDefaultLimit = 50
+	rewardLedgerMaxLimit     = 500
+)
+
+// RewardLedgerResponse is the balances of a user in the reward ledger along with a page of their history
+type RewardLedgerResponse struct {
+	UserID   int64                    `json:"user_id"`
+	Balances []db.RewardLedgerBalance `json:"balances"`
+	Entries  []db.RewardLedgerEntry   `json:"entries"`
+}
+
+// RewardLedgerReconciliationResponse lists the users whose ledger doesn't match their actual balances
+type RewardLedgerReconciliationResponse struct {
+	Discrepancies []db.RewardLedgerDiscrepancy `json:"discrepancies"`
+}
+
+// HandleRewardLedger returns the reward ledger balances and history of a user
+func (ta *TruAPI) HandleRewardLedger(w http.ResponseWriter, r *http.Request) {
+	// only supports GET requests
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := strconv.ParseInt(r.FormValue("user_id"), 10, 64)
+	if err != nil {
+		render.Error(w, r, "valid user id is required", http.StatusBadRequest)
+		return
+	}
+	currency := db.RewardLedgerEntryCurrency(r.FormValue("currency"))
+	if currency != "" && currency != db.RewardLedgerEntryCurrencyInvite && currency != db.RewardLedgerEntryCurrencyTru {
+		render.Error(w, r, "unknown currency", http.StatusBadRequest)
+		return
+	}
+	var beforeID int64
+	if r.FormValue("before") != "" {
+		beforeID, err = strconv.ParseInt(r.FormValue("before"), 10, 64)
+		if err != nil {
+			render.Error(w, r, "invalid before", http.StatusBadRequest)
+			return
+		}
+	}
+	limit := rewardLedgerDefaultLimit
+	if r.FormValue("limit") != "" {
+		limit, err = strconv.Atoi(r.FormValue("limit"))
+		if err != nil || limit <= 0 || limit > rewardLedgerMaxLimit {
+			render.Error(w, r, "invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	user, err := ta.DBClient.UserByID(userID)
+	if err != nil {
+		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		render.Error(w, r, "user not found", http.StatusNotFound)
+		return
+	}
+	balances, err := ta.DBClient.RewardLedgerBalances(user.ID)
+	if err != nil {
+		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	entries, err := ta.DBClient.RewardLedgerEntries(user.ID, currency, beforeID, limit)
+	if err != nil {
+		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.Response(w, r, RewardLedgerResponse{
+		UserID:   user.ID,
+		Balances: balances,
+		Entries:  entries,
+	}, http.StatusOK)
+}
+
+// HandleRewardLedgerReconciliation reports the discrepancies between the ledger and the actual balances
+func (ta *TruAPI) HandleRewardLedgerReconciliation(w http.ResponseWriter, r *http.Request) {
+	// only supports GET requests
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	discrepancies, err := ta.DBClient.RewardLedgerDiscrepancies(chainIndexerName)
+	if err != nil {
+		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render.Response(w, r, RewardLedgerReconciliationResponse{Discrepancies: discrepancies}, http.StatusOK)
+}
@@ -1,20 +1,27 @@
 package truapi
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	truCtx "github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/logging"
+	"github.com/TruStory/octopus/services/truapi/sigauth"
+	"github.com/TruStory/octopus/services/truapi/telemetry"
+	"github.com/TruStory/octopus/services/truapi/truapi/cookies"
 	"github.com/TruStory/octopus/services/truapi/truapi/render"
+	"github.com/gorilla/mux"
 )
 
 // HandleSpotlight proxies the request from the clients to the spotlight service
 func (ta *TruAPI) HandleSpotlight(res http.ResponseWriter, req *http.Request) {
 	// firing up the http client
-	client := &http.Client{
-		Timeout: time.Second * 10,
-	}
+	client := telemetry.HTTPClient(time.Second * 10)
 
 	err := req.ParseForm()
 	if err != nil {
@@ -25,8 +32,10 @@ func (ta *TruAPI) HandleSpotlight(res http.ResponseWriter, req *http.Request) {
 	argumentID := req.FormValue("argument_id")
 	commentID := req.FormValue("comment_id")
 	highlightID := req.FormValue("highlight_id")
-	if claimID == "" && argumentID == "" && commentID == "" && highlightID == "" {
-		render.Error(res, req, "provide a valid claim or argument or comment or highlight", http.StatusBadRequest)
+	userAddress := req.FormValue("user_address")
+	communityID := req.FormValue("community_id")
+	if claimID == "" && argumentID == "" && commentID == "" && highlightID == "" && userAddress == "" && communityID == "" {
+		render.Error(res, req, "provide a valid claim or argument or comment or highlight or user or community", http.StatusBadRequest)
 		return
 	}
 
@@ -40,34 +49,232 @@ func (ta *TruAPI) HandleSpotlight(res http.ResponseWriter, req *http.Request) {
 		spotlightURL = fmt.Sprintf("%s/argument/%s/spotlight", ta.APIContext.Config.Spotlight.URL, argumentID)
 	} else if highlightID != "" {
 		spotlightURL = fmt.Sprintf("%s/highlight/%s/spotlight", ta.APIContext.Config.Spotlight.URL, highlightID)
+	} else if userAddress != "" {
+		spotlightURL = fmt.Sprintf("%s/user/%s/spotlight", ta.APIContext.Config.Spotlight.URL, url.PathEscape(userAddress))
+	} else if communityID != "" {
+		spotlightURL = fmt.Sprintf("%s/community/%s/spotlight", ta.APIContext.Config.Spotlight.URL, url.PathEscape(communityID))
+	}
+	// output size and format, like size=twitter&format=webp
+	query := url.Values{}
+	for _, param := range []string{"size", "format"} {
+		if value := req.FormValue(param); value != "" {
+			query.Set(param, value)
+		}
 	}
-	request, err := http.NewRequest("GET", spotlightURL, req.Body)
+	if len(query) > 0 {
+		spotlightURL += "?" + query.Encode()
+	}
+	request, err := http.NewRequestWithContext(req.Context(), "GET", spotlightURL, req.Body)
 	if err != nil {
-		fmt.Println("error creating request ", err.Error())
+		logging.FromContext(req.Context()).WithError(err).Error("error creating spotlight request")
 		render.Error(res, req, err.Error(), http.StatusBadRequest)
 		return
 	}
+	logging.Forward(request)
+	if etag := req.Header.Get("If-None-Match"); etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
 	// processing the request
 	response, err := client.Do(request)
 	if err != nil {
-		fmt.Println("error requesting spotlight: ", err.Error())
+		logging.FromContext(req.Context()).WithError(err).Error("error requesting spotlight")
 		render.Error(res, req, err.Error(), http.StatusBadRequest)
 		return
 	}
+	defer response.Body.Close()
+	if etag := response.Header.Get("ETag"); etag != "" {
+		res.Header().Set("ETag", etag)
+		res.Header().Set("Cache-Control", response.Header.Get("Cache-Control"))
+	}
+	if response.StatusCode == http.StatusNotModified {
+		res.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if response.StatusCode == http.StatusBadRequest {
+		responseBody, _ := ioutil.ReadAll(response.Body)
+		render.Error(res, req, strings.TrimSpace(string(responseBody)), http.StatusBadRequest)
+		return
+	}
 
 	// reading the response
 	responseBody, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		fmt.Println("error processing spotlight response ", err.Error())
+		logging.FromContext(req.Context()).WithError(err).Error("error reading spotlight response")
 		render.Error(res, req, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// if all went well, sending back the response
-	res.Header().Set("Content-Type", "image/jpeg")
+	contentType := response.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "image/jpeg"
+	}
+	res.Header().Set("Content-Type", contentType)
 	res.WriteHeader(http.StatusOK)
 	_, err = res.Write(responseBody)
 	if err != nil {
 		render.Error(res, req, err.Error(), http.StatusBadRequest)
 	}
 }
+
+// defaultSnippetsPerHour is the number of snippets a user can start rendering in an hour when it isn't configured
+const defaultSnippetsPerHour = 10
+
+func newSnippetLimiter(config truCtx.SpotlightConfig) *rateLimiter {
+	limit := defaultSnippetsPerHour
+	if config.SnippetsPerHour > 0 {
+		limit = config.SnippetsPerHour
+	}
+	return newRateLimiter(limit, time.Hour)
+}
+
+// HandleSpotlightSnippet proxies the animated snippet jobs of the spotlight service.
+// POST /spotlight/snippets?claim_id=1 starts rendering the snippet of a claim, GET /spotlight/snippets/{id}
+// polls the job and GET /spotlight/snippets/{id}/download serves the snippet once the job is done.
+// Rendering is expensive, so starting a job needs a logged in user and is rate limited per user.
+func (ta *TruAPI) HandleSpotlightSnippet(res http.ResponseWriter, req *http.Request) {
+	client := telemetry.HTTPClient(time.Second * 10)
+	jobID := mux.Vars(req)["id"]
+	method := http.MethodGet
+	spotlightURL := fmt.Sprintf("%s/snippets/%s", ta.APIContext.Config.Spotlight.URL, jobID)
+	if jobID == "" {
+		user, ok := req.Context().Value(userContextKey).(*cookies.AuthenticatedUser)
+		if !ok || user == nil {
+			render.Error(res, req, Err401NotAuthenticated.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !ta.snippetLimiter.allow(user.Address, time.Now()) {
+			render.Error(res, req, "too many snippets, try again later", http.StatusTooManyRequests)
+			return
+		}
+		err := req.ParseForm()
+		if err != nil {
+			render.Error(res, req, err.Error(), http.StatusBadRequest)
+			return
+		}
+		claimID := req.FormValue("claim_id")
+		if claimID == "" {
+			render.Error(res, req, "provide a valid claim", http.StatusBadRequest)
+			return
+		}
+		method = http.MethodPost
+		spotlightURL = fmt.Sprintf("%s/claim/%s/snippet", ta.APIContext.Config.Spotlight.URL, url.PathEscape(claimID))
+		query := url.Values{}
+		for _, param := range []string{"size", "format"} {
+			if value := req.FormValue(param); value != "" {
+				query.Set(param, value)
+			}
+		}
+		if len(query) > 0 {
+			spotlightURL += "?" + query.Encode()
+		}
+	} else if strings.HasSuffix(req.URL.Path, "/download") {
+		spotlightURL += "/download"
+	}
+	request, err := http.NewRequestWithContext(req.Context(), method, spotlightURL, nil)
+	if err != nil {
+		render.Error(res, req, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logging.Forward(request)
+	if etag := req.Header.Get("If-None-Match"); etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		logging.FromContext(req.Context()).WithError(err).Error("error requesting spotlight snippet")
+		render.Error(res, req, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer response.Body.Close()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		render.Error(res, req, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		render.Error(res, req, strings.TrimSpace(string(responseBody)), response.StatusCode)
+		return
+	}
+	for _, header := range []string{"Content-Type", "ETag", "Cache-Control"} {
+		if value := response.Header.Get(header); value != "" {
+			res.Header().Set(header, value)
+		}
+	}
+	res.WriteHeader(response.StatusCode)
+	_, err = res.Write(responseBody)
+	if err != nil {
+		logging.FromContext(req.Context()).WithError(err).Error("error writing spotlight snippet")
+	}
+}
+
+// backgroundContext keeps the request ID, logger and trace of a request for the spotlight
+// calls made in the background once it is served.
+func backgroundContext(r *http.Request) context.Context {
+	return telemetry.ContextWithSpanOf(logging.Detach(r.Context()), r.Context())
+}
+
+// invalidateSpotlight makes the spotlight service render the previews of a claim, argument or comment again.
+// The index of spotlight is shared by its replicas, any of them drops the previews for all of them.
+func (ta *TruAPI) invalidateSpotlight(ctx context.Context, entityType string, id uint64) {
+	config := ta.APIContext.Config.Spotlight
+	err := spotlightRequest(ctx, http.MethodDelete, config.URL, config.Secret, entityType, id, http.StatusNoContent)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("error invalidating spotlight")
+	}
+}
+
+// invalidateArgumentSpotlight invalidates the previews of an argument and of its claim,
+// which both show counts of the argument.
+func (ta *TruAPI) invalidateArgumentSpotlight(ctx context.Context, argumentID uint64) {
+	ta.invalidateSpotlight(ctx, "argument", argumentID)
+	argument, err := ta.claimArgumentResolver(ctx, queryByArgumentID{ID: argumentID})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("error fetching the claim of an argument to invalidate spotlight")
+		return
+	}
+	ta.invalidateSpotlight(ctx, "claim", argument.ClaimID)
+}
+
+// prewarmSpotlight makes the spotlight service render the previews of a new claim, argument or highlight
+// before anyone shares them.
+func (ta *TruAPI) prewarmSpotlight(ctx context.Context, entityType string, id uint64) {
+	config := ta.APIContext.Config.Spotlight
+	err := PrewarmSpotlight(ctx, config.URL, config.Secret, entityType, id)
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("error prewarming spotlight")
+	}
+}
+
+// PrewarmSpotlight queues rendering the previews of a claim, argument, comment or highlight in the spotlight
+// service at spotlightURL. The request is signed with secret, nothing is sent when it is empty.
+func PrewarmSpotlight(ctx context.Context, spotlightURL, secret, entityType string, id uint64) error {
+	return spotlightRequest(ctx, http.MethodPost, spotlightURL, secret, entityType, id, http.StatusAccepted)
+}
+
+// spotlightRequest sends a signed request to the spotlight endpoint of an entity, expecting the given status.
+// The request continues the trace and carries the request ID of ctx.
+func spotlightRequest(ctx context.Context, method, spotlightURL, secret, entityType string, id uint64, expected int) error {
+	if secret == "" {
+		return nil
+	}
+	client := telemetry.HTTPClient(time.Second * 10)
+	request, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s/%s/%d/spotlight", spotlightURL, entityType, id), nil)
+	if err != nil {
+		return err
+	}
+	logging.Forward(request)
+	err = sigauth.Sign(request, secret)
+	if err != nil {
+		return err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != expected {
+		return fmt.Errorf("%s %d: status %d", entityType, id, response.StatusCode)
+	}
+	return nil
+}
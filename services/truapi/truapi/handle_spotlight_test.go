@@ -0,0 +1,84 @@
+package truapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	truCtx "github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/logging"
+	"github.com/TruStory/octopus/services/truapi/truapi/cookies"
+)
+
+func TestSpotlightRequestForwardsRequestID(t *testing.T) {
+	var method, path, requestID string
+	spotlight := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path, requestID = r.Method, r.URL.Path, r.Header.Get(logging.RequestIDHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer spotlight.Close()
+
+	var ctx context.Context
+	handler := logging.Middleware(logging.New("test"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = backgroundContext(r)
+	}))
+	reqCtx, cancel := context.WithCancel(context.Background())
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil).WithContext(reqCtx))
+	// the request is served and canceled before the background call is made
+	cancel()
+
+	err := spotlightRequest(ctx, http.MethodDelete, spotlight.URL, "secret", "claim", 7, http.StatusNoContent)
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodDelete, method)
+	assert.Equal(t, "/claim/7/spotlight", path)
+	assert.NotEmpty(t, requestID)
+	assert.Equal(t, logging.RequestID(ctx), requestID)
+
+	err = spotlightRequest(ctx, http.MethodDelete, spotlight.URL, "secret", "claim", 7, http.StatusAccepted)
+	assert.Error(t, err)
+}
+
+func TestHandleSpotlightSnippetLimits(t *testing.T) {
+	var started int
+	spotlight := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started++
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer spotlight.Close()
+	ta := &TruAPI{snippetLimiter: newRateLimiter(1, time.Hour)}
+	ta.APIContext.Config.Spotlight = truCtx.SpotlightConfig{URL: spotlight.URL}
+
+	snippet := func(user *cookies.AuthenticatedUser) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/spotlight/snippets?claim_id=1", nil)
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), userContextKey, user))
+		}
+		w := httptest.NewRecorder()
+		ta.HandleSpotlightSnippet(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusUnauthorized, snippet(nil))
+	assert.Equal(t, http.StatusAccepted, snippet(&cookies.AuthenticatedUser{Address: "alice"}))
+	assert.Equal(t, http.StatusTooManyRequests, snippet(&cookies.AuthenticatedUser{Address: "alice"}))
+	assert.Equal(t, http.StatusAccepted, snippet(&cookies.AuthenticatedUser{Address: "bob"}))
+	assert.Equal(t, 2, started)
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(2, time.Minute)
+	now := time.Now()
+	assert.True(t, limiter.allow("alice", now))
+	assert.True(t, limiter.allow("alice", now.Add(10*time.Second)))
+	assert.False(t, limiter.allow("alice", now.Add(20*time.Second)))
+	assert.True(t, limiter.allow("bob", now.Add(20*time.Second)))
+	// the first request left the window
+	assert.True(t, limiter.allow("alice", now.Add(time.Minute)))
+	assert.False(t, limiter.allow("alice", now.Add(time.Minute+time.Second)))
+	// clients without recent requests are forgotten
+	limiter.allow("carol", now.Add(time.Hour))
+	assert.Len(t, limiter.hits, 1)
+}
@@ -7,6 +7,7 @@ import (
 
 	"encoding/base64"
 
+	"github.com/TruStory/octopus/services/truapi/analytics"
 	"github.com/TruStory/octopus/services/truapi/db"
 	"github.com/TruStory/octopus/services/truapi/truapi/cookies"
 )
@@ -53,8 +54,8 @@ func (ta *TruAPI) HandleTrackEvent(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
-		claim := ta.claimResolver(r.Context(), queryByClaimID{ID: uint64(*evt.Properties.ClaimID)})
-		if claim.ID == 0 {
+		claim, err := ta.claimResolver(r.Context(), queryByClaimID{ID: uint64(*evt.Properties.ClaimID)})
+		if err != nil || claim.ID == 0 {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
@@ -76,8 +77,8 @@ func (ta *TruAPI) HandleTrackEvent(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
-		claim := ta.claimResolver(r.Context(), queryByClaimID{ID: uint64(*evt.Properties.ClaimID)})
-		if claim.ID == 0 {
+		claim, err := ta.claimResolver(r.Context(), queryByClaimID{ID: uint64(*evt.Properties.ClaimID)})
+		if err != nil || claim.ID == 0 {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
@@ -123,6 +124,25 @@ func (ta *TruAPI) HandleTrackEvent(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		fmt.Println("error adding track event", err)
 	}
+	ta.trackAnalytics(analytics.Identity{Address: dbEvent.Address, SessionID: dbEvent.SessionID}, analytics.Event{
+		Name:       dbEvent.Event,
+		Properties: trackEventProperties(dbEvent.Meta),
+	})
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// trackEventProperties are the properties of a track event in the analytics pipeline
+func trackEventProperties(meta db.TrackEventMeta) map[string]interface{} {
+	properties := make(map[string]interface{})
+	if meta.ClaimID != nil {
+		properties["claimId"] = *meta.ClaimID
+	}
+	if meta.ArgumentID != nil {
+		properties["argumentId"] = *meta.ArgumentID
+	}
+	if meta.CommunityID != nil {
+		properties["communityId"] = *meta.CommunityID
+	}
+	return properties
+}
@@ -66,6 +66,8 @@ func (ta *TruAPI) HandleUnsigned(r *http.Request) chttp.Response {
 			err := staking.ModuleCodec.UnmarshalJSON(data, argument)
 			if err == nil {
 				ta.sendArgumentToSlack(*argument)
+				// the claim preview shows the argument count
+				go ta.invalidateSpotlight(backgroundContext(r), "claim", argument.ClaimID)
 			}
 		} else if txr.MsgTypes[0] == "MsgCreateClaim" {
 			c := new(claim.Claim)
@@ -73,6 +75,25 @@ func (ta *TruAPI) HandleUnsigned(r *http.Request) chttp.Response {
 			if err == nil {
 				ta.sendClaimToSlack(*c)
 			}
+		} else if txr.MsgTypes[0] == "MsgEditClaim" {
+			c := new(claim.Claim)
+			err = claim.ModuleCodec.UnmarshalJSON(data, c)
+			if err == nil {
+				go ta.invalidateSpotlight(backgroundContext(r), "claim", c.ID)
+			}
+		} else if txr.MsgTypes[0] == "MsgEditArgument" {
+			argument := new(staking.Argument)
+			err := staking.ModuleCodec.UnmarshalJSON(data, argument)
+			if err == nil {
+				go ta.invalidateSpotlight(backgroundContext(r), "argument", argument.ID)
+			}
+		} else if txr.MsgTypes[0] == "MsgSubmitUpvote" {
+			stake := new(staking.Stake)
+			err := staking.ModuleCodec.UnmarshalJSON(data, stake)
+			if err == nil {
+				// the argument preview shows the agree count
+				go ta.invalidateArgumentSpotlight(backgroundContext(r), stake.ArgumentID)
+			}
 		}
 	}
 
@@ -11,6 +11,7 @@ import (
 	"unicode"
 
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/logging"
 	"github.com/TruStory/octopus/services/truapi/postman/messages"
 	"github.com/TruStory/octopus/services/truapi/truapi/cookies"
 	"github.com/TruStory/octopus/services/truapi/truapi/regex"
@@ -71,6 +72,9 @@ type UpdateUserViaCookieRequest struct {
 
 	// Credentials fields
 	Credentials *db.UserCredentials `json:"credentials,omitempty"`
+
+	// Locale used to render notifications and emails
+	Locale *string `json:"locale,omitempty"`
 }
 
 // TruErrors for handle user
@@ -291,7 +295,11 @@ func (ta *TruAPI) verifyUserViaToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// follow all communities by default
-	communities := ta.communitiesResolver(ctx)
+	communities, err := ta.communitiesResolver(ctx)
+	if err != nil {
+		render.Error(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	communityIDs := make([]string, 0)
 	for _, community := range communities {
 		communityIDs = append(communityIDs, community.ID)
@@ -355,6 +363,24 @@ func (ta *TruAPI) updateUserDetailsViaCookie(w http.ResponseWriter, r *http.Requ
 
 	// if user wants to change their profile
 	if request.Profile != nil {
+		current, err := ta.DBClient.UserByID(user.ID)
+		if err != nil {
+			render.Error(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// only new uploads are processed, the current avatar is sent back unchanged and an empty one clears it
+		if current != nil && request.Profile.AvatarURL != current.AvatarURL {
+			avatarURL, err := ta.processUpload(request.Profile.AvatarURL, uploadKindAvatar)
+			if _, ok := err.(uploadRejectedError); ok {
+				render.Error(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				render.Error(w, r, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			request.Profile.AvatarURL = avatarURL
+		}
 		err = ta.DBClient.UpdateProfile(user.ID, request.Profile)
 		if err != nil {
 			render.Error(w, r, err.Error(), http.StatusBadRequest)
@@ -365,6 +391,22 @@ func (ta *TruAPI) updateUserDetailsViaCookie(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// if user wants to change their locale
+	if request.Locale != nil {
+		if !ta.Catalog.HasLocale(*request.Locale) {
+			render.Error(w, r, "unsupported locale", http.StatusBadRequest)
+			return
+		}
+		err = ta.DBClient.SetUserLocale(user.ID, *request.Locale)
+		if err != nil {
+			render.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		render.Response(w, r, true, http.StatusOK)
+		return
+	}
+
 	// if user (who was previously authorized via connected account) wants to add a password to their accounts
 	if request.Credentials != nil {
 		err = ta.DBClient.SetUserCredentials(user.ID, request.Credentials)
@@ -421,7 +463,10 @@ func (ta *TruAPI) createUserResponse(ctx context.Context, user *db.User, singedU
 	largeURI := strings.Replace(user.AvatarURL, "_bigger", "_200x200", 1)
 	largeURI = strings.Replace(largeURI, "http://", "https://", 1)
 
-	aa := ta.appAccountResolver(ctx, queryByAddress{ID: user.Address})
+	aa, err := ta.appAccountResolver(ctx, queryByAddress{ID: user.Address})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("could not read the account of the user")
+	}
 	var accountNumber, sequence uint64
 	if aa != nil {
 		accountNumber = aa.AccountNumber
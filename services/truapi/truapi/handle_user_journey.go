@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/logging"
 	"github.com/TruStory/octopus/services/truapi/truapi/render"
 )
 
@@ -101,7 +102,11 @@ func (ta *TruAPI) isOneArgumentStepComplete(ctx context.Context, user *db.User)
 	if user.Address == "" {
 		return false
 	}
-	arguments := ta.appAccountArgumentsResolver(ctx, queryByAddress{ID: user.Address})
+	arguments, err := ta.appAccountArgumentsResolver(ctx, queryByAddress{ID: user.Address})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("could not read the arguments of the user")
+		return false
+	}
 	return len(arguments) >= 1
 }
 
@@ -110,7 +115,11 @@ func (ta *TruAPI) isFiveAgreesStepComplete(ctx context.Context, user *db.User) b
 		return false
 	}
 
-	arguments := ta.appAccountArgumentsResolver(ctx, queryByAddress{ID: user.Address})
+	arguments, err := ta.appAccountArgumentsResolver(ctx, queryByAddress{ID: user.Address})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("could not read the arguments of the user")
+		return false
+	}
 
 	agreesReceived := 0
 	for _, argument := range arguments {
@@ -130,7 +139,11 @@ func (ta *TruAPI) isGivenOneAgreeStepComplete(ctx context.Context, user *db.User
 		return false
 	}
 
-	agrees := ta.agreesResolver(ctx, queryByAddress{ID: user.Address})
+	agrees, err := ta.agreesResolver(ctx, queryByAddress{ID: user.Address})
+	if err != nil {
+		logging.FromContext(ctx).WithError(err).Error("could not read the agrees of the user")
+		return false
+	}
 
 	return len(agrees) >= 1
 }
@@ -2,18 +2,14 @@ package truapi
 
 import (
 	"context"
-	"fmt"
 	"log"
-	"path"
 	"time"
 
-	"github.com/TruStory/truchain/x/bank/exported"
-	"github.com/TruStory/truchain/x/claim"
-	"github.com/TruStory/truchain/x/staking"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/go-pg/pg"
 
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/graphql"
 )
 
 // leaderboard defaults
@@ -24,8 +20,21 @@ const (
 	leaderboardDefaultInterval = 30
 	// display top 50
 	leaderboardDefaultTopDisplaying = 50
+	// users shown above and below the user's own rank
+	leaderboardDefaultNeighbors = 5
+	leaderboardMaxNeighbors     = 25
+	// days of rank history for sparklines
+	leaderboardDefaultHistoryDays = 30
+	leaderboardMaxHistoryDays     = 365
 )
 
+// leaderboardPeriodDays is the length in days of the rolling periods ranks are kept for,
+// all time ranks start at the beta release.
+var leaderboardPeriodDays = map[string]int{
+	db.LeaderboardPeriodWeek:  7,
+	db.LeaderboardPeriodMonth: 30,
+}
+
 type UserStatsByCommunity struct {
 	EarnedCoin     sdk.Int
 	Claims         int64
@@ -61,94 +70,29 @@ func (l *LeaderboardStats) getUserStatsByCommunity(address, communityID string)
 	return ucs
 }
 
+// statsByDate aggregates the activity indexed before the date.
 func (ta *TruAPI) statsByDate(date time.Time) (*LeaderboardStats, error) {
-	// Get all claims
-	claims := make([]claim.Claim, 0)
-	result, err := ta.Query(
-		path.Join(claim.QuerierRoute, claim.QueryClaimsBeforeTime),
-		claim.QueryClaimsTimeParams{CreatedTime: date},
-		claim.ModuleCodec,
-	)
+	err := ta.checkChainIndexedUntil(date)
 	if err != nil {
 		return nil, err
 	}
-	err = claim.ModuleCodec.UnmarshalJSON(result, &claims)
+	betaReleaseDate, err := time.Parse("2006-01-02", leaderboardInitialDate)
 	if err != nil {
 		return nil, err
 	}
-
-	// For each user, get the available stake calculated.
-	users := make([]db.User, 0)
-	err = ta.DBClient.FindAll(&users)
+	userCommunityStats, err := ta.DBClient.ChainUserCommunityStatsBefore(date, betaReleaseDate)
 	if err != nil {
 		return nil, err
 	}
 
 	stats := &LeaderboardStats{UserStats: make(map[string]*UserStats)}
-	for _, claim := range claims {
-		if !claim.CreatedTime.Before(date) {
-			continue
-		}
-		argumentCreatorsMappings := make(map[uint64]string)
-		ucs := stats.getUserStatsByCommunity(claim.Creator.String(), claim.CommunityID)
-		ucs.Claims++
-		arguments, err := ta.getClaimArguments(claim.ID)
-		if err != nil {
-			return nil, err
-		}
-		for _, argument := range arguments {
-			if !argument.CreatedTime.Before(date) {
-				continue
-			}
-			ucs := stats.getUserStatsByCommunity(argument.Creator.String(), claim.CommunityID)
-			ucs.Arguments++
-			argumentCreatorsMappings[argument.ID] = argument.Creator.String()
-		}
-		stakes := ta.claimStakesResolver(context.Background(), claim)
-		for _, stake := range stakes {
-			if !stake.CreatedTime.Before(date) {
-				continue
-			}
-			ucs := stats.getUserStatsByCommunity(stake.Creator.String(), claim.CommunityID)
-			if stake.Type == staking.StakeUpvote {
-				stats.getUserStatsByCommunity(argumentCreatorsMappings[stake.ArgumentID], claim.CommunityID).AgreesReceived++
-				ucs.AgreesGiven++
-			}
-		}
-	}
-	trackedTransactions := []exported.TransactionType{
-		exported.TransactionInterestArgumentCreation,
-		exported.TransactionInterestUpvoteReceived,
-		exported.TransactionInterestUpvoteGiven,
-	}
-	for _, user := range users {
-		if user.Address == "" || !user.CreatedAt.Before(date) {
-			continue
-		}
-		transactions := ta.appAccountTransactionsResolver(context.Background(), queryByAddress{ID: user.Address})
-		for _, transaction := range transactions {
-			if !transaction.CreatedTime.Before(date) {
-				continue
-			}
-			if !transaction.Type.OneOf(trackedTransactions) {
-				continue
-			}
-			if transaction.CommunityID == "" {
-				return nil, fmt.Errorf("transaction %s [%d] must contain community id",
-					transaction.Type.String(), transaction.ID)
-			}
-			ucs := stats.getUserStatsByCommunity(user.Address, transaction.CommunityID)
-			switch transaction.Type {
-			case exported.TransactionInterestArgumentCreation:
-				fallthrough
-			case exported.TransactionInterestUpvoteReceived:
-				fallthrough
-			case exported.TransactionInterestUpvoteGiven:
-				fallthrough
-			case exported.TransactionCuratorReward:
-				ucs.EarnedCoin = ucs.EarnedCoin.Add(transaction.Amount.Amount)
-			}
-		}
+	for _, s := range userCommunityStats {
+		ucs := stats.getUserStatsByCommunity(s.Address, s.CommunityID)
+		ucs.Claims = s.Claims
+		ucs.Arguments = s.Arguments
+		ucs.AgreesGiven = s.AgreesGiven
+		ucs.AgreesReceived = s.AgreesReceived
+		ucs.EarnedCoin = sdk.NewInt(s.Interest())
 	}
 	return stats, nil
 }
@@ -236,7 +180,45 @@ func (ta *TruAPI) leaderboardStatsBetween(start, end time.Time) error {
 		}
 		return nil
 	})
-	return nil
+	return err
+}
+
+// leaderboardPeriodStart returns the first day of the period ending on the date.
+func leaderboardPeriodStart(date time.Time, period string) (time.Time, error) {
+	days, ok := leaderboardPeriodDays[period]
+	if !ok {
+		return time.Parse("2006-01-02", leaderboardInitialDate)
+	}
+	return date.AddDate(0, 0, -(days - 1)), nil
+}
+
+// leaderboardPreviousDate returns the date ranks on the date are compared with, the end
+// of the previous period or the day before for all time ranks.
+func leaderboardPreviousDate(date time.Time, period string) time.Time {
+	days, ok := leaderboardPeriodDays[period]
+	if !ok {
+		days = 1
+	}
+	return date.AddDate(0, 0, -days)
+}
+
+// saveLeaderboardRanks ranks users for every period and metric ending on the date.
+func (ta *TruAPI) saveLeaderboardRanks(date time.Time) error {
+	date = getZeroHour(date)
+	return ta.DBClient.FeedLeaderboardInTransaction(func(tx *pg.Tx) error {
+		for _, period := range LeaderboardPeriodMapping {
+			since, err := leaderboardPeriodStart(date, period)
+			if err != nil {
+				return err
+			}
+			err = ta.DBClient.SaveLeaderboardRanks(tx, date, period, since,
+				LeaderboardMetricSortByMapping, ta.APIContext.Config.Community.InactiveCommunities)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func getZeroHour(t time.Time) time.Time {
@@ -267,12 +249,24 @@ func (ta *TruAPI) processStats() error {
 			return err
 		}
 		lastProcessedDateTime = date
+		err = ta.saveLeaderboardRanks(date)
+		if err != nil {
+			return err
+		}
 	}
 
 	if lastDate != nil {
 		lastProcessedDateTime = lastDate.Date
 	}
+	// metrics of the current day go up to the last indexed block
 	now := time.Now().UTC()
+	indexedTime, err := ta.chainIndexedTime()
+	if err != nil {
+		return err
+	}
+	if indexedTime.Before(now) {
+		now = indexedTime.UTC()
+	}
 	start := getZeroHour(now)
 	dateToProcess := lastProcessedDateTime.Add(time.Duration(24) * time.Hour)
 	for dateToProcess.Before(start) {
@@ -282,6 +276,10 @@ func (ta *TruAPI) processStats() error {
 		if err != nil {
 			return err
 		}
+		err = ta.saveLeaderboardRanks(s)
+		if err != nil {
+			return err
+		}
 		dateToProcess = dateToProcess.Add(time.Duration(24) * time.Hour)
 	}
 
@@ -291,6 +289,10 @@ func (ta *TruAPI) processStats() error {
 	if err != nil {
 		return err
 	}
+	err = ta.saveLeaderboardRanks(start)
+	if err != nil {
+		return err
+	}
 	log.Println("Completed leaderboard stats")
 	return nil
 }
@@ -324,7 +326,7 @@ type queryByDateAndMetricFilter struct {
 	Metric     LeaderboardMetricFilter `graphql:"metricFilter,optional"`
 }
 
-func (ta *TruAPI) leaderboardResolver(ctx context.Context, q queryByDateAndMetricFilter) []db.LeaderboardTopUser {
+func (ta *TruAPI) leaderboardResolver(ctx context.Context, q queryByDateAndMetricFilter) ([]db.LeaderboardTopUser, error) {
 	limit := leaderboardDefaultTopDisplaying
 	if ta.APIContext.Config.Leaderboard.TopDisplaying > 0 {
 		limit = ta.APIContext.Config.Leaderboard.TopDisplaying
@@ -337,7 +339,112 @@ func (ta *TruAPI) leaderboardResolver(ctx context.Context, q queryByDateAndMetri
 	sortBy := q.Metric.Value()
 	topUsers, err := ta.DBClient.Leaderboard(since, sortBy, limit, ta.APIContext.Config.Community.InactiveCommunities, "")
 	if err != nil {
-		log.Println("couldn't get leaderboard results", err)
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
+	}
+	return topUsers, nil
+}
+
+type queryLeaderboardRanks struct {
+	Period      LeaderboardPeriodFilter `graphql:"periodFilter,optional"`
+	Metric      LeaderboardMetricFilter `graphql:"metricFilter,optional"`
+	CommunityID string                  `graphql:"communityId,optional"`
+	Limit       int64                   `graphql:"limit,optional"`
+	Offset      int64                   `graphql:"offset,optional"`
+}
+
+type queryLeaderboardRankByAddress struct {
+	Address     string                  `graphql:"address"`
+	Period      LeaderboardPeriodFilter `graphql:"periodFilter,optional"`
+	Metric      LeaderboardMetricFilter `graphql:"metricFilter,optional"`
+	CommunityID string                  `graphql:"communityId,optional"`
+	// Count is the number of neighbors on each side, or days of history
+	Count int64 `graphql:"count,optional"`
+}
+
+// lastLeaderboardRankDates returns the date of the latest ranks and the date they're compared with,
+// ok is false when no ranks were saved yet.
+func (ta *TruAPI) lastLeaderboardRankDates(period string) (date, previousDate time.Time, ok bool, err error) {
+	lastDate, err := ta.DBClient.LastLeaderboardRankDate()
+	if err != nil {
+		return date, previousDate, false, graphql.NewError(graphql.CodeUnavailable, err)
+	}
+	if lastDate == nil {
+		return date, previousDate, false, nil
+	}
+	return *lastDate, leaderboardPreviousDate(*lastDate, period), true, nil
+}
+
+func (ta *TruAPI) leaderboardRanksResolver(ctx context.Context, q queryLeaderboardRanks) ([]db.LeaderboardRankEntry, error) {
+	period := q.Period.Value()
+	date, previousDate, ok, err := ta.lastLeaderboardRankDates(period)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return []db.LeaderboardRankEntry{}, nil
+	}
+	limit := leaderboardDefaultTopDisplaying
+	if ta.APIContext.Config.Leaderboard.TopDisplaying > 0 {
+		limit = ta.APIContext.Config.Leaderboard.TopDisplaying
+	}
+	if q.Limit > 0 && int(q.Limit) < limit {
+		limit = int(q.Limit)
+	}
+	offset := 0
+	if q.Offset > 0 {
+		offset = int(q.Offset)
+	}
+	ranks, err := ta.DBClient.LeaderboardRanks(date, previousDate, period, q.Metric.Value(), q.CommunityID, limit, offset)
+	if err != nil {
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
+	}
+	return ranks, nil
+}
+
+func (ta *TruAPI) leaderboardNeighborsResolver(ctx context.Context, q queryLeaderboardRankByAddress) ([]db.LeaderboardRankEntry, error) {
+	period := q.Period.Value()
+	date, previousDate, ok, err := ta.lastLeaderboardRankDates(period)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return []db.LeaderboardRankEntry{}, nil
+	}
+	count := leaderboardDefaultNeighbors
+	if q.Count > 0 {
+		count = int(q.Count)
+	}
+	if count > leaderboardMaxNeighbors {
+		count = leaderboardMaxNeighbors
+	}
+	ranks, err := ta.DBClient.LeaderboardRankNeighbors(date, previousDate, period, q.Metric.Value(), q.CommunityID, q.Address, count)
+	if err != nil {
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
+	}
+	return ranks, nil
+}
+
+func (ta *TruAPI) leaderboardRankHistoryResolver(ctx context.Context, q queryLeaderboardRankByAddress) ([]db.LeaderboardRank, error) {
+	days := leaderboardDefaultHistoryDays
+	if q.Count > 0 {
+		days = int(q.Count)
+	}
+	if days > leaderboardMaxHistoryDays {
+		days = leaderboardMaxHistoryDays
+	}
+	since := getZeroHour(time.Now().UTC()).AddDate(0, 0, -(days - 1))
+	ranks, err := ta.DBClient.LeaderboardRankHistory(q.Address, q.Period.Value(), q.Metric.Value(), q.CommunityID, since)
+	if err != nil {
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
+	}
+	return ranks, nil
+}
+
+// leaderboardRankMovement is the number of places moved up since the previous period,
+// negative when moving down and 0 for users who weren't ranked.
+func leaderboardRankMovement(rank, previousRank int64) int64 {
+	if previousRank == 0 {
+		return 0
 	}
-	return topUsers
+	return previousRank - rank
 }
@@ -0,0 +1,36 @@
+package truapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+func TestLeaderboardPeriods(t *testing.T) {
+	date := time.Date(2019, 9, 30, 0, 0, 0, 0, time.UTC)
+
+	since, err := leaderboardPeriodStart(date, db.LeaderboardPeriodWeek)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 9, 24, 0, 0, 0, 0, time.UTC), since)
+	assert.Equal(t, time.Date(2019, 9, 23, 0, 0, 0, 0, time.UTC), leaderboardPreviousDate(date, db.LeaderboardPeriodWeek))
+
+	since, err = leaderboardPeriodStart(date, db.LeaderboardPeriodMonth)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 9, 1, 0, 0, 0, 0, time.UTC), since)
+	assert.Equal(t, time.Date(2019, 8, 31, 0, 0, 0, 0, time.UTC), leaderboardPreviousDate(date, db.LeaderboardPeriodMonth))
+
+	// all time ranks start at the beta release and compare with the day before
+	since, err = leaderboardPeriodStart(date, db.LeaderboardPeriodAllTime)
+	assert.NoError(t, err)
+	assert.Equal(t, leaderboardInitialDate, since.Format("2006-01-02"))
+	assert.Equal(t, time.Date(2019, 9, 29, 0, 0, 0, 0, time.UTC), leaderboardPreviousDate(date, db.LeaderboardPeriodAllTime))
+}
+
+func TestLeaderboardRankMovement(t *testing.T) {
+	assert.Equal(t, int64(3), leaderboardRankMovement(2, 5))
+	assert.Equal(t, int64(-1), leaderboardRankMovement(6, 5))
+	assert.Equal(t, int64(0), leaderboardRankMovement(4, 0))
+}
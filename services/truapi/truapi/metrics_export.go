@@ -0,0 +1,231 @@
+package truapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/truapi/render"
+)
+
+// maxMetricsDays is the longest range of days a metrics export can cover,
+// every day is computed from scratch
+const maxMetricsDays = 31
+
+// metricsFormat is the format of a metrics export
+type metricsFormat string
+
+const (
+	metricsFormatCSV    metricsFormat = "csv"
+	metricsFormatJSON   metricsFormat = "json"
+	metricsFormatNDJSON metricsFormat = "ndjson"
+)
+
+var metricsContentTypes = map[metricsFormat]string{
+	metricsFormatCSV:    "text/csv",
+	metricsFormatJSON:   "application/json",
+	metricsFormatNDJSON: "application/x-ndjson",
+}
+
+// metricsParams are the parameters shared by the metrics exports
+type metricsParams struct {
+	// From and To are the first and last days exported, the metrics of a day are before its start
+	From      time.Time
+	To        time.Time
+	Community string
+	Format    metricsFormat
+}
+
+// parseMetricsParams reads the days of an export from `from` and `to`, or `date` for a single day,
+// along with the optional `community` and `format` parameters.
+func parseMetricsParams(r *http.Request) (metricsParams, error) {
+	params := metricsParams{
+		Community: r.FormValue("community"),
+		Format:    metricsFormat(strings.ToLower(r.FormValue("format"))),
+	}
+	if params.Format == "" {
+		params.Format = metricsFormatCSV
+	}
+	if _, ok := metricsContentTypes[params.Format]; !ok {
+		return params, fmt.Errorf("invalid format %s, must be csv, json or ndjson", params.Format)
+	}
+	from, to := r.FormValue("from"), r.FormValue("to")
+	if date := r.FormValue("date"); date != "" {
+		from, to = date, date
+	}
+	if from == "" {
+		return params, fmt.Errorf("provide a valid date or from and to dates")
+	}
+	if to == "" {
+		to = from
+	}
+	var err error
+	params.From, err = time.Parse("2006-01-02", from)
+	if err != nil {
+		return params, fmt.Errorf("invalid date %s", from)
+	}
+	params.To, err = time.Parse("2006-01-02", to)
+	if err != nil {
+		return params, fmt.Errorf("invalid date %s", to)
+	}
+	if params.To.Before(params.From) {
+		return params, fmt.Errorf("to must not be before from")
+	}
+	if len(params.Dates()) > maxMetricsDays {
+		return params, fmt.Errorf("a range can't be longer than %d days", maxMetricsDays)
+	}
+	return params, nil
+}
+
+// Dates returns every day from From to To
+func (p metricsParams) Dates() []time.Time {
+	dates := make([]time.Time, 0)
+	for d := p.From; !d.After(p.To); d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d)
+	}
+	return dates
+}
+
+// Filename names the file of an export
+func (p metricsParams) Filename(name string) string {
+	if p.From.Equal(p.To) {
+		return fmt.Sprintf("%s_%s.%s", name, p.From.Format("2006-01-02"), p.Format)
+	}
+	return fmt.Sprintf("%s_%s_%s.%s", name, p.From.Format("2006-01-02"), p.To.Format("2006-01-02"), p.Format)
+}
+
+// metricsExport streams the rows of an export to the client one at a time.
+// Nothing is written until the first row, so errors before it are still rendered as such.
+// JSON and NDJSON rows are objects with the columns of the header in order, numbers are
+// written as JSON numbers, use json.Number for amounts that don't fit in an int64.
+type metricsExport struct {
+	w        http.ResponseWriter
+	format   metricsFormat
+	filename string
+	header   []string
+	csv      *csv.Writer
+	rows     int
+	started  bool
+}
+
+func newMetricsExport(w http.ResponseWriter, params metricsParams, name string, header []string) *metricsExport {
+	return &metricsExport{
+		w:        w,
+		format:   params.Format,
+		filename: params.Filename(name),
+		header:   header,
+	}
+}
+
+func (e *metricsExport) start() error {
+	e.started = true
+	e.w.Header().Set("Content-Type", metricsContentTypes[e.format])
+	e.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.filename))
+	switch e.format {
+	case metricsFormatCSV:
+		e.csv = csv.NewWriter(e.w)
+		return e.csv.Write(e.header)
+	case metricsFormatJSON:
+		_, err := e.w.Write([]byte("["))
+		return err
+	}
+	return nil
+}
+
+// Write writes a row and flushes it to the client
+func (e *metricsExport) Write(row []interface{}) error {
+	if len(row) != len(e.header) {
+		return fmt.Errorf("header and row content mismatch")
+	}
+	if !e.started {
+		err := e.start()
+		if err != nil {
+			return err
+		}
+	}
+	var err error
+	switch e.format {
+	case metricsFormatCSV:
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = fmt.Sprint(value)
+		}
+		err = e.csv.Write(record)
+		e.csv.Flush()
+		if err == nil {
+			err = e.csv.Error()
+		}
+	default:
+		err = e.writeObject(row)
+	}
+	if err != nil {
+		return err
+	}
+	e.rows++
+	if f, ok := e.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// writeObject writes a row as an object, by hand as a map would sort the columns
+func (e *metricsExport) writeObject(row []interface{}) error {
+	b := []byte("{")
+	for i, column := range e.header {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		key, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(row[i])
+		if err != nil {
+			return err
+		}
+		b = append(b, key...)
+		b = append(b, ':')
+		b = append(b, value...)
+	}
+	b = append(b, '}')
+	switch {
+	case e.format == metricsFormatNDJSON:
+		b = append(b, '\n')
+	case e.rows > 0:
+		b = append([]byte(","), b...)
+	}
+	_, err := e.w.Write(b)
+	return err
+}
+
+// Close finishes the export, writing the header of an export without rows
+func (e *metricsExport) Close() error {
+	if !e.started {
+		err := e.start()
+		if err != nil {
+			return err
+		}
+	}
+	switch e.format {
+	case metricsFormatCSV:
+		e.csv.Flush()
+		return e.csv.Error()
+	case metricsFormatJSON:
+		_, err := e.w.Write([]byte("]"))
+		return err
+	}
+	return nil
+}
+
+// Error renders an error if the export didn't start, the response is cut short otherwise
+func (e *metricsExport) Error(r *http.Request, err error, code int) {
+	if !e.started {
+		render.Error(e.w, r, err.Error(), code)
+		return
+	}
+	log.Printf("metrics export %s failed after %d rows: %s\n", e.filename, e.rows, err)
+}
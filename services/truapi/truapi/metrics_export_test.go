@@ -0,0 +1,64 @@
+package truapi
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMetricsParams(t *testing.T) {
+	r := httptest.NewRequest("GET", "/metrics/users?date=2019-11-01", nil)
+	params, err := parseMetricsParams(r)
+	assert.NoError(t, err)
+	assert.Len(t, params.Dates(), 1)
+	assert.Equal(t, metricsFormatCSV, params.Format)
+	assert.Equal(t, "users_2019-11-01.csv", params.Filename("users"))
+
+	r = httptest.NewRequest("GET", "/metrics/users?from=2019-10-30&to=2019-11-02&community=crypto&format=ndjson", nil)
+	params, err = parseMetricsParams(r)
+	assert.NoError(t, err)
+	assert.Len(t, params.Dates(), 4)
+	assert.Equal(t, "crypto", params.Community)
+	assert.Equal(t, "users_2019-10-30_2019-11-02.ndjson", params.Filename("users"))
+
+	for _, query := range []string{
+		"",
+		"date=11-01-2019",
+		"from=2019-11-02&to=2019-11-01",
+		"from=2019-01-01&to=2019-03-01",
+		"date=2019-11-01&format=xml",
+	} {
+		_, err = parseMetricsParams(httptest.NewRequest("GET", "/metrics/users?"+query, nil))
+		assert.Error(t, err, query)
+	}
+}
+
+func TestMetricsExport(t *testing.T) {
+	// columns keep the order of the header and numbers are written as such
+	header := []string{"name", "id", "amount"}
+	rows := [][]interface{}{{"a, b", 1, json.Number("12345678901234567890")}, {"c", int64(2), json.Number("0")}}
+	expected := map[metricsFormat]string{
+		metricsFormatCSV:    "name,id,amount\n\"a, b\",1,12345678901234567890\nc,2,0\n",
+		metricsFormatJSON:   `[{"name":"a, b","id":1,"amount":12345678901234567890},{"name":"c","id":2,"amount":0}]`,
+		metricsFormatNDJSON: "{\"name\":\"a, b\",\"id\":1,\"amount\":12345678901234567890}\n{\"name\":\"c\",\"id\":2,\"amount\":0}\n",
+	}
+	for format, body := range expected {
+		w := httptest.NewRecorder()
+		export := newMetricsExport(w, metricsParams{Format: format}, "claims", header)
+		for _, row := range rows {
+			assert.NoError(t, export.Write(row))
+		}
+		assert.NoError(t, export.Close())
+		assert.Equal(t, body, w.Body.String())
+		assert.Equal(t, metricsContentTypes[format], w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+	}
+
+	w := httptest.NewRecorder()
+	export := newMetricsExport(w, metricsParams{Format: metricsFormatJSON}, "claims", header)
+	assert.Error(t, export.Write([]interface{}{"1"}))
+	assert.NoError(t, export.Close())
+	assert.Equal(t, "[]", w.Body.String())
+}
@@ -0,0 +1,48 @@
+package truapi
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter allows each client a number of requests in a sliding window.
+// It is kept in memory, so every replica counts the requests it receives.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	hits   map[string][]time.Time
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:  limit,
+		window: window,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// allow records a request of client at now, reporting whether it is within the limit.
+func (l *rateLimiter) allow(client string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// forget the clients that stopped sending requests so the map doesn't grow forever
+	for key, hits := range l.hits {
+		if !now.Before(hits[len(hits)-1].Add(l.window)) {
+			delete(l.hits, key)
+		}
+	}
+	hits := l.hits[client]
+	recent := hits[:0]
+	for _, hit := range hits {
+		if now.Before(hit.Add(l.window)) {
+			recent = append(recent, hit)
+		}
+	}
+	if len(recent) >= l.limit {
+		l.hits[client] = recent
+		return false
+	}
+	l.hits[client] = append(recent, now)
+	return true
+}
@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/TruStory/octopus/services/truapi/db"
+	"github.com/TruStory/octopus/services/truapi/graphql"
+	"github.com/TruStory/octopus/services/truapi/logging"
 	"github.com/TruStory/octopus/services/truapi/truapi/cookies"
 	app "github.com/TruStory/truchain/types"
 	"github.com/TruStory/truchain/x/account"
@@ -27,6 +29,9 @@ import (
 	stripmd "github.com/writeas/go-strip-markdown"
 )
 
+// errLoadersMissing is returned by resolvers batching their queries outside of a request
+var errLoadersMissing = errors.New("loaders not present")
+
 type queryByCommunityID struct {
 	CommunityID string `graphql:"communityId"`
 }
@@ -114,12 +119,11 @@ func (ta *TruAPI) accountQuery(ctx context.Context, addrStr string) (authexporte
 
 	addr, err := sdk.AccAddressFromBech32(addrStr)
 	if err != nil {
-		return nil, err
+		return nil, graphql.NewError(graphql.CodeBadRequest, err)
 	}
 	res, err := ta.Query(queryRoute, auth.QueryAccountParams{Address: addr}, auth.ModuleCdc)
 	if err != nil {
-		fmt.Println("accountResolver err: ", err)
-		return nil, err
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 	var acc authexported.Account
 	err = auth.ModuleCdc.UnmarshalJSON(res, &acc)
@@ -166,18 +170,20 @@ func (ta *TruAPI) appAccountsResolver(ctx context.Context, addresses []sdk.AccAd
 	return accounts, nil
 }
 
-func (ta *TruAPI) appAccountResolver(ctx context.Context, q queryByAddress) *AppAccount {
+func (ta *TruAPI) appAccountResolver(ctx context.Context, q queryByAddress) (*AppAccount, error) {
 	l, ok := getDataLoaders(ctx)
 	if !ok {
-		fmt.Println("loaders not present")
-		return nil
+		return nil, errLoadersMissing
+	}
+	_, err := sdk.AccAddressFromBech32(q.ID)
+	if err != nil {
+		return nil, graphql.NewError(graphql.CodeBadRequest, err)
 	}
 	appAccount, err := l.appAccountLoader.Load(q.ID)
 	if err != nil {
-		return nil
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
-	return appAccount
-
+	return appAccount, nil
 }
 
 // deprecated, use userProfileResolver instead
@@ -187,67 +193,68 @@ func (ta *TruAPI) twitterProfileResolver(ctx context.Context, addr string) db.Tw
 		return db.TwitterProfile{}
 	}
 	if err != nil {
-		// TODO [shanev]: Add back after adding error handling to resolvers
-		// fmt.Println("Resolver err: ", err)
+		logging.FromContext(ctx).WithError(err).Error("could not read twitter profile")
 		return db.TwitterProfile{}
 	}
 
 	return *twitterProfile
 }
 
-func (ta *TruAPI) userProfileResolver(ctx context.Context, addr string) *db.UserProfile {
+func (ta *TruAPI) userProfileResolver(ctx context.Context, addr string) (*db.UserProfile, error) {
 	loaders, ok := getDataLoaders(ctx)
 	if !ok {
-		return nil
+		return nil, errLoadersMissing
 	}
 	profile, err := loaders.userProfileLoader.Load(addr)
 	if err != nil {
-		return nil
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
-	return profile
+	return profile, nil
 }
 
-func (ta *TruAPI) userResolver(ctx context.Context, addr string) *db.User {
+func (ta *TruAPI) userResolver(ctx context.Context, addr string) (*db.User, error) {
 	user, err := ta.DBClient.UserByAddress(addr)
 	if err != nil {
-		fmt.Println("userResolver err: ", err)
-		return nil
+		return nil, err
 	}
 
-	return user
+	return user, nil
 }
 
-func (ta *TruAPI) earnedBalanceResolver(ctx context.Context, q queryByAddress) sdk.Coin {
-	earnedCoins := ta.earnedStakeResolver(ctx, q)
+func (ta *TruAPI) earnedBalanceResolver(ctx context.Context, q queryByAddress) (sdk.Coin, error) {
+	earnedCoins, err := ta.earnedStakeResolver(ctx, q)
+	if err != nil {
+		return sdk.Coin{}, err
+	}
 	balance := sdk.ZeroInt()
 	for _, coin := range earnedCoins {
 		balance = balance.Add(coin.Coin.Amount)
 	}
-	return sdk.NewCoin(app.StakeDenom, balance)
+	return sdk.NewCoin(app.StakeDenom, balance), nil
 }
 
-func (ta *TruAPI) earnedStakeResolver(ctx context.Context, q queryByAddress) []EarnedCoin {
+func (ta *TruAPI) earnedStakeResolver(ctx context.Context, q queryByAddress) ([]EarnedCoin, error) {
 	address, err := sdk.AccAddressFromBech32(q.ID)
 	if err != nil {
-		fmt.Println("earnedStakeResolver err: ", err)
-		return []EarnedCoin{}
+		return nil, graphql.NewError(graphql.CodeBadRequest, err)
 	}
 
 	queryRoute := path.Join(staking.QuerierRoute, staking.QueryEarnedCoins)
 	res, err := ta.Query(queryRoute, staking.QueryEarnedCoinsParams{Address: address}, staking.ModuleCodec)
 	if err != nil {
-		fmt.Println("earnedStakeResolver err: ", err)
-		return []EarnedCoin{}
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	coins := new(sdk.Coins)
 	err = staking.ModuleCodec.UnmarshalJSON(res, coins)
 	if err != nil {
-		fmt.Println("earnedCoin UnmarshalJSON err: ", err)
-		return []EarnedCoin{}
+		return nil, err
 	}
 
-	communities := ta.communitiesResolver(ctx)
+	communities, err := ta.communitiesResolver(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	earnedCoins := make([]EarnedCoin, 0)
 	for _, community := range communities {
@@ -257,28 +264,25 @@ func (ta *TruAPI) earnedStakeResolver(ctx context.Context, q queryByAddress) []E
 		})
 	}
 
-	return earnedCoins
+	return earnedCoins, nil
 }
 
-func (ta *TruAPI) pendingBalanceResolver(ctx context.Context, q queryByAddress) sdk.Coin {
+func (ta *TruAPI) pendingBalanceResolver(ctx context.Context, q queryByAddress) (sdk.Coin, error) {
 	address, err := sdk.AccAddressFromBech32(q.ID)
 	if err != nil {
-		fmt.Println("pendingBalanceResolver err: ", err)
-		return sdk.Coin{}
+		return sdk.Coin{}, graphql.NewError(graphql.CodeBadRequest, err)
 	}
 
 	queryRoute := path.Join(staking.QuerierRoute, staking.QueryUserStakes)
 	res, err := ta.Query(queryRoute, staking.QueryUserStakesParams{Address: address}, staking.ModuleCodec)
 	if err != nil {
-		fmt.Println("pendingBalanceResolver err: ", err)
-		return sdk.Coin{}
+		return sdk.Coin{}, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	stakes := make([]staking.Stake, 0)
 	err = staking.ModuleCodec.UnmarshalJSON(res, &stakes)
 	if err != nil {
-		fmt.Println("stakes UnmarshalJSON err: ", err)
-		return sdk.Coin{}
+		return sdk.Coin{}, err
 	}
 
 	balance := sdk.NewCoin(app.StakeDenom, sdk.ZeroInt())
@@ -288,32 +292,32 @@ func (ta *TruAPI) pendingBalanceResolver(ctx context.Context, q queryByAddress)
 		}
 	}
 
-	return balance
+	return balance, nil
 }
 
-func (ta *TruAPI) pendingStakeResolver(ctx context.Context, q queryByAddress) []EarnedCoin {
+func (ta *TruAPI) pendingStakeResolver(ctx context.Context, q queryByAddress) ([]EarnedCoin, error) {
 	address, err := sdk.AccAddressFromBech32(q.ID)
 	if err != nil {
-		fmt.Println("pendingStakeResolver err: ", err)
-		return []EarnedCoin{}
+		return nil, graphql.NewError(graphql.CodeBadRequest, err)
 	}
 
-	communities := ta.communitiesResolver(ctx)
+	communities, err := ta.communitiesResolver(ctx)
+	if err != nil {
+		return nil, err
+	}
 	pendingStakes := make([]EarnedCoin, 0)
 
 	for _, community := range communities {
 		queryRoute := path.Join(staking.QuerierRoute, staking.QueryUserCommunityStakes)
 		res, err := ta.Query(queryRoute, staking.QueryUserCommunityStakesParams{Address: address, CommunityID: community.ID}, staking.ModuleCodec)
 		if err != nil {
-			fmt.Println("pendingStakeResolver err: ", err)
-			return []EarnedCoin{}
+			return nil, graphql.NewError(graphql.CodeUnavailable, err)
 		}
 
 		stakes := make([]staking.Stake, 0)
 		err = staking.ModuleCodec.UnmarshalJSON(res, &stakes)
 		if err != nil {
-			fmt.Println("stake UnmarshalJSON err: ", err)
-			return []EarnedCoin{}
+			return nil, err
 		}
 
 		total := sdk.ZeroInt()
@@ -332,22 +336,20 @@ func (ta *TruAPI) pendingStakeResolver(ctx context.Context, q queryByAddress) []
 		})
 	}
 
-	return pendingStakes
+	return pendingStakes, nil
 }
 
-func (ta *TruAPI) communitiesResolver(ctx context.Context) []community.Community {
+func (ta *TruAPI) communitiesResolver(ctx context.Context) ([]community.Community, error) {
 	queryRoute := path.Join(community.QuerierRoute, community.QueryCommunities)
 	res, err := ta.Query(queryRoute, struct{}{}, community.ModuleCodec)
 	if err != nil {
-		fmt.Println("communitiesResolver err: ", err)
-		return []community.Community{}
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	cs := make([]community.Community, 0)
 	err = community.ModuleCodec.UnmarshalJSON(res, &cs)
 	if err != nil {
-		fmt.Println("community UnmarshalJSON err: ", err)
-		return []community.Community{}
+		return nil, err
 	}
 
 	// sort in alphabetical order
@@ -363,23 +365,22 @@ func (ta *TruAPI) communitiesResolver(ctx context.Context) []community.Community
 		}
 	}
 
-	return filteredCommunities
+	return filteredCommunities, nil
 }
 
-func (ta *TruAPI) communityResolver(ctx context.Context, q queryByCommunityID) *community.Community {
+func (ta *TruAPI) communityResolver(ctx context.Context, q queryByCommunityID) (*community.Community, error) {
 	queryRoute := path.Join(community.QuerierRoute, community.QueryCommunity)
 	res, err := ta.Query(queryRoute, community.QueryCommunityParams{ID: q.CommunityID}, community.ModuleCodec)
 	if err != nil {
-		fmt.Println("getCommunityByIDResolver err: ", err)
-		return nil
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	c := new(community.Community)
 	err = community.ModuleCodec.UnmarshalJSON(res, c)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-	return c
+	return c, nil
 }
 
 func (ta *TruAPI) communityIconImageResolver(ctx context.Context, q community.Community) CommunityIconImage {
@@ -405,7 +406,7 @@ func (ta *TruAPI) followedCommunityIDs(ctx context.Context) ([]string, error) {
 	return followedCommunityIDs, nil
 }
 
-func (ta *TruAPI) claimsResolver(ctx context.Context, q queryByCommunityIDAndFeedFilter) []claim.Claim {
+func (ta *TruAPI) claimsResolver(ctx context.Context, q queryByCommunityIDAndFeedFilter) ([]claim.Claim, error) {
 	var res []byte
 	var err error
 
@@ -416,7 +417,7 @@ func (ta *TruAPI) claimsResolver(ctx context.Context, q queryByCommunityIDAndFee
 	case "home":
 		communityIDs, cErr := ta.followedCommunityIDs(ctx)
 		if cErr != nil {
-			return []claim.Claim{}
+			return []claim.Claim{}, nil
 		}
 		queryRoute := path.Join(claim.QuerierRoute, claim.QueryCommunitiesClaims)
 		res, err = ta.Query(queryRoute, claim.QueryCommunitiesClaimsParams{CommunityIDs: communityIDs}, claim.ModuleCodec)
@@ -426,14 +427,13 @@ func (ta *TruAPI) claimsResolver(ctx context.Context, q queryByCommunityIDAndFee
 	}
 
 	if err != nil {
-		fmt.Println("claimsResolver err: ", err)
-		return []claim.Claim{}
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	claims := make([]claim.Claim, 0)
 	err = claim.ModuleCodec.UnmarshalJSON(res, &claims)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	if !q.IsSearch {
@@ -442,47 +442,47 @@ func (ta *TruAPI) claimsResolver(ctx context.Context, q queryByCommunityIDAndFee
 
 	unflaggedClaims, err := ta.filterFlaggedClaims(claims)
 	if err != nil {
-		fmt.Println("filterFlaggedClaims err: ", err)
-		panic(err)
+		return nil, err
 	}
 
 	filteredClaims := ta.filterFeedClaims(ctx, unflaggedClaims, q.FeedFilter)
 
-	return filteredClaims
+	return filteredClaims, nil
 }
 
-func (ta *TruAPI) claimResolver(ctx context.Context, q queryByClaimID) claim.Claim {
+func (ta *TruAPI) claimResolver(ctx context.Context, q queryByClaimID) (claim.Claim, error) {
 	queryRoute := path.Join(claim.QuerierRoute, claim.QueryClaim)
 	res, err := ta.Query(queryRoute, claim.QueryClaimParams{ID: q.ID}, claim.ModuleCodec)
 	if err != nil {
-		fmt.Println("claimResolver err: ", err)
-		return claim.Claim{}
+		return claim.Claim{}, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	c := new(claim.Claim)
 	err = claim.ModuleCodec.UnmarshalJSON(res, c)
 	if err != nil {
-		fmt.Println("claim UnmarshalJSON err: ", err)
-		return claim.Claim{}
+		return claim.Claim{}, err
 	}
 
-	return *c
+	return *c, nil
 }
 
-func (ta *TruAPI) claimOfTheDayResolver(ctx context.Context, q queryByCommunityID) *claim.Claim {
+func (ta *TruAPI) claimOfTheDayResolver(ctx context.Context, q queryByCommunityID) (*claim.Claim, error) {
 	communityID := q.CommunityID
 	claimOfTheDayID, err := ta.DBClient.ClaimOfTheDayIDByCommunityID(communityID)
 	if err != nil {
-		return nil
+		return nil, nil
 	}
 
-	claim := ta.claimResolver(ctx, queryByClaimID{ID: uint64(claimOfTheDayID)})
+	claim, err := ta.claimResolver(ctx, queryByClaimID{ID: uint64(claimOfTheDayID)})
+	if err != nil {
+		return nil, err
+	}
 
 	if claim.ID == 0 {
-		return nil
+		return nil, nil
 	}
 
-	return &claim
+	return &claim, nil
 }
 
 func (ta *TruAPI) removeClaimOfTheDay(claims []claim.Claim, communityID string) []claim.Claim {
@@ -518,19 +518,17 @@ func (ta *TruAPI) filterFlaggedClaims(claims []claim.Claim) ([]claim.Claim, erro
 	return unflaggedClaims, nil
 }
 
-func (ta *TruAPI) claimArgumentsResolver(ctx context.Context, q queryClaimArgumentParams) []staking.Argument {
+func (ta *TruAPI) claimArgumentsResolver(ctx context.Context, q queryClaimArgumentParams) ([]staking.Argument, error) {
 	queryRoute := path.Join(staking.ModuleName, staking.QueryClaimArguments)
 	res, err := ta.Query(queryRoute, staking.QueryClaimArgumentsParams{ClaimID: q.ClaimID}, staking.ModuleCodec)
 	if err != nil {
-		fmt.Println("claimArgumentsResolver err: ", err)
-		return []staking.Argument{}
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	arguments := make([]staking.Argument, 0)
 	err = staking.ModuleCodec.UnmarshalJSON(res, &arguments)
 	if err != nil {
-		fmt.Println("[]staking.Argument UnmarshalJSON err: ", err)
-		return []staking.Argument{}
+		return nil, err
 	}
 	filteredArguments := make([]staking.Argument, 0)
 	for _, argument := range arguments {
@@ -539,7 +537,10 @@ func (ta *TruAPI) claimArgumentsResolver(ctx context.Context, q queryClaimArgume
 				filteredArguments = append(filteredArguments, argument)
 			}
 		} else if q.Filter == ArgumentAgreed {
-			stakes := ta.claimArgumentStakesResolver(ctx, argument)
+			stakes, err := ta.claimArgumentStakesResolver(ctx, argument)
+			if err != nil {
+				return nil, err
+			}
 			for _, stake := range stakes {
 				if stake.Creator.String() == *q.Address && stake.Type == staking.StakeUpvote {
 					filteredArguments = append(filteredArguments, argument)
@@ -575,66 +576,72 @@ func (ta *TruAPI) claimArgumentsResolver(ctx context.Context, q queryClaimArgume
 		})
 	}
 	resultArguments = append(resultArguments, unhelpful...)
-	return resultArguments
+	return resultArguments, nil
 }
 
-func (ta *TruAPI) claimArgumentResolver(ctx context.Context, q queryByArgumentID) *staking.Argument {
+func (ta *TruAPI) claimArgumentResolver(ctx context.Context, q queryByArgumentID) (*staking.Argument, error) {
 	queryRoute := path.Join(staking.ModuleName, staking.QueryClaimArgument)
 	res, err := ta.Query(queryRoute, staking.QueryClaimArgumentParams{ArgumentID: q.ID}, staking.ModuleCodec)
 	if err != nil {
-		fmt.Println("claimArgumentResolver err: ", err)
-		return nil
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	argument := new(staking.Argument)
 	err = staking.ModuleCodec.UnmarshalJSON(res, argument)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
-	return argument
+	return argument, nil
 }
 
-func (ta *TruAPI) topArgumentResolver(ctx context.Context, q claim.Claim) *staking.Argument {
+func (ta *TruAPI) topArgumentResolver(ctx context.Context, q claim.Claim) (*staking.Argument, error) {
 	queryRoute := path.Join(staking.ModuleName, staking.QueryClaimTopArgument)
 	res, err := ta.Query(queryRoute, staking.QueryClaimTopArgumentParams{ClaimID: q.ID}, staking.ModuleCodec)
 	if err != nil {
-		fmt.Println("topArgumentResolver err: ", err)
-		return nil
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	argument := new(staking.Argument)
 	err = staking.ModuleCodec.UnmarshalJSON(res, argument)
 	if err != nil {
-		fmt.Println("staking.Argument UnmarshalJSON err: ", err)
-		return nil
+		return nil, err
 	}
 
 	// no top argument
 	if argument.ID == 0 {
-		return nil
+		return nil, nil
 	}
 
-	return argument
+	return argument, nil
 }
 
 // returns all argument writer and upvoter stakes on a claim
-func (ta *TruAPI) claimStakesResolver(ctx context.Context, q claim.Claim) []staking.Stake {
+func (ta *TruAPI) claimStakesResolver(ctx context.Context, q claim.Claim) ([]staking.Stake, error) {
 	stakes := make([]staking.Stake, 0)
-	arguments := ta.claimArgumentsResolver(ctx, queryClaimArgumentParams{ClaimID: q.ID})
+	arguments, err := ta.claimArgumentsResolver(ctx, queryClaimArgumentParams{ClaimID: q.ID})
+	if err != nil {
+		return nil, err
+	}
 	for _, argument := range arguments {
-		stakes = append(stakes, ta.claimArgumentStakesResolver(ctx, argument)...)
+		argumentStakes, err := ta.claimArgumentStakesResolver(ctx, argument)
+		if err != nil {
+			return nil, err
+		}
+		stakes = append(stakes, argumentStakes...)
 	}
-	return stakes
+	return stakes, nil
 }
 
-func (ta *TruAPI) claimParticipantsResolver(ctx context.Context, q claim.Claim) []AppAccount {
+func (ta *TruAPI) claimParticipantsResolver(ctx context.Context, q claim.Claim) ([]AppAccount, error) {
 	loaders, ok := getDataLoaders(ctx)
 	if !ok {
-		fmt.Println("loaders not present")
-		return nil
+		return nil, errLoadersMissing
+	}
+	stakes, err := ta.claimStakesResolver(ctx, q)
+	if err != nil {
+		return nil, err
 	}
-	stakes := ta.claimStakesResolver(ctx, q)
 	comments, _ := ta.DBClient.CommentsByClaimID(q.ID)
 
 	// use map to prevent duplicate participants
@@ -653,240 +660,224 @@ func (ta *TruAPI) claimParticipantsResolver(ctx context.Context, q claim.Claim)
 		addresses = append(addresses, address)
 	}
 	accounts, errs := loaders.appAccountLoader.LoadAll(addresses)
-	errors := make([]error, 0)
 	for _, e := range errs {
 		if e != nil {
-			errors = append(errors, e)
+			return nil, graphql.NewError(graphql.CodeUnavailable, e)
 		}
 	}
-	if len(errors) > 0 {
-		fmt.Println("errors", errors)
-		return participants
-	}
 	for _, acc := range accounts {
 		participants = append(participants, *acc)
 	}
-	return participants
+	return participants, nil
 }
 
-func (ta *TruAPI) stakeResolver(ctx context.Context, q queryByStakeID) *staking.Stake {
+func (ta *TruAPI) stakeResolver(ctx context.Context, q queryByStakeID) (*staking.Stake, error) {
 	queryRoute := path.Join(staking.ModuleName, staking.QueryStake)
 	res, err := ta.Query(queryRoute, staking.QueryStakeParams{StakeID: q.ID}, staking.ModuleCodec)
 	if err != nil {
-		fmt.Println("stakeResolver err: ", err)
-		return nil
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	stake := new(staking.Stake)
 	err = staking.ModuleCodec.UnmarshalJSON(res, stake)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
-	return stake
+	return stake, nil
 }
 
-func (ta *TruAPI) claimArgumentStakesResolver(ctx context.Context, q staking.Argument) []staking.Stake {
+func (ta *TruAPI) claimArgumentStakesResolver(ctx context.Context, q staking.Argument) ([]staking.Stake, error) {
 	queryRoute := path.Join(staking.ModuleName, staking.QueryArgumentStakes)
 	res, err := ta.Query(queryRoute, staking.QueryArgumentStakesParams{ArgumentID: q.ID}, staking.ModuleCodec)
 	if err != nil {
-		fmt.Println("claimArgumentStakesResolver err: ", err)
-		return []staking.Stake{}
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	stakes := make([]staking.Stake, 0)
 	err = staking.ModuleCodec.UnmarshalJSON(res, &stakes)
 	if err != nil {
-		fmt.Println("[]staking.Stake UnmarshalJSON err: ", err)
-		return []staking.Stake{}
+		return nil, err
 	}
 
-	return stakes
+	return stakes, nil
 }
 
-func (ta *TruAPI) slashResolver(ctx context.Context, q queryBySlashID) *slashing.Slash {
+func (ta *TruAPI) slashResolver(ctx context.Context, q queryBySlashID) (*slashing.Slash, error) {
 	queryRoute := path.Join(slashing.ModuleName, slashing.QuerySlash)
 	res, err := ta.Query(queryRoute, slashing.QuerySlashParams{ID: q.ID}, slashing.ModuleCodec)
 	if err != nil {
-		fmt.Println("slashResolver err: ", err)
-		return nil
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	slash := new(slashing.Slash)
 	err = slashing.ModuleCodec.UnmarshalJSON(res, slash)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
-	return slash
+	return slash, nil
 }
 
-func (ta *TruAPI) slashesResolver(ctx context.Context) []slashing.Slash {
+func (ta *TruAPI) slashesResolver(ctx context.Context) ([]slashing.Slash, error) {
 	user, ok := ctx.Value(userContextKey).(*cookies.AuthenticatedUser)
 	if !ok {
-		return make([]slashing.Slash, 0)
+		return make([]slashing.Slash, 0), nil
 	}
 
-	settings := ta.settingsResolver(ctx)
+	settings, err := ta.settingsResolver(ctx)
+	if err != nil {
+		return nil, err
+	}
 	if !contains(settings.ClaimAdmins, user.Address) {
-		return make([]slashing.Slash, 0)
+		return make([]slashing.Slash, 0), nil
 	}
 
 	queryRoute := path.Join(slashing.ModuleName, slashing.QuerySlashes)
 	res, err := ta.Query(queryRoute, struct{}{}, slashing.ModuleCodec)
 	if err != nil {
-		fmt.Println("slashesResolver err: ", err)
-		return nil
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	slashes := make([]slashing.Slash, 0)
 	err = slashing.ModuleCodec.UnmarshalJSON(res, &slashes)
 	if err != nil {
-		fmt.Println("[]slashing.Slash UnmarshalJSON err: ", err)
-		return nil
+		return nil, err
 	}
 
-	return slashes
+	return slashes, nil
 }
 
-func (ta *TruAPI) claimArgumentSlashesResolver(ctx context.Context, q staking.Argument) []slashing.Slash {
+func (ta *TruAPI) claimArgumentSlashesResolver(ctx context.Context, q staking.Argument) ([]slashing.Slash, error) {
 	queryRoute := path.Join(slashing.ModuleName, slashing.QueryArgumentSlashes)
 	res, err := ta.Query(queryRoute, slashing.QueryArgumentSlashesParams{ArgumentID: q.ID}, slashing.ModuleCodec)
 	if err != nil {
-		fmt.Println("claimArgumentSlashesResolver err: ", err)
-		return []slashing.Slash{}
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	slashes := make([]slashing.Slash, 0)
 	err = slashing.ModuleCodec.UnmarshalJSON(res, &slashes)
 	if err != nil {
-		fmt.Println("[]slashing.Slash UnmarshalJSON err: ", err)
-		return []slashing.Slash{}
+		return nil, err
 	}
 
-	return slashes
+	return slashes, nil
 }
 
-func (ta *TruAPI) claimArgumentUpvoteStakersResolver(ctx context.Context, q staking.Argument) []AppAccount {
-	stakes := ta.claimArgumentStakesResolver(ctx, q)
+func (ta *TruAPI) claimArgumentUpvoteStakersResolver(ctx context.Context, q staking.Argument) ([]AppAccount, error) {
+	stakes, err := ta.claimArgumentStakesResolver(ctx, q)
+	if err != nil {
+		return nil, err
+	}
 	appAccounts := make([]AppAccount, 0)
 	for _, stake := range stakes {
 		if stake.Type == staking.StakeUpvote {
-			appAccounts = append(appAccounts, *ta.appAccountResolver(ctx, queryByAddress{ID: stake.Creator.String()}))
+			appAccount, err := ta.appAccountResolver(ctx, queryByAddress{ID: stake.Creator.String()})
+			if err != nil {
+				return nil, err
+			}
+			if appAccount != nil {
+				appAccounts = append(appAccounts, *appAccount)
+			}
 		}
 	}
-	return appAccounts
+	return appAccounts, nil
 }
 
-func (ta *TruAPI) appAccountStakeResolver(ctx context.Context, q staking.Argument) *staking.Stake {
+func (ta *TruAPI) appAccountStakeResolver(ctx context.Context, q staking.Argument) (*staking.Stake, error) {
 	user, ok := ctx.Value(userContextKey).(*cookies.AuthenticatedUser)
 	if ok {
-		stakes := ta.claimArgumentStakesResolver(ctx, q)
+		stakes, err := ta.claimArgumentStakesResolver(ctx, q)
+		if err != nil {
+			return nil, err
+		}
 		for _, stake := range stakes {
 			if user.Address == stake.Creator.String() {
-				return &stake
+				return &stake, nil
 			}
 		}
 	}
-	return nil
+	return nil, nil
 }
 
-func (ta *TruAPI) appAccountSlashResolver(ctx context.Context, q staking.Argument) *slashing.Slash {
+func (ta *TruAPI) appAccountSlashResolver(ctx context.Context, q staking.Argument) (*slashing.Slash, error) {
 	user, ok := ctx.Value(userContextKey).(*cookies.AuthenticatedUser)
 	if ok {
-		slashes := ta.claimArgumentSlashesResolver(ctx, q)
+		slashes, err := ta.claimArgumentSlashesResolver(ctx, q)
+		if err != nil {
+			return nil, err
+		}
 		for _, slash := range slashes {
 			if user.Address == slash.Creator.String() {
-				return &slash
+				return &slash, nil
 			}
 		}
 	}
-	return nil
+	return nil, nil
 }
 
-func (ta *TruAPI) commentsResolver(ctx context.Context, q queryCommentsParams) []db.Comment {
-	var comments []db.Comment
-	var err error
+func (ta *TruAPI) commentsResolver(ctx context.Context, q queryCommentsParams) ([]db.Comment, error) {
 	if q.ArgumentID == nil || q.ElementID == nil {
 		id := q.ID
 		if q.ClaimID != nil && *q.ClaimID > 0 {
 			id = *q.ClaimID
 		}
-		comments, err = ta.DBClient.ClaimLevelComments(id)
-		if err != nil {
-			fmt.Println("commentsResolver err: ", err)
-		}
-	} else {
-		comments, err = ta.DBClient.ArgumentLevelComments(*q.ArgumentID, *q.ElementID)
-		if err != nil {
-			fmt.Println("commentsResolver err: ", err)
-		}
+		return ta.DBClient.ClaimLevelComments(id)
 	}
-	return comments
+	return ta.DBClient.ArgumentLevelComments(*q.ArgumentID, *q.ElementID)
 }
 
-func (ta *TruAPI) claimQuestionsResolver(ctx context.Context, q queryByClaimID) []db.Question {
-	questions, err := ta.DBClient.QuestionsByClaimID(q.ID)
-	if err != nil {
-		fmt.Println("claimQuestions err: ", err)
-	}
-	return questions
+func (ta *TruAPI) claimQuestionsResolver(ctx context.Context, q queryByClaimID) ([]db.Question, error) {
+	return ta.DBClient.QuestionsByClaimID(q.ID)
 }
 
-func (ta *TruAPI) appAccountClaimsCreatedResolver(ctx context.Context, q queryByAddress) []claim.Claim {
+func (ta *TruAPI) appAccountClaimsCreatedResolver(ctx context.Context, q queryByAddress) ([]claim.Claim, error) {
 	creator, err := sdk.AccAddressFromBech32(q.ID)
 	if err != nil {
-		fmt.Println("appAccountClaimsCreatedResolver err: ", err)
-		return []claim.Claim{}
+		return nil, graphql.NewError(graphql.CodeBadRequest, err)
 	}
 
 	queryRoute := path.Join(claim.QuerierRoute, claim.QueryCreatorClaims)
 	res, err := ta.Query(queryRoute, claim.QueryCreatorClaimsParams{Creator: creator}, claim.ModuleCodec)
 	if err != nil {
-		return []claim.Claim{}
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	claimsCreated := make([]claim.Claim, 0)
 	err = claim.ModuleCodec.UnmarshalJSON(res, &claimsCreated)
 	if err != nil {
-		fmt.Println("[]claim.Claim UnmarshalJSON err: ", err)
-		return []claim.Claim{}
-	}
-
-	unflaggedClaims, err := ta.filterFlaggedClaims(claimsCreated)
-	if err != nil {
-		fmt.Println("filterFlaggedClaims err: ", err)
-		panic(err)
+		return nil, err
 	}
 
-	return unflaggedClaims
+	return ta.filterFlaggedClaims(claimsCreated)
 }
 
-func (ta *TruAPI) appAccountArgumentsResolver(ctx context.Context, q queryByAddress) []staking.Argument {
+func (ta *TruAPI) appAccountArgumentsResolver(ctx context.Context, q queryByAddress) ([]staking.Argument, error) {
 	creator, err := sdk.AccAddressFromBech32(q.ID)
 	if err != nil {
-		return []staking.Argument{}
+		return nil, graphql.NewError(graphql.CodeBadRequest, err)
 	}
 
 	queryRoute := path.Join(staking.QuerierRoute, staking.QueryUserArguments)
 	res, err := ta.Query(queryRoute, staking.QueryUserArgumentsParams{Address: creator}, staking.ModuleCodec)
 	if err != nil {
-		fmt.Println("appAccountArguments err: ", err)
-		return []staking.Argument{}
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	arguments := make([]staking.Argument, 0)
 	err = staking.ModuleCodec.UnmarshalJSON(res, &arguments)
 	if err != nil {
-		fmt.Println("[]staking.Argument UnmarshalJSON err: ", err)
-		return []staking.Argument{}
+		return nil, err
 	}
 
-	return arguments
+	return arguments, nil
 }
 
-func (ta *TruAPI) appAccountClaimsWithArgumentsResolver(ctx context.Context, q queryByAddress) []claim.Claim {
-	arguments := ta.appAccountArgumentsResolver(ctx, q)
+func (ta *TruAPI) appAccountClaimsWithArgumentsResolver(ctx context.Context, q queryByAddress) ([]claim.Claim, error) {
+	arguments, err := ta.appAccountArgumentsResolver(ctx, q)
+	if err != nil {
+		return nil, err
+	}
 
 	// Use map to prevent duplicate claim IDs
 	claimIDsWithArgumentMap := make(map[uint64]uint64)
@@ -906,36 +897,32 @@ func (ta *TruAPI) appAccountClaimsWithArgumentsResolver(ctx context.Context, q q
 	queryRoute := path.Join(claim.QuerierRoute, claim.QueryClaimsByIDs)
 	res, err := ta.Query(queryRoute, claim.QueryClaimsParams{IDs: claimIDsWithArgument}, claim.ModuleCodec)
 	if err != nil {
-		fmt.Println("appAccountClaimsWithArguments err: ", err)
-		return []claim.Claim{}
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	claimsWithArgument := make([]claim.Claim, 0)
 	err = claim.ModuleCodec.UnmarshalJSON(res, &claimsWithArgument)
 	if err != nil {
-		fmt.Println("[]claim.Claim UnmarshalJSON err: ", err)
-		return []claim.Claim{}
-	}
-
-	unflaggedClaims, err := ta.filterFlaggedClaims(claimsWithArgument)
-	if err != nil {
-		fmt.Println("filterFlaggedClaims err: ", err)
-		panic(err)
+		return nil, err
 	}
 
-	return unflaggedClaims
+	return ta.filterFlaggedClaims(claimsWithArgument)
 }
 
-func (ta *TruAPI) appAccountClaimsWithAgreesResolver(ctx context.Context, q queryByAddress) []claim.Claim {
-	stakes := ta.agreesResolver(ctx, q)
+func (ta *TruAPI) appAccountClaimsWithAgreesResolver(ctx context.Context, q queryByAddress) ([]claim.Claim, error) {
+	stakes, err := ta.agreesResolver(ctx, q)
+	if err != nil {
+		return nil, err
+	}
 
 	// Use map to prevent duplicate claim IDs
 	claimIDsWithAgreesMap := make(map[uint64]uint64)
 	for _, stake := range stakes {
-		argument := ta.claimArgumentResolver(ctx, queryByArgumentID{ID: stake.ArgumentID})
-		if argument != nil {
-			claimIDsWithAgreesMap[argument.ClaimID] = argument.ClaimID
+		argument, err := ta.claimArgumentResolver(ctx, queryByArgumentID{ID: stake.ArgumentID})
+		if err != nil {
+			return nil, err
 		}
+		claimIDsWithAgreesMap[argument.ClaimID] = argument.ClaimID
 	}
 
 	claimIDsWithAgrees := make([]uint64, 0)
@@ -950,43 +937,34 @@ func (ta *TruAPI) appAccountClaimsWithAgreesResolver(ctx context.Context, q quer
 	queryRoute := path.Join(claim.QuerierRoute, claim.QueryClaimsByIDs)
 	res, err := ta.Query(queryRoute, claim.QueryClaimsParams{IDs: claimIDsWithAgrees}, claim.ModuleCodec)
 	if err != nil {
-		fmt.Println("appAccountClaimsWithAgrees err: ", err)
-		return []claim.Claim{}
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	claimsWithAgrees := make([]claim.Claim, 0)
 	err = claim.ModuleCodec.UnmarshalJSON(res, &claimsWithAgrees)
 	if err != nil {
-		fmt.Println("[]claim.Claim UnmarshalJSON err: ", err)
-		return []claim.Claim{}
-	}
-
-	unflaggedClaims, err := ta.filterFlaggedClaims(claimsWithAgrees)
-	if err != nil {
-		fmt.Println("filterFlaggedClaims err: ", err)
-		panic(err)
+		return nil, err
 	}
 
-	return unflaggedClaims
+	return ta.filterFlaggedClaims(claimsWithAgrees)
 }
 
-func (ta *TruAPI) agreesResolver(ctx context.Context, q queryByAddress) []staking.Stake {
+func (ta *TruAPI) agreesResolver(ctx context.Context, q queryByAddress) ([]staking.Stake, error) {
 	creator, err := sdk.AccAddressFromBech32(q.ID)
 	if err != nil {
-		return []staking.Stake{}
+		return nil, graphql.NewError(graphql.CodeBadRequest, err)
 	}
 
 	queryRoute := path.Join(staking.QuerierRoute, staking.QueryUserStakes)
 	res, err := ta.Query(queryRoute, staking.QueryUserStakesParams{Address: creator}, staking.ModuleCodec)
 	if err != nil {
-		fmt.Println("agreesResolver err: ", err)
-		return []staking.Stake{}
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	stakes := make([]staking.Stake, 0)
 	err = staking.ModuleCodec.UnmarshalJSON(res, &stakes)
 	if err != nil {
-		return []staking.Stake{}
+		return nil, err
 	}
 
 	agrees := make([]staking.Stake, 0)
@@ -996,56 +974,64 @@ func (ta *TruAPI) agreesResolver(ctx context.Context, q queryByAddress) []stakin
 		}
 	}
 
-	return agrees
+	return agrees, nil
 }
 
-func (ta *TruAPI) agreesReceivedResolver(ctx context.Context, address string) int64 {
-	limit := ta.APIContext.Config.Leaderboard.TopDisplaying
-	since := time.Time{}
-
-	userData, err := ta.DBClient.Leaderboard(since, "1", limit, ta.APIContext.Config.Community.InactiveCommunities, address)
+// agreesReceivedResolver returns the agrees received by the address across active communities,
+// as of the latest all time ranks.
+func (ta *TruAPI) agreesReceivedResolver(ctx context.Context, address string) (int64, error) {
+	date, _, ok, err := ta.lastLeaderboardRankDates(db.LeaderboardPeriodAllTime)
+	if err != nil || !ok {
+		return 0, err
+	}
+	metric := LeaderboardMetricSortByMapping[LeaderboardMetricFilterAgreesReceived]
+	rank, err := ta.DBClient.LeaderboardUserRank(date, db.LeaderboardPeriodAllTime, metric, "", address)
 	if err != nil {
-		return 0
+		return 0, graphql.NewError(graphql.CodeUnavailable, err)
 	}
-	if len(userData) == 0 {
-		return 0
+	if rank == nil {
+		return 0, nil
 	}
-
-	return userData[0].AgreesReceived
+	return rank.Value, nil
 }
 
-func (ta *TruAPI) appAccountTransactionsResolver(ctx context.Context, q queryByAddress) []bank.Transaction {
+func (ta *TruAPI) appAccountTransactionsResolver(ctx context.Context, q queryByAddress) ([]bank.Transaction, error) {
 	creator, err := sdk.AccAddressFromBech32(q.ID)
 	if err != nil {
-		return []bank.Transaction{}
+		return nil, graphql.NewError(graphql.CodeBadRequest, err)
 	}
 
 	queryRoute := path.Join(bank.QuerierRoute, bank.QueryTransactionsByAddress)
 	res, err := ta.Query(queryRoute, bank.QueryTransactionsByAddressParams{Address: creator}, bank.ModuleCodec)
 	if err != nil {
-		fmt.Println("appAccountTransactionsResolver err: ", err)
-		return []bank.Transaction{}
+		return nil, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	transactions := make([]bank.Transaction, 0)
 	err = bank.ModuleCodec.UnmarshalJSON(res, &transactions)
 	if err != nil {
-		return []bank.Transaction{}
+		return nil, err
 	}
 
 	sort.Slice(transactions, func(i, j int) bool {
 		return transactions[j].CreatedTime.Before(transactions[i].CreatedTime) && transactions[j].ID < transactions[i].ID
 	})
 
-	return transactions
+	return transactions, nil
 }
 
-func (ta *TruAPI) transactionReferenceResolver(ctx context.Context, t bank.Transaction) TransactionReference {
+func (ta *TruAPI) transactionReferenceResolver(ctx context.Context, t bank.Transaction) (TransactionReference, error) {
 	var tr TransactionReference
 	switch t.Type {
 	case bank.TransactionCuratorReward:
-		slash := ta.slashResolver(ctx, queryBySlashID{t.ReferenceID})
-		argument := ta.claimArgumentResolver(ctx, queryByArgumentID{slash.ArgumentID})
+		slash, err := ta.slashResolver(ctx, queryBySlashID{t.ReferenceID})
+		if err != nil {
+			return tr, err
+		}
+		argument, err := ta.claimArgumentResolver(ctx, queryByArgumentID{slash.ArgumentID})
+		if err != nil {
+			return tr, err
+		}
 		tr = TransactionReference{
 			ReferenceID: t.ReferenceID,
 			Type:        ReferenceArgument,
@@ -1076,8 +1062,14 @@ func (ta *TruAPI) transactionReferenceResolver(ctx context.Context, t bank.Trans
 	case bank.TransactionInterestArgumentCreationSlashed:
 		fallthrough
 	case bank.TransactionInterestUpvoteReceivedSlashed:
-		stake := ta.stakeResolver(ctx, queryByStakeID{t.ReferenceID})
-		argument := ta.claimArgumentResolver(ctx, queryByArgumentID{stake.ArgumentID})
+		stake, err := ta.stakeResolver(ctx, queryByStakeID{t.ReferenceID})
+		if err != nil {
+			return tr, err
+		}
+		argument, err := ta.claimArgumentResolver(ctx, queryByArgumentID{stake.ArgumentID})
+		if err != nil {
+			return tr, err
+		}
 		tr = TransactionReference{
 			ReferenceID: t.ReferenceID,
 			Type:        ReferenceArgument,
@@ -1089,7 +1081,10 @@ func (ta *TruAPI) transactionReferenceResolver(ctx context.Context, t bank.Trans
 	case bank.TransactionUpvoteReturned:
 		fallthrough
 	case bank.TransactionInterestUpvoteGiven:
-		argument := ta.claimArgumentResolver(ctx, queryByArgumentID{t.ReferenceID})
+		argument, err := ta.claimArgumentResolver(ctx, queryByArgumentID{t.ReferenceID})
+		if err != nil {
+			return tr, err
+		}
 		creatorTwitterProfile := ta.twitterProfileResolver(ctx, argument.Creator.String())
 		tr = TransactionReference{
 			ReferenceID: t.ReferenceID,
@@ -1098,8 +1093,14 @@ func (ta *TruAPI) transactionReferenceResolver(ctx context.Context, t bank.Trans
 			Body:        stripmd.Strip(argument.Summary),
 		}
 	case bank.TransactionInterestUpvoteReceived:
-		stake := ta.stakeResolver(ctx, queryByStakeID{ID: t.ReferenceID})
-		argument := ta.claimArgumentResolver(ctx, queryByArgumentID{stake.ArgumentID})
+		stake, err := ta.stakeResolver(ctx, queryByStakeID{ID: t.ReferenceID})
+		if err != nil {
+			return tr, err
+		}
+		argument, err := ta.claimArgumentResolver(ctx, queryByArgumentID{stake.ArgumentID})
+		if err != nil {
+			return tr, err
+		}
 		stakerTwitterProfile := ta.twitterProfileResolver(ctx, stake.Creator.String())
 		tr = TransactionReference{
 			ReferenceID: t.ReferenceID,
@@ -1116,7 +1117,10 @@ func (ta *TruAPI) transactionReferenceResolver(ctx context.Context, t bank.Trans
 	case bank.TransactionChallengeReturned:
 		fallthrough
 	case bank.TransactionInterestArgumentCreation:
-		argument := ta.claimArgumentResolver(ctx, queryByArgumentID{t.ReferenceID})
+		argument, err := ta.claimArgumentResolver(ctx, queryByArgumentID{t.ReferenceID})
+		if err != nil {
+			return tr, err
+		}
 		tr = TransactionReference{
 			ReferenceID: t.ReferenceID,
 			Type:        ReferenceArgument,
@@ -1131,7 +1135,7 @@ func (ta *TruAPI) transactionReferenceResolver(ctx context.Context, t bank.Trans
 			Body:        "",
 		}
 	}
-	return tr
+	return tr, nil
 }
 
 func (ta *TruAPI) claimImageResolver(ctx context.Context, q claim.Claim) string {
@@ -1175,74 +1179,66 @@ func (ta *TruAPI) claimVideoResolver(ctx context.Context, q claim.Claim) *string
 	return nil
 }
 
-func (ta *TruAPI) settingsResolver(_ context.Context) Settings {
+func (ta *TruAPI) settingsResolver(_ context.Context) (Settings, error) {
 	queryRoute := path.Join(account.QuerierRoute, account.QueryParams)
 	res, err := ta.Query(queryRoute, struct{}{}, account.ModuleCodec)
 	if err != nil {
-		fmt.Println("settingsResolver err: ", err)
-		return Settings{}
+		return Settings{}, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	accountParams := new(account.Params)
 	err = account.ModuleCodec.UnmarshalJSON(res, &accountParams)
 	if err != nil {
-		fmt.Println("accountParams UnmarshalJSON err: ", err)
-		return Settings{}
+		return Settings{}, err
 	}
 
 	queryRoute = path.Join(claim.QuerierRoute, claim.QueryParams)
 	res, err = ta.Query(queryRoute, struct{}{}, claim.ModuleCodec)
 	if err != nil {
-		fmt.Println("settingsResolver err: ", err)
-		return Settings{}
+		return Settings{}, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	claimParams := new(claim.Params)
 	err = claim.ModuleCodec.UnmarshalJSON(res, &claimParams)
 	if err != nil {
-		fmt.Println("claimParams UnmarshalJSON err: ", err)
-		return Settings{}
+		return Settings{}, err
 	}
 
 	queryRoute = path.Join(staking.QuerierRoute, staking.QueryParams)
 	res, err = ta.Query(queryRoute, struct{}{}, staking.ModuleCodec)
 	if err != nil {
-		fmt.Println("settingsResolver err: ", err)
-		return Settings{}
+		return Settings{}, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	stakingParams := new(staking.Params)
 	err = staking.ModuleCodec.UnmarshalJSON(res, &stakingParams)
 	if err != nil {
-		fmt.Println("stakingParams UnmarshalJSON err: ", err)
-		return Settings{}
+		return Settings{}, err
 	}
 
 	queryRoute = path.Join(slashing.QuerierRoute, slashing.QueryParams)
 	res, err = ta.Query(queryRoute, struct{}{}, slashing.ModuleCodec)
 	if err != nil {
-		fmt.Println("settingsResolver err: ", err)
-		return Settings{}
+		return Settings{}, graphql.NewError(graphql.CodeUnavailable, err)
 	}
 
 	slashingParams := new(slashing.Params)
 	err = slashing.ModuleCodec.UnmarshalJSON(res, &slashingParams)
 	if err != nil {
-		fmt.Println("slashingParams UnmarshalJSON err: ", err)
-		return Settings{}
+		return Settings{}, err
 	}
 
 	creatorShare, err := strconv.ParseFloat(stakingParams.CreatorShare.String(), 64)
 	if err != nil {
-		return Settings{}
+		return Settings{}, err
 	}
 	interestRate, err := strconv.ParseFloat(stakingParams.InterestRate.String(), 64)
 	if err != nil {
-		return Settings{}
+		return Settings{}, err
 	}
 	curatorShare, err := strconv.ParseFloat(slashingParams.CuratorShare.String(), 64)
 	if err != nil {
-		return Settings{}
+		return Settings{}, err
 	}
 
 	argumentCreationInterest := staking.Interest(stakingParams.InterestRate, stakingParams.ArgumentCreationStake, stakingParams.Period)
@@ -1298,10 +1294,10 @@ func (ta *TruAPI) settingsResolver(_ context.Context) Settings {
 		MinSummaryLength:  int32(stakingParams.ArgumentSummaryMinLength),
 		MaxSummaryLength:  int32(stakingParams.ArgumentSummaryMaxLength),
 		DefaultStake:      sdk.NewCoin(app.StakeDenom, sdk.NewInt(30*app.Shanev)),
-	}
+	}, nil
 }
 
-func (ta *TruAPI) appAccountCommunityEarningsResolver(ctx context.Context, q queryByAddress) []appAccountCommunityEarning {
+func (ta *TruAPI) appAccountCommunityEarningsResolver(ctx context.Context, q queryByAddress) ([]appAccountCommunityEarning, error) {
 	now := time.Now()
 
 	from := now.Add(-7 * 24 * time.Hour) // starting from 6 days before yesterday
@@ -1311,13 +1307,19 @@ func (ta *TruAPI) appAccountCommunityEarningsResolver(ctx context.Context, q que
 	communityAllTimeEarnings := make(map[string]sdk.Coin)
 
 	// seeding empty communities
-	communities := ta.communitiesResolver(ctx)
+	communities, err := ta.communitiesResolver(ctx)
+	if err != nil {
+		return nil, err
+	}
 	for _, community := range communities {
 		communityWeeklyEarnings[community.ID] = sdk.NewCoin(app.StakeDenom, sdk.NewInt(0))
 		communityAllTimeEarnings[community.ID] = sdk.NewCoin(app.StakeDenom, sdk.NewInt(0))
 	}
 
-	transactions := ta.appAccountTransactionsResolver(ctx, q)
+	transactions, err := ta.appAccountTransactionsResolver(ctx, q)
+	if err != nil {
+		return nil, err
+	}
 	// reversing the order of transactions
 	for i := len(transactions)/2 - 1; i >= 0; i-- {
 		opp := len(transactions) - 1 - i
@@ -1372,10 +1374,10 @@ func (ta *TruAPI) appAccountCommunityEarningsResolver(ctx context.Context, q que
 		})
 	}
 
-	return communityEarnings
+	return communityEarnings, nil
 }
 
-func (ta *TruAPI) appAccountEarningsResolver(ctx context.Context, q appAccountEarningsFilter) appAccountEarnings {
+func (ta *TruAPI) appAccountEarningsResolver(ctx context.Context, q appAccountEarningsFilter) (appAccountEarnings, error) {
 	now := time.Now()
 
 	dataPoints := make([]appAccountEarning, 0)
@@ -1385,7 +1387,10 @@ func (ta *TruAPI) appAccountEarningsResolver(ctx context.Context, q appAccountEa
 	// seeding empty dates
 	from, err := time.Parse("2006-01-02", q.From)
 	if err != nil {
-		panic(err)
+		return appAccountEarnings{}, graphql.NewError(graphql.CodeBadRequest, err)
+	}
+	if !from.Before(now) {
+		return appAccountEarnings{}, graphql.Errorf(graphql.CodeBadRequest, "from must be in the past")
 	}
 	for date := from; date.Before(now); date = date.AddDate(0, 0, 1) {
 		key := date.Format("2006-01-02")
@@ -1393,7 +1398,10 @@ func (ta *TruAPI) appAccountEarningsResolver(ctx context.Context, q appAccountEa
 		mappedSortedKeys = append(mappedSortedKeys, key) // storing the key so that we can later sort the map in the same order
 	}
 
-	transactions := ta.appAccountTransactionsResolver(ctx, queryByAddress{ID: q.ID})
+	transactions, err := ta.appAccountTransactionsResolver(ctx, queryByAddress{ID: q.ID})
+	if err != nil {
+		return appAccountEarnings{}, err
+	}
 	// reversing the order of transactions
 	for i := len(transactions)/2 - 1; i >= 0; i-- {
 		opp := len(transactions) - 1 - i
@@ -1402,15 +1410,14 @@ func (ta *TruAPI) appAccountEarningsResolver(ctx context.Context, q appAccountEa
 
 	runningBalance := sdk.NewCoin(app.StakeDenom, sdk.NewInt(0))
 	dailyRunningBalances := make(map[string]sdk.Coin)
-	firstTxnDate := transactions[0].CreatedTime
 	firstDataPointDate, err := time.Parse("2006-01-02", mappedSortedKeys[0])
 	if err != nil {
-		panic(err)
+		return appAccountEarnings{}, err
 	}
 
 	beginning := firstDataPointDate
-	if firstTxnDate.Before(firstDataPointDate) {
-		beginning = firstTxnDate
+	if len(transactions) > 0 && transactions[0].CreatedTime.Before(firstDataPointDate) {
+		beginning = transactions[0].CreatedTime
 	}
 	for date := beginning; date.Before(now); date = date.AddDate(0, 0, 1) {
 		key := date.Format("2006-01-02")
@@ -1485,58 +1492,49 @@ func (ta *TruAPI) appAccountEarningsResolver(ctx context.Context, q appAccountEa
 	return appAccountEarnings{
 		NetEarnings: netEarnings,
 		DataPoints:  reducedDataPoints,
-	}
+	}, nil
 }
 
-func (ta *TruAPI) unreadNotificationsCountResolver(ctx context.Context, q struct{}) *db.NotificationsCountResponse {
+func (ta *TruAPI) unreadNotificationsCountResolver(ctx context.Context, q struct{}) (*db.NotificationsCountResponse, error) {
 	user, ok := ctx.Value(userContextKey).(*cookies.AuthenticatedUser)
 	if !ok {
 		return &db.NotificationsCountResponse{
 			Count: 0,
-		}
-	}
-	response, err := ta.DBClient.UnreadNotificationEventsCountByAddress(user.Address)
-	if err != nil {
-		panic(err)
+		}, nil
 	}
-	return response
+	return ta.DBClient.UnreadNotificationEventsCountByAddress(user.Address)
 }
 
-func (ta *TruAPI) unseenNotificationsCountResolver(ctx context.Context, q struct{}) *db.NotificationsCountResponse {
+func (ta *TruAPI) unseenNotificationsCountResolver(ctx context.Context, q struct{}) (*db.NotificationsCountResponse, error) {
 	user, ok := ctx.Value(userContextKey).(*cookies.AuthenticatedUser)
 	if !ok {
 		return &db.NotificationsCountResponse{
 			Count: 0,
-		}
+		}, nil
 	}
-	response, err := ta.DBClient.UnseenNotificationEventsCountByAddress(user.Address)
-	if err != nil {
-		panic(err)
-	}
-	return response
+	return ta.DBClient.UnseenNotificationEventsCountByAddress(user.Address)
 }
 
-func (ta *TruAPI) notificationsResolver(ctx context.Context, q struct{}) []db.NotificationEvent {
+func (ta *TruAPI) notificationsResolver(ctx context.Context, q struct{}) ([]db.NotificationEvent, error) {
 	user, ok := ctx.Value(userContextKey).(*cookies.AuthenticatedUser)
 	if !ok {
-		return make([]db.NotificationEvent, 0)
+		return make([]db.NotificationEvent, 0), nil
 	}
-	evts, err := ta.DBClient.NotificationEventsByAddress(user.Address)
-	if err != nil {
-		panic(err)
-	}
-	return evts
+	return ta.DBClient.NotificationEventsByAddress(user.Address)
 }
 
-func (ta *TruAPI) invitesResolver(ctx context.Context) []db.Invite {
+func (ta *TruAPI) invitesResolver(ctx context.Context) ([]db.Invite, error) {
 	user, ok := ctx.Value(userContextKey).(*cookies.AuthenticatedUser)
 	if !ok {
-		return make([]db.Invite, 0)
+		return make([]db.Invite, 0), nil
 	}
 
 	userProfile, err := ta.DBClient.UserProfileByAddress(user.Address)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if userProfile == nil {
+		return nil, graphql.Errorf(graphql.CodeNotFound, "no profile for %s", user.Address)
 	}
 
 	// TODO: pull this in from an ENV
@@ -1546,50 +1544,46 @@ func (ta *TruAPI) invitesResolver(ctx context.Context) []db.Invite {
 		strings.EqualFold(userProfile.Username, "truted2") ||
 		strings.EqualFold(userProfile.Username, "mohitmamoria") ||
 		strings.EqualFold(userProfile.Username, "shanev") {
-		invites, err := ta.DBClient.Invites()
-		if err != nil {
-			panic(err)
-		}
-		return invites
-	}
-	invites, err := ta.DBClient.InvitesByAddress(user.Address)
-	if err != nil {
-		panic(err)
+		return ta.DBClient.Invites()
 	}
-	return invites
+	return ta.DBClient.InvitesByAddress(user.Address)
 }
 
-func (ta *TruAPI) referredAppAccountsResolver(ctx context.Context, q queryReferredAppAccountsParams) []AppAccount {
+func (ta *TruAPI) referredAppAccountsResolver(ctx context.Context, q queryReferredAppAccountsParams) ([]AppAccount, error) {
 	user, ok := ctx.Value(userContextKey).(*cookies.AuthenticatedUser)
 	if !ok {
-		return make([]AppAccount, 0)
+		return make([]AppAccount, 0), nil
 	}
 
-	var users []db.User
-	var err error
-	settings := ta.settingsResolver(ctx)
+	settings, err := ta.settingsResolver(ctx)
+	if err != nil {
+		return nil, err
+	}
 
+	var users []db.User
 	if q.Admin && contains(settings.ClaimAdmins, user.Address) {
 		users, err = ta.DBClient.ReferredUsers()
-		if err != nil {
-			fmt.Println("referredAppAccountsResolver err: ", err)
-			return make([]AppAccount, 0)
-		}
 	} else {
 		users, err = ta.DBClient.ReferredUsersByID(user.ID)
-		if err != nil {
-			fmt.Println("referredAppAccountsResolver err: ", err)
-			return make([]AppAccount, 0)
-		}
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	appAccounts := make([]AppAccount, 0)
 	for _, user := range users {
-		if user.Address != "" {
-			appAccounts = append(appAccounts, *ta.appAccountResolver(ctx, queryByAddress{ID: user.Address}))
+		if user.Address == "" {
+			continue
+		}
+		appAccount, err := ta.appAccountResolver(ctx, queryByAddress{ID: user.Address})
+		if err != nil {
+			return nil, err
+		}
+		if appAccount != nil {
+			appAccounts = append(appAccounts, *appAccount)
 		}
 	}
-	return appAccounts
+	return appAccounts, nil
 }
 
 func (ta *TruAPI) followsCommunity(ctx context.Context, q queryByCommunityID) bool {
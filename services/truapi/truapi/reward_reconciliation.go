@@ -0,0 +1,71 @@
+package truapi
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+// rewardReconciliationDefaultInterval is the interval in minutes between reconciliations of the reward ledger
+const rewardReconciliationDefaultInterval = 60
+
+// rewardReconciliationSlackLimit is the number of discrepancies listed in the Slack report
+const rewardReconciliationSlackLimit = 20
+
+// reconcileRewardLedger compares the reward ledger with the actual balances and reports the discrepancies
+func (ta *TruAPI) reconcileRewardLedger() error {
+	discrepancies, err := ta.DBClient.RewardLedgerDiscrepancies(chainIndexerName)
+	if err != nil {
+		return err
+	}
+	if len(discrepancies) == 0 {
+		log.Println("reward reconciliation: ledger matches the balances")
+		return nil
+	}
+	for _, d := range discrepancies {
+		log.Printf("reward reconciliation: user %d %s ledger %d actual %d difference %d\n",
+			d.UserID, d.Currency, d.Ledger, d.Actual, d.Difference)
+	}
+	webhook := ta.APIContext.Config.App.SlackWebhook
+	if webhook != "" {
+		ta.sendToSlack(rewardReconciliationReport(discrepancies), webhook)
+	}
+	return nil
+}
+
+// rewardReconciliationReport summarizes the discrepancies for Slack
+func rewardReconciliationReport(discrepancies []db.RewardLedgerDiscrepancy) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Reward ledger reconciliation found %d discrepancies\n", len(discrepancies))
+	for i, d := range discrepancies {
+		if i == rewardReconciliationSlackLimit {
+			fmt.Fprintf(&b, "...and %d more\n", len(discrepancies)-i)
+			break
+		}
+		fmt.Fprintf(&b, "• %s (%d) %s: ledger %d, actual %d\n", d.Username, d.UserID, d.Currency, d.Ledger, d.Actual)
+	}
+	return b.String()
+}
+
+func (ta *TruAPI) rewardReconciliationScheduler() {
+	if !ta.APIContext.Config.Ledger.Enabled {
+		log.Println("reward reconciliation is disabled")
+		return
+	}
+	interval := rewardReconciliationDefaultInterval
+	if ta.APIContext.Config.Ledger.Interval > 0 {
+		interval = ta.APIContext.Config.Ledger.Interval
+	}
+	log.Printf("reward reconciliation: interval of %d minutes \n", interval)
+	ticker := time.NewTicker(time.Duration(interval) * time.Minute)
+	for {
+		err := ta.reconcileRewardLedger()
+		if err != nil {
+			log.Println("an error occurred reconciling the reward ledger", err)
+		}
+		<-ticker.C
+	}
+}
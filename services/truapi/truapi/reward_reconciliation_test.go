@@ -0,0 +1,26 @@
+package truapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TruStory/octopus/services/truapi/db"
+)
+
+func TestRewardReconciliationReport(t *testing.T) {
+	discrepancies := []db.RewardLedgerDiscrepancy{
+		{UserID: 1, Username: "alice", Currency: db.RewardLedgerEntryCurrencyTru, Ledger: 5000000, Actual: 0, Difference: 5000000},
+		{UserID: 2, Username: "bob", Currency: db.RewardLedgerEntryCurrencyInvite, Ledger: 3, Actual: 5, Difference: -2},
+	}
+	report := rewardReconciliationReport(discrepancies)
+	assert.Contains(t, report, "found 2 discrepancies")
+	assert.Contains(t, report, "alice (1) utru: ledger 5000000, actual 0")
+	assert.Contains(t, report, "bob (2) invite: ledger 3, actual 5")
+
+	many := make([]db.RewardLedgerDiscrepancy, rewardReconciliationSlackLimit+5)
+	report = rewardReconciliationReport(many)
+	assert.Contains(t, report, "...and 5 more")
+	assert.Equal(t, rewardReconciliationSlackLimit+2, strings.Count(report, "\n"))
+}
@@ -14,14 +14,19 @@ import (
 
 	"github.com/TruStory/octopus/services/truapi/chttp"
 	truCtx "github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/storage"
+	"github.com/TruStory/octopus/services/truapi/telemetry"
 	"github.com/TruStory/octopus/services/truapi/truapi/cookies"
 )
 
 // RegisterRoutes applies the TruStory API routes to the `chttp.API` router
 func (ta *TruAPI) RegisterRoutes(apiCtx truCtx.TruAPIContext) {
+	ta.Use(telemetry.Middleware("truapi"))
 	sessionHandler := cookies.AnonymousSessionHandler(ta.APIContext)
 	ta.Use(sessionHandler)
 
+	ta.Handle("/metrics", MetricsAuth(apiCtx, telemetry.Handler()))
+
 	liveRedirectHandler := RedirectHandler(apiCtx.Config.App.LiveDebateURL, http.StatusFound)
 	ta.Handle("/live", liveRedirectHandler)
 
@@ -53,9 +58,13 @@ func (ta *TruAPI) RegisterRoutes(apiCtx truCtx.TruAPIContext) {
 	api.Handle("/reactions", WrapHandler(ta.HandleReaction))
 	api.HandleFunc("/mentions/translateToCosmos", ta.HandleTranslateCosmosMentions)
 	api.Handle("/track/", http.HandlerFunc(ta.HandleTrackEvent))
+	api.HandleFunc("/events", ta.HandleEvents).Methods(http.MethodPost)
 	api.Handle("/claim_of_the_day", WrapHandler(ta.HandleClaimOfTheDayID))
 	api.Handle("/claim/image", WrapHandler(ta.HandleClaimImage))
 	api.HandleFunc("/spotlight", ta.HandleSpotlight)
+	api.HandleFunc("/spotlight/snippets", ta.HandleSpotlightSnippet).Methods(http.MethodPost)
+	api.HandleFunc("/spotlight/snippets/{id:[0-9a-f]+}", ta.HandleSpotlightSnippet).Methods(http.MethodGet)
+	api.HandleFunc("/spotlight/snippets/{id:[0-9a-f]+}/download", ta.HandleSpotlightSnippet).Methods(http.MethodGet)
 	api.HandleFunc("/request_tru", ta.HandleRequestTru)
 
 	// users
@@ -71,19 +80,23 @@ func (ta *TruAPI) RegisterRoutes(apiCtx truCtx.TruAPIContext) {
 	api.HandleFunc("/users/journey", BasicAuth(apiCtx, http.HandlerFunc(ta.HandleUserJourney)))
 
 	api.HandleFunc("/gift", BasicAuth(apiCtx, http.HandlerFunc(ta.HandleGift)))
+	api.HandleFunc("/rewards/ledger", BasicAuth(apiCtx, http.HandlerFunc(ta.HandleRewardLedger)))
+	api.HandleFunc("/rewards/reconciliation", BasicAuth(apiCtx, http.HandlerFunc(ta.HandleRewardLedgerReconciliation)))
 	api.Handle("/communities/follow", http.HandlerFunc(ta.handleFollowCommunities)).Methods(http.MethodPost)
 	api.Handle("/communities/unfollow/{communityID}",
 		http.HandlerFunc(ta.handleUnfollowCommunity)).Methods(http.MethodDelete)
 	api.Handle("/highlights", http.HandlerFunc(ta.HandleHighlights))
-	api.PathPrefix("/push/").HandlerFunc(ta.HandlePush)
+	api.PathPrefix("/push/").HandlerFunc(BasicAuth(apiCtx, http.HandlerFunc(ta.HandlePush)))
+	api.HandleFunc("/broadcasts/campaigns", BasicAuth(apiCtx, http.HandlerFunc(ta.HandleBroadcastCampaigns)))
+	api.HandleFunc("/broadcasts/campaigns/{id:[0-9]+}", BasicAuth(apiCtx, http.HandlerFunc(ta.HandleBroadcastCampaign)))
 
 	// metrics
-	api.HandleFunc("/metrics/users", ta.HandleUsersMetrics)
-	api.HandleFunc("/metrics/claims", ta.HandleClaimMetrics)
-	api.HandleFunc("/metrics/user_claims", ta.HandleUserClaims)
-	api.HandleFunc("/metrics/auth", BasicAuth(apiCtx, http.HandlerFunc(ta.HandleAuthMetrics)))
-	api.HandleFunc("/metrics/invites", BasicAuth(apiCtx, http.HandlerFunc(ta.HandleInvitesMetrics)))
-	api.HandleFunc("/metrics/user_base", ta.HandleUserBase)
+	api.HandleFunc("/metrics/users", MetricsAuth(apiCtx, http.HandlerFunc(ta.HandleUsersMetrics)))
+	api.HandleFunc("/metrics/claims", MetricsAuth(apiCtx, http.HandlerFunc(ta.HandleClaimMetrics)))
+	api.HandleFunc("/metrics/user_claims", MetricsAuth(apiCtx, http.HandlerFunc(ta.HandleUserClaims)))
+	api.HandleFunc("/metrics/auth", MetricsAuth(apiCtx, http.HandlerFunc(ta.HandleAuthMetrics)))
+	api.HandleFunc("/metrics/invites", MetricsAuth(apiCtx, http.HandlerFunc(ta.HandleInvitesMetrics)))
+	api.HandleFunc("/metrics/user_base", MetricsAuth(apiCtx, http.HandlerFunc(ta.HandleUserBase)))
 
 	if apiCtx.Config.App.MockRegistration {
 		api.HandleFunc("/mock_register", ta.HandleMockRegistration)
@@ -95,6 +108,11 @@ func (ta *TruAPI) RegisterRoutes(apiCtx truCtx.TruAPIContext) {
 	fs := http.FileServer(http.Dir(apiCtx.Config.Web.Directory))
 
 	// add specific route for file
+	// files of the local storage, S3 serves its own
+	if local, ok := ta.Storage.(*storage.Local); ok {
+		ta.PathPrefix("/storage/", http.StripPrefix("/storage", local))
+	}
+
 	ta.Handle("/apple-app-site-association", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		http.ServeFile(w, r, filepath.Join(apiCtx.Config.Web.Directory, "apple-app-site-association"))
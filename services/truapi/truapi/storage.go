@@ -0,0 +1,31 @@
+package truapi
+
+import (
+	"fmt"
+
+	truCtx "github.com/TruStory/octopus/services/truapi/context"
+	"github.com/TruStory/octopus/services/truapi/storage"
+)
+
+// newStorage creates the storage selected in the config.
+func newStorage(config truCtx.Config) (storage.Storage, error) {
+	baseURL := config.Storage.BaseURL
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("http://localhost:%d/storage", config.Host.Port)
+	}
+	store, err := storage.New(storage.Config{
+		Backend:      config.Storage.Backend,
+		Bucket:       config.AWS.S3Bucket,
+		Region:       config.AWS.S3Region,
+		Endpoint:     config.Storage.Endpoint,
+		AccessKey:    config.AWS.AccessKey,
+		AccessSecret: config.AWS.AccessSecret,
+		Dir:          config.Storage.Dir,
+		BaseURL:      baseURL,
+		Secret:       config.Storage.Secret,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
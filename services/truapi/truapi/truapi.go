@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,12 +21,15 @@ import (
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/gorilla/mux"
 
+	"github.com/TruStory/octopus/services/truapi/analytics"
 	"github.com/TruStory/octopus/services/truapi/chttp"
 	truCtx "github.com/TruStory/octopus/services/truapi/context"
 	"github.com/TruStory/octopus/services/truapi/db"
 	"github.com/TruStory/octopus/services/truapi/dripper"
 	"github.com/TruStory/octopus/services/truapi/graphql"
+	"github.com/TruStory/octopus/services/truapi/i18n"
 	"github.com/TruStory/octopus/services/truapi/postman"
+	"github.com/TruStory/octopus/services/truapi/storage"
 	"github.com/TruStory/octopus/services/truapi/truapi/cookies"
 )
 
@@ -65,12 +69,19 @@ type TruAPI struct {
 	DBClient      db.Datastore
 	Postman       *postman.Postman
 	Dripper       *dripper.Dripper
+	Catalog       *i18n.Catalog
+	// Storage stores uploaded media, nil when it isn't configured
+	Storage storage.Storage
+	// Analytics is the pipeline of the tracked events, nil when it's disabled
+	Analytics *analytics.Pipeline
 
 	// notifications
-	notificationsInitialized bool
-	commentsNotificationsCh  chan CommentNotificationRequest
-	broadcastNotificationsCh chan BroadcastNotificationRequest
-	httpClient               *http.Client
+	notificationsInitialized   bool
+	commentsNotificationsCh    chan CommentNotificationRequest
+	broadcastNotificationsCh   chan BroadcastNotificationRequest
+	achievementNotificationsCh chan AchievementNotificationRequest
+	httpClient                 *http.Client
+	snippetLimiter             *rateLimiter
 }
 
 // NewTruAPI returns a `TruAPI` instance populated with the existing app and a new GraphQL client
@@ -90,18 +101,39 @@ func NewTruAPI(apiCtx truCtx.TruAPIContext) *TruAPI {
 	if err != nil {
 		log.Fatal(err)
 	}
+	store, err := newStorage(apiCtx.Config)
+	if err != nil {
+		log.Println("storage is not configured, avatars are linked from Twitter:", err)
+	}
+	if apiCtx.Config.Spotlight.Secret == "" {
+		log.Println("spotlight secret is not set, previews won't be invalidated or prewarmed")
+	}
+	dbClient := db.NewDBClient(apiCtx.Config)
+	dbClient.RegisterPoolMetrics()
+	var pipeline *analytics.Pipeline
+	if apiCtx.Config.Analytics.Enabled {
+		pipeline, err = newAnalytics(apiCtx.Config, dbClient)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 	ta := TruAPI{
-		API:                      chttp.NewAPI(apiCtx, supported),
-		APIContext:               apiCtx,
-		GraphQLClient:            graphql.NewGraphQLClient(),
-		DBClient:                 db.NewDBClient(apiCtx.Config),
-		Postman:                  postmanService,
-		Dripper:                  dripperService,
-		commentsNotificationsCh:  make(chan CommentNotificationRequest),
-		broadcastNotificationsCh: make(chan BroadcastNotificationRequest),
+		API:                        chttp.NewAPI(apiCtx, supported),
+		APIContext:                 apiCtx,
+		GraphQLClient:              graphql.NewGraphQLClient(),
+		DBClient:                   dbClient,
+		Postman:                    postmanService,
+		Dripper:                    dripperService,
+		Catalog:                    postmanService.Catalog,
+		Storage:                    store,
+		Analytics:                  pipeline,
+		commentsNotificationsCh:    make(chan CommentNotificationRequest),
+		broadcastNotificationsCh:   make(chan BroadcastNotificationRequest),
+		achievementNotificationsCh: make(chan AchievementNotificationRequest),
 		httpClient: &http.Client{
 			Timeout: time.Second * 5,
 		},
+		snippetLimiter: newSnippetLimiter(apiCtx.Config.Spotlight),
 	}
 
 	return &ta
@@ -112,6 +144,7 @@ func (ta *TruAPI) RunNotificationSender(apiCtx truCtx.TruAPIContext) error {
 	ta.notificationsInitialized = true
 	go ta.runCommentNotificationSender(ta.commentsNotificationsCh, apiCtx.Config.Push.EndpointURL)
 	go ta.runBroadcastNotificationSender(ta.broadcastNotificationsCh, apiCtx.Config.Push.EndpointURL)
+	go ta.runAchievementNotificationSender(ta.achievementNotificationsCh, apiCtx.Config.Push.EndpointURL)
 	return nil
 }
 
@@ -120,6 +153,25 @@ func (ta *TruAPI) RunLeaderboardScheduler(apiCtx truCtx.TruAPIContext) {
 	go ta.leaderboardScheduler()
 }
 
+// RunAchievementsEngine runs the achievements background processing.
+func (ta *TruAPI) RunAchievementsEngine(apiCtx truCtx.TruAPIContext) {
+	go ta.achievementsEngine()
+}
+
+// RunRewardReconciliation runs the reward ledger reconciliation in the background.
+func (ta *TruAPI) RunRewardReconciliation(apiCtx truCtx.TruAPIContext) {
+	go ta.rewardReconciliationScheduler()
+}
+
+// RunAnalytics writes the tracked events and runs the exporters in the background.
+func (ta *TruAPI) RunAnalytics(apiCtx truCtx.TruAPIContext) {
+	if ta.Analytics == nil {
+		log.Println("analytics are disabled")
+		return
+	}
+	go ta.Analytics.Run(nil)
+}
+
 // WrapHandler wraps a chttp.Handler and returns a standar http.Handler
 func WrapHandler(h chttp.Handler) http.Handler {
 	return h.HandlerFunc()
@@ -187,6 +239,18 @@ func BasicAuth(apiCtx truCtx.TruAPIContext, handler http.Handler) http.HandlerFu
 	})
 }
 
+// MetricsAuth lets through requests with the metrics secret, or with admin credentials
+func MetricsAuth(apiCtx truCtx.TruAPIContext, handler http.Handler) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := apiCtx.Config.Metrics.Secret
+		if secret != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Metrics-Secret")), []byte(secret)) == 1 {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		BasicAuth(apiCtx, handler).ServeHTTP(w, r)
+	})
+}
+
 // RegisterMutations registers mutations
 func (ta *TruAPI) RegisterMutations() {
 	ta.GraphQLClient.RegisterMutation("addComment", func(args struct {
@@ -203,7 +267,7 @@ func (ta *TruAPI) RegisterResolvers() {
 	ta.GraphQLClient.RegisterObjectResolver("Reaction", db.Reaction{}, map[string]interface{}{
 		"id":   func(_ context.Context, q db.Reaction) int64 { return q.ID },
 		"type": func(_ context.Context, q db.Reaction) db.ReactionType { return q.ReactionType },
-		"creator": func(ctx context.Context, q db.Reaction) *AppAccount {
+		"creator": func(ctx context.Context, q db.Reaction) (*AppAccount, error) {
 			return ta.appAccountResolver(ctx, queryByAddress{ID: q.Creator})
 		},
 	})
@@ -217,13 +281,13 @@ func (ta *TruAPI) RegisterResolvers() {
 	ta.GraphQLClient.RegisterQueryResolver("invites", ta.invitesResolver)
 	ta.GraphQLClient.RegisterObjectResolver("Invite", db.Invite{}, map[string]interface{}{
 		"id": func(_ context.Context, i db.Invite) int64 { return i.ID },
-		"creator": func(ctx context.Context, q db.Invite) *AppAccount {
+		"creator": func(ctx context.Context, q db.Invite) (*AppAccount, error) {
 			return ta.appAccountResolver(ctx, queryByAddress{ID: q.Creator})
 		},
-		"friend": func(ctx context.Context, i db.Invite) *AppAccount {
+		"friend": func(ctx context.Context, i db.Invite) (*AppAccount, error) {
 			friend, err := ta.DBClient.UserByEmail(i.FriendEmail)
 			if err != nil || friend == nil {
-				return nil
+				return nil, err
 			}
 			return ta.appAccountResolver(ctx, queryByAddress{ID: friend.Address})
 		},
@@ -242,40 +306,49 @@ func (ta *TruAPI) RegisterResolvers() {
 		"availableBalance": func(_ context.Context, q AppAccount) sdk.Coin {
 			return sdk.NewCoin(app.StakeDenom, q.Coins.AmountOf(app.StakeDenom))
 		},
-		"totalClaims": func(ctx context.Context, q AppAccount) int {
-			return len(ta.appAccountClaimsCreatedResolver(ctx, queryByAddress{ID: q.Address}))
+		"totalClaims": func(ctx context.Context, q AppAccount) (int, error) {
+			items, err := ta.appAccountClaimsCreatedResolver(ctx, queryByAddress{ID: q.Address})
+			return len(items), err
 		},
-		"totalArguments": func(ctx context.Context, q AppAccount) int {
-			return len(ta.appAccountArgumentsResolver(ctx, queryByAddress{ID: q.Address}))
+		"totalArguments": func(ctx context.Context, q AppAccount) (int, error) {
+			items, err := ta.appAccountArgumentsResolver(ctx, queryByAddress{ID: q.Address})
+			return len(items), err
 		},
-		"totalAgrees": func(ctx context.Context, q AppAccount) int {
-			return len(ta.agreesResolver(ctx, queryByAddress{ID: q.Address}))
+		"totalAgrees": func(ctx context.Context, q AppAccount) (int, error) {
+			items, err := ta.agreesResolver(ctx, queryByAddress{ID: q.Address})
+			return len(items), err
 		},
-		"totalAgreesReceived": func(ctx context.Context, q AppAccount) int64 {
+		"totalAgreesReceived": func(ctx context.Context, q AppAccount) (int64, error) {
 			return ta.agreesReceivedResolver(ctx, q.Address)
 		},
-		"earnedBalance": func(ctx context.Context, q AppAccount) sdk.Coin {
+		"badges": func(ctx context.Context, q AppAccount) ([]Badge, error) {
+			return ta.badgesResolver(ctx, q.Address)
+		},
+		"earnedBalance": func(ctx context.Context, q AppAccount) (sdk.Coin, error) {
 			return ta.earnedBalanceResolver(ctx, queryByAddress{ID: q.Address})
 		},
-		"earnedStake": func(ctx context.Context, q AppAccount) []EarnedCoin {
+		"earnedStake": func(ctx context.Context, q AppAccount) ([]EarnedCoin, error) {
 			return ta.earnedStakeResolver(ctx, queryByAddress{ID: q.Address})
 		},
-		"pendingBalance": func(ctx context.Context, q AppAccount) sdk.Coin {
+		"pendingBalance": func(ctx context.Context, q AppAccount) (sdk.Coin, error) {
 			return ta.pendingBalanceResolver(ctx, queryByAddress{ID: q.Address})
 		},
-		"pendingStake": func(ctx context.Context, q AppAccount) []EarnedCoin {
+		"pendingStake": func(ctx context.Context, q AppAccount) ([]EarnedCoin, error) {
 			return ta.pendingStakeResolver(ctx, queryByAddress{ID: q.Address})
 		},
-		"userProfile": func(ctx context.Context, q AppAccount) *db.UserProfile {
+		"userProfile": func(ctx context.Context, q AppAccount) (*db.UserProfile, error) {
 			return ta.userProfileResolver(ctx, q.Address)
 		},
-		"userJourney": func(ctx context.Context, q AppAccount) []db.UserJourneyStep {
-			user := ta.userResolver(ctx, q.Address)
+		"userJourney": func(ctx context.Context, q AppAccount) ([]db.UserJourneyStep, error) {
+			user, err := ta.userResolver(ctx, q.Address)
+			if err != nil {
+				return nil, err
+			}
 			if user == nil {
-				return []db.UserJourneyStep{}
+				return []db.UserJourneyStep{}, nil
 			}
 
-			return user.Meta.Journey
+			return user.Meta.Journey, nil
 		},
 		// deprecated, use "userProfile" instead
 		"twitterProfile": func(ctx context.Context, q AppAccount) db.TwitterProfile {
@@ -284,7 +357,7 @@ func (ta *TruAPI) RegisterResolvers() {
 	})
 
 	ta.GraphQLClient.RegisterObjectResolver("TwitterProfile", db.TwitterProfile{}, map[string]interface{}{
-		"id": func(_ context.Context, q db.TwitterProfile) string { return string(q.ID) },
+		"id": func(_ context.Context, q db.TwitterProfile) string { return strconv.FormatInt(q.ID, 10) },
 		"avatarURI": func(_ context.Context, q db.TwitterProfile) string {
 			largeURI := strings.Replace(q.AvatarURI, "_bigger", "_200x200", 1)
 			return strings.Replace(largeURI, "http://", "https://", 1)
@@ -299,7 +372,7 @@ func (ta *TruAPI) RegisterResolvers() {
 	})
 
 	ta.GraphQLClient.RegisterObjectResolver("EarnedCoin", EarnedCoin{}, map[string]interface{}{
-		"community": func(ctx context.Context, q EarnedCoin) *community.Community {
+		"community": func(ctx context.Context, q EarnedCoin) (*community.Community, error) {
 			return ta.communityResolver(ctx, queryByCommunityID{CommunityID: q.CommunityID})
 		},
 	})
@@ -307,7 +380,7 @@ func (ta *TruAPI) RegisterResolvers() {
 	ta.GraphQLClient.RegisterQueryResolver("appAccountCommunityEarnings", ta.appAccountCommunityEarningsResolver)
 	ta.GraphQLClient.RegisterObjectResolver("AppAccountCommunityEarnings", appAccountCommunityEarning{}, map[string]interface{}{
 		"id": func(_ context.Context, q appAccountCommunityEarning) string { return q.CommunityID },
-		"community": func(ctx context.Context, q appAccountCommunityEarning) *community.Community {
+		"community": func(ctx context.Context, q appAccountCommunityEarning) (*community.Community, error) {
 			return ta.communityResolver(ctx, queryByCommunityID{CommunityID: q.CommunityID})
 		},
 	})
@@ -316,13 +389,27 @@ func (ta *TruAPI) RegisterResolvers() {
 
 	ta.GraphQLClient.RegisterQueryResolver("leaderboard", ta.leaderboardResolver)
 	ta.GraphQLClient.RegisterObjectResolver("LeaderboardTopUser", db.LeaderboardTopUser{}, map[string]interface{}{
-		"account": func(ctx context.Context, t db.LeaderboardTopUser) *AppAccount {
+		"account": func(ctx context.Context, t db.LeaderboardTopUser) (*AppAccount, error) {
 			return ta.appAccountResolver(ctx, queryByAddress{ID: t.Address})
 		},
 		"earned": func(ctx context.Context, t db.LeaderboardTopUser) sdk.Coin {
 			return sdk.NewInt64Coin(app.StakeDenom, t.Earned)
 		},
 	})
+	ta.GraphQLClient.RegisterQueryResolver("leaderboardRanks", ta.leaderboardRanksResolver)
+	ta.GraphQLClient.RegisterQueryResolver("leaderboardNeighbors", ta.leaderboardNeighborsResolver)
+	ta.GraphQLClient.RegisterQueryResolver("leaderboardRankHistory", ta.leaderboardRankHistoryResolver)
+	ta.GraphQLClient.RegisterObjectResolver("LeaderboardRankEntry", db.LeaderboardRankEntry{}, map[string]interface{}{
+		"account": func(ctx context.Context, r db.LeaderboardRankEntry) (*AppAccount, error) {
+			return ta.appAccountResolver(ctx, queryByAddress{ID: r.Address})
+		},
+		"movement": func(_ context.Context, r db.LeaderboardRankEntry) int64 {
+			return leaderboardRankMovement(r.Rank, r.PreviousRank)
+		},
+	})
+	ta.GraphQLClient.RegisterObjectResolver("LeaderboardRank", db.LeaderboardRank{}, map[string]interface{}{
+		"date": func(_ context.Context, r db.LeaderboardRank) string { return r.Date.Format("2006-01-02") },
+	})
 
 	ta.GraphQLClient.RegisterQueryResolver("communities", ta.communitiesResolver)
 	ta.GraphQLClient.RegisterQueryResolver("community", ta.communityResolver)
@@ -348,29 +435,34 @@ func (ta *TruAPI) RegisterResolvers() {
 	})
 	ta.GraphQLClient.RegisterPaginatedObjectResolver("claims", "iD", claim.Claim{}, map[string]interface{}{
 		"id": func(_ context.Context, q claim.Claim) uint64 { return q.ID },
-		"community": func(ctx context.Context, q claim.Claim) *community.Community {
+		"community": func(ctx context.Context, q claim.Claim) (*community.Community, error) {
 			return ta.communityResolver(ctx, queryByCommunityID{CommunityID: q.CommunityID})
 		},
 		"source": func(ctx context.Context, q claim.Claim) string { return q.Source.String() },
 		"image":  ta.claimImageResolver,
 		"video":  ta.claimVideoResolver,
-		"argumentCount": func(ctx context.Context, q claim.Claim) int {
-			return len(ta.claimArgumentsResolver(ctx, queryClaimArgumentParams{ClaimID: q.ID}))
+		"argumentCount": func(ctx context.Context, q claim.Claim) (int, error) {
+			arguments, err := ta.claimArgumentsResolver(ctx, queryClaimArgumentParams{ClaimID: q.ID})
+			return len(arguments), err
 		},
 		"topArgument": ta.topArgumentResolver,
-		"arguments": func(ctx context.Context, q claim.Claim, a queryClaimArgumentParams) []staking.Argument {
+		"arguments": func(ctx context.Context, q claim.Claim, a queryClaimArgumentParams) ([]staking.Argument, error) {
 			return ta.claimArgumentsResolver(ctx, queryClaimArgumentParams{ClaimID: q.ID, Address: a.Address, Filter: a.Filter})
 		},
 		"participants":      ta.claimParticipantsResolver,
-		"participantsCount": func(ctx context.Context, q claim.Claim) int { return len(ta.claimParticipantsResolver(ctx, q)) },
-		"comments": func(ctx context.Context, q claim.Claim) []db.Comment {
+		"participantsCount": func(ctx context.Context, q claim.Claim) (int, error) {
+			participants, err := ta.claimParticipantsResolver(ctx, q)
+			return len(participants), err
+		},
+		"comments": func(ctx context.Context, q claim.Claim) ([]db.Comment, error) {
 			return ta.commentsResolver(ctx, queryCommentsParams{ClaimID: &q.ID})
 		},
-		"creator": func(ctx context.Context, q claim.Claim) *AppAccount {
+		"creator": func(ctx context.Context, q claim.Claim) (*AppAccount, error) {
 			return ta.appAccountResolver(ctx, queryByAddress{ID: q.Creator.String()})
 		},
-		"commentCount": func(ctx context.Context, q claim.Claim) int {
-			return len(ta.commentsResolver(ctx, queryCommentsParams{ClaimID: &q.ID}))
+		"commentCount": func(ctx context.Context, q claim.Claim) (int, error) {
+			comments, err := ta.commentsResolver(ctx, queryCommentsParams{ClaimID: &q.ID})
+			return len(comments), err
 		},
 
 		// deprecated
@@ -401,15 +493,18 @@ func (ta *TruAPI) RegisterResolvers() {
 		"createdTime": func(_ context.Context, q staking.Argument) string { return q.CreatedTime.String() },
 		"editedTime":  func(_ context.Context, q staking.Argument) string { return q.EditedTime.String() },
 		"edited":      func(_ context.Context, q staking.Argument) bool { return q.Edited },
-		"creator": func(ctx context.Context, q staking.Argument) *AppAccount {
+		"creator": func(ctx context.Context, q staking.Argument) (*AppAccount, error) {
 			return ta.appAccountResolver(ctx, queryByAddress{ID: q.Creator.String()})
 		},
 		"appAccountStake": ta.appAccountStakeResolver,
 		"appAccountSlash": ta.appAccountSlashResolver,
 		"stakers":         ta.claimArgumentUpvoteStakersResolver,
-		"claim": func(ctx context.Context, q staking.Argument) *claim.Claim {
-			claim := ta.claimResolver(ctx, queryByClaimID{ID: q.ClaimID})
-			return &claim
+		"claim": func(ctx context.Context, q staking.Argument) (*claim.Claim, error) {
+			claim, err := ta.claimResolver(ctx, queryByClaimID{ID: q.ClaimID})
+			if err != nil {
+				return nil, err
+			}
+			return &claim, nil
 		},
 		"communityId": func(ctx context.Context, q staking.Argument) string {
 			return q.CommunityID
@@ -424,7 +519,7 @@ func (ta *TruAPI) RegisterResolvers() {
 		"argumentId": func(_ context.Context, q db.Comment) int64 { return q.ArgumentID },
 		"elementId":  func(_ context.Context, q db.Comment) int64 { return q.ElementID },
 		"body":       func(_ context.Context, q db.Comment) string { return q.Body },
-		"creator": func(ctx context.Context, q db.Comment) *AppAccount {
+		"creator": func(ctx context.Context, q db.Comment) (*AppAccount, error) {
 			return ta.appAccountResolver(ctx, queryByAddress{ID: q.Creator})
 		},
 		"createdAt": func(_ context.Context, q db.Comment) time.Time { return q.CreatedAt },
@@ -435,7 +530,7 @@ func (ta *TruAPI) RegisterResolvers() {
 		"id":      func(_ context.Context, q db.Question) int64 { return q.ID },
 		"claimId": func(_ context.Context, q db.Question) int64 { return q.ClaimID },
 		"body":    func(_ context.Context, q db.Question) string { return q.Body },
-		"creator": func(ctx context.Context, q db.Question) *AppAccount {
+		"creator": func(ctx context.Context, q db.Question) (*AppAccount, error) {
 			return ta.appAccountResolver(ctx, queryByAddress{ID: q.Creator})
 		},
 		"createdAt": func(_ context.Context, q db.Question) time.Time { return q.CreatedAt },
@@ -443,7 +538,7 @@ func (ta *TruAPI) RegisterResolvers() {
 
 	ta.GraphQLClient.RegisterObjectResolver("Stake", staking.Stake{}, map[string]interface{}{
 		"id": func(_ context.Context, q staking.Stake) uint64 { return q.ID },
-		"creator": func(ctx context.Context, q staking.Stake) *AppAccount {
+		"creator": func(ctx context.Context, q staking.Stake) (*AppAccount, error) {
 			return ta.appAccountResolver(ctx, queryByAddress{ID: q.Creator.String()})
 		},
 		"stake": func(ctx context.Context, q staking.Stake) sdk.Coin { return q.Amount },
@@ -454,10 +549,10 @@ func (ta *TruAPI) RegisterResolvers() {
 	ta.GraphQLClient.RegisterObjectResolver("Slash", slashing.Slash{}, map[string]interface{}{
 		"id":         func(_ context.Context, q slashing.Slash) uint64 { return q.ID },
 		"argumentId": func(_ context.Context, q slashing.Slash) uint64 { return q.ArgumentID },
-		"argument": func(ctx context.Context, q slashing.Slash) *staking.Argument {
+		"argument": func(ctx context.Context, q slashing.Slash) (*staking.Argument, error) {
 			return ta.claimArgumentResolver(ctx, queryByArgumentID{ID: q.ArgumentID})
 		},
-		"creator": func(ctx context.Context, q slashing.Slash) *AppAccount {
+		"creator": func(ctx context.Context, q slashing.Slash) (*AppAccount, error) {
 			return ta.appAccountResolver(ctx, queryByAddress{ID: q.Creator.String()})
 		},
 	})
@@ -496,17 +591,21 @@ func (ta *TruAPI) RegisterResolvers() {
 			return q.UserProfileID
 		},
 		"title": func(_ context.Context, q db.NotificationEvent) string {
-			return q.Type.String()
+			locale := i18n.DefaultLocale
+			if q.UserProfile != nil {
+				locale = q.UserProfile.Locale
+			}
+			return ta.Catalog.T(locale, q.Type.Key(), i18n.Params{"coin": db.CoinDisplayName})
 		},
-		"senderProfile": func(ctx context.Context, q db.NotificationEvent) *AppAccount {
+		"senderProfile": func(ctx context.Context, q db.NotificationEvent) (*AppAccount, error) {
 			if q.SenderProfile != nil {
 				sender, err := ta.DBClient.UserByID(q.SenderProfileID)
 				if err != nil {
-					return nil
+					return nil, err
 				}
 				return ta.appAccountResolver(ctx, queryByAddress{ID: sender.Address})
 			}
-			return nil
+			return nil, nil
 		},
 		"createdTime": func(_ context.Context, q db.NotificationEvent) time.Time {
 			return q.Timestamp
@@ -1,6 +1,7 @@
 package truapi
 
 import (
+	"context"
 	"time"
 
 	"github.com/TruStory/truchain/x/bank"
@@ -46,6 +47,7 @@ const (
 
 type LeaderboardMetricFilter int64
 type LeaderboardDateFilter int64
+type LeaderboardPeriodFilter int64
 
 const (
 	LeaderboardMetricFilterTruEarned LeaderboardMetricFilter = iota
@@ -89,6 +91,26 @@ func (f LeaderboardDateFilter) Value() time.Duration {
 	return LeaderboardDateRangeMapping[f]
 }
 
+const (
+	LeaderboardPeriodFilterWeek LeaderboardPeriodFilter = iota
+	LeaderboardPeriodFilterMonth
+	LeaderboardPeriodFilterAllTime
+)
+
+var LeaderboardPeriodMapping = []string{
+	LeaderboardPeriodFilterWeek:    db.LeaderboardPeriodWeek,
+	LeaderboardPeriodFilterMonth:   db.LeaderboardPeriodMonth,
+	LeaderboardPeriodFilterAllTime: db.LeaderboardPeriodAllTime,
+}
+
+func (f LeaderboardPeriodFilter) Value() string {
+	// if unknown fallback to week
+	if int(f) >= len(LeaderboardPeriodMapping) {
+		return LeaderboardPeriodMapping[LeaderboardPeriodFilterWeek]
+	}
+	return LeaderboardPeriodMapping[f]
+}
+
 // ArgumentCreatedResponse represents truchain transaction response for creating an argument
 type ArgumentCreatedResponse struct {
 	Type  string           `json:"type"`
@@ -117,6 +139,34 @@ type CommentNotificationRequest struct {
 // BroadcastNotificationRequest is the payload sent to pushd for broadcasting notifications.
 type BroadcastNotificationRequest struct {
 	Type db.NotificationType `json:"type"`
+
+	// ctx carries the logger and request ID of the request that broadcast it
+	ctx context.Context
+}
+
+// AchievementNotificationRequest is the payload sent to pushd when a user unlocks an achievement.
+type AchievementNotificationRequest struct {
+	Address       string `json:"address"`
+	AchievementID string `json:"achievement_id"`
+	Name          string `json:"name"`
+	CommunityID   string `json:"community_id,omitempty"`
+	Invites       int64  `json:"invites"`
+	// Reward is the TRU rewarded in utru
+	Reward int64 `json:"reward"`
+
+	// ctx carries the logger and request ID of the work that unlocked it
+	ctx context.Context
+}
+
+// Badge is an achievement unlocked by a user
+type Badge struct {
+	ID          string
+	Name        string
+	Description string
+	Image       string
+	CommunityID string
+	Value       int64
+	UnlockedAt  time.Time
 }
 
 // AppAccount represents graphql serializable representation of a cosmos account
@@ -0,0 +1,89 @@
+package truapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TruStory/octopus/services/truapi/sigauth"
+)
+
+// Kinds of uploads processed by the uploader service.
+const (
+	uploadKindAvatar = "avatar"
+	uploadKindClaim  = "claim"
+)
+
+// uploadRejectedError is returned when the uploader refuses an upload, e.g. when it isn't an image.
+type uploadRejectedError string
+
+func (e uploadRejectedError) Error() string {
+	return string(e)
+}
+
+type processedUpload struct {
+	URL        string            `json:"url"`
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
+// processUpload has the uploader service verify an uploaded image, strip its metadata and generate its thumbnails.
+// It returns the URL to store, the uploaded URL is kept as is when the uploader isn't configured.
+// An empty URL clears the image and images the uploader already processed are kept, only fresh uploads are sent.
+func (ta *TruAPI) processUpload(uploadURL, kind string) (string, error) {
+	config := ta.APIContext.Config.Uploader
+	if config.URL == "" || config.Secret == "" || uploadURL == "" || isProcessedUpload(uploadURL, config.ImagesURL, kind) {
+		return uploadURL, nil
+	}
+	body, err := json.Marshal(map[string]string{"url": uploadURL, "kind": kind})
+	if err != nil {
+		return "", err
+	}
+	client := &http.Client{
+		Timeout: time.Second * 30,
+	}
+	request, err := http.NewRequest(http.MethodPost, config.URL+"/v1/upload/process", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	err = sigauth.Sign(request, config.Secret)
+	if err != nil {
+		return "", err
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 400 && response.StatusCode < 500 {
+		var rejection struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(response.Body).Decode(&rejection); err != nil || rejection.Message == "" {
+			return "", uploadRejectedError("invalid upload")
+		}
+		return "", uploadRejectedError(rejection.Message)
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("processing upload: status %d", response.StatusCode)
+	}
+	processed := new(processedUpload)
+	err = json.NewDecoder(response.Body).Decode(processed)
+	if err != nil {
+		return "", err
+	}
+	return processed.URL, nil
+}
+
+// isProcessedUpload reports whether uploadURL is in the folder the uploader stores processed images of a kind in,
+// like {imagesURL}avatars/ for avatars. Nothing is processed when imagesURL is empty.
+func isProcessedUpload(uploadURL, imagesURL, kind string) bool {
+	if imagesURL == "" {
+		return false
+	}
+	name := strings.TrimPrefix(uploadURL, imagesURL+kind+"s/")
+	return name != uploadURL && name != "" && !strings.Contains(name, "/")
+}
@@ -0,0 +1,65 @@
+package truapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	truCtx "github.com/TruStory/octopus/services/truapi/context"
+)
+
+func TestProcessUpload(t *testing.T) {
+	var processed []string
+	uploader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			URL string `json:"url"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		processed = append(processed, req.URL)
+		if !strings.HasPrefix(req.URL, "https://trustory.s3.amazonaws.com/images/uploads/") {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"message": "url is not an upload"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(processedUpload{URL: "https://trustory.s3.amazonaws.com/images/avatars/1f2e.png"})
+	}))
+	defer uploader.Close()
+	ta := &TruAPI{}
+	ta.APIContext.Config.Uploader = truCtx.UploaderConfig{
+		URL: uploader.URL, Secret: "secret", ImagesURL: "https://trustory.s3.amazonaws.com/images/",
+	}
+
+	url, err := ta.processUpload("https://trustory.s3.amazonaws.com/images/uploads/1f2e.png", uploadKindAvatar)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://trustory.s3.amazonaws.com/images/avatars/1f2e.png", url)
+
+	// clearing the image and keeping a processed one don't reach the uploader
+	url, err = ta.processUpload("", uploadKindAvatar)
+	assert.NoError(t, err)
+	assert.Equal(t, "", url)
+	url, err = ta.processUpload("https://trustory.s3.amazonaws.com/images/avatars/1f2e.png", uploadKindAvatar)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://trustory.s3.amazonaws.com/images/avatars/1f2e.png", url)
+	_, err = ta.processUpload("https://trustory.s3.amazonaws.com/images/claims/1f2e.png", uploadKindClaim)
+	assert.NoError(t, err)
+
+	// an avatar can't reuse a processed claim image
+	_, err = ta.processUpload("https://trustory.s3.amazonaws.com/images/claims/1f2e.png", uploadKindAvatar)
+	assert.Equal(t, uploadRejectedError("url is not an upload"), err)
+
+	// the same folders on another host aren't processed images
+	_, err = ta.processUpload("https://evil.example/x/avatars/a.jpg", uploadKindAvatar)
+	assert.Equal(t, uploadRejectedError("url is not an upload"), err)
+	_, err = ta.processUpload("https://trustory.s3.amazonaws.com/images/avatars/x/a.jpg", uploadKindAvatar)
+	assert.Equal(t, uploadRejectedError("url is not an upload"), err)
+	assert.Equal(t, []string{
+		"https://trustory.s3.amazonaws.com/images/uploads/1f2e.png",
+		"https://trustory.s3.amazonaws.com/images/claims/1f2e.png",
+		"https://evil.example/x/avatars/a.jpg",
+		"https://trustory.s3.amazonaws.com/images/avatars/x/a.jpg",
+	}, processed)
+}
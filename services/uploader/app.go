@@ -1,20 +1,37 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/BurntSushi/toml"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/gorilla/mux"
+
+	"github.com/TruStory/octopus/services/truapi/sigauth"
+	"github.com/TruStory/octopus/services/truapi/storage"
 )
 
+// defaultMaxUploadSize is the largest upload accepted when MaxUploadSize isn't configured.
+const defaultMaxUploadSize = 10 << 20
+
+// uploadExpiry is how long a presigned upload can be used.
+const uploadExpiry = 15 * time.Minute
+
+// imageTypes maps the content types that can be uploaded to their file extension.
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
 // URL defines the struct for input data from the client
 type URL struct {
 	Name    string `json:"image_name"`
@@ -29,6 +46,25 @@ type Config struct {
 	Port        string
 	Region      string
 	ImageFolder string
+	// Storage is s3 (default), minio or local
+	Storage string
+	// Endpoint is the URL of a MinIO compatible store
+	Endpoint string
+	// StorageDir, StorageURL and StorageSecret configure the local storage, served under /storage/
+	StorageDir    string
+	StorageURL    string
+	StorageSecret string
+	// AllowedOrigins are the origins allowed to request uploads, "*" is rejected
+	AllowedOrigins []string
+	// MaxUploadSize is the largest upload in bytes
+	MaxUploadSize int64
+	// Secret verifies the signature of process requests from truapi, processing is disabled when empty
+	Secret string
+}
+
+type uploader struct {
+	store storage.Storage
+	conf  Config
 }
 
 func getConfig() string {
@@ -46,48 +82,95 @@ func main() {
 		log.Fatal(err)
 		return
 	}
+	if conf.Storage == storage.BackendLocal && conf.StorageURL == "" {
+		conf.StorageURL = "http://localhost:" + conf.Port + "/storage"
+	}
+	if conf.MaxUploadSize <= 0 {
+		conf.MaxUploadSize = defaultMaxUploadSize
+	}
+	if err := validateOrigins(conf.AllowedOrigins); err != nil {
+		log.Fatal(err)
+		return
+	}
+	store, err := storage.New(storage.Config{
+		Backend:      conf.Storage,
+		Bucket:       conf.BucketName,
+		Region:       conf.Region,
+		Endpoint:     conf.Endpoint,
+		AccessKey:    conf.AWSKey,
+		AccessSecret: conf.AWSSecret,
+		Dir:          conf.StorageDir,
+		BaseURL:      conf.StorageURL,
+		Secret:       conf.StorageSecret,
+	})
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	u := &uploader{store: store, conf: conf}
 
 	r := mux.NewRouter()
-	r.Use(CORSMiddleware)
-	r.HandleFunc("/v1/upload/aws", getURL).Methods("POST", "OPTIONS")
+	r.Use(CORSMiddleware(conf.AllowedOrigins))
+	r.HandleFunc("/v1/upload/aws", u.getURL).Methods("POST", "OPTIONS")
+	if conf.Secret != "" {
+		verifier := sigauth.NewVerifier(conf.Secret, sigauth.DefaultMaxSkew)
+		r.Handle("/v1/upload/process", verifier.Middleware(http.HandlerFunc(u.process))).Methods("POST")
+	} else {
+		log.Println("Secret is not set, uploads can't be processed")
+	}
+	if local, ok := store.(*storage.Local); ok {
+		r.PathPrefix("/storage/").Handler(http.StripPrefix("/storage", local))
+	}
 	if err := http.ListenAndServe(":"+conf.Port, r); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func getURL(w http.ResponseWriter, r *http.Request) {
+// uploadKey generates the key of an upload, the name sent by the client is never used in keys.
+func (u *uploader) uploadKey(ext string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return u.conf.ImageFolder + "uploads/" + hex.EncodeToString(random) + ext, nil
+}
+
+// getURL presigns a POST of an image, limited to its content type and the maximum upload size.
+func (u *uploader) getURL(w http.ResponseWriter, r *http.Request) {
 	var url URL
-	var conf Config
-	if _, err := toml.DecodeFile(getConfig(), &conf); err != nil {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&url); err != nil {
 		errorHandler(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	if err := json.NewDecoder(r.Body).Decode(&url); err != nil {
-		errorHandler(w, http.StatusBadRequest, err.Error())
+	contentType := strings.ToLower(strings.TrimSpace(url.Content))
+	ext, ok := imageTypes[contentType]
+	if !ok {
+		errorHandler(w, http.StatusBadRequest, "only JPEG, PNG, GIF and WebP images can be uploaded")
 		return
 	}
-
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(conf.Region), Credentials: credentials.NewStaticCredentials(conf.AWSKey, conf.AWSSecret, "")})
-
+	key, err := u.uploadKey(ext)
 	if err != nil {
-		errorHandler(w, http.StatusBadRequest, err.Error())
+		errorHandler(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	svc := s3.New(sess)
-
-	req, _ := svc.PutObjectRequest(&s3.PutObjectInput{Bucket: aws.String(conf.BucketName), Key: aws.String(conf.ImageFolder + url.Name)})
-
-	str, err := req.Presign(15 * time.Minute)
-
+	post, err := u.store.PresignPost(storage.PostPolicy{
+		Key:         key,
+		ContentType: contentType,
+		MaxSize:     u.conf.MaxUploadSize,
+		Expires:     uploadExpiry,
+	})
 	if err != nil {
 		errorHandler(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	response(w, http.StatusOK, map[string]interface{}{"url": str})
+	response(w, http.StatusOK, map[string]interface{}{
+		"url":        post.URL,
+		"fields":     post.Fields,
+		"object_url": u.store.URL(key),
+		"max_size":   u.conf.MaxUploadSize,
+	})
 }
 
 func errorHandler(w http.ResponseWriter, code int, msg string) {
@@ -111,15 +194,36 @@ func response(w http.ResponseWriter, code int, payload interface{}) {
 
 }
 
-// CORSMiddleware is an HTTP-handling middleware that adds `Access-Control-Allow-Origin: *` to the response.
-func CORSMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-		if r.Method == "OPTIONS" {
-			return
+// validateOrigins rejects the wildcard origin, which would let any site request uploads.
+func validateOrigins(origins []string) error {
+	for _, origin := range origins {
+		if strings.TrimSpace(origin) == "*" {
+			return errors.New(`AllowedOrigins can't contain "*", list the origins allowed to request uploads`)
 		}
-		next.ServeHTTP(w, r)
-	})
+	}
+	return nil
+}
+
+// CORSMiddleware is an HTTP-handling middleware that allows the configured origins to request uploads.
+// Browsers only POST to the uploader and the storage, presigned PUT URLs are for servers.
+func CORSMiddleware(origins []string) mux.MiddlewareFunc {
+	allowed := make(map[string]bool)
+	for _, origin := range origins {
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+			if allowed[origin] {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+			if r.Method == "OPTIONS" {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOrigins(t *testing.T) {
+	assert.NoError(t, validateOrigins([]string{"https://beta.trustory.io", "http://localhost:3000"}))
+	assert.Error(t, validateOrigins([]string{"https://beta.trustory.io", "*"}))
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	handler := CORSMiddleware([]string{"https://beta.trustory.io/"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/v1/upload/aws", nil)
+	preflight.Header.Set("Origin", "https://beta.trustory.io")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, preflight)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://beta.trustory.io", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.NotContains(t, w.Header().Get("Access-Control-Allow-Methods"), "PUT")
+
+	other := httptest.NewRequest(http.MethodPost, "/v1/upload/aws", nil)
+	other.Header.Set("Origin", "https://evil.example")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, other)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
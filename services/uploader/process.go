@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif" // register the decoders of uploaded formats
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+
+	"github.com/TruStory/octopus/services/truapi/storage"
+)
+
+// Kinds of uploads.
+const (
+	kindAvatar = "avatar"
+	kindClaim  = "claim"
+)
+
+// maxPixels bounds the decoded size of an upload, so small files can't expand into huge images.
+const maxPixels = 40 * 1000 * 1000
+
+var errNotImage = errors.New("upload is not a JPEG, PNG, GIF or WebP image")
+
+// size is an image generated from an upload. Cropped sizes are centered squares,
+// the others keep the aspect ratio. Images are never upscaled.
+type size struct {
+	name  string
+	width int
+	crop  bool
+}
+
+// sizes are generated per kind, the first one being the processed upload and the others its thumbnails.
+var sizes = map[string][]size{
+	kindAvatar: {{"", 400, true}, {"small", 96, true}},
+	kindClaim:  {{"", 1600, false}, {"medium", 800, false}, {"small", 320, false}},
+}
+
+type processRequest struct {
+	URL  string `json:"url"`
+	Kind string `json:"kind"`
+}
+
+type processResponse struct {
+	URL        string            `json:"url"`
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
+// process verifies an upload is an image and replaces it with its re-encoded sizes,
+// which drops EXIF and any other metadata. Uploads that aren't images are deleted.
+func (u *uploader) process(w http.ResponseWriter, r *http.Request) {
+	var req processRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errorHandler(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	kindSizes, ok := sizes[req.Kind]
+	if !ok {
+		errorHandler(w, http.StatusBadRequest, "kind must be avatar or claim")
+		return
+	}
+	prefix := u.store.URL(u.conf.ImageFolder + "uploads/")
+	name := strings.TrimPrefix(req.URL, prefix)
+	if name == req.URL || name == "" || strings.Contains(name, "/") {
+		errorHandler(w, http.StatusBadRequest, "url is not an upload")
+		return
+	}
+	key := u.conf.ImageFolder + "uploads/" + name
+	data, err := u.store.Get(key)
+	if err == storage.ErrNotFound {
+		errorHandler(w, http.StatusNotFound, "upload not found")
+		return
+	}
+	if err != nil {
+		errorHandler(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if int64(len(data)) > u.conf.MaxUploadSize {
+		u.reject(w, key, "upload is too large")
+		return
+	}
+	img, err := decodeImage(data)
+	if err != nil {
+		u.reject(w, key, err.Error())
+		return
+	}
+
+	res := processResponse{Thumbnails: make(map[string]string)}
+	id := strings.TrimSuffix(name, path.Ext(name))
+	for _, s := range kindSizes {
+		encoded, contentType, ext, err := encodeImage(resize(img, s))
+		if err != nil {
+			errorHandler(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		key := u.conf.ImageFolder + req.Kind + "s/" + id + ext
+		if s.name != "" {
+			key = u.conf.ImageFolder + req.Kind + "s/" + id + "-" + s.name + ext
+		}
+		url, err := u.store.Put(key, bytes.NewReader(encoded), contentType)
+		if err != nil {
+			errorHandler(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if s.name == "" {
+			res.URL = url
+		} else {
+			res.Thumbnails[s.name] = url
+		}
+	}
+	if err := u.store.Delete(key); err != nil {
+		log.Println("Error deleting upload", key, err)
+	}
+	response(w, http.StatusOK, res)
+}
+
+// reject deletes an upload that failed validation.
+func (u *uploader) reject(w http.ResponseWriter, key, msg string) {
+	if err := u.store.Delete(key); err != nil {
+		log.Println("Error deleting upload", key, err)
+	}
+	errorHandler(w, http.StatusUnprocessableEntity, msg)
+}
+
+// decodeImage decodes an upload after checking its magic bytes, applying the EXIF orientation of JPEGs.
+func decodeImage(data []byte) (image.Image, error) {
+	if _, ok := imageTypes[http.DetectContentType(data)]; !ok {
+		return nil, errNotImage
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errNotImage
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return nil, errors.New("image dimensions are too large")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errNotImage
+	}
+	if format == "jpeg" {
+		img = orient(img, exifOrientation(data))
+	}
+	return img, nil
+}
+
+// encodeImage encodes opaque images as JPEG and the others as PNG to keep their transparency.
+func encodeImage(img image.Image) ([]byte, string, string, error) {
+	buf := new(bytes.Buffer)
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 85})
+		return buf.Bytes(), "image/jpeg", ".jpg", err
+	}
+	err := png.Encode(buf, img)
+	return buf.Bytes(), "image/png", ".png", err
+}
+
+// resize scales an image down to a size.
+func resize(img image.Image, s size) image.Image {
+	src := img.Bounds()
+	width, height := src.Dx(), src.Dy()
+	if s.crop {
+		side := width
+		if height < side {
+			side = height
+		}
+		x := src.Min.X + (width-side)/2
+		y := src.Min.Y + (height-side)/2
+		src = image.Rect(x, y, x+side, y+side)
+		width, height = side, side
+		if side > s.width {
+			width, height = s.width, s.width
+		}
+	} else if width > s.width {
+		height = height * s.width / width
+		if height < 1 {
+			height = 1
+		}
+		width = s.width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, src, draw.Src, nil)
+	return dst
+}
+
+// orient rotates and flips an image as described by an EXIF orientation,
+// so it displays upright once the EXIF metadata is dropped.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// exifOrientation reads the orientation tag of a JPEG's EXIF metadata, 1 (upright) when missing.
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		// metadata segments come before the start of scan
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 0 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < entries; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/TruStory/octopus/services/truapi/storage"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	buf := new(bytes.Buffer)
+	assert.NoError(t, png.Encode(buf, img))
+	return buf.Bytes()
+}
+
+// exifJPEG builds the start of a JPEG with an APP1 segment holding an orientation tag.
+func exifJPEG(orientation byte) []byte {
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8,
+		0, 1,
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0,
+		0, 0, 0, 0,
+	}
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	length := len(segment) + 2
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1, byte(length >> 8), byte(length)}
+	data = append(data, segment...)
+	return append(data, 0xFF, 0xDA, 0, 2)
+}
+
+func TestExifOrientation(t *testing.T) {
+	assert.Equal(t, 6, exifOrientation(exifJPEG(6)))
+	assert.Equal(t, 1, exifOrientation([]byte{0xFF, 0xD8, 0xFF, 0xDA, 0, 2}))
+	assert.Equal(t, 1, exifOrientation([]byte("not a jpeg")))
+	assert.Equal(t, 1, exifOrientation(exifJPEG(6)[:20]))
+}
+
+func TestOrient(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	red := color.RGBA{R: 255, A: 255}
+	img.Set(0, 0, red)
+
+	rotated := orient(img, 6)
+	assert.Equal(t, image.Rect(0, 0, 2, 3), rotated.Bounds())
+	assert.Equal(t, red, rotated.At(1, 0))
+	rotated = orient(img, 8)
+	assert.Equal(t, red, rotated.At(0, 2))
+	assert.Equal(t, red, orient(img, 3).At(2, 1))
+	assert.Equal(t, img, orient(img, 1))
+}
+
+func TestDecodeImage(t *testing.T) {
+	_, err := decodeImage([]byte("<html><script>alert(1)</script></html>"))
+	assert.Equal(t, errNotImage, err)
+	_, err = decodeImage(append([]byte("\x89PNG\r\n\x1a\n"), "truncated"...))
+	assert.Equal(t, errNotImage, err)
+	img, err := decodeImage(testPNG(t, 4, 3))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 4, 3), img.Bounds())
+}
+
+func TestResize(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1000, 500))
+	assert.Equal(t, image.Rect(0, 0, 400, 400), resize(img, size{width: 400, crop: true}).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 320, 160), resize(img, size{width: 320}).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 1000, 500), resize(img, size{width: 1600}).Bounds())
+	assert.Equal(t, image.Rect(0, 0, 500, 500), resize(img, size{width: 600, crop: true}).Bounds())
+}
+
+func TestProcess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "uploader")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := storage.NewLocal(dir, "http://localhost/storage", "secret")
+	assert.NoError(t, err)
+	u := &uploader{store: store, conf: Config{ImageFolder: "images/", MaxUploadSize: defaultMaxUploadSize}}
+
+	process := func(url, kind string) (int, processResponse) {
+		body, _ := json.Marshal(processRequest{URL: url, Kind: kind})
+		w := httptest.NewRecorder()
+		u.process(w, httptest.NewRequest(http.MethodPost, "/v1/upload/process", bytes.NewReader(body)))
+		var res processResponse
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res
+	}
+
+	url, err := store.Put("images/uploads/abc.png", bytes.NewReader(testPNG(t, 800, 600)), "image/png")
+	assert.NoError(t, err)
+	code, _ := process(url, "banner")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = process("http://localhost/storage/images/avatars/abc.png", kindAvatar)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, res := process(url, kindAvatar)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "http://localhost/storage/images/avatars/abc.png", res.URL)
+	assert.Equal(t, "http://localhost/storage/images/avatars/abc-small.png", res.Thumbnails["small"])
+	data, err := store.Get("images/avatars/abc-small.png")
+	assert.NoError(t, err)
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, 96, config.Width)
+	assert.Equal(t, 96, config.Height)
+	_, err = store.Get("images/uploads/abc.png")
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	url, err = store.Put("images/uploads/def.png", bytes.NewBufferString("<svg onload=alert(1)>"), "image/png")
+	assert.NoError(t, err)
+	code, _ = process(url, kindClaim)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	_, err = store.Get("images/uploads/def.png")
+	assert.Equal(t, storage.ErrNotFound, err)
+}